@@ -0,0 +1,334 @@
+// connectorctl controls a running connector through its local admin API (see sdk.AdminHandler),
+// giving field engineers the same CLI for every connector type, even when the console is unreachable.
+//
+//	CONNECTORCTL_TOKEN=... connectorctl status
+//	connectorctl -token-file /var/lib/gmhost/admin.token logs -f
+//
+// Commands:
+//
+//	status               connector status, version, pending events and unresolved errors
+//	logs [-n N] [-f]     last N logs, -f to follow new ones
+//	rescan               trigger a rescan
+//	quarantine [list]    list quarantined items
+//	quarantine show <id> show a quarantined item
+//	quarantine export <id> [-o file]
+//	                     export a quarantined item (metadata and content) as a tar archive, "<id>.tar" by default
+//	restore <id>         restore a quarantined item
+//	log-level [level]    show or set log level (debug, info, warn, error)
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+const defaultAddr = "127.0.0.1:6061"
+
+// followInterval is the wait between two polls of new logs with logs -f
+var followInterval = time.Second
+
+var errUsage = errors.New("usage: connectorctl [-addr host:port] [-token token | -token-file file] <status|logs|rescan|quarantine|restore|log-level> [args]")
+
+type ctlConfig struct {
+	addr      string
+	token     string
+	tokenFile string
+	command   string
+	args      []string
+}
+
+func main() {
+	cfg, err := parseFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	ctl, err := newCtl(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err = ctl.run(ctx, cfg.command, cfg.args, os.Stdout); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func parseFlags(args []string) (cfg ctlConfig, err error) {
+	fs := flag.NewFlagSet("connectorctl", flag.ContinueOnError)
+	fs.StringVar(&cfg.addr, "addr", defaultAddr, "connector admin API address")
+	fs.StringVar(&cfg.token, "token", os.Getenv("CONNECTORCTL_TOKEN"), "admin API token (default: $CONNECTORCTL_TOKEN)")
+	fs.StringVar(&cfg.tokenFile, "token-file", "", "file holding admin API token (see sdk.AdminOptions.TokenFile)")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() == 0 {
+		err = errUsage
+		return
+	}
+	cfg.command = fs.Arg(0)
+	cfg.args = fs.Args()[1:]
+	if cfg.token == "" && cfg.tokenFile == "" {
+		err = errors.New("token or token file is required")
+		return
+	}
+	return
+}
+
+type ctl struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+func newCtl(cfg ctlConfig) (c ctl, err error) {
+	token := cfg.token
+	if token == "" {
+		raw, readErr := os.ReadFile(cfg.tokenFile) //nolint:gosec // path given by operator
+		if readErr != nil {
+			err = fmt.Errorf("could not read token file, %w", readErr)
+			return
+		}
+		token = strings.TrimSpace(string(raw))
+	}
+	c = ctl{
+		baseURL:    "http://" + cfg.addr,
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	return
+}
+
+func (c ctl) run(ctx context.Context, command string, args []string, out io.Writer) (err error) {
+	switch command {
+	case "status":
+		err = c.printJSON(ctx, "/admin/status", out)
+	case "quarantine":
+		err = c.quarantine(ctx, args, out)
+	case "logs":
+		err = c.logs(ctx, args, out)
+	case "rescan":
+		if err = c.call(ctx, http.MethodPost, "/admin/rescan", nil, nil); err == nil {
+			_, err = fmt.Fprintln(out, "rescan triggered")
+		}
+	case "restore":
+		if len(args) != 1 {
+			err = errors.New("usage: connectorctl restore <id>")
+			return
+		}
+		if err = c.call(ctx, http.MethodPost, "/admin/restore", sdk.RestoreActionContent{ID: args[0]}, nil); err == nil {
+			_, err = fmt.Fprintf(out, "%s restored\n", args[0])
+		}
+	case "log-level":
+		err = c.logLevel(ctx, args, out)
+	default:
+		err = fmt.Errorf("unknown command %q, %w", command, errUsage)
+	}
+	return
+}
+
+func (c ctl) call(ctx context.Context, method string, path string, body any, res any) (err error) {
+	var reqBody io.Reader
+	if body != nil {
+		raw, marshalErr := json.Marshal(body)
+		if marshalErr != nil {
+			err = marshalErr
+			return
+		}
+		reqBody = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req) //nolint:gosec // admin API address given by operator
+	if err != nil {
+		err = fmt.Errorf("could not reach connector admin API, %w", err)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		err = fmt.Errorf("admin API error, %d (%s): %s", resp.StatusCode, http.StatusText(resp.StatusCode), strings.TrimSpace(string(respBody)))
+		return
+	}
+	if res == nil || len(respBody) == 0 {
+		return
+	}
+	err = json.Unmarshal(respBody, res)
+	return
+}
+
+func (c ctl) printJSON(ctx context.Context, path string, out io.Writer) (err error) {
+	var res json.RawMessage
+	if err = c.call(ctx, http.MethodGet, path, nil, &res); err != nil {
+		return
+	}
+	indented := bytes.Buffer{}
+	if err = json.Indent(&indented, res, "", "  "); err != nil {
+		return
+	}
+	_, err = fmt.Fprintln(out, indented.String())
+	return
+}
+
+func (c ctl) logs(ctx context.Context, args []string, out io.Writer) (err error) {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	lines := fs.Int("n", 50, "number of last logs to show")
+	follow := fs.Bool("f", false, "follow new logs")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	query := url.Values{"limit": {strconv.Itoa(*lines)}}
+	var after uint64
+	for {
+		var logs []events.BufferedLog
+		if err = c.call(ctx, http.MethodGet, "/admin/logs?"+query.Encode(), nil, &logs); err != nil {
+			return
+		}
+		for _, log := range logs {
+			if _, err = fmt.Fprintln(out, formatLog(log)); err != nil {
+				return
+			}
+			after = log.Seq
+		}
+		if !*follow {
+			return
+		}
+		query = url.Values{"after": {strconv.FormatUint(after, 10)}}
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-time.After(followInterval):
+		}
+	}
+}
+
+// formatLog formats log as "<RFC 3339 time> <LEVEL> <message> key=value...", keys sorted.
+func formatLog(log events.BufferedLog) string {
+	b := strings.Builder{}
+	b.WriteString(time.Unix(log.Time, 0).UTC().Format(time.RFC3339))
+	b.WriteString(" ")
+	b.WriteString(strings.ToUpper(log.Level))
+	b.WriteString(" ")
+	b.WriteString(log.Message)
+	keys := make([]string, 0, len(log.Attributes))
+	for k := range log.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value, err := json.Marshal(log.Attributes[k])
+		if err != nil {
+			value = []byte(fmt.Sprint(log.Attributes[k]))
+		}
+		fmt.Fprintf(&b, " %s=%s", k, value)
+	}
+	return b.String()
+}
+
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+func (c ctl) logLevel(ctx context.Context, args []string, out io.Writer) (err error) {
+	res := logLevel{}
+	switch len(args) {
+	case 0:
+		err = c.call(ctx, http.MethodGet, "/admin/log-level", nil, &res)
+	case 1:
+		err = c.call(ctx, http.MethodPut, "/admin/log-level", logLevel{Level: args[0]}, &res)
+	default:
+		err = errors.New("usage: connectorctl log-level [debug|info|warn|error]")
+	}
+	if err != nil {
+		return
+	}
+	_, err = fmt.Fprintln(out, res.Level)
+	return
+}
+
+var errQuarantineUsage = errors.New("usage: connectorctl quarantine [list | show <id> | export <id> [-o file]]")
+
+func (c ctl) quarantine(ctx context.Context, args []string, out io.Writer) (err error) {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		err = c.printJSON(ctx, "/admin/quarantine", out)
+	case args[0] == "show" && len(args) == 2:
+		err = c.printJSON(ctx, "/admin/quarantine/"+url.PathEscape(args[1]), out)
+	case args[0] == "export" && len(args) >= 2:
+		err = c.export(ctx, args[1], args[2:], out)
+	default:
+		err = errQuarantineUsage
+	}
+	return
+}
+
+// export writes quarantined item id archive to a new file, readable by its owner only, as it holds malware.
+func (c ctl) export(ctx context.Context, id string, args []string, out io.Writer) (err error) {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := fs.String("o", id+".tar", "output file")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/admin/quarantine/"+url.PathEscape(id)+"/export", nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	// archive may be large, it is only bounded by context
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+	resp, err := httpClient.Do(req) //nolint:gosec // admin API address given by operator
+	if err != nil {
+		err = fmt.Errorf("could not reach connector admin API, %w", err)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= http.StatusBadRequest {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		err = fmt.Errorf("admin API error, %d (%s): %s", resp.StatusCode, http.StatusText(resp.StatusCode), strings.TrimSpace(string(respBody)))
+		return
+	}
+	f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) //nolint:gosec // path given by operator
+	if err != nil {
+		return
+	}
+	if _, err = io.Copy(f, resp.Body); err != nil {
+		_ = f.Close()
+		_ = os.Remove(*output)
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(out, "%s exported to %s\n", id, *output)
+	return
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/quarantine"
+	"github.com/google/go-cmp/cmp"
+)
+
+type ctlConnector struct {
+	*quarantine.Store
+	restored []string
+	rescans  int
+}
+
+func (c *ctlConnector) Start(ctx context.Context) (err error) { return }
+func (c *ctlConnector) Stop(ctx context.Context) (err error)  { return }
+func (c *ctlConnector) Configure(ctx context.Context, config json.RawMessage) (err error) {
+	return
+}
+func (c *ctlConnector) Restore(ctx context.Context, restoreInfo sdk.RestoreActionContent) (err error) {
+	c.restored = append(c.restored, restoreInfo.ID)
+	return
+}
+func (c *ctlConnector) Status() (status sdk.ConnectorStatus) { return sdk.Started }
+func (c *ctlConnector) Rescan(ctx context.Context) (err error) {
+	c.rescans++
+	return
+}
+
+func Test_ctl_run(t *testing.T) {
+	level := sdk.LogLevel.Level()
+	defer sdk.LogLevel.Set(level)
+
+	manager := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer manager.Close()
+	client := sdk.NewConnectorManagerClient(t.Context(), sdk.ConnectorManagerClientConfig{URL: manager.URL, APIKey: "key"})
+	handler := client.NewConsoleEventHandler(slog.LevelInfo, nil)
+	logger := slog.New(handler.GetLogHandler())
+	logger.Info("scan started", slog.String("path", "/data"))
+	logger.Warn("file skipped")
+
+	store, err := quarantine.Open(quarantine.Config{Location: t.TempDir(), Password: "infected"})
+	if err != nil {
+		t.Fatalf("quarantine.Open() error = %v", err)
+	}
+	item, err := store.Add(strings.NewReader("eicar"), sdk.QuarantineItem{Name: "/data/eicar.com", Time: 1738000000})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	exported := filepath.Join(t.TempDir(), "eicar.tar")
+
+	connector := &ctlConnector{Store: store}
+	server := httptest.NewServer(client.AdminHandler(connector, "token", func() any { return &sdk.HostConfig{} }))
+	defer server.Close()
+	c := ctl{baseURL: server.URL, token: "token", httpClient: server.Client()}
+
+	tests := []struct {
+		name     string
+		command  string
+		args     []string
+		token    string
+		want     string
+		contains string
+		wantErr  bool
+	}{
+		{name: "status", command: "status", contains: `"status": "started"`},
+		{name: "quarantine", command: "quarantine", contains: `"name": "/data/eicar.com"`},
+		{name: "quarantine show", command: "quarantine", args: []string{"show", item.ID}, contains: `"sha256": "` + item.SHA256 + `"`},
+		{name: "quarantine show unknown", command: "quarantine", args: []string{"show", "unknown"}, wantErr: true},
+		{name: "quarantine export", command: "quarantine", args: []string{"export", item.ID, "-o", exported}, want: item.ID + " exported to " + exported + "\n"},
+		{name: "quarantine export existing", command: "quarantine", args: []string{"export", item.ID, "-o", exported}, wantErr: true},
+		{name: "quarantine invalid", command: "quarantine", args: []string{"purge"}, wantErr: true},
+		{name: "logs", command: "logs", args: []string{"-n", "1"}, contains: "WARN file skipped"},
+		{name: "rescan", command: "rescan", want: "rescan triggered\n"},
+		{name: "restore", command: "restore", args: []string{"q1"}, want: "q1 restored\n"},
+		{name: "restore without id", command: "restore", wantErr: true},
+		{name: "set log level", command: "log-level", args: []string{"debug"}, want: "debug\n"},
+		{name: "log level", command: "log-level", want: "debug\n"},
+		{name: "invalid log level", command: "log-level", args: []string{"verbose"}, wantErr: true},
+		{name: "invalid token", command: "status", token: "other", wantErr: true},
+		{name: "unknown", command: "purge", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := c
+			if tt.token != "" {
+				c.token = tt.token
+			}
+			out := bytes.Buffer{}
+			err := c.run(t.Context(), tt.command, tt.args, &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != "" && out.String() != tt.want {
+				t.Errorf("run() output = %q, want %q", out.String(), tt.want)
+			}
+			if !strings.Contains(out.String(), tt.contains) {
+				t.Errorf("run() output = %q, want it to contain %q", out.String(), tt.contains)
+			}
+		})
+	}
+	if diff := cmp.Diff(connector.restored, []string{"q1"}); diff != "" {
+		t.Errorf("run() restored diff(got-want)=%s", diff)
+	}
+	info, err := os.Stat(exported)
+	if err != nil || info.Mode().Perm() != 0o600 || info.Size() == 0 {
+		t.Errorf("run() exported archive = %v, error: %v, want owner only non empty file", info, err)
+	}
+	if connector.rescans != 1 {
+		t.Errorf("run() rescans = %d, want 1", connector.rescans)
+	}
+}
+
+func Test_formatLog(t *testing.T) {
+	log := events.BufferedLog{Seq: 1, LogEvent: events.LogEvent{
+		Level:      "info",
+		Message:    "scan started",
+		Time:       1738000000,
+		Attributes: map[string]any{"path": "/data", "files": 2},
+	}}
+	want := `2025-01-27T17:46:40Z INFO scan started files=2 path="/data"`
+	if got := formatLog(log); got != want {
+		t.Errorf("formatLog() = %q, want %q", got, want)
+	}
+}
+
+func Test_parseFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    ctlConfig
+		wantErr bool
+	}{
+		{
+			name: "ok",
+			args: []string{"-token", "t", "logs", "-f"},
+			want: ctlConfig{addr: defaultAddr, token: "t", command: "logs", args: []string{"-f"}},
+		},
+		{name: "no command", args: []string{"-token", "t"}, wantErr: true},
+		{name: "no token", args: []string{"status"}, wantErr: true},
+	}
+	t.Setenv("CONNECTORCTL_TOKEN", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFlags(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(got, tt.want, cmp.AllowUnexported(ctlConfig{})); diff != "" {
+				t.Errorf("parseFlags() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// event-replay replays events from an NDJSON spool file (see events.WriteNDJSON)
+// to a connector manager, to recover from prolonged console outages or to migrate
+// a connector to a new console instance.
+//
+//	EVENT_REPLAY_API_KEY=... event-replay -file spool.ndjson -url https://console.example.com -rate 20
+//
+// Replayed events are recorded in a marker file (default: <file>.replayed), so
+// a replay can be interrupted and restarted without sending events twice.
+// The tool registers as the connector first, for events to be sent with its connector ID and
+// negotiated schema version, and replays events keeping the creation time recorded in spool
+// (see events.WriteNDJSONAt), so manager discards the ones it already recorded.
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+type replayConfig struct {
+	file     string
+	marker   string
+	url      string
+	apiKey   string
+	insecure bool
+	version  string
+	rate     float64
+	dryRun   bool
+}
+
+type replayStats struct {
+	replayed int
+	skipped  int
+	failed   int
+}
+
+func main() {
+	cfg, err := parseFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	client := sdk.NewConnectorManagerClient(ctx, sdk.ConnectorManagerClientConfig{
+		URL:      cfg.url,
+		APIKey:   cfg.apiKey,
+		Insecure: cfg.insecure,
+	})
+	if !cfg.dryRun {
+		if err = client.Register(ctx, cfg.version, &sdk.RegistrationInfo{}); err != nil {
+			logger.Error("could not register connector", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+	stats, err := replay(ctx, cfg, client)
+	logger.Info("replay done", slog.Int("replayed", stats.replayed), slog.Int("skipped", stats.skipped), slog.Int("failed", stats.failed))
+	if err != nil {
+		logger.Error("replay interrupted", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
+
+func parseFlags(args []string) (cfg replayConfig, err error) {
+	fs := flag.NewFlagSet("event-replay", flag.ContinueOnError)
+	fs.StringVar(&cfg.file, "file", "", "NDJSON spool file to replay")
+	fs.StringVar(&cfg.marker, "marker", "", "file recording replayed events (default: <file>.replayed)")
+	fs.StringVar(&cfg.url, "url", "", "connector manager URL")
+	fs.StringVar(&cfg.apiKey, "api-key", os.Getenv("EVENT_REPLAY_API_KEY"), "connector API key (default: $EVENT_REPLAY_API_KEY)")
+	fs.BoolVar(&cfg.insecure, "insecure", false, "disable connector manager certificate check")
+	fs.StringVar(&cfg.version, "version", "", "version of the connector whose events are replayed, reported on registration")
+	fs.Float64Var(&cfg.rate, "rate", 10, "maximum number of events sent per second (0 = unlimited)")
+	fs.BoolVar(&cfg.dryRun, "dry-run", false, "only decode spool and report what would be replayed")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if cfg.file == "" {
+		err = errors.New("file is required")
+		return
+	}
+	if !cfg.dryRun && (cfg.url == "" || cfg.apiKey == "") {
+		err = errors.New("url and api key are required")
+		return
+	}
+	if cfg.marker == "" {
+		cfg.marker = cfg.file + ".replayed"
+	}
+	return
+}
+
+// eventKey identifies an event in marker file, it is also used as request id
+// so the manager can discard duplicates.
+func eventKey(event any) (key string, err error) {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	h := sha256.Sum256(raw)
+	key = hex.EncodeToString(h[:])
+	return
+}
+
+func readMarkers(path string) (markers map[string]struct{}, err error) {
+	markers = make(map[string]struct{})
+	f, err := os.Open(path) //nolint:gosec // path given by operator
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		err = nil
+		return
+	case err != nil:
+		return
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			markers[line] = struct{}{}
+		}
+	}
+	err = scanner.Err()
+	return
+}
+
+func replay(ctx context.Context, cfg replayConfig, notifier events.Notifier) (stats replayStats, err error) {
+	markers, err := readMarkers(cfg.marker)
+	if err != nil {
+		err = fmt.Errorf("could not read marker file, %w", err)
+		return
+	}
+	spool, err := os.Open(cfg.file)
+	if err != nil {
+		return
+	}
+	defer func() { _ = spool.Close() }()
+
+	var markerFile io.Writer = io.Discard
+	if !cfg.dryRun {
+		f, openErr := os.OpenFile(cfg.marker, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if openErr != nil {
+			err = fmt.Errorf("could not open marker file, %w", openErr)
+			return
+		}
+		defer func() { _ = f.Close() }()
+		markerFile = f
+	}
+
+	var tick <-chan time.Time
+	if cfg.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / cfg.rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	dec := events.NewNDJSONDecoder(spool)
+	for {
+		event, nextErr := dec.Next()
+		switch {
+		case errors.Is(nextErr, io.EOF):
+			return
+		case nextErr != nil:
+			logger.Warn("skip invalid spool line", slog.Int("line", dec.Line()), slog.String("error", nextErr.Error()))
+			stats.skipped++
+			continue
+		}
+		key, keyErr := eventKey(event)
+		if keyErr != nil {
+			err = keyErr
+			return
+		}
+		if _, done := markers[key]; done {
+			stats.skipped++
+			continue
+		}
+		if cfg.dryRun {
+			logger.Info("would replay event", slog.Int("line", dec.Line()), slog.String("type", fmt.Sprintf("%T", event)))
+			stats.replayed++
+			continue
+		}
+		if tick != nil {
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			case <-tick:
+			}
+		}
+		notifyCtx := context.WithValue(ctx, sdk.CtxRequestIDKey{}, key)
+		if created, ok := dec.Created(); ok {
+			notifyCtx = events.WithEventTime(notifyCtx, created)
+		}
+		notifyErr := notifier.Notify(notifyCtx, event)
+		switch {
+		case errors.Is(notifyErr, sdk.ErrUnauthorizedConnector), errors.Is(notifyErr, context.Canceled):
+			err = notifyErr
+			return
+		case notifyErr != nil:
+			logger.Error("could not replay event", slog.Int("line", dec.Line()), slog.String("error", notifyErr.Error()))
+			stats.failed++
+			continue
+		}
+		markers[key] = struct{}{}
+		if _, err = fmt.Fprintln(markerFile, key); err != nil {
+			return
+		}
+		stats.replayed++
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/sdktest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_replay(t *testing.T) {
+	dir := t.TempDir()
+	spoolPath := filepath.Join(dir, "spool.ndjson")
+	spool, err := os.Create(spoolPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = events.WriteNDJSON(spool,
+		events.TaskEvent{TaskID: "1"},
+		events.LogEvent{Level: "info", Message: "2", Time: 1},
+		events.ErrorEvent{Error: "3", Type: events.GMalwareError, Time: 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = spool.WriteString("not json\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err = spool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	cfg := replayConfig{file: spoolPath, marker: spoolPath + ".replayed"}
+
+	// first run: second event fails
+	notifier := sdktest.NewFlakyNotifier(nil, sdktest.FlakyOptions{
+		Script: []sdktest.Outcome{sdktest.OutcomeOK, sdktest.OutcomeError, sdktest.OutcomeOK},
+	})
+	stats, err := replay(t.Context(), cfg, notifier)
+	if err != nil {
+		t.Fatalf("replay() error = %v", err)
+	}
+	if stats != (replayStats{replayed: 2, failed: 1, skipped: 1}) {
+		t.Errorf("replay() first run stats = %+v", stats)
+	}
+
+	// second run: only failed event is replayed
+	notifier = sdktest.NewFlakyNotifier(nil, sdktest.FlakyOptions{})
+	stats, err = replay(t.Context(), cfg, notifier)
+	if err != nil {
+		t.Fatalf("replay() error = %v", err)
+	}
+	if stats != (replayStats{replayed: 1, skipped: 3}) {
+		t.Errorf("replay() second run stats = %+v", stats)
+	}
+	delivered := notifier.Delivered()
+	if len(delivered) != 1 || delivered[0].(events.LogEvent).Message != "2" {
+		t.Errorf("replay() second run delivered = %v, want log event 2", delivered)
+	}
+
+	// unauthorized stops replay
+	if err = os.Remove(cfg.marker); err != nil {
+		t.Fatal(err)
+	}
+	notifier = sdktest.NewFlakyNotifier(nil, sdktest.FlakyOptions{Script: []sdktest.Outcome{sdktest.OutcomeUnauthorized}})
+	if _, err = replay(t.Context(), cfg, notifier); err == nil {
+		t.Errorf("replay() error = nil, want unauthorized error")
+	}
+}
+
+type notifierFunc func(ctx context.Context, event any) error
+
+func (f notifierFunc) Notify(ctx context.Context, event any) error { return f(ctx, event) }
+
+func Test_replay_eventTime(t *testing.T) {
+	spoolPath := filepath.Join(t.TempDir(), "spool.ndjson")
+	created := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	buffer := bytes.NewBuffer(nil)
+	if err := events.WriteNDJSONAt(buffer, created, events.TaskEvent{TaskID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := events.WriteNDJSON(buffer, events.TaskEvent{TaskID: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(spoolPath, buffer.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var times []time.Time
+	notifier := notifierFunc(func(ctx context.Context, event any) error {
+		eventTime, _ := events.EventTimeFromContext(ctx)
+		times = append(times, eventTime)
+		return nil
+	})
+	if _, err := replay(t.Context(), replayConfig{file: spoolPath, marker: spoolPath + ".replayed"}, notifier); err != nil {
+		t.Fatalf("replay() error = %v", err)
+	}
+	if diff := cmp.Diff(times, []time.Time{created, {}}); diff != "" {
+		t.Errorf("replay() event times diff(got-want)=%s", diff)
+	}
+}
+
+func Test_parseFlags(t *testing.T) {
+	t.Setenv("EVENT_REPLAY_API_KEY", "key")
+	cfg, err := parseFlags([]string{"-file", "spool.ndjson", "-url", "https://console"})
+	if err != nil {
+		t.Fatalf("parseFlags() error = %v", err)
+	}
+	if cfg.apiKey != "key" || cfg.marker != "spool.ndjson.replayed" {
+		t.Errorf("parseFlags() = %+v", cfg)
+	}
+	if _, err = parseFlags([]string{"-file", "spool.ndjson"}); err == nil {
+		t.Errorf("parseFlags() without url error = nil")
+	}
+}
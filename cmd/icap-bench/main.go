@@ -0,0 +1,327 @@
+// icap-bench generates REQMOD/RESPMOD traffic against a deployed ICAP connector
+// and reports latency percentiles, to size deployments before go-live.
+//
+//	icap-bench -addr icap.local:1344 -service respmod -sizes 10KB,1MB,10MB -concurrency 8 -duration 1m
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http/httputil"
+	"net/textproto"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+type benchConfig struct {
+	addr        string
+	service     string
+	mode        string
+	sizes       []int64
+	concurrency int
+	requests    int
+	duration    time.Duration
+	timeout     time.Duration
+}
+
+type result struct {
+	size    int64
+	latency time.Duration
+	status  int
+	err     error
+}
+
+func main() {
+	cfg, err := parseFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	if cfg.duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, cfg.duration)
+		defer cancel()
+	}
+
+	start := time.Now()
+	results := run(ctx, cfg)
+	report(os.Stdout, results, time.Since(start))
+}
+
+func parseFlags(args []string) (cfg benchConfig, err error) {
+	fs := flag.NewFlagSet("icap-bench", flag.ContinueOnError)
+	sizes := fs.String("sizes", "10KB,1MB", "comma separated list of generated file sizes (e.g. 512B,10KB,5MB)")
+	fs.StringVar(&cfg.addr, "addr", "localhost:1344", "ICAP connector address (host:port)")
+	fs.StringVar(&cfg.service, "service", "", "ICAP service path (default: same as mode)")
+	fs.StringVar(&cfg.mode, "mode", "respmod", "ICAP mode: reqmod or respmod")
+	fs.IntVar(&cfg.concurrency, "concurrency", 4, "number of concurrent ICAP clients")
+	fs.IntVar(&cfg.requests, "requests", 100, "total number of requests (ignored if duration is set)")
+	fs.DurationVar(&cfg.duration, "duration", 0, "run for given duration instead of a fixed number of requests")
+	fs.DurationVar(&cfg.timeout, "timeout", time.Minute, "timeout of a single ICAP request")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	cfg.mode = strings.ToLower(cfg.mode)
+	if cfg.mode != "reqmod" && cfg.mode != "respmod" {
+		err = fmt.Errorf("invalid mode %q, must be reqmod or respmod", cfg.mode)
+		return
+	}
+	if cfg.service == "" {
+		cfg.service = cfg.mode
+	}
+	if cfg.concurrency < 1 {
+		err = errors.New("concurrency must be >= 1")
+		return
+	}
+	for s := range strings.SplitSeq(*sizes, ",") {
+		size, parseErr := parseSize(strings.TrimSpace(s))
+		if parseErr != nil {
+			err = parseErr
+			return
+		}
+		cfg.sizes = append(cfg.sizes, size)
+	}
+	return
+}
+
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+	{"B", 1},
+}
+
+func parseSize(s string) (size int64, err error) {
+	upper := strings.ToUpper(s)
+	factor := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper = strings.TrimSuffix(upper, u.suffix)
+			factor = u.factor
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(upper), 10, 64)
+	if err != nil || n < 0 {
+		err = fmt.Errorf("invalid size %q", s)
+		return
+	}
+	size = n * factor
+	return
+}
+
+func run(ctx context.Context, cfg benchConfig) (results []result) {
+	payloads := make(map[int64][]byte, len(cfg.sizes))
+	for _, size := range cfg.sizes {
+		payload := make([]byte, size)
+		_, _ = rand.Read(payload)
+		payloads[size] = payload
+	}
+
+	jobs := make(chan int64)
+	resultsC := make(chan result)
+	wg := sync.WaitGroup{}
+	for range cfg.concurrency {
+		wg.Go(func() {
+			for size := range jobs {
+				resultsC <- send(ctx, cfg, payloads[size])
+			}
+		})
+	}
+	go func() {
+		defer close(jobs)
+		for i := 0; cfg.duration > 0 || i < cfg.requests; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- cfg.sizes[i%len(cfg.sizes)]:
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(resultsC)
+	}()
+	for r := range resultsC {
+		// requests interrupted by the end of a timed run are not meaningful
+		if errors.Is(r.err, context.DeadlineExceeded) && ctx.Err() != nil {
+			continue
+		}
+		results = append(results, r)
+	}
+	return
+}
+
+func send(ctx context.Context, cfg benchConfig, payload []byte) (r result) {
+	r.size = int64(len(payload))
+	start := time.Now()
+	defer func() {
+		r.latency = time.Since(start)
+	}()
+	ctx, cancel := context.WithTimeout(ctx, cfg.timeout)
+	defer cancel()
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", cfg.addr)
+	if err != nil {
+		r.err = err
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	_, err = conn.Write(buildRequest(cfg, payload))
+	if err != nil {
+		r.err = err
+		return
+	}
+	r.status, r.err = readResponse(bufio.NewReader(conn))
+	return
+}
+
+func buildRequest(cfg benchConfig, payload []byte) []byte {
+	host, _, _ := net.SplitHostPort(cfg.addr)
+	var httpHeaders bytes.Buffer
+	var encapsulated string
+	switch cfg.mode {
+	case "reqmod":
+		fmt.Fprintf(&httpHeaders, "POST http://bench.local/upload HTTP/1.1\r\nHost: bench.local\r\nContent-Length: %d\r\n\r\n", len(payload))
+		encapsulated = fmt.Sprintf("req-hdr=0, req-body=%d", httpHeaders.Len())
+	default:
+		reqHdr := "GET http://bench.local/file.bin HTTP/1.1\r\nHost: bench.local\r\n\r\n"
+		httpHeaders.WriteString(reqHdr)
+		fmt.Fprintf(&httpHeaders, "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\nContent-Length: %d\r\n\r\n", len(payload))
+		encapsulated = fmt.Sprintf("req-hdr=0, res-hdr=%d, res-body=%d", len(reqHdr), httpHeaders.Len())
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s icap://%s/%s ICAP/1.0\r\n", strings.ToUpper(cfg.mode), cfg.addr, strings.TrimPrefix(cfg.service, "/"))
+	fmt.Fprintf(&b, "Host: %s\r\nAllow: 204\r\nEncapsulated: %s\r\n\r\n", host, encapsulated)
+	b.Write(httpHeaders.Bytes())
+	if len(payload) > 0 {
+		fmt.Fprintf(&b, "%x\r\n", len(payload))
+		b.Write(payload)
+		b.WriteString("\r\n")
+	}
+	b.WriteString("0\r\n\r\n")
+	return b.Bytes()
+}
+
+// readResponse reads a whole ICAP response, so a latency covers the full exchange.
+func readResponse(r *bufio.Reader) (status int, err error) {
+	tp := textproto.NewReader(r)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return
+	}
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "ICAP/") {
+		err = fmt.Errorf("invalid ICAP status line %q", line)
+		return
+	}
+	status, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return
+	}
+	headers, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return
+	}
+	if status >= 400 {
+		err = fmt.Errorf("ICAP error status %d", status)
+		return
+	}
+	bodyOffset, hasBody, err := parseEncapsulated(headers.Get("Encapsulated"))
+	if err != nil {
+		return
+	}
+	if _, err = io.CopyN(io.Discard, r, bodyOffset); err != nil {
+		return
+	}
+	if !hasBody {
+		return
+	}
+	_, err = io.Copy(io.Discard, httputil.NewChunkedReader(r))
+	return
+}
+
+func parseEncapsulated(header string) (bodyOffset int64, hasBody bool, err error) {
+	if header == "" {
+		return
+	}
+	for part := range strings.SplitSeq(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			err = fmt.Errorf("invalid Encapsulated header %q", header)
+			return
+		}
+		if !strings.HasSuffix(name, "-body") {
+			continue
+		}
+		bodyOffset, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return
+		}
+		hasBody = name != "null-body"
+	}
+	return
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p/100+0.5) - 1
+	i = max(0, min(i, len(sorted)-1))
+	return sorted[i]
+}
+
+func report(w io.Writer, results []result, elapsed time.Duration) {
+	bySize := make(map[int64][]time.Duration)
+	errorsBySize := make(map[int64]int)
+	statuses := make(map[int]int)
+	sizes := []int64{}
+	for _, r := range results {
+		if _, ok := bySize[r.size]; !ok {
+			sizes = append(sizes, r.size)
+			bySize[r.size] = []time.Duration{}
+		}
+		if r.err != nil {
+			errorsBySize[r.size]++
+			continue
+		}
+		statuses[r.status]++
+		bySize[r.size] = append(bySize[r.size], r.latency)
+	}
+	slices.Sort(sizes)
+
+	fmt.Fprintf(w, "%d requests in %s (%.1f req/s)\n", len(results), elapsed.Round(time.Millisecond), float64(len(results))/elapsed.Seconds())
+	for _, status := range slices.Sorted(maps.Keys(statuses)) {
+		fmt.Fprintf(w, "  ICAP %d: %d\n", status, statuses[status])
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "size\tok\terrors\tp50\tp90\tp95\tp99\tmax")
+	for _, size := range sizes {
+		latencies := bySize[size]
+		slices.Sort(latencies)
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n", size, len(latencies), errorsBySize[size],
+			percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 95), percentile(latencies, 99), percentile(latencies, 100))
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantSize int64
+		wantErr  bool
+	}{
+		{input: "512", wantSize: 512},
+		{input: "512B", wantSize: 512},
+		{input: "10KB", wantSize: 10000},
+		{input: "10kib", wantSize: 10240},
+		{input: "5MB", wantSize: 5000000},
+		{input: "-1KB", wantErr: true},
+		{input: "toto", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			gotSize, err := parseSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotSize != tt.wantSize {
+				t.Errorf("parseSize() = %d, want %d", gotSize, tt.wantSize)
+			}
+		})
+	}
+}
+
+func Test_percentile(t *testing.T) {
+	sorted := []time.Duration{}
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	for p, want := range map[float64]time.Duration{50: 50, 90: 90, 99: 99, 100: 100} {
+		if got := percentile(sorted, p); got != want {
+			t.Errorf("percentile(%v) = %v, want %v", p, got, want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile() on empty = %v, want 0", got)
+	}
+}
+
+func Test_send(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+	go func() {
+		for {
+			conn, acceptErr := l.Accept()
+			if acceptErr != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			for {
+				line, readErr := r.ReadString('\n')
+				if readErr != nil || line == "0\r\n" {
+					break
+				}
+			}
+			_, _ = conn.Write([]byte("ICAP/1.0 200 OK\r\nEncapsulated: res-hdr=0, res-body=19\r\n\r\nHTTP/1.1 403 NO\r\n\r\n5\r\nblock\r\n0\r\n\r\n"))
+			_ = conn.Close()
+		}
+	}()
+
+	for _, mode := range []string{"reqmod", "respmod"} {
+		cfg, err := parseFlags([]string{"-addr", l.Addr().String(), "-mode", mode, "-sizes", "1KB", "-timeout", "5s"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := send(t.Context(), cfg, []byte(strings.Repeat("a", 1000)))
+		if r.err != nil {
+			t.Fatalf("send() error = %v", r.err)
+		}
+		if r.status != 200 {
+			t.Errorf("send() status = %d, want 200", r.status)
+		}
+	}
+}
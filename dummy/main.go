@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/bootstrap"
 	"github.com/glimps-re/connector-integration/sdk/events"
 	"github.com/glimps-re/connector-integration/sdk/metrics"
 	"github.com/glimps-re/go-gdetect/pkg/gdetect"
@@ -28,19 +29,16 @@ var (
 
 func main() {
 	sdk.LogLevel.Set(slog.LevelDebug)
-	consoleURL := getEnvVariableOrPanic("DUMMY_CONSOLE_URL", "console url")
-	consoleAPIKey := getEnvVariableOrPanic("DUMMY_CONSOLE_API_KEY", "console apikey")
-	gMalwareApiUrl := getEnvVariableOrPanic("GMALWARE_API_URL", "gmalware api url")
-	gMalwareApiToken := getEnvVariableOrPanic("GMALWARE_API_TOKEN", "gmalware api token")
-	consoleInsecure, err := strconv.ParseBool(os.Getenv("DUMMY_CONSOLE_INSECURE"))
+	managerConfig, err := bootstrap.LoadManagerConfig(bootstrap.Options{
+		EnvPrefix: "DUMMY",
+		Defaults:  sdk.ConnectorManagerClientConfig{ConnectorType: sdk.DummyKey},
+	})
 	if err != nil {
-		consoleInsecure = false
+		panic(err)
 	}
-	c := sdk.NewConnectorManagerClient(context.Background(), sdk.ConnectorManagerClientConfig{
-		URL:      consoleURL,
-		APIKey:   consoleAPIKey,
-		Insecure: consoleInsecure,
-	})
+	gMalwareApiUrl := getEnvVariableOrPanic("GMALWARE_API_URL", "gmalware api url")
+	gMalwareApiToken := getEnvVariableOrPanic("GMALWARE_API_TOKEN", "gmalware api token")
+	c := sdk.NewConnectorManagerClient(context.Background(), managerConfig)
 	config := &sdk.DummyConfig{
 		ReconfigurableDummyConfig: sdk.ReconfigurableDummyConfig{
 			CommonConnectorConfig: sdk.CommonConnectorConfig{
@@ -71,6 +69,9 @@ func main() {
 	}
 	dummy.metricCollecter = c.NewMetricCollecter(detectClient)
 	consoleLogger = slog.New(dummy.eventHandler.GetLogHandler())
+	if err = c.ServeHealth(context.Background(), sdk.HealthOptions{}, func() sdk.Connector { return dummy }); err != nil {
+		panic(err)
+	}
 	dummy.Launch(context.Background())
 
 	c.Start(context.Background(), dummy)
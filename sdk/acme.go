@@ -0,0 +1,14 @@
+package sdk
+
+// ACMEConfig configures the certificate of connector webhook endpoints obtained from an ACME certificate authority
+// (e.g. Let's Encrypt), for deployments without a PKI (see webhook.ServeACME).
+type ACMEConfig struct {
+	Enabled      bool     `json:"enabled" yaml:"enabled" mapstructure:"enabled" desc:"Obtain webhook endpoint certificate from an ACME certificate authority (e.g. Let's Encrypt)"`
+	Domains      []string `json:"domains" yaml:"domains" mapstructure:"domains" validate:"dive,fqdn" desc:"Domain names of webhook endpoint, required when enabled. Certificates are only requested for them" default:"[]"`
+	Email        string   `json:"email" yaml:"email" mapstructure:"email" validate:"omitempty,email" desc:"Optional contact email of ACME account, notified of certificate problems"`
+	DirectoryURL string   `json:"directory_url" yaml:"directory_url" mapstructure:"directory_url" validate:"omitempty,url" desc:"Optional ACME directory URL (e.g. internal ACME server), Let's Encrypt if empty"`
+	Challenge    string   `json:"challenge" yaml:"challenge" mapstructure:"challenge" validate:"omitempty,oneof=tls-alpn-01 http-01" desc:"ACME challenge: tls-alpn-01 (answered on webhook endpoint, which must be reachable on port 443) or http-01 (answered on port 80) (default: tls-alpn-01)"`
+	HTTPAddress  string   `json:"http_address" yaml:"http_address" mapstructure:"http_address" desc:"Listen address of http-01 challenges (default: :80)"`
+	CacheDir     string   `json:"cache_dir" yaml:"cache_dir" mapstructure:"cache_dir" validate:"required_if=Enabled true" desc:"Directory keeping ACME account key and certificates across restarts, required when enabled"`
+	AcceptTOS    bool     `json:"accept_tos" yaml:"accept_tos" mapstructure:"accept_tos" validate:"required_if=Enabled true" desc:"Accept terms of service of ACME certificate authority, required when enabled"`
+}
@@ -0,0 +1,347 @@
+package sdk
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+const DefaultAdminPort = 6061
+
+var (
+	ErrMissingAdminToken      = errors.New("admin api token or token file is required")
+	ErrQuarantineItemNotFound = errors.New("quarantined item not found")
+)
+
+type AdminOptions struct {
+	// Enabled starts admin api server on localhost (see ConnectorManagerClient.AdminHandler).
+	Enabled bool
+	// Port admin api listens to on localhost, DefaultAdminPort if 0.
+	Port int
+	// Token authenticates admin api requests (Authorization: Bearer <token>).
+	Token string
+	// TokenFile, used when Token is empty, receives a token generated on start, readable by its owner only.
+	TokenFile string
+}
+
+// Rescanner may be implemented by connectors able to scan again their whole scope on demand (e.g. monitored paths).
+type Rescanner interface {
+	Rescan(ctx context.Context) (err error)
+}
+
+// EventFlusher may be implemented by connectors buffering events, to push them to manager on demand.
+type EventFlusher interface {
+	FlushEvents(ctx context.Context) (err error)
+}
+
+// QuarantineItem is a file kept in connector quarantine.
+type QuarantineItem struct {
+	ID       string   `json:"id" desc:"id to restore item with"`
+	Name     string   `json:"name" desc:"original file name or path"`
+	SHA256   string   `json:"sha256"`
+	Size     int64    `json:"size"`
+	Malwares []string `json:"malwares,omitempty"`
+	Time     int64    `json:"time" desc:"unix timestamp of quarantine"`
+}
+
+// QuarantineLister may be implemented by connectors keeping a local quarantine, to list it on demand.
+type QuarantineLister interface {
+	ListQuarantine(ctx context.Context) (items []QuarantineItem, err error)
+}
+
+// QuarantineBrowser may be implemented by connectors keeping a local quarantine, to inspect and export its items
+// for incident response (see quarantine.Store). Unknown ids must return ErrQuarantineItemNotFound.
+type QuarantineBrowser interface {
+	QuarantineLister
+	InspectQuarantine(ctx context.Context, id string) (item QuarantineItem, err error)
+	// ExportQuarantine writes a tar archive of item metadata and content to w.
+	ExportQuarantine(ctx context.Context, id string, w io.Writer) (err error)
+}
+
+type adminLogLevel struct {
+	Level string `json:"level"`
+}
+
+type adminStatus struct {
+	Status           string                           `json:"status"`
+	Version          string                           `json:"version"`
+	ConnectorID      string                           `json:"connector_id,omitempty"`
+	ConfigHash       string                           `json:"config_hash,omitempty"`
+	EventsPending    int64                            `json:"events_pending"`
+	TasksQueued      int64                            `json:"tasks_queued"`
+	UnresolvedErrors map[events.ErrorEventType]string `json:"unresolved_errors"`
+}
+
+// AdminHandler serves, under /admin/, to on-host operators when console is unreachable:
+//   - GET status: connector status, version, config hash, event queue stats and unresolved errors
+//   - GET metrics: manager communication metrics (see MetricsHandler)
+//   - GET config: config returned by config func, with secrets stripped
+//   - POST rescan: triggers a rescan, connector must be a Rescanner
+//   - POST events/flush: pushes pending metrics counters, buffered events of EventFlusher connectors, and replays
+//     spooled events
+//   - GET logs: last logs of console event handler (see events.LogBuffer), "after" and "limit" query parameters
+//     selecting logs with a greater seq, and the number of last logs returned
+//   - GET, PUT log-level: get or set LogLevel, as {"level": "debug"}
+//   - POST restore: restores an item (RestoreActionContent), as a restore task would, notifying console of it
+//   - GET quarantine: lists quarantined items, connector must be a QuarantineLister
+//   - GET quarantine/{id}: quarantined item metadata, connector must be a QuarantineBrowser
+//   - GET quarantine/{id}/export: tar archive of quarantined item metadata and content, connector must be a
+//     QuarantineBrowser
+//
+// Requests must be authenticated with token (Authorization: Bearer <token>).
+// It must only be served locally (see RunOptions.Admin).
+func (c ConnectorManagerClient) AdminHandler(connector Connector, token string, config func() any) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/status", func(w http.ResponseWriter, r *http.Request) {
+		stats := c.eventQueueStats()
+		status := adminStatus{
+			Status:           connector.Status().String(),
+			ConfigHash:       c.currentConfigHash(),
+			EventsPending:    stats.EventsPending,
+			TasksQueued:      stats.TasksQueued,
+			UnresolvedErrors: stats.UnresolvedErrors,
+		}
+		if v := c.version.Load(); v != nil {
+			status.Version = *v
+		}
+		if id := c.connectorID.Load(); id != nil {
+			status.ConnectorID = *id
+		}
+		writeDebugJSON(w, status)
+	})
+	mux.Handle("GET /admin/metrics", c.MetricsHandler())
+	mux.HandleFunc("GET /admin/config", func(w http.ResponseWriter, r *http.Request) {
+		stripped, err := stripSecrets(config())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeDebugJSON(w, stripped)
+	})
+	mux.HandleFunc("POST /admin/rescan", func(w http.ResponseWriter, r *http.Request) {
+		rescanner, ok := connector.(Rescanner)
+		if !ok {
+			http.Error(w, "connector does not support rescan", http.StatusNotImplemented)
+			return
+		}
+		if err := rescanner.Rescan(r.Context()); err != nil {
+			http.Error(w, fmt.Sprintf("could not trigger rescan, %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("POST /admin/events/flush", func(w http.ResponseWriter, r *http.Request) {
+		if err := c.flush(r.Context(), connector); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /admin/logs", func(w http.ResponseWriter, r *http.Request) {
+		h := c.handler.Load()
+		if h == nil || h.LogBuffer() == nil {
+			http.Error(w, "logs are not buffered", http.StatusNotImplemented)
+			return
+		}
+		after, limit, err := logsQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeDebugJSON(w, h.LogBuffer().Since(after, limit))
+	})
+	mux.HandleFunc("GET /admin/log-level", func(w http.ResponseWriter, r *http.Request) {
+		writeDebugJSON(w, adminLogLevel{Level: strings.ToLower(LogLevel.Level().String())})
+	})
+	mux.HandleFunc("PUT /admin/log-level", func(w http.ResponseWriter, r *http.Request) {
+		req := adminLogLevel{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid log level request, %v", err), http.StatusBadRequest)
+			return
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		LogLevel.Set(level)
+		logger.Info("log level set from admin api", slog.String("level", level.String()))
+		writeDebugJSON(w, adminLogLevel{Level: strings.ToLower(level.String())})
+	})
+	mux.HandleFunc("POST /admin/restore", func(w http.ResponseWriter, r *http.Request) {
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not read restore request, %v", err), http.StatusBadRequest)
+			return
+		}
+		id, err := restore(r.Context(), connector, content)
+		if errors.Is(err, ErrInvalidRestore) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.notifyAdminRestore(r.Context(), id, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /admin/quarantine", func(w http.ResponseWriter, r *http.Request) {
+		lister, ok := connector.(QuarantineLister)
+		if !ok {
+			http.Error(w, "connector does not list its quarantine", http.StatusNotImplemented)
+			return
+		}
+		items, err := lister.ListQuarantine(r.Context())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not list quarantine, %v", err), http.StatusInternalServerError)
+			return
+		}
+		writeDebugJSON(w, items)
+	})
+	mux.HandleFunc("GET /admin/quarantine/{id}", func(w http.ResponseWriter, r *http.Request) {
+		item, ok := inspectQuarantine(w, r, connector)
+		if !ok {
+			return
+		}
+		writeDebugJSON(w, item)
+	})
+	mux.HandleFunc("GET /admin/quarantine/{id}/export", func(w http.ResponseWriter, r *http.Request) {
+		item, ok := inspectQuarantine(w, r, connector)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", item.ID+".tar"))
+		// status is already sent once archive is written, errors are only logged
+		if err := connector.(QuarantineBrowser).ExportQuarantine(r.Context(), item.ID, w); err != nil {
+			logger.Error("could not export quarantined item", slog.String("id", item.ID), slog.String("error", err.Error()))
+			return
+		}
+		logger.Info("quarantined item exported from admin api", slog.String("id", item.ID), slog.String("sha256", item.SHA256))
+	})
+	return adminAuth(token, mux)
+}
+
+// inspectQuarantine returns quarantined item of request id, writing error response if it could not.
+func inspectQuarantine(w http.ResponseWriter, r *http.Request, connector Connector) (item QuarantineItem, ok bool) {
+	browser, ok := connector.(QuarantineBrowser)
+	if !ok {
+		http.Error(w, "connector does not support quarantine browsing", http.StatusNotImplemented)
+		return
+	}
+	item, err := browser.InspectQuarantine(r.Context(), r.PathValue("id"))
+	switch {
+	case errors.Is(err, ErrQuarantineItemNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		ok = false
+	case err != nil:
+		http.Error(w, fmt.Sprintf("could not inspect quarantined item, %v", err), http.StatusInternalServerError)
+		ok = false
+	}
+	return
+}
+
+func logsQuery(r *http.Request) (after uint64, limit int, err error) {
+	query := r.URL.Query()
+	if v := query.Get("after"); v != "" {
+		if after, err = strconv.ParseUint(v, 10, 64); err != nil {
+			err = fmt.Errorf("invalid after parameter, %w", err)
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			err = fmt.Errorf("invalid limit parameter, %w", err)
+			return
+		}
+	}
+	return
+}
+
+func adminAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "invalid admin token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// flush pushes pending metrics counters (restored on failure), connector buffered events, then replays spooled
+// events (see SetEventSpool).
+func (c ConnectorManagerClient) flush(ctx context.Context, connector Connector) (err error) {
+	pending := c.metricsCollector.GetAndReset()
+	if err = c.pushMetrics(ctx, pending); err != nil {
+		c.metricsCollector.RestoreCounterMetrics(pending)
+		err = fmt.Errorf("could not push metrics, %w", err)
+		return
+	}
+	if flusher, ok := connector.(EventFlusher); ok {
+		if err = flusher.FlushEvents(ctx); err != nil {
+			err = fmt.Errorf("could not flush events, %w", err)
+			return
+		}
+	}
+	if spool := c.spool.Load(); spool != nil {
+		if _, err = spool.Replay(ctx); err != nil {
+			err = fmt.Errorf("could not replay spooled events, %w", err)
+			return
+		}
+	}
+	return
+}
+
+// adminToken returns opts token, generating it in TokenFile if not set.
+func adminToken(opts AdminOptions) (token string, err error) {
+	switch {
+	case opts.Token != "":
+		token = opts.Token
+		return
+	case opts.TokenFile == "":
+		err = ErrMissingAdminToken
+		return
+	}
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+	token = hex.EncodeToString(b)
+	// removed first, so an existing file with a larger mode is not reused
+	if err = os.Remove(opts.TokenFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		err = fmt.Errorf("could not write admin token file, %w", err)
+		return
+	}
+	if err = os.WriteFile(opts.TokenFile, []byte(token+"\n"), 0o600); err != nil {
+		err = fmt.Errorf("could not write admin token file, %w", err)
+	}
+	return
+}
+
+// serveAdmin serves AdminHandler on localhost until ctx is done.
+func (c ConnectorManagerClient) serveAdmin(ctx context.Context, opts AdminOptions, connector Connector, config func() any) (err error) {
+	if opts.Port == 0 {
+		opts.Port = DefaultAdminPort
+	}
+	token, err := adminToken(opts)
+	if err != nil {
+		return
+	}
+	err = serveLocal(ctx, "admin", opts.Port, c.AdminHandler(connector, token, config))
+	return
+}
@@ -0,0 +1,372 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+type adminConnector struct {
+	fakeConnector
+	rescans int
+	flushes int
+}
+
+func (c *adminConnector) Rescan(ctx context.Context) (err error) {
+	c.rescans++
+	return
+}
+
+func (c *adminConnector) FlushEvents(ctx context.Context) (err error) {
+	c.flushes++
+	return
+}
+
+func TestConnectorManagerClient_AdminHandler(t *testing.T) {
+	metricsStatus := http.StatusOK
+	var managerRequests []string
+	manager := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		managerRequests = append(managerRequests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(metricsStatus)
+	}))
+	defer manager.Close()
+
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: manager.URL, APIKey: "key"})
+	version := "1.2.0"
+	c.version.Store(&version)
+	c.storeConnectorID("connector-1")
+	connector := &adminConnector{}
+	config := &HostConfig{Quarantine: HostQuarantineConfig{Location: "/var/lib/gmhost", Password: "secret"}}
+	handler := c.AdminHandler(connector, "admin-token", func() any { return config })
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		token         string
+		metricsStatus int
+		wantStatus    int
+		wantBody      map[string]any
+		wantRequests  []string
+	}{
+		{name: "no token", method: http.MethodGet, path: "/admin/status", wantStatus: http.StatusUnauthorized},
+		{name: "invalid token", method: http.MethodGet, path: "/admin/status", token: "other", wantStatus: http.StatusUnauthorized},
+		{
+			name:       "status",
+			method:     http.MethodGet,
+			path:       "/admin/status",
+			token:      "admin-token",
+			wantStatus: http.StatusOK,
+			wantBody: map[string]any{
+				"status":            "started",
+				"version":           "1.2.0",
+				"connector_id":      "connector-1",
+				"events_pending":    float64(0),
+				"tasks_queued":      float64(0),
+				"unresolved_errors": nil,
+			},
+		},
+		{name: "metrics", method: http.MethodGet, path: "/admin/metrics", token: "admin-token", wantStatus: http.StatusOK},
+		{name: "config", method: http.MethodGet, path: "/admin/config", token: "admin-token", wantStatus: http.StatusOK},
+		{name: "rescan", method: http.MethodPost, path: "/admin/rescan", token: "admin-token", wantStatus: http.StatusAccepted},
+		{name: "rescan get", method: http.MethodGet, path: "/admin/rescan", token: "admin-token", wantStatus: http.StatusMethodNotAllowed},
+		{
+			name:         "flush",
+			method:       http.MethodPost,
+			path:         "/admin/events/flush",
+			token:        "admin-token",
+			wantStatus:   http.StatusNoContent,
+			wantRequests: []string{"POST /api/v1/connectors/metrics"},
+		},
+		{
+			name:          "flush unreachable manager",
+			method:        http.MethodPost,
+			path:          "/admin/events/flush",
+			token:         "admin-token",
+			metricsStatus: http.StatusInternalServerError,
+			wantStatus:    http.StatusBadGateway,
+			wantRequests:  []string{"POST /api/v1/connectors/metrics"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			managerRequests = nil
+			metricsStatus = http.StatusOK
+			if tt.metricsStatus != 0 {
+				metricsStatus = tt.metricsStatus
+			}
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("%s %s status = %d, want %d", tt.method, tt.path, rec.Code, tt.wantStatus)
+			}
+			if diff := cmp.Diff(managerRequests, tt.wantRequests); diff != "" {
+				t.Errorf("%s %s manager requests diff(got-want)=%s", tt.method, tt.path, diff)
+			}
+			if tt.wantBody == nil {
+				return
+			}
+			var got map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("%s %s invalid body, error: %v", tt.method, tt.path, err)
+			}
+			if diff := cmp.Diff(got, tt.wantBody); diff != "" {
+				t.Errorf("%s %s body diff(got-want)=%s", tt.method, tt.path, diff)
+			}
+		})
+	}
+	if connector.rescans != 1 || connector.flushes != 1 {
+		t.Errorf("AdminHandler() rescans = %d, flushes = %d, want 1 and 1", connector.rescans, connector.flushes)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/admin/rescan", nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	c.AdminHandler(&fakeConnector{}, "admin-token", func() any { return config }).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("POST /admin/rescan status = %d, want %d", rec.Code, http.StatusNotImplemented)
+	}
+}
+
+func TestConnectorManagerClient_AdminHandler_flushSpool(t *testing.T) {
+	var down atomic.Bool
+	var eventRequests atomic.Int32
+	manager := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == basePath+"/events" {
+			eventRequests.Add(1)
+		}
+	}))
+	defer manager.Close()
+
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: manager.URL, APIKey: "key", RetryMaxRetries: 1, RetryInitialInterval: time.Millisecond})
+	spool, err := events.NewSpool(c, events.SpoolOptions{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewSpool() error = %v", err)
+	}
+	c.SetEventSpool(spool)
+	down.Store(true)
+	if err = spool.Notify(t.Context(), events.LogEvent{Level: "info", Message: "spooled", Time: time.Now().Unix()}); err != nil {
+		t.Fatalf("Spool.Notify() error = %v", err)
+	}
+	if spool.Len() != 1 {
+		t.Fatalf("Spool.Len() = %d, want 1", spool.Len())
+	}
+
+	down.Store(false)
+	req := httptest.NewRequest(http.MethodPost, "/admin/events/flush", nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	rec := httptest.NewRecorder()
+	c.AdminHandler(&fakeConnector{}, "admin-token", func() any { return &HostConfig{} }).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("POST /admin/events/flush status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if spool.Len() != 0 || eventRequests.Load() != 1 {
+		t.Errorf("POST /admin/events/flush spooled = %d, events sent = %d, want 0 and 1", spool.Len(), eventRequests.Load())
+	}
+}
+
+type restoreConnector struct {
+	fakeConnector
+	restored []string
+}
+
+func (c *restoreConnector) Restore(ctx context.Context, restoreInfo RestoreActionContent) (err error) {
+	if restoreInfo.ID == "unknown" {
+		err = errors.New("not in quarantine")
+		return
+	}
+	c.restored = append(c.restored, restoreInfo.ID)
+	return
+}
+
+func TestConnectorManagerClient_AdminHandler_restore(t *testing.T) {
+	var logs []events.LogEvent
+	manager := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		envelope := events.Envelope{}
+		if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+			t.Errorf("invalid event, error: %v", err)
+		}
+		log := events.LogEvent{}
+		if err := json.Unmarshal(envelope.Event, &log); err != nil {
+			t.Errorf("invalid log event, error: %v", err)
+		}
+		log.Time = 0
+		logs = append(logs, log)
+	}))
+	defer manager.Close()
+
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: manager.URL, APIKey: "key"})
+	connector := &restoreConnector{}
+	handler := c.AdminHandler(connector, "admin-token", func() any { return &HostConfig{} })
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantLogs   []events.LogEvent
+	}{
+		{name: "no id", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid body", body: `{"id":`, wantStatus: http.StatusBadRequest},
+		{
+			name:       "restored",
+			body:       `{"id":"f1c1e4a2"}`,
+			wantStatus: http.StatusNoContent,
+			wantLogs:   []events.LogEvent{{Level: "info", Message: "element restored from admin api", Attributes: map[string]any{"id": "f1c1e4a2"}}},
+		},
+		{
+			name:       "restore error",
+			body:       `{"id":"unknown"}`,
+			wantStatus: http.StatusInternalServerError,
+			wantLogs: []events.LogEvent{{
+				Level:      "error",
+				Message:    "could not restore element from admin api",
+				Attributes: map[string]any{"id": "unknown", "error": "error restoring element unknown, error: not in quarantine"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs = nil
+			req := httptest.NewRequest(http.MethodPost, "/admin/restore", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer admin-token")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("POST /admin/restore status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if diff := cmp.Diff(logs, tt.wantLogs); diff != "" {
+				t.Errorf("POST /admin/restore notified logs diff(got-want)=%s", diff)
+			}
+		})
+	}
+	if diff := cmp.Diff(connector.restored, []string{"f1c1e4a2"}); diff != "" {
+		t.Errorf("POST /admin/restore restored diff(got-want)=%s", diff)
+	}
+}
+
+func TestAdminToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "admin.token")
+	if err := os.WriteFile(tokenFile, []byte("previous"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		opts    AdminOptions
+		want    string
+		wantErr bool
+	}{
+		{name: "token", opts: AdminOptions{Token: "token", TokenFile: tokenFile}, want: "token"},
+		{name: "generated", opts: AdminOptions{TokenFile: tokenFile}},
+		{name: "missing", opts: AdminOptions{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adminToken(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("adminToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != "" && got != tt.want {
+				t.Errorf("adminToken() = %s, want %s", got, tt.want)
+			}
+			if tt.wantErr || tt.opts.Token != "" {
+				return
+			}
+			written, err := os.ReadFile(tokenFile)
+			if err != nil {
+				t.Fatalf("could not read token file, error: %v", err)
+			}
+			if string(written) != got+"\n" || len(got) != 64 {
+				t.Errorf("adminToken() token file = %q, token %q", written, got)
+			}
+			info, err := os.Stat(tokenFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0o600 {
+				t.Errorf("adminToken() token file mode = %v, want 0600", info.Mode().Perm())
+			}
+		})
+	}
+}
+
+type quarantineConnector struct {
+	fakeConnector
+	items map[string]QuarantineItem
+}
+
+func (c *quarantineConnector) ListQuarantine(ctx context.Context) (items []QuarantineItem, err error) {
+	for _, item := range c.items {
+		items = append(items, item)
+	}
+	return
+}
+
+func (c *quarantineConnector) InspectQuarantine(ctx context.Context, id string) (item QuarantineItem, err error) {
+	item, ok := c.items[id]
+	if !ok {
+		err = ErrQuarantineItemNotFound
+	}
+	return
+}
+
+func (c *quarantineConnector) ExportQuarantine(ctx context.Context, id string, w io.Writer) (err error) {
+	_, err = io.WriteString(w, "archive of "+id)
+	return
+}
+
+func TestConnectorManagerClient_AdminHandler_quarantine(t *testing.T) {
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: "http://localhost", APIKey: "key"})
+	item := QuarantineItem{ID: "q1", Name: "/data/eicar.com", SHA256: strings.Repeat("a", 64), Size: 68, Time: 1738000000}
+	browser := &quarantineConnector{items: map[string]QuarantineItem{"q1": item}}
+
+	tests := []struct {
+		name       string
+		connector  Connector
+		path       string
+		wantStatus int
+		wantBody   string
+		wantHeader string
+	}{
+		{name: "inspect", connector: browser, path: "/admin/quarantine/q1", wantStatus: http.StatusOK, wantBody: `"name": "/data/eicar.com"`},
+		{name: "inspect unknown", connector: browser, path: "/admin/quarantine/q2", wantStatus: http.StatusNotFound},
+		{name: "export", connector: browser, path: "/admin/quarantine/q1/export", wantStatus: http.StatusOK, wantBody: "archive of q1", wantHeader: `attachment; filename="q1.tar"`},
+		{name: "export unknown", connector: browser, path: "/admin/quarantine/q2/export", wantStatus: http.StatusNotFound},
+		{name: "not supported", connector: &fakeConnector{}, path: "/admin/quarantine/q1", wantStatus: http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer admin-token")
+			rec := httptest.NewRecorder()
+			c.AdminHandler(tt.connector, "admin-token", func() any { return nil }).ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("GET %s status = %d, want %d", tt.path, rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("GET %s body = %q, want it to contain %q", tt.path, rec.Body.String(), tt.wantBody)
+			}
+			if got := rec.Header().Get("Content-Disposition"); got != tt.wantHeader {
+				t.Errorf("GET %s Content-Disposition = %q, want %q", tt.path, got, tt.wantHeader)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/glimps-re/connector-integration/sdk/state"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+)
+
+// budgetKey is the state key daily budget consumption is persisted under.
+const budgetKey = "analysis-budget"
+
+// ErrBudgetExceeded is returned by submissions once connector daily budget is consumed, until next day (UTC).
+var ErrBudgetExceeded = errors.New("daily submission budget exceeded")
+
+type BudgetOptions struct {
+	// Daily is the number of submissions allowed per day (e.g. CommonConnectorConfig.GMalwareDailyBudget), no limit
+	// if 0. Days are UTC ones, as GLIMPS Malware daily quota.
+	Daily int
+	// Store, if set, persists consumption of the day, so it is not reset by connector restarts.
+	Store state.Store
+	// Metrics, if set, records submissions rejected by budget (e.g. ConnectorManagerClient.AnalysisMetrics).
+	Metrics *metrics.AnalysisMetrics
+}
+
+var (
+	_ gdetect.GDetectSubmitter = &BudgetClient{}
+	_ ExtendedSubmitterGetter  = &BudgetClient{}
+)
+
+// BudgetClient is a GDetectSubmitter enforcing a per-connector daily budget of submissions, so a runaway connector
+// (e.g. a host scan of a whole disk) can not consume the whole organization daily quota. Submissions failing before
+// reaching GLIMPS Malware are not counted. It is safe for concurrent use.
+type BudgetClient struct {
+	gdetect.GDetectSubmitter
+	opts BudgetOptions
+	now  func() time.Time
+
+	lock  sync.Mutex
+	usage budgetUsage
+}
+
+type budgetUsage struct {
+	Day  string `json:"day"`
+	Used int    `json:"used"`
+}
+
+// NewBudgetClient returns a BudgetClient submitting to submitter, resuming consumption of the day persisted in
+// opts.Store.
+func NewBudgetClient(submitter gdetect.GDetectSubmitter, opts BudgetOptions) (c *BudgetClient) {
+	if opts.Metrics == nil {
+		opts.Metrics = &metrics.AnalysisMetrics{}
+	}
+	c = &BudgetClient{GDetectSubmitter: submitter, opts: opts, now: time.Now}
+	if opts.Store == nil {
+		return
+	}
+	raw, err := opts.Store.Get(budgetKey)
+	switch {
+	case errors.Is(err, state.ErrNotFound):
+	case err != nil:
+		logger.Warn("could not load daily budget consumption", slog.String("error", err.Error()))
+	default:
+		if err = json.Unmarshal(raw, &c.usage); err != nil {
+			logger.Warn("invalid daily budget consumption", slog.String("error", err.Error()))
+		}
+	}
+	return
+}
+
+// Remaining returns submissions left in budget today, -1 without budget.
+func (c *BudgetClient) Remaining() (remaining int) {
+	if c.opts.Daily <= 0 {
+		remaining = -1
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.rollover()
+	remaining = max(c.opts.Daily-c.usage.Used, 0)
+	return
+}
+
+// ResetAt returns when budget is reset: next midnight UTC.
+func (c *BudgetClient) ResetAt() time.Time {
+	now := c.now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+// rollover resets consumption on a new day, c.lock must be held.
+func (c *BudgetClient) rollover() {
+	if day := c.now().UTC().Format(time.DateOnly); c.usage.Day != day {
+		c.usage = budgetUsage{Day: day}
+	}
+}
+
+// reserve counts a submission in budget of day, failing with ErrBudgetExceeded once it is consumed.
+func (c *BudgetClient) reserve() (day string, err error) {
+	if c.opts.Daily <= 0 {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.rollover()
+	if c.usage.Used >= c.opts.Daily {
+		c.opts.Metrics.AddBudgetRejected()
+		err = ErrBudgetExceeded
+		return
+	}
+	c.usage.Used++
+	day = c.usage.Day
+	c.persist()
+	return
+}
+
+// release uncounts a submission reserved on day if it provably did not reach GLIMPS Malware: it failed without
+// returning a UUID, and not on a wait timeout or a cancellation, which may happen once file is uploaded.
+func (c *BudgetClient) release(ctx context.Context, day string, uuid string, err error) {
+	if err == nil || c.opts.Daily <= 0 || uuid != "" || ctx.Err() != nil || errors.Is(err, gdetect.ErrTimeout) {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.usage.Day == day && c.usage.Used > 0 {
+		c.usage.Used--
+		c.persist()
+	}
+}
+
+// persist persists consumption in store, if any, c.lock must be held.
+func (c *BudgetClient) persist() {
+	if c.opts.Store == nil {
+		return
+	}
+	raw, err := json.Marshal(c.usage)
+	if err == nil {
+		err = c.opts.Store.Put(budgetKey, raw)
+	}
+	if err != nil {
+		logger.Warn("could not persist daily budget consumption", slog.String("error", err.Error()))
+	}
+}
+
+func (c *BudgetClient) SubmitFile(ctx context.Context, filepath string, options gdetect.SubmitOptions) (uuid string, err error) {
+	day, err := c.reserve()
+	if err != nil {
+		return
+	}
+	uuid, err = c.GDetectSubmitter.SubmitFile(ctx, filepath, options)
+	c.release(ctx, day, uuid, err)
+	return
+}
+
+func (c *BudgetClient) SubmitReader(ctx context.Context, r io.Reader, options gdetect.SubmitOptions) (uuid string, err error) {
+	day, err := c.reserve()
+	if err != nil {
+		return
+	}
+	uuid, err = c.GDetectSubmitter.SubmitReader(ctx, r, options)
+	c.release(ctx, day, uuid, err)
+	return
+}
+
+func (c *BudgetClient) WaitForFile(ctx context.Context, filepath string, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+	day, err := c.reserve()
+	if err != nil {
+		return
+	}
+	result, err = c.GDetectSubmitter.WaitForFile(ctx, filepath, options)
+	c.release(ctx, day, result.UUID, err)
+	return
+}
+
+func (c *BudgetClient) WaitForReader(ctx context.Context, r io.Reader, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+	day, err := c.reserve()
+	if err != nil {
+		return
+	}
+	result, err = c.GDetectSubmitter.WaitForReader(ctx, r, options)
+	c.release(ctx, day, result.UUID, err)
+	return
+}
+
+func (c *BudgetClient) ExtendedSubmitter() (extended gdetect.ExtendedGDetectSubmitter, ok bool) {
+	return ExtendedSubmitter(c.GDetectSubmitter)
+}
@@ -0,0 +1,134 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/glimps-re/connector-integration/sdk/state"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+	gdetectmock "github.com/glimps-re/go-gdetect/pkg/gdetect/mock"
+)
+
+func TestBudgetClient(t *testing.T) {
+	submitErr := errors.New("upload failed")
+	tests := []struct {
+		name          string
+		daily         int
+		submissions   []bool // failed or not
+		nextDay       bool   // checked submission is made on next day
+		wantErr       error
+		wantRemaining int
+		wantRejected  int64
+	}{
+		{name: "no budget", submissions: []bool{false, false}, wantRemaining: -1},
+		{name: "within budget", daily: 3, submissions: []bool{false}, wantRemaining: 1},
+		{name: "exceeded", daily: 2, submissions: []bool{false, false}, wantErr: ErrBudgetExceeded, wantRejected: 1},
+		{name: "failed submissions not counted", daily: 2, submissions: []bool{true, false, true}, wantRemaining: 0},
+		{name: "reset next day", daily: 2, submissions: []bool{false, false}, nextDay: true, wantRemaining: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := state.NewFileStore(t.TempDir(), nil)
+			if err != nil {
+				t.Fatalf("NewFileStore() error = %v", err)
+			}
+			fail := false
+			submitter := &gdetectmock.MockGDetectSubmitter{
+				WaitForReaderMock: func(ctx context.Context, r io.Reader, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+					if fail {
+						err = submitErr
+					}
+					return
+				},
+			}
+			analysisMetrics := &metrics.AnalysisMetrics{}
+			now := time.Date(2026, 10, 14, 23, 0, 0, 0, time.UTC)
+			opts := BudgetOptions{Daily: tt.daily, Store: store, Metrics: analysisMetrics}
+			c := NewBudgetClient(submitter, opts)
+			c.now = func() time.Time { return now }
+			for _, failed := range tt.submissions {
+				fail = failed
+				_, err = c.WaitForReader(t.Context(), strings.NewReader("file"), gdetect.WaitForOptions{})
+				if !errors.Is(err, submitErr) && err != nil {
+					t.Fatalf("WaitForReader() error = %v", err)
+				}
+			}
+			fail = false
+			// restarted connector resumes consumption of the day
+			c = NewBudgetClient(submitter, opts)
+			c.now = func() time.Time { return now }
+			if tt.nextDay {
+				now = now.Add(2 * time.Hour)
+			}
+			_, err = c.WaitForReader(t.Context(), strings.NewReader("file"), gdetect.WaitForOptions{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WaitForReader() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := c.Remaining(); got != tt.wantRemaining {
+				t.Errorf("Remaining() = %d, want %d", got, tt.wantRemaining)
+			}
+			if got := analysisMetrics.Snapshot().BudgetRejected; got != tt.wantRejected {
+				t.Errorf("budget rejected = %d, want %d", got, tt.wantRejected)
+			}
+			if want := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC); !c.ResetAt().Equal(want) {
+				t.Errorf("ResetAt() = %s, want %s", c.ResetAt(), want)
+			}
+		})
+	}
+}
+
+func TestBudgetClient_release(t *testing.T) {
+	uploadErr := errors.New("upload failed")
+	tests := []struct {
+		name          string
+		submit        bool // SubmitReader or WaitForReader
+		uuid          string
+		err           error
+		cancel        bool
+		wantRemaining int
+	}{
+		{name: "upload failed", err: uploadErr, wantRemaining: 1},
+		{name: "submit failed", submit: true, err: uploadErr, wantRemaining: 1},
+		{name: "submitted", submit: true, uuid: "uuid", wantRemaining: 0},
+		{name: "wait timeout", err: fmt.Errorf("error waiting for result: %w", gdetect.ErrTimeout), wantRemaining: 0},
+		{name: "wait failed after submission", uuid: "uuid", err: errors.New("error waiting for result"), wantRemaining: 0},
+		{name: "submit failed with uuid", submit: true, uuid: "uuid", err: uploadErr, wantRemaining: 0},
+		{name: "cancelled", err: context.Canceled, cancel: true, wantRemaining: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			submitter := &gdetectmock.MockGDetectSubmitter{
+				SubmitReaderMock: func(ctx context.Context, r io.Reader, options gdetect.SubmitOptions) (uuid string, err error) {
+					return tt.uuid, tt.err
+				},
+				WaitForReaderMock: func(ctx context.Context, r io.Reader, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+					return gdetect.Result{UUID: tt.uuid}, tt.err
+				},
+			}
+			c := NewBudgetClient(submitter, BudgetOptions{Daily: 1})
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+			var err error
+			if tt.submit {
+				_, err = c.SubmitReader(ctx, strings.NewReader("file"), gdetect.SubmitOptions{})
+			} else {
+				_, err = c.WaitForReader(ctx, strings.NewReader("file"), gdetect.WaitForOptions{})
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("submission error = %v, want %v", err, tt.err)
+			}
+			if got := c.Remaining(); got != tt.wantRemaining {
+				t.Errorf("Remaining() = %d, want %d", got, tt.wantRemaining)
+			}
+		})
+	}
+}
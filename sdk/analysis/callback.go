@@ -0,0 +1,248 @@
+package analysis
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/state"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+)
+
+// DefaultCallbackTimeout is the default delay after which results of pending submissions are fetched by Reconcile.
+const DefaultCallbackTimeout = 30 * time.Minute
+
+// maxWebhookBody bounds result webhook bodies.
+const maxWebhookBody = 16 << 20
+
+// callbackKeyPrefix prefixes state keys of pending submissions.
+const callbackKeyPrefix = "analysis/callback/"
+
+var (
+	ErrMissingCallbackToken = errors.New("callback token is required to authenticate result webhooks")
+	ErrUnknownSubmission    = errors.New("unknown pending submission")
+)
+
+// PendingSubmission is a submission awaiting its result, persisted in state store so workflows resume after restart.
+type PendingSubmission struct {
+	UUID        string    `json:"uuid"`
+	SubmittedAt time.Time `json:"submitted_at"`
+	// Data is what connector needs to resume its workflow (e.g. item location), given to Submit
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// ResultHandler resumes connector workflow of submission with its result. Submission is kept pending on error, to
+// be handled again on next webhook delivery or Reconcile.
+type ResultHandler func(ctx context.Context, submission PendingSubmission, result gdetect.Result) (err error)
+
+type CallbackOptions struct {
+	// Token authenticates result webhooks (Authorization: Bearer <token>), required.
+	Token string
+	// Timeout is the delay after which results of pending submissions are fetched by Reconcile, e.g. for
+	// webhooks lost while connector was down. DefaultCallbackTimeout if 0.
+	Timeout time.Duration
+}
+
+// CallbackClient submits items in callback mode: results are posted by GLIMPS Malware to its Handler (result
+// webhook, configured on GLIMPS Malware side with Handler URL and Token) rather than polled, avoiding long polling
+// for slow analyses. Pending submissions are correlated with webhooks through state store.
+type CallbackClient struct {
+	submitter gdetect.GDetectSubmitter
+	store     state.Store
+	handle    ResultHandler
+	opts      CallbackOptions
+	now       func() time.Time
+
+	lock     sync.Mutex
+	handling map[string]bool // submissions being handled, so a result delivered twice is handled once
+}
+
+// NewCallbackClient returns a CallbackClient submitting to submitter, handle being called with results.
+func NewCallbackClient(submitter gdetect.GDetectSubmitter, store state.Store, handle ResultHandler, opts CallbackOptions) (c *CallbackClient, err error) {
+	if opts.Token == "" {
+		err = ErrMissingCallbackToken
+		return
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultCallbackTimeout
+	}
+	c = &CallbackClient{
+		submitter: submitter,
+		store:     store,
+		handle:    handle,
+		opts:      opts,
+		now:       time.Now,
+		handling:  map[string]bool{},
+	}
+	return
+}
+
+// Submit submits r without waiting for its result, data (JSON encoded) being given back to ResultHandler.
+func (c *CallbackClient) Submit(ctx context.Context, r io.Reader, options gdetect.SubmitOptions, data any) (uuid string, err error) {
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		err = fmt.Errorf("could not encode submission data, %w", err)
+		return
+	}
+	uuid, err = c.submitter.SubmitReader(ctx, r, options)
+	if err != nil {
+		return
+	}
+	submission := PendingSubmission{UUID: uuid, SubmittedAt: c.now(), Data: rawData}
+	raw, err := json.Marshal(submission)
+	if err != nil {
+		return
+	}
+	if err = c.store.Put(callbackKeyPrefix+uuid, raw); err != nil {
+		err = fmt.Errorf("could not record pending submission %s, %w", uuid, err)
+		return
+	}
+	return
+}
+
+// Pending returns submissions awaiting their result.
+func (c *CallbackClient) Pending() (submissions []PendingSubmission, err error) {
+	keys, err := c.store.Keys(callbackKeyPrefix)
+	if err != nil {
+		return
+	}
+	submissions = []PendingSubmission{}
+	for _, key := range keys {
+		submission, getErr := c.pending(strings.TrimPrefix(key, callbackKeyPrefix))
+		if errors.Is(getErr, ErrUnknownSubmission) {
+			// handled meanwhile
+			continue
+		}
+		if getErr != nil {
+			err = getErr
+			return
+		}
+		submissions = append(submissions, submission)
+	}
+	return
+}
+
+func (c *CallbackClient) pending(uuid string) (submission PendingSubmission, err error) {
+	raw, err := c.store.Get(callbackKeyPrefix + uuid)
+	if errors.Is(err, state.ErrNotFound) {
+		err = fmt.Errorf("%w: %s", ErrUnknownSubmission, uuid)
+		return
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(raw, &submission)
+	return
+}
+
+// resume handles result of pending submission uuid, then forgets it. It returns ErrUnknownSubmission if submission
+// is not pending, or is being handled.
+func (c *CallbackClient) resume(ctx context.Context, uuid string, result gdetect.Result) (err error) {
+	c.lock.Lock()
+	if c.handling[uuid] {
+		c.lock.Unlock()
+		err = fmt.Errorf("%w: %s is being handled", ErrUnknownSubmission, uuid)
+		return
+	}
+	c.handling[uuid] = true
+	c.lock.Unlock()
+	defer func() {
+		c.lock.Lock()
+		delete(c.handling, uuid)
+		c.lock.Unlock()
+	}()
+
+	submission, err := c.pending(uuid)
+	if err != nil {
+		return
+	}
+	if err = c.handle(ctx, submission, result); err != nil {
+		err = fmt.Errorf("could not handle result of submission %s, %w", uuid, err)
+		return
+	}
+	err = c.store.Delete(callbackKeyPrefix + uuid)
+	return
+}
+
+// Reconcile fetches results of submissions pending for more than Timeout, and handles finished ones.
+func (c *CallbackClient) Reconcile(ctx context.Context) (err error) {
+	submissions, err := c.Pending()
+	if err != nil {
+		return
+	}
+	var errs []error
+	for _, submission := range submissions {
+		if c.now().Sub(submission.SubmittedAt) < c.opts.Timeout {
+			continue
+		}
+		result, getErr := c.submitter.GetResultByUUID(ctx, submission.UUID)
+		if getErr != nil {
+			errs = append(errs, fmt.Errorf("could not get result of submission %s, %w", submission.UUID, getErr))
+			continue
+		}
+		if !result.Done {
+			continue
+		}
+		if resumeErr := c.resume(ctx, submission.UUID, result); resumeErr != nil && !errors.Is(resumeErr, ErrUnknownSubmission) {
+			errs = append(errs, resumeErr)
+		}
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+// Handler returns the handler of result webhooks: POST requests with a gdetect.Result body, authenticated with
+// Token. It answers 204 once result is handled, 404 for unknown submissions, and 500 if handling failed, for
+// GLIMPS Malware to deliver it again.
+func (c *CallbackClient) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(c.opts.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "invalid callback token", http.StatusUnauthorized)
+			return
+		}
+		result := gdetect.Result{}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBody)).Decode(&result); err != nil {
+			http.Error(w, fmt.Sprintf("invalid result, %s", err), http.StatusBadRequest)
+			return
+		}
+		uuid := result.UUID
+		if uuid == "" {
+			// syndetect analysis ID
+			uuid = result.ID
+		}
+		if uuid == "" {
+			http.Error(w, "invalid result, missing uuid", http.StatusBadRequest)
+			return
+		}
+		if !result.Done {
+			// progress notification, final result still expected
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		err := c.resume(r.Context(), uuid, result)
+		switch {
+		case errors.Is(err, ErrUnknownSubmission):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case err != nil:
+			logger.Error("could not handle result webhook", slog.String("uuid", uuid), slog.String("error", err.Error()))
+			http.Error(w, "could not handle result", http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
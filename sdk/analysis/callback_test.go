@@ -0,0 +1,130 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/state"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+	gdetectmock "github.com/glimps-re/go-gdetect/pkg/gdetect/mock"
+	"github.com/google/go-cmp/cmp"
+)
+
+type testItem struct {
+	Location string `json:"location"`
+}
+
+func TestCallbackClient(t *testing.T) {
+	store, err := state.NewFileStore(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	submitted := 0
+	submitter := &gdetectmock.MockGDetectSubmitter{
+		SubmitReaderMock: func(ctx context.Context, r io.Reader, options gdetect.SubmitOptions) (uuid string, err error) {
+			submitted++
+			uuid = []string{"", "uuid-1", "uuid-2", "uuid-3"}[submitted]
+			return
+		},
+		GetResultByUUIDMock: func(ctx context.Context, uuid string) (result gdetect.Result, err error) {
+			result = gdetect.Result{UUID: uuid, Done: uuid == "uuid-2", Malware: true}
+			return
+		},
+	}
+	var handled []string // "uuid location malware"
+	failHandle := false
+	handle := func(ctx context.Context, submission PendingSubmission, result gdetect.Result) (err error) {
+		if failHandle {
+			return errors.New("quarantine unavailable")
+		}
+		item := testItem{}
+		if err = json.Unmarshal(submission.Data, &item); err != nil {
+			return
+		}
+		handled = append(handled, submission.UUID+" "+item.Location+" "+map[bool]string{true: "malware", false: "clean"}[result.Malware])
+		return
+	}
+	if _, err = NewCallbackClient(submitter, store, handle, CallbackOptions{}); !errors.Is(err, ErrMissingCallbackToken) {
+		t.Fatalf("NewCallbackClient() error = %v, want %v", err, ErrMissingCallbackToken)
+	}
+	c, err := NewCallbackClient(submitter, store, handle, CallbackOptions{Token: "secret", Timeout: time.Minute})
+	if err != nil {
+		t.Fatalf("NewCallbackClient() error = %v", err)
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	for _, location := range []string{"/data/a", "/data/b", "/data/c"} {
+		if _, err = c.Submit(t.Context(), strings.NewReader("content"), gdetect.SubmitOptions{}, testItem{Location: location}); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+
+	server := httptest.NewServer(c.Handler())
+	defer server.Close()
+	post := func(token string, body string) int {
+		t.Helper()
+		req, reqErr := http.NewRequestWithContext(t.Context(), http.MethodPost, server.URL, strings.NewReader(body))
+		if reqErr != nil {
+			t.Fatalf("NewRequest() error = %v", reqErr)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, doErr := http.DefaultClient.Do(req)
+		if doErr != nil {
+			t.Fatalf("POST error = %v", doErr)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	webhooks := []struct {
+		name  string
+		token string
+		body  string
+		fail  bool
+		want  int
+	}{
+		{name: "invalid token", token: "other", body: `{"uuid":"uuid-1","done":true}`, want: http.StatusUnauthorized},
+		{name: "invalid body", token: "secret", body: `{"uuid":`, want: http.StatusBadRequest},
+		{name: "not done", token: "secret", body: `{"uuid":"uuid-1"}`, want: http.StatusAccepted},
+		{name: "handle error", token: "secret", body: `{"uuid":"uuid-1","done":true}`, fail: true, want: http.StatusInternalServerError},
+		{name: "result", token: "secret", body: `{"uuid":"uuid-1","done":true,"is_malware":true}`, want: http.StatusNoContent},
+		{name: "delivered again", token: "secret", body: `{"uuid":"uuid-1","done":true}`, want: http.StatusNotFound},
+		{name: "unknown", token: "secret", body: `{"uuid":"uuid-9","done":true}`, want: http.StatusNotFound},
+	}
+	for _, tt := range webhooks {
+		failHandle = tt.fail
+		if got := post(tt.token, tt.body); got != tt.want {
+			t.Errorf("%s: webhook status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+	failHandle = false
+
+	// uuid-2 done, uuid-3 still running
+	if err = c.Reconcile(t.Context()); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if len(handled) != 1 {
+		t.Fatalf("Reconcile() before timeout handled %v", handled)
+	}
+	now = now.Add(time.Minute)
+	if err = c.Reconcile(t.Context()); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if diff := cmp.Diff(handled, []string{"uuid-1 /data/a malware", "uuid-2 /data/b malware"}); diff != "" {
+		t.Errorf("handled results diff(got-want)=%s", diff)
+	}
+	pending, err := c.Pending()
+	if err != nil {
+		t.Fatalf("Pending() error = %v", err)
+	}
+	want := []PendingSubmission{{UUID: "uuid-3", SubmittedAt: now.Add(-time.Minute), Data: []byte(`{"location":"/data/c"}`)}}
+	if diff := cmp.Diff(pending, want); diff != "" {
+		t.Errorf("Pending() diff(got-want)=%s", diff)
+	}
+}
@@ -0,0 +1,270 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/glimps-re/connector-integration/sdk/state"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+)
+
+const (
+	// DefaultDeferredRetryInterval is the delay between retries of a deferred item, as SharepointConfig
+	// RetryFrequency default.
+	DefaultDeferredRetryInterval = 10 * time.Minute
+	// DefaultDeferredMaxAge is the age after which deferred items are dropped.
+	DefaultDeferredMaxAge = 24 * time.Hour
+	// deferredKeyPrefix prefixes state keys of deferred items.
+	deferredKeyPrefix = "deferred/"
+)
+
+var ErrInvalidDeferredItem = errors.New("invalid deferred item")
+
+// IsQuotaError reports whether err is a submission rejected due to quota: connector daily budget
+// (ErrBudgetExceeded) or GLIMPS Malware quota (429 response).
+func IsQuotaError(err error) bool {
+	if errors.Is(err, ErrBudgetExceeded) {
+		return true
+	}
+	var httpErr gdetect.HTTPError
+	return errors.As(err, &httpErr) && httpErr.Code == http.StatusTooManyRequests
+}
+
+type DeferredOptions struct {
+	// Store persists deferred items, so they are retried after connector restarts. Items are kept in memory if nil.
+	Store state.Store
+	// RetryInterval is the delay between retries of an item (e.g. SharepointConfig.RetryFrequency),
+	// DefaultDeferredRetryInterval if 0.
+	RetryInterval time.Duration
+	// MaxAge is the age after which an item not submitted yet is dropped, DefaultDeferredMaxAge if 0.
+	MaxAge time.Duration
+	// Metrics, if set, records queue depth and dropped items (e.g. ConnectorManagerClient.AnalysisMetrics).
+	Metrics *metrics.AnalysisMetrics
+}
+
+// DeferredItem is an item parked in a DeferredQueue. Payload is connector data needed to submit it again (e.g.
+// a file ID and its drive), it must not depend on process state, items being persisted.
+type DeferredItem struct {
+	ID        string          `json:"id"`
+	Payload   json.RawMessage `json:"payload"`
+	Added     time.Time       `json:"added"`
+	Attempts  int             `json:"attempts"`
+	NextRetry time.Time       `json:"next_retry"`
+	LastError string          `json:"last_error,omitempty"`
+}
+
+// DeferredRetry submits a deferred item again. An item is removed from queue once it returns nil or a non quota
+// error (see IsQuotaError), and retried later otherwise.
+type DeferredRetry func(ctx context.Context, item DeferredItem) (err error)
+
+// DeferredQueue parks items rejected due to quota (see IsQuotaError), retried uniformly on a schedule until
+// they are submitted or too old. It is safe for concurrent use.
+type DeferredQueue struct {
+	opts DeferredOptions
+	now  func() time.Time
+
+	lock  sync.Mutex
+	items map[string]DeferredItem
+}
+
+// NewDeferredQueue returns a DeferredQueue, loading items persisted in opts.Store.
+func NewDeferredQueue(opts DeferredOptions) (q *DeferredQueue, err error) {
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = DefaultDeferredRetryInterval
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultDeferredMaxAge
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = &metrics.AnalysisMetrics{}
+	}
+	q = &DeferredQueue{opts: opts, now: time.Now, items: map[string]DeferredItem{}}
+	if opts.Store == nil {
+		return
+	}
+	keys, err := opts.Store.Keys(deferredKeyPrefix)
+	if err != nil {
+		err = fmt.Errorf("could not list deferred items, %w", err)
+		return
+	}
+	for _, key := range keys {
+		raw, getErr := opts.Store.Get(key)
+		if getErr != nil {
+			err = fmt.Errorf("could not load deferred item, %w", getErr)
+			return
+		}
+		var item DeferredItem
+		if jsonErr := json.Unmarshal(raw, &item); jsonErr != nil || item.ID == "" {
+			logger.Warn("drop invalid deferred item", slog.String("key", key))
+			if err = opts.Store.Delete(key); err != nil {
+				return
+			}
+			continue
+		}
+		q.items[item.ID] = item
+	}
+	q.opts.Metrics.SetDeferred(int64(len(q.items)))
+	return
+}
+
+// Defer parks item id, rejected with cause, to be retried after RetryInterval. payload is marshaled in JSON. An
+// item deferred again keeps its age and attempts.
+func (q *DeferredQueue) Defer(id string, payload any, cause error) (err error) {
+	if id == "" {
+		err = fmt.Errorf("%w, id must not be empty", ErrInvalidDeferredItem)
+		return
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		err = fmt.Errorf("%w, %w", ErrInvalidDeferredItem, err)
+		return
+	}
+	now := q.now()
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	item, ok := q.items[id]
+	if !ok {
+		item = DeferredItem{ID: id, Added: now}
+	}
+	item.Payload = raw
+	item.NextRetry = now.Add(q.opts.RetryInterval)
+	if cause != nil {
+		item.LastError = cause.Error()
+	}
+	if err = q.persist(item); err != nil {
+		return
+	}
+	q.items[id] = item
+	q.opts.Metrics.SetDeferred(int64(len(q.items)))
+	return
+}
+
+// Len returns the number of deferred items.
+func (q *DeferredQueue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.items)
+}
+
+// Items returns deferred items, oldest first.
+func (q *DeferredQueue) Items() (items []DeferredItem) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	items = q.sorted()
+	return
+}
+
+// sorted returns items, oldest first, q.lock must be held.
+func (q *DeferredQueue) sorted() (items []DeferredItem) {
+	items = make([]DeferredItem, 0, len(q.items))
+	for _, item := range q.items {
+		items = append(items, item)
+	}
+	slices.SortFunc(items, func(a, b DeferredItem) int {
+		return a.Added.Compare(b.Added)
+	})
+	return
+}
+
+// Run retries due items with retry, oldest first, every RetryInterval until ctx is done. A retry rejected due to
+// quota stops the round, remaining items waiting for next one.
+func (q *DeferredQueue) Run(ctx context.Context, retry DeferredRetry) {
+	ticker := time.NewTicker(q.opts.RetryInterval)
+	defer ticker.Stop()
+	for {
+		q.RetryDue(ctx, retry)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RetryDue retries due items once, oldest first, dropping too old ones.
+func (q *DeferredQueue) RetryDue(ctx context.Context, retry DeferredRetry) {
+	q.lock.Lock()
+	items := q.sorted()
+	q.lock.Unlock()
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		now := q.now()
+		if now.Sub(item.Added) >= q.opts.MaxAge {
+			logger.Warn("drop deferred item, too old", slog.String("id", item.ID), slog.Int("attempts", item.Attempts), slog.String("last-error", item.LastError))
+			q.opts.Metrics.AddDeferredExpired()
+			q.remove(item.ID)
+			continue
+		}
+		if now.Before(item.NextRetry) {
+			continue
+		}
+		item.Attempts++
+		err := retry(ctx, item)
+		switch {
+		case err == nil:
+			q.remove(item.ID)
+		case IsQuotaError(err):
+			item.NextRetry = q.now().Add(q.opts.RetryInterval)
+			item.LastError = err.Error()
+			q.update(item)
+			// quota is still reached, other items would be rejected too
+			return
+		case ctx.Err() != nil:
+			return
+		default:
+			logger.Warn("deferred item retry failed, drop it", slog.String("id", item.ID), slog.String("error", err.Error()))
+			q.remove(item.ID)
+		}
+	}
+}
+
+// update stores item, unless it was removed meanwhile.
+func (q *DeferredQueue) update(item DeferredItem) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if _, ok := q.items[item.ID]; !ok {
+		return
+	}
+	if err := q.persist(item); err != nil {
+		logger.Warn("could not persist deferred item", slog.String("id", item.ID), slog.String("error", err.Error()))
+	}
+	q.items[item.ID] = item
+}
+
+func (q *DeferredQueue) remove(id string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	delete(q.items, id)
+	q.opts.Metrics.SetDeferred(int64(len(q.items)))
+	if q.opts.Store == nil {
+		return
+	}
+	if err := q.opts.Store.Delete(deferredKeyPrefix + id); err != nil {
+		logger.Warn("could not delete deferred item", slog.String("id", id), slog.String("error", err.Error()))
+	}
+}
+
+// persist writes item in store, if any, q.lock must be held.
+func (q *DeferredQueue) persist(item DeferredItem) (err error) {
+	if q.opts.Store == nil {
+		return
+	}
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return
+	}
+	if err = q.opts.Store.Put(deferredKeyPrefix+item.ID, raw); err != nil {
+		err = fmt.Errorf("could not persist deferred item, %w", err)
+	}
+	return
+}
@@ -0,0 +1,122 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/glimps-re/connector-integration/sdk/state"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIsQuotaError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil"},
+		{name: "budget", err: fmt.Errorf("submit, %w", ErrBudgetExceeded), want: true},
+		{name: "gmalware quota", err: gdetect.HTTPError{Code: 429}, want: true},
+		{name: "other", err: gdetect.HTTPError{Code: 500}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsQuotaError(tt.err); got != tt.want {
+				t.Errorf("IsQuotaError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeferredQueue(t *testing.T) {
+	type payload struct {
+		Drive string `json:"drive"`
+	}
+	tests := []struct {
+		name        string
+		after       time.Duration // clock moved forward before retries
+		results     map[string]error
+		wantRetried []string
+		wantLeft    []string
+		wantExpired int64
+	}{
+		{name: "not due", after: time.Minute, wantLeft: []string{"file-1", "file-2", "file-3"}},
+		{
+			name:        "submitted or failed",
+			after:       10 * time.Minute,
+			results:     map[string]error{"file-2": errors.New("file deleted")},
+			wantRetried: []string{"file-1", "file-2", "file-3"},
+		},
+		{
+			name:        "quota still reached",
+			after:       10 * time.Minute,
+			results:     map[string]error{"file-2": ErrBudgetExceeded},
+			wantRetried: []string{"file-1", "file-2"},
+			wantLeft:    []string{"file-2", "file-3"},
+		},
+		{name: "too old", after: 2 * time.Hour, wantExpired: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := state.NewFileStore(t.TempDir(), nil)
+			if err != nil {
+				t.Fatalf("NewFileStore() error = %v", err)
+			}
+			m := &metrics.AnalysisMetrics{}
+			opts := DeferredOptions{Store: store, MaxAge: time.Hour, Metrics: m}
+			q, err := NewDeferredQueue(opts)
+			if err != nil {
+				t.Fatalf("NewDeferredQueue() error = %v", err)
+			}
+			now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+			q.now = func() time.Time { return now }
+			for _, id := range []string{"file-1", "file-2", "file-3"} {
+				now = now.Add(time.Second)
+				if err = q.Defer(id, payload{Drive: "drive-1"}, ErrBudgetExceeded); err != nil {
+					t.Fatalf("Defer() error = %v", err)
+				}
+			}
+
+			// items are retried after restart
+			if q, err = NewDeferredQueue(opts); err != nil {
+				t.Fatalf("NewDeferredQueue() error = %v", err)
+			}
+			q.now = func() time.Time { return now }
+			now = now.Add(tt.after)
+			var retried []string
+			q.RetryDue(t.Context(), func(ctx context.Context, item DeferredItem) (err error) {
+				if string(item.Payload) != `{"drive":"drive-1"}` || item.Attempts != 1 {
+					t.Errorf("retried item = %+v", item)
+				}
+				retried = append(retried, item.ID)
+				err = tt.results[item.ID]
+				return
+			})
+			if diff := cmp.Diff(retried, tt.wantRetried); diff != "" {
+				t.Errorf("retried items diff(got-want)=%s", diff)
+			}
+			var left []string
+			for _, item := range q.Items() {
+				left = append(left, item.ID)
+			}
+			if diff := cmp.Diff(left, tt.wantLeft); diff != "" {
+				t.Errorf("Items() diff(got-want)=%s", diff)
+			}
+			keys, err := store.Keys(deferredKeyPrefix)
+			if err != nil {
+				t.Fatalf("Keys() error = %v", err)
+			}
+			if len(keys) != len(tt.wantLeft) {
+				t.Errorf("persisted items = %v, want %v", keys, tt.wantLeft)
+			}
+			if s := m.Snapshot(); s.Deferred != int64(len(tt.wantLeft)) || s.DeferredExpired != tt.wantExpired {
+				t.Errorf("Snapshot() deferred = %d, expired = %d, want %d, %d", s.Deferred, s.DeferredExpired, len(tt.wantLeft), tt.wantExpired)
+			}
+		})
+	}
+}
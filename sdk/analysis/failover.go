@@ -0,0 +1,393 @@
+// Package analysis provides helpers around GLIMPS Malware detect clients, shared by connectors.
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+)
+
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: sdk.LogLevel})).WithGroup("analysis")
+
+const (
+	DefaultFailureThreshold = 3
+	DefaultRecoveryInterval = time.Minute
+)
+
+type FailoverOptions struct {
+	// FailureThreshold is the number of consecutive endpoint errors before switching to secondary endpoint.
+	FailureThreshold int
+	// RecoveryInterval is the delay between two checks of primary endpoint while using secondary one.
+	RecoveryInterval time.Duration
+	// EventHandler, if set, is notified with a GMalwareError on failover and a resolution on recovery.
+	EventHandler events.EventErrorHandler
+	// ConnectorID, if set, gives ConnectorID of user tags of clients created from config (see NewTaggingClient), e.g.
+	// sdk.ConnectorManagerClient.ConnectorID.
+	ConnectorID func() string
+	// Budget is the budget of clients created from config (see NewFailoverClientFromConfig), its Daily being set
+	// from GMalwareDailyBudget of config.
+	Budget BudgetOptions
+}
+
+type endpoint int
+
+const (
+	primaryEndpoint endpoint = iota
+	secondaryEndpoint
+)
+
+func (e endpoint) String() string {
+	if e == secondaryEndpoint {
+		return "secondary"
+	}
+	return "primary"
+}
+
+var (
+	_ gdetect.GDetectSubmitter = &FailoverClient{}
+	_ ExtendedSubmitterGetter  = &FailoverClient{}
+)
+
+// FailoverClient is a GDetectSubmitter sending requests to a primary endpoint, switching to
+// a secondary one after FailureThreshold consecutive endpoint errors (network errors or 5xx responses),
+// and back to primary once it answers again.
+// Failed requests are not retried on the other endpoint: readers may have been consumed.
+// Results must be fetched from the endpoint that received the submission, so callers
+// should prefer WaitForFile/WaitForReader to submit-then-poll.
+type FailoverClient struct {
+	primary   gdetect.GDetectSubmitter
+	secondary gdetect.GDetectSubmitter
+	opts      FailoverOptions
+
+	lock      sync.Mutex
+	active    endpoint
+	failures  int
+	lastCheck time.Time
+	now       func() time.Time
+}
+
+// NewFailoverClient returns a FailoverClient, secondary may be nil to only use primary.
+func NewFailoverClient(primary gdetect.GDetectSubmitter, secondary gdetect.GDetectSubmitter, opts FailoverOptions) (c *FailoverClient) {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultFailureThreshold
+	}
+	if opts.RecoveryInterval <= 0 {
+		opts.RecoveryInterval = DefaultRecoveryInterval
+	}
+	c = &FailoverClient{
+		primary:   primary,
+		secondary: secondary,
+		opts:      opts,
+		now:       time.Now,
+	}
+	return
+}
+
+// NewFailoverClientFromConfig creates detect clients for GLIMPS Malware endpoints of config
+// (secondary one only if GMalwareFallbackAPIURL is set) and wraps them in a FailoverClient.
+// Both go through outbound proxy of config and trust its custom CA certificates, if set.
+// With GMalwareRouting rules or default, items are routed between detect and syndetect (see NewRoutingClientFromConfig).
+// With GMalwareUserTags, submissions are tagged with them (see NewTaggingClient). With GMalwareProfiles, items are
+// submitted to the endpoint of their profile (see NewProfileClient). With GMalwareDailyBudget, the client is wrapped
+// in a BudgetClient (see FailoverOptions.Budget), e.g. for PoolOptions.Budget.
+func NewFailoverClientFromConfig(config sdk.CommonConnectorConfig, opts FailoverOptions) (c gdetect.GDetectSubmitter, err error) {
+	switch {
+	case len(config.GMalwareProfiles) > 0:
+		profileClient, profileErr := newProfileClientFromConfig(config, opts)
+		if profileErr != nil {
+			err = profileErr
+			return
+		}
+		c = profileClient
+	default:
+		if c, err = newUnbudgetedClientFromConfig(config, opts); err != nil {
+			return
+		}
+	}
+	if config.GMalwareDailyBudget > 0 {
+		budget := opts.Budget
+		budget.Daily = config.GMalwareDailyBudget
+		c = NewBudgetClient(c, budget)
+	}
+	return
+}
+
+// newUnbudgetedClientFromConfig is NewFailoverClientFromConfig ignoring GMalwareProfiles and GMalwareDailyBudget.
+func newUnbudgetedClientFromConfig(config sdk.CommonConnectorConfig, opts FailoverOptions) (c gdetect.GDetectSubmitter, err error) {
+	switch routing := config.GMalwareRouting; {
+	case routing.Default != "" || len(routing.Rules) > 0:
+		routingClient, routingErr := NewRoutingClientFromConfig(config, opts)
+		if routingErr != nil {
+			err = routingErr
+			return
+		}
+		c = routingClient
+	default:
+		failoverClient, failoverErr := newFailoverClientFromConfig(config, config.GMalwareSyndetect, opts)
+		if failoverErr != nil {
+			err = failoverErr
+			return
+		}
+		c = failoverClient
+	}
+	if len(config.GMalwareUserTags) > 0 {
+		policy, policyErr := NewTagPolicy(config.GMalwareUserTags)
+		if policyErr != nil {
+			err = policyErr
+			return
+		}
+		c = NewTaggingClient(c, policy, opts.ConnectorID)
+	}
+	return
+}
+
+// newFailoverClientFromConfig is NewFailoverClientFromConfig submitting to syndetect or detect.
+func newFailoverClientFromConfig(config sdk.CommonConnectorConfig, syndetect bool, opts FailoverOptions) (c *FailoverClient, err error) {
+	httpClient, err := NewHTTPClient(config)
+	if err != nil {
+		return
+	}
+	primary, err := gdetect.NewClientFromConfig(gdetect.ClientConfig{
+		Endpoint:   config.GMalwareAPIURL,
+		Token:      config.GMalwareAPIToken,
+		ExpertURL:  config.GMalwareExpertURL,
+		Syndetect:  syndetect,
+		Insecure:   config.GMalwareNoCertCheck,
+		HTTPClient: httpClient,
+	})
+	if err != nil {
+		err = fmt.Errorf("could not create primary GLIMPS Malware client, %w", err)
+		return
+	}
+	var secondary gdetect.GDetectSubmitter
+	if config.GMalwareFallbackAPIURL != "" {
+		secondary, err = gdetect.NewClientFromConfig(gdetect.ClientConfig{
+			Endpoint:   config.GMalwareFallbackAPIURL,
+			Token:      config.GMalwareFallbackAPIToken,
+			Syndetect:  syndetect,
+			Insecure:   config.GMalwareNoCertCheck,
+			HTTPClient: httpClient,
+		})
+		if err != nil {
+			err = fmt.Errorf("could not create secondary GLIMPS Malware client, %w", err)
+			return
+		}
+	}
+	c = NewFailoverClient(primary, secondary, opts)
+	return
+}
+
+// NewHTTPClient returns the http client of GLIMPS Malware detect clients for config (see gdetect.ClientConfig), going
+// through its outbound proxy and trusting its custom CA certificates. It is nil if neither is configured, for detect
+// clients to use their default one.
+func NewHTTPClient(config sdk.CommonConnectorConfig) (client *http.Client, err error) {
+	proxyURL, err := config.OutboundProxy()
+	if err != nil {
+		return
+	}
+	tlsConfig, err := config.TLSConfig()
+	if err != nil || (proxyURL == nil && tlsConfig.RootCAs == nil) {
+		return
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != nil {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	transport.TLSClientConfig = tlsConfig
+	client = &http.Client{Transport: transport, Timeout: gdetect.DefaultTimeout}
+	return
+}
+
+// Active returns "primary" or "secondary", the endpoint currently used.
+func (c *FailoverClient) Active() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.active.String()
+}
+
+// IsEndpointError reports whether err is caused by the endpoint being unavailable,
+// rather than by the request itself.
+func IsEndpointError(err error) bool {
+	httpErr := new(gdetect.HTTPError)
+	if errors.As(err, httpErr) {
+		return httpErr.Code >= 500
+	}
+	urlErr := new(url.Error)
+	if errors.As(err, &urlErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// submitter returns endpoint to use, checking primary first if it is time to.
+func (c *FailoverClient) submitter(ctx context.Context) (active endpoint, submitter gdetect.GDetectSubmitter) {
+	c.lock.Lock()
+	active = c.active
+	checkPrimary := active == secondaryEndpoint && c.now().Sub(c.lastCheck) >= c.opts.RecoveryInterval
+	if checkPrimary {
+		c.lastCheck = c.now()
+	}
+	c.lock.Unlock()
+
+	if checkPrimary {
+		if _, err := c.primary.GetProfileStatus(ctx); err == nil {
+			c.recover(ctx)
+			active = primaryEndpoint
+		}
+	}
+	if active == secondaryEndpoint {
+		return active, c.secondary
+	}
+	return active, c.primary
+}
+
+func (c *FailoverClient) recover(ctx context.Context) {
+	c.lock.Lock()
+	if c.active == primaryEndpoint {
+		c.lock.Unlock()
+		return
+	}
+	c.active = primaryEndpoint
+	c.failures = 0
+	c.lock.Unlock()
+
+	logger.Info("primary GLIMPS Malware endpoint recovered")
+	if c.opts.EventHandler != nil {
+		if err := c.opts.EventHandler.NotifyResolution(ctx, "primary GLIMPS Malware endpoint recovered", events.GMalwareError); err != nil {
+			logger.Warn("could not notify resolution", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (c *FailoverClient) record(ctx context.Context, used endpoint, err error) {
+	c.lock.Lock()
+	if used != c.active {
+		// endpoint switched meanwhile
+		c.lock.Unlock()
+		return
+	}
+	endpointErr := err != nil && ctx.Err() == nil && IsEndpointError(err)
+	if !endpointErr {
+		if err == nil {
+			c.failures = 0
+		}
+		c.lock.Unlock()
+		return
+	}
+	c.failures++
+	failover := used == primaryEndpoint && c.secondary != nil && c.failures >= c.opts.FailureThreshold
+	if failover {
+		c.active = secondaryEndpoint
+		c.failures = 0
+		c.lastCheck = c.now()
+	}
+	c.lock.Unlock()
+
+	if !failover {
+		return
+	}
+	failoverErr := fmt.Errorf("primary GLIMPS Malware endpoint unavailable, failover to secondary endpoint: %w", err)
+	logger.Warn(failoverErr.Error())
+	if c.opts.EventHandler != nil {
+		if notifyErr := c.opts.EventHandler.NotifyError(ctx, events.GMalwareError, failoverErr); notifyErr != nil {
+			logger.Warn("could not notify error", slog.String("error", notifyErr.Error()))
+		}
+	}
+}
+
+// ExtendedSubmitter returns the client of active endpoint, which received last submissions.
+func (c *FailoverClient) ExtendedSubmitter() (extended gdetect.ExtendedGDetectSubmitter, ok bool) {
+	c.lock.Lock()
+	active := c.active
+	c.lock.Unlock()
+	if active == secondaryEndpoint {
+		return ExtendedSubmitter(c.secondary)
+	}
+	return ExtendedSubmitter(c.primary)
+}
+
+func call[T any](ctx context.Context, c *FailoverClient, fn func(s gdetect.GDetectSubmitter) (T, error)) (result T, err error) {
+	used, s := c.submitter(ctx)
+	result, err = fn(s)
+	c.record(ctx, used, err)
+	return
+}
+
+func (c *FailoverClient) GetResultByUUID(ctx context.Context, uuid string) (result gdetect.Result, err error) {
+	return call(ctx, c, func(s gdetect.GDetectSubmitter) (gdetect.Result, error) {
+		return s.GetResultByUUID(ctx, uuid)
+	})
+}
+
+func (c *FailoverClient) GetResultByUUIDWithWait(ctx context.Context, uuid string, waitSeconds int) (result gdetect.Result, err error) {
+	return call(ctx, c, func(s gdetect.GDetectSubmitter) (gdetect.Result, error) {
+		return s.GetResultByUUIDWithWait(ctx, uuid, waitSeconds)
+	})
+}
+
+func (c *FailoverClient) GetResultBySHA256(ctx context.Context, sha256 string) (result gdetect.Result, err error) {
+	return call(ctx, c, func(s gdetect.GDetectSubmitter) (gdetect.Result, error) {
+		return s.GetResultBySHA256(ctx, sha256)
+	})
+}
+
+func (c *FailoverClient) GetResults(ctx context.Context, from int, size int, tags ...string) (submissions []gdetect.Submission, err error) {
+	return call(ctx, c, func(s gdetect.GDetectSubmitter) ([]gdetect.Submission, error) {
+		return s.GetResults(ctx, from, size, tags...)
+	})
+}
+
+func (c *FailoverClient) SubmitFile(ctx context.Context, filepath string, options gdetect.SubmitOptions) (uuid string, err error) {
+	return call(ctx, c, func(s gdetect.GDetectSubmitter) (string, error) {
+		return s.SubmitFile(ctx, filepath, options)
+	})
+}
+
+func (c *FailoverClient) SubmitReader(ctx context.Context, r io.Reader, options gdetect.SubmitOptions) (uuid string, err error) {
+	return call(ctx, c, func(s gdetect.GDetectSubmitter) (string, error) {
+		return s.SubmitReader(ctx, r, options)
+	})
+}
+
+func (c *FailoverClient) WaitForFile(ctx context.Context, filepath string, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+	return call(ctx, c, func(s gdetect.GDetectSubmitter) (gdetect.Result, error) {
+		return s.WaitForFile(ctx, filepath, options)
+	})
+}
+
+func (c *FailoverClient) WaitForReader(ctx context.Context, r io.Reader, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+	return call(ctx, c, func(s gdetect.GDetectSubmitter) (gdetect.Result, error) {
+		return s.WaitForReader(ctx, r, options)
+	})
+}
+
+func (c *FailoverClient) GetProfileStatus(ctx context.Context) (status gdetect.ProfileStatus, err error) {
+	return call(ctx, c, func(s gdetect.GDetectSubmitter) (gdetect.ProfileStatus, error) {
+		return s.GetProfileStatus(ctx)
+	})
+}
+
+func (c *FailoverClient) GetAPIVersion(ctx context.Context) (version string, err error) {
+	return call(ctx, c, func(s gdetect.GDetectSubmitter) (string, error) {
+		return s.GetAPIVersion(ctx)
+	})
+}
+
+func (c *FailoverClient) ExportResult(ctx context.Context, uuid string, options gdetect.ExportOptions) (data []byte, err error) {
+	return call(ctx, c, func(s gdetect.GDetectSubmitter) ([]byte, error) {
+		return s.ExportResult(ctx, uuid, options)
+	})
+}
@@ -0,0 +1,362 @@
+package analysis
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+	gdetectmock "github.com/glimps-re/go-gdetect/pkg/gdetect/mock"
+	"github.com/google/go-cmp/cmp"
+)
+
+type errorHandlerMock struct {
+	calls []string
+}
+
+func (h *errorHandlerMock) NotifyError(ctx context.Context, errorType events.ErrorEventType, e error) (err error) {
+	h.calls = append(h.calls, "error "+string(errorType))
+	return
+}
+
+func (h *errorHandlerMock) NotifyResolution(ctx context.Context, msg string, errorTypes ...events.ErrorEventType) (err error) {
+	h.calls = append(h.calls, "resolution "+string(errorTypes[0]))
+	return
+}
+
+func TestIsEndpointError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "network", err: &url.Error{Op: "Post", URL: "https://gmalware", Err: errors.New("connection refused")}, want: true},
+		{name: "5xx", err: gdetect.HTTPError{Code: 503}, want: true},
+		{name: "wrapped 5xx", err: errors.Join(errors.New("submit"), gdetect.HTTPError{Code: 500}), want: true},
+		{name: "4xx", err: gdetect.HTTPError{Code: 400}, want: false},
+		{name: "request error", err: gdetect.ErrInvalidUUID, want: false},
+		{name: "analysis timeout", err: gdetect.ErrTimeout, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsEndpointError(tt.err); got != tt.want {
+				t.Errorf("IsEndpointError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailoverClient(t *testing.T) {
+	endpointErr := gdetect.HTTPError{Code: 502}
+	primaryDown := true
+	primaryCalls, secondaryCalls := 0, 0
+	primary := &gdetectmock.MockGDetectSubmitter{
+		GetResultBySHA256Mock: func(ctx context.Context, sha256 string) (result gdetect.Result, err error) {
+			primaryCalls++
+			if primaryDown {
+				err = endpointErr
+			}
+			return
+		},
+		GetProfileStatusMock: func(ctx context.Context) (status gdetect.ProfileStatus, err error) {
+			if primaryDown {
+				err = endpointErr
+			}
+			return
+		},
+	}
+	secondary := &gdetectmock.MockGDetectSubmitter{
+		GetResultBySHA256Mock: func(ctx context.Context, sha256 string) (result gdetect.Result, err error) {
+			secondaryCalls++
+			return
+		},
+	}
+	handler := &errorHandlerMock{}
+	c := NewFailoverClient(primary, secondary, FailoverOptions{FailureThreshold: 2, RecoveryInterval: time.Minute, EventHandler: handler})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	get := func(wantErr bool) {
+		t.Helper()
+		if _, err := c.GetResultBySHA256(t.Context(), "sha256"); (err != nil) != wantErr {
+			t.Fatalf("GetResultBySHA256() error = %v, wantErr %v", err, wantErr)
+		}
+	}
+
+	get(true)
+	if got := c.Active(); got != "primary" {
+		t.Fatalf("Active() = %s after 1 failure, want primary", got)
+	}
+	get(true)
+	if got := c.Active(); got != "secondary" {
+		t.Fatalf("Active() = %s after threshold, want secondary", got)
+	}
+	get(false)
+	if primaryCalls != 2 || secondaryCalls != 1 {
+		t.Fatalf("calls primary=%d secondary=%d, want 2 and 1", primaryCalls, secondaryCalls)
+	}
+
+	// primary still down at recovery check
+	now = now.Add(time.Minute)
+	get(false)
+	if got := c.Active(); got != "secondary" {
+		t.Fatalf("Active() = %s with primary down, want secondary", got)
+	}
+
+	// primary is checked again only after recovery interval
+	primaryDown = false
+	now = now.Add(30 * time.Second)
+	get(false)
+	if got := c.Active(); got != "secondary" {
+		t.Fatalf("Active() = %s before recovery interval, want secondary", got)
+	}
+	now = now.Add(30 * time.Second)
+	get(false)
+	if got := c.Active(); got != "primary" {
+		t.Fatalf("Active() = %s after recovery, want primary", got)
+	}
+	if primaryCalls != 3 || secondaryCalls != 3 {
+		t.Fatalf("calls primary=%d secondary=%d, want 3 and 3", primaryCalls, secondaryCalls)
+	}
+	if diff := cmp.Diff(handler.calls, []string{"error gmalware", "resolution gmalware"}); diff != "" {
+		t.Errorf("events diff(got-want)=%s", diff)
+	}
+}
+
+func TestFailoverClient_noSecondary(t *testing.T) {
+	primary := &gdetectmock.MockGDetectSubmitter{
+		GetResultBySHA256Mock: func(ctx context.Context, sha256 string) (result gdetect.Result, err error) {
+			err = gdetect.HTTPError{Code: 500}
+			return
+		},
+	}
+	handler := &errorHandlerMock{}
+	c := NewFailoverClient(primary, nil, FailoverOptions{FailureThreshold: 1, EventHandler: handler})
+	for range 3 {
+		if _, err := c.GetResultBySHA256(t.Context(), "sha256"); err == nil {
+			t.Fatal("GetResultBySHA256() expected error")
+		}
+	}
+	if got := c.Active(); got != "primary" {
+		t.Errorf("Active() = %s, want primary", got)
+	}
+	if len(handler.calls) != 0 {
+		t.Errorf("unexpected events %v", handler.calls)
+	}
+}
+
+func TestNewFailoverClientFromConfig_outboundProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.Host+" "+r.Header.Get("Proxy-Authorization"))
+		_, _ = w.Write([]byte(`{"daily_quota":10,"available_daily_quota":10}`))
+	}))
+	defer proxy.Close()
+
+	config := sdk.CommonConnectorConfig{
+		GMalwareAPIURL:   "http://gmalware.invalid",
+		GMalwareAPIToken: "00000000-00000000-00000000-00000000-00000000",
+		OutboundProxyURL: proxy.URL,
+		ProxyUsername:    "user",
+		ProxyPassword:    "pass",
+	}
+	c, err := NewFailoverClientFromConfig(config, FailoverOptions{})
+	if err != nil {
+		t.Fatalf("NewFailoverClientFromConfig() error = %v", err)
+	}
+	if _, err = c.GetProfileStatus(t.Context()); err != nil {
+		t.Fatalf("GetProfileStatus() error = %v", err)
+	}
+	if diff := cmp.Diff(proxied, []string{"gmalware.invalid Basic dXNlcjpwYXNz"}); diff != "" {
+		t.Errorf("proxied requests diff(got-want)=%s", diff)
+	}
+
+	if client, err := NewHTTPClient(sdk.CommonConnectorConfig{}); client != nil || err != nil {
+		t.Errorf("NewHTTPClient() without proxy = %v, %v, want nil", client, err)
+	}
+	config.OutboundProxyURL = "proxy.example.com"
+	if _, err = NewFailoverClientFromConfig(config, FailoverOptions{}); !errors.Is(err, sdk.ErrInvalidProxyURL) {
+		t.Errorf("NewFailoverClientFromConfig() error = %v, want %v", err, sdk.ErrInvalidProxyURL)
+	}
+}
+
+func TestNewFailoverClientFromConfig_budget(t *testing.T) {
+	tests := []struct {
+		name          string
+		budget        int
+		wantRemaining int
+	}{
+		{name: "no budget", wantRemaining: -1},
+		{name: "budget", budget: 10, wantRemaining: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := sdk.CommonConnectorConfig{
+				GMalwareAPIURL:      "http://gmalware.invalid",
+				GMalwareAPIToken:    "00000000-00000000-00000000-00000000-00000000",
+				GMalwareDailyBudget: tt.budget,
+			}
+			c, err := NewFailoverClientFromConfig(config, FailoverOptions{})
+			if err != nil {
+				t.Fatalf("NewFailoverClientFromConfig() error = %v", err)
+			}
+			remaining := -1
+			if budget, ok := c.(*BudgetClient); ok {
+				remaining = budget.Remaining()
+			}
+			if remaining != tt.wantRemaining {
+				t.Errorf("NewFailoverClientFromConfig() remaining budget = %d, want %d", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestNewFailoverClientFromConfig_routing(t *testing.T) {
+	tests := []struct {
+		name        string
+		routing     sdk.GMalwareRouting
+		budget      int
+		wantRouting bool
+	}{
+		{name: "no routing"},
+		{name: "default", routing: sdk.GMalwareRouting{Default: sdk.GMalwareSyndetect}, wantRouting: true},
+		{name: "rules", routing: sdk.GMalwareRouting{Rules: []sdk.GMalwareRoutingRule{{Engine: sdk.GMalwareSyndetect, Extensions: []string{".exe"}}}}, wantRouting: true},
+		{name: "rules with budget", routing: sdk.GMalwareRouting{Rules: []sdk.GMalwareRoutingRule{{Engine: sdk.GMalwareSyndetect}}}, budget: 10, wantRouting: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := sdk.CommonConnectorConfig{
+				GMalwareAPIURL:      "http://gmalware.invalid",
+				GMalwareAPIToken:    "00000000-00000000-00000000-00000000-00000000",
+				GMalwareRouting:     tt.routing,
+				GMalwareDailyBudget: tt.budget,
+			}
+			c, err := NewFailoverClientFromConfig(config, FailoverOptions{})
+			if err != nil {
+				t.Fatalf("NewFailoverClientFromConfig() error = %v", err)
+			}
+			if budget, ok := c.(*BudgetClient); ok {
+				c = budget.GDetectSubmitter
+			}
+			if _, got := c.(*RoutingClient); got != tt.wantRouting {
+				t.Errorf("NewFailoverClientFromConfig() routing = %v, want %v", got, tt.wantRouting)
+			}
+		})
+	}
+}
+
+func TestNewFailoverClientFromConfig_userTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		userTags []string
+		budget   int
+		want     []string
+		wantErr  error
+	}{
+		{name: "no tags"},
+		{name: "templates", userTags: []string{"connector", "site:{{.Site}}", "{{.ConnectorID}}"}, want: []string{"connector", "site:finance", "c1"}},
+		{name: "templates with budget", userTags: []string{"site:{{.Site}}"}, budget: 10, want: []string{"site:finance"}},
+		{name: "invalid template", userTags: []string{"{{.Department}}"}, wantErr: ErrInvalidTagTemplate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := sdk.CommonConnectorConfig{
+				GMalwareAPIURL:      "http://gmalware.invalid",
+				GMalwareAPIToken:    "00000000-00000000-00000000-00000000-00000000",
+				GMalwareUserTags:    tt.userTags,
+				GMalwareDailyBudget: tt.budget,
+			}
+			c, err := NewFailoverClientFromConfig(config, FailoverOptions{ConnectorID: func() string { return "c1" }})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewFailoverClientFromConfig() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if budget, ok := c.(*BudgetClient); ok {
+				c = budget.GDetectSubmitter
+			}
+			var got []string
+			if tagging, ok := c.(*TaggingClient); ok {
+				got = tagging.tags(WithSubmissionContext(t.Context(), SubmissionContext{GMalwareItem: sdk.GMalwareItem{Site: "finance"}}), nil)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("NewFailoverClientFromConfig() tags diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewFailoverClientFromConfig_expertURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		config sdk.CommonConnectorConfig
+	}{
+		{name: "failover"},
+		{name: "full chain", config: sdk.CommonConnectorConfig{
+			GMalwareRouting:     sdk.GMalwareRouting{Default: sdk.GMalwareDetect, Rules: []sdk.GMalwareRoutingRule{{Engine: sdk.GMalwareSyndetect, Extensions: []string{".exe"}}}},
+			GMalwareUserTags:    []string{"connector"},
+			GMalwareDailyBudget: 10,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.GMalwareAPIURL = "http://gmalware.invalid"
+			config.GMalwareAPIToken = "00000000-00000000-00000000-00000000-00000000"
+			config.GMalwareExpertURL = "https://expert.example.com"
+			c, err := NewFailoverClientFromConfig(config, FailoverOptions{})
+			if err != nil {
+				t.Fatalf("NewFailoverClientFromConfig() error = %v", err)
+			}
+			got := NewVerdict(gdetect.Result{SID: "sid", Done: true}, c).ExpertURL
+			if want := "https://expert.example.com/expert/en/analysis/advanced/sid"; got != want {
+				t.Errorf("NewVerdict() expert url = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestNewHTTPClient_customCACerts(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"daily_quota":10,"available_daily_quota":10}`))
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	defer server.Close()
+	cert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	config := sdk.CommonConnectorConfig{
+		GMalwareAPIURL:   server.URL,
+		GMalwareAPIToken: "00000000-00000000-00000000-00000000-00000000",
+		CustomCACertsPEM: sdk.PEMBundle{cert},
+	}
+	c, err := NewFailoverClientFromConfig(config, FailoverOptions{})
+	if err != nil {
+		t.Fatalf("NewFailoverClientFromConfig() error = %v", err)
+	}
+	if _, err = c.GetProfileStatus(t.Context()); err != nil {
+		t.Fatalf("GetProfileStatus() error = %v", err)
+	}
+
+	config.CustomCACertsPEM = nil
+	if c, err = NewFailoverClientFromConfig(config, FailoverOptions{}); err != nil {
+		t.Fatalf("NewFailoverClientFromConfig() error = %v", err)
+	}
+	if _, err = c.GetProfileStatus(t.Context()); err == nil {
+		t.Error("GetProfileStatus() without custom CA, want error")
+	}
+	config.CustomCACertsPEM = sdk.PEMBundle{"certificate"}
+	if _, err = NewHTTPClient(config); !errors.Is(err, sdk.ErrInvalidCACerts) {
+		t.Errorf("NewHTTPClient() error = %v, want %v", err, sdk.ErrInvalidCACerts)
+	}
+}
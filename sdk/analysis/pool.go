@@ -0,0 +1,401 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+)
+
+// DefaultAnalysisWorkers is the default number of concurrent submissions of a Pool.
+const DefaultAnalysisWorkers = 4
+
+var (
+	ErrPoolClosed  = errors.New("submission pool closed")
+	ErrPoolStopped = errors.New("submission pool stopped")
+)
+
+var _ sdk.AnalysisStopper = &Pool{}
+
+// Job is a submission run by a Pool worker, e.g. a WaitForReader call and the handling of its result.
+// Its context is canceled when pool is stopped (see StopAnalyses) or drain times out. A requeued job is run again
+// from the start, so it must be safe to retry.
+type Job func(ctx context.Context) (err error)
+
+type PoolOptions struct {
+	// Workers is the number of jobs run concurrently (e.g. CommonConnectorConfig.AnalysisWorkers),
+	// DefaultAnalysisWorkers if 0.
+	Workers int
+	// QueueSize is the number of jobs waiting for a worker before Submit blocks, Workers if 0.
+	QueueSize int
+	// Metrics, if set, records pool queue (e.g. ConnectorManagerClient.AnalysisMetrics).
+	Metrics *metrics.AnalysisMetrics
+	// Budget, if set, is the BudgetClient jobs submit with. Once a job fails with ErrBudgetExceeded, pool is
+	// throttled: the job is deferred, and workers wait for budget reset (Budget.ResetAt) before running it and
+	// queued ones, Submit blocking once queue is full.
+	Budget *BudgetClient
+	// EventHandler, if set, is notified a QuotaBudgetError when pool is throttled, resolved when it resumes.
+	EventHandler events.EventErrorHandler
+}
+
+// Pool runs submission jobs on a bounded number of workers, so connectors get consistent, tunable parallelism.
+// Job errors are logged and recorded in metrics, jobs report their own errors to console.
+//
+// Pool implements sdk.AnalysisStopper: a connector embedding it has its in-flight jobs handled per policy of stop
+// tasks, and run again on start.
+type Pool struct {
+	jobs       chan Job
+	workers    int
+	metrics    *metrics.AnalysisMetrics
+	budget     *BudgetClient
+	handler    events.EventErrorHandler
+	running    sync.WaitGroup // workers
+	submitting sync.WaitGroup // Submit calls
+
+	lock     sync.Mutex
+	closed   bool
+	stopped  bool
+	stopping chan struct{} // closed when pool is stopped, releasing blocked Submit calls
+	quit     chan struct{} // closed when workers must exit
+	cancel   context.CancelFunc
+	requeue  bool  // interrupted jobs are requeued rather than abandoned
+	requeued []Job // jobs to run before queued ones
+	inflight int   // queued, requeued and running jobs
+	idle     chan struct{}
+	report   sdk.StopReport
+	until    time.Time // end of throttling, zero if pool is not throttled
+}
+
+// NewPool starts pool workers.
+func NewPool(opts PoolOptions) (p *Pool) {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultAnalysisWorkers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = opts.Workers
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = &metrics.AnalysisMetrics{}
+	}
+	p = &Pool{
+		jobs:    make(chan Job, opts.QueueSize),
+		workers: opts.Workers,
+		metrics: opts.Metrics,
+		budget:  opts.Budget,
+		handler: opts.EventHandler,
+	}
+	p.metrics.SetWorkers(int64(opts.Workers))
+	p.lock.Lock()
+	p.start()
+	p.lock.Unlock()
+	return
+}
+
+// start starts workers, p.lock must be held.
+func (p *Pool) start() {
+	p.stopped = false
+	p.requeue = false
+	p.stopping = make(chan struct{})
+	quit := make(chan struct{})
+	p.quit = quit
+	var ctx context.Context
+	ctx, p.cancel = context.WithCancel(context.Background())
+	for range p.workers {
+		p.running.Go(func() {
+			p.work(ctx, quit)
+		})
+	}
+}
+
+// next returns next job to run, requeued ones first, ok being false once quit is closed.
+func (p *Pool) next(ctx context.Context, quit <-chan struct{}) (job Job, ok bool) {
+	select {
+	case <-quit:
+		return
+	default:
+	}
+	if !p.waitThrottled(ctx, quit) {
+		return
+	}
+	p.lock.Lock()
+	if len(p.requeued) > 0 {
+		job = p.requeued[0]
+		p.requeued = p.requeued[1:]
+		p.lock.Unlock()
+		ok = true
+		return
+	}
+	p.lock.Unlock()
+	select {
+	case job = <-p.jobs:
+		ok = true
+	case <-quit:
+	}
+	return
+}
+
+func (p *Pool) work(ctx context.Context, quit <-chan struct{}) {
+	for {
+		job, ok := p.next(ctx, quit)
+		if !ok {
+			return
+		}
+		p.metrics.AddQueued(-1)
+		p.metrics.AddRunning(1)
+		start := time.Now()
+		err := job(ctx)
+		p.metrics.AddRunning(-1)
+		interrupted := err != nil && ctx.Err() != nil
+
+		if p.deferred(ctx, job, err) {
+			continue
+		}
+
+		p.lock.Lock()
+		if interrupted && p.requeue {
+			p.requeued = append(p.requeued, job)
+			p.lock.Unlock()
+			p.metrics.AddQueued(1)
+			continue
+		}
+		switch {
+		case interrupted:
+			p.report.Abandoned++
+		case p.stopped:
+			p.report.Completed++
+		}
+		p.release(1)
+		p.lock.Unlock()
+
+		p.metrics.ObserveSubmission(time.Since(start), err != nil)
+		if err != nil && !interrupted {
+			logger.Warn("analysis job failed", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// deferred defers job if it failed as budget is consumed, throttling pool until budget reset.
+func (p *Pool) deferred(ctx context.Context, job Job, err error) (ok bool) {
+	if p.budget == nil || !errors.Is(err, ErrBudgetExceeded) {
+		return
+	}
+	ok = true
+	until := p.budget.ResetAt()
+	p.lock.Lock()
+	p.requeued = append(p.requeued, job)
+	throttled := !p.until.IsZero()
+	if !throttled {
+		p.until = until
+	}
+	p.lock.Unlock()
+	p.metrics.AddQueued(1)
+	if throttled {
+		return
+	}
+	logger.Warn("daily submission budget consumed, analyses deferred", slog.String("until", until.Format(time.RFC3339)))
+	if p.handler != nil {
+		budgetErr := fmt.Errorf("%w, analyses deferred until %s", ErrBudgetExceeded, until.Format(time.RFC3339))
+		if notifyErr := p.handler.NotifyError(ctx, events.QuotaBudgetError, budgetErr); notifyErr != nil {
+			logger.Error("could not notify budget exceeded", slog.String("error", notifyErr.Error()))
+		}
+	}
+	return
+}
+
+// waitThrottled waits for end of throttling, if pool is throttled. ok is false if quit is closed first.
+func (p *Pool) waitThrottled(ctx context.Context, quit <-chan struct{}) (ok bool) {
+	p.lock.Lock()
+	until := p.until
+	p.lock.Unlock()
+	if until.IsZero() {
+		ok = true
+		return
+	}
+	timer := time.NewTimer(time.Until(until))
+	defer timer.Stop()
+	select {
+	case <-quit:
+		return
+	case <-timer.C:
+	}
+	ok = true
+	p.lock.Lock()
+	resumed := p.until.Equal(until)
+	if resumed {
+		p.until = time.Time{}
+	}
+	p.lock.Unlock()
+	if !resumed {
+		return
+	}
+	logger.Info("daily submission budget reset, analyses resumed")
+	if p.handler != nil {
+		if err := p.handler.NotifyResolution(ctx, "daily submission budget reset, analyses resumed", events.QuotaBudgetError); err != nil {
+			logger.Error("could not notify budget reset", slog.String("error", err.Error()))
+		}
+	}
+	return
+}
+
+// ThrottledUntil returns when analyses deferred as budget is consumed are resumed, zero if pool is not throttled.
+func (p *Pool) ThrottledUntil() (until time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	until = p.until
+	return
+}
+
+// release forgets n finished jobs, p.lock must be held.
+func (p *Pool) release(n int) {
+	p.inflight -= n
+	if p.inflight == 0 && p.idle != nil {
+		close(p.idle)
+		p.idle = nil
+	}
+}
+
+// Submit queues job, waiting for room in queue until ctx is done. It returns ErrPoolStopped while pool is stopped,
+// and ErrPoolClosed once Drain is called.
+func (p *Pool) Submit(ctx context.Context, job Job) (err error) {
+	p.lock.Lock()
+	switch {
+	case p.closed:
+		err = ErrPoolClosed
+	case p.stopped:
+		err = ErrPoolStopped
+	}
+	if err != nil {
+		p.lock.Unlock()
+		return
+	}
+	stopping := p.stopping
+	p.inflight++
+	p.submitting.Add(1)
+	p.lock.Unlock()
+	defer p.submitting.Done()
+
+	p.metrics.AddQueued(1)
+	select {
+	case p.jobs <- job:
+		return
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-stopping:
+		err = ErrPoolStopped
+	}
+	p.metrics.AddQueued(-1)
+	p.lock.Lock()
+	p.release(1)
+	p.lock.Unlock()
+	return
+}
+
+// StopAnalyses stops pool workers, in-flight jobs being handled per policy (see sdk.StopPolicy), until
+// ResumeAnalyses. With sdk.StopWait, it waits up to timeout (or until ctx is done) for queued and running jobs.
+// It returns ErrPoolStopped, wrapping sdk.ErrAnalysesStopped, if pool is already stopped.
+func (p *Pool) StopAnalyses(ctx context.Context, policy sdk.StopPolicy, timeout time.Duration) (report sdk.StopReport, err error) {
+	p.lock.Lock()
+	if p.closed || p.stopped {
+		p.lock.Unlock()
+		err = fmt.Errorf("%w, %w", ErrPoolStopped, sdk.ErrAnalysesStopped)
+		return
+	}
+	p.stopped = true
+	close(p.stopping)
+	p.requeue = policy == sdk.StopRequeue
+	p.report = sdk.StopReport{Policy: policy}
+	p.lock.Unlock()
+	// no job is queued from now on
+	p.submitting.Wait()
+
+	if policy == sdk.StopWait {
+		waitCtx := ctx
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			waitCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		p.lock.Lock()
+		idle := make(chan struct{})
+		if p.inflight == 0 {
+			close(idle)
+		} else {
+			p.idle = idle
+		}
+		p.lock.Unlock()
+		select {
+		case <-idle:
+		case <-waitCtx.Done():
+		}
+		p.lock.Lock()
+		p.idle = nil
+		p.lock.Unlock()
+	}
+
+	// interrupt running jobs
+	p.cancel()
+	close(p.quit)
+	p.running.Wait()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.requeue {
+		p.abandonQueued()
+	}
+	p.report.Requeued = len(p.requeued) + len(p.jobs)
+	report = p.report
+	return
+}
+
+// abandonQueued drops queued and requeued jobs, p.lock must be held and workers stopped.
+func (p *Pool) abandonQueued() {
+	n := len(p.requeued)
+	p.requeued = nil
+	for len(p.jobs) > 0 {
+		<-p.jobs
+		n++
+	}
+	p.metrics.AddQueued(-int64(n))
+	p.report.Abandoned += n
+	p.release(n)
+}
+
+// ResumeAnalyses restarts workers of a stopped pool, requeued jobs being run first.
+func (p *Pool) ResumeAnalyses() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed || !p.stopped {
+		return
+	}
+	p.start()
+}
+
+// Drain stops accepting jobs and waits for queued and running ones to finish. If ctx is done first, running jobs
+// context is canceled, and Drain returns ctx error once workers stopped. Jobs requeued by a previous stop are
+// dropped.
+func (p *Pool) Drain(ctx context.Context) (err error) {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return
+	}
+	stopped := p.stopped
+	p.lock.Unlock()
+
+	if !stopped {
+		if _, stopErr := p.StopAnalyses(ctx, sdk.StopWait, 0); stopErr == nil {
+			err = ctx.Err()
+		}
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.closed = true
+	p.abandonQueued()
+	return
+}
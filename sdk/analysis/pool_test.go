@@ -0,0 +1,259 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+	gdetectmock "github.com/glimps-re/go-gdetect/pkg/gdetect/mock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPool(t *testing.T) {
+	m := &metrics.AnalysisMetrics{}
+	p := NewPool(PoolOptions{Workers: 2, QueueSize: 4, Metrics: m})
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 6)
+	var running, maxRunning atomic.Int64
+	job := func(fail bool) Job {
+		return func(ctx context.Context) (err error) {
+			n := running.Add(1)
+			if n > maxRunning.Load() {
+				maxRunning.Store(n)
+			}
+			started <- struct{}{}
+			<-release
+			running.Add(-1)
+			if fail {
+				err = errors.New("submission failed")
+			}
+			return
+		}
+	}
+	for i := range 6 {
+		if err := p.Submit(t.Context(), job(i == 0)); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+	<-started
+	<-started
+	if s := m.Snapshot(); s.Workers != 2 || s.Running != 2 || s.Queued != 4 {
+		t.Errorf("Snapshot() workers = %d, running = %d, queued = %d, want 2, 2, 4", s.Workers, s.Running, s.Queued)
+	}
+
+	// queue full
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Submit(ctx, job(false)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Submit() on full queue error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	if err := p.Drain(t.Context()); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	if got := maxRunning.Load(); got != 2 {
+		t.Errorf("max running jobs = %d, want 2", got)
+	}
+	s := m.Snapshot()
+	if s.Running != 0 || s.Queued != 0 || s.Completed != 5 || s.Failed != 1 || s.Duration.Count != 6 {
+		t.Errorf("Snapshot() after drain = %+v, want 5 completed and 1 failed", s)
+	}
+	if err := p.Submit(t.Context(), job(false)); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Submit() after drain error = %v, want %v", err, ErrPoolClosed)
+	}
+}
+
+func TestPool_Drain_timeout(t *testing.T) {
+	p := NewPool(PoolOptions{Workers: 1})
+	started := make(chan struct{})
+	canceled := false
+	if err := p.Submit(t.Context(), func(ctx context.Context) (err error) {
+		close(started)
+		<-ctx.Done()
+		canceled = true
+		err = ctx.Err()
+		return
+	}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Drain() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !canceled {
+		t.Errorf("running job context not canceled by Drain()")
+	}
+}
+
+func TestPool_StopAnalyses(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  sdk.StopPolicy
+		timeout time.Duration
+		// release jobs once pool is stopped, they last until canceled otherwise
+		release    bool
+		wantReport sdk.StopReport
+		wantRuns   int64 // job runs, including ones after resume
+	}{
+		{
+			name:       "wait for in-flight jobs",
+			policy:     sdk.StopWait,
+			timeout:    5 * time.Second,
+			release:    true,
+			wantReport: sdk.StopReport{Policy: sdk.StopWait, Completed: 3},
+			wantRuns:   3,
+		},
+		{
+			name:       "wait timeout abandons remaining jobs",
+			policy:     sdk.StopWait,
+			timeout:    20 * time.Millisecond,
+			wantReport: sdk.StopReport{Policy: sdk.StopWait, Abandoned: 3},
+			wantRuns:   1,
+		},
+		{
+			name:       "abandon",
+			policy:     sdk.StopAbandon,
+			wantReport: sdk.StopReport{Policy: sdk.StopAbandon, Abandoned: 3},
+			wantRuns:   1,
+		},
+		{
+			name:       "requeue",
+			policy:     sdk.StopRequeue,
+			wantReport: sdk.StopReport{Policy: sdk.StopRequeue, Requeued: 3},
+			// running job is run again
+			wantRuns: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &metrics.AnalysisMetrics{}
+			p := NewPool(PoolOptions{Workers: 1, QueueSize: 2, Metrics: m})
+			release := make(chan struct{})
+			started := make(chan struct{}, 10)
+			var runs atomic.Int64
+			for range 3 {
+				if err := p.Submit(t.Context(), func(ctx context.Context) (err error) {
+					runs.Add(1)
+					started <- struct{}{}
+					select {
+					case <-release:
+					case <-ctx.Done():
+						err = ctx.Err()
+					}
+					return
+				}); err != nil {
+					t.Fatalf("Submit() error = %v", err)
+				}
+			}
+			<-started
+
+			if tt.release {
+				go func() {
+					// Submit fails fast on full queue with a done context, until pool is stopped
+					done, cancel := context.WithCancel(t.Context())
+					cancel()
+					for !errors.Is(p.Submit(done, nil), ErrPoolStopped) {
+						time.Sleep(time.Millisecond)
+					}
+					close(release)
+				}()
+			}
+			got, err := p.StopAnalyses(t.Context(), tt.policy, tt.timeout)
+			if err != nil {
+				t.Fatalf("StopAnalyses() error = %v", err)
+			}
+			if diff := cmp.Diff(got, tt.wantReport); diff != "" {
+				t.Errorf("StopAnalyses() diff(got-want)=%s", diff)
+			}
+			if err := p.Submit(t.Context(), nil); !errors.Is(err, ErrPoolStopped) {
+				t.Errorf("Submit() on stopped pool error = %v, want %v", err, ErrPoolStopped)
+			}
+			if _, err := p.StopAnalyses(t.Context(), tt.policy, tt.timeout); !errors.Is(err, ErrPoolStopped) || !errors.Is(err, sdk.ErrAnalysesStopped) {
+				t.Errorf("StopAnalyses() on stopped pool error = %v, want %v", err, sdk.ErrAnalysesStopped)
+			}
+
+			if !tt.release {
+				close(release)
+			}
+			p.ResumeAnalyses()
+			if err := p.Drain(t.Context()); err != nil {
+				t.Fatalf("Drain() error = %v", err)
+			}
+			if got := runs.Load(); got != tt.wantRuns {
+				t.Errorf("job runs = %d, want %d", got, tt.wantRuns)
+			}
+			if s := m.Snapshot(); s.Queued != 0 || s.Running != 0 {
+				t.Errorf("Snapshot() queued = %d, running = %d, want 0, 0", s.Queued, s.Running)
+			}
+		})
+	}
+}
+
+// budgetHandlerMock moves clock to next day when budget exceeded is notified.
+type budgetHandlerMock struct {
+	errorHandlerMock
+	now *atomic.Pointer[time.Time]
+}
+
+func (h *budgetHandlerMock) NotifyError(ctx context.Context, errorType events.ErrorEventType, e error) (err error) {
+	next := h.now.Load().Add(24 * time.Hour)
+	h.now.Store(&next)
+	return h.errorHandlerMock.NotifyError(ctx, errorType, e)
+}
+
+func TestPool_throttled(t *testing.T) {
+	now := &atomic.Pointer[time.Time]{}
+	// budget resets are in the past, pool resumes right away
+	start := time.Date(2020, 1, 14, 10, 0, 0, 0, time.UTC)
+	now.Store(&start)
+	m := &metrics.AnalysisMetrics{}
+	budget := NewBudgetClient(&gdetectmock.MockGDetectSubmitter{
+		WaitForReaderMock: func(ctx context.Context, r io.Reader, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+			return
+		},
+	}, BudgetOptions{Daily: 2, Metrics: m})
+	budget.now = func() time.Time { return *now.Load() }
+	handler := &budgetHandlerMock{now: now}
+	p := NewPool(PoolOptions{Workers: 1, QueueSize: 4, Metrics: m, Budget: budget, EventHandler: handler})
+
+	var completed atomic.Int64
+	for range 3 {
+		err := p.Submit(t.Context(), func(ctx context.Context) (err error) {
+			if _, err = budget.WaitForReader(ctx, strings.NewReader("file"), gdetect.WaitForOptions{}); err == nil {
+				completed.Add(1)
+			}
+			return
+		})
+		if err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+	if err := p.Drain(t.Context()); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	if got := completed.Load(); got != 3 {
+		t.Errorf("completed jobs = %d, want 3", got)
+	}
+	if diff := cmp.Diff(handler.calls, []string{"error quota-budget", "resolution quota-budget"}); diff != "" {
+		t.Errorf("notified events diff(got-want)=%s", diff)
+	}
+	if s := m.Snapshot(); s.Failed != 0 || s.BudgetRejected != 1 || s.Queued != 0 {
+		t.Errorf("Snapshot() failed = %d, budget rejected = %d, queued = %d, want 0, 1, 0", s.Failed, s.BudgetRejected, s.Queued)
+	}
+	if !p.ThrottledUntil().IsZero() {
+		t.Errorf("ThrottledUntil() = %s, want zero", p.ThrottledUntil())
+	}
+}
@@ -0,0 +1,93 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+)
+
+var (
+	_ gdetect.GDetectSubmitter = &ProfileClient{}
+	_ ExtendedSubmitterGetter  = &ProfileClient{}
+)
+
+// ProfileClient is a GDetectSubmitter submitting items to the client of their GLIMPS Malware profile, selected for
+// the item of their submission context (see WithSubmissionContext). Items of no known profile, and other requests
+// (e.g. GetResultByUUID), go to default client, so callers should prefer WaitForFile/WaitForReader to
+// submit-then-poll.
+type ProfileClient struct {
+	gdetect.GDetectSubmitter
+	profiles      map[string]gdetect.GDetectSubmitter
+	selectProfile func(item sdk.GMalwareItem) sdk.GMalwareProfile
+}
+
+// NewProfileClient returns a ProfileClient, profiles being clients by profile name and selectProfile being e.g.
+// sdk.CommonConnectorConfig.SelectGMalwareProfile.
+func NewProfileClient(defaultClient gdetect.GDetectSubmitter, profiles map[string]gdetect.GDetectSubmitter, selectProfile func(item sdk.GMalwareItem) sdk.GMalwareProfile) (c *ProfileClient) {
+	c = &ProfileClient{
+		GDetectSubmitter: defaultClient,
+		profiles:         profiles,
+		selectProfile:    selectProfile,
+	}
+	return
+}
+
+// newProfileClientFromConfig creates clients of default profile and of GMalwareProfiles of config, each as
+// NewFailoverClientFromConfig without profiles and budget would, with GLIMPS Malware settings of its profile.
+// Profiles have no fallback endpoint.
+func newProfileClientFromConfig(config sdk.CommonConnectorConfig, opts FailoverOptions) (c *ProfileClient, err error) {
+	defaultClient, err := newUnbudgetedClientFromConfig(config, opts)
+	if err != nil {
+		return
+	}
+	profiles := make(map[string]gdetect.GDetectSubmitter, len(config.GMalwareProfiles))
+	for _, profile := range config.GMalwareProfiles {
+		profileConfig := config
+		profileConfig.GMalwareAPIURL = profile.APIURL
+		profileConfig.GMalwareAPIToken = profile.APIToken
+		profileConfig.GMalwareNoCertCheck = profile.NoCertCheck
+		profileConfig.GMalwareUserTags = profile.UserTags
+		profileConfig.GMalwareExpertURL = ""
+		profileConfig.GMalwareFallbackAPIURL = ""
+		profileConfig.GMalwareFallbackAPIToken = ""
+		if profiles[profile.Name], err = newUnbudgetedClientFromConfig(profileConfig, opts); err != nil {
+			err = fmt.Errorf("could not create client of profile %s, %w", profile.Name, err)
+			return
+		}
+	}
+	c = NewProfileClient(defaultClient, profiles, config.SelectGMalwareProfile)
+	return
+}
+
+// submitter returns client of profile of submission of ctx.
+func (c *ProfileClient) submitter(ctx context.Context) gdetect.GDetectSubmitter {
+	profile := c.selectProfile(SubmissionContextFromContext(ctx).GMalwareItem)
+	if submitter, ok := c.profiles[profile.Name]; ok {
+		return submitter
+	}
+	return c.GDetectSubmitter
+}
+
+func (c *ProfileClient) SubmitFile(ctx context.Context, file string, options gdetect.SubmitOptions) (uuid string, err error) {
+	return c.submitter(ctx).SubmitFile(ctx, file, options)
+}
+
+func (c *ProfileClient) SubmitReader(ctx context.Context, r io.Reader, options gdetect.SubmitOptions) (uuid string, err error) {
+	return c.submitter(ctx).SubmitReader(ctx, r, options)
+}
+
+func (c *ProfileClient) WaitForFile(ctx context.Context, file string, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+	return c.submitter(ctx).WaitForFile(ctx, file, options)
+}
+
+func (c *ProfileClient) WaitForReader(ctx context.Context, r io.Reader, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+	return c.submitter(ctx).WaitForReader(ctx, r, options)
+}
+
+// ExtendedSubmitter returns the default client, as for other requests than submissions.
+func (c *ProfileClient) ExtendedSubmitter() (extended gdetect.ExtendedGDetectSubmitter, ok bool) {
+	return ExtendedSubmitter(c.GDetectSubmitter)
+}
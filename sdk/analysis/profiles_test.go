@@ -0,0 +1,82 @@
+package analysis
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+	gdetectmock "github.com/glimps-re/go-gdetect/pkg/gdetect/mock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProfileClient(t *testing.T) {
+	var submitted []string
+	submitter := func(profile string) *gdetectmock.MockGDetectSubmitter {
+		return &gdetectmock.MockGDetectSubmitter{
+			WaitForReaderMock: func(ctx context.Context, r io.Reader, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+				submitted = append(submitted, profile)
+				return
+			},
+		}
+	}
+	config := sdk.CommonConnectorConfig{GMalwareProfiles: []sdk.GMalwareProfile{
+		{Name: "finance", Sites: []string{"finance"}},
+		{Name: "hr", Paths: []string{"/data/hr"}},
+	}}
+	// hr has no client
+	c := NewProfileClient(submitter(sdk.DefaultGMalwareProfileName), map[string]gdetect.GDetectSubmitter{"finance": submitter("finance")}, config.SelectGMalwareProfile)
+	for _, item := range []sdk.GMalwareItem{{Site: "finance"}, {Path: "/data/hr/file"}, {Path: "/data/it/file"}} {
+		ctx := WithSubmissionContext(t.Context(), SubmissionContext{GMalwareItem: item})
+		if _, err := c.WaitForReader(ctx, strings.NewReader("content"), gdetect.WaitForOptions{}); err != nil {
+			t.Fatalf("WaitForReader() error = %v", err)
+		}
+	}
+	if diff := cmp.Diff(submitted, []string{"finance", sdk.DefaultGMalwareProfileName, sdk.DefaultGMalwareProfileName}); diff != "" {
+		t.Errorf("submissions diff(got-want)=%s", diff)
+	}
+}
+
+func TestNewFailoverClientFromConfig_profiles(t *testing.T) {
+	var lock sync.Mutex
+	var submitted []string // "endpoint token"
+	endpoint := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			submitted = append(submitted, name+" "+r.Header.Get("X-Auth-Token"))
+			lock.Unlock()
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+	}
+	defaultEndpoint, financeEndpoint := endpoint("default"), endpoint("finance")
+	defer defaultEndpoint.Close()
+	defer financeEndpoint.Close()
+	const (
+		defaultToken = "00000000-00000000-00000000-00000000-00000000"
+		financeToken = "11111111-11111111-11111111-11111111-11111111"
+	)
+	config := sdk.CommonConnectorConfig{
+		GMalwareAPIURL:      defaultEndpoint.URL,
+		GMalwareAPIToken:    defaultToken,
+		GMalwareDailyBudget: 10,
+		GMalwareProfiles:    []sdk.GMalwareProfile{{Name: "finance", APIURL: financeEndpoint.URL, APIToken: financeToken, Sites: []string{"finance"}}},
+	}
+	c, err := NewFailoverClientFromConfig(config, FailoverOptions{})
+	if err != nil {
+		t.Fatalf("NewFailoverClientFromConfig() error = %v", err)
+	}
+	for _, site := range []string{"finance", "it"} {
+		ctx := WithSubmissionContext(t.Context(), SubmissionContext{GMalwareItem: sdk.GMalwareItem{Site: site}})
+		if _, err = c.SubmitReader(ctx, strings.NewReader("content"), gdetect.SubmitOptions{}); err == nil {
+			t.Fatalf("SubmitReader() error = nil, want rejected submission")
+		}
+	}
+	if diff := cmp.Diff(submitted, []string{"finance " + financeToken, "default " + defaultToken}); diff != "" {
+		t.Errorf("submissions diff(got-want)=%s", diff)
+	}
+}
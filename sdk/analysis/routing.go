@@ -0,0 +1,97 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+)
+
+var (
+	_ gdetect.GDetectSubmitter = &RoutingClient{}
+	_ ExtendedSubmitterGetter  = &RoutingClient{}
+)
+
+// RoutingClient is a GDetectSubmitter submitting items to detect or syndetect, the engine being decided by route for
+// the item of their submission context (see WithSubmissionContext). Other requests (e.g. GetResultByUUID) go to
+// detect, so callers should prefer WaitForFile/WaitForReader to submit-then-poll.
+type RoutingClient struct {
+	gdetect.GDetectSubmitter
+	syndetect gdetect.GDetectSubmitter
+	route     func(item sdk.GMalwareItem) sdk.GMalwareEngine
+}
+
+// NewRoutingClient returns a RoutingClient, route being e.g. sdk.CommonConnectorConfig.SelectGMalwareEngine.
+func NewRoutingClient(detect gdetect.GDetectSubmitter, syndetect gdetect.GDetectSubmitter, route func(item sdk.GMalwareItem) sdk.GMalwareEngine) (c *RoutingClient) {
+	c = &RoutingClient{
+		GDetectSubmitter: detect,
+		syndetect:        syndetect,
+		route:            route,
+	}
+	return
+}
+
+// NewRoutingClientFromConfig creates failover clients (see NewFailoverClientFromConfig) to detect and syndetect,
+// and routes items between them with routing of config (see sdk.CommonConnectorConfig.SelectGMalwareEngine).
+func NewRoutingClientFromConfig(config sdk.CommonConnectorConfig, opts FailoverOptions) (c *RoutingClient, err error) {
+	detect, err := newFailoverClientFromConfig(config, false, opts)
+	if err != nil {
+		err = fmt.Errorf("could not create detect client, %w", err)
+		return
+	}
+	syndetect, err := newFailoverClientFromConfig(config, true, opts)
+	if err != nil {
+		err = fmt.Errorf("could not create syndetect client, %w", err)
+		return
+	}
+	c = NewRoutingClient(detect, syndetect, config.SelectGMalwareEngine)
+	return
+}
+
+// submitter returns submitter of submission of ctx, file being the submitted file if any and filename the one set
+// in submission options.
+func (c *RoutingClient) submitter(ctx context.Context, file string, filename string) gdetect.GDetectSubmitter {
+	item := SubmissionContextFromContext(ctx).GMalwareItem
+	if item.Filename == "" {
+		item.Filename = filename
+	}
+	if file != "" {
+		if item.Filename == "" && item.Path == "" {
+			item.Filename = filepath.Base(file)
+		}
+		if item.Size == 0 {
+			if info, err := os.Stat(file); err == nil {
+				item.Size = info.Size()
+			}
+		}
+	}
+	if c.route(item) == sdk.GMalwareSyndetect {
+		return c.syndetect
+	}
+	return c.GDetectSubmitter
+}
+
+func (c *RoutingClient) SubmitFile(ctx context.Context, file string, options gdetect.SubmitOptions) (uuid string, err error) {
+	return c.submitter(ctx, file, options.Filename).SubmitFile(ctx, file, options)
+}
+
+func (c *RoutingClient) SubmitReader(ctx context.Context, r io.Reader, options gdetect.SubmitOptions) (uuid string, err error) {
+	return c.submitter(ctx, "", options.Filename).SubmitReader(ctx, r, options)
+}
+
+func (c *RoutingClient) WaitForFile(ctx context.Context, file string, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+	return c.submitter(ctx, file, options.Filename).WaitForFile(ctx, file, options)
+}
+
+func (c *RoutingClient) WaitForReader(ctx context.Context, r io.Reader, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+	return c.submitter(ctx, "", options.Filename).WaitForReader(ctx, r, options)
+}
+
+// ExtendedSubmitter returns the detect client, as for other requests than submissions.
+func (c *RoutingClient) ExtendedSubmitter() (extended gdetect.ExtendedGDetectSubmitter, ok bool) {
+	return ExtendedSubmitter(c.GDetectSubmitter)
+}
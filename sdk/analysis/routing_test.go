@@ -0,0 +1,66 @@
+package analysis
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+	gdetectmock "github.com/glimps-re/go-gdetect/pkg/gdetect/mock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRoutingClient(t *testing.T) {
+	var submitted []string // "engine name"
+	submitter := func(engine string) *gdetectmock.MockGDetectSubmitter {
+		return &gdetectmock.MockGDetectSubmitter{
+			SubmitReaderMock: func(ctx context.Context, r io.Reader, options gdetect.SubmitOptions) (uuid string, err error) {
+				submitted = append(submitted, engine+" "+options.Filename)
+				return
+			},
+			WaitForFileMock: func(ctx context.Context, file string, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+				submitted = append(submitted, engine+" "+filepath.Base(file))
+				return
+			},
+		}
+	}
+	config := sdk.CommonConnectorConfig{GMalwareRouting: sdk.GMalwareRouting{Rules: []sdk.GMalwareRoutingRule{
+		{Engine: sdk.GMalwareSyndetect, Extensions: []string{"docm"}},
+		{Engine: sdk.GMalwareSyndetect, MaxSize: "10B", Sites: []string{"finance"}},
+	}}}
+	c := NewRoutingClient(submitter("detect"), submitter("syndetect"), config.SelectGMalwareEngine)
+
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.bin")
+	if err := os.WriteFile(small, []byte("content"), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	finance := WithSubmissionContext(t.Context(), SubmissionContext{GMalwareItem: sdk.GMalwareItem{Site: "finance"}})
+
+	if _, err := c.SubmitReader(t.Context(), strings.NewReader("content"), gdetect.SubmitOptions{Filename: "report.docm"}); err != nil {
+		t.Fatalf("SubmitReader() error = %v", err)
+	}
+	if _, err := c.SubmitReader(finance, strings.NewReader("content"), gdetect.SubmitOptions{Filename: "report.pdf"}); err != nil {
+		t.Fatalf("SubmitReader() error = %v", err)
+	}
+	if _, err := c.WaitForFile(finance, small, gdetect.WaitForOptions{}); err != nil {
+		t.Fatalf("WaitForFile() error = %v", err)
+	}
+	if _, err := c.WaitForFile(t.Context(), small, gdetect.WaitForOptions{}); err != nil {
+		t.Fatalf("WaitForFile() error = %v", err)
+	}
+	want := []string{
+		"syndetect report.docm",
+		// size of reader unknown
+		"detect report.pdf",
+		"syndetect small.bin",
+		"detect small.bin",
+	}
+	if diff := cmp.Diff(submitted, want); diff != "" {
+		t.Errorf("submissions diff(got-want)=%s", diff)
+	}
+}
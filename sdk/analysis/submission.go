@@ -0,0 +1,27 @@
+package analysis
+
+import (
+	"context"
+
+	"github.com/glimps-re/connector-integration/sdk"
+)
+
+// SubmissionContext is the source context of a submission, used to evaluate user tags templates (e.g. "site:{{.Site}}",
+// "{{.ConnectorID}}", see TaggingClient) and to route it (see RoutingClient). Connectors set the attributes they know about.
+type SubmissionContext struct {
+	sdk.GMalwareItem
+	ConnectorID string
+}
+
+type submissionContextKey struct{}
+
+// WithSubmissionContext returns a copy of ctx carrying sc, used for submissions made with it.
+func WithSubmissionContext(ctx context.Context, sc SubmissionContext) context.Context {
+	return context.WithValue(ctx, submissionContextKey{}, sc)
+}
+
+// SubmissionContextFromContext returns submission context set in ctx by WithSubmissionContext, zero if none.
+func SubmissionContextFromContext(ctx context.Context) (sc SubmissionContext) {
+	sc, _ = ctx.Value(submissionContextKey{}).(SubmissionContext)
+	return
+}
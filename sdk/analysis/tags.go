@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+)
+
+// ErrInvalidTagTemplate is returned for user tags that are not valid tag templates.
+var ErrInvalidTagTemplate = errors.New("invalid tag template")
+
+// TagPolicy evaluates user tags (e.g. GMalwareUserTags) per submission. Tags are text/template templates,
+// tags without action being static. It is safe for concurrent use.
+type TagPolicy struct {
+	templates []*template.Template
+}
+
+// NewTagPolicy parses tags templates, returning ErrInvalidTagTemplate for invalid ones (e.g. unknown SubmissionContext field).
+func NewTagPolicy(tags []string) (p *TagPolicy, err error) {
+	p = &TagPolicy{}
+	for _, tag := range tags {
+		tmpl, parseErr := template.New("tag").Option("missingkey=error").Parse(tag)
+		if parseErr != nil {
+			err = fmt.Errorf("%w %q: %w", ErrInvalidTagTemplate, tag, parseErr)
+			return
+		}
+		// unknown fields are only reported on execution
+		if execErr := tmpl.Execute(io.Discard, SubmissionContext{}); execErr != nil {
+			err = fmt.Errorf("%w %q: %w", ErrInvalidTagTemplate, tag, execErr)
+			return
+		}
+		p.templates = append(p.templates, tmpl)
+	}
+	return
+}
+
+// Tags returns tags of a submission of context sc. Tags evaluated to an empty string (e.g. "{{.Site}}", or
+// "{{with .Site}}site:{{.}}{{end}}", for items without site) are dropped, as are duplicates.
+func (p *TagPolicy) Tags(sc SubmissionContext) (tags []string) {
+	tags = []string{}
+	for _, tmpl := range p.templates {
+		b := strings.Builder{}
+		if err := tmpl.Execute(&b, sc); err != nil {
+			logger.Warn("could not evaluate tag template", slog.String("error", err.Error()))
+			continue
+		}
+		tag := strings.TrimSpace(b.String())
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return
+}
+
+var (
+	_ gdetect.GDetectSubmitter = &TaggingClient{}
+	_ ExtendedSubmitterGetter  = &TaggingClient{}
+)
+
+// TaggingClient is a GDetectSubmitter adding tags of its TagPolicy to submissions, evaluated with submission
+// context of their context (see WithSubmissionContext). Tags set in submission options are kept.
+type TaggingClient struct {
+	gdetect.GDetectSubmitter
+	policy      *TagPolicy
+	connectorID func() string
+}
+
+// NewTaggingClient returns a TaggingClient submitting to submitter. connectorID, if not nil, gives ConnectorID of
+// submission contexts not setting it, e.g. sdk.ConnectorManagerClient.ConnectorID.
+func NewTaggingClient(submitter gdetect.GDetectSubmitter, policy *TagPolicy, connectorID func() string) (c *TaggingClient) {
+	c = &TaggingClient{
+		GDetectSubmitter: submitter,
+		policy:           policy,
+		connectorID:      connectorID,
+	}
+	return
+}
+
+// tags returns tags of submission of ctx, after tags already set.
+func (c *TaggingClient) tags(ctx context.Context, tags []string) []string {
+	sc := SubmissionContextFromContext(ctx)
+	if sc.ConnectorID == "" && c.connectorID != nil {
+		sc.ConnectorID = c.connectorID()
+	}
+	tags = slices.Clone(tags)
+	for _, tag := range c.policy.Tags(sc) {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func (c *TaggingClient) SubmitFile(ctx context.Context, filepath string, options gdetect.SubmitOptions) (uuid string, err error) {
+	options.Tags = c.tags(ctx, options.Tags)
+	return c.GDetectSubmitter.SubmitFile(ctx, filepath, options)
+}
+
+func (c *TaggingClient) SubmitReader(ctx context.Context, r io.Reader, options gdetect.SubmitOptions) (uuid string, err error) {
+	options.Tags = c.tags(ctx, options.Tags)
+	return c.GDetectSubmitter.SubmitReader(ctx, r, options)
+}
+
+func (c *TaggingClient) WaitForFile(ctx context.Context, filepath string, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+	options.Tags = c.tags(ctx, options.Tags)
+	return c.GDetectSubmitter.WaitForFile(ctx, filepath, options)
+}
+
+func (c *TaggingClient) WaitForReader(ctx context.Context, r io.Reader, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+	options.Tags = c.tags(ctx, options.Tags)
+	return c.GDetectSubmitter.WaitForReader(ctx, r, options)
+}
+
+func (c *TaggingClient) ExtendedSubmitter() (extended gdetect.ExtendedGDetectSubmitter, ok bool) {
+	return ExtendedSubmitter(c.GDetectSubmitter)
+}
@@ -0,0 +1,90 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+	gdetectmock "github.com/glimps-re/go-gdetect/pkg/gdetect/mock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTagPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		sc      SubmissionContext
+		want    []string
+		wantErr error
+	}{
+		{name: "no tags", want: []string{}},
+		{name: "static", tags: []string{"connector", "host"}, want: []string{"connector", "host"}},
+		{
+			name: "templates",
+			tags: []string{"connector", "id:{{.ConnectorID}}", "site:{{.Site}}", "{{.Path}}", "{{.Filename}}"},
+			sc:   SubmissionContext{GMalwareItem: sdk.GMalwareItem{Site: "finance", Path: "/data/finance"}, ConnectorID: "c1"},
+			want: []string{"connector", "id:c1", "site:finance", "/data/finance"},
+		},
+		{
+			name: "duplicates",
+			tags: []string{"{{.Site}}", "finance", `{{if .Sender}}mail{{end}}`},
+			sc:   SubmissionContext{GMalwareItem: sdk.GMalwareItem{Site: "finance"}},
+			want: []string{"finance"},
+		},
+		{name: "syntax error", tags: []string{"{{.Site"}, wantErr: ErrInvalidTagTemplate},
+		{name: "unknown field", tags: []string{"{{.Department}}"}, wantErr: ErrInvalidTagTemplate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewTagPolicy(tt.tags)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewTagPolicy() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(p.Tags(tt.sc), tt.want); diff != "" {
+				t.Errorf("Tags() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestTaggingClient(t *testing.T) {
+	var submitted [][]string
+	submitter := &gdetectmock.MockGDetectSubmitter{
+		SubmitReaderMock: func(ctx context.Context, r io.Reader, options gdetect.SubmitOptions) (uuid string, err error) {
+			submitted = append(submitted, options.Tags)
+			return
+		},
+		WaitForFileMock: func(ctx context.Context, filepath string, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+			submitted = append(submitted, options.Tags)
+			return
+		},
+	}
+	policy, err := NewTagPolicy([]string{"host", "id:{{.ConnectorID}}", "{{with .Path}}path:{{.}}{{end}}"})
+	if err != nil {
+		t.Fatalf("NewTagPolicy() error = %v", err)
+	}
+	c := NewTaggingClient(submitter, policy, func() string { return "c1" })
+
+	ctx := WithSubmissionContext(t.Context(), SubmissionContext{GMalwareItem: sdk.GMalwareItem{Path: "/data/a"}})
+	if _, err = c.SubmitReader(ctx, strings.NewReader("content"), gdetect.SubmitOptions{Tags: []string{"manual", "host"}}); err != nil {
+		t.Fatalf("SubmitReader() error = %v", err)
+	}
+	ctx = WithSubmissionContext(t.Context(), SubmissionContext{ConnectorID: "other"})
+	if _, err = c.WaitForFile(ctx, "/data/b", gdetect.WaitForOptions{}); err != nil {
+		t.Fatalf("WaitForFile() error = %v", err)
+	}
+	want := [][]string{
+		{"manual", "host", "id:c1", "path:/data/a"},
+		{"host", "id:other"},
+	}
+	if diff := cmp.Diff(submitted, want); diff != "" {
+		t.Errorf("submitted tags diff(got-want)=%s", diff)
+	}
+}
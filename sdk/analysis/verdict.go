@@ -0,0 +1,179 @@
+package analysis
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+)
+
+// VerdictStatus is the outcome of a GLIMPS Malware analysis.
+type VerdictStatus string
+
+const (
+	VerdictClean   VerdictStatus = "clean"
+	VerdictMalware VerdictStatus = "malware"
+	// VerdictError is an analysis which failed (result error), file could not be analyzed
+	VerdictError VerdictStatus = "error"
+	// VerdictPending is an analysis not done yet
+	VerdictPending VerdictStatus = "pending"
+)
+
+// VerdictFile is a file analyzed within a submission, e.g. a member of a submitted archive.
+type VerdictFile struct {
+	SHA256 string
+	// Filenames are names of file in submission, e.g. its paths within archive
+	Filenames []string
+	Malware   bool
+	Score     int
+	// Malwares are malware names of file, given by antivirus engines
+	Malwares []string
+	Size     int64
+	Type     string
+}
+
+// Verdict is a GLIMPS Malware analysis result, as connectors interpret and report it.
+type Verdict struct {
+	Status   VerdictStatus
+	SHA256   string
+	Score    int
+	Malwares []string
+	FileType string
+	Size     int64
+	// Files are files found inside submitted file (e.g. archive members), submitted file excluded
+	Files []VerdictFile
+	// ExpertURL is the expert view URL of analysis, empty if unknown
+	ExpertURL string
+	Duration  time.Duration
+	// Error is the analysis error, for VerdictError
+	Error string
+}
+
+// NewVerdict returns verdict of result. Its expert view URL is given by submitter (which may be nil) if it
+// implements gdetect.ExtendedGDetectSubmitter (e.g. *gdetect.Client).
+func NewVerdict(result gdetect.Result, submitter gdetect.GDetectSubmitter) (v Verdict) {
+	v = Verdict{
+		SHA256:   strings.ToLower(result.SHA256),
+		Score:    result.Score,
+		Malwares: result.Malwares,
+		FileType: result.FileType,
+		Size:     result.FileSize,
+		// in milliseconds
+		Duration: time.Duration(result.Duration) * time.Millisecond,
+		Error:    result.Error,
+	}
+	switch {
+	case result.Malware:
+		v.Status = VerdictMalware
+	case result.Error != "":
+		v.Status = VerdictError
+	case !result.Done:
+		v.Status = VerdictPending
+	default:
+		v.Status = VerdictClean
+	}
+	for _, file := range result.Files {
+		if strings.EqualFold(file.SHA256, result.SHA256) {
+			continue
+		}
+		v.Files = append(v.Files, newVerdictFile(file, result.Threats))
+	}
+	if extended, ok := ExtendedSubmitter(submitter); ok && result.SID != "" {
+		expertURL, err := extended.ExtractExpertViewURL(&result)
+		if err != nil {
+			logger.Debug("could not get expert view url", slog.String("error", err.Error()))
+		}
+		v.ExpertURL = expertURL
+	}
+	return
+}
+
+func newVerdictFile(file gdetect.FileResult, threats map[string]gdetect.Threat) (f VerdictFile) {
+	f = VerdictFile{
+		SHA256:  strings.ToLower(file.SHA256),
+		Malware: file.IsMalware,
+		Size:    file.Size,
+		Type:    file.Magic,
+	}
+	for _, av := range file.AVResults {
+		f.Score = max(f.Score, av.Score)
+		if av.Result != "" && !slices.Contains(f.Malwares, av.Result) {
+			f.Malwares = append(f.Malwares, av.Result)
+		}
+	}
+	for sha256, threat := range threats {
+		if !strings.EqualFold(sha256, file.SHA256) {
+			continue
+		}
+		f.Filenames = threat.Filenames
+		f.Score = max(f.Score, threat.Score)
+	}
+	return
+}
+
+// Details returns mitigation event details of verdict.
+func (v Verdict) Details() (details events.CommonDetails) {
+	details = events.CommonDetails{
+		Malwares: v.Malwares,
+		SHA256:   v.SHA256,
+	}
+	if v.ExpertURL != "" {
+		details.GmalwareURLs = []string{v.ExpertURL}
+	}
+	if v.Status == VerdictError {
+		details.AnalysisError = v.Error
+	}
+	return
+}
+
+// FileInfos returns file mitigation event infos of verdict of file, to give to events.Handler
+// NotifyFileMitigation. Malicious members of archive file are reported in it.
+func (v Verdict) FileInfos(file string) (info events.FileInfos) {
+	info = events.FileInfos{
+		CommonDetails: v.Details(),
+		File:          file,
+		Filetype:      v.FileType,
+		Size:          v.Size,
+		Members:       v.Members(),
+	}
+	return
+}
+
+// Members returns malicious archive members of verdict, one per path within archive. Members whose path is unknown
+// are reported by their SHA256.
+func (v Verdict) Members() (members []events.ArchiveMember) {
+	for _, file := range v.Files {
+		if !file.Malware {
+			continue
+		}
+		paths := file.Filenames
+		if len(paths) == 0 {
+			paths = []string{file.SHA256}
+		}
+		for _, path := range paths {
+			members = append(members, events.ArchiveMember{Path: path, SHA256: file.SHA256, Malwares: file.Malwares})
+		}
+	}
+	return
+}
+
+// ExtendedSubmitterGetter is implemented by submitters wrapping detect clients (e.g. BudgetClient), for callers to
+// use extended features of the client they submit to, e.g. expert view URLs of verdicts.
+type ExtendedSubmitterGetter interface {
+	ExtendedSubmitter() (extended gdetect.ExtendedGDetectSubmitter, ok bool)
+}
+
+// ExtendedSubmitter returns submitter as an ExtendedGDetectSubmitter, unwrapping it if it is an
+// ExtendedSubmitterGetter. ok is false if the client it submits to is not extended.
+func ExtendedSubmitter(submitter gdetect.GDetectSubmitter) (extended gdetect.ExtendedGDetectSubmitter, ok bool) {
+	switch s := submitter.(type) {
+	case ExtendedSubmitterGetter:
+		extended, ok = s.ExtendedSubmitter()
+	case gdetect.ExtendedGDetectSubmitter:
+		extended, ok = s, true
+	}
+	return
+}
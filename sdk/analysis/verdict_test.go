@@ -0,0 +1,162 @@
+package analysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+	gdetectmock "github.com/glimps-re/go-gdetect/pkg/gdetect/mock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewVerdict(t *testing.T) {
+	archive := "AA" + sha256Of("archive")[2:]
+	member := sha256Of("member")
+	submitter := &gdetectmock.MockGDetectSubmitter{
+		ExtractExpertViewURLMock: func(result *gdetect.Result) (urlExpertView string, err error) {
+			urlExpertView = "https://gmalware.example.com/expert/en/analysis/advanced/" + result.SID
+			return
+		},
+	}
+	tests := []struct {
+		name      string
+		result    gdetect.Result
+		submitter gdetect.GDetectSubmitter
+		want      Verdict
+		wantInfos events.FileInfos
+	}{
+		{
+			name:   "clean",
+			result: gdetect.Result{SHA256: member, Done: true, Score: 100, FileType: "exe", FileSize: 10, Duration: 1500},
+			want:   Verdict{Status: VerdictClean, SHA256: member, Score: 100, FileType: "exe", Size: 10, Duration: 1500 * time.Millisecond},
+			wantInfos: events.FileInfos{
+				CommonDetails: events.CommonDetails{SHA256: member},
+				File:          "/tmp/file.exe",
+				Filetype:      "exe",
+				Size:          10,
+			},
+		},
+		{
+			name:   "pending",
+			result: gdetect.Result{SHA256: member},
+			want:   Verdict{Status: VerdictPending, SHA256: member},
+			wantInfos: events.FileInfos{
+				CommonDetails: events.CommonDetails{SHA256: member},
+				File:          "/tmp/file.exe",
+			},
+		},
+		{
+			name:   "error",
+			result: gdetect.Result{SHA256: member, Done: true, Error: "file is encrypted"},
+			want:   Verdict{Status: VerdictError, SHA256: member, Error: "file is encrypted"},
+			wantInfos: events.FileInfos{
+				CommonDetails: events.CommonDetails{SHA256: member, AnalysisError: "file is encrypted"},
+				File:          "/tmp/file.exe",
+			},
+		},
+		{
+			name: "malware archive",
+			result: gdetect.Result{
+				SHA256:   archive,
+				SID:      "sid-1",
+				Done:     true,
+				Malware:  true,
+				Score:    3000,
+				Malwares: []string{"Eicar"},
+				FileType: "zip",
+				FileSize: 300,
+				Files: []gdetect.FileResult{
+					{SHA256: archive, Magic: "Zip archive", Size: 300},
+					{
+						SHA256:    member,
+						Magic:     "PE32",
+						Size:      100,
+						IsMalware: true,
+						AVResults: []gdetect.AvResult{{AVName: "av1", Result: "Eicar", Score: 1000}, {AVName: "av2", Result: "Eicar", Score: 3000}},
+					},
+				},
+				Threats: map[string]gdetect.Threat{member: {Filenames: []string{"archive.zip/eicar.exe"}, Score: 3000, SHA256: member}},
+			},
+			submitter: submitter,
+			want: Verdict{
+				Status:   VerdictMalware,
+				SHA256:   "aa" + archive[2:],
+				Score:    3000,
+				Malwares: []string{"Eicar"},
+				FileType: "zip",
+				Size:     300,
+				Files: []VerdictFile{{
+					SHA256:    member,
+					Filenames: []string{"archive.zip/eicar.exe"},
+					Malware:   true,
+					Score:     3000,
+					Malwares:  []string{"Eicar"},
+					Size:      100,
+					Type:      "PE32",
+				}},
+				ExpertURL: "https://gmalware.example.com/expert/en/analysis/advanced/sid-1",
+			},
+			wantInfos: events.FileInfos{
+				CommonDetails: events.CommonDetails{
+					Malwares:     []string{"Eicar"},
+					GmalwareURLs: []string{"https://gmalware.example.com/expert/en/analysis/advanced/sid-1"},
+					SHA256:       "aa" + archive[2:],
+				},
+				File:     "/tmp/file.exe",
+				Filetype: "zip",
+				Size:     300,
+				Members:  []events.ArchiveMember{{Path: "archive.zip/eicar.exe", SHA256: member, Malwares: []string{"Eicar"}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewVerdict(tt.result, tt.submitter)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("NewVerdict() diff(got-want)=%s", diff)
+			}
+			if diff := cmp.Diff(got.FileInfos("/tmp/file.exe"), tt.wantInfos); diff != "" {
+				t.Errorf("FileInfos() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestVerdict_Members(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []VerdictFile
+		want  []events.ArchiveMember
+	}{
+		{name: "no member"},
+		{name: "clean members", files: []VerdictFile{{SHA256: "a", Filenames: []string{"archive.zip/readme.txt"}}}},
+		{
+			name: "malicious members",
+			files: []VerdictFile{
+				{SHA256: "a", Filenames: []string{"archive.zip/readme.txt"}},
+				{SHA256: "b", Filenames: []string{"archive.zip/eicar.exe", "archive.zip/copy/eicar.exe"}, Malware: true, Malwares: []string{"Eicar"}},
+				{SHA256: "c", Malware: true, Malwares: []string{"Trojan.Generic"}},
+			},
+			want: []events.ArchiveMember{
+				{Path: "archive.zip/eicar.exe", SHA256: "b", Malwares: []string{"Eicar"}},
+				{Path: "archive.zip/copy/eicar.exe", SHA256: "b", Malwares: []string{"Eicar"}},
+				{Path: "c", SHA256: "c", Malwares: []string{"Trojan.Generic"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(Verdict{Files: tt.files}.Members(), tt.want); diff != "" {
+				t.Errorf("Members() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func sha256Of(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,203 @@
+// Package archive handles password-protected archives: passwords of connector config are tried on encrypted
+// archives, and archives none of them opens get the configured action, so every connector treats them the same way.
+package archive
+
+import (
+	"archive/zip"
+	"crypto/pbkdf2"
+	"crypto/sha1" //nolint:gosec // WinZip AES key derivation
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+var ErrNotArchive = errors.New("not a supported archive")
+
+// zip method of WinZip AES encrypted entries, and header ID of their extra field
+const (
+	methodWinZipAES = 99
+	extraWinZipAES  = 0x9901
+)
+
+// Policy is the handling of encrypted archives.
+type Policy struct {
+	Passwords []string                `json:"passwords" yaml:"passwords" mapstructure:"passwords" password:"true" desc:"Passwords tried on encrypted archives (e.g. infected), the one opening an archive is given to GLIMPS Malware" default:"[]"`
+	Action    events.MitigationAction `json:"action" yaml:"action" mapstructure:"action" validate:"omitempty,oneof=block quarantine log" desc:"Action on encrypted archives no password opens: block, quarantine or log (default: log)"`
+}
+
+// Resolution is how an archive must be handled.
+type Resolution struct {
+	// Encrypted reports whether archive has encrypted entries
+	Encrypted bool
+	// Password opens encrypted archive, to submit it with (gdetect.SubmitOptions.ArchivePassword)
+	Password string
+	// Action, set for encrypted archives no password opens, is the mitigation to apply with Reason
+	Action events.MitigationAction
+	Reason events.MitigationReason
+}
+
+// Undecryptable reports whether archive is encrypted and no password opens it.
+func (r Resolution) Undecryptable() bool {
+	return r.Encrypted && r.Password == ""
+}
+
+// Resolve checks whether zip archive r (of size bytes) is encrypted, and which policy password opens it. Encrypted
+// archives no password opens get policy action (log by default) with events.ReasonInvalid. It returns
+// ErrNotArchive for other content, which can be submitted as is.
+func (p Policy) Resolve(r io.ReaderAt, size int64) (res Resolution, err error) {
+	entries, err := encryptedEntries(r, size)
+	if err != nil || len(entries) == 0 {
+		return
+	}
+	res.Encrypted = true
+	for _, password := range p.Passwords {
+		ok, checkErr := opens(entries, password)
+		if checkErr != nil {
+			err = checkErr
+			return
+		}
+		if ok {
+			res.Password = password
+			return
+		}
+	}
+	res.Action = p.Action
+	if res.Action == "" {
+		res.Action = events.ActionLog
+	}
+	res.Reason = events.ReasonInvalid
+	return
+}
+
+func encryptedEntries(r io.ReaderAt, size int64) (entries []*zip.File, err error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		err = fmt.Errorf("%w, %w", ErrNotArchive, err)
+		return
+	}
+	for _, f := range zr.File {
+		if f.Flags&0x1 != 0 {
+			entries = append(entries, f)
+		}
+	}
+	return
+}
+
+// opens reports whether password opens every encrypted entry (ZipCrypto checks have 1/256 false positives, so all
+// entries are checked).
+func opens(entries []*zip.File, password string) (ok bool, err error) {
+	for _, f := range entries {
+		if ok, err = checkPassword(f, password); err != nil || !ok {
+			return
+		}
+	}
+	return
+}
+
+// checkPassword checks password against encryption header of f, without decrypting its content.
+func checkPassword(f *zip.File, password string) (ok bool, err error) {
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return
+	}
+	if f.Method == methodWinZipAES {
+		return checkWinZipAES(f, raw, password)
+	}
+	return checkZipCrypto(f, raw, password)
+}
+
+// checkZipCrypto checks last byte of decrypted 12 bytes header, the high byte of CRC (or of modified time when
+// entry has a data descriptor).
+func checkZipCrypto(f *zip.File, raw io.Reader, password string) (ok bool, err error) {
+	header := make([]byte, 12)
+	if _, err = io.ReadFull(raw, header); err != nil {
+		err = fmt.Errorf("could not read encryption header of %s, %w", f.Name, err)
+		return
+	}
+	keys := newZipCryptoKeys(password)
+	for i := range header {
+		header[i] = keys.decrypt(header[i])
+	}
+	check := byte(f.CRC32 >> 24)
+	if f.Flags&0x8 != 0 {
+		check = byte(f.ModifiedTime >> 8) //nolint:staticcheck // MS-DOS time is what header is checked against
+	}
+	ok = header[11] == check
+	return
+}
+
+type zipCryptoKeys [3]uint32
+
+func newZipCryptoKeys(password string) (k *zipCryptoKeys) {
+	k = &zipCryptoKeys{0x12345678, 0x23456789, 0x34567890}
+	for i := range len(password) {
+		k.update(password[i])
+	}
+	return
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ (crc >> 8)
+}
+
+func (k *zipCryptoKeys) update(b byte) {
+	k[0] = crc32Update(k[0], b)
+	k[1] = (k[1]+k[0]&0xff)*134775813 + 1
+	k[2] = crc32Update(k[2], byte(k[1]>>24))
+}
+
+func (k *zipCryptoKeys) decrypt(c byte) (b byte) {
+	t := k[2] | 2
+	b = c ^ byte((t*(t^1))>>8)
+	k.update(b)
+	return
+}
+
+// checkWinZipAES checks the password verification value following salt, derived with the AES key.
+func checkWinZipAES(f *zip.File, raw io.Reader, password string) (ok bool, err error) {
+	strength, err := winZipAESStrength(f.Extra)
+	if err != nil {
+		err = fmt.Errorf("invalid AES encryption of %s, %w", f.Name, err)
+		return
+	}
+	keySize := 8 + 8*int(strength) // 16, 24 or 32 bytes
+	saltSize := keySize / 2
+	header := make([]byte, saltSize+2)
+	if _, err = io.ReadFull(raw, header); err != nil {
+		err = fmt.Errorf("could not read encryption header of %s, %w", f.Name, err)
+		return
+	}
+	derived, err := pbkdf2.Key(sha1.New, password, header[:saltSize], 1000, 2*keySize+2)
+	if err != nil {
+		return
+	}
+	ok = subtle.ConstantTimeCompare(derived[2*keySize:], header[saltSize:]) == 1
+	return
+}
+
+// winZipAESStrength returns AES strength (1: AES-128, 2: AES-192, 3: AES-256) of WinZip AES extra field.
+func winZipAESStrength(extra []byte) (strength byte, err error) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		n := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+n {
+			break
+		}
+		if id == extraWinZipAES && n >= 7 {
+			strength = extra[4+4]
+			if !slices.Contains([]byte{1, 2, 3}, strength) {
+				err = fmt.Errorf("unknown strength %d", strength)
+			}
+			return
+		}
+		extra = extra[4+n:]
+	}
+	err = errors.New("missing AES extra field")
+	return
+}
@@ -0,0 +1,136 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+type testEntry struct {
+	name     string
+	content  string
+	password string // entry is stored in clear if empty
+	aes      bool
+}
+
+// testZip returns a zip archive of stored entries, encrypted ones having only their encryption header checked.
+func testZip(t *testing.T, entries ...testEntry) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Store, CRC32: crc32.ChecksumIEEE([]byte(e.content))}
+		data := []byte(e.content)
+		switch {
+		case e.password != "" && e.aes:
+			header.Flags |= 0x1
+			header.Method = methodWinZipAES
+			// AES-256
+			header.Extra = binary.LittleEndian.AppendUint16(nil, extraWinZipAES)
+			header.Extra = binary.LittleEndian.AppendUint16(header.Extra, 7)
+			header.Extra = append(header.Extra, 0x02, 0x00, 'A', 'E', 0x03, byte(zip.Store), 0x00)
+			salt := bytes.Repeat([]byte{0x42}, 16)
+			derived, err := pbkdf2.Key(sha1.New, e.password, salt, 1000, 2*32+2)
+			if err != nil {
+				t.Fatalf("pbkdf2.Key() error = %v", err)
+			}
+			data = append(append(salt, derived[64:]...), data...)
+		case e.password != "":
+			header.Flags |= 0x1
+			keys := newZipCryptoKeys(e.password)
+			plain := append(bytes.Repeat([]byte{0x17}, 11), byte(header.CRC32>>24))
+			plain = append(plain, data...)
+			data = make([]byte, len(plain))
+			for i, b := range plain {
+				k := keys[2] | 2
+				data[i] = b ^ byte((k*(k^1))>>8)
+				keys.update(b)
+			}
+		}
+		header.CompressedSize64 = uint64(len(data))
+		header.UncompressedSize64 = uint64(len(e.content))
+		fw, err := w.CreateRaw(header)
+		if err != nil {
+			t.Fatalf("CreateRaw() error = %v", err)
+		}
+		if _, err = fw.Write(data); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestPolicy_Resolve(t *testing.T) {
+	policy := Policy{Passwords: []string{"secret", "infected"}, Action: events.ActionQuarantine}
+	tests := []struct {
+		name    string
+		policy  Policy
+		content []byte
+		want    Resolution
+		wantErr error
+	}{
+		{
+			name:    "not encrypted",
+			policy:  policy,
+			content: testZip(t, testEntry{name: "a.txt", content: "hello"}),
+		},
+		{
+			name:   "zipcrypto password found",
+			policy: policy,
+			content: testZip(t,
+				testEntry{name: "readme.txt", content: "clear"},
+				testEntry{name: "a.exe", content: "MZ payload", password: "infected"},
+				testEntry{name: "b.exe", content: "MZ other payload", password: "infected"},
+			),
+			want: Resolution{Encrypted: true, Password: "infected"},
+		},
+		{
+			name:    "aes password found",
+			policy:  policy,
+			content: testZip(t, testEntry{name: "a.exe", content: "MZ payload", password: "secret", aes: true}),
+			want:    Resolution{Encrypted: true, Password: "secret"},
+		},
+		{
+			name:    "undecryptable",
+			policy:  policy,
+			content: testZip(t, testEntry{name: "a.exe", content: "MZ payload", password: "unknown"}),
+			want:    Resolution{Encrypted: true, Action: events.ActionQuarantine, Reason: events.ReasonInvalid},
+		},
+		{
+			name:    "undecryptable, default action",
+			content: testZip(t, testEntry{name: "a.exe", content: "MZ payload", password: "unknown", aes: true}),
+			want:    Resolution{Encrypted: true, Action: events.ActionLog, Reason: events.ReasonInvalid},
+		},
+		{
+			name:    "not an archive",
+			policy:  policy,
+			content: []byte("MZ not an archive"),
+			wantErr: ErrNotArchive,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.Resolve(bytes.NewReader(tt.content), int64(len(tt.content)))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Resolve() error = %v, want %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Resolve() diff(got-want)=%s", diff)
+			}
+			if got.Undecryptable() != (tt.want.Action != "") {
+				t.Errorf("Undecryptable() = %v, want %v", got.Undecryptable(), tt.want.Action != "")
+			}
+		})
+	}
+}
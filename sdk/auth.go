@@ -0,0 +1,359 @@
+package sdk
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultJWTTTL = 5 * time.Minute
+	// credentials are renewed when they expire within authExpirySkew, so they stay valid while requests are retried
+	authExpirySkew = 30 * time.Second
+)
+
+var (
+	ErrAuthentication      = errors.New("could not authenticate to connector manager")
+	ErrUnsupportedJWTKey   = errors.New("unsupported jwt signing key")
+	ErrMissingOAuth2Config = errors.New("oauth2 token url and client id are required")
+)
+
+// Authenticator sets credentials of requests to connector manager, e.g. an Authorization header.
+// It is called for every request, implementations must be safe for concurrent use.
+type Authenticator interface {
+	Authenticate(ctx context.Context, req *http.Request) (err error)
+}
+
+// CachingAuthenticator is implemented by authenticators caching credentials (e.g. access tokens).
+// Invalidate is called when manager answers unauthorized, so next request gets new credentials.
+type CachingAuthenticator interface {
+	Authenticator
+	Invalidate()
+}
+
+// AuthenticatorFunc adapts a function to Authenticator, e.g. to set headers expected by an identity-aware proxy.
+type AuthenticatorFunc func(ctx context.Context, req *http.Request) (err error)
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, req *http.Request) (err error) {
+	return f(ctx, req)
+}
+
+// authorizationHeader authenticates requests with a static Authorization header.
+type authorizationHeader string
+
+func (h authorizationHeader) Authenticate(ctx context.Context, req *http.Request) (err error) {
+	req.Header.Set("Authorization", string(h))
+	return
+}
+
+// NewAPIKeyAuthenticator returns an Authenticator using console api key, the default one.
+func NewAPIKeyAuthenticator(apiKey string) Authenticator {
+	return authorizationHeader("ApiKey " + apiKey)
+}
+
+// bearerToken caches a token and its expiry, renewing it with fetch when it is about to expire.
+type bearerToken struct {
+	lock    sync.Mutex
+	token   string
+	expires time.Time
+	now     func() time.Time
+	fetch   func(ctx context.Context) (token string, expires time.Time, err error)
+}
+
+func (b *bearerToken) Authenticate(ctx context.Context, req *http.Request) (err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.token == "" || !b.now().Add(authExpirySkew).Before(b.expires) {
+		b.token, b.expires, err = b.fetch(ctx)
+		if err != nil {
+			b.token = ""
+			err = errors.Join(ErrAuthentication, err)
+			return
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	return
+}
+
+func (b *bearerToken) Invalidate() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.token = ""
+}
+
+type OAuth2ClientCredentialsConfig struct {
+	TokenURL     string   `mapstructure:"token-url"`
+	ClientID     string   `mapstructure:"client-id"`
+	ClientSecret string   `mapstructure:"client-secret"`
+	Scopes       []string `mapstructure:"scopes"`
+	// Audience is sent as "audience" parameter when set, as expected by some identity providers
+	Audience string `mapstructure:"audience"`
+}
+
+// OAuth2Authenticator authenticates requests with an access token got from an OAuth2 client credentials grant
+// (RFC 6749 section 4.4), renewed before it expires.
+type OAuth2Authenticator struct {
+	*bearerToken
+	config     OAuth2ClientCredentialsConfig
+	httpClient *http.Client
+}
+
+// NewOAuth2Authenticator returns an OAuth2Authenticator requesting tokens with httpClient (http.DefaultClient if nil).
+func NewOAuth2Authenticator(config OAuth2ClientCredentialsConfig, httpClient *http.Client) (a *OAuth2Authenticator, err error) {
+	if config.TokenURL == "" || config.ClientID == "" {
+		err = ErrMissingOAuth2Config
+		return
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	a = &OAuth2Authenticator{config: config, httpClient: httpClient}
+	a.bearerToken = &bearerToken{now: time.Now, fetch: a.token}
+	return
+}
+
+type oauth2Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type oauth2Error struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (a *OAuth2Authenticator) token(ctx context.Context) (token string, expires time.Time, err error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.config.Scopes, " "))
+	}
+	if a.config.Audience != "" {
+		form.Set("audience", a.config.Audience)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(a.config.ClientID), url.QueryEscape(a.config.ClientSecret))
+	requested := a.now()
+	resp, err := a.httpClient.Do(req) //nolint:gosec // token URL from client config, not user input
+	if err != nil {
+		return
+	}
+	defer func() {
+		if e := resp.Body.Close(); e != nil && err == nil {
+			err = e
+		}
+	}()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		tokenErr := oauth2Error{}
+		if jsonErr := json.Unmarshal(body, &tokenErr); jsonErr != nil || tokenErr.Error == "" {
+			err = fmt.Errorf("unexpected response from token endpoint, %d: %s", resp.StatusCode, body)
+			return
+		}
+		err = fmt.Errorf("token endpoint error, %s: %s", tokenErr.Error, tokenErr.ErrorDescription)
+		return
+	}
+	res := oauth2Token{}
+	if err = json.Unmarshal(body, &res); err != nil {
+		err = fmt.Errorf("could not parse token response, %w", err)
+		return
+	}
+	if res.AccessToken == "" {
+		err = errors.New("token endpoint returned an empty access token")
+		return
+	}
+	if res.TokenType != "" && !strings.EqualFold(res.TokenType, "bearer") {
+		err = fmt.Errorf("unsupported token type %q", res.TokenType)
+		return
+	}
+	token = res.AccessToken
+	// without expires_in, token is renewed on unauthorized responses only
+	expires = requested.Add(100 * 365 * 24 * time.Hour)
+	if res.ExpiresIn > 0 {
+		expires = requested.Add(time.Duration(res.ExpiresIn) * time.Second)
+	}
+	return
+}
+
+type JWTConfig struct {
+	Issuer   string
+	Subject  string
+	Audience string
+	// TTL is the validity of signed tokens, DefaultJWTTTL if 0
+	TTL time.Duration
+	// Claims are added to registered claims (iss, sub, aud, iat, nbf, exp, jti)
+	Claims map[string]any
+}
+
+// JWTAuthenticator authenticates requests with short-lived JWTs it signs, renewed before they expire.
+// Signing key may be rotated at any time with Rotate, e.g. when a new key is provisioned.
+// Supported keys are *ecdsa.PrivateKey (P-256, P-384, P-521), *rsa.PrivateKey (RS256) and ed25519.PrivateKey.
+type JWTAuthenticator struct {
+	*bearerToken
+	config JWTConfig
+
+	keyLock sync.RWMutex
+	key     crypto.Signer
+	keyID   string
+}
+
+func NewJWTAuthenticator(config JWTConfig, key crypto.Signer, keyID string) (a *JWTAuthenticator, err error) {
+	if config.TTL <= 0 {
+		config.TTL = DefaultJWTTTL
+	}
+	a = &JWTAuthenticator{config: config}
+	if err = a.Rotate(key, keyID); err != nil {
+		a = nil
+		return
+	}
+	a.bearerToken = &bearerToken{now: time.Now, fetch: a.sign}
+	return
+}
+
+// Rotate replaces signing key, next requests use tokens signed with it.
+func (a *JWTAuthenticator) Rotate(key crypto.Signer, keyID string) (err error) {
+	if _, _, err = jwtAlgorithm(key); err != nil {
+		return
+	}
+	a.keyLock.Lock()
+	a.key = key
+	a.keyID = keyID
+	a.keyLock.Unlock()
+	if a.bearerToken != nil {
+		a.Invalidate()
+	}
+	return
+}
+
+// jwtAlgorithm returns JWS algorithm (RFC 7518) of key, and hash to sign with (0 for ed25519).
+func jwtAlgorithm(key crypto.Signer) (alg string, hash crypto.Hash, err error) {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return "ES256", crypto.SHA256, nil
+		case elliptic.P384():
+			return "ES384", crypto.SHA384, nil
+		case elliptic.P521():
+			return "ES512", crypto.SHA512, nil
+		}
+	case *rsa.PrivateKey:
+		return "RS256", crypto.SHA256, nil
+	case ed25519.PrivateKey:
+		return "EdDSA", 0, nil
+	}
+	err = fmt.Errorf("%w, %T", ErrUnsupportedJWTKey, key)
+	return
+}
+
+func (a *JWTAuthenticator) sign(ctx context.Context) (token string, expires time.Time, err error) {
+	a.keyLock.RLock()
+	key, keyID := a.key, a.keyID
+	a.keyLock.RUnlock()
+	alg, hash, err := jwtAlgorithm(key)
+	if err != nil {
+		return
+	}
+
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if keyID != "" {
+		header["kid"] = keyID
+	}
+	now := a.now()
+	expires = now.Add(a.config.TTL)
+	jti := make([]byte, 16)
+	if _, err = rand.Read(jti); err != nil {
+		return
+	}
+	claims := make(map[string]any, len(a.config.Claims)+7)
+	for k, v := range a.config.Claims {
+		claims[k] = v
+	}
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = expires.Unix()
+	claims["jti"] = hex.EncodeToString(jti)
+	if a.config.Issuer != "" {
+		claims["iss"] = a.config.Issuer
+	}
+	if a.config.Subject != "" {
+		claims["sub"] = a.config.Subject
+	}
+	if a.config.Audience != "" {
+		claims["aud"] = a.config.Audience
+	}
+
+	rawHeader, err := json.Marshal(header)
+	if err != nil {
+		return
+	}
+	rawClaims, err := json.Marshal(claims)
+	if err != nil {
+		return
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(rawHeader) + "." + base64.RawURLEncoding.EncodeToString(rawClaims)
+	signature, err := signJWT(key, hash, []byte(signingInput))
+	if err != nil {
+		err = fmt.Errorf("could not sign jwt, %w", err)
+		return
+	}
+	token = signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return
+}
+
+func signJWT(key crypto.Signer, hash crypto.Hash, input []byte) (signature []byte, err error) {
+	var digest []byte
+	switch hash {
+	case crypto.SHA256:
+		sum := sha256.Sum256(input)
+		digest = sum[:]
+	case crypto.SHA384:
+		sum := sha512.Sum384(input)
+		digest = sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512(input)
+		digest = sum[:]
+	default:
+		// ed25519 signs message itself
+		return key.Sign(rand.Reader, input, crypto.Hash(0))
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return key.Sign(rand.Reader, digest, hash)
+	}
+	// JWS uses fixed size r||s signatures (RFC 7518 section 3.4), not ASN.1 ones
+	r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest)
+	if err != nil {
+		return
+	}
+	size := (ecKey.Curve.Params().BitSize + 7) / 8
+	signature = make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+	return
+}
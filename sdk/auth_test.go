@@ -0,0 +1,289 @@
+package sdk
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOAuth2Authenticator(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		response  string
+		elapsed   time.Duration
+		wantErr   bool
+		wantCalls int
+	}{
+		{name: "cached", status: http.StatusOK, response: `{"access_token":"token","token_type":"Bearer","expires_in":3600}`, elapsed: time.Minute, wantCalls: 1},
+		{name: "expired", status: http.StatusOK, response: `{"access_token":"token","token_type":"Bearer","expires_in":3600}`, elapsed: time.Hour, wantCalls: 2},
+		{name: "renewed before expiry", status: http.StatusOK, response: `{"access_token":"token","expires_in":60}`, elapsed: 40 * time.Second, wantCalls: 2},
+		{name: "invalid client", status: http.StatusUnauthorized, response: `{"error":"invalid_client"}`, wantErr: true, wantCalls: 1},
+		{name: "unsupported type", status: http.StatusOK, response: `{"access_token":"token","token_type":"mac"}`, wantErr: true, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				user, password, _ := r.BasicAuth()
+				if user != "client" || password != "secret" {
+					t.Errorf("token request credentials = %s:%s, want client:secret", user, password)
+				}
+				if err := r.ParseForm(); err != nil {
+					t.Errorf("could not parse token request, error: %v", err)
+				}
+				if got := r.PostForm.Get("grant_type") + " " + r.PostForm.Get("scope"); got != "client_credentials connector:read connector:write" {
+					t.Errorf("token request form = %s", got)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			a, err := NewOAuth2Authenticator(OAuth2ClientCredentialsConfig{
+				TokenURL:     server.URL,
+				ClientID:     "client",
+				ClientSecret: "secret",
+				Scopes:       []string{"connector:read", "connector:write"},
+			}, nil)
+			if err != nil {
+				t.Fatalf("NewOAuth2Authenticator() error = %v", err)
+			}
+			now := time.Unix(1738000000, 0)
+			a.now = func() time.Time { return now }
+
+			for range 2 {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				err = a.Authenticate(t.Context(), req)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err != nil {
+					if !errors.Is(err, ErrAuthentication) {
+						t.Errorf("Authenticate() error = %v, want ErrAuthentication", err)
+					}
+					break
+				}
+				if got := req.Header.Get("Authorization"); got != "Bearer token" {
+					t.Errorf("Authenticate() Authorization = %s, want Bearer token", got)
+				}
+				now = now.Add(tt.elapsed)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Authenticate() token requests = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		key     crypto.Signer
+		wantAlg string
+	}{
+		{name: "es256", key: ecKey, wantAlg: "ES256"},
+		{name: "es384", key: ec384Key, wantAlg: "ES384"},
+		{name: "rs256", key: rsaKey, wantAlg: "RS256"},
+		{name: "eddsa", key: edKey, wantAlg: "EdDSA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewJWTAuthenticator(JWTConfig{Issuer: "connector", Audience: "manager", Claims: map[string]any{"tenant": "t1"}}, tt.key, "key-1")
+			if err != nil {
+				t.Fatalf("NewJWTAuthenticator() error = %v", err)
+			}
+			now := time.Unix(1738000000, 0)
+			a.now = func() time.Time { return now }
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if err = a.Authenticate(t.Context(), req); err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			header, claims := verifyJWT(t, strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "), tt.key.Public())
+			if diff := cmp.Diff(header, map[string]any{"alg": tt.wantAlg, "typ": "JWT", "kid": "key-1"}); diff != "" {
+				t.Errorf("Authenticate() jwt header diff(got-want)=%s", diff)
+			}
+			delete(claims, "jti")
+			wantClaims := map[string]any{"iss": "connector", "aud": "manager", "tenant": "t1", "iat": 1738000000.0, "nbf": 1738000000.0, "exp": 1738000300.0}
+			if diff := cmp.Diff(claims, wantClaims); diff != "" {
+				t.Errorf("Authenticate() jwt claims diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticator_Rotate(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewJWTAuthenticator(JWTConfig{}, oldKey, "old")
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator() error = %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if err = a.Authenticate(t.Context(), req); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	verifyJWT(t, strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "), oldKey.Public())
+
+	if err = a.Rotate(newKey, "new"); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if err = a.Authenticate(t.Context(), req); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	header, _ := verifyJWT(t, strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "), newKey.Public())
+	if header["kid"] != "new" {
+		t.Errorf("Authenticate() kid = %v, want new", header["kid"])
+	}
+
+	_, ed, _ := ed25519.GenerateKey(rand.Reader)
+	if err = a.Rotate(unsupportedSigner{ed}, "other"); !errors.Is(err, ErrUnsupportedJWTKey) {
+		t.Errorf("Rotate() error = %v, want ErrUnsupportedJWTKey", err)
+	}
+}
+
+type unsupportedSigner struct {
+	crypto.Signer
+}
+
+func verifyJWT(t *testing.T, token string, public crypto.PublicKey) (header map[string]any, claims map[string]any) {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("invalid jwt %q", token)
+	}
+	decode := func(part string, v any) {
+		raw, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			t.Fatalf("could not decode jwt part, error: %v", err)
+		}
+		if v == nil {
+			return
+		}
+		if err = json.Unmarshal(raw, v); err != nil {
+			t.Fatalf("could not parse jwt part, error: %v", err)
+		}
+	}
+	decode(parts[0], &header)
+	decode(parts[1], &claims)
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("could not decode jwt signature, error: %v", err)
+	}
+	input := []byte(parts[0] + "." + parts[1])
+	valid := false
+	switch k := public.(type) {
+	case *ecdsa.PublicKey:
+		var digest []byte
+		switch k.Curve {
+		case elliptic.P384():
+			sum := sha512.Sum384(input)
+			digest = sum[:]
+		default:
+			sum := sha256.Sum256(input)
+			digest = sum[:]
+		}
+		size := len(signature) / 2
+		valid = ecdsa.Verify(k, digest, new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:]))
+	case *rsa.PublicKey:
+		sum := sha256.Sum256(input)
+		valid = rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], signature) == nil
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(k, input, signature)
+	}
+	if !valid {
+		t.Fatalf("invalid jwt signature")
+	}
+	return
+}
+
+func TestConnectorManagerClient_Authenticator(t *testing.T) {
+	var gotAuthorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorizations = append(gotAuthorizations, r.Header.Get("Authorization")+" "+r.Header.Get("X-Proxy-Identity"))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	fetches := 0
+	auth := proxyAuthenticator{&bearerToken{
+		now: time.Now,
+		fetch: func(ctx context.Context) (token string, expires time.Time, err error) {
+			fetches++
+			return "token", time.Now().Add(time.Hour), nil
+		},
+	}}
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "ignored", Authenticator: auth})
+	for range 2 {
+		if _, err := c.getTasks(t.Context()); !errors.Is(err, ErrUnauthorizedConnector) {
+			t.Fatalf("getTasks() error = %v, want ErrUnauthorizedConnector", err)
+		}
+	}
+	if diff := cmp.Diff(gotAuthorizations, []string{"Bearer token connector", "Bearer token connector"}); diff != "" {
+		t.Errorf("getTasks() authorizations diff(got-want)=%s", diff)
+	}
+	// token is invalidated on unauthorized responses
+	if fetches != 2 {
+		t.Errorf("getTasks() token fetches = %d, want 2", fetches)
+	}
+
+	failing := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{
+		URL: server.URL,
+		Authenticator: AuthenticatorFunc(func(ctx context.Context, req *http.Request) error {
+			return ErrAuthentication
+		}),
+	})
+	if _, err := failing.getTasks(t.Context()); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("getTasks() error = %v, want ErrAuthentication", err)
+	}
+}
+
+// proxyAuthenticator sets a header expected by an identity-aware proxy along with a bearer token.
+type proxyAuthenticator struct {
+	*bearerToken
+}
+
+func (a proxyAuthenticator) Authenticate(ctx context.Context, req *http.Request) (err error) {
+	req.Header.Set("X-Proxy-Identity", "connector")
+	return a.bearerToken.Authenticate(ctx, req)
+}
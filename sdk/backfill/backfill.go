@@ -0,0 +1,279 @@
+// Package backfill runs initial scans of large repositories (e.g. Sharepoint sites, S3 buckets, host directories)
+// resumably: enumerated work is checkpointed in a state store, so an interrupted scan resumes where it stopped
+// instead of restarting.
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/state"
+)
+
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: sdk.LogLevel})).WithGroup("backfill")
+
+// DefaultCheckpointInterval is the default number of items handled between checkpoints.
+const DefaultCheckpointInterval = 100
+
+// checkpointKeyPrefix prefixes state keys of scan checkpoints.
+const checkpointKeyPrefix = "backfill/"
+
+var ErrInvalidScan = errors.New("invalid backfill scan")
+
+// Page is a batch of items to scan.
+type Page[T any] struct {
+	Items []T
+	// Next is the cursor of next page, empty for the last one
+	Next string
+}
+
+// Enumerator lists items to scan page by page. Listing a cursor again MUST return the same items (e.g. sorted
+// listing, continuation token...), so a scan resumes at the item it stopped.
+type Enumerator[T any] interface {
+	// List returns page at cursor, empty for the first page
+	List(ctx context.Context, cursor string) (page Page[T], err error)
+}
+
+// Counter may be implemented by enumerators knowing (or estimating) the number of items to scan, reported in
+// progress.
+type Counter interface {
+	Count(ctx context.Context) (total int64, err error)
+}
+
+// Handler handles a scanned item (e.g. submits it for analysis). Failed items are logged and counted, they do not
+// stop scan.
+type Handler[T any] func(ctx context.Context, item T) (err error)
+
+// Checkpoint is the persisted progress of a scan.
+type Checkpoint struct {
+	// Cursor is the cursor of page being scanned
+	Cursor string `json:"cursor"`
+	// Offset is the number of items of page already handled
+	Offset    int       `json:"offset"`
+	Processed int64     `json:"processed"`
+	Failed    int64     `json:"failed"`
+	Total     int64     `json:"total,omitempty"`
+	Done      bool      `json:"done"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Options struct {
+	// Name identifies scan in state store and progress events, e.g. "initial-scan", required
+	Name string
+	// Store persists scan checkpoints, required
+	Store state.Store
+	// Rate is the maximum number of items handled per second, unlimited if 0
+	Rate float64
+	// CheckpointInterval is the number of items handled between checkpoints, DefaultCheckpointInterval if 0.
+	// A checkpoint is also saved at the end of each page, and when scan stops.
+	CheckpointInterval int
+	// Notifier, if set, is notified an events.ProgressEvent at each checkpoint
+	Notifier events.Notifier
+}
+
+// Scan is a resumable scan of items listed by an Enumerator.
+type Scan[T any] struct {
+	enumerator Enumerator[T]
+	handle     Handler[T]
+	opts       Options
+	limiter    *limiter
+	now        func() time.Time
+}
+
+// NewScan returns a scan of items of enumerator, handled by handle.
+func NewScan[T any](enumerator Enumerator[T], handle Handler[T], opts Options) (s *Scan[T], err error) {
+	switch {
+	case opts.Name == "":
+		err = fmt.Errorf("%w, name is required", ErrInvalidScan)
+	case opts.Store == nil:
+		err = fmt.Errorf("%w, state store is required", ErrInvalidScan)
+	case opts.Rate < 0:
+		err = fmt.Errorf("%w, rate must not be negative", ErrInvalidScan)
+	}
+	if err != nil {
+		return
+	}
+	if opts.CheckpointInterval <= 0 {
+		opts.CheckpointInterval = DefaultCheckpointInterval
+	}
+	s = &Scan[T]{
+		enumerator: enumerator,
+		handle:     handle,
+		opts:       opts,
+		limiter:    newLimiter(opts.Rate),
+		now:        time.Now,
+	}
+	return
+}
+
+func (s *Scan[T]) key() string {
+	return checkpointKeyPrefix + s.opts.Name
+}
+
+// Checkpoint returns last saved checkpoint of scan, zero if scan never ran.
+func (s *Scan[T]) Checkpoint() (checkpoint Checkpoint, err error) {
+	raw, err := s.opts.Store.Get(s.key())
+	if errors.Is(err, state.ErrNotFound) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(raw, &checkpoint); err != nil {
+		err = fmt.Errorf("could not decode checkpoint of %s, %w", s.opts.Name, err)
+	}
+	return
+}
+
+// Reset forgets scan checkpoint, next Run scans every item again.
+func (s *Scan[T]) Reset() (err error) {
+	return s.opts.Store.Delete(s.key())
+}
+
+func (s *Scan[T]) save(ctx context.Context, checkpoint *Checkpoint) (err error) {
+	checkpoint.UpdatedAt = s.now()
+	raw, err := json.Marshal(checkpoint)
+	if err != nil {
+		return
+	}
+	if err = s.opts.Store.Put(s.key(), raw); err != nil {
+		err = fmt.Errorf("could not save checkpoint of %s, %w", s.opts.Name, err)
+		return
+	}
+	if s.opts.Notifier == nil {
+		return
+	}
+	if notifyErr := s.opts.Notifier.Notify(ctx, events.ProgressEvent{
+		Name:      s.opts.Name,
+		Processed: checkpoint.Processed,
+		Failed:    checkpoint.Failed,
+		Total:     checkpoint.Total,
+		Done:      checkpoint.Done,
+		StartedAt: checkpoint.StartedAt.Unix(),
+		Time:      checkpoint.UpdatedAt.Unix(),
+	}); notifyErr != nil {
+		// progress is checkpointed anyway
+		logger.Warn("could not notify scan progress", slog.String("scan", s.opts.Name), slog.String("error", notifyErr.Error()))
+	}
+	return
+}
+
+// Run scans items from last checkpoint until every item is handled, and returns final checkpoint. If ctx is done or
+// listing fails, progress is checkpointed and next Run resumes from it. A completed scan is not run again until
+// Reset.
+func (s *Scan[T]) Run(ctx context.Context) (checkpoint Checkpoint, err error) {
+	checkpoint, err = s.Checkpoint()
+	if err != nil || checkpoint.Done {
+		return
+	}
+	if checkpoint.StartedAt.IsZero() {
+		checkpoint.StartedAt = s.now()
+		if counter, ok := s.enumerator.(Counter); ok {
+			total, countErr := counter.Count(ctx)
+			if countErr != nil {
+				logger.Warn("could not count items to scan", slog.String("scan", s.opts.Name), slog.String("error", countErr.Error()))
+			}
+			checkpoint.Total = total
+		}
+	} else {
+		logger.Info("resuming scan", slog.String("scan", s.opts.Name), slog.Int64("processed", checkpoint.Processed))
+	}
+
+	defer func() {
+		if saveErr := s.save(context.WithoutCancel(ctx), &checkpoint); saveErr != nil {
+			err = errors.Join(err, saveErr)
+		}
+	}()
+	sinceCheckpoint := 0
+	for {
+		page, listErr := s.enumerator.List(ctx, checkpoint.Cursor)
+		if listErr != nil {
+			err = fmt.Errorf("could not list items of %s, %w", s.opts.Name, listErr)
+			return
+		}
+		for checkpoint.Offset < len(page.Items) {
+			if err = s.limiter.wait(ctx); err != nil {
+				return
+			}
+			handleErr := s.handle(ctx, page.Items[checkpoint.Offset])
+			if handleErr != nil && ctx.Err() != nil {
+				// item interrupted, handled again on resume
+				err = ctx.Err()
+				return
+			}
+			checkpoint.Offset++
+			checkpoint.Processed++
+			if handleErr != nil {
+				checkpoint.Failed++
+				logger.Warn("could not handle scanned item", slog.String("scan", s.opts.Name), slog.String("error", handleErr.Error()))
+			}
+			sinceCheckpoint++
+			if sinceCheckpoint >= s.opts.CheckpointInterval {
+				sinceCheckpoint = 0
+				if err = s.save(ctx, &checkpoint); err != nil {
+					return
+				}
+			}
+		}
+		if page.Next == "" {
+			checkpoint.Done = true
+			return
+		}
+		checkpoint.Cursor = page.Next
+		checkpoint.Offset = 0
+		sinceCheckpoint = 0
+		if err = s.save(ctx, &checkpoint); err != nil {
+			return
+		}
+		if err = ctx.Err(); err != nil {
+			return
+		}
+	}
+}
+
+// limiter spaces events to a maximum rate.
+type limiter struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(rate float64) (l *limiter) {
+	l = &limiter{}
+	if rate > 0 {
+		l.interval = time.Duration(float64(time.Second) / rate)
+	}
+	return
+}
+
+// wait waits for next event slot, or until ctx is done.
+func (l *limiter) wait(ctx context.Context) (err error) {
+	if err = ctx.Err(); err != nil || l.interval == 0 {
+		return
+	}
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	if delay == 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
@@ -0,0 +1,188 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/state"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// sliceEnumerator lists items in pages of pageSize, cursors being page offsets.
+type sliceEnumerator struct {
+	items    []string
+	pageSize int
+	failAt   string // cursor whose listing fails once
+}
+
+func (e *sliceEnumerator) List(ctx context.Context, cursor string) (page Page[string], err error) {
+	if cursor != "" && cursor == e.failAt {
+		e.failAt = ""
+		err = errors.New("listing throttled")
+		return
+	}
+	start := 0
+	if cursor != "" {
+		if start, err = strconv.Atoi(cursor); err != nil {
+			return
+		}
+	}
+	end := min(start+e.pageSize, len(e.items))
+	page.Items = e.items[start:end]
+	if end < len(e.items) {
+		page.Next = strconv.Itoa(end)
+	}
+	return
+}
+
+func (e *sliceEnumerator) Count(ctx context.Context) (total int64, err error) {
+	return int64(len(e.items)), nil
+}
+
+type progressRecorder struct {
+	events []events.ProgressEvent
+}
+
+func (r *progressRecorder) Notify(ctx context.Context, event any) (err error) {
+	r.events = append(r.events, event.(events.ProgressEvent))
+	return
+}
+
+func TestScan_Run(t *testing.T) {
+	store, err := state.NewFileStore(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	enumerator := &sliceEnumerator{items: []string{"a", "b", "c", "d", "e", "f", "g"}, pageSize: 3, failAt: "6"}
+	var handled []string
+	ctx, cancel := context.WithCancel(t.Context())
+	interrupted := false
+	handle := func(ctx context.Context, item string) (err error) {
+		switch {
+		case item == "b":
+			err = errors.New("item locked")
+			return
+		case item == "e" && !interrupted:
+			// connector stopped while handling e
+			interrupted = true
+			cancel()
+			err = ctx.Err()
+			return
+		}
+		handled = append(handled, item)
+		return
+	}
+	progress := &progressRecorder{}
+	scan, err := NewScan(enumerator, handle, Options{Name: "initial-scan", Store: store, CheckpointInterval: 2, Notifier: progress})
+	if err != nil {
+		t.Fatalf("NewScan() error = %v", err)
+	}
+	ignoreTimes := cmpopts.IgnoreFields(Checkpoint{}, "StartedAt", "UpdatedAt")
+
+	// interrupted while handling e
+	got, err := scan.Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want %v", err, context.Canceled)
+	}
+	want := Checkpoint{Cursor: "3", Offset: 1, Processed: 4, Failed: 1, Total: 7}
+	if diff := cmp.Diff(got, want, ignoreTimes); diff != "" {
+		t.Errorf("Run() diff(got-want)=%s", diff)
+	}
+
+	// listing of last page fails
+	got, err = scan.Run(t.Context())
+	if err == nil {
+		t.Fatalf("Run() error = nil, want listing error")
+	}
+	want = Checkpoint{Cursor: "6", Processed: 6, Failed: 1, Total: 7}
+	if diff := cmp.Diff(got, want, ignoreTimes); diff != "" {
+		t.Errorf("Run() diff(got-want)=%s", diff)
+	}
+
+	// resumed to completion
+	got, err = scan.Run(t.Context())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want = Checkpoint{Cursor: "6", Offset: 1, Processed: 7, Failed: 1, Total: 7, Done: true}
+	if diff := cmp.Diff(got, want, ignoreTimes); diff != "" {
+		t.Errorf("Run() diff(got-want)=%s", diff)
+	}
+	if diff := cmp.Diff(handled, []string{"a", "c", "d", "e", "f", "g"}); diff != "" {
+		t.Errorf("handled items diff(got-want)=%s", diff)
+	}
+
+	// completed scan is not run again
+	if _, err = scan.Run(t.Context()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(handled) != 6 {
+		t.Errorf("completed scan handled items again: %v", handled)
+	}
+
+	last := progress.events[len(progress.events)-1]
+	if !last.Done || last.Processed != 7 || last.Failed != 1 || last.Total != 7 {
+		t.Errorf("last progress event = %+v, want done with 7 processed, 1 failed", last)
+	}
+	for _, e := range progress.events {
+		if e.StartedAt != last.StartedAt {
+			t.Errorf("progress event started at %d, want %d kept across resumes", e.StartedAt, last.StartedAt)
+		}
+	}
+
+	if err = scan.Reset(); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if got, err = scan.Checkpoint(); err != nil || got != (Checkpoint{}) {
+		t.Errorf("Checkpoint() after Reset = %+v, %v, want zero", got, err)
+	}
+}
+
+func TestNewScan(t *testing.T) {
+	store, err := state.NewFileStore(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	enumerator := &sliceEnumerator{pageSize: 1}
+	handle := func(context.Context, string) error { return nil }
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "missing name", opts: Options{Store: store}},
+		{name: "missing store", opts: Options{Name: "scan"}},
+		{name: "negative rate", opts: Options{Name: "scan", Store: store, Rate: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewScan(enumerator, handle, tt.opts); !errors.Is(err, ErrInvalidScan) {
+				t.Errorf("NewScan() error = %v, want %v", err, ErrInvalidScan)
+			}
+		})
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(200)
+	start := time.Now()
+	for range 5 {
+		if err := l.wait(t.Context()); err != nil {
+			t.Fatalf("wait() error = %v", err)
+		}
+	}
+	// first event is not delayed
+	if elapsed := time.Since(start); elapsed < 4*5*time.Millisecond {
+		t.Errorf("5 events at 200/s took %s, want at least 20ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := newLimiter(1).wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("wait() error = %v, want %v", err, context.Canceled)
+	}
+}
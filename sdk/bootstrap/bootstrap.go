@@ -0,0 +1,183 @@
+// Package bootstrap standardizes connector startup configuration: connector manager settings are bound the same way
+// by every connector main, from command line flags, environment variables and an optional config file.
+package bootstrap
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/go-viper/mapstructure/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileKey is the key of the config file flag (-console-config) and environment variable (<PREFIX>_CONSOLE_CONFIG).
+const configFileKey = "config"
+
+var ErrMissingManagerConfig = errors.New("missing connector manager config")
+
+// managerField is a setting bound by LoadManagerConfig, key being its mapstructure key.
+type managerField struct {
+	key     string
+	usage   string
+	boolean bool
+}
+
+var configFileField = managerField{key: configFileKey, usage: "connector manager config file, yaml or json"}
+
+// managerFields are sdk.ConnectorManagerClientConfig fields bound by LoadManagerConfig.
+var managerFields = []managerField{
+	{key: "url", usage: "connector manager URL"},
+	{key: "api-key", usage: "connector API key"},
+	{key: "insecure", usage: "disable connector manager certificate check", boolean: true},
+	{key: "ca-certs-file", usage: "PEM bundle of CA certificates trusted for connector manager certificate"},
+	{key: "client-cert-file", usage: "PEM client certificate for connector manager mutual TLS"},
+	{key: "client-key-file", usage: "PEM key of client certificate"},
+	{key: "min-tls-version", usage: "minimum TLS version of connector manager requests, 1.2 or 1.3"},
+	{key: "tasks-wait", usage: "long polling duration of tasks (e.g. 30s), 0 disables long polling"},
+	{key: "poll-interval", usage: "delay between tasks requests (e.g. 5s)"},
+	{key: "poll-jitter", usage: "maximum random delay added to poll interval (e.g. 1s)"},
+	{key: "task-stream", usage: "receive tasks streamed by manager, polled if stream is unavailable", boolean: true},
+	{key: "task-queue-size", usage: "number of tasks waiting to be handled, per priority"},
+	{key: "unauthorized-retries", usage: "number of unauthorized responses tolerated in a row, e.g. during api key rotation"},
+	{key: "unauthorized-backoff", usage: "initial wait between unauthorized retries (e.g. 5s)"},
+	{key: "retry-max-elapsed", usage: "maximum duration of retries of a failed request (e.g. 30s)"},
+	{key: "retry-initial-interval", usage: "initial wait between retries of a failed request (e.g. 500ms)"},
+	{key: "retry-max-retries", usage: "maximum number of retries of a failed request, 0 for no limit but retry-max-elapsed"},
+	{key: "retry-status-codes", usage: "comma separated manager response statuses retried (e.g. 502,503,504)"},
+	{key: "breaker-threshold", usage: "number of failed requests in a row after which requests fail fast, 0 disables circuit breaker"},
+	{key: "breaker-probe-interval", usage: "delay between probes of an unavailable connector manager (e.g. 30s)"},
+	{key: "compress-requests", usage: "gzip request bodies to connector manager", boolean: true},
+}
+
+type Options struct {
+	// EnvPrefix prefixes environment variables, e.g. DUMMY binds DUMMY_CONSOLE_URL and DUMMY_CONSOLE_API_KEY
+	EnvPrefix string
+	// FlagSet flags are registered on, so connectors may add their own flags. A new one is used if nil.
+	FlagSet *flag.FlagSet
+	// Args are command line arguments parsed by FlagSet, os.Args[1:] if nil
+	Args []string
+	// ConfigFile is the default path of config file, overridden by -console-config flag and <PREFIX>_CONSOLE_CONFIG
+	// environment variable. No file is read if all are empty.
+	ConfigFile string
+	// Defaults are returned for settings set nowhere, e.g. ConnectorType
+	Defaults sdk.ConnectorManagerClientConfig
+}
+
+// LoadManagerConfig returns connector manager config, each setting (e.g. api-key) being bound from, by precedence:
+//   - flag -console-<key>, e.g. -console-api-key
+//   - environment variable <PREFIX>_CONSOLE_<KEY>, e.g. DUMMY_CONSOLE_API_KEY
+//   - key <key> of config file, yaml or json
+//   - opts Defaults
+//
+// Durations accept strings (e.g. 30s) and numbers of nanoseconds. It fails with ErrMissingManagerConfig if URL is
+// not set, or neither APIKey nor Authenticator.
+func LoadManagerConfig(opts Options) (config sdk.ConnectorManagerClientConfig, err error) {
+	fs := opts.FlagSet
+	if fs == nil {
+		fs = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	}
+	args := opts.Args
+	if args == nil {
+		args = os.Args[1:]
+	}
+	flags := map[string]any{}
+	for _, field := range append(managerFields, configFileField) {
+		name := "console-" + field.key
+		usage := fmt.Sprintf("%s (env: %s)", field.usage, envName(opts.EnvPrefix, field.key))
+		set := func(value string) (err error) {
+			flags[field.key] = value
+			return
+		}
+		if field.boolean {
+			fs.BoolFunc(name, usage, func(value string) (err error) {
+				if _, err = strconv.ParseBool(value); err != nil {
+					return
+				}
+				err = set(value)
+				return
+			})
+			continue
+		}
+		fs.Func(name, usage, set)
+	}
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+
+	settings := map[string]any{}
+	configFile := opts.ConfigFile
+	if path, ok := os.LookupEnv(envName(opts.EnvPrefix, configFileKey)); ok {
+		configFile = path
+	}
+	if path, ok := flags[configFileKey].(string); ok {
+		configFile = path
+	}
+	delete(flags, configFileKey)
+	if configFile != "" {
+		if settings, err = readConfigFile(configFile); err != nil {
+			return
+		}
+	}
+	for _, field := range managerFields {
+		if value, ok := os.LookupEnv(envName(opts.EnvPrefix, field.key)); ok {
+			settings[field.key] = value
+		}
+	}
+	for key, value := range flags {
+		settings[key] = value
+	}
+
+	config = opts.Defaults
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.ComposeDecodeHookFunc(sdk.DurationMapstructureHook(), mapstructure.StringToWeakSliceHookFunc(",")),
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+		Result:           &config,
+	})
+	if err != nil {
+		return
+	}
+	if err = decoder.Decode(settings); err != nil {
+		err = fmt.Errorf("invalid connector manager config, %w", err)
+		return
+	}
+	switch {
+	case config.URL == "":
+		err = fmt.Errorf("%w, url is required (-console-url or %s)", ErrMissingManagerConfig, envName(opts.EnvPrefix, "url"))
+	case config.APIKey == "" && config.Authenticator == nil:
+		err = fmt.Errorf("%w, api key is required (-console-api-key or %s)", ErrMissingManagerConfig, envName(opts.EnvPrefix, "api-key"))
+	}
+	return
+}
+
+// envName returns environment variable of key, e.g. DUMMY_CONSOLE_API_KEY for api-key.
+func envName(prefix string, key string) string {
+	name := "CONSOLE_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+	if prefix == "" {
+		return name
+	}
+	return prefix + "_" + name
+}
+
+func readConfigFile(path string) (settings map[string]any, err error) {
+	raw, err := os.ReadFile(path) //nolint:gosec // path given by operator
+	if err != nil {
+		err = fmt.Errorf("could not read connector manager config file, %w", err)
+		return
+	}
+	// yaml is a superset of json
+	settings = map[string]any{}
+	if err = yaml.Unmarshal(raw, &settings); err != nil {
+		err = fmt.Errorf("invalid connector manager config file %s, %w", path, err)
+		return
+	}
+	if settings == nil {
+		settings = map[string]any{}
+	}
+	return
+}
@@ -0,0 +1,149 @@
+package bootstrap
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestLoadManagerConfig(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "manager.yaml")
+	if err := os.WriteFile(configFile, []byte("url: https://file.example.com\napi-key: file-key\ntasks-wait: 30s\ntask-queue-size: 20\n"), 0o600); err != nil {
+		t.Fatalf("could not write config file, error = %v", err)
+	}
+	jsonFile := filepath.Join(dir, "manager.json")
+	if err := os.WriteFile(jsonFile, []byte(`{"url":"https://json.example.com","api-key":"json-key","unauthorized-backoff":1000000000}`), 0o600); err != nil {
+		t.Fatalf("could not write config file, error = %v", err)
+	}
+	unknownFile := filepath.Join(dir, "unknown.yaml")
+	if err := os.WriteFile(unknownFile, []byte("url: https://file.example.com\napi_key: file-key\n"), 0o600); err != nil {
+		t.Fatalf("could not write config file, error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		env     map[string]string
+		want    sdk.ConnectorManagerClientConfig
+		wantErr error
+	}{
+		{
+			name: "environment",
+			opts: Options{EnvPrefix: "DUMMY", Args: []string{}, Defaults: sdk.ConnectorManagerClientConfig{ConnectorType: sdk.DummyKey}},
+			env:  map[string]string{"DUMMY_CONSOLE_URL": "https://env.example.com", "DUMMY_CONSOLE_API_KEY": "env-key", "DUMMY_CONSOLE_INSECURE": "true"},
+			want: sdk.ConnectorManagerClientConfig{ConnectorType: sdk.DummyKey, URL: "https://env.example.com", APIKey: "env-key", Insecure: true},
+		},
+		{
+			name: "flags over environment over file",
+			opts: Options{EnvPrefix: "DUMMY", Args: []string{"-console-api-key", "flag-key", "-console-insecure", "-console-unauthorized-retries=3"}, ConfigFile: configFile},
+			env:  map[string]string{"DUMMY_CONSOLE_API_KEY": "env-key", "DUMMY_CONSOLE_UNAUTHORIZED_BACKOFF": "5s"},
+			want: sdk.ConnectorManagerClientConfig{
+				URL:                 "https://file.example.com",
+				APIKey:              "flag-key",
+				Insecure:            true,
+				TasksWait:           30 * time.Second,
+				TaskQueueSize:       20,
+				UnauthorizedRetries: 3,
+				UnauthorizedBackoff: 5 * time.Second,
+			},
+		},
+		{
+			name: "retry policy",
+			opts: Options{Args: []string{"-console-url", "https://flag.example.com", "-console-api-key", "flag-key", "-console-retry-max-retries=5"}},
+			env:  map[string]string{"CONSOLE_RETRY_STATUS_CODES": "502,503", "CONSOLE_RETRY_MAX_ELAPSED": "1m"},
+			want: sdk.ConnectorManagerClientConfig{
+				URL:              "https://flag.example.com",
+				APIKey:           "flag-key",
+				RetryMaxElapsed:  time.Minute,
+				RetryMaxRetries:  5,
+				RetryStatusCodes: []int{502, 503},
+			},
+		},
+		{
+			name: "compressed requests",
+			opts: Options{Args: []string{"-console-url", "https://flag.example.com", "-console-api-key", "flag-key", "-console-compress-requests"}},
+			want: sdk.ConnectorManagerClientConfig{URL: "https://flag.example.com", APIKey: "flag-key", CompressRequests: true},
+		},
+		{
+			name: "config file from environment",
+			opts: Options{Args: []string{}, ConfigFile: configFile},
+			env:  map[string]string{"CONSOLE_CONFIG": jsonFile},
+			want: sdk.ConnectorManagerClientConfig{URL: "https://json.example.com", APIKey: "json-key", UnauthorizedBackoff: time.Second},
+		},
+		{
+			name: "config file from flag",
+			opts: Options{Args: []string{"-console-config", jsonFile}},
+			want: sdk.ConnectorManagerClientConfig{URL: "https://json.example.com", APIKey: "json-key", UnauthorizedBackoff: time.Second},
+		},
+		{
+			name: "authenticator default",
+			opts: Options{Args: []string{"-console-url", "https://flag.example.com"}, Defaults: sdk.ConnectorManagerClientConfig{Authenticator: sdk.NewAPIKeyAuthenticator("key")}},
+			want: sdk.ConnectorManagerClientConfig{URL: "https://flag.example.com"},
+		},
+		{
+			name:    "missing url",
+			opts:    Options{Args: []string{"-console-api-key", "flag-key"}},
+			wantErr: ErrMissingManagerConfig,
+		},
+		{
+			name:    "missing api key",
+			opts:    Options{EnvPrefix: "DUMMY", Args: []string{}},
+			env:     map[string]string{"DUMMY_CONSOLE_URL": "https://env.example.com"},
+			wantErr: ErrMissingManagerConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			got, err := LoadManagerConfig(tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LoadManagerConfig() error = %v, want %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(got, tt.want, cmpopts.IgnoreFields(sdk.ConnectorManagerClientConfig{}, "Authenticator")); tt.wantErr == nil && diff != "" {
+				t.Errorf("LoadManagerConfig() diff(got-want)=%s", diff)
+			}
+		})
+	}
+
+	t.Run("invalid settings", func(t *testing.T) {
+		fs := flag.NewFlagSet("connector", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		invalid := []Options{
+			{FlagSet: fs, Args: []string{"-console-insecure=maybe"}},
+			{Args: []string{"-console-url", "https://flag.example.com", "-console-api-key", "key", "-console-tasks-wait", "soon"}},
+			{Args: []string{"-console-config", unknownFile}},
+			{Args: []string{"-console-config", filepath.Join(dir, "missing.yaml")}},
+		}
+		for _, opts := range invalid {
+			if opts.FlagSet == nil {
+				opts.FlagSet = flag.NewFlagSet("connector", flag.ContinueOnError)
+				opts.FlagSet.SetOutput(io.Discard)
+			}
+			if _, err := LoadManagerConfig(opts); err == nil {
+				t.Errorf("LoadManagerConfig(%v) error = nil, want an error", opts.Args)
+			}
+		}
+	})
+
+	t.Run("connector flags", func(t *testing.T) {
+		fs := flag.NewFlagSet("connector", flag.ContinueOnError)
+		workers := fs.Int("workers", 1, "number of workers")
+		if _, err := LoadManagerConfig(Options{FlagSet: fs, Args: []string{"-workers", "8", "-console-url", "https://flag.example.com", "-console-api-key", "key"}}); err != nil {
+			t.Fatalf("LoadManagerConfig() error = %v", err)
+		}
+		if *workers != 8 {
+			t.Errorf("workers = %d, want 8", *workers)
+		}
+	})
+}
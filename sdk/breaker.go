@@ -0,0 +1,120 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultBreakerProbeInterval is the delay between probes of manager while circuit is open, when
+// ConnectorManagerClientConfig.BreakerProbeInterval is 0.
+const DefaultBreakerProbeInterval = 30 * time.Second
+
+// ErrCircuitOpen is returned by requests failed fast, manager being unavailable (see
+// ConnectorManagerClientConfig.BreakerThreshold).
+var ErrCircuitOpen = errors.New("connector manager circuit open")
+
+// BreakerState is the state of the circuit breaker of requests to manager.
+type BreakerState int
+
+const (
+	// BreakerClosed sends requests to manager.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails requests fast with ErrCircuitOpen, manager being unavailable.
+	BreakerOpen
+	// BreakerHalfOpen sends a probe request to manager, other requests failing fast until it succeeds.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker opens after threshold failed requests in a row, then lets a probe request through every
+// probeInterval, closing once one succeeds.
+type circuitBreaker struct {
+	threshold     int // 0 disables breaker
+	probeInterval time.Duration
+	now           func() time.Time
+
+	lock     sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time // or last probe failure
+}
+
+func newCircuitBreaker(threshold int, probeInterval time.Duration) (b *circuitBreaker) {
+	if probeInterval <= 0 {
+		probeInterval = DefaultBreakerProbeInterval
+	}
+	return &circuitBreaker{threshold: threshold, probeInterval: probeInterval, now: time.Now}
+}
+
+// allow returns ErrCircuitOpen if request must fail fast. An allowed request must be reported with done.
+func (b *circuitBreaker) allow() (err error) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.probeInterval {
+			err = ErrCircuitOpen
+			return
+		}
+		b.state = BreakerHalfOpen
+		logger.Debug("probe connector manager")
+	case BreakerHalfOpen:
+		// a probe is in flight
+		err = ErrCircuitOpen
+	}
+	return
+}
+
+// done reports result of an allowed request: err is nil if manager answered.
+func (b *circuitBreaker) done(err error) {
+	if b.threshold <= 0 {
+		return
+	}
+	// requests canceled by connector tell nothing about manager
+	failed := err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, ErrInvalidTLSConfig)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	switch {
+	case !failed && b.state == BreakerHalfOpen:
+		logger.Info("connector manager is back, circuit closed")
+		b.state = BreakerClosed
+		b.failures = 0
+	case !failed:
+		b.failures = 0
+	case b.state == BreakerHalfOpen:
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	default:
+		b.failures++
+		if b.state == BreakerClosed && b.failures >= b.threshold {
+			logger.Warn("connector manager unavailable, circuit open", slog.Int("failures", b.failures), slog.String("error", err.Error()))
+			b.state = BreakerOpen
+			b.openedAt = b.now()
+		}
+	}
+}
+
+// BreakerState returns state of the circuit breaker of requests to manager, always BreakerClosed without
+// ConnectorManagerClientConfig.BreakerThreshold.
+func (c ConnectorManagerClient) BreakerState() (state BreakerState) {
+	c.breaker.lock.Lock()
+	defer c.breaker.lock.Unlock()
+	state = c.breaker.state
+	return
+}
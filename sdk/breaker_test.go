@@ -0,0 +1,150 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	failure := errors.New("connection refused")
+	type step struct {
+		after     time.Duration // clock moved forward before request
+		err       error         // request result, if allowed
+		wantErr   error
+		wantState BreakerState
+	}
+	tests := []struct {
+		name      string
+		threshold int
+		steps     []step
+	}{
+		{
+			name:  "disabled",
+			steps: []step{{err: failure}, {err: failure}, {err: failure}},
+		},
+		{
+			name:      "opens after threshold",
+			threshold: 2,
+			steps: []step{
+				{err: failure},
+				{err: nil},
+				{err: failure},
+				{err: failure, wantState: BreakerOpen},
+				{wantErr: ErrCircuitOpen, wantState: BreakerOpen},
+			},
+		},
+		{
+			name:      "canceled requests ignored",
+			threshold: 1,
+			steps:     []step{{err: context.Canceled}, {err: ErrInvalidTLSConfig}},
+		},
+		{
+			name:      "probe succeeds",
+			threshold: 1,
+			steps: []step{
+				{err: failure, wantState: BreakerOpen},
+				{after: 10 * time.Second, wantErr: ErrCircuitOpen, wantState: BreakerOpen},
+				{after: 20 * time.Second, err: nil},
+				{err: nil},
+			},
+		},
+		{
+			name:      "probe fails",
+			threshold: 1,
+			steps: []step{
+				{err: failure, wantState: BreakerOpen},
+				{after: 30 * time.Second, err: failure, wantState: BreakerOpen},
+				{after: 20 * time.Second, wantErr: ErrCircuitOpen, wantState: BreakerOpen},
+				{after: 10 * time.Second, err: nil},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			b := newCircuitBreaker(tt.threshold, 0)
+			b.now = func() time.Time { return now }
+			for i, s := range tt.steps {
+				now = now.Add(s.after)
+				err := b.allow()
+				if !errors.Is(err, s.wantErr) {
+					t.Fatalf("step %d allow() error = %v, want %v", i, err, s.wantErr)
+				}
+				if err == nil {
+					b.done(s.err)
+				}
+				if b.state != s.wantState {
+					t.Fatalf("step %d state = %s, want %s", i, b.state, s.wantState)
+				}
+			}
+		})
+	}
+}
+
+func TestConnectorManagerClient_breaker(t *testing.T) {
+	var calls atomic.Int64
+	var down atomic.Bool
+	down.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{
+		URL:                  server.URL,
+		APIKey:               "key",
+		RetryMaxRetries:      1,
+		RetryInitialInterval: time.Millisecond,
+		BreakerThreshold:     2,
+		BreakerProbeInterval: time.Hour,
+	})
+	now := time.Now()
+	c.breaker.now = func() time.Time { return now }
+	var gotErrs []error
+	for range 3 {
+		_, err := c.getTasks(t.Context())
+		gotErrs = append(gotErrs, err)
+	}
+	if !errors.Is(gotErrs[2], ErrCircuitOpen) || errors.Is(gotErrs[1], ErrCircuitOpen) {
+		t.Fatalf("getTasks() errors = %v, want circuit open on third request", gotErrs)
+	}
+	// 2 requests of 2 tries each, third one failed fast
+	if got := calls.Load(); got != 4 {
+		t.Errorf("manager calls = %d, want 4", got)
+	}
+	if got := c.BreakerState(); got != BreakerOpen {
+		t.Errorf("BreakerState() = %s, want %s", got, BreakerOpen)
+	}
+
+	rec := httptest.NewRecorder()
+	c.HealthHandler(func() Connector { return nil }, 0).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReadinessPath, nil))
+	var report healthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(report.Breaker, "open"); diff != "" {
+		t.Errorf("readiness breaker diff(got-want)=%s", diff)
+	}
+
+	down.Store(false)
+	now = now.Add(time.Hour)
+	if _, err := c.getTasks(t.Context()); err != nil {
+		t.Fatalf("getTasks() probe error = %v", err)
+	}
+	if got := c.BreakerState(); got != BreakerClosed {
+		t.Errorf("BreakerState() = %s, want %s", got, BreakerClosed)
+	}
+}
@@ -0,0 +1,170 @@
+package sdk
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrInvalidCACerts is returned for custom CA certificates that are not PEM encoded X.509 certificates.
+var ErrInvalidCACerts = errors.New("invalid custom ca certificates")
+
+// PEMBundle is a list of PEM encoded certificates, each entry holding one or more of them. It is decoded from a
+// string array, or from a single (multi-line) string.
+type PEMBundle []string
+
+func (b *PEMBundle) UnmarshalJSON(raw []byte) (err error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '"' {
+		err = json.Unmarshal(raw, (*[]string)(b))
+		return
+	}
+	var s string
+	if err = json.Unmarshal(raw, &s); err != nil {
+		return
+	}
+	*b = PEMBundle{}
+	if s != "" {
+		*b = PEMBundle{s}
+	}
+	return
+}
+
+// Certificates parses certificates of b.
+func (b PEMBundle) Certificates() (certs []*x509.Certificate, err error) {
+	for i, entry := range b {
+		rest := []byte(entry)
+		found := false
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, parseErr := x509.ParseCertificate(block.Bytes)
+			if parseErr != nil {
+				err = fmt.Errorf("%w: entry %d: %w", ErrInvalidCACerts, i, parseErr)
+				return
+			}
+			certs = append(certs, cert)
+			found = true
+		}
+		if !found {
+			err = fmt.Errorf("%w: entry %d: no PEM certificate found", ErrInvalidCACerts, i)
+			return
+		}
+	}
+	return
+}
+
+// CertPool returns system roots with CustomCACertsPEM certificates added, nil if no custom CA is configured.
+func (c CommonConnectorConfig) CertPool() (pool *x509.CertPool, err error) {
+	if len(c.CustomCACertsPEM) == 0 {
+		return
+	}
+	certs, err := c.CustomCACertsPEM.Certificates()
+	if err != nil {
+		return
+	}
+	pool, sysErr := x509.SystemCertPool()
+	if sysErr != nil {
+		logger.Warn("could not load system certificates, only custom ca certificates are trusted", slog.String("error", sysErr.Error()))
+		pool = x509.NewCertPool()
+	}
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return
+}
+
+// TLSConfig returns the TLS config of clients to GLIMPS Malware: custom CA certificates are trusted along system
+// ones, and certificates are not checked at all with GMalwareNoCertCheck.
+func (c CommonConnectorConfig) TLSConfig() (tlsConfig *tls.Config, err error) {
+	pool, err := c.CertPool()
+	if err != nil {
+		return
+	}
+	tlsConfig = NewTLSConfig(c.GMalwareNoCertCheck)
+	tlsConfig.RootCAs = pool
+	return
+}
+
+// SetRootCAs makes client trust pool for manager certificates, system roots if nil. It is set from console
+// config at registration and on config updates (see CommonConnectorConfig.CertPool).
+func (c ConnectorManagerClient) SetRootCAs(pool *x509.CertPool) {
+	c.transport.setRootCAs(pool)
+}
+
+// applyConsoleCACerts sets custom CA certificates of raw console config, keeping current ones if they are invalid.
+func (c ConnectorManagerClient) applyConsoleCACerts(raw json.RawMessage) {
+	common := CommonConnectorConfig{}
+	if err := json.Unmarshal(raw, &common); err != nil {
+		logger.Warn("could not read custom ca certificates of console config", slog.String("error", err.Error()))
+		return
+	}
+	pool, err := common.CertPool()
+	if err != nil {
+		logger.Warn("could not set custom ca certificates of console config", slog.String("error", err.Error()))
+		return
+	}
+	c.SetRootCAs(pool)
+}
+
+// rootCAsTransport is the transport of requests to manager, a clone of base with current root CAs. Transport is
+// replaced when root CAs change, as a transport TLS config must not be modified once used.
+type rootCAsTransport struct {
+	base    *http.Transport
+	caCerts []*x509.Certificate // CA bundle of client config, trusted along root CAs
+	current atomic.Pointer[http.Transport]
+}
+
+func newRootCAsTransport(base *http.Transport, caCerts []*x509.Certificate) (t *rootCAsTransport) {
+	t = &rootCAsTransport{base: base, caCerts: caCerts}
+	t.current.Store(base)
+	return
+}
+
+func (t *rootCAsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.current.Load().RoundTrip(req)
+}
+
+func (t *rootCAsTransport) CloseIdleConnections() {
+	t.current.Load().CloseIdleConnections()
+}
+
+func (t *rootCAsTransport) setRootCAs(pool *x509.CertPool) {
+	if pool != nil && len(t.caCerts) > 0 {
+		pool = pool.Clone()
+		for _, cert := range t.caCerts {
+			pool.AddCert(cert)
+		}
+	}
+	current := t.current.Load()
+	var currentPool *x509.CertPool
+	if current.TLSClientConfig != nil {
+		currentPool = current.TLSClientConfig.RootCAs
+	}
+	if currentPool.Equal(pool) {
+		return
+	}
+	transport := t.base.Clone()
+	if pool != nil {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = NewTLSConfig(false)
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	if old := t.current.Swap(transport); old != nil {
+		old.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,124 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testPEMCert(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+}
+
+func TestPEMBundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	cert := testPEMCert(t, server)
+	raw, err := json.Marshal(cert)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		raw       string
+		want      PEMBundle
+		wantCerts int
+		wantErr   error
+	}{
+		{name: "null", raw: `null`},
+		{name: "empty string", raw: `""`, want: PEMBundle{}},
+		{name: "string", raw: string(raw), want: PEMBundle{cert}, wantCerts: 1},
+		{name: "concatenated", raw: `"` + strings.Trim(string(raw), `"`) + strings.Trim(string(raw), `"`) + `"`, want: PEMBundle{cert + cert}, wantCerts: 2},
+		{name: "array", raw: `[` + string(raw) + `,` + string(raw) + `]`, want: PEMBundle{cert, cert}, wantCerts: 2},
+		{name: "not pem", raw: `["certificate"]`, want: PEMBundle{"certificate"}, wantErr: ErrInvalidCACerts},
+		{
+			name:    "invalid certificate",
+			raw:     `["-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"]`,
+			want:    PEMBundle{"-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"},
+			wantErr: ErrInvalidCACerts,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got PEMBundle
+			if err := json.Unmarshal([]byte(tt.raw), &got); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("json.Unmarshal() diff(got-want)=%s", diff)
+			}
+			certs, err := got.Certificates()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Certificates() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(certs) != tt.wantCerts {
+				t.Errorf("Certificates() = %d certificates, want %d", len(certs), tt.wantCerts)
+			}
+		})
+	}
+}
+
+// untrustedCtx bounds requests failing on certificate check, retried until their context is done.
+func untrustedCtx(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestConnectorManagerClient_customCACerts(t *testing.T) {
+	var config []byte
+	manager := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case basePath + "/register":
+			_, _ = w.Write([]byte(`{"config":` + string(config) + `}`))
+		default:
+			_, _ = w.Write([]byte(`{"tasks":[]}`))
+		}
+	}))
+	manager.Config.ErrorLog = log.New(io.Discard, "", 0)
+	defer manager.Close()
+	config, err := json.Marshal(map[string]any{"custom_ca_certs_pem": testPEMCert(t, manager)})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: manager.URL, APIKey: "key"})
+	if _, err = c.getTasks(untrustedCtx(t)); err == nil {
+		t.Fatal("getTasks() with untrusted manager certificate, want error")
+	}
+
+	pool, err := CommonConnectorConfig{CustomCACertsPEM: PEMBundle{testPEMCert(t, manager)}}.CertPool()
+	if err != nil {
+		t.Fatalf("CertPool() error = %v", err)
+	}
+	c.SetRootCAs(pool)
+	if err = c.Register(t.Context(), "1.0.0", &RegistrationInfo{Config: &DummyConfig{}}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	// custom CA of console config kept on a config update with an invalid one
+	if err = c.configure(t.Context(), &fakeConnector{}, []byte(`{"custom_ca_certs_pem":"certificate"}`)); err != nil {
+		t.Fatalf("configure() error = %v", err)
+	}
+	if _, err = c.getTasks(t.Context()); err != nil {
+		t.Fatalf("getTasks() error = %v", err)
+	}
+	// and cleared by a config update without it
+	if err = c.configure(t.Context(), &fakeConnector{}, []byte(`{}`)); err != nil {
+		t.Fatalf("configure() error = %v", err)
+	}
+	if _, err = c.getTasks(untrustedCtx(t)); err == nil {
+		t.Error("getTasks() without custom CA, want error")
+	}
+}
@@ -0,0 +1,231 @@
+// Package changefeed unifies how connectors track incremental changes of monitored repositories (e.g. Graph delta
+// queries, S3 event notifications, filesystem watchers): sources list changes after an opaque cursor, and a Feed
+// persists acknowledged cursors in a state store, so monitoring resumes after restart without missing changes.
+package changefeed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/state"
+)
+
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: sdk.LogLevel})).WithGroup("changefeed")
+
+// DefaultPollInterval is the default delay between polls of a Feed source with no pending changes.
+const DefaultPollInterval = time.Minute
+
+// cursorKeyPrefix prefixes state keys of feed cursors.
+const cursorKeyPrefix = "changefeed/"
+
+var (
+	ErrInvalidFeed = errors.New("invalid change feed")
+	// ErrCursorExpired is returned by sources no longer able to list changes after a cursor (e.g. expired Graph
+	// delta token), feed is then listed again from its start.
+	ErrCursorExpired = errors.New("change feed cursor expired")
+)
+
+type ChangeKind string
+
+const (
+	Created  ChangeKind = "created"
+	Modified ChangeKind = "modified"
+	Deleted  ChangeKind = "deleted"
+)
+
+func (ChangeKind) Values() []ChangeKind {
+	return []ChangeKind{Created, Modified, Deleted}
+}
+
+// Change is a change of a monitored item.
+type Change struct {
+	// ID identifies item in its repository (e.g. drive item id, object key, file path)
+	ID   string
+	Kind ChangeKind
+	Path string
+	Size int64
+	Time time.Time
+}
+
+// Batch is a batch of changes listed by a Source.
+type Batch struct {
+	Changes []Change
+	// Cursor is the position of feed after changes, to list next changes from once they are acknowledged
+	Cursor string
+	// More is true when changes are pending after Cursor, so they are listed without waiting
+	More bool
+}
+
+// Source lists changes of a repository, implemented by adapters (e.g. Graph delta queries, S3 event notifications,
+// filesystem watchers).
+type Source interface {
+	// Changes returns changes after cursor, empty for the start of feed (e.g. every item, or changes from now on,
+	// depending on source). It returns ErrCursorExpired if cursor is no longer valid.
+	Changes(ctx context.Context, cursor string) (batch Batch, err error)
+}
+
+// Waiter may be implemented by push sources (e.g. event notifications, filesystem watchers): Wait returns when
+// changes may be available, so feed is polled on notifications rather than on interval.
+type Waiter interface {
+	Wait(ctx context.Context) (err error)
+}
+
+// Handler handles a change. Failed changes are logged, they do not stop feed.
+type Handler func(ctx context.Context, change Change) (err error)
+
+// ChangeSource is how connectors consume incremental changes: either by subscribing to them, or by polling and
+// acknowledging batches themselves.
+type ChangeSource interface {
+	// Subscribe handles changes until ctx is done, acknowledging each handled batch
+	Subscribe(ctx context.Context, handle Handler) (err error)
+	// Poll returns changes after last acknowledged cursor
+	Poll(ctx context.Context) (batch Batch, err error)
+	// Ack acknowledges changes up to cursor (a Batch cursor), next Poll returns changes after it
+	Ack(ctx context.Context, cursor string) (err error)
+}
+
+var _ ChangeSource = &Feed{}
+
+type FeedOptions struct {
+	// Name identifies feed in state store, e.g. "sharepoint-delta", required
+	Name string
+	// Store persists acknowledged cursors, required
+	Store state.Store
+	// PollInterval is the delay between polls of sources not implementing Waiter, or failing,
+	// DefaultPollInterval if 0
+	PollInterval time.Duration
+}
+
+// Feed is a ChangeSource over a Source, its acknowledged cursor persisted in a state store.
+type Feed struct {
+	source Source
+	opts   FeedOptions
+}
+
+// NewFeed returns a feed of source changes.
+func NewFeed(source Source, opts FeedOptions) (f *Feed, err error) {
+	switch {
+	case source == nil:
+		err = fmt.Errorf("%w, source is required", ErrInvalidFeed)
+	case opts.Name == "":
+		err = fmt.Errorf("%w, name is required", ErrInvalidFeed)
+	case opts.Store == nil:
+		err = fmt.Errorf("%w, state store is required", ErrInvalidFeed)
+	}
+	if err != nil {
+		return
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	f = &Feed{source: source, opts: opts}
+	return
+}
+
+func (f *Feed) key() string {
+	return cursorKeyPrefix + f.opts.Name
+}
+
+// Cursor returns last acknowledged cursor, empty if none.
+func (f *Feed) Cursor() (cursor string, err error) {
+	raw, err := f.opts.Store.Get(f.key())
+	if errors.Is(err, state.ErrNotFound) {
+		err = nil
+		return
+	}
+	cursor = string(raw)
+	return
+}
+
+func (f *Feed) Poll(ctx context.Context) (batch Batch, err error) {
+	cursor, err := f.Cursor()
+	if err != nil {
+		return
+	}
+	batch, err = f.source.Changes(ctx, cursor)
+	if errors.Is(err, ErrCursorExpired) && cursor != "" {
+		logger.Warn("cursor expired, listing changes from feed start", slog.String("feed", f.opts.Name))
+		batch, err = f.source.Changes(ctx, "")
+	}
+	if err != nil {
+		err = fmt.Errorf("could not list changes of %s, %w", f.opts.Name, err)
+	}
+	return
+}
+
+func (f *Feed) Ack(ctx context.Context, cursor string) (err error) {
+	if err = f.opts.Store.Put(f.key(), []byte(cursor)); err != nil {
+		err = fmt.Errorf("could not save cursor of %s, %w", f.opts.Name, err)
+	}
+	return
+}
+
+// Reset forgets acknowledged cursor, changes are listed again from feed start.
+func (f *Feed) Reset() (err error) {
+	return f.opts.Store.Delete(f.key())
+}
+
+// Subscribe polls changes until ctx is done, each batch being acknowledged once its changes are handled. A batch
+// interrupted by ctx is not acknowledged, so it is handled again on next subscription. Listing errors are logged and
+// retried after PollInterval.
+func (f *Feed) Subscribe(ctx context.Context, handle Handler) (err error) {
+	for {
+		batch, pollErr := f.Poll(ctx)
+		switch {
+		case ctx.Err() != nil:
+			err = ctx.Err()
+			return
+		case pollErr != nil:
+			logger.Warn("could not poll changes", slog.String("feed", f.opts.Name), slog.String("error", pollErr.Error()))
+		default:
+			if err = f.handle(ctx, batch, handle); err != nil {
+				return
+			}
+		}
+		if pollErr == nil && batch.More {
+			continue
+		}
+		if err = f.wait(ctx, pollErr != nil); err != nil {
+			return
+		}
+	}
+}
+
+func (f *Feed) handle(ctx context.Context, batch Batch, handle Handler) (err error) {
+	for _, change := range batch.Changes {
+		handleErr := handle(ctx, change)
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		if handleErr != nil {
+			logger.Warn("could not handle change", slog.String("feed", f.opts.Name), slog.String("item", change.ID), slog.String("error", handleErr.Error()))
+		}
+	}
+	err = f.Ack(ctx, batch.Cursor)
+	return
+}
+
+// wait waits for next poll: source notification, or PollInterval for sources not implementing Waiter (or failing to
+// wait) and failed polls.
+func (f *Feed) wait(ctx context.Context, failed bool) (err error) {
+	if waiter, ok := f.source.(Waiter); ok && !failed {
+		if err = waiter.Wait(ctx); err == nil || ctx.Err() != nil {
+			return
+		}
+		logger.Warn("could not wait for changes", slog.String("feed", f.opts.Name), slog.String("error", err.Error()))
+		err = nil
+	}
+	timer := time.NewTimer(f.opts.PollInterval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
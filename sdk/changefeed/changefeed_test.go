@@ -0,0 +1,188 @@
+package changefeed
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/state"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeSource lists its changes by batches of 2, cursors being change offsets.
+type fakeSource struct {
+	changes []Change
+	fails   int // number of failing listings
+}
+
+func (s *fakeSource) Changes(ctx context.Context, cursor string) (batch Batch, err error) {
+	if s.fails > 0 {
+		s.fails--
+		err = errors.New("service unavailable")
+		return
+	}
+	start := 0
+	if cursor != "" {
+		if start, err = strconv.Atoi(cursor); err != nil {
+			err = ErrCursorExpired
+			return
+		}
+	}
+	end := min(start+2, len(s.changes))
+	batch = Batch{Changes: s.changes[start:end], Cursor: strconv.Itoa(end), More: end < len(s.changes)}
+	return
+}
+
+// waitingSource is a push source, notified of changes on Wait.
+type waitingSource struct {
+	*fakeSource
+	wait func(ctx context.Context) error
+}
+
+func (s waitingSource) Wait(ctx context.Context) (err error) {
+	return s.wait(ctx)
+}
+
+func newStore(t *testing.T) state.Store {
+	t.Helper()
+	store, err := state.NewFileStore(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	return store
+}
+
+func testChanges(n int) (changes []Change) {
+	for i := range n {
+		changes = append(changes, Change{ID: strconv.Itoa(i), Kind: Modified, Path: "/docs/" + strconv.Itoa(i)})
+	}
+	return
+}
+
+func TestFeed_Subscribe(t *testing.T) {
+	source := &fakeSource{changes: testChanges(5), fails: 1}
+	feed, err := NewFeed(source, FeedOptions{Name: "delta", Store: newStore(t), PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewFeed() error = %v", err)
+	}
+	var handled []string
+	ctx, cancel := context.WithCancel(t.Context())
+	err = feed.Subscribe(ctx, func(ctx context.Context, change Change) (err error) {
+		handled = append(handled, change.ID)
+		switch change.ID {
+		case "1":
+			err = errors.New("item locked")
+		case "4":
+			// connector stopped while handling last batch
+			cancel()
+		}
+		return
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Subscribe() error = %v, want %v", err, context.Canceled)
+	}
+	if diff := cmp.Diff(handled, []string{"0", "1", "2", "3", "4"}); diff != "" {
+		t.Errorf("handled changes diff(got-want)=%s", diff)
+	}
+	cursor, err := feed.Cursor()
+	if err != nil || cursor != "4" {
+		t.Errorf("Cursor() = %q, %v, want interrupted batch not acknowledged", cursor, err)
+	}
+
+	// interrupted batch is listed again
+	batch, err := feed.Poll(t.Context())
+	if err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if diff := cmp.Diff(batch, Batch{Changes: source.changes[4:], Cursor: "5"}); diff != "" {
+		t.Errorf("Poll() diff(got-want)=%s", diff)
+	}
+	if err = feed.Ack(t.Context(), batch.Cursor); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if batch, err = feed.Poll(t.Context()); err != nil || len(batch.Changes) != 0 {
+		t.Errorf("Poll() after Ack = %+v, %v, want no change", batch, err)
+	}
+}
+
+func TestFeed_SubscribeWaiter(t *testing.T) {
+	source := &fakeSource{changes: testChanges(3)}
+	ctx, cancel := context.WithCancel(t.Context())
+	waits := 0
+	waiter := waitingSource{fakeSource: source, wait: func(ctx context.Context) (err error) {
+		waits++
+		if waits == 1 {
+			// new change notified
+			source.changes = append(source.changes, Change{ID: "3", Kind: Deleted})
+			return
+		}
+		cancel()
+		return ctx.Err()
+	}}
+	// source is not polled on interval
+	feed, err := NewFeed(waiter, FeedOptions{Name: "watch", Store: newStore(t), PollInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewFeed() error = %v", err)
+	}
+	var handled []string
+	err = feed.Subscribe(ctx, func(ctx context.Context, change Change) (err error) {
+		handled = append(handled, change.ID)
+		return
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Subscribe() error = %v, want %v", err, context.Canceled)
+	}
+	if diff := cmp.Diff(handled, []string{"0", "1", "2", "3"}); diff != "" {
+		t.Errorf("handled changes diff(got-want)=%s", diff)
+	}
+	if cursor, _ := feed.Cursor(); cursor != "4" {
+		t.Errorf("Cursor() = %q, want 4", cursor)
+	}
+}
+
+func TestFeed_PollExpiredCursor(t *testing.T) {
+	source := &fakeSource{changes: testChanges(1)}
+	feed, err := NewFeed(source, FeedOptions{Name: "delta", Store: newStore(t)})
+	if err != nil {
+		t.Fatalf("NewFeed() error = %v", err)
+	}
+	if err = feed.Ack(t.Context(), "expired-token"); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	batch, err := feed.Poll(t.Context())
+	if err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if diff := cmp.Diff(batch, Batch{Changes: source.changes, Cursor: "1"}); diff != "" {
+		t.Errorf("Poll() diff(got-want)=%s", diff)
+	}
+
+	if err = feed.Reset(); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if cursor, err := feed.Cursor(); err != nil || cursor != "" {
+		t.Errorf("Cursor() after Reset = %q, %v, want empty", cursor, err)
+	}
+}
+
+func TestNewFeed(t *testing.T) {
+	store := newStore(t)
+	tests := []struct {
+		name   string
+		source Source
+		opts   FeedOptions
+	}{
+		{name: "missing source", opts: FeedOptions{Name: "feed", Store: store}},
+		{name: "missing name", source: &fakeSource{}, opts: FeedOptions{Store: store}},
+		{name: "missing store", source: &fakeSource{}, opts: FeedOptions{Name: "feed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFeed(tt.source, tt.opts); !errors.Is(err, ErrInvalidFeed) {
+				t.Errorf("NewFeed() error = %v, want %v", err, ErrInvalidFeed)
+			}
+		})
+	}
+}
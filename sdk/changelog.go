@@ -0,0 +1,82 @@
+package sdk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	changelogFileName = "CHANGELOG.yaml"
+	changelogDate     = time.DateOnly
+)
+
+var (
+	ErrNoChangelog      = errors.New("no changelog for this connector type")
+	ErrInvalidChangelog = errors.New("invalid CHANGELOG.yaml")
+)
+
+// ChangelogRelease is a connector type release, listed in CHANGELOG.yaml of its folder, most recent first.
+type ChangelogRelease struct {
+	Version string   `yaml:"version" json:"version" desc:"e.g. 1.2.0"`
+	Date    string   `yaml:"date" json:"date" desc:"release date, YYYY-MM-DD"`
+	Entries []string `yaml:"entries" json:"entries" desc:"changes of this release"`
+}
+
+// decodeChangelog decodes CHANGELOG.yaml content. Unknown fields are rejected, every release requires a version,
+// a valid date and changes, releases being listed from the most recent.
+func decodeChangelog(rawContent []byte) (releases []ChangelogRelease, err error) {
+	dec := yaml.NewDecoder(bytes.NewReader(rawContent))
+	dec.KnownFields(true)
+	err = dec.Decode(&releases)
+	switch {
+	case errors.Is(err, io.EOF):
+		err = fmt.Errorf("%w: %s is empty", ErrInvalidChangelog, changelogFileName)
+		return
+	case err != nil:
+		err = fmt.Errorf("%w: %w", ErrInvalidChangelog, err)
+		return
+	}
+	var previous time.Time
+	for i, release := range releases {
+		if release.Version == "" {
+			err = fmt.Errorf("%w: release %d has no version", ErrInvalidChangelog, i)
+			return
+		}
+		date, dateErr := time.Parse(changelogDate, release.Date)
+		if dateErr != nil {
+			err = fmt.Errorf("%w: release %s has an invalid date, %w", ErrInvalidChangelog, release.Version, dateErr)
+			return
+		}
+		if i > 0 && date.After(previous) {
+			err = fmt.Errorf("%w: release %s is more recent than previous one", ErrInvalidChangelog, release.Version)
+			return
+		}
+		if len(release.Entries) == 0 {
+			err = fmt.Errorf("%w: release %s has no entries", ErrInvalidChangelog, release.Version)
+			return
+		}
+		previous = date
+	}
+	return
+}
+
+// GetConnectorTypeChangelog returns releases of given connector type, most recent first, e.g. to show users what
+// changed when prompting them to update a deployed connector.
+func (c ConnectorTypeLoader) GetConnectorTypeChangelog(connectorTypeID string) (releases []ChangelogRelease, err error) {
+	connectorType, ok := c.connectorsTypes[connectorTypeID]
+	if !ok {
+		err = ErrConnectorTypeNotFound
+		return
+	}
+	if len(connectorType.Changelog) == 0 {
+		err = ErrNoChangelog
+		return
+	}
+	releases = connectorType.Changelog
+	return
+}
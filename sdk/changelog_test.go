@@ -0,0 +1,86 @@
+package sdk
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_decodeChangelog(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        []ChangelogRelease
+		wantMessage string
+	}{
+		{
+			name:    "ok",
+			content: "- version: 1.1.0\n  date: 2026-09-01\n  entries: [fix]\n- version: 1.0.0\n  date: 2026-09-01\n  entries: [first]\n",
+			want: []ChangelogRelease{
+				{Version: "1.1.0", Date: "2026-09-01", Entries: []string{"fix"}},
+				{Version: "1.0.0", Date: "2026-09-01", Entries: []string{"first"}},
+			},
+		},
+		{name: "empty", wantMessage: "CHANGELOG.yaml is empty"},
+		{name: "unknown field", content: "- version: 1.0.0\n  date: 2026-09-01\n  entries: [first]\n  notes: typo\n", wantMessage: "line 4: field notes not found"},
+		{name: "no version", content: "- date: 2026-09-01\n  entries: [first]\n", wantMessage: "release 0 has no version"},
+		{name: "invalid date", content: "- version: 1.0.0\n  date: 01/09/2026\n  entries: [first]\n", wantMessage: "release 1.0.0 has an invalid date"},
+		{name: "no entries", content: "- version: 1.0.0\n  date: 2026-09-01\n", wantMessage: "release 1.0.0 has no entries"},
+		{
+			name:        "oldest first",
+			content:     "- version: 1.0.0\n  date: 2026-06-15\n  entries: [first]\n- version: 1.1.0\n  date: 2026-09-01\n  entries: [fix]\n",
+			wantMessage: "release 1.1.0 is more recent than previous one",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeChangelog([]byte(tt.content))
+			if tt.wantMessage != "" {
+				if !errors.Is(err, ErrInvalidChangelog) || !strings.Contains(err.Error(), tt.wantMessage) {
+					t.Fatalf("decodeChangelog() error = %v, want ErrInvalidChangelog containing %q", err, tt.wantMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeChangelog() error = %v", err)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("decodeChangelog() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectorTypeLoader_GetConnectorTypeChangelog(t *testing.T) {
+	c, err := NewConnectorsTypesLoader(true)
+	if err != nil {
+		t.Fatalf("could not init connector types loader, err: %v", err)
+	}
+	tests := []struct {
+		name          string
+		connectorType string
+		wantVersions  []string
+		wantErr       error
+	}{
+		{name: "ok dummy", connectorType: DummyKey, wantVersions: []string{"1.1.0", "1.0.0"}},
+		{name: "error no changelog", connectorType: ICAPKey, wantErr: ErrNoChangelog},
+		{name: "error connector type not found", connectorType: "toto", wantErr: ErrConnectorTypeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			releases, err := c.GetConnectorTypeChangelog(tt.connectorType)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConnectorTypeLoader.GetConnectorTypeChangelog() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var gotVersions []string
+			for _, release := range releases {
+				gotVersions = append(gotVersions, release.Version)
+			}
+			if diff := cmp.Diff(gotVersions, tt.wantVersions); diff != "" {
+				t.Errorf("ConnectorTypeLoader.GetConnectorTypeChangelog() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
-	"crypto/tls"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -14,11 +14,16 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"slices"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/cenkalti/backoff/v5"
 	"github.com/glimps-re/connector-integration/sdk/events"
 	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/glimps-re/connector-integration/sdk/secrets"
+	"github.com/glimps-re/connector-integration/sdk/state"
 	"github.com/glimps-re/go-gdetect/pkg/gdetect"
 )
 
@@ -28,10 +33,10 @@ var logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level:
 
 const (
 	// Error code
-	InvalidAPIKeyCode     = 1
-	RevokedAPIKeyCode     = 2
-	basePath              = "/api/v1/connectors"
-	taskChannelBufferSize = 10
+	InvalidAPIKeyCode    = 1
+	RevokedAPIKeyCode    = 2
+	basePath             = "/api/v1/connectors"
+	DefaultTaskQueueSize = 10
 )
 
 type APIErrorResponse struct {
@@ -41,6 +46,7 @@ type APIErrorResponse struct {
 var (
 	ErrUnauthorizedConnector = errors.New("connector's api key is either revoked or invalid")
 	ErrNoHelmConfig          = errors.New("no specific helm config for this connector type")
+	ErrNoMSAuthConfig        = errors.New("no microsoft entra app registration for this connector type")
 	ErrInvalidConnectorType  = errors.New("invalid connector type")
 	ErrNoHelmForConnector    = errors.New("no helm chart available for this connector")
 	ErrNoComposeForConnector = errors.New("no docker compose available for this connector")
@@ -49,17 +55,112 @@ var (
 // Context Key that can be used to insert a specific X-Request-Id header
 type CtxRequestIDKey struct{}
 
+// RequestIDFromContext returns request ID set in context, e.g. the one of the task being handled
+// in contexts given to Connector methods.
+func RequestIDFromContext(ctx context.Context) (reqID string) {
+	reqID, _ = ctx.Value(CtxRequestIDKey{}).(string)
+	return
+}
+
 type ConnectorManagerClientConfig struct {
-	URL      string `mapstructure:"url"`
-	APIKey   string `mapstructure:"api-key"`
-	Insecure bool   `mapstructure:"insecure"`
+	// ConnectorType (e.g. HostKey) identifies connector population in User-Agent of requests
+	ConnectorType string `mapstructure:"connector-type"`
+	URL           string `mapstructure:"url"`
+	APIKey        string `mapstructure:"api-key"`
+	// Authenticator sets credentials of requests to manager (e.g. OAuth2Authenticator, JWTAuthenticator)
+	// in place of APIKey. A migrated connector uses the api key given by its new manager.
+	Authenticator Authenticator `mapstructure:"-"`
+	Insecure      bool          `mapstructure:"insecure"`
+	// CACertsFile is an optional PEM bundle of CA certificates trusted for manager certificate, along system ones
+	// (and console custom CA certificates).
+	CACertsFile string `mapstructure:"ca-certs-file"`
+	// ClientCertFile and ClientKeyFile are an optional PEM client certificate and its key, presented to managers
+	// requiring mutual TLS. They are read when client is created.
+	ClientCertFile string `mapstructure:"client-cert-file"`
+	ClientKeyFile  string `mapstructure:"client-key-file"`
+	// MinTLSVersion is the minimum TLS version of requests to manager, "1.2" (default) or "1.3".
+	MinTLSVersion string `mapstructure:"min-tls-version"`
+	// TasksWait enables long polling of tasks: manager holds GET /tasks until tasks exist or TasksWait elapses.
+	// 0 disables long polling.
+	TasksWait time.Duration `mapstructure:"tasks-wait"`
+	// TaskStream receives tasks pushed by manager in real time on a long-lived stream (GET tasks/stream, one task JSON
+	// object per line), tasks being polled meanwhile if manager does not support it or stream cannot be opened.
+	TaskStream bool `mapstructure:"task-stream"`
+	// PollInterval is the delay between tasks requests, DefaultPollInterval if 0 without long polling (no delay with
+	// it). Failed requests in a row are retried with an exponential backoff, from DefaultPollErrorBackoff.
+	PollInterval time.Duration `mapstructure:"poll-interval"`
+	// PollJitter is the maximum random delay added to PollInterval, so connectors started together do not poll in
+	// sync.
+	PollJitter time.Duration `mapstructure:"poll-jitter"`
+	// TaskQueueSize is the number of tasks received and waiting to be handled, per priority (see TaskPriority),
+	// DefaultTaskQueueSize if 0. Tasks are no longer fetched while a queue is full.
+	TaskQueueSize int `mapstructure:"task-queue-size"`
+	// UnauthorizedRetries is the number of unauthorized responses tolerated in a row before giving up,
+	// e.g. during api key rotation. 0 gives up on first one.
+	UnauthorizedRetries int `mapstructure:"unauthorized-retries"`
+	// UnauthorizedBackoff is the initial wait between unauthorized retries, doubled on each retry with jitter,
+	// DefaultUnauthorizedBackoff if 0.
+	UnauthorizedBackoff time.Duration `mapstructure:"unauthorized-backoff"`
+	// RetryMaxElapsed bounds retries of a failed request to manager (no response, or a RetryStatusCodes status),
+	// DefaultRetryMaxElapsed if 0. Retries wait an exponential backoff from RetryInitialInterval
+	// (DefaultRetryInitialInterval if 0).
+	RetryMaxElapsed      time.Duration `mapstructure:"retry-max-elapsed"`
+	RetryInitialInterval time.Duration `mapstructure:"retry-initial-interval"`
+	// RetryMaxRetries is the maximum number of retries of a request, only bounded by RetryMaxElapsed if 0.
+	RetryMaxRetries int `mapstructure:"retry-max-retries"`
+	// RetryStatusCodes are manager response statuses retried, DefaultRetryStatusCodes (502) if empty.
+	RetryStatusCodes []int `mapstructure:"retry-status-codes"`
+	// BreakerThreshold is the number of failed requests in a row (retries included) after which manager is deemed
+	// unavailable: requests then fail fast with ErrCircuitOpen, a probe request being sent every
+	// BreakerProbeInterval (DefaultBreakerProbeInterval if 0) until manager answers. 0 disables circuit breaker.
+	BreakerThreshold     int           `mapstructure:"breaker-threshold"`
+	BreakerProbeInterval time.Duration `mapstructure:"breaker-probe-interval"`
+	// CompressRequests gzips request bodies of at least CompressMinSize (e.g. config reports, batched events), for
+	// connectors on constrained links. Compression is disabled once manager rejects a compressed request (415
+	// Unsupported Media Type), request being resent uncompressed.
+	CompressRequests bool `mapstructure:"compress-requests"`
 }
 
 type ConnectorManagerClient struct {
 	httpClient       *http.Client
-	url              string
-	apiKey           string
+	endpoint         *atomic.Pointer[managerEndpoint] // swapped as a whole on console migration
 	metricsCollector *metrics.MetricsCollector
+	schemaVersion    *atomic.Int64           // event schema version negotiated at register
+	version          *atomic.Pointer[string] // connector version given at register
+	handler          *atomic.Pointer[events.Handler]
+	configETag       *atomic.Pointer[string]          // ETag of last config fetched
+	configHash       *atomic.Pointer[string]          // hash of config the connector runs
+	lastConfig       *atomic.Pointer[json.RawMessage] // config the connector runs, restored on failed apply
+	plugins          *atomic.Pointer[[]PluginInfo]    // plugin inventory sent at register
+	featureFlags     *atomic.Pointer[map[string]bool] // feature flags set by manager with config
+	connectorID      *atomic.Pointer[string]          // set by manager at register or in tasks, used in event idempotency keys
+	store            state.Store                      // connector ID is persisted in, optional (see WithStateStore)
+	secrets          secrets.Store                    // GLIMPS Malware token is kept in, optional (see WithSecrets)
+	offlineSince     *atomic.Int64                    // unix time connector started from cached config, 0 once registered
+	spool            *atomic.Pointer[events.Spool]    // events are notified through, optional (see SetEventSpool)
+	compress         *atomic.Bool                     // request bodies are gzipped, until manager rejects it
+	provenance       *ConfigProvenance                // sources of config fields, reported with effective config
+	proxy            *atomic.Pointer[url.URL]         // outbound proxy set by console config, environment one if nil
+	transport        *rootCAsTransport                // transport of httpClient, trusting custom CAs set by console config
+	tasksWait        time.Duration
+	taskStream       bool
+	pollInterval     time.Duration
+	pollJitter       time.Duration
+	taskQueueSize    int
+	unauthorized     *unauthorizedPolicy
+	retry            retryPolicy
+	breaker          *circuitBreaker
+	connectorType    string
+}
+
+// managerEndpoint is a connector manager URL and the Authenticator to use with it.
+type managerEndpoint struct {
+	url  string
+	auth Authenticator
+}
+
+func apiKeyEndpoint(url string, apiKey string) *managerEndpoint {
+	return &managerEndpoint{url: url, auth: NewAPIKeyAuthenticator(apiKey)}
 }
 
 type ConnectorStatus int
@@ -67,8 +168,23 @@ type ConnectorStatus int
 const (
 	Started ConnectorStatus = iota
 	Stopped
+	// Degraded is reported for started connectors not working properly, e.g. stalled ones (see Watchdog)
+	Degraded
 )
 
+func (s ConnectorStatus) String() string {
+	switch s {
+	case Started:
+		return "started"
+	case Stopped:
+		return "stopped"
+	case Degraded:
+		return "degraded"
+	default:
+		return "unknown"
+	}
+}
+
 // Connector must comply to this interface to be used with manager
 type Connector interface {
 	Start(ctx context.Context) (err error)
@@ -87,6 +203,16 @@ func (e HTTPError) Error() string {
 	return fmt.Sprintf("invalid response from connector manager, %d (%s): %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
 }
 
+// Rejected reports whether manager rejected request (4xx response), sending it again being pointless. Unauthorized,
+// timed out and rate limited requests are not rejected, they may be accepted later.
+func (e HTTPError) Rejected() bool {
+	switch e.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
 func NewHTTPError(code int, body []byte) HTTPError {
 	return HTTPError{
 		StatusCode: code,
@@ -95,26 +221,81 @@ func NewHTTPError(code int, body []byte) HTTPError {
 }
 
 type registerRequest struct {
-	Version string `json:"version"`
+	Version        string       `json:"version"`
+	SchemaVersions []int        `json:"schema_versions" desc:"event schema versions supported by connector"`
+	CryptoMode     CryptoMode   `json:"crypto_mode" desc:"cryptography mode connector runs in, so compliance can be checked from console"`
+	Plugins        []PluginInfo `json:"plugins,omitempty" desc:"plugins run by connector"`
+	ConnectorID    string       `json:"connector_id,omitempty" desc:"ID persisted from previous registrations (see WithStateStore), for manager to link them, e.g. after api key rotation"`
 }
 
-func NewConnectorManagerClient(ctx context.Context, config ConnectorManagerClientConfig) (c ConnectorManagerClient) {
-	c.httpClient = http.DefaultClient
-	if config.Insecure {
-		transport := http.DefaultTransport
-		transport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // optional insecure
-		c.httpClient = &http.Client{Transport: transport}
+func NewConnectorManagerClient(ctx context.Context, config ConnectorManagerClientConfig, opts ...ClientOption) (c ConnectorManagerClient) {
+	options := clientOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	c.proxy = &atomic.Pointer[url.URL]{}
+	c.httpClient, c.transport = newHTTPClient(config, options, c.proxyFunc)
+	c.endpoint = &atomic.Pointer[managerEndpoint]{}
+	if config.Authenticator != nil {
+		c.endpoint.Store(&managerEndpoint{url: config.URL, auth: config.Authenticator})
+	} else {
+		c.endpoint.Store(apiKeyEndpoint(config.URL, config.APIKey))
 	}
-	c.url = config.URL
-	c.apiKey = config.APIKey
 	c.metricsCollector = &metrics.MetricsCollector{}
+	c.schemaVersion = &atomic.Int64{}
+	c.schemaVersion.Store(events.SchemaVersionLegacy)
+	c.version = &atomic.Pointer[string]{}
+	c.handler = &atomic.Pointer[events.Handler]{}
+	c.configETag = &atomic.Pointer[string]{}
+	c.configHash = &atomic.Pointer[string]{}
+	c.lastConfig = &atomic.Pointer[json.RawMessage]{}
+	c.plugins = &atomic.Pointer[[]PluginInfo]{}
+	c.featureFlags = &atomic.Pointer[map[string]bool]{}
+	c.connectorID = &atomic.Pointer[string]{}
+	c.offlineSince = &atomic.Int64{}
+	c.spool = &atomic.Pointer[events.Spool]{}
+	c.compress = &atomic.Bool{}
+	c.compress.Store(config.CompressRequests)
+	c.store = options.store
+	c.secrets = options.secrets
+	c.loadConnectorID()
+	if config.Authenticator == nil {
+		c.loadEndpoint()
+	}
+	c.provenance = NewConfigProvenance()
+	c.tasksWait = config.TasksWait
+	if options.tasksWait > 0 {
+		c.tasksWait = options.tasksWait
+	}
+	c.taskStream = config.TaskStream
+	c.pollInterval = config.PollInterval
+	c.pollJitter = config.PollJitter
+	c.taskQueueSize = config.TaskQueueSize
+	c.connectorType = config.ConnectorType
+	c.unauthorized = newUnauthorizedPolicy(config.UnauthorizedRetries, config.UnauthorizedBackoff)
+	c.retry = newRetryPolicy(config)
+	c.breaker = newCircuitBreaker(config.BreakerThreshold, config.BreakerProbeInterval)
 	return
 }
 
 var _ events.Notifier = &ConnectorManagerClient{}
 
 func (c ConnectorManagerClient) NewConsoleEventHandler(logLeveler slog.Leveler, unresolvedError map[events.ErrorEventType]string) *events.Handler {
-	return events.NewHandler(c, logLeveler, unresolvedError, c.metricsCollector)
+	var notifier events.Notifier = c
+	if spool := c.spool.Load(); spool != nil {
+		notifier = spool
+	}
+	h := events.NewHandler(notifier, logLeveler, unresolvedError, c.metricsCollector)
+	// last logs are kept for local admin api
+	h.SetLogBuffer(events.NewLogBuffer(events.DefaultLogBufferSize))
+	c.handler.Store(h)
+	return h
+}
+
+// SetEventSpool makes event handlers created from now on (see NewConsoleEventHandler) notify events through spool,
+// spooling them while manager is unreachable. spool must notify events with c (see events.NewSpool).
+func (c ConnectorManagerClient) SetEventSpool(spool *events.Spool) {
+	c.spool.Store(spool)
 }
 
 // NewMetricCollecter returns a MetricCollecter for the connector to report metrics.
@@ -124,35 +305,170 @@ func (c ConnectorManagerClient) NewMetricCollecter(detectClient gdetect.GDetectS
 	return c.metricsCollector
 }
 
+// AnalysisMetrics returns metrics of the analysis submission pool, to give to analysis.NewPool.
+func (c ConnectorManagerClient) AnalysisMetrics() *metrics.AnalysisMetrics {
+	return c.metricsCollector.Analysis()
+}
+
+// WebhookMetrics returns metrics of webhook receivers, to give to webhook.NewReceiver and webhook.NewRenewer.
+func (c ConnectorManagerClient) WebhookMetrics() *metrics.WebhookMetrics {
+	return c.metricsCollector.Webhook()
+}
+
+// MetricsHandler serves connector gauges and connector manager communication metrics
+// (requests by endpoint and status, retries, pending events, task latency) analysis pool and webhook metrics in Prometheus format.
+func (c ConnectorManagerClient) MetricsHandler() http.Handler {
+	return metrics.PrometheusHandler(c.metricsCollector)
+}
+
 type RegistrationInfo struct {
 	Stopped          bool                             `json:"stopped"`
 	Config           any                              `json:"config"`
 	UnresolvedErrors map[events.ErrorEventType]string `json:"unresolved_errors"`
+	SchemaVersion    int                              `json:"schema_version" desc:"event schema version to use, chosen by manager among connector's ones (legacy version if unset)"`
+	FeatureFlags     map[string]bool                  `json:"feature_flags" desc:"experimental connector behaviors toggled from console"`
+	ConnectorID      string                           `json:"connector_id" desc:"used in event idempotency keys and sent with events, also learnt from tasks if unset. Set to persisted or generated ID with a state store (see WithStateStore) when manager does not set it"`
+}
+
+// SetPlugins sets plugin inventory reported to console on next registration (e.g. HostConfig.PluginInventory).
+func (c ConnectorManagerClient) SetPlugins(plugins []PluginInfo) {
+	plugins = slices.Clone(plugins)
+	c.plugins.Store(&plugins)
 }
 
+// Register fails with ErrFIPSModeDisabled if connector was built with fips tag but does not run in FIPS mode.
 func (c ConnectorManagerClient) Register(ctx context.Context, version string, info *RegistrationInfo) (err error) {
+	err = c.registerConnector(ctx, version, info, true)
+	return
+}
+
+// registerConnector registers connector, storing and applying console config only if applyConfig, callers applying
+// it on connector themselves otherwise (see configure).
+func (c ConnectorManagerClient) registerConnector(ctx context.Context, version string, info *RegistrationInfo, applyConfig bool) (err error) {
+	if err = CheckCryptoMode(); err != nil {
+		return
+	}
+	// console config is captured raw, to record which fields it sets
+	var capture *configCapture
+	if info.Config != nil {
+		capture = &configCapture{config: info.Config}
+		info.Config = capture
+	}
+	schemaVersion, err := c.register(ctx, c.endpoint.Load(), version, info)
+	// a null config sets info.Config to nil
+	if capture != nil && info.Config != nil {
+		info.Config = capture.config
+	}
+	if err != nil {
+		return
+	}
+	if applyConfig && capture != nil && capture.raw != nil {
+		c.consoleConfigApplied(capture.raw)
+	}
+	c.version.Store(&version)
+	c.schemaVersion.Store(int64(schemaVersion))
+	c.configETag.Store(nil)
+	if applyConfig && info.Config != nil {
+		c.storeConfig(info.Config)
+	}
+	c.storeFeatureFlags(info.FeatureFlags)
+	c.assignConnectorID(info.ConnectorID)
+	info.ConnectorID = c.ConnectorID()
+	c.metricsCollector.SetRegistered(time.Now())
+	return
+}
+
+func (c ConnectorManagerClient) register(ctx context.Context, endpoint *managerEndpoint, version string, info *RegistrationInfo) (schemaVersion int, err error) {
 	registerReq := registerRequest{
-		Version: version,
+		Version:        version,
+		SchemaVersions: events.SupportedSchemaVersions(),
+		CryptoMode:     CurrentCryptoMode(),
+		ConnectorID:    c.ConnectorID(),
 	}
-	err = c.call(ctx, http.MethodPost, "register", registerReq, info)
+	if plugins := c.plugins.Load(); plugins != nil {
+		registerReq.Plugins = *plugins
+	}
+	err = c.callEndpoint(ctx, endpoint, http.MethodPost, "register", registerReq, info)
 	if err != nil {
 		return
 	}
-	c.metricsCollector.SetLastStart(time.Now().Unix())
+	schemaVersion = info.SchemaVersion
+	if !slices.Contains(events.SupportedSchemaVersions(), schemaVersion) {
+		if schemaVersion != 0 {
+			logger.Warn("manager chose an unsupported event schema version, fallback to legacy", slog.Int("schema-version", schemaVersion))
+		}
+		schemaVersion = events.SchemaVersionLegacy
+	}
 	return
 }
 
 type getConfigResponse struct {
-	Config json.RawMessage `json:"config"`
+	Config       json.RawMessage `json:"config"`
+	FeatureFlags map[string]bool `json:"feature_flags"`
 }
 
-func (c ConnectorManagerClient) getConfig(ctx context.Context) (config json.RawMessage, err error) {
+// getConfig fetches connector config and feature flags, unchanged is true (and config nil) if manager
+// answered 304 Not Modified to the ETag of the last fetched config.
+func (c ConnectorManagerClient) getConfig(ctx context.Context) (config json.RawMessage, featureFlags map[string]bool, unchanged bool, err error) {
+	opts := &callOptions{header: http.Header{}}
+	if etag := c.configETag.Load(); etag != nil {
+		opts.header.Set("If-None-Match", *etag)
+	}
 	resp := new(getConfigResponse)
-	err = c.call(ctx, http.MethodGet, "config", nil, &resp)
+	err = c.callEndpointWithOptions(ctx, c.endpoint.Load(), http.MethodGet, "config", nil, &resp, opts)
 	if err != nil {
 		return
 	}
+	if opts.statusCode == http.StatusNotModified {
+		unchanged = true
+		return
+	}
 	config = resp.Config
+	featureFlags = resp.FeatureFlags
+	if etag := opts.respHeader.Get("ETag"); etag != "" {
+		c.configETag.Store(&etag)
+	} else {
+		c.configETag.Store(nil)
+	}
+	return
+}
+
+// ConfigHash returns a hash of config content, independent of JSON formatting and keys order.
+func ConfigHash(config any) (hash string, err error) {
+	raw, ok := config.(json.RawMessage)
+	if !ok {
+		if raw, err = json.Marshal(config); err != nil {
+			return
+		}
+	}
+	var canonical any
+	if err = json.Unmarshal(raw, &canonical); err != nil {
+		return
+	}
+	// maps keys are sorted when marshalled
+	raw, err = json.Marshal(canonical)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(raw)
+	hash = hex.EncodeToString(sum[:])
+	return
+}
+
+func (c ConnectorManagerClient) storeConfigHash(config any) {
+	hash, err := ConfigHash(config)
+	if err != nil {
+		logger.Warn("could not compute config hash", slog.String("error", err.Error()))
+		c.configHash.Store(nil)
+		return
+	}
+	c.configHash.Store(&hash)
+}
+
+func (c ConnectorManagerClient) currentConfigHash() (hash string) {
+	if h := c.configHash.Load(); h != nil {
+		hash = *h
+	}
 	return
 }
 
@@ -161,124 +477,194 @@ func (c ConnectorManagerClient) Start(ctx context.Context, connector Connector)
 	tasks := c.tasks(ctx)
 
 	for {
-		select {
-		case task, chanOpened := <-tasks:
-			if !chanOpened {
-				logger.Warn("tasks channel is closed")
+		task, ok := tasks.pop(ctx)
+		if !ok {
+			if ctx.Err() != nil {
+				logger.Warn("context done", slog.String("reason", ctx.Err().Error()))
 				return
 			}
-			logger.Debug("received tasks", "task", task)
-
-			var taskError string
-			switch task.Action {
-			case ActionUpdateConfig:
-				config, err := c.getConfig(ctx)
-				if err != nil {
-					taskError = fmt.Sprintf("error cannot get updated config, error : %v\n", err)
-					break
-				}
-				err = connector.Configure(ctx, config)
-				if err != nil {
-					taskError = fmt.Sprintf("error reconfiguring connector, error: %v\n", err)
-				}
-			case ActionStop:
-				if connector.Status() == Stopped {
-					taskError = "error stopping connector, error: connector is already stopped"
-					break
-				}
-				err := connector.Stop(ctx)
-				if err != nil {
-					taskError = fmt.Sprintf("error stopping connector, error: %v\n", err)
-				}
-			case ActionStart:
-				if connector.Status() == Started {
-					taskError = "error starting connector, error: connector is already started"
-					break
-				}
-				err := connector.Start(ctx)
-				if err != nil {
-					taskError = fmt.Sprintf("error start connector, error: %s", err)
-				}
-			case ActionRestore:
-				restoreAction := new(RestoreActionContent)
-				err := json.Unmarshal(task.Content, restoreAction)
-				if err != nil {
-					taskError = fmt.Sprintf("error reading restore task, error: %v\n", err.Error())
-					break
-				}
-				if restoreAction.ID == "" {
-					taskError = "error reading restore task, the id of the element to restore is not provided"
-					break
-				}
-				err = connector.Restore(ctx, *restoreAction)
-				if err != nil {
-					taskError = fmt.Sprintf("error restoring element %s, error: %s\n", restoreAction.ID, err.Error())
-					logger.Error(taskError)
-				}
+			logger.Warn("tasks queue is closed")
+			return
+		}
+		c.metricsCollector.Client().SetTasksQueued(int64(tasks.len()))
+		c.storeConnectorID(task.ConnectorID)
+		received := time.Now()
+		logger.Debug("received tasks", "task", task)
+		// calls made while handling task (and connector's ones) share its request id
+		ctx := context.WithValue(ctx, CtxRequestIDKey{}, task.RequestID)
+
+		var taskError string
+		var taskResult any
+		var taskProvenance map[string]string
+		switch task.Action {
+		case ActionUpdateConfig:
+			config, featureFlags, unchanged, err := c.getConfig(ctx)
+			if err != nil {
+				taskError = fmt.Sprintf("error cannot get updated config, error : %v\n", err)
+				break
+			}
+			if unchanged {
+				logger.Debug("config not modified, skip reconfiguration")
+				break
+			}
+			c.updateFeatureFlags(ctx, connector, featureFlags)
+			err = c.configure(ctx, connector, config)
+			if err != nil {
+				taskError = fmt.Sprintf("error reconfiguring connector, error: %v\n", err)
+			}
+		case ActionStop:
+			if connector.Status() == Stopped {
+				taskError = "error stopping connector, error: connector is already stopped"
+				break
+			}
+			report, err := stop(ctx, connector, task)
+			if err != nil {
+				taskError = fmt.Sprintf("error stopping connector, error: %v\n", err)
+			}
+			if report != nil {
+				taskResult = report
+			}
+		case ActionStart:
+			if connector.Status() == Started {
+				taskError = "error starting connector, error: connector is already started"
+				break
+			}
+			err := connector.Start(ctx)
+			if err != nil {
+				taskError = fmt.Sprintf("error start connector, error: %s", err)
+				break
+			}
+			if stopper, ok := asAnalysisStopper(connector); ok {
+				stopper.ResumeAnalyses()
 			}
-			event := events.TaskEvent{
-				TaskID: task.ID,
-				Error:  taskError,
+		case ActionRestore:
+			if _, err := restore(ctx, connector, task.Content); err != nil {
+				taskError = err.Error()
+				logger.Error(taskError)
+			}
+		case ActionGetEffectiveConfig:
+			config, err := effectiveConfig(connector)
+			if err != nil {
+				taskError = fmt.Sprintf("error getting effective config, error: %v", err)
+				break
 			}
-			err := c.Notify(ctx, event)
-			switch {
-			case errors.Is(err, ErrUnauthorizedConnector):
+			taskResult = config
+			taskProvenance = map[string]string{}
+			for path, source := range c.provenance.Sources(config) {
+				taskProvenance[path] = string(source)
+			}
+		case ActionMigrate:
+			// migration acks task itself, on previous manager, before switching
+			err := c.migrate(ctx, connector, task)
+			if errors.Is(err, ErrUnauthorizedConnector) && c.giveUpUnauthorized(ctx, err) {
 				return
-			case err != nil:
-				logger.Error("could not push event to ack task", slog.String("task-id", task.ID))
 			}
-		case <-ctx.Done():
-			logger.Warn("context done", slog.String("reason", ctx.Err().Error()))
-			// TODO
+			continue
+		}
+		event := events.TaskEvent{
+			TaskID:     task.ID,
+			Error:      taskError,
+			ConfigHash: c.currentConfigHash(),
+			Result:     taskResult,
+			RequestID:  task.RequestID,
+
+			ConfigProvenance: taskProvenance,
+		}
+		if !c.ackTask(ctx, task, event) {
 			return
 		}
+		c.metricsCollector.Client().ObserveTaskLatency(time.Since(received))
 	}
 }
 
-type postEventRequest struct {
-	EventType events.EventType `json:"type"`
-	Event     json.RawMessage  `json:"event"`
+// ackTask pushes event acking task handled, retrying it while manager answers unauthorized: a task not acked is
+// redelivered, and handled again. It returns false when connector must stop handling tasks (see
+// giveUpUnauthorized).
+func (c ConnectorManagerClient) ackTask(ctx context.Context, task Task, event events.TaskEvent) (ok bool) {
+	// retried ack keeps its idempotency key
+	ctx = events.WithEventTime(ctx, time.Now())
+	for {
+		err := c.Notify(ctx, event)
+		switch {
+		case errors.Is(err, ErrUnauthorizedConnector):
+			if c.giveUpUnauthorized(ctx, err) {
+				return
+			}
+			logger.Info("ack task again", slog.String("task-id", task.ID))
+			continue
+		case err != nil:
+			logger.Error("could not push event to ack task", slog.String("task-id", task.ID))
+		default:
+			c.unauthorized.reset()
+		}
+		ok = true
+		return
+	}
 }
 
+type postEventRequest = events.Envelope
+
+// Notify pushes event to manager. Event envelope carries an idempotency key (schema version 2), so the manager
+// can discard duplicates of events it already recorded: it answers 409 Conflict to such duplicates,
+// which Notify reports as success.
 func (c ConnectorManagerClient) Notify(ctx context.Context, event any) (err error) {
-	reqBody := new(postEventRequest)
-	switch event.(type) {
-	case events.MitigationEvent:
-		reqBody.EventType = events.Mitigation
-	case events.TaskEvent:
-		reqBody.EventType = events.TaskAck
-	case events.LogEvent:
-		reqBody.EventType = events.Log
-	case events.ErrorEvent:
-		reqBody.EventType = events.Error
-	case events.ResolutionEvent:
-		reqBody.EventType = events.Resolution
-	default:
-		err = errors.New("invalid type")
-		return
-	}
-	rawEvent, err := json.Marshal(event)
+	c.metricsCollector.Client().AddEventsPending(1)
+	defer c.metricsCollector.Client().AddEventsPending(-1)
+	reqBody, err := events.NewEnvelope(int(c.schemaVersion.Load()), event)
 	if err != nil {
 		return
 	}
-	reqBody.Event = rawEvent
+	var connectorID string
+	if id := c.connectorID.Load(); id != nil {
+		connectorID = *id
+	}
+	created := time.Now()
+	if t, ok := events.EventTimeFromContext(ctx); ok {
+		// replayed event keeps idempotency key of its first notification
+		created = t
+	}
+	reqBody.SetIdempotencyKey(connectorID, created)
+	reqBody.SetConnectorID(connectorID)
 	err = c.call(ctx, http.MethodPost, "events", reqBody, nil)
-	if err != nil {
-		return
+	if httpErr := (HTTPError{}); errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusConflict {
+		logger.Debug("event already recorded by manager", slog.String("idempotency-key", reqBody.IdempotencyKey))
+		err = nil
 	}
 	return
 }
 
+// ConnectorID returns ID of connector set by manager (or persisted one, see WithStateStore), empty until registered.
+func (c ConnectorManagerClient) ConnectorID() (id string) {
+	if current := c.connectorID.Load(); current != nil {
+		id = *current
+	}
+	return
+}
+
+func (c ConnectorManagerClient) storeConnectorID(id string) {
+	if id == "" {
+		return
+	}
+	if current := c.connectorID.Load(); current != nil && *current == id {
+		return
+	}
+	c.connectorID.Store(&id)
+	c.persistConnectorID(id)
+	logger.Info("connector id assigned", slog.String("connector-id", id))
+}
+
 func (c ConnectorManagerClient) pushMetrics(ctx context.Context, m metrics.ConnectorMetrics) (err error) {
 	err = c.call(ctx, http.MethodPost, "metrics", m, nil)
 	return
 }
 
-func (c ConnectorManagerClient) tasks(ctx context.Context) (tasks <-chan Task) {
-	tasksC := make(chan Task, taskChannelBufferSize) // Buffer to allow multiple tasks to be queued
-	tasks = tasksC
-	go func(ctx context.Context, tasksC chan<- Task) {
-		defer close(tasksC)
+func (c ConnectorManagerClient) tasks(ctx context.Context) (queue *taskQueue) {
+	queue = newTaskQueue(c.taskQueueSize)
+	go func(ctx context.Context) {
+		defer queue.close()
+		// tasks are polled until then, once task stream could not be opened
+		var streamRetry time.Time
+		poll := newPollPolicy(c.pollInterval, c.pollJitter, c.tasksWait)
 		for {
 			select {
 			case <-ctx.Done():
@@ -293,56 +679,199 @@ func (c ConnectorManagerClient) tasks(ctx context.Context) (tasks <-chan Task) {
 				err = c.pushMetrics(ctx, metrics)
 				switch {
 				case errors.Is(err, ErrUnauthorizedConnector):
-					return
+					c.metricsCollector.RestoreCounterMetrics(metrics)
+					if c.giveUpUnauthorized(ctx, err) {
+						return
+					}
+					continue
 				case err != nil:
 					logger.Error("failed to push metrics", slog.String("error", err.Error()))
 					c.metricsCollector.RestoreCounterMetrics(metrics)
 				}
 
+				if c.taskStream && !time.Now().Before(streamRetry) {
+					err = c.streamTasks(ctx, queue)
+					switch {
+					case errors.Is(err, ErrUnauthorizedConnector):
+						if c.giveUpUnauthorized(ctx, err) {
+							return
+						}
+						continue
+					case ctx.Err() != nil:
+						return
+					case errors.Is(err, errTaskStreamClosed), errors.Is(err, ErrCircuitOpen):
+						logger.Warn("task stream interrupted", slog.String("error", err.Error()))
+						if poll.pause(ctx, true) {
+							return
+						}
+						continue
+					case err != nil:
+						logger.Warn("could not open task stream, fallback to polling", slog.String("error", err.Error()), slog.Duration("retry-in", taskStreamRetryInterval))
+						streamRetry = time.Now().Add(taskStreamRetryInterval)
+					default:
+						poll.succeeded()
+						continue
+					}
+				}
+
+				polled := time.Now()
 				tasks, err := c.getTasks(ctx)
 				switch {
 				case errors.Is(err, ErrUnauthorizedConnector):
-					return
+					if c.giveUpUnauthorized(ctx, err) {
+						return
+					}
+					continue
 				case err != nil:
 					logger.Error("cannot get tasks", slog.String("error", err.Error()))
+					if poll.pause(ctx, true) {
+						return
+					}
 					continue
 				}
+				c.unauthorized.reset()
+				poll.polled(time.Since(polled), len(tasks))
+				// control tasks first, so they are not stuck behind a full bulk lane
+				slices.SortStableFunc(tasks, func(a, b Task) int {
+					return int(a.Action.Priority() - b.Action.Priority())
+				})
 				for _, t := range tasks {
-					tasksC <- t
+					if err = c.queueTask(ctx, queue, t); err != nil {
+						return
+					}
+				}
+				if poll.pause(ctx, false) {
+					return
 				}
 			}
 		}
-	}(ctx, tasksC)
+	}(ctx)
+
+	return
+}
 
+// queueTask pushes task to queue, waiting while queue is full, and acks the update-config task it supersedes.
+func (c ConnectorManagerClient) queueTask(ctx context.Context, queue *taskQueue, task Task) (err error) {
+	superseded, full, err := queue.push(ctx, task)
+	if full {
+		c.metricsCollector.Client().AddTasksOverflow()
+		logger.Warn("tasks queue is full, wait for connector to handle queued tasks", slog.Int("size", queue.size))
+	}
+	if err != nil {
+		return
+	}
+	c.metricsCollector.Client().SetTasksQueued(int64(queue.len()))
+	if superseded == nil {
+		return
+	}
+	c.metricsCollector.Client().AddTasksSuperseded()
+	logger.Debug("update-config task superseded", slog.String("task-id", superseded.ID), slog.String("by", task.ID))
+	ackCtx := context.WithValue(ctx, CtxRequestIDKey{}, superseded.RequestID)
+	ackErr := c.Notify(ackCtx, events.TaskEvent{
+		TaskID:     superseded.ID,
+		ConfigHash: c.currentConfigHash(),
+		Result:     SupersededTaskResult{SupersededBy: task.ID},
+		RequestID:  superseded.RequestID,
+	})
+	// an unauthorized ack is handled by next tasks request
+	if ackErr != nil {
+		logger.Error("could not push event to ack superseded task", slog.String("task-id", superseded.ID))
+	}
 	return
 }
 
+// SupersededTaskResult is the result of update-config tasks superseded by a newer one before being handled.
+type SupersededTaskResult struct {
+	SupersededBy string `json:"superseded_by"`
+}
+
 type getTasksResp struct {
 	Tasks []Task `json:"tasks"`
 }
 
 func (c ConnectorManagerClient) getTasks(ctx context.Context) (tasks []Task, err error) {
+	reqID := RequestIDFromContext(ctx)
+	if reqID == "" {
+		reqID = generateReqID()
+		ctx = context.WithValue(ctx, CtxRequestIDKey{}, reqID)
+	}
+	var opts *callOptions
+	if c.tasksWait > 0 {
+		// in seconds, rounded up so a sub-second wait still long polls
+		wait := int((c.tasksWait + time.Second - 1) / time.Second)
+		opts = &callOptions{query: url.Values{"wait": []string{strconv.Itoa(wait)}}}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.tasksWait+longPollTimeoutMargin)
+		defer cancel()
+	}
 	resp := new(getTasksResp)
-	err = c.call(ctx, http.MethodGet, "tasks", nil, resp)
+	err = c.callEndpointWithOptions(ctx, c.endpoint.Load(), http.MethodGet, "tasks", nil, resp, opts)
 	if err != nil {
 		return
 	}
 	tasks = resp.Tasks
+	for i := range tasks {
+		if tasks[i].RequestID == "" {
+			tasks[i].RequestID = reqID
+		}
+	}
 	return
 }
 
 func (c ConnectorManagerClient) call(ctx context.Context, method string, path string, body any, res any) (err error) {
+	return c.callEndpoint(ctx, c.endpoint.Load(), method, path, body, res)
+}
+
+func (c ConnectorManagerClient) callEndpoint(ctx context.Context, endpoint *managerEndpoint, method string, path string, body any, res any) (err error) {
+	return c.callEndpointWithOptions(ctx, endpoint, method, path, body, res, nil)
+}
+
+// callOptions allows to set request query and headers, and read response status and headers.
+type callOptions struct {
+	query      url.Values
+	header     http.Header
+	statusCode int
+	respHeader http.Header
+}
+
+func (c ConnectorManagerClient) callEndpointWithOptions(ctx context.Context, endpoint *managerEndpoint, method string, path string, body any, res any, opts *callOptions) (err error) {
 	reqBody, err := json.Marshal(body)
 	if err != nil {
 		return
 	}
-	req, err := c.prepareRequest(ctx, method, path, bytes.NewReader(reqBody))
-	if err != nil {
-		return
+	var resp *http.Response
+	for {
+		var req *http.Request
+		req, err = c.prepareRequest(ctx, endpoint, method, path, bytes.NewReader(reqBody))
+		if err != nil {
+			return
+		}
+		if opts != nil {
+			if len(opts.query) > 0 {
+				req.URL.RawQuery = opts.query.Encode()
+			}
+			for key, values := range opts.header {
+				for _, v := range values {
+					req.Header.Add(key, v)
+				}
+			}
+		}
+		start := time.Now()
+		resp, err = c.retryDo(req, path)
+		if err != nil {
+			c.metricsCollector.Client().ObserveRequest(path, 0, time.Since(start))
+			return
+		}
+		c.metricsCollector.Client().ObserveRequest(path, resp.StatusCode, time.Since(start))
+		// resent uncompressed, compression being disabled
+		if !c.compressionRejected(req, resp) {
+			break
+		}
+		_ = resp.Body.Close()
 	}
-	resp, err := c.retryDo(req)
-	if err != nil {
-		return
+	if opts != nil {
+		opts.statusCode = resp.StatusCode
+		opts.respHeader = resp.Header
 	}
 	defer func() {
 		if e := resp.Body.Close(); e != nil {
@@ -356,6 +885,8 @@ func (c ConnectorManagerClient) call(ctx context.Context, method string, path st
 
 	switch resp.StatusCode {
 	case http.StatusOK:
+	case http.StatusNotModified:
+		return
 	case http.StatusUnauthorized:
 		apiError := new(APIErrorResponse)
 		err = json.Unmarshal(respBody, apiError)
@@ -363,6 +894,9 @@ func (c ConnectorManagerClient) call(ctx context.Context, method string, path st
 			logger.Error("could not parse api error response", slog.String("error", err.Error()))
 		}
 		err = NewHTTPError(resp.StatusCode, respBody)
+		if auth, ok := endpoint.auth.(CachingAuthenticator); ok {
+			auth.Invalidate()
+		}
 		switch apiError.Code {
 		case InvalidAPIKeyCode:
 			logger.Error("The API key is invalid. The connector may have been started with the wrong API key or has been deleted from the manager.")
@@ -389,30 +923,54 @@ func (c ConnectorManagerClient) call(ctx context.Context, method string, path st
 	return
 }
 
-func (c ConnectorManagerClient) retryDo(req *http.Request) (resp *http.Response, err error) {
+func (c ConnectorManagerClient) retryDo(req *http.Request, endpoint string) (resp *http.Response, err error) {
+	if err = c.breaker.allow(); err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return
+	}
+	defer func() {
+		c.breaker.done(err)
+	}()
+	attempts := 0
 	resp, err = backoff.Retry(
 		req.Context(),
 		func() (resp *http.Response, err error) {
+			attempts++
+			if attempts > 1 {
+				c.metricsCollector.Client().AddRetry(endpoint)
+				// retried requests send the same body, e.g. events with the same idempotency key
+				if req.GetBody != nil {
+					if req.Body, err = req.GetBody(); err != nil {
+						err = backoff.Permanent(err)
+						return
+					}
+				}
+			}
 			resp, err = c.httpClient.Do(req) //nolint:gosec // Base URL from client config, not user input
+			if errors.Is(err, ErrInvalidTLSConfig) {
+				err = backoff.Permanent(err)
+				return
+			}
 			if err != nil {
 				logger.Debug("try http request error", slog.String("error", err.Error()))
 				return
 			}
-			if resp.StatusCode == http.StatusBadGateway {
-				logger.Debug("try http request error", slog.String("error", "bad gateway"))
-				err = errors.New("bad gateway")
+			if err = c.retry.retried(resp); err != nil {
+				logger.Debug("try http request error", slog.String("error", err.Error()))
+				_ = resp.Body.Close()
 				return
 			}
 			return
 		},
-		backoff.WithBackOff(backoff.NewExponentialBackOff()),
-		backoff.WithMaxElapsedTime(3*time.Second),
+		c.retry.options()...,
 	)
 	return
 }
 
-func (c ConnectorManagerClient) prepareRequest(ctx context.Context, method string, path string, body io.Reader) (req *http.Request, err error) {
-	reqURL, err := url.JoinPath(c.url, basePath, path)
+func (c ConnectorManagerClient) prepareRequest(ctx context.Context, endpoint *managerEndpoint, method string, path string, body io.Reader) (req *http.Request, err error) {
+	reqURL, err := url.JoinPath(endpoint.url, basePath, path)
 	if err != nil {
 		return
 	}
@@ -420,11 +978,23 @@ func (c ConnectorManagerClient) prepareRequest(ctx context.Context, method strin
 	if err != nil {
 		return
 	}
-	req.Header.Add("Authorization", "ApiKey "+c.apiKey)
+	if err = c.compressRequest(req); err != nil {
+		return
+	}
+	if err = endpoint.auth.Authenticate(ctx, req); err != nil {
+		return
+	}
 	req.Header.Add("Content-Type", "application/json")
-	v := ctx.Value(CtxRequestIDKey{})
-	reqID, ok := v.(string)
-	if !ok || reqID == "" {
+	var version string
+	if v := c.version.Load(); v != nil {
+		version = *v
+	}
+	req.Header.Set("User-Agent", userAgent(c.connectorType, version))
+	if id := c.connectorID.Load(); id != nil {
+		req.Header.Set("X-Connector-Id", *id)
+	}
+	reqID := RequestIDFromContext(ctx)
+	if reqID == "" {
 		reqID = generateReqID()
 	}
 	req.Header.Add("X-Request-Id", reqID)
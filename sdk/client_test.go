@@ -0,0 +1,357 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfigHash(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        any
+		b        any
+		wantSame bool
+	}{
+		{
+			name:     "keys order and spaces",
+			a:        json.RawMessage(`{"a":1,"b":{"c":"d"}}`),
+			b:        json.RawMessage(`{ "b": {"c": "d"}, "a": 1 }`),
+			wantSame: true,
+		},
+		{
+			name:     "struct and raw",
+			a:        struct{ A int }{A: 1},
+			b:        json.RawMessage(`{"A":1}`),
+			wantSame: true,
+		},
+		{
+			name: "different value",
+			a:    json.RawMessage(`{"a":1}`),
+			b:    json.RawMessage(`{"a":2}`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hashA, err := ConfigHash(tt.a)
+			if err != nil {
+				t.Fatalf("ConfigHash() error = %v", err)
+			}
+			hashB, err := ConfigHash(tt.b)
+			if err != nil {
+				t.Fatalf("ConfigHash() error = %v", err)
+			}
+			if (hashA == hashB) != tt.wantSame {
+				t.Errorf("ConfigHash() = %s and %s, want same %v", hashA, hashB, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestConnectorManagerClient_getConfig(t *testing.T) {
+	etag := `"rev-1"`
+	var gotIfNoneMatch []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIfNoneMatch = append(gotIfNoneMatch, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(`{"config":{"debug":true},"feature_flags":{"new-parser":true}}`))
+	}))
+	defer server.Close()
+
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"})
+	config, flags, unchanged, err := c.getConfig(t.Context())
+	if err != nil {
+		t.Fatalf("getConfig() error = %v", err)
+	}
+	if unchanged || string(config) != `{"debug":true}` || !flags["new-parser"] {
+		t.Fatalf("getConfig() = %s, %v, %v, want config", config, flags, unchanged)
+	}
+	config, _, unchanged, err = c.getConfig(t.Context())
+	if err != nil {
+		t.Fatalf("getConfig() error = %v", err)
+	}
+	if !unchanged || config != nil {
+		t.Fatalf("getConfig() = %s, %v, want unchanged", config, unchanged)
+	}
+	if diff := cmp.Diff(gotIfNoneMatch, []string{"", etag}); diff != "" {
+		t.Errorf("getConfig() If-None-Match diff(got-want)=%s", diff)
+	}
+}
+
+func TestConnectorManagerClient_getTasks(t *testing.T) {
+	tests := []struct {
+		name          string
+		tasksWait     time.Duration
+		opts          []ClientOption
+		tasks         string
+		wantQuery     string
+		wantRequestID string // "header" for X-Request-Id of get tasks request
+	}{
+		{
+			name:          "no long polling",
+			tasks:         `[{"id":"task-1","action":"stop"}]`,
+			wantRequestID: "header",
+		},
+		{
+			name:          "long polling",
+			tasksWait:     30 * time.Second,
+			tasks:         `[{"id":"task-1","action":"stop"}]`,
+			wantQuery:     "wait=30",
+			wantRequestID: "header",
+		},
+		{
+			name:          "sub-second wait rounded up",
+			tasksWait:     200 * time.Millisecond,
+			tasks:         `[{"id":"task-1","action":"stop"}]`,
+			wantQuery:     "wait=1",
+			wantRequestID: "header",
+		},
+		{
+			name:          "long polling option",
+			tasksWait:     30 * time.Second,
+			opts:          []ClientOption{WithLongPolling(time.Minute)},
+			tasks:         `[{"id":"task-1","action":"stop"}]`,
+			wantQuery:     "wait=60",
+			wantRequestID: "header",
+		},
+		{
+			name:          "request id set by manager",
+			tasks:         `[{"id":"task-1","action":"stop","request_id":"manager-id"}]`,
+			wantRequestID: "manager-id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotQuery, gotHeader string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotQuery = r.URL.RawQuery
+				gotHeader = r.Header.Get("X-Request-Id")
+				_, _ = w.Write([]byte(`{"tasks":` + tt.tasks + `}`))
+			}))
+			defer server.Close()
+
+			c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key", TasksWait: tt.tasksWait}, tt.opts...)
+			tasks, err := c.getTasks(t.Context())
+			if err != nil {
+				t.Fatalf("getTasks() error = %v", err)
+			}
+			wantRequestID := tt.wantRequestID
+			if wantRequestID == "header" {
+				wantRequestID = gotHeader
+			}
+			if gotHeader == "" {
+				t.Errorf("getTasks() no X-Request-Id sent")
+			}
+			if diff := cmp.Diff(tasks, []Task{{ID: "task-1", Action: ActionStop, RequestID: wantRequestID}}); diff != "" {
+				t.Errorf("getTasks() diff(got-want)=%s", diff)
+			}
+			if gotQuery != tt.wantQuery {
+				t.Errorf("getTasks() query = %q, want %q", gotQuery, tt.wantQuery)
+			}
+		})
+	}
+}
+
+type restoreRecorder struct {
+	fakeConnector
+	requestIDs chan string
+}
+
+func (c *restoreRecorder) Restore(ctx context.Context, restoreInfo RestoreActionContent) (err error) {
+	c.requestIDs <- RequestIDFromContext(ctx)
+	return
+}
+
+func TestConnectorManagerClient_Start_requestID(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	acks := make(chan [2]string, 1) // request id in event, X-Request-Id header
+	var served atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case basePath + "/tasks":
+			if served.Swap(true) {
+				_, _ = w.Write([]byte(`{"tasks":[]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"tasks":[{"id":"task-1","action":"restore","content":{"id":"elem"},"request_id":"req-1"}]}`))
+		case basePath + "/events":
+			envelope := events.Envelope{}
+			_ = json.NewDecoder(r.Body).Decode(&envelope)
+			event, err := envelope.Decode()
+			if ack, ok := event.(events.TaskEvent); err == nil && ok {
+				acks <- [2]string{ack.RequestID, r.Header.Get("X-Request-Id")}
+			}
+		}
+	}))
+	defer server.Close()
+
+	c := NewConnectorManagerClient(ctx, ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"})
+	connector := &restoreRecorder{requestIDs: make(chan string, 1)}
+	go c.Start(ctx, connector)
+
+	select {
+	case got := <-connector.requestIDs:
+		if got != "req-1" {
+			t.Errorf("Restore() request id = %s, want req-1", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("task not handled")
+	}
+	select {
+	case got := <-acks:
+		if diff := cmp.Diff(got, [2]string{"req-1", "req-1"}); diff != "" {
+			t.Errorf("ack request id diff(got-want)=%s", diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("task not acked")
+	}
+}
+
+func TestConnectorManagerClient_Notify_idempotency(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantErr   bool
+		wantCalls int
+	}{
+		{name: "recorded", statuses: []int{http.StatusOK}, wantCalls: 1},
+		{name: "retried", statuses: []int{http.StatusBadGateway, http.StatusOK}, wantCalls: 2},
+		{name: "duplicate", statuses: []int{http.StatusBadGateway, http.StatusConflict}, wantCalls: 2},
+		{name: "rejected", statuses: []int{http.StatusBadRequest}, wantErr: true, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []events.Envelope
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var envelope events.Envelope
+				if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+					t.Errorf("could not decode event, error: %v", err)
+				}
+				w.WriteHeader(tt.statuses[len(bodies)])
+				bodies = append(bodies, envelope)
+			}))
+			defer server.Close()
+
+			c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"})
+			c.schemaVersion.Store(events.SchemaVersionCurrent)
+			c.storeConnectorID("connector-1")
+			err := c.Notify(t.Context(), events.TaskEvent{TaskID: "task-1"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(bodies) != tt.wantCalls {
+				t.Fatalf("Notify() calls = %d, want %d", len(bodies), tt.wantCalls)
+			}
+			if bodies[0].IdempotencyKey == "" {
+				t.Errorf("Notify() idempotency key is empty")
+			}
+			if bodies[0].ConnectorID != "connector-1" {
+				t.Errorf("Notify() connector id = %q, want connector-1", bodies[0].ConnectorID)
+			}
+			for _, body := range bodies[1:] {
+				if diff := cmp.Diff(body, bodies[0]); diff != "" {
+					t.Errorf("Notify() retried body diff(got-want)=%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestConnectorManagerClient_prepareRequest_identity(t *testing.T) {
+	tests := []struct {
+		name          string
+		connectorType string
+		version       string
+		connectorID   string
+		wantPrefix    string
+	}{
+		{name: "unregistered", wantPrefix: "glimps-connector/unknown connector-integration/"},
+		{
+			name:          "registered",
+			connectorType: HostKey,
+			version:       "1.2.0",
+			connectorID:   "connector-1",
+			wantPrefix:    "glimps-connector-host/1.2.0 connector-integration/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: "http://manager", APIKey: "key", ConnectorType: tt.connectorType})
+			if tt.version != "" {
+				c.version.Store(&tt.version)
+			}
+			if tt.connectorID != "" {
+				c.storeConnectorID(tt.connectorID)
+			}
+			req, err := c.prepareRequest(t.Context(), c.endpoint.Load(), http.MethodGet, "tasks", nil)
+			if err != nil {
+				t.Fatalf("prepareRequest() error = %v", err)
+			}
+			ua := req.Header.Get("User-Agent")
+			if !strings.HasPrefix(ua, tt.wantPrefix) || !strings.HasSuffix(ua, " go/"+runtime.Version()) {
+				t.Errorf("prepareRequest() User-Agent = %q, want prefix %q", ua, tt.wantPrefix)
+			}
+			if got := req.Header.Get("X-Connector-Id"); got != tt.connectorID {
+				t.Errorf("prepareRequest() X-Connector-Id = %q, want %q", got, tt.connectorID)
+			}
+		})
+	}
+}
+
+func TestConnectorManagerClient_Notify_eventTime(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var envelope events.Envelope
+		if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+			t.Errorf("could not decode event, error: %v", err)
+		}
+		keys = append(keys, envelope.IdempotencyKey)
+	}))
+	defer server.Close()
+
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"})
+	c.schemaVersion.Store(events.SchemaVersionCurrent)
+	ctx := events.WithEventTime(t.Context(), time.Unix(1738000000, 0))
+	for range 2 {
+		if err := c.Notify(ctx, events.LogEvent{Level: "info", Message: "spooled", Time: 1738000000}); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("Notify() idempotency keys = %v, want the same key", keys)
+	}
+}
+
+func TestHTTPError_Rejected(t *testing.T) {
+	tests := []struct {
+		code int
+		want bool
+	}{
+		{code: http.StatusBadRequest, want: true},
+		{code: http.StatusRequestEntityTooLarge, want: true},
+		{code: http.StatusUnauthorized},
+		{code: http.StatusTooManyRequests},
+		{code: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.code), func(t *testing.T) {
+			if got := NewHTTPError(tt.code, nil).Rejected(); got != tt.want {
+				t.Errorf("Rejected() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
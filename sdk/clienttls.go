@@ -0,0 +1,85 @@
+package sdk
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ErrInvalidTLSConfig is returned by requests of clients whose TLS config could not be loaded (e.g. missing CA bundle
+// or client certificate files).
+var ErrInvalidTLSConfig = errors.New("invalid connector manager tls config")
+
+// tlsVersions are supported ConnectorManagerClientConfig.MinTLSVersion values.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newClientTLSConfig returns TLS config of requests to manager, nil if config does not customize it. caCerts are
+// the CA bundle certificates, trusted along system roots and console custom CAs.
+func newClientTLSConfig(config ConnectorManagerClientConfig) (tlsConfig *tls.Config, caCerts []*x509.Certificate, err error) {
+	if !config.Insecure && config.CACertsFile == "" && config.ClientCertFile == "" && config.ClientKeyFile == "" && config.MinTLSVersion == "" {
+		return
+	}
+	tlsConfig = NewTLSConfig(config.Insecure)
+	if config.MinTLSVersion != "" {
+		version, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(config.MinTLSVersion), "tls")]
+		if !ok {
+			err = fmt.Errorf("%w, unsupported min tls version %q (1.2 or 1.3)", ErrInvalidTLSConfig, config.MinTLSVersion)
+			return
+		}
+		tlsConfig.MinVersion = version
+	}
+	if config.CACertsFile != "" {
+		bundle, readErr := os.ReadFile(config.CACertsFile)
+		if readErr != nil {
+			err = fmt.Errorf("%w, could not read ca bundle, %w", ErrInvalidTLSConfig, readErr)
+			return
+		}
+		if caCerts, err = (PEMBundle{string(bundle)}).Certificates(); err != nil {
+			err = fmt.Errorf("%w, ca bundle %s, %w", ErrInvalidTLSConfig, config.CACertsFile, err)
+			return
+		}
+		pool, sysErr := x509.SystemCertPool()
+		if sysErr != nil {
+			logger.Warn("could not load system certificates, only ca bundle certificates are trusted", slog.String("error", sysErr.Error()))
+			pool = x509.NewCertPool()
+		}
+		for _, cert := range caCerts {
+			pool.AddCert(cert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		if config.ClientCertFile == "" || config.ClientKeyFile == "" {
+			err = fmt.Errorf("%w, client certificate and key files must both be set", ErrInvalidTLSConfig)
+			return
+		}
+		cert, loadErr := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+		if loadErr != nil {
+			err = fmt.Errorf("%w, could not load client certificate, %w", ErrInvalidTLSConfig, loadErr)
+			return
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return
+}
+
+// failingTransport fails every request with err, for clients whose TLS config could not be loaded not to fall back
+// on a less secure one.
+type failingTransport struct {
+	err error
+}
+
+func (t failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	return nil, t.err
+}
@@ -0,0 +1,140 @@
+package sdk
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestClientCert writes a self-signed client certificate and its key in dir.
+func writeTestClientCert(t *testing.T, dir string) (certFile string, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "connector"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() error = %v", err)
+	}
+	certFile = filepath.Join(dir, "client.crt")
+	keyFile = filepath.Join(dir, "client.key")
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestConnectorManagerClient_tlsConfig(t *testing.T) {
+	var gotVersion uint16
+	var gotClientCert bool
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotVersion = r.TLS.Version
+		gotClientCert = len(r.TLS.PeerCertificates) > 0
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert, MinVersion: tls.VersionTLS12}
+	// handshake errors of untrusted requests
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, []byte(testPEMCert(t, server)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	invalidCAFile := filepath.Join(dir, "invalid.pem")
+	if err := os.WriteFile(invalidCAFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := writeTestClientCert(t, dir)
+
+	tests := []struct {
+		name           string
+		config         ConnectorManagerClientConfig
+		consoleCAs     bool
+		wantErr        error
+		wantUntrusted  bool
+		wantVersion    uint16
+		wantMinVersion uint16
+		wantClientCert bool
+	}{
+		{name: "untrusted", wantUntrusted: true},
+		{name: "ca bundle", config: ConnectorManagerClientConfig{CACertsFile: caFile}, wantVersion: tls.VersionTLS13, wantMinVersion: tls.VersionTLS12},
+		{name: "ca bundle kept with console custom ca", config: ConnectorManagerClientConfig{CACertsFile: caFile}, consoleCAs: true, wantVersion: tls.VersionTLS13},
+		{
+			name:           "client certificate",
+			config:         ConnectorManagerClientConfig{CACertsFile: caFile, ClientCertFile: certFile, ClientKeyFile: keyFile},
+			wantVersion:    tls.VersionTLS13,
+			wantClientCert: true,
+		},
+		{name: "min tls version", config: ConnectorManagerClientConfig{Insecure: true, MinTLSVersion: "TLS1.3"}, wantVersion: tls.VersionTLS13, wantMinVersion: tls.VersionTLS13},
+		{name: "missing ca bundle", config: ConnectorManagerClientConfig{CACertsFile: filepath.Join(dir, "missing.pem")}, wantErr: ErrInvalidTLSConfig},
+		{name: "invalid ca bundle", config: ConnectorManagerClientConfig{CACertsFile: invalidCAFile}, wantErr: ErrInvalidTLSConfig},
+		{name: "client certificate without key", config: ConnectorManagerClientConfig{ClientCertFile: certFile}, wantErr: ErrInvalidTLSConfig},
+		{name: "unsupported min tls version", config: ConnectorManagerClientConfig{MinTLSVersion: "1.1"}, wantErr: ErrInvalidTLSConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotVersion, gotClientCert = 0, false
+			tt.config.URL = server.URL
+			tt.config.APIKey = "key"
+			c := NewConnectorManagerClient(t.Context(), tt.config)
+			if tt.consoleCAs {
+				c.SetRootCAs(x509.NewCertPool())
+			}
+			ctx := t.Context()
+			if tt.wantUntrusted {
+				ctx = untrustedCtx(t)
+			}
+			_, err := c.getTasks(ctx)
+			switch {
+			case tt.wantErr != nil || tt.wantUntrusted:
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("getTasks() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case err != nil:
+				t.Fatalf("getTasks() error = %v", err)
+			}
+			if gotVersion != tt.wantVersion {
+				t.Errorf("getTasks() tls version = %x, want %x", gotVersion, tt.wantVersion)
+			}
+			if tt.wantMinVersion != 0 && c.transport.base.TLSClientConfig.MinVersion != tt.wantMinVersion {
+				t.Errorf("NewConnectorManagerClient() min tls version = %x, want %x", c.transport.base.TLSClientConfig.MinVersion, tt.wantMinVersion)
+			}
+			if gotClientCert != tt.wantClientCert {
+				t.Errorf("getTasks() client certificate sent = %v, want %v", gotClientCert, tt.wantClientCert)
+			}
+		})
+	}
+}
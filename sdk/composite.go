@@ -0,0 +1,28 @@
+package sdk
+
+import "github.com/glimps-re/connector-integration/sdk/status"
+
+// CompositeConnector wraps a Connector composed of subsystems (e.g. webhook listener, scanner pool, uploader)
+// reporting their state to a status.Registry: a started connector is reported Degraded when any subsystem is not OK.
+// It is meant to be given to ConnectorManagerClient.Start in place of the wrapped connector.
+type CompositeConnector struct {
+	Connector
+	registry *status.Registry
+}
+
+func NewCompositeConnector(connector Connector, registry *status.Registry) (c *CompositeConnector) {
+	return &CompositeConnector{Connector: connector, registry: registry}
+}
+
+// Unwrap returns wrapped connector.
+func (c *CompositeConnector) Unwrap() Connector {
+	return c.Connector
+}
+
+func (c *CompositeConnector) Status() (status ConnectorStatus) {
+	status = c.Connector.Status()
+	if status == Started && !c.registry.Healthy() {
+		status = Degraded
+	}
+	return
+}
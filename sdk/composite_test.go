@@ -0,0 +1,35 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/status"
+)
+
+func TestCompositeConnector_Status(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     ConnectorStatus
+		subsystems map[string]status.State
+		want       ConnectorStatus
+	}{
+		{name: "no subsystem", status: Started, want: Started},
+		{name: "healthy", status: Started, subsystems: map[string]status.State{"webhook": status.OK, "scanner": status.OK}, want: Started},
+		{name: "partially down", status: Started, subsystems: map[string]status.State{"webhook": status.Down, "scanner": status.OK}, want: Degraded},
+		{name: "stopped", status: Stopped, subsystems: map[string]status.State{"webhook": status.Down}, want: Stopped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := status.NewRegistry(nil)
+			for name, state := range tt.subsystems {
+				if err := registry.Report(t.Context(), name, state, ""); err != nil {
+					t.Fatalf("Report() error = %v", err)
+				}
+			}
+			c := NewCompositeConnector(&restartRecorder{status: tt.status}, registry)
+			if got := c.Status(); got != tt.want {
+				t.Errorf("Status() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
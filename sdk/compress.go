@@ -0,0 +1,52 @@
+package sdk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+)
+
+// CompressMinSize is the minimum size of request bodies gzipped with ConnectorManagerClientConfig.CompressRequests,
+// smaller ones are not worth it.
+const CompressMinSize = 1024
+
+// compressRequest gzips body of req, if request compression is enabled and body is at least CompressMinSize long.
+// Body is compressed once, retries resend it as is.
+func (c ConnectorManagerClient) compressRequest(req *http.Request) (err error) {
+	if !c.compress.Load() || req.GetBody == nil || req.ContentLength < CompressMinSize {
+		return
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return
+	}
+	buf := new(bytes.Buffer)
+	zw := gzip.NewWriter(buf)
+	if _, err = io.Copy(zw, body); err != nil {
+		return
+	}
+	if err = zw.Close(); err != nil {
+		return
+	}
+	compressed := buf.Bytes()
+	req.Body = io.NopCloser(bytes.NewReader(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.ContentLength = int64(len(compressed))
+	req.Header.Set("Content-Encoding", "gzip")
+	return
+}
+
+// compressionRejected reports whether manager rejected compressed req (415 Unsupported Media Type), disabling
+// request compression of client for req to be resent uncompressed.
+func (c ConnectorManagerClient) compressionRejected(req *http.Request, resp *http.Response) bool {
+	if resp.StatusCode != http.StatusUnsupportedMediaType || req.Header.Get("Content-Encoding") != "gzip" {
+		return false
+	}
+	if c.compress.CompareAndSwap(true, false) {
+		logger.Warn("connector manager does not support compressed requests, compression disabled")
+	}
+	return true
+}
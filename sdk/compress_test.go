@@ -0,0 +1,104 @@
+package sdk
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConnectorManagerClient_compressRequests(t *testing.T) {
+	large := map[string]string{"data": strings.Repeat("a", CompressMinSize)}
+	type call struct {
+		encoding string
+		body     string
+	}
+	tests := []struct {
+		name      string
+		compress  bool
+		body      any
+		statuses  []int
+		wantErr   bool
+		wantCalls []call
+		// wantCompress is whether compression is still enabled after request
+		wantCompress bool
+	}{
+		{name: "disabled", body: large, statuses: []int{http.StatusOK}, wantCalls: []call{{body: `{"data":"` + large["data"] + `"}`}}},
+		{name: "small body", compress: true, body: map[string]string{"data": "a"}, statuses: []int{http.StatusOK}, wantCalls: []call{{body: `{"data":"a"}`}}, wantCompress: true},
+		{name: "large body", compress: true, body: large, statuses: []int{http.StatusOK}, wantCalls: []call{{encoding: "gzip", body: `{"data":"` + large["data"] + `"}`}}, wantCompress: true},
+		{
+			name:     "retried large body",
+			compress: true,
+			body:     large,
+			statuses: []int{http.StatusBadGateway, http.StatusOK},
+			wantCalls: []call{
+				{encoding: "gzip", body: `{"data":"` + large["data"] + `"}`},
+				{encoding: "gzip", body: `{"data":"` + large["data"] + `"}`},
+			},
+			wantCompress: true,
+		},
+		{
+			name:     "compression rejected",
+			compress: true,
+			body:     large,
+			statuses: []int{http.StatusUnsupportedMediaType, http.StatusOK},
+			wantCalls: []call{
+				{encoding: "gzip", body: `{"data":"` + large["data"] + `"}`},
+				{body: `{"data":"` + large["data"] + `"}`},
+			},
+		},
+		{
+			name:      "uncompressed request rejected",
+			body:      large,
+			statuses:  []int{http.StatusUnsupportedMediaType},
+			wantErr:   true,
+			wantCalls: []call{{body: `{"data":"` + large["data"] + `"}`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []call
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body io.Reader = r.Body
+				if r.Header.Get("Content-Encoding") == "gzip" {
+					zr, err := gzip.NewReader(r.Body)
+					if err != nil {
+						t.Errorf("invalid gzip body, %v", err)
+						return
+					}
+					body = zr
+				}
+				raw, err := io.ReadAll(body)
+				if err != nil {
+					t.Errorf("could not read body, %v", err)
+				}
+				calls = append(calls, call{encoding: r.Header.Get("Content-Encoding"), body: string(raw)})
+				w.WriteHeader(tt.statuses[min(len(calls)-1, len(tt.statuses)-1)])
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{
+				URL:                  server.URL,
+				APIKey:               "key",
+				CompressRequests:     tt.compress,
+				RetryInitialInterval: time.Millisecond,
+			})
+			err := c.callEndpoint(t.Context(), c.endpoint.Load(), http.MethodPost, "events", tt.body, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("callEndpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(calls, tt.wantCalls, cmp.AllowUnexported(call{})); diff != "" {
+				t.Errorf("callEndpoint() calls diff(got-want)=%s", diff)
+			}
+			if got := c.compress.Load(); got != tt.wantCompress {
+				t.Errorf("callEndpoint() compression enabled = %v, want %v", got, tt.wantCompress)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+)
+
+var (
+	ErrInvalidConfigSchema        = errors.New("invalid connector config schemas")
+	ErrUnknownConfigSchemaVersion = errors.New("unknown connector config schema version")
+	ErrInvalidConnectorVersion    = errors.New("invalid connector version")
+)
+
+// ConfigSchema records connector versions able to run configs of a config schema version, declared in connector.yaml
+// config_schemas section.
+type ConfigSchema struct {
+	Version             int    `yaml:"version" json:"version"`
+	MinConnectorVersion string `yaml:"min_connector_version" json:"min_connector_version" desc:"first connector version running configs of this schema, e.g. 1.2.0"`
+	MaxConnectorVersion string `yaml:"max_connector_version" json:"max_connector_version,omitempty" desc:"last connector version running configs of this schema, any later version if empty"`
+}
+
+func parseConnectorVersion(version string) (v *semver.Version, err error) {
+	v, err = semver.NewVersion(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		err = fmt.Errorf("%w %q, %w", ErrInvalidConnectorVersion, version, err)
+	}
+	return
+}
+
+// supports reports whether connector version runs configs of schema s.
+func (s ConfigSchema) supports(version *semver.Version) bool {
+	minVersion, err := parseConnectorVersion(s.MinConnectorVersion)
+	if err != nil || version.LessThan(*minVersion) {
+		return false
+	}
+	if s.MaxConnectorVersion == "" {
+		return true
+	}
+	maxVersion, err := parseConnectorVersion(s.MaxConnectorVersion)
+	return err == nil && !maxVersion.LessThan(*version)
+}
+
+// checkConfigSchemas checks config schemas of c: increasing unique versions, valid connector versions, and
+// current ConfigSchemaVersion being declared.
+func checkConfigSchemas(c ConnectorType) (err error) {
+	if c.ConfigSchemaVersion == 0 && len(c.ConfigSchemas) == 0 {
+		return
+	}
+	previous := 0
+	current := false
+	for _, schema := range c.ConfigSchemas {
+		if schema.Version <= previous {
+			err = fmt.Errorf("%w: version %d must be greater than %d", ErrInvalidConfigSchema, schema.Version, previous)
+			return
+		}
+		previous = schema.Version
+		current = current || schema.Version == c.ConfigSchemaVersion
+		minVersion, parseErr := parseConnectorVersion(schema.MinConnectorVersion)
+		if parseErr != nil {
+			err = fmt.Errorf("%w: version %d, %w", ErrInvalidConfigSchema, schema.Version, parseErr)
+			return
+		}
+		if schema.MaxConnectorVersion == "" {
+			continue
+		}
+		maxVersion, parseErr := parseConnectorVersion(schema.MaxConnectorVersion)
+		if parseErr != nil {
+			err = fmt.Errorf("%w: version %d, %w", ErrInvalidConfigSchema, schema.Version, parseErr)
+			return
+		}
+		if maxVersion.LessThan(*minVersion) {
+			err = fmt.Errorf("%w: version %d max connector version is lower than min one", ErrInvalidConfigSchema, schema.Version)
+			return
+		}
+	}
+	if !current {
+		err = fmt.Errorf("%w: current config_schema_version %d is not declared in config_schemas", ErrInvalidConfigSchema, c.ConfigSchemaVersion)
+	}
+	return
+}
+
+// IsConfigCompatible reports whether a config of configSchemaVersion, stored by the manager, can be run by
+// connectorVersion of given connector type, e.g. before prompting users to upgrade a connector.
+// Configs of connector types declaring no config schemas are always compatible.
+func (c ConnectorTypeLoader) IsConfigCompatible(connectorTypeID string, configSchemaVersion int, connectorVersion string) (compatible bool, err error) {
+	connectorType, ok := c.connectorsTypes[connectorTypeID]
+	if !ok {
+		err = ErrConnectorTypeNotFound
+		return
+	}
+	if len(connectorType.ConfigSchemas) == 0 {
+		compatible = true
+		return
+	}
+	version, err := parseConnectorVersion(connectorVersion)
+	if err != nil {
+		return
+	}
+	for _, schema := range connectorType.ConfigSchemas {
+		if schema.Version == configSchemaVersion {
+			compatible = schema.supports(version)
+			return
+		}
+	}
+	err = fmt.Errorf("%w %d for connector type %s", ErrUnknownConfigSchemaVersion, configSchemaVersion, connectorTypeID)
+	return
+}
+
+// ConfigSchemaVersionFor returns the latest config schema version connectorVersion of given connector type runs,
+// 0 if connector type declares no config schemas or connector version runs none.
+func (c ConnectorTypeLoader) ConfigSchemaVersionFor(connectorTypeID string, connectorVersion string) (configSchemaVersion int, err error) {
+	connectorType, ok := c.connectorsTypes[connectorTypeID]
+	if !ok {
+		err = ErrConnectorTypeNotFound
+		return
+	}
+	if len(connectorType.ConfigSchemas) == 0 {
+		return
+	}
+	version, err := parseConnectorVersion(connectorVersion)
+	if err != nil {
+		return
+	}
+	for _, schema := range connectorType.ConfigSchemas {
+		if schema.supports(version) {
+			configSchemaVersion = schema.Version
+		}
+	}
+	return
+}
@@ -0,0 +1,103 @@
+package sdk
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_checkConfigSchemas(t *testing.T) {
+	tests := []struct {
+		name          string
+		connectorType ConnectorType
+		wantErr       error
+	}{
+		{name: "none"},
+		{
+			name: "ok",
+			connectorType: ConnectorType{ConfigSchemaVersion: 2, ConfigSchemas: []ConfigSchema{
+				{Version: 1, MinConnectorVersion: "1.0.0", MaxConnectorVersion: "1.4.9"},
+				{Version: 2, MinConnectorVersion: "v1.1.0"},
+			}},
+		},
+		{
+			name:          "current not declared",
+			connectorType: ConnectorType{ConfigSchemaVersion: 2, ConfigSchemas: []ConfigSchema{{Version: 1, MinConnectorVersion: "1.0.0"}}},
+			wantErr:       ErrInvalidConfigSchema,
+		},
+		{
+			name:          "no schemas",
+			connectorType: ConnectorType{ConfigSchemaVersion: 1},
+			wantErr:       ErrInvalidConfigSchema,
+		},
+		{
+			name: "not increasing",
+			connectorType: ConnectorType{ConfigSchemaVersion: 1, ConfigSchemas: []ConfigSchema{
+				{Version: 2, MinConnectorVersion: "1.0.0"},
+				{Version: 1, MinConnectorVersion: "1.0.0"},
+			}},
+			wantErr: ErrInvalidConfigSchema,
+		},
+		{
+			name:          "invalid connector version",
+			connectorType: ConnectorType{ConfigSchemaVersion: 1, ConfigSchemas: []ConfigSchema{{Version: 1, MinConnectorVersion: "latest"}}},
+			wantErr:       ErrInvalidConnectorVersion,
+		},
+		{
+			name:          "max lower than min",
+			connectorType: ConnectorType{ConfigSchemaVersion: 1, ConfigSchemas: []ConfigSchema{{Version: 1, MinConnectorVersion: "1.2.0", MaxConnectorVersion: "1.1.0"}}},
+			wantErr:       ErrInvalidConfigSchema,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkConfigSchemas(tt.connectorType); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkConfigSchemas() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConnectorTypeLoader_IsConfigCompatible(t *testing.T) {
+	c, err := NewConnectorsTypesLoader(true)
+	if err != nil {
+		t.Fatalf("could not init connector types loader, err: %v", err)
+	}
+	tests := []struct {
+		name                string
+		connectorType       string
+		configSchemaVersion int
+		connectorVersion    string
+		want                bool
+		wantSchemaVersion   int
+		wantErr             error
+	}{
+		{name: "before first schema", connectorType: DummyKey, configSchemaVersion: 1, connectorVersion: "0.9.0"},
+		{name: "old schema", connectorType: DummyKey, configSchemaVersion: 1, connectorVersion: "1.0.0", want: true, wantSchemaVersion: 1},
+		{name: "current schema too recent", connectorType: DummyKey, configSchemaVersion: 2, connectorVersion: "1.0.3", wantSchemaVersion: 1},
+		{name: "both schemas", connectorType: DummyKey, configSchemaVersion: 1, connectorVersion: "v1.4.9", want: true, wantSchemaVersion: 2},
+		{name: "old schema dropped", connectorType: DummyKey, configSchemaVersion: 1, connectorVersion: "1.5.0", wantSchemaVersion: 2},
+		{name: "current schema", connectorType: DummyKey, configSchemaVersion: 2, connectorVersion: "2.0.0", want: true, wantSchemaVersion: 2},
+		{name: "unknown schema", connectorType: DummyKey, configSchemaVersion: 3, connectorVersion: "2.0.0", wantSchemaVersion: 2, wantErr: ErrUnknownConfigSchemaVersion},
+		{name: "invalid connector version", connectorType: DummyKey, configSchemaVersion: 1, connectorVersion: "dev", wantErr: ErrInvalidConnectorVersion},
+		{name: "no config schemas", connectorType: ICAPKey, configSchemaVersion: 4, connectorVersion: "1.0.0", want: true},
+		{name: "connector type not found", connectorType: "toto", wantErr: ErrConnectorTypeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.IsConfigCompatible(tt.connectorType, tt.configSchemaVersion, tt.connectorVersion)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConnectorTypeLoader.IsConfigCompatible() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ConnectorTypeLoader.IsConfigCompatible() = %v, want %v", got, tt.want)
+			}
+			schemaVersion, err := c.ConfigSchemaVersionFor(tt.connectorType, tt.connectorVersion)
+			if err != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConnectorTypeLoader.ConfigSchemaVersionFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if schemaVersion != tt.wantSchemaVersion {
+				t.Errorf("ConnectorTypeLoader.ConfigSchemaVersionFor() = %d, want %d", schemaVersion, tt.wantSchemaVersion)
+			}
+		})
+	}
+}
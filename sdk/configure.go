@@ -0,0 +1,182 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+// TransactionalConnector may be implemented by connectors to be configured in two phases.
+// When a connector implements it, client calls ValidateConfig then ApplyConfig instead of Configure,
+// and re-applies previous config if ApplyConfig fails, so connector is not left in a mixed state.
+type TransactionalConnector interface {
+	// ValidateConfig checks config without applying any of it.
+	ValidateConfig(ctx context.Context, config json.RawMessage) (err error)
+	// ApplyConfig applies a config previously validated.
+	ApplyConfig(ctx context.Context, config json.RawMessage) (err error)
+}
+
+// EffectiveConfigGetter may be implemented by connectors to report the config they actually run
+// (e.g. their typed config, after defaults and reconfigurations).
+// Returned config is stripped of its secrets before being sent to the manager (see ConfigStripper and PasswordTag),
+// it must be a struct for them to be found.
+type EffectiveConfigGetter interface {
+	EffectiveConfig() (config any)
+}
+
+var (
+	ErrConfigRolledBack  = errors.New("config apply failed, previous config restored")
+	ErrNoEffectiveConfig = errors.New("connector does not report its effective config")
+)
+
+func effectiveConfig(connector Connector) (config any, err error) {
+	getter, ok := connector.(EffectiveConfigGetter)
+	if !ok {
+		err = ErrNoEffectiveConfig
+		return
+	}
+	config, err = stripSecrets(getter.EffectiveConfig())
+	return
+}
+
+func (c ConnectorManagerClient) storeConfig(config any) {
+	raw, ok := config.(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(config); err != nil {
+			logger.Warn("could not store config", slog.String("error", err.Error()))
+			c.lastConfig.Store(nil)
+			return
+		}
+	}
+	c.lastConfig.Store(&raw)
+	c.storeConfigHash(raw)
+}
+
+// ConfigProvenance returns sources of config fields. Console ones are recorded at registration and on config
+// updates, connectors record ones they apply themselves (e.g. environment variables, local config file).
+func (c ConnectorManagerClient) ConfigProvenance() *ConfigProvenance {
+	return c.provenance
+}
+
+// consoleConfigApplied records provenance of config, applied by connector, applies its outbound proxy, custom CA
+// certificates, privacy and field encryption, and caches it for connector to start from it when manager is unavailable.
+func (c ConnectorManagerClient) consoleConfigApplied(config json.RawMessage) {
+	if err := c.provenance.SetRaw(ConfigSourceConsole, config); err != nil {
+		logger.Warn("could not record console config provenance", slog.String("error", err.Error()))
+	}
+	c.cacheConsoleConfig(config)
+	c.applyConsoleProxy(config)
+	c.applyConsoleCACerts(config)
+	c.applyConsolePrivacy(config)
+}
+
+// applyConsolePrivacy sets PII minimization and field encryption of console config on console event handler, if
+// created (see Run for the initial ones). Invalid ones keep previous ones.
+func (c ConnectorManagerClient) applyConsolePrivacy(raw json.RawMessage) {
+	h := c.handler.Load()
+	if h == nil {
+		return
+	}
+	common := CommonConnectorConfig{}
+	if err := json.Unmarshal(raw, &common); err != nil {
+		logger.Warn("could not read privacy of console config", slog.String("error", err.Error()))
+		return
+	}
+	if err := h.SetPrivacy(common.Privacy); err != nil {
+		logger.Error("could not set privacy of console config", slog.String("error", err.Error()))
+	}
+	if err := h.SetFieldEncryption(common.FieldEncryption); err != nil {
+		logger.Error("could not set field encryption of console config", slog.String("error", err.Error()))
+	}
+}
+
+// configure applies config on connector, two-phase for a TransactionalConnector. Connector is given GLIMPS Malware
+// token of secrets store if config does not set it (see WithSecrets), config being stored and cached without it, and
+// token of applied config is stored otherwise.
+func (c ConnectorManagerClient) configure(ctx context.Context, connector Connector, config json.RawMessage) (err error) {
+	applied, token, tokenFromStore, err := c.withGMalwareToken(config)
+	if err != nil {
+		return
+	}
+	applyConsoleConfig := func() {
+		c.storeConfig(config)
+		c.consoleConfigApplied(config)
+		switch {
+		case tokenFromStore:
+			c.provenance.Set(ConfigSourceSecrets, "gmalware_api_token")
+		case token != "":
+			storeGMalwareToken(c.secrets, token)
+		}
+	}
+	tc, ok := connector.(TransactionalConnector)
+	if !ok {
+		if err = connector.Configure(ctx, applied); err != nil {
+			c.configETag.Store(nil)
+			return
+		}
+		applyConsoleConfig()
+		return
+	}
+	if err = tc.ValidateConfig(ctx, applied); err != nil {
+		err = fmt.Errorf("invalid config, %w", err)
+		return
+	}
+	applyErr := tc.ApplyConfig(ctx, applied)
+	if applyErr == nil {
+		applyConsoleConfig()
+		c.notifyRollbackResolution(ctx)
+		return
+	}
+	// connector may not run the same config anymore, force next fetch
+	c.configETag.Store(nil)
+	previous := c.lastConfig.Load()
+	if previous == nil {
+		err = fmt.Errorf("could not apply config, no previous config to restore, %w", applyErr)
+		return
+	}
+	previousApplied, _, _, rollbackErr := c.withGMalwareToken(*previous)
+	if rollbackErr == nil {
+		rollbackErr = tc.ApplyConfig(ctx, previousApplied)
+	}
+	if rollbackErr != nil {
+		c.lastConfig.Store(nil)
+		c.configHash.Store(nil)
+		err = fmt.Errorf("could not apply config, %w, could not restore previous config, %w", applyErr, rollbackErr)
+		return
+	}
+	err = fmt.Errorf("%w: %w", ErrConfigRolledBack, applyErr)
+	c.notifyRollback(ctx, err)
+	return
+}
+
+func (c ConnectorManagerClient) notifyRollback(ctx context.Context, rollbackErr error) {
+	var err error
+	if h := c.handler.Load(); h != nil {
+		err = h.NotifyError(ctx, events.ConfigRollbackError, rollbackErr)
+	} else {
+		err = c.Notify(ctx, events.ErrorEvent{
+			Error: rollbackErr.Error(),
+			Type:  events.ConfigRollbackError,
+			Time:  time.Now().Unix(),
+		})
+	}
+	if err != nil {
+		logger.Error("could not notify config rollback", slog.String("error", err.Error()))
+	}
+}
+
+func (c ConnectorManagerClient) notifyRollbackResolution(ctx context.Context) {
+	h := c.handler.Load()
+	if h == nil {
+		return
+	}
+	if err := h.NotifyResolution(ctx, "config applied", events.ConfigRollbackError); err != nil {
+		logger.Error("could not notify config rollback resolution", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,360 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/secrets"
+	"github.com/google/go-cmp/cmp"
+)
+
+type transactionalConnector struct {
+	fakeConnector
+	invalid  string
+	failOn   map[string]bool
+	applied  []string
+	validate []string
+}
+
+func (c *transactionalConnector) ValidateConfig(ctx context.Context, config json.RawMessage) (err error) {
+	c.validate = append(c.validate, string(config))
+	if string(config) == c.invalid {
+		err = errors.New("invalid")
+	}
+	return
+}
+
+func (c *transactionalConnector) ApplyConfig(ctx context.Context, config json.RawMessage) (err error) {
+	c.applied = append(c.applied, string(config))
+	if c.failOn[string(config)] {
+		err = errors.New("apply failed")
+	}
+	return
+}
+
+type notifierFunc func(ctx context.Context, event any) error
+
+func (f notifierFunc) Notify(ctx context.Context, event any) error { return f(ctx, event) }
+
+func TestConnectorManagerClient_configure(t *testing.T) {
+	const (
+		previous = `{"rev":1}`
+		next     = `{"rev":2}`
+	)
+	tests := []struct {
+		name        string
+		previous    string
+		connector   *transactionalConnector
+		wantErr     error
+		wantApplied []string
+		wantConfig  string
+		wantEvents  []events.ErrorEventType
+	}{
+		{
+			name:        "ok",
+			previous:    previous,
+			connector:   &transactionalConnector{},
+			wantApplied: []string{next},
+			wantConfig:  next,
+		},
+		{
+			name:       "invalid config is not applied",
+			previous:   previous,
+			connector:  &transactionalConnector{invalid: next},
+			wantErr:    errors.New("any"),
+			wantConfig: previous,
+		},
+		{
+			name:        "rollback",
+			previous:    previous,
+			connector:   &transactionalConnector{failOn: map[string]bool{next: true}},
+			wantErr:     ErrConfigRolledBack,
+			wantApplied: []string{next, previous},
+			wantConfig:  previous,
+			wantEvents:  []events.ErrorEventType{events.ConfigRollbackError},
+		},
+		{
+			name:        "rollback fails",
+			previous:    previous,
+			connector:   &transactionalConnector{failOn: map[string]bool{next: true, previous: true}},
+			wantErr:     errors.New("any"),
+			wantApplied: []string{next, previous},
+		},
+		{
+			name:        "no previous config",
+			connector:   &transactionalConnector{failOn: map[string]bool{next: true}},
+			wantErr:     errors.New("any"),
+			wantApplied: []string{next},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotEvents []events.ErrorEventType
+			c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{})
+			c.handler.Store(events.NewHandler(notifierFunc(func(ctx context.Context, event any) error {
+				if e, ok := event.(events.ErrorEvent); ok {
+					gotEvents = append(gotEvents, e.Type)
+				}
+				return nil
+			}), slog.LevelError, nil, c.metricsCollector))
+			if tt.previous != "" {
+				c.storeConfig(json.RawMessage(tt.previous))
+			}
+
+			err := c.configure(t.Context(), tt.connector, json.RawMessage(next))
+			switch {
+			case tt.wantErr == nil && err != nil, tt.wantErr != nil && err == nil:
+				t.Fatalf("configure() error = %v, wantErr %v", err, tt.wantErr)
+			case errors.Is(tt.wantErr, ErrConfigRolledBack) && !errors.Is(err, ErrConfigRolledBack):
+				t.Fatalf("configure() error = %v, want %v", err, ErrConfigRolledBack)
+			}
+			if diff := cmp.Diff(tt.connector.applied, tt.wantApplied); diff != "" {
+				t.Errorf("configure() applied diff(got-want)=%s", diff)
+			}
+			var gotConfig string
+			if raw := c.lastConfig.Load(); raw != nil {
+				gotConfig = string(*raw)
+			}
+			if gotConfig != tt.wantConfig {
+				t.Errorf("configure() running config = %s, want %s", gotConfig, tt.wantConfig)
+			}
+			wantHash := ""
+			if tt.wantConfig != "" {
+				wantHash, _ = ConfigHash(json.RawMessage(tt.wantConfig))
+			}
+			if got := c.currentConfigHash(); got != wantHash {
+				t.Errorf("configure() config hash = %s, want %s", got, wantHash)
+			}
+			if diff := cmp.Diff(gotEvents, tt.wantEvents); diff != "" {
+				t.Errorf("configure() events diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectorManagerClient_configure_secrets(t *testing.T) {
+	tests := []struct {
+		name        string
+		stored      string
+		config      string
+		failOn      string
+		wantApplied []string
+		wantStored  string
+		wantSource  ConfigSource
+	}{
+		{
+			name:        "token of store",
+			stored:      "stored-token",
+			config:      `{"dummy_string":"new"}`,
+			wantApplied: []string{`{"dummy_string":"new","gmalware_api_token":"stored-token"}`},
+			wantStored:  "stored-token",
+			wantSource:  ConfigSourceSecrets,
+		},
+		{
+			name:        "empty token",
+			stored:      "stored-token",
+			config:      `{"gmalware_api_token":""}`,
+			wantApplied: []string{`{"gmalware_api_token":"stored-token"}`},
+			wantStored:  "stored-token",
+			wantSource:  ConfigSourceSecrets,
+		},
+		{
+			name:        "token of config stored",
+			stored:      "stored-token",
+			config:      `{"gmalware_api_token":"new-token"}`,
+			wantApplied: []string{`{"gmalware_api_token":"new-token"}`},
+			wantStored:  "new-token",
+			wantSource:  ConfigSourceConsole,
+		},
+		{
+			name:        "token of invalid config not stored",
+			stored:      "stored-token",
+			config:      `{"gmalware_api_token":"new-token"}`,
+			failOn:      `{"gmalware_api_token":"new-token"}`,
+			wantApplied: []string{`{"gmalware_api_token":"new-token"}`},
+			wantStored:  "stored-token",
+			wantSource:  ConfigSourceDefault,
+		},
+		{
+			name:        "no token",
+			config:      `{"dummy_string":"new"}`,
+			wantApplied: []string{`{"dummy_string":"new"}`},
+			wantSource:  ConfigSourceDefault,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := secrets.NewMemoryStore()
+			if tt.stored != "" {
+				if err := store.Set(secrets.GMalwareAPIToken, tt.stored); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{}, WithSecrets(store))
+			connector := &transactionalConnector{failOn: map[string]bool{tt.failOn: true}}
+			err := c.configure(t.Context(), connector, json.RawMessage(tt.config))
+			if (err != nil) != (tt.failOn != "") {
+				t.Fatalf("configure() error = %v", err)
+			}
+			if diff := cmp.Diff(connector.applied, tt.wantApplied); diff != "" {
+				t.Errorf("configure() applied diff(got-want)=%s", diff)
+			}
+			if raw := c.lastConfig.Load(); raw != nil && string(*raw) != tt.config {
+				t.Errorf("configure() running config = %s, want %s", *raw, tt.config)
+			}
+			stored, _ := store.Get(secrets.GMalwareAPIToken)
+			if stored != tt.wantStored {
+				t.Errorf("configure() stored token = %q, want %q", stored, tt.wantStored)
+			}
+			if got := c.ConfigProvenance().Sources(&DummyConfig{})["gmalware_api_token"]; got != tt.wantSource {
+				t.Errorf("configure() token source = %s, want %s", got, tt.wantSource)
+			}
+		})
+	}
+}
+
+type effectiveConfigConnector struct {
+	fakeConnector
+	config any
+}
+
+func (c *effectiveConfigConnector) EffectiveConfig() any { return c.config }
+
+func TestConnectorManagerClient_configure_privacy(t *testing.T) {
+	manager := &fakeManager{}
+	server := httptest.NewServer(manager.handler(t))
+	defer server.Close()
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"})
+	h := c.NewConsoleEventHandler(slog.LevelError, nil)
+	for _, config := range []string{`{"privacy":{"sender":"truncate"}}`, `{"privacy":{"sender":"hash"}}`} {
+		if err := c.configure(t.Context(), &fakeConnector{}, json.RawMessage(config)); err != nil {
+			t.Fatalf("configure() error = %v", err)
+		}
+		// invalid privacy (hash without key) keeps previous one
+		if err := h.NotifyEmailMitigation(t.Context(), events.ActionBlock, "id", events.ReasonPhishing, events.EmailInfos{Sender: "jane.doe@example.com"}); err != nil {
+			t.Fatalf("NotifyEmailMitigation() error = %v", err)
+		}
+	}
+	var senders []string
+	for _, envelope := range manager.events {
+		event, err := envelope.Decode()
+		if err != nil {
+			t.Fatalf("could not decode event, error: %v", err)
+		}
+		senders = append(senders, event.(events.MitigationEvent).Info.(map[string]any)["sender"].(string))
+	}
+	if diff := cmp.Diff(senders, []string{"…@example.com", "…@example.com"}); diff != "" {
+		t.Errorf("configure() minimized senders diff(got-want)=%s", diff)
+	}
+}
+
+func TestConnectorManagerClient_configure_fieldEncryption(t *testing.T) {
+	manager := &fakeManager{}
+	server := httptest.NewServer(manager.handler(t))
+	defer server.Close()
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"})
+	h := c.NewConsoleEventHandler(slog.LevelError, nil)
+	keys := []events.EncryptionKey{{ID: "1", Key: bytes.Repeat([]byte{1}, 32)}, {ID: "2", Key: bytes.Repeat([]byte{2}, 32)}}
+	for _, key := range keys {
+		config := fmt.Sprintf(`{"field_encryption":{"fields":["sender"],"key_id":%q,"key":%q}}`, key.ID, base64.StdEncoding.EncodeToString(key.Key))
+		if err := c.configure(t.Context(), &fakeConnector{}, json.RawMessage(config)); err != nil {
+			t.Fatalf("configure() error = %v", err)
+		}
+		if err := h.NotifyEmailMitigation(t.Context(), events.ActionBlock, "id", events.ReasonPhishing, events.EmailInfos{Sender: "jane.doe@example.com"}); err != nil {
+			t.Fatalf("NotifyEmailMitigation() error = %v", err)
+		}
+	}
+	cipher, err := events.NewFieldCipher(nil, keys[1], keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keyIDs []string
+	for _, envelope := range manager.events {
+		event, err := envelope.Decode()
+		if err != nil {
+			t.Fatalf("could not decode event, error: %v", err)
+		}
+		sender := event.(events.MitigationEvent).Info.(map[string]any)["sender"].(string)
+		if decrypted, err := cipher.Decrypt(events.EncryptSender, sender); err != nil || decrypted != "jane.doe@example.com" {
+			t.Errorf("configure() sender decrypted = %s (error %v), want encrypted", decrypted, err)
+		}
+		keyIDs = append(keyIDs, strings.SplitN(strings.TrimPrefix(sender, events.EncryptedPrefix), ":", 2)[0])
+	}
+	if diff := cmp.Diff(keyIDs, []string{"1", "2"}); diff != "" {
+		t.Errorf("configure() encryption key ids diff(got-want)=%s", diff)
+	}
+}
+
+func Test_effectiveConfig(t *testing.T) {
+	dummy := &DummyConfig{ReconfigurableDummyConfig: ReconfigurableDummyConfig{DummyString: "value", Password: "secret"}}
+	tests := []struct {
+		name      string
+		connector Connector
+		want      any
+		wantErr   error
+	}{
+		{
+			name:      "stripped",
+			connector: &effectiveConfigConnector{config: dummy},
+			want:      &DummyConfig{ReconfigurableDummyConfig: ReconfigurableDummyConfig{DummyString: "value"}},
+		},
+		{
+			name: "not stripper",
+			connector: &effectiveConfigConnector{config: &HostConfig{
+				CommonConnectorConfig: CommonConnectorConfig{
+					GMalwareAPIURL:           "https://gmalware.example.com",
+					GMalwareAPIToken:         "token",
+					GMalwareFallbackAPIToken: "fallback",
+					GMalwareProfiles:         []GMalwareProfile{{Name: "hr", APIToken: "profile"}},
+					ProxyUsername:            "proxy",
+					ProxyPassword:            "secret",
+					Privacy:                  events.Privacy{HashKey: "key"},
+					FieldEncryption:          events.FieldEncryption{KeyID: "1", Key: "key"},
+				},
+				Paths: []string{"/data"},
+			}},
+			want: &HostConfig{
+				CommonConnectorConfig: CommonConnectorConfig{
+					GMalwareAPIURL:   "https://gmalware.example.com",
+					GMalwareProfiles: []GMalwareProfile{{Name: "hr"}},
+					ProxyUsername:    "proxy",
+					FieldEncryption:  events.FieldEncryption{KeyID: "1"},
+				},
+				Paths: []string{"/data"},
+			},
+		},
+		{
+			name:      "raw",
+			connector: &effectiveConfigConnector{config: map[string]string{"gmalware_api_token": "token"}},
+			wantErr:   ErrUnstrippableConfig,
+		},
+		{
+			name:      "not implemented",
+			connector: &fakeConnector{},
+			wantErr:   ErrNoEffectiveConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := effectiveConfig(tt.connector)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("effectiveConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("effectiveConfig() diff(got-want)=%s", diff)
+			}
+		})
+	}
+	if dummy.Password != "secret" {
+		t.Errorf("effectiveConfig() modified connector config")
+	}
+}
@@ -0,0 +1,59 @@
+package sdk
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/glimps-re/connector-integration/sdk/state"
+	"github.com/google/uuid"
+)
+
+// connectorIDKey is the state key connector ID is persisted under.
+const connectorIDKey = "connector-id"
+
+// WithStateStore makes client persist connector ID in store, so it survives restarts and re-registrations (e.g. after
+// api key rotation). Persisted ID is sent at register for manager to link connector history, and an ID is generated
+// if neither store nor manager has one.
+func WithStateStore(store state.Store) ClientOption {
+	return func(o *clientOptions) {
+		o.store = store
+	}
+}
+
+// loadConnectorID loads connector ID persisted in store, if any.
+func (c ConnectorManagerClient) loadConnectorID() {
+	if c.store == nil {
+		return
+	}
+	id, err := c.store.Get(connectorIDKey)
+	switch {
+	case errors.Is(err, state.ErrNotFound):
+	case err != nil:
+		logger.Warn("could not load connector id", slog.String("error", err.Error()))
+	case len(id) > 0:
+		persisted := string(id)
+		c.connectorID.Store(&persisted)
+	}
+}
+
+// assignConnectorID sets connector ID after registration: manager one, else persisted or generated one with a store.
+func (c ConnectorManagerClient) assignConnectorID(managerID string) {
+	if managerID != "" {
+		c.storeConnectorID(managerID)
+		return
+	}
+	if c.store == nil || c.ConnectorID() != "" {
+		return
+	}
+	c.storeConnectorID(uuid.NewString())
+}
+
+// persistConnectorID persists connector ID in store, if any.
+func (c ConnectorManagerClient) persistConnectorID(id string) {
+	if c.store == nil {
+		return
+	}
+	if err := c.store.Put(connectorIDKey, []byte(id)); err != nil {
+		logger.Warn("could not persist connector id", slog.String("connector-id", id), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,99 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/state"
+	"github.com/google/uuid"
+)
+
+func TestConnectorManagerClient_Register_connectorID(t *testing.T) {
+	tests := []struct {
+		name          string
+		store         bool
+		persisted     string
+		managerID     string
+		wantSent      string
+		wantID        string // "generated" for a generated uuid
+		wantPersisted bool
+	}{
+		{name: "manager id without store", managerID: "manager-1", wantID: "manager-1"},
+		{name: "no id without store"},
+		{name: "generated", store: true, wantID: "generated", wantPersisted: true},
+		{name: "persisted", store: true, persisted: "persisted-1", wantSent: "persisted-1", wantID: "persisted-1", wantPersisted: true},
+		{name: "manager id replaces persisted one", store: true, persisted: "persisted-1", managerID: "manager-1", wantSent: "persisted-1", wantID: "manager-1", wantPersisted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent registerRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+					t.Errorf("could not decode register request, error: %v", err)
+				}
+				_ = json.NewEncoder(w).Encode(map[string]string{"connector_id": tt.managerID})
+			}))
+			defer server.Close()
+
+			var opts []ClientOption
+			var store *state.FileStore
+			if tt.store {
+				var err error
+				if store, err = state.NewFileStore(t.TempDir(), nil); err != nil {
+					t.Fatalf("NewFileStore() error = %v", err)
+				}
+				if tt.persisted != "" {
+					if err = store.Put(connectorIDKey, []byte(tt.persisted)); err != nil {
+						t.Fatalf("Put() error = %v", err)
+					}
+				}
+				opts = append(opts, WithStateStore(store))
+			}
+			c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"}, opts...)
+			info := &RegistrationInfo{}
+			if err := c.Register(t.Context(), "1.0.0", info); err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+			if sent.ConnectorID != tt.wantSent {
+				t.Errorf("Register() sent connector id = %q, want %q", sent.ConnectorID, tt.wantSent)
+			}
+			got := c.ConnectorID()
+			switch {
+			case tt.wantID == "generated":
+				if err := uuid.Validate(got); err != nil {
+					t.Errorf("ConnectorID() = %q, want a generated uuid", got)
+				}
+			case got != tt.wantID:
+				t.Errorf("ConnectorID() = %q, want %q", got, tt.wantID)
+			}
+			if info.ConnectorID != got {
+				t.Errorf("Register() info connector id = %q, want %q", info.ConnectorID, got)
+			}
+			if store == nil {
+				return
+			}
+			persisted, err := store.Get(connectorIDKey)
+			switch {
+			case !tt.wantPersisted && !errors.Is(err, state.ErrNotFound):
+				t.Errorf("persisted connector id = %q, error = %v, want none", persisted, err)
+			case tt.wantPersisted && string(persisted) != got:
+				t.Errorf("persisted connector id = %q, error = %v, want %q", persisted, err, got)
+			}
+
+			// restarted connector registers with its persisted id
+			restarted := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "rotated-key"}, opts...)
+			if restarted.ConnectorID() != got {
+				t.Errorf("restarted ConnectorID() = %q, want %q", restarted.ConnectorID(), got)
+			}
+			if err = restarted.Register(t.Context(), "1.0.0", &RegistrationInfo{}); err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+			if sent.ConnectorID != got {
+				t.Errorf("restarted Register() sent connector id = %q, want %q", sent.ConnectorID, got)
+			}
+		})
+	}
+}
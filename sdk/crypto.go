@@ -0,0 +1,54 @@
+package sdk
+
+import (
+	"crypto/fips140"
+	"crypto/tls"
+	"errors"
+)
+
+// CryptoMode is the cryptography mode a connector runs in, reported at registration.
+type CryptoMode string
+
+const (
+	// any algorithm supported by Go standard library
+	CryptoStandard CryptoMode = "standard"
+	// FIPS 140-3 mode of Go cryptographic module: only FIPS-approved algorithms are used
+	// (TLS included), see https://go.dev/doc/security/fips140
+	CryptoFIPS CryptoMode = "fips"
+)
+
+func (CryptoMode) Values() []CryptoMode {
+	return []CryptoMode{CryptoStandard, CryptoFIPS}
+}
+
+var ErrFIPSModeDisabled = errors.New("connector built with fips tag must run in FIPS 140-3 mode (GODEBUG=fips140=on, or built with GOFIPS140)")
+
+// CurrentCryptoMode returns the mode of Go cryptographic module.
+func CurrentCryptoMode() CryptoMode {
+	if fips140.Enabled() {
+		return CryptoFIPS
+	}
+	return CryptoStandard
+}
+
+// FIPSRequired reports whether connector was built with the fips build tag, which requires FIPS 140-3 mode.
+func FIPSRequired() bool {
+	return fipsBuild
+}
+
+// CheckCryptoMode returns ErrFIPSModeDisabled if FIPS mode is required (fips build tag) but not enabled.
+func CheckCryptoMode() (err error) {
+	if FIPSRequired() && CurrentCryptoMode() != CryptoFIPS {
+		err = ErrFIPSModeDisabled
+	}
+	return
+}
+
+// NewTLSConfig returns the TLS config used by SDK clients: TLS 1.2 minimum; in FIPS mode,
+// crypto/tls only negotiates FIPS-approved versions, cipher suites and curves.
+func NewTLSConfig(insecure bool) *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecure, //nolint:gosec // optional insecure
+	}
+}
@@ -0,0 +1,6 @@
+//go:build fips
+
+package sdk
+
+// built with -tags fips, FIPS 140-3 mode is required (see CheckCryptoMode)
+const fipsBuild = true
@@ -0,0 +1,5 @@
+//go:build !fips
+
+package sdk
+
+const fipsBuild = false
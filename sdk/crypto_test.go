@@ -0,0 +1,28 @@
+package sdk
+
+import (
+	"crypto/fips140"
+	"crypto/tls"
+	"errors"
+	"testing"
+)
+
+func TestCheckCryptoMode(t *testing.T) {
+	wantMode := CryptoStandard
+	if fips140.Enabled() {
+		wantMode = CryptoFIPS
+	}
+	if got := CurrentCryptoMode(); got != wantMode {
+		t.Errorf("CurrentCryptoMode() = %s, want %s", got, wantMode)
+	}
+	var wantErr error
+	if FIPSRequired() && !fips140.Enabled() {
+		wantErr = ErrFIPSModeDisabled
+	}
+	if err := CheckCryptoMode(); !errors.Is(err, wantErr) {
+		t.Errorf("CheckCryptoMode() error = %v, want %v", err, wantErr)
+	}
+	if got := NewTLSConfig(false); got.MinVersion != tls.VersionTLS12 || got.InsecureSkipVerify {
+		t.Errorf("NewTLSConfig() = %+v", got)
+	}
+}
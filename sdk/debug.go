@@ -0,0 +1,202 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+const DefaultDebugPort = 6060
+
+type DebugOptions struct {
+	// Enabled starts debug server (pprof, expvar, config dump, event queue stats).
+	Enabled bool
+	// Port debug server listens to on localhost, DefaultDebugPort if 0.
+	Port int
+}
+
+// secret fields are blanked in config dumps: password tagged ones, and tokens or secrets (e.g. GLIMPS Malware token)
+// ErrUnstrippableConfig is returned for configs whose secrets can not be found, e.g. raw ones, not to expose them.
+var ErrUnstrippableConfig = errors.New("cannot strip secrets of config")
+
+var secretFieldRe = regexp.MustCompile(`(?i)(token|secret|password)`)
+
+// DebugHandler serves, under /debug/:
+//   - pprof/: runtime profiles (net/http/pprof)
+//   - vars: expvar variables
+//   - config: config returned by config func, with secrets stripped
+//   - provenance: source of each config field (see ConfigProvenance)
+//   - events: event queue stats and unresolved errors
+//   - metrics: manager communication metrics (see MetricsHandler)
+//
+// It exposes connector internals, it must only be served locally (see RunOptions.Debug).
+func (c ConnectorManagerClient) DebugHandler(config func() any) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/debug/metrics", c.MetricsHandler())
+	mux.HandleFunc("/debug/config", func(w http.ResponseWriter, r *http.Request) {
+		stripped, err := stripSecrets(config())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeDebugJSON(w, stripped)
+	})
+	mux.HandleFunc("/debug/provenance", func(w http.ResponseWriter, r *http.Request) {
+		writeDebugJSON(w, c.provenance.Sources(config()))
+	})
+	mux.HandleFunc("/debug/events", func(w http.ResponseWriter, r *http.Request) {
+		writeDebugJSON(w, c.eventQueueStats())
+	})
+	return mux
+}
+
+type eventQueueStats struct {
+	EventsPending    int64                            `json:"events_pending"`
+	TasksQueued      int64                            `json:"tasks_queued"`
+	UnresolvedErrors map[events.ErrorEventType]string `json:"unresolved_errors"`
+}
+
+func (c ConnectorManagerClient) eventQueueStats() (stats eventQueueStats) {
+	snapshot := c.metricsCollector.Client().Snapshot()
+	stats = eventQueueStats{
+		EventsPending: snapshot.EventsPending,
+		TasksQueued:   snapshot.TasksQueued,
+	}
+	if h := c.handler.Load(); h != nil {
+		stats.UnresolvedErrors = h.UnresolvedErrors()
+	}
+	return
+}
+
+func writeDebugJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		logger.Warn("could not write debug response", slog.String("error", err.Error()))
+	}
+}
+
+// stripSecrets returns a copy of config with secret fields blanked, stripped by config itself if it is a ConfigStripper.
+func stripSecrets(config any) (stripped any, err error) {
+	if config == nil {
+		return
+	}
+	if stripper, ok := config.(ConfigStripper); ok {
+		config = stripper.Strip()
+	}
+	v := reflect.ValueOf(config)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		// raw configs (e.g. json.RawMessage) carry no field information to find secrets
+		err = fmt.Errorf("%w, %T config", ErrUnstrippableConfig, config)
+		return
+	}
+	// deep copy, to blank secrets without altering config
+	raw, err := json.Marshal(v.Interface())
+	if err != nil {
+		return
+	}
+	copied := reflect.New(v.Type())
+	if err = json.Unmarshal(raw, copied.Interface()); err != nil {
+		return
+	}
+	blankSecrets(copied.Elem())
+	stripped = copied.Interface()
+	return
+}
+
+func blankSecrets(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			blankSecrets(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			blankSecrets(v.Index(i))
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fv := v.Field(i)
+			secret := field.Tag.Get(PasswordTag) == "true" || secretFieldRe.MatchString(field.Name)
+			switch {
+			case secret && fv.Kind() == reflect.String:
+				fv.SetString("")
+				continue
+			case secret && fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+				// e.g. archive passwords, count is kept
+				for j := range fv.Len() {
+					fv.Index(j).SetString("")
+				}
+				continue
+			}
+			blankSecrets(fv)
+		}
+	}
+}
+
+// serveDebug serves DebugHandler on localhost until ctx is done.
+func (c ConnectorManagerClient) serveDebug(ctx context.Context, opts DebugOptions, config func() any) (err error) {
+	if opts.Port == 0 {
+		opts.Port = DefaultDebugPort
+	}
+	err = serveLocal(ctx, "debug", opts.Port, c.DebugHandler(config))
+	return
+}
+
+// serveLocal serves handler on localhost port until ctx is done.
+func serveLocal(ctx context.Context, name string, port int, handler http.Handler) (err error) {
+	err = serve(ctx, name, net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), handler)
+	return
+}
+
+// serve serves handler on addr until ctx is done.
+func serve(ctx context.Context, name string, addr string, handler http.Handler) (err error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		err = fmt.Errorf("could not start %s server, %w", name, err)
+		return
+	}
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		logger.Info(name+" server started", slog.String("addr", listener.Addr().String()))
+		if serveErr := server.Serve(listener); serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			logger.Error(name+" server stopped", slog.String("error", serveErr.Error()))
+		}
+	}()
+	return
+}
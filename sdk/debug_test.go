@@ -0,0 +1,116 @@
+package sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/archive"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStripSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  any
+		want    any
+		wantErr bool
+	}{
+		{name: "nil"},
+		{
+			name: "host",
+			config: &HostConfig{
+				CommonConnectorConfig: CommonConnectorConfig{
+					GMalwareAPIURL:    "https://gmalware.example.com",
+					GMalwareAPIToken:  "token",
+					EncryptedArchives: archive.Policy{Passwords: []string{"infected", "secret"}},
+				},
+				Paths:      []string{"/data"},
+				Quarantine: HostQuarantineConfig{Location: "/var/lib/gmhost", Password: "infected"},
+			},
+			want: &HostConfig{
+				CommonConnectorConfig: CommonConnectorConfig{
+					GMalwareAPIURL:    "https://gmalware.example.com",
+					EncryptedArchives: archive.Policy{Passwords: []string{"", ""}},
+				},
+				Paths:      []string{"/data"},
+				Quarantine: HostQuarantineConfig{Location: "/var/lib/gmhost"},
+			},
+		},
+		{
+			name:   "stripper",
+			config: &M365Config{M365ClientID: "client", M365ClientSecret: "secret", HeaderTokenValue: "header"},
+			want:   &M365Config{M365ClientID: "client"},
+		},
+		{name: "raw", config: json.RawMessage(`{"gmalware_api_token":"token"}`), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stripSecrets(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("stripSecrets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("stripSecrets() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectorManagerClient_DebugHandler(t *testing.T) {
+	config := &HostConfig{Quarantine: HostQuarantineConfig{Password: "secret"}}
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: "http://127.0.0.1:0", APIKey: "key"})
+	c.metricsCollector.Client().AddEventsPending(2)
+	handler := c.DebugHandler(func() any { return config })
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   map[string]any
+	}{
+		{path: "/debug/events", wantStatus: http.StatusOK, wantBody: map[string]any{"events_pending": float64(2), "tasks_queued": float64(0), "unresolved_errors": nil}},
+		{path: "/debug/pprof/", wantStatus: http.StatusOK},
+		{path: "/debug/vars", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("GET %s status = %d, want %d", tt.path, rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody == nil {
+				return
+			}
+			var got map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("GET %s invalid body, error: %v", tt.path, err)
+			}
+			if diff := cmp.Diff(got, tt.wantBody); diff != "" {
+				t.Errorf("GET %s diff(got-want)=%s", tt.path, diff)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+	var got HostConfig
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("GET /debug/config invalid body, error: %v", err)
+	}
+	if got.Quarantine.Password != "" || config.Quarantine.Password != "secret" {
+		t.Errorf("GET /debug/config password = %q (config %q), want stripped copy", got.Quarantine.Password, config.Quarantine.Password)
+	}
+
+	c.ConfigProvenance().Set(ConfigSourceFile, "quarantine")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/provenance", nil))
+	sources := map[string]ConfigSource{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &sources); err != nil {
+		t.Fatalf("GET /debug/provenance invalid body, error: %v", err)
+	}
+	if sources["quarantine.password"] != ConfigSourceFile || sources["workers"] != ConfigSourceDefault {
+		t.Errorf("GET /debug/provenance = %v, want quarantine fields from file", sources)
+	}
+}
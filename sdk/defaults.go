@@ -0,0 +1,159 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTag is the struct tag holding a config field default value, e.g. `default:"5m"`.
+// Slices take "[]" for an empty slice or comma separated values for string slices.
+const DefaultTag = "default"
+
+// ErrInvalidDefault is returned by SetDefaults when a default tag can not be parsed into its field type.
+var ErrInvalidDefault = errors.New("invalid default value")
+
+// SetDefaults sets zero fields of config, a pointer to struct, to their default tag value. Nested and embedded
+// structs are walked, fields already set are left untouched. For a config decoded from a source, zero values it
+// sets (e.g. an explicit false on a default:"true" bool) can not be told apart from unset fields: use SetDefaultsRaw.
+func SetDefaults(config any) (err error) {
+	err = setDefaults(config, nil)
+	return
+}
+
+// SetDefaultsRaw is SetDefaults for config decoded from raw, a JSON object (e.g. a local config file converted to
+// JSON): only fields absent from raw are set to their default tag value, fields raw sets to their zero value or
+// null being kept.
+func SetDefaultsRaw(config any, raw []byte) (err error) {
+	paths := []string{}
+	if err = rawFieldPaths(raw, "", &paths); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidDefault, err)
+		return
+	}
+	err = setDefaults(config, paths)
+	return
+}
+
+// setDefaults sets defaults of config fields, except those at paths or under them.
+func setDefaults(config any, paths []string) (err error) {
+	value := reflect.ValueOf(config)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		err = fmt.Errorf("%w: %T is not a pointer to struct", ErrInvalidDefault, config)
+		return
+	}
+	err = setStructDefaults(value.Elem(), "", paths)
+	return
+}
+
+func setStructDefaults(value reflect.Value, path string, present []string) (err error) {
+	valueType := value.Type()
+	for i := range valueType.NumField() {
+		field := valueType.Field(i)
+		// exported fields of unexported embedded structs are still settable
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		fieldPath := path
+		switch {
+		case name == "-":
+			continue
+		case name != "":
+			fieldPath = jsonPath(path, name)
+		case !field.Anonymous:
+			fieldPath = jsonPath(path, field.Name)
+		}
+		if fieldPath != path && isPresent(fieldPath, present) {
+			continue
+		}
+		fieldValue := value.Field(i)
+		if defaultValue, ok := field.Tag.Lookup(DefaultTag); ok && field.IsExported() && fieldValue.IsZero() {
+			if err = setDefault(fieldValue, defaultValue); err != nil {
+				err = fmt.Errorf("%w: %s.%s: %w", ErrInvalidDefault, valueType.Name(), field.Name, err)
+				return
+			}
+		}
+		if fieldValue.Kind() == reflect.Struct {
+			if err = setStructDefaults(fieldValue, fieldPath, present); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// isPresent returns whether field at path is set by a source setting fields at present paths (see rawFieldPaths):
+// the field itself or one of its parents (e.g. a null object).
+func isPresent(path string, present []string) bool {
+	for _, p := range present {
+		if p == path || isSubPath(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func setDefault(value reflect.Value, defaultValue string) (err error) {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(defaultValue)
+	case reflect.Bool:
+		b, parseErr := strconv.ParseBool(defaultValue)
+		if parseErr != nil {
+			err = parseErr
+			return
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Type() == reflect.TypeFor[Duration]() || value.Type() == reflect.TypeFor[time.Duration]() {
+			d, parseErr := time.ParseDuration(defaultValue)
+			if parseErr != nil {
+				err = parseErr
+				return
+			}
+			value.SetInt(int64(d))
+			return
+		}
+		n, parseErr := strconv.ParseInt(defaultValue, 10, value.Type().Bits())
+		if parseErr != nil {
+			err = parseErr
+			return
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, parseErr := strconv.ParseUint(defaultValue, 10, value.Type().Bits())
+		if parseErr != nil {
+			err = parseErr
+			return
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, parseErr := strconv.ParseFloat(defaultValue, value.Type().Bits())
+		if parseErr != nil {
+			err = parseErr
+			return
+		}
+		value.SetFloat(f)
+	case reflect.Slice:
+		if defaultValue == "[]" {
+			value.Set(reflect.MakeSlice(value.Type(), 0, 0))
+			return
+		}
+		if value.Type().Elem().Kind() != reflect.String {
+			err = fmt.Errorf("only [] is supported for %s", value.Type())
+			return
+		}
+		items := strings.Split(defaultValue, ",")
+		slice := reflect.MakeSlice(value.Type(), len(items), len(items))
+		for i, item := range items {
+			slice.Index(i).SetString(strings.TrimSpace(item))
+		}
+		value.Set(slice)
+	default:
+		err = fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return
+}
@@ -0,0 +1,152 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testDefaultsNested struct {
+	Delay   time.Duration `default:"1m30s"`
+	Enabled bool          `default:"true"`
+}
+
+type testDefaultsConfig struct {
+	testDefaultsNested
+	Name    string         `default:"connector"`
+	Engine  RealTimeEngine `default:"polling"`
+	Timeout Duration       `default:"5m"`
+	Workers int            `default:"4"`
+	Limit   uint16         `default:"512"`
+	Ratio   float64        `default:"0.5"`
+	Tags    []string       `default:"a, b"`
+	Objects []DummyObject  `default:"[]"`
+	Nested  testDefaultsNested
+	NoTag   string
+}
+
+func TestSetDefaults(t *testing.T) {
+	got := testDefaultsConfig{Workers: 8}
+	if err := SetDefaults(&got); err != nil {
+		t.Fatalf("SetDefaults() error = %v", err)
+	}
+	want := testDefaultsConfig{
+		testDefaultsNested: testDefaultsNested{Delay: 90 * time.Second, Enabled: true},
+		Name:               "connector",
+		Engine:             RealTimePolling,
+		Timeout:            Duration(5 * time.Minute),
+		Workers:            8,
+		Limit:              512,
+		Ratio:              0.5,
+		Tags:               []string{"a", "b"},
+		Objects:            []DummyObject{},
+		Nested:             testDefaultsNested{Delay: 90 * time.Second, Enabled: true},
+	}
+	if diff := cmp.Diff(got, want, cmp.AllowUnexported(testDefaultsConfig{})); diff != "" {
+		t.Errorf("SetDefaults() diff(got-want)=%s", diff)
+	}
+
+	tests := []struct {
+		name   string
+		config any
+	}{
+		{name: "not a pointer", config: testDefaultsConfig{}},
+		{name: "invalid int", config: &struct {
+			Workers int `default:"four"`
+		}{}},
+		{name: "int overflow", config: &struct {
+			Limit uint8 `default:"512"`
+		}{}},
+		{name: "invalid duration", config: &struct {
+			Timeout Duration `default:"10"`
+		}{}},
+		{name: "struct slice values", config: &struct {
+			Objects []DummyObject `default:"a,b"`
+		}{}},
+		{name: "unsupported type", config: &struct {
+			Labels map[string]string `default:"a"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetDefaults(tt.config); !errors.Is(err, ErrInvalidDefault) {
+				t.Errorf("SetDefaults() error = %v, want %v", err, ErrInvalidDefault)
+			}
+		})
+	}
+}
+
+func TestSetDefaultsRaw(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    HostActionsConfig
+		wantErr error
+	}{
+		{name: "absent", raw: `{}`, want: HostActionsConfig{Delete: true, Quarantine: true, Log: true}},
+		{name: "explicit false", raw: `{"actions":{"delete":false,"log":false}}`, want: HostActionsConfig{Quarantine: true}},
+		{name: "explicit true", raw: `{"actions":{"delete":true}}`, want: HostActionsConfig{Delete: true, Quarantine: true, Log: true}},
+		{name: "null object", raw: `{"actions":null}`},
+		{name: "invalid json", raw: `[]`, wantErr: ErrInvalidDefault},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := HostConfig{}
+			if tt.wantErr == nil {
+				if err := json.Unmarshal([]byte(tt.raw), &config); err != nil {
+					t.Fatalf("could not decode config, error: %v", err)
+				}
+			}
+			err := SetDefaultsRaw(&config, []byte(tt.raw))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetDefaultsRaw() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got := HostActionsConfig{Delete: config.Actions.Delete, Quarantine: config.Actions.Quarantine, Log: config.Actions.Log}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("SetDefaultsRaw() actions diff(got-want)=%s", diff)
+			}
+			// fields absent from raw are set
+			if config.Workers != 4 {
+				t.Errorf("SetDefaultsRaw() workers = %d, want 4", config.Workers)
+			}
+		})
+	}
+}
+
+func TestInitDefault_configFields(t *testing.T) {
+	config, err := InitDefault(HostKey)
+	if err != nil {
+		t.Fatalf("InitDefault() error = %v", err)
+	}
+	hostConfig, ok := config.(*HostConfig)
+	if !ok {
+		t.Fatalf("InitDefault() = %T, want *HostConfig", config)
+	}
+	fields, err := getConfigFields(*hostConfig)
+	if err != nil {
+		t.Fatalf("getConfigFields() error = %v", err)
+	}
+	got := map[string]any{}
+	for _, field := range fields {
+		switch field.Key {
+		case "workers", "max_file_size", "stale_mount_timeout", "gmalware_timeout", "os":
+			got[field.Key] = field.DefaultValue
+		}
+	}
+	want := map[string]any{
+		"workers":             4,
+		"max_file_size":       "100MiB",
+		"stale_mount_timeout": Duration(10 * time.Second),
+		"gmalware_timeout":    Duration(5 * time.Minute),
+		"os":                  HostLinux,
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("getConfigFields() default values diff(got-want)=%s", diff)
+	}
+}
@@ -0,0 +1,72 @@
+package sdk
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/secrets"
+	"github.com/glimps-re/connector-integration/sdk/state"
+)
+
+// DialContextFunc dials connections to connector manager, see net.Dialer.DialContext.
+type DialContextFunc func(ctx context.Context, network string, addr string) (conn net.Conn, err error)
+
+// ClientOption customizes a ConnectorManagerClient, see NewConnectorManagerClient.
+type ClientOption func(o *clientOptions)
+
+type clientOptions struct {
+	dial      DialContextFunc
+	store     state.Store
+	secrets   secrets.Store
+	tasksWait time.Duration
+}
+
+// WithDialer makes client dial manager with dial, e.g. through an SSH tunnel.
+func WithDialer(dial DialContextFunc) ClientOption {
+	return func(o *clientOptions) {
+		o.dial = dial
+	}
+}
+
+// WithLongPolling makes client long poll tasks (see ConnectorManagerClientConfig.TasksWait): manager holds GET /tasks
+// until a task is available or wait elapses, so idle connectors send few requests and still get tasks right away.
+// It overrides TasksWait of client config.
+func WithLongPolling(wait time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.tasksWait = wait
+	}
+}
+
+// WithUnixSocket makes client talk to a colocated manager over Unix socket at path.
+// Client URL is still used for requests path, Host header and TLS server name (e.g. "http://manager").
+func WithUnixSocket(path string) ClientOption {
+	return WithDialer(func(ctx context.Context, network string, addr string) (conn net.Conn, err error) {
+		dialer := net.Dialer{}
+		return dialer.DialContext(ctx, "unix", path)
+	})
+}
+
+// newHTTPClient returns a client with a dedicated transport, going through proxy returned by proxy. Client requests
+// fail with ErrInvalidTLSConfig if config TLS settings could not be loaded.
+func newHTTPClient(config ConnectorManagerClientConfig, opts clientOptions, proxy func(req *http.Request) (*url.URL, error)) (client *http.Client, transport *rootCAsTransport) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.Proxy = proxy
+	tlsConfig, caCerts, err := newClientTLSConfig(config)
+	if tlsConfig != nil && err == nil {
+		base.TLSClientConfig = tlsConfig
+	}
+	if opts.dial != nil {
+		base.DialContext = opts.dial
+	}
+	transport = newRootCAsTransport(base, caCerts)
+	client = &http.Client{Transport: transport}
+	if err != nil {
+		logger.Error("could not load connector manager tls config", slog.String("error", err.Error()))
+		client.Transport = failingTransport{err: err}
+	}
+	return
+}
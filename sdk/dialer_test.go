@@ -0,0 +1,70 @@
+package sdk
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewConnectorManagerClient_dialer(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "manager.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("could not listen on unix socket, error: %v", err)
+	}
+	var gotHost string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		opt        func(dialed *[]string) ClientOption
+		wantDialed []string
+	}{
+		{
+			name: "unix socket",
+			opt:  func(dialed *[]string) ClientOption { return WithUnixSocket(socket) },
+		},
+		{
+			name: "dialer",
+			opt: func(dialed *[]string) ClientOption {
+				return WithDialer(func(ctx context.Context, network string, addr string) (net.Conn, error) {
+					*dialed = append(*dialed, network+" "+addr)
+					return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+				})
+			},
+			wantDialed: []string{"tcp manager:80"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dialed []string
+			c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: "http://manager", APIKey: "key"}, tt.opt(&dialed))
+			if c.httpClient == http.DefaultClient {
+				t.Fatalf("NewConnectorManagerClient() uses default client")
+			}
+			if _, err := c.getTasks(t.Context()); err != nil {
+				t.Fatalf("getTasks() error = %v", err)
+			}
+			if gotHost != "manager" {
+				t.Errorf("getTasks() host = %s, want manager", gotHost)
+			}
+			if diff := cmp.Diff(dialed, tt.wantDialed); diff != "" {
+				t.Errorf("getTasks() dialed diff(got-want)=%s", diff)
+			}
+		})
+	}
+	if http.DefaultTransport.(*http.Transport).DialContext == nil {
+		t.Errorf("NewConnectorManagerClient() altered default transport")
+	}
+}
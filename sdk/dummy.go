@@ -10,7 +10,7 @@ type ReconfigurableDummyConfig struct {
 	DummyString string        `json:"dummy_string" validate:"required" `
 	Password    string        `json:"password,omitempty" password:"true"`
 	Enum        string        `json:"enum" validate:"required,oneof=quarantine delete log" desc:"Action to perform when a file is detected as malware."`
-	Objects     []DummyObject `json:"objects" desc:"Array of objects"`
+	Objects     []DummyObject `json:"objects" desc:"Array of objects" default:"[]"`
 }
 
 type DummyObject struct {
@@ -1,11 +1,16 @@
 package sdk
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"reflect"
 	"time"
 
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
 	"github.com/go-viper/mapstructure/v2"
 )
 
@@ -32,49 +37,128 @@ func (d Duration) MarshalJSON() ([]byte, error) {
 	return json.Marshal(time.Duration(d).String())
 }
 
+// ErrInvalidDuration is returned for durations that are neither a duration string (e.g. "1m30s") nor a whole number
+// of nanoseconds within time.Duration range. Negative durations are valid, fields reject them with validate tags.
+var ErrInvalidDuration = errors.New("invalid duration")
+
+// UnmarshalJSON parses a duration string or a number of nanoseconds, null leaving d unchanged.
 func (d *Duration) UnmarshalJSON(b []byte) (err error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
 	var v any
-	if err := json.Unmarshal(b, &v); err != nil {
-		return err
+	if err = dec.Decode(&v); err != nil {
+		return
 	}
+	if v == nil {
+		return
+	}
+	duration, err := parseDuration(v)
+	if err != nil {
+		return
+	}
+	*d = duration
+	return
+}
+
+// parseDuration parses a duration string or a number of nanoseconds.
+func parseDuration(v any) (d Duration, err error) {
 	switch value := v.(type) {
+	case int:
+		d = Duration(value)
 	case int64:
-		*d = Duration(time.Duration(value))
-		return
+		d = Duration(value)
+	case json.Number:
+		if n, intErr := value.Int64(); intErr == nil {
+			d = Duration(n)
+			return
+		}
+		f, floatErr := value.Float64()
+		if floatErr != nil {
+			err = fmt.Errorf("%w: %s", ErrInvalidDuration, value)
+			return
+		}
+		d, err = parseDuration(f)
 	case float64:
-		*d = Duration(time.Duration(value))
-		return
+		// float64(math.MaxInt64) rounds up to 2^63, out of range
+		if value != math.Trunc(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+			err = fmt.Errorf("%w: %v", ErrInvalidDuration, value)
+			return
+		}
+		d = Duration(value)
 	case string:
-		duration, err := time.ParseDuration(value)
-		if err != nil {
-			return err
+		duration, parseErr := time.ParseDuration(value)
+		if parseErr != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidDuration, parseErr)
+			return
 		}
-		*d = Duration(duration)
-		return nil
+		d = Duration(duration)
 	default:
-		err = errors.New("invalid duration")
+		err = fmt.Errorf("%w: %v", ErrInvalidDuration, v)
 	}
 	return
 }
 
+// DurationMapstructureHook decodes duration strings and numbers of nanoseconds into Duration and time.Duration fields.
 func DurationMapstructureHook() mapstructure.DecodeHookFuncType {
 	return func(_, targetType reflect.Type, a any) (any, error) {
-		if targetType.Kind() != reflect.Int64 {
+		if targetType != reflect.TypeFor[Duration]() && targetType != reflect.TypeFor[time.Duration]() {
 			return a, nil
 		}
-		switch value := a.(type) {
-		case int64:
-			return Duration(value), nil
-		case float64:
-			return Duration(value), nil
-		case string:
-			duration, err := time.ParseDuration(value)
-			if err != nil {
-				return nil, err
-			}
-			return Duration(duration), nil
+		switch a.(type) {
+		case int, int64, float64, string:
 		default:
 			return a, nil
 		}
+		d, err := parseDuration(a)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ValueOf(d).Convert(targetType).Interface(), nil
+	}
+}
+
+// Duration validator tags, bounding Duration and time.Duration fields, e.g. validate:"durmin=10s,durmax=24h".
+const (
+	DurationMinTag = "durmin"
+	DurationMaxTag = "durmax"
+)
+
+func registerDurationValidation(validate *validator.Validate, trans ut.Translator) (err error) {
+	bounds := []struct {
+		tag     string
+		message string
+		valid   func(d time.Duration, bound time.Duration) bool
+	}{
+		{tag: DurationMinTag, message: "{0} must be at least {1}", valid: func(d, bound time.Duration) bool { return d >= bound }},
+		{tag: DurationMaxTag, message: "{0} must be at most {1}", valid: func(d, bound time.Duration) bool { return d <= bound }},
+	}
+	for _, b := range bounds {
+		err = validate.RegisterValidation(b.tag, func(fl validator.FieldLevel) bool {
+			if fl.Field().Kind() != reflect.Int64 {
+				return false
+			}
+			bound, parseErr := time.ParseDuration(fl.Param())
+			if parseErr != nil {
+				// invalid tags are programming errors, as validator does for its own tags
+				panic(fmt.Sprintf("invalid %s bound %q on %s", b.tag, fl.Param(), fl.FieldName()))
+			}
+			return b.valid(time.Duration(fl.Field().Int()), bound)
+		})
+		if err != nil {
+			return
+		}
+		err = validate.RegisterTranslation(b.tag, trans, func(ut ut.Translator) error {
+			return ut.Add(b.tag, b.message, false)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, transErr := ut.T(b.tag, fe.Field(), fe.Param())
+			if transErr != nil {
+				return fe.(error).Error()
+			}
+			return t
+		})
+		if err != nil {
+			return
+		}
 	}
+	return
 }
@@ -0,0 +1,166 @@
+package sdk
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDuration_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    Duration
+		wantErr error
+	}{
+		{name: "string", raw: `"1m30s"`, want: Duration(90 * time.Second)},
+		{name: "negative string", raw: `"-5s"`, want: Duration(-5 * time.Second)},
+		{name: "nanoseconds", raw: `1500`, want: Duration(1500)},
+		{name: "max int64", raw: `9223372036854775807`, want: Duration(math.MaxInt64)},
+		{name: "exponent", raw: `1e9`, want: Duration(time.Second)},
+		{name: "null keeps value", raw: `null`, want: Duration(time.Hour)},
+		{name: "fraction of nanosecond", raw: `1.5`, wantErr: ErrInvalidDuration},
+		{name: "out of range", raw: `9223372036854775808`, wantErr: ErrInvalidDuration},
+		{name: "huge", raw: `1e300`, wantErr: ErrInvalidDuration},
+		{name: "overflowing string", raw: `"9999999999h"`, wantErr: ErrInvalidDuration},
+		{name: "no unit", raw: `"10"`, wantErr: ErrInvalidDuration},
+		{name: "bool", raw: `true`, wantErr: ErrInvalidDuration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Duration(time.Hour)
+			err := d.UnmarshalJSON([]byte(tt.raw))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Duration.UnmarshalJSON() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && d != tt.want {
+				t.Errorf("Duration.UnmarshalJSON() = %v, want %v", time.Duration(d), time.Duration(tt.want))
+			}
+		})
+	}
+}
+
+func FuzzDuration_UnmarshalJSON(f *testing.F) {
+	for _, seed := range []string{`"1m30s"`, `"-5s"`, `1500`, `-1`, `1e300`, `1.5`, `9223372036854775808`, `"2562047h47m16.854775807s"`, `null`, `{}`, `"`} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		d := Duration(0)
+		if err := d.UnmarshalJSON(raw); err != nil {
+			return
+		}
+		marshaled, err := d.MarshalJSON()
+		if err != nil {
+			t.Fatalf("Duration.MarshalJSON() error = %v", err)
+		}
+		got := Duration(0)
+		if err = got.UnmarshalJSON(marshaled); err != nil {
+			t.Fatalf("Duration.UnmarshalJSON(%s) error = %v, marshaled from %s", marshaled, err, raw)
+		}
+		if got != d {
+			t.Errorf("Duration round-trip of %s = %d, want %d", raw, got, d)
+		}
+	})
+}
+
+func TestDurationMapstructureHook(t *testing.T) {
+	durationType := reflect.TypeFor[Duration]()
+	tests := []struct {
+		name       string
+		targetType reflect.Type
+		value      any
+		want       any
+		wantErr    error
+	}{
+		{name: "string", targetType: durationType, value: "30s", want: Duration(30 * time.Second)},
+		{name: "time.Duration", targetType: reflect.TypeFor[time.Duration](), value: "1h", want: time.Hour},
+		{name: "int from yaml", targetType: durationType, value: 42, want: Duration(42)},
+		{name: "float", targetType: durationType, value: 1e9, want: Duration(time.Second)},
+		{name: "other int64 field", targetType: reflect.TypeFor[int64](), value: "10", want: "10"},
+		{name: "huge float", targetType: durationType, value: 1e300, wantErr: ErrInvalidDuration},
+		{name: "invalid string", targetType: durationType, value: "soon", wantErr: ErrInvalidDuration},
+	}
+	hook := DurationMapstructureHook()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := hook(reflect.TypeOf(tt.value), tt.targetType, tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DurationMapstructureHook() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("DurationMapstructureHook() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzDurationMapstructureHook(f *testing.F) {
+	f.Add("1h", int64(0), 0.0)
+	f.Add("-2562047h47m16.854775808s", int64(math.MinInt64), 1e19)
+	f.Add("1.5.5s", int64(-1), math.Inf(1))
+	hook := DurationMapstructureHook()
+	durationType := reflect.TypeFor[Duration]()
+	f.Fuzz(func(t *testing.T, s string, n int64, x float64) {
+		for _, value := range []any{s, n, x} {
+			got, err := hook(reflect.TypeOf(value), durationType, value)
+			if err != nil {
+				continue
+			}
+			if _, ok := got.(Duration); !ok {
+				t.Errorf("DurationMapstructureHook(%#v) = %#v, want a Duration", value, got)
+			}
+		}
+	})
+}
+
+func TestDurationBoundsValidation(t *testing.T) {
+	type config struct {
+		Period  Duration      `json:"period" validate:"omitempty,durmin=1m,durmax=24h"`
+		Timeout time.Duration `json:"timeout" validate:"durmin=0s"`
+	}
+	tests := []struct {
+		name   string
+		config config
+		want   []string
+	}{
+		{name: "ok", config: config{Period: Duration(time.Hour), Timeout: time.Second}},
+		{name: "unset period", config: config{}},
+		{name: "bounds", config: config{Period: Duration(48 * time.Hour), Timeout: -time.Second}, want: []string{"period must be at most 24h", "timeout must be at least 0s"}},
+		{name: "negative period", config: config{Period: Duration(-time.Minute)}, want: []string{"period must be at least 1m"}},
+	}
+	v, err := DefaultValidator()
+	if err != nil {
+		t.Fatalf("DefaultValidator() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			var fieldErrs validator.ValidationErrors
+			if err := v.Validate(tt.config); errors.As(err, &fieldErrs) {
+				for _, fe := range fieldErrs {
+					got = append(got, fe.Translate(v.Trans))
+				}
+			} else if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Validate() diff(got-want)=%s", diff)
+			}
+		})
+	}
+
+	fields, err := getConfigFields(config{})
+	if err != nil {
+		t.Fatalf("getConfigFields() error = %v", err)
+	}
+	for _, field := range fields {
+		if diff := cmp.Diff(field.Validation, []FrontValidation{FrontValidDuration}); diff != "" {
+			t.Errorf("getConfigFields() %s validation diff(got-want)=%s", field.Key, diff)
+		}
+	}
+}
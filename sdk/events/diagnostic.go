@@ -0,0 +1,28 @@
+package events
+
+import (
+	"context"
+	"time"
+)
+
+type EventDiagnosticHandler interface {
+	NotifyDiagnostic(ctx context.Context, name string, success bool, report any) (err error)
+}
+
+// DiagnosticEvent carries the structured result of a connector self check (e.g. granted permissions).
+type DiagnosticEvent struct {
+	Name    string `json:"name" validate:"required"`
+	Success bool   `json:"success"`
+	Report  any    `json:"report"`
+	Time    int64  `json:"time" validate:"required"`
+}
+
+func (h *Handler) NotifyDiagnostic(ctx context.Context, name string, success bool, report any) (err error) {
+	err = h.notifier.Notify(ctx, DiagnosticEvent{
+		Name:    name,
+		Success: success,
+		Report:  report,
+		Time:    time.Now().Unix(),
+	})
+	return
+}
@@ -0,0 +1,261 @@
+package events
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/glimps-re/connector-integration/sdk/validation"
+)
+
+// EncryptedField is a mitigation info field that can be encrypted before transmission.
+type EncryptedField string
+
+const (
+	EncryptSubject    EncryptedField = "subject"
+	EncryptSender     EncryptedField = "sender"
+	EncryptRecipients EncryptedField = "recipients"
+	EncryptFilename   EncryptedField = "filename"
+	EncryptOwner      EncryptedField = "owner"
+	EncryptURL        EncryptedField = "url"
+)
+
+func (EncryptedField) Values() []EncryptedField {
+	return []EncryptedField{EncryptSubject, EncryptSender, EncryptRecipients, EncryptFilename, EncryptOwner, EncryptURL}
+}
+
+// EncryptedFieldTag is the validator tag validating an EncryptedField.
+const EncryptedFieldTag = "encrypted_field"
+
+func (EncryptedField) Validation() validation.EnumValidation {
+	return validation.NewEnumValidation(EncryptedField("").Values())
+}
+
+// EncryptedPrefix starts every encrypted field value: "enc:v1:<key id>:<base64 nonce and AES-256-GCM ciphertext>".
+// Field name is authenticated with the value, so an encrypted value cannot be moved to another field.
+const EncryptedPrefix = "enc:v1:"
+
+var (
+	ErrInvalidEncryptionKey = errors.New("invalid field encryption key")
+	ErrUnknownEncryptionKey = errors.New("unknown field encryption key")
+	ErrNotEncrypted         = errors.New("value is not encrypted")
+)
+
+// FieldEncryption configures encryption of mitigation info fields with a key shared with the console.
+// Keys are rotated by reconfiguring a new key with a new ID: values carry the ID of the key encrypting them,
+// so the console can keep decrypting values encrypted with previous keys.
+type FieldEncryption struct {
+	Fields []EncryptedField `json:"fields" yaml:"fields" mapstructure:"fields" validate:"omitempty,unique,dive,encrypted_field" desc:"Mitigation info fields encrypted before being sent to console"`
+	KeyID  string           `json:"key_id" yaml:"key_id" mapstructure:"key_id" validate:"required_with=Fields,omitempty,excludes=:" desc:"ID of encryption key, required with fields, change it on each key rotation"`
+	Key    string           `json:"key" yaml:"key" mapstructure:"key" validate:"required_with=Fields,omitempty,base64" password:"true" desc:"Base64 encoded AES-256 key (32 bytes) shared with console, required with fields"`
+}
+
+// EncryptionKey is an AES-256 key identified by ID.
+type EncryptionKey struct {
+	ID  string
+	Key []byte
+}
+
+// ParseEncryptionKey decodes a base64 encoded AES-256 key.
+func ParseEncryptionKey(id string, encoded string) (key EncryptionKey, err error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		err = fmt.Errorf("%w, %w", ErrInvalidEncryptionKey, err)
+		return
+	}
+	key = EncryptionKey{ID: id, Key: raw}
+	return
+}
+
+// FieldCipher encrypts fields with its current key, and decrypts fields encrypted with any of its keys.
+type FieldCipher struct {
+	fields  []EncryptedField
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewFieldCipher returns a cipher encrypting fields with current key. previous keys are only used to decrypt.
+func NewFieldCipher(fields []EncryptedField, current EncryptionKey, previous ...EncryptionKey) (c *FieldCipher, err error) {
+	c = &FieldCipher{
+		fields:  slices.Clone(fields),
+		current: current.ID,
+		aeads:   make(map[string]cipher.AEAD, len(previous)+1),
+	}
+	for _, key := range append([]EncryptionKey{current}, previous...) {
+		if key.ID == "" || strings.Contains(key.ID, ":") {
+			err = fmt.Errorf("%w, key id must be set and must not contain ':'", ErrInvalidEncryptionKey)
+			return
+		}
+		if len(key.Key) != 32 {
+			err = fmt.Errorf("%w, key %s must be 32 bytes long, got %d", ErrInvalidEncryptionKey, key.ID, len(key.Key))
+			return
+		}
+		block, blockErr := aes.NewCipher(key.Key)
+		if blockErr != nil {
+			err = blockErr
+			return
+		}
+		// FIPS-approved: nonce generated by the module and prepended to ciphertext
+		if c.aeads[key.ID], err = cipher.NewGCMWithRandomNonce(block); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// NewCipher returns a FieldCipher encrypting configured fields, nil if no field is configured.
+func (e FieldEncryption) NewCipher() (c *FieldCipher, err error) {
+	if len(e.Fields) == 0 {
+		return
+	}
+	key, err := ParseEncryptionKey(e.KeyID, e.Key)
+	if err != nil {
+		return
+	}
+	c, err = NewFieldCipher(e.Fields, key)
+	return
+}
+
+// Encrypt encrypts value of field with current key. Empty values are kept empty.
+func (c *FieldCipher) Encrypt(field EncryptedField, value string) (encrypted string, err error) {
+	if value == "" {
+		return
+	}
+	sealed := c.aeads[c.current].Seal(nil, nil, []byte(value), []byte(field))
+	encrypted = EncryptedPrefix + c.current + ":" + base64.RawStdEncoding.EncodeToString(sealed)
+	return
+}
+
+// Decrypt decrypts a value of field encrypted by Encrypt, with the key it was encrypted with.
+func (c *FieldCipher) Decrypt(field EncryptedField, encrypted string) (value string, err error) {
+	if encrypted == "" {
+		return
+	}
+	rest, ok := strings.CutPrefix(encrypted, EncryptedPrefix)
+	if !ok {
+		err = ErrNotEncrypted
+		return
+	}
+	keyID, payload, ok := strings.Cut(rest, ":")
+	if !ok {
+		err = ErrNotEncrypted
+		return
+	}
+	aead, ok := c.aeads[keyID]
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrUnknownEncryptionKey, keyID)
+		return
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil {
+		return
+	}
+	plain, err := aead.Open(nil, nil, sealed, []byte(field))
+	if err != nil {
+		return
+	}
+	value = string(plain)
+	return
+}
+
+// EncryptEmail returns info with configured fields encrypted.
+func (c *FieldCipher) EncryptEmail(info EmailInfos) (encrypted EmailInfos, err error) {
+	err = c.transformEmail(&info, c.Encrypt)
+	encrypted = info
+	return
+}
+
+// DecryptEmail returns info with configured fields decrypted.
+func (c *FieldCipher) DecryptEmail(info EmailInfos) (decrypted EmailInfos, err error) {
+	err = c.transformEmail(&info, c.Decrypt)
+	decrypted = info
+	return
+}
+
+// EncryptFile returns info with configured fields encrypted.
+func (c *FieldCipher) EncryptFile(info FileInfos) (encrypted FileInfos, err error) {
+	err = c.transformFile(&info, c.Encrypt)
+	encrypted = info
+	return
+}
+
+// DecryptFile returns info with configured fields decrypted.
+func (c *FieldCipher) DecryptFile(info FileInfos) (decrypted FileInfos, err error) {
+	err = c.transformFile(&info, c.Decrypt)
+	decrypted = info
+	return
+}
+
+// EncryptURL returns info with configured fields encrypted.
+func (c *FieldCipher) EncryptURL(info URLInfos) (encrypted URLInfos, err error) {
+	err = c.transform(EncryptURL, &info.URL, c.Encrypt)
+	encrypted = info
+	return
+}
+
+// DecryptURL returns info with configured fields decrypted.
+func (c *FieldCipher) DecryptURL(info URLInfos) (decrypted URLInfos, err error) {
+	err = c.transform(EncryptURL, &info.URL, c.Decrypt)
+	decrypted = info
+	return
+}
+
+type fieldTransform func(field EncryptedField, value string) (string, error)
+
+func (c *FieldCipher) transform(field EncryptedField, value *string, transform fieldTransform) (err error) {
+	if !slices.Contains(c.fields, field) {
+		return
+	}
+	transformed, err := transform(field, *value)
+	if err != nil {
+		err = fmt.Errorf("field %s, %w", field, err)
+		return
+	}
+	*value = transformed
+	return
+}
+
+func (c *FieldCipher) transformEmail(info *EmailInfos, transform fieldTransform) (err error) {
+	if err = c.transform(EncryptSubject, &info.Subject, transform); err != nil {
+		return
+	}
+	if err = c.transform(EncryptSender, &info.Sender, transform); err != nil {
+		return
+	}
+	if len(info.Recipients) == 0 {
+		return
+	}
+	recipients := slices.Clone(info.Recipients)
+	for i := range recipients {
+		if err = c.transform(EncryptRecipients, &recipients[i], transform); err != nil {
+			return
+		}
+	}
+	info.Recipients = recipients
+	return
+}
+
+func (c *FieldCipher) transformFile(info *FileInfos, transform fieldTransform) (err error) {
+	if err = c.transform(EncryptFilename, &info.File, transform); err != nil {
+		return
+	}
+	if err = c.transform(EncryptOwner, &info.Owner, transform); err != nil {
+		return
+	}
+	if len(info.Members) == 0 {
+		return
+	}
+	members := make([]ArchiveMember, len(info.Members))
+	for i, member := range info.Members {
+		if err = c.transform(EncryptFilename, &member.Path, transform); err != nil {
+			return
+		}
+		members[i] = member
+	}
+	info.Members = members
+	return
+}
@@ -0,0 +1,175 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	testKey1 = EncryptionKey{ID: "key-1", Key: bytes.Repeat([]byte{1}, 32)}
+	testKey2 = EncryptionKey{ID: "key-2", Key: bytes.Repeat([]byte{2}, 32)}
+)
+
+func TestFieldCipher_Email(t *testing.T) {
+	c, err := NewFieldCipher([]EncryptedField{EncryptSubject, EncryptRecipients}, testKey1)
+	if err != nil {
+		t.Fatalf("NewFieldCipher() error = %v", err)
+	}
+	info := EmailInfos{
+		Subject:    "Salary review",
+		Sender:     "jane.doe@example.com",
+		Recipients: []string{"john.doe@example.com", ""},
+	}
+	encrypted, err := c.EncryptEmail(info)
+	if err != nil {
+		t.Fatalf("EncryptEmail() error = %v", err)
+	}
+	if !strings.HasPrefix(encrypted.Subject, EncryptedPrefix+"key-1:") || !strings.HasPrefix(encrypted.Recipients[0], EncryptedPrefix) {
+		t.Errorf("EncryptEmail() fields not encrypted: %+v", encrypted)
+	}
+	if encrypted.Sender != info.Sender || encrypted.Recipients[1] != "" {
+		t.Errorf("EncryptEmail() encrypted fields not configured or empty: %+v", encrypted)
+	}
+	if info.Recipients[0] != "john.doe@example.com" {
+		t.Errorf("EncryptEmail() modified given recipients")
+	}
+	decrypted, err := c.DecryptEmail(encrypted)
+	if err != nil {
+		t.Fatalf("DecryptEmail() error = %v", err)
+	}
+	if diff := cmp.Diff(decrypted, info); diff != "" {
+		t.Errorf("DecryptEmail() diff(got-want)=%s", diff)
+	}
+}
+
+func TestFieldCipher_Decrypt(t *testing.T) {
+	old, err := NewFieldCipher([]EncryptedField{EncryptFilename}, testKey1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := old.Encrypt(EncryptFilename, "/home/john/invoice.exe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := NewFieldCipher([]EncryptedField{EncryptFilename}, testKey2, testKey1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, err := NewFieldCipher([]EncryptedField{EncryptFilename}, testKey2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		cipher    *FieldCipher
+		field     EncryptedField
+		encrypted string
+		want      string
+		wantErr   error
+	}{
+		{
+			name:      "previous key after rotation",
+			cipher:    rotated,
+			field:     EncryptFilename,
+			encrypted: encrypted,
+			want:      "/home/john/invoice.exe",
+		},
+		{
+			name:      "unknown key",
+			cipher:    unknown,
+			field:     EncryptFilename,
+			encrypted: encrypted,
+			wantErr:   ErrUnknownEncryptionKey,
+		},
+		{
+			name:      "other field",
+			cipher:    old,
+			field:     EncryptOwner,
+			encrypted: encrypted,
+			wantErr:   errors.New("any"),
+		},
+		{
+			name:      "cleartext",
+			cipher:    old,
+			field:     EncryptFilename,
+			encrypted: "/home/john/invoice.exe",
+			wantErr:   ErrNotEncrypted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.Decrypt(tt.field, tt.encrypted)
+			switch {
+			case tt.wantErr == nil && err != nil, tt.wantErr != nil && err == nil:
+				t.Fatalf("Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			case tt.wantErr != nil && tt.wantErr.Error() != "any" && !errors.Is(err, tt.wantErr):
+				t.Fatalf("Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Decrypt() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFieldCipher_invalidKey(t *testing.T) {
+	for _, key := range []EncryptionKey{{ID: "short", Key: []byte("short")}, {ID: "a:b", Key: testKey1.Key}, {Key: testKey1.Key}} {
+		if _, err := NewFieldCipher(nil, key); !errors.Is(err, ErrInvalidEncryptionKey) {
+			t.Errorf("NewFieldCipher(%s) error = %v, want %v", key.ID, err, ErrInvalidEncryptionKey)
+		}
+	}
+}
+
+func TestHandler_SetFieldEncryption(t *testing.T) {
+	var got FileInfos
+	h := NewHandler(notifierMock{notifyMock: func(ctx context.Context, event any) (err error) {
+		got = event.(MitigationEvent).Info.(FileInfos)
+		return
+	}}, slog.LevelInfo, nil, &metrics.MetricsCollector{})
+	if err := h.SetPrivacy(Privacy{Filename: PIITruncate}); err != nil {
+		t.Fatal(err)
+	}
+	err := h.SetFieldEncryption(FieldEncryption{
+		Fields: []EncryptedField{EncryptFilename},
+		KeyID:  testKey1.ID,
+		Key:    base64.StdEncoding.EncodeToString(testKey1.Key),
+	})
+	if err != nil {
+		t.Fatalf("SetFieldEncryption() error = %v", err)
+	}
+	members := []ArchiveMember{{Path: "invoice.zip/john/invoice.exe", SHA256: "a"}}
+	if err = h.NotifyFileMitigation(t.Context(), ActionQuarantine, "id", ReasonMalware, FileInfos{File: "/home/john/invoice.exe", Members: members}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewFieldCipher(nil, testKey1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted, err := c.Decrypt(EncryptFilename, got.File); err != nil || decrypted != "…/invoice.exe" {
+		t.Errorf("NotifyFileMitigation() filename decrypted = %s (error %v), want minimized then encrypted", decrypted, err)
+	}
+	if decrypted, err := c.Decrypt(EncryptFilename, got.Members[0].Path); err != nil || decrypted != "…/invoice.exe" {
+		t.Errorf("NotifyFileMitigation() member path decrypted = %s (error %v), want minimized then encrypted", decrypted, err)
+	}
+	if members[0].Path != "invoice.zip/john/invoice.exe" {
+		t.Errorf("NotifyFileMitigation() modified given members")
+	}
+
+	if err = h.SetFieldEncryption(FieldEncryption{}); err != nil {
+		t.Fatalf("SetFieldEncryption() error = %v", err)
+	}
+	if err = h.NotifyFileMitigation(t.Context(), ActionQuarantine, "id", ReasonMalware, FileInfos{File: "/home/john/invoice.exe"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.File != "…/invoice.exe" {
+		t.Errorf("NotifyFileMitigation() filename = %s, want not encrypted", got.File)
+	}
+}
@@ -0,0 +1,185 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Event schema versions. Version 1 is the legacy envelope, without schema_version field.
+// Version 2 adds schema_version, idempotency_key, connector_id, diagnostic, summary, status and progress events.
+const (
+	SchemaVersionLegacy  = 1
+	SchemaVersionCurrent = 2
+)
+
+var (
+	ErrUnsupportedSchemaVersion = errors.New("unsupported event schema version")
+	ErrUnsupportedEvent         = errors.New("event type not supported by schema version")
+)
+
+// SupportedSchemaVersions lists event schema versions the SDK can encode and decode.
+func SupportedSchemaVersions() []int {
+	return []int{SchemaVersionLegacy, SchemaVersionCurrent}
+}
+
+// Envelope is the wire format of an event pushed to the manager.
+type Envelope struct {
+	SchemaVersion int             `json:"schema_version,omitempty" desc:"omitted for legacy version 1"`
+	EventType     EventType       `json:"type"`
+	Event         json.RawMessage `json:"event"`
+	// IdempotencyKey identifies event for manager to discard duplicates, see IdempotencyKey
+	IdempotencyKey string `json:"idempotency_key,omitempty" desc:"omitted for legacy version 1"`
+	// ConnectorID identifies connector across re-registrations (e.g. after api key rotation), see SetConnectorID
+	ConnectorID string `json:"connector_id,omitempty" desc:"omitted for legacy version 1 and until connector ID is known"`
+}
+
+// SetIdempotencyKey sets envelope idempotency key, for event created at timestamp by connector.
+// It is a no-op for legacy version 1, which has no idempotency key.
+func (e *Envelope) SetIdempotencyKey(connectorID string, timestamp time.Time) {
+	if e.SchemaVersion <= SchemaVersionLegacy {
+		return
+	}
+	e.IdempotencyKey = IdempotencyKey(connectorID, e.EventType, e.Event, timestamp)
+}
+
+// SetConnectorID sets ID of connector sending envelope. It is a no-op for legacy version 1.
+func (e *Envelope) SetConnectorID(connectorID string) {
+	if e.SchemaVersion <= SchemaVersionLegacy {
+		return
+	}
+	e.ConnectorID = connectorID
+}
+
+// NegotiateSchemaVersion returns the highest version supported by both sides,
+// legacy version if peer did not advertise any (older SDK).
+func NegotiateSchemaVersion(peerVersions []int) (version int) {
+	version = SchemaVersionLegacy
+	for _, v := range peerVersions {
+		if v > version && slices.Contains(SupportedSchemaVersions(), v) {
+			version = v
+		}
+	}
+	return
+}
+
+// event types introduced after legacy version
+var eventTypeMinVersion = map[EventType]int{
+	Diagnostic: SchemaVersionCurrent,
+	Summary:    SchemaVersionCurrent,
+	Status:     SchemaVersionCurrent,
+	Progress:   SchemaVersionCurrent,
+}
+
+func eventTypeOf(event any) (eventType EventType, err error) {
+	switch event.(type) {
+	case MitigationEvent:
+		eventType = Mitigation
+	case TaskEvent:
+		eventType = TaskAck
+	case LogEvent:
+		eventType = Log
+	case ErrorEvent:
+		eventType = Error
+	case ResolutionEvent:
+		eventType = Resolution
+	case DiagnosticEvent:
+		eventType = Diagnostic
+	case SummaryEvent:
+		eventType = Summary
+	case StatusEvent:
+		eventType = Status
+	case ProgressEvent:
+		eventType = Progress
+	default:
+		err = errors.New("invalid type")
+	}
+	return
+}
+
+func checkVersion(version int, eventType EventType) (err error) {
+	if !slices.Contains(SupportedSchemaVersions(), version) {
+		return fmt.Errorf("%w: %d", ErrUnsupportedSchemaVersion, version)
+	}
+	if minVersion, ok := eventTypeMinVersion[eventType]; ok && version < minVersion {
+		return fmt.Errorf("%w: %s requires version %d, got %d", ErrUnsupportedEvent, eventType, minVersion, version)
+	}
+	return
+}
+
+// NewEnvelope wraps event (which MUST be an `Event`) for given schema version.
+func NewEnvelope(version int, event any) (envelope Envelope, err error) {
+	eventType, err := eventTypeOf(event)
+	if err != nil {
+		return
+	}
+	if err = checkVersion(version, eventType); err != nil {
+		return
+	}
+	rawEvent, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	envelope = Envelope{
+		EventType: eventType,
+		Event:     rawEvent,
+	}
+	if version > SchemaVersionLegacy {
+		envelope.SchemaVersion = version
+	}
+	return
+}
+
+// DecodeEnvelope decodes a raw envelope of any supported version and its event
+// into the matching `Event` struct.
+func DecodeEnvelope(raw []byte) (envelope Envelope, event any, err error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&envelope); err != nil {
+		return
+	}
+	event, err = envelope.Decode()
+	return
+}
+
+// Decode decodes envelope event into the matching `Event` struct.
+func (e Envelope) Decode() (event any, err error) {
+	version := e.SchemaVersion
+	if version == 0 {
+		version = SchemaVersionLegacy
+	}
+	if err = checkVersion(version, e.EventType); err != nil {
+		return
+	}
+	switch e.EventType {
+	case Mitigation:
+		event, err = decodeEvent[MitigationEvent](e.Event)
+	case TaskAck:
+		event, err = decodeEvent[TaskEvent](e.Event)
+	case Log:
+		event, err = decodeEvent[LogEvent](e.Event)
+	case Error:
+		event, err = decodeEvent[ErrorEvent](e.Event)
+	case Resolution:
+		event, err = decodeEvent[ResolutionEvent](e.Event)
+	case Diagnostic:
+		event, err = decodeEvent[DiagnosticEvent](e.Event)
+	case Summary:
+		event, err = decodeEvent[SummaryEvent](e.Event)
+	case Status:
+		event, err = decodeEvent[StatusEvent](e.Event)
+	case Progress:
+		event, err = decodeEvent[ProgressEvent](e.Event)
+	default:
+		err = fmt.Errorf("unknown event type %q", e.EventType)
+	}
+	return
+}
+
+func decodeEvent[T any](raw json.RawMessage) (event T, err error) {
+	err = json.Unmarshal(raw, &event)
+	return
+}
@@ -0,0 +1,127 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestNegotiateSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		peerVersions []int
+		want         int
+	}{
+		{name: "legacy peer", peerVersions: nil, want: SchemaVersionLegacy},
+		{name: "same versions", peerVersions: []int{1, 2}, want: SchemaVersionCurrent},
+		{name: "peer knows newer versions", peerVersions: []int{1, 2, 3}, want: SchemaVersionCurrent},
+		{name: "peer only knows legacy", peerVersions: []int{1}, want: SchemaVersionLegacy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NegotiateSchemaVersion(tt.peerVersions); got != tt.want {
+				t.Errorf("NegotiateSchemaVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEnvelope(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		event   any
+		wantRaw string
+		wantErr error
+	}{
+		{
+			name:    "ok legacy omits schema version",
+			version: SchemaVersionLegacy,
+			event:   TaskEvent{TaskID: "id"},
+			wantRaw: `{"type":"task","event":{"task_id":"id","error":""}}`,
+		},
+		{
+			name:    "ok current",
+			version: SchemaVersionCurrent,
+			event:   TaskEvent{TaskID: "id"},
+			wantRaw: `{"schema_version":2,"type":"task","event":{"task_id":"id","error":""}}`,
+		},
+		{
+			name:    "ko diagnostic in legacy version",
+			version: SchemaVersionLegacy,
+			event:   DiagnosticEvent{Name: "test"},
+			wantErr: ErrUnsupportedEvent,
+		},
+		{
+			name:    "ko unknown version",
+			version: 42,
+			event:   TaskEvent{TaskID: "id"},
+			wantErr: ErrUnsupportedSchemaVersion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope, err := NewEnvelope(tt.version, tt.event)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewEnvelope() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			raw, err := json.Marshal(envelope)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(raw) != tt.wantRaw {
+				t.Errorf("NewEnvelope() = %s, want %s", raw, tt.wantRaw)
+			}
+		})
+	}
+}
+
+func TestDecodeEnvelope(t *testing.T) {
+	fixtures, err := Fixtures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range SupportedSchemaVersions() {
+		for _, f := range fixtures {
+			t.Run(f.Name, func(t *testing.T) {
+				envelope, err := NewEnvelope(version, f.Event)
+				if errors.Is(err, ErrUnsupportedEvent) {
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				raw, err := json.Marshal(envelope)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, event, err := DecodeEnvelope(raw)
+				if err != nil {
+					t.Fatalf("DecodeEnvelope() error = %v", err)
+				}
+				if mustEventType(t, event) != f.Type {
+					t.Fatalf("DecodeEnvelope() event type = %T, want %s", event, f.Type)
+				}
+				redone, err := json.Marshal(event)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !jsonEqual(t, redone, f.JSON) {
+					t.Errorf("DecodeEnvelope() = %s, want %s", redone, f.JSON)
+				}
+			})
+		}
+	}
+}
+
+func mustEventType(t *testing.T, event any) EventType {
+	t.Helper()
+	eventType, err := eventTypeOf(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return eventType
+}
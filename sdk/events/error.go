@@ -3,6 +3,7 @@ package events
 import (
 	"context"
 	"errors"
+	"maps"
 	"time"
 )
 
@@ -30,6 +31,16 @@ const (
 	GMalwareError ErrorEventType = "gmalware"
 	// MUST be used for gmalware configuration or reconfiguration error
 	GMalwareConfigError ErrorEventType = "gmalware-bad-config"
+	// MUST be used when a config could not be applied and previous config was restored
+	ConfigRollbackError ErrorEventType = "config-rollback"
+	// Used when a connector stopped sending heartbeats to its watchdog
+	StalledConnectorError ErrorEventType = "stalled-connector"
+	// Used when connector gives up after manager kept answering unauthorized
+	UnauthorizedConnectorError ErrorEventType = "unauthorized-connector"
+	// Used when connector started from cached config, manager being unavailable, resolved once console config is applied
+	OfflineStartError ErrorEventType = "offline-start"
+	// Used when connector daily submission budget is consumed and analyses are deferred, resolved once it is reset
+	QuotaBudgetError ErrorEventType = "quota-budget"
 )
 
 // returns an error if e is nil
@@ -77,6 +88,13 @@ func (h *Handler) NotifyResolution(ctx context.Context, msg string, errorTypes .
 	return
 }
 
+// UnresolvedErrors returns a copy of errors notified and not resolved yet.
+func (h *Handler) UnresolvedErrors() (unresolved map[ErrorEventType]string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return maps.Clone(h.errors)
+}
+
 // MUST be used under RLock
 func (h *Handler) hadErrors(errorTypes []ErrorEventType) bool {
 	for _, errType := range errorTypes {
@@ -0,0 +1,250 @@
+package events
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+)
+
+//go:embed testdata/fixtures/*.json
+var fixturesFS embed.FS
+
+const fixturesDir = "testdata/fixtures"
+
+// Fixture is a canonical event payload, shared with the manager and third-party
+// consumers to regression-test events deserialization.
+type Fixture struct {
+	Name  string          `json:"name"`
+	Type  EventType       `json:"type"`
+	Event any             `json:"-" desc:"go value of the event, marshals to JSON"`
+	JSON  json.RawMessage `json:"event"`
+}
+
+const fixtureTime int64 = 1738000000
+
+// fixtureEvents lists go values of fixtures, JSON files are generated from them (go test -update).
+var fixtureEvents = []struct {
+	name      string
+	eventType EventType
+	event     any
+}{
+	{
+		name:      "mitigation_file",
+		eventType: Mitigation,
+		event: MitigationEvent{
+			Action:    ActionQuarantine,
+			InfoType:  InfoTypeFile,
+			Time:      fixtureTime,
+			ElementID: "f1c1e4a2",
+			Reason:    ReasonMalware,
+			Info: FileInfos{
+				CommonDetails: CommonDetails{
+					Malwares:           []string{"Trojan.Generic"},
+					GmalwareURLs:       []string{"https://gmalware.example.com/expert/en/analysis/advanced/1234"},
+					QuarantineLocation: "/var/lib/gmhost/quarantine/f1c1e4a2.lock",
+					SHA256:             "131f95c51cc819465fa1797f6ccacf9d494aaaff46fa3eac73ae63ffbdfd8267",
+					AdditionalInfo:     "detected during initial scan",
+				},
+				File:     "/home/user/Downloads/invoice.exe",
+				Filetype: "exe",
+				Size:     73802,
+			},
+		},
+	},
+	{
+		name:      "mitigation_file_archive",
+		eventType: Mitigation,
+		event: MitigationEvent{
+			Action:    ActionQuarantine,
+			InfoType:  InfoTypeFile,
+			Time:      fixtureTime,
+			ElementID: "9b2d7e10",
+			Reason:    ReasonMalware,
+			Info: FileInfos{
+				CommonDetails: CommonDetails{
+					Malwares:           []string{"Trojan.Generic"},
+					GmalwareURLs:       []string{"https://gmalware.example.com/expert/en/analysis/advanced/5678"},
+					QuarantineLocation: "/var/lib/gmhost/quarantine/9b2d7e10.lock",
+					SHA256:             "4b6c1d5e8f0a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4",
+				},
+				File:     "/home/user/Downloads/invoices.zip",
+				Filetype: "zip",
+				Size:     120455,
+				Members: []ArchiveMember{{
+					Path:     "invoices.zip/2025/invoice.exe",
+					SHA256:   "131f95c51cc819465fa1797f6ccacf9d494aaaff46fa3eac73ae63ffbdfd8267",
+					Malwares: []string{"Trojan.Generic"},
+				}},
+			},
+		},
+	},
+	{
+		name:      "mitigation_email",
+		eventType: Mitigation,
+		event: MitigationEvent{
+			Action:    ActionBlock,
+			InfoType:  InfoTypeEmail,
+			Time:      fixtureTime,
+			ElementID: "AAMkAGI2TG93AAA=",
+			Reason:    ReasonPhishing,
+			Info: EmailInfos{
+				CommonDetails: CommonDetails{
+					Malwares:     []string{"Phishing.Generic"},
+					GmalwareURLs: []string{},
+					SHA256:       "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+				},
+				Subject:    "Your invoice is ready",
+				Sender:     "billing@example.net",
+				Recipients: []string{"john.doe@example.com", "jane.doe@example.com"},
+			},
+		},
+	},
+	{
+		name:      "mitigation_url",
+		eventType: Mitigation,
+		event: MitigationEvent{
+			Action:    ActionBlock,
+			InfoType:  InfoTypeURL,
+			Time:      fixtureTime,
+			ElementID: "c0ffee",
+			Reason:    ReasonError,
+			Info: URLInfos{
+				CommonDetails: CommonDetails{
+					Malwares:      []string{},
+					GmalwareURLs:  []string{},
+					AnalysisError: "analysis timeout",
+				},
+				Method:        "GET",
+				URL:           "http://downloads.example.com/setup.msi",
+				ContentLength: 1048576,
+				ContentType:   "application/x-msi",
+			},
+		},
+	},
+	{
+		name:      "task",
+		eventType: TaskAck,
+		event: TaskEvent{
+			TaskID:     "0a3f6e1c-6b2d-4d8e-9f51-2c1e5b7d9a40",
+			Error:      "error restoring element f1c1e4a2, error: not in quarantine",
+			ConfigHash: "4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945",
+		},
+	},
+	{
+		name:      "log",
+		eventType: Log,
+		event: LogEvent{
+			Level:   "warn",
+			Message: "could not process file",
+			Time:    fixtureTime,
+			Attributes: map[string]any{
+				"file": "/home/user/report.pdf",
+				"analysis": map[string]any{
+					"attempt": 3,
+					"error": map[string]any{
+						"code":    "timeout",
+						"message": "context deadline exceeded",
+					},
+				},
+			},
+		},
+	},
+	{
+		name:      "error",
+		eventType: Error,
+		event: ErrorEvent{
+			Error: "could not reach gmalware, dial tcp: i/o timeout",
+			Type:  GMalwareError,
+			Time:  fixtureTime,
+		},
+	},
+	{
+		name:      "resolution",
+		eventType: Resolution,
+		event: ResolutionEvent{
+			Types:      []ErrorEventType{GMalwareError, GMalwareConfigError},
+			Resolution: "gmalware is reachable again",
+			Time:       fixtureTime,
+		},
+	},
+	{
+		name:      "diagnostic",
+		eventType: Diagnostic,
+		event: DiagnosticEvent{
+			Name:    "graph-permissions",
+			Success: false,
+			Report: map[string]any{
+				"ok":      false,
+				"granted": []string{"Sites.Read.All"},
+			},
+			Time: fixtureTime,
+		},
+	},
+	{
+		name:      "summary",
+		eventType: Summary,
+		event: SummaryEvent{
+			PeriodStart:    fixtureTime - 86400,
+			PeriodEnd:      fixtureTime,
+			ItemsProcessed: 1250,
+			SizeProcessed:  734003200,
+			ItemsMitigated: 4,
+			ItemsError:     2,
+			DetectionsByReason: map[MitigationReason]int64{
+				ReasonMalware:  3,
+				ReasonPhishing: 1,
+			},
+			TopMalwares: []MalwareCount{
+				{Name: "Trojan.Generic", Count: 2},
+				{Name: "Phishing.Generic", Count: 1},
+				{Name: "Ransom.Lockbit", Count: 1},
+			},
+			Quota: QuotaUsage{DailyQuota: 1000, AvailableDailyQuota: 250},
+		},
+	},
+	{
+		name:      "status",
+		eventType: Status,
+		event: StatusEvent{
+			State: "degraded",
+			Subsystems: []SubsystemStatus{
+				{Name: "scanner", State: "ok", Since: fixtureTime - 3600},
+				{Name: "webhook", State: "down", Message: "listen tcp :8443: bind: address already in use", Since: fixtureTime},
+			},
+			Time: fixtureTime,
+		},
+	},
+	{
+		name:      "progress",
+		eventType: Progress,
+		event: ProgressEvent{
+			Name:      "initial-scan",
+			Processed: 1200,
+			Failed:    3,
+			Total:     5000,
+			StartedAt: fixtureTime - 600,
+			Time:      fixtureTime,
+		},
+	},
+}
+
+// Fixtures returns canonical payloads for every event type: mitigation for each
+// info type, task ack, log with nested groups, error, resolution, diagnostic, summary, status and progress.
+func Fixtures() (fixtures []Fixture, err error) {
+	fixtures = make([]Fixture, 0, len(fixtureEvents))
+	for _, f := range fixtureEvents {
+		raw, readErr := fixturesFS.ReadFile(path.Join(fixturesDir, f.name+".json"))
+		if readErr != nil {
+			err = fmt.Errorf("could not read fixture %s, %w", f.name, readErr)
+			return
+		}
+		fixtures = append(fixtures, Fixture{
+			Name:  f.name,
+			Type:  f.eventType,
+			Event: f.event,
+			JSON:  raw,
+		})
+	}
+	return
+}
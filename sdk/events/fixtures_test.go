@@ -0,0 +1,104 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestFixtures_Golden(t *testing.T) {
+	if *update {
+		for _, f := range fixtureEvents {
+			raw, err := json.MarshalIndent(f.event, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = os.WriteFile(filepath.Join(fixturesDir, f.name+".json"), append(raw, '\n'), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	fixtures, err := Fixtures()
+	if err != nil {
+		t.Fatalf("Fixtures() error = %v", err)
+	}
+	gotTypes := map[EventType]bool{}
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			gotTypes[f.Type] = true
+			got, err := json.Marshal(f.Event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := bytes.NewBuffer(nil)
+			if err = json.Compact(want, f.JSON); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want.Bytes()) {
+				t.Errorf("marshaled event differs from golden file (run go test -update if intended)\ngot:  %s\nwant: %s", got, want)
+			}
+
+			// decoding must be strict and symmetric
+			decoded := reflect.New(reflect.TypeOf(f.Event))
+			dec := json.NewDecoder(bytes.NewReader(f.JSON))
+			dec.DisallowUnknownFields()
+			if err = dec.Decode(decoded.Interface()); err != nil {
+				t.Fatalf("could not decode golden file, %v", err)
+			}
+			redone, err := json.Marshal(decoded.Elem().Interface())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !jsonEqual(t, redone, want.Bytes()) {
+				t.Errorf("decoded then marshaled event differs from golden file\ngot:  %s\nwant: %s", redone, want)
+			}
+			if ev, ok := f.Event.(MitigationEvent); ok {
+				checkMitigationInfo(t, ev, f.JSON)
+			}
+		})
+	}
+	for _, eventType := range EventType("").Values() {
+		if !gotTypes[eventType] {
+			t.Errorf("no fixture for event type %s", eventType)
+		}
+	}
+}
+
+func jsonEqual(t *testing.T, a []byte, b []byte) bool {
+	t.Helper()
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// info is decoded as a map in MitigationEvent, check it strictly matches its info type struct
+func checkMitigationInfo(t *testing.T, ev MitigationEvent, raw []byte) {
+	t.Helper()
+	wire := struct {
+		Info json.RawMessage `json:"info"`
+	}{}
+	if err := json.Unmarshal(raw, &wire); err != nil {
+		t.Fatal(err)
+	}
+	info := reflect.New(reflect.TypeOf(ev.Info))
+	dec := json.NewDecoder(bytes.NewReader(wire.Info))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(info.Interface()); err != nil {
+		t.Fatalf("could not decode %s mitigation info, %v", ev.InfoType, err)
+	}
+	if !reflect.DeepEqual(info.Elem().Interface(), ev.Info) {
+		t.Errorf("decoded %s info = %+v, want %+v", ev.InfoType, info.Elem().Interface(), ev.Info)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 
 	"github.com/glimps-re/connector-integration/sdk/metrics"
 	"github.com/glimps-re/connector-integration/sdk/validation"
@@ -13,12 +14,13 @@ type EventHandler interface {
 	EventLogHandler
 	EventErrorHandler
 	EventMitigationHandler
+	EventDiagnosticHandler
 }
 
 var _ EventHandler = &Handler{}
 
 type Event interface {
-	MitigationEvent | TaskEvent | LogEvent | ErrorEvent | ResolutionEvent
+	MitigationEvent | TaskEvent | LogEvent | ErrorEvent | ResolutionEvent | DiagnosticEvent | SummaryEvent | StatusEvent | ProgressEvent
 }
 
 type EventType string
@@ -29,10 +31,14 @@ const (
 	Log        EventType = "log"
 	Error      EventType = "error"
 	Resolution EventType = "resolution"
+	Diagnostic EventType = "diagnostic"
+	Summary    EventType = "summary"
+	Status     EventType = "status"
+	Progress   EventType = "progress"
 )
 
 func (EventType) Values() []EventType {
-	return []EventType{TaskAck, Mitigation, Log, Error, Resolution}
+	return []EventType{TaskAck, Mitigation, Log, Error, Resolution, Diagnostic, Summary, Status, Progress}
 }
 
 // EventTypeTag is the validator tag validating an EventType.
@@ -52,6 +58,8 @@ type Handler struct {
 	notifier         Notifier
 	errors           map[ErrorEventType]string
 	metricsCollector *metrics.MetricsCollector
+	privacy          atomic.Pointer[Privacy]
+	fieldCipher      atomic.Pointer[FieldCipher]
 	lock             sync.Mutex
 }
 
@@ -63,9 +71,50 @@ func NewHandler(notifier Notifier, logLeveler slog.Leveler, unresolvedError map[
 		logHandler: &LogHandler{
 			eventPusher: notifier,
 			leveler:     logLeveler,
+			buffer:      &atomic.Pointer[LogBuffer]{},
 		},
 		notifier:         notifier,
 		errors:           unresolvedError,
 		metricsCollector: metricsCollector,
 	}
 }
+
+// SetLogBuffer makes logs handled by h (and its sub loggers) be kept in buffer, nil to stop.
+func (h *Handler) SetLogBuffer(buffer *LogBuffer) {
+	if lh, ok := h.logHandler.(*LogHandler); ok {
+		lh.buffer.Store(buffer)
+	}
+}
+
+// LogBuffer returns buffer set with SetLogBuffer, nil if none.
+func (h *Handler) LogBuffer() (buffer *LogBuffer) {
+	if lh, ok := h.logHandler.(*LogHandler); ok {
+		buffer = lh.buffer.Load()
+	}
+	return
+}
+
+// SetPrivacy sets PII minimization applied to mitigation events before they are transmitted,
+// e.g. on each connector (re)configuration. Minimization is disabled when no field is minimized.
+func (h *Handler) SetPrivacy(privacy Privacy) (err error) {
+	if err = privacy.Check(); err != nil {
+		return
+	}
+	if !privacy.Enabled() {
+		h.privacy.Store(nil)
+		return
+	}
+	h.privacy.Store(&privacy)
+	return
+}
+
+// SetFieldEncryption sets encryption of mitigation info fields, applied after PII minimization
+// (see SetPrivacy), e.g. on each connector (re)configuration. Encryption is disabled when no field is configured.
+func (h *Handler) SetFieldEncryption(encryption FieldEncryption) (err error) {
+	fieldCipher, err := encryption.NewCipher()
+	if err != nil {
+		return
+	}
+	h.fieldCipher.Store(fieldCipher)
+	return
+}
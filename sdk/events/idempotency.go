@@ -0,0 +1,25 @@
+package events
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyNamespace is the UUID namespace of event idempotency keys.
+var IdempotencyNamespace = uuid.MustParse("8e8f1854-d26a-4844-9de7-02e4982c87ee")
+
+// IdempotencyKey returns the deterministic idempotency key of an event: a UUID v5 of connector ID,
+// event content hash and timestamp. Key is the same in FIPS mode, SHA-1 of UUID v5 only deriving an
+// identifier, not securing anything.
+// Manager discards events whose key was already recorded, so events sent again (client retries,
+// replayed spools) are not duplicated.
+func IdempotencyKey(connectorID string, eventType EventType, event []byte, timestamp time.Time) (key string) {
+	contentHash := sha256.Sum256(append([]byte(string(eventType)+"\n"), event...))
+	name := []byte(connectorID + ":" + hex.EncodeToString(contentHash[:]) + ":" + strconv.FormatInt(timestamp.UnixMilli(), 10))
+	key = uuid.NewSHA1(IdempotencyNamespace, name).String()
+	return
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	ts := time.Unix(1738000000, 0)
+	event := []byte(`{"task_id":"task-1","error":""}`)
+	key := IdempotencyKey("connector-1", TaskAck, event, ts)
+
+	tests := []struct {
+		name        string
+		connectorID string
+		eventType   EventType
+		event       []byte
+		timestamp   time.Time
+		wantSame    bool
+	}{
+		{name: "same event", connectorID: "connector-1", eventType: TaskAck, event: event, timestamp: ts, wantSame: true},
+		{name: "other connector", connectorID: "connector-2", eventType: TaskAck, event: event, timestamp: ts},
+		{name: "other content", connectorID: "connector-1", eventType: TaskAck, event: []byte(`{"task_id":"task-2","error":""}`), timestamp: ts},
+		{name: "other type", connectorID: "connector-1", eventType: Log, event: event, timestamp: ts},
+		{name: "other time", connectorID: "connector-1", eventType: TaskAck, event: event, timestamp: ts.Add(time.Millisecond)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IdempotencyKey(tt.connectorID, tt.eventType, tt.event, tt.timestamp)
+			if (got == key) != tt.wantSame {
+				t.Errorf("IdempotencyKey() = %s, key %s, want same %v", got, key, tt.wantSame)
+			}
+		})
+	}
+
+	parsed, err := uuid.Parse(key)
+	if err != nil {
+		t.Fatalf("IdempotencyKey() = %s, invalid uuid: %v", key, err)
+	}
+	if parsed.Version() != 5 {
+		t.Errorf("IdempotencyKey() version = %d, want 5", parsed.Version())
+	}
+}
+
+func TestEnvelope_SetIdempotencyKey(t *testing.T) {
+	ts := time.Unix(1738000000, 0)
+	for _, version := range SupportedSchemaVersions() {
+		envelope, err := NewEnvelope(version, TaskEvent{TaskID: "task-1"})
+		if err != nil {
+			t.Fatalf("NewEnvelope() error = %v", err)
+		}
+		envelope.SetIdempotencyKey("connector-1", ts)
+		if got, want := envelope.IdempotencyKey != "", version > SchemaVersionLegacy; got != want {
+			t.Errorf("version %d: SetIdempotencyKey() set key = %v (%q), want %v", version, got, envelope.IdempotencyKey, want)
+		}
+	}
+}
+
+func TestEnvelope_SetConnectorID(t *testing.T) {
+	for _, version := range SupportedSchemaVersions() {
+		envelope, err := NewEnvelope(version, TaskEvent{TaskID: "task-1"})
+		if err != nil {
+			t.Fatalf("NewEnvelope() error = %v", err)
+		}
+		envelope.SetConnectorID("connector-1")
+		if got, want := envelope.ConnectorID != "", version > SchemaVersionLegacy; got != want {
+			t.Errorf("version %d: SetConnectorID() set id = %v (%q), want %v", version, got, envelope.ConnectorID, want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package events
+
+import (
+	"sync"
+)
+
+const DefaultLogBufferSize = 1000
+
+// BufferedLog is a LogEvent kept in a LogBuffer, Seq increasing with each log.
+type BufferedLog struct {
+	Seq uint64 `json:"seq"`
+	LogEvent
+}
+
+// LogBuffer keeps last logs handled by a Handler (see Handler.SetLogBuffer), available even when console is unreachable,
+// e.g. to tail logs from a local admin api. It is thread-safe.
+type LogBuffer struct {
+	lock sync.Mutex
+	logs []BufferedLog // ring, logs[next] is the oldest once full
+	next int
+	seq  uint64
+}
+
+// NewLogBuffer returns a LogBuffer keeping size logs, DefaultLogBufferSize if size <= 0.
+func NewLogBuffer(size int) (b *LogBuffer) {
+	if size <= 0 {
+		size = DefaultLogBufferSize
+	}
+	return &LogBuffer{logs: make([]BufferedLog, 0, size)}
+}
+
+func (b *LogBuffer) Add(log LogEvent) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.seq++
+	buffered := BufferedLog{Seq: b.seq, LogEvent: log}
+	if len(b.logs) < cap(b.logs) {
+		b.logs = append(b.logs, buffered)
+		return
+	}
+	b.logs[b.next] = buffered
+	b.next = (b.next + 1) % len(b.logs)
+}
+
+// Since returns, oldest first, at most limit (all if <= 0) of the last logs with a Seq greater than after.
+func (b *LogBuffer) Since(after uint64, limit int) (logs []BufferedLog) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	logs = make([]BufferedLog, 0)
+	for i := range b.logs {
+		log := b.logs[(b.next+i)%len(b.logs)]
+		if log.Seq > after {
+			logs = append(logs, log)
+		}
+	}
+	if limit > 0 && len(logs) > limit {
+		logs = logs[len(logs)-limit:]
+	}
+	return
+}
@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestLogBuffer_Since(t *testing.T) {
+	b := NewLogBuffer(3)
+	for _, msg := range []string{"1", "2", "3", "4"} {
+		b.Add(LogEvent{Level: "info", Message: msg})
+	}
+	tests := []struct {
+		name  string
+		after uint64
+		limit int
+		want  []string
+	}{
+		{name: "all", want: []string{"2", "3", "4"}},
+		{name: "after", after: 2, want: []string{"3", "4"}},
+		{name: "limit", limit: 1, want: []string{"4"}},
+		{name: "none", after: 4, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, log := range b.Since(tt.after, tt.limit) {
+				got = append(got, log.Message)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Since() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandler_SetLogBuffer(t *testing.T) {
+	notifier := notifierMock{notifyMock: func(ctx context.Context, event any) (err error) { return }}
+	h := NewHandler(notifier, slog.LevelInfo, nil, &metrics.MetricsCollector{})
+	logger := slog.New(h.GetLogHandler()).With(slog.String("component", "scan"))
+	logger.Info("before buffer")
+	b := NewLogBuffer(10)
+	h.SetLogBuffer(b)
+	if h.LogBuffer() != b {
+		t.Fatalf("LogBuffer() = %p, want %p", h.LogBuffer(), b)
+	}
+	logger.Info("buffered")
+	logger.Debug("filtered")
+
+	got := b.Since(0, 0)
+	want := []BufferedLog{{Seq: 1, LogEvent: LogEvent{Level: "info", Message: "buffered", Attributes: map[string]any{"component": "scan"}}}}
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(LogEvent{}, "Time")); diff != "" {
+		t.Errorf("SetLogBuffer() logs diff(got-want)=%s", diff)
+	}
+}
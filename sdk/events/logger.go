@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,6 +30,7 @@ type LogHandler struct {
 	leveler     slog.Leveler
 	attributes  []attrWithGroups
 	groups      []string
+	buffer      *atomic.Pointer[LogBuffer] // shared with sub handlers
 }
 
 func (h *Handler) GetLogHandler() slog.Handler {
@@ -50,6 +52,11 @@ func (lh LogHandler) Handle(ctx context.Context, record slog.Record) (err error)
 		Level:      strings.ToLower(record.Level.String()),
 		Attributes: lh.getAttributes(record),
 	}
+	if lh.buffer != nil {
+		if buffer := lh.buffer.Load(); buffer != nil {
+			buffer.Add(log)
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*15)
 	defer cancel()
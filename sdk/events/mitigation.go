@@ -112,6 +112,16 @@ type FileInfos struct {
 	File     string `json:"filename"` // filePath + fileName
 	Filetype string `json:"filetype"`
 	Size     int64  `json:"size"`
+	Owner    string `json:"owner,omitempty" desc:"optional, file owner (e.g. user name)"`
+	// Members are the malicious members of archive File, the entries which made it malicious
+	Members []ArchiveMember `json:"archive_members,omitempty" desc:"optional, malicious members of archive file"`
+}
+
+// ArchiveMember is a member of an archive reported in FileInfos.
+type ArchiveMember struct {
+	Path     string   `json:"path" desc:"path of member within archive"`
+	SHA256   string   `json:"sha256"`
+	Malwares []string `json:"malwares"`
 }
 
 type EmailInfos struct {
@@ -131,6 +141,15 @@ type URLInfos struct {
 
 func (h *Handler) NotifyFileMitigation(ctx context.Context, action MitigationAction, elementID string, reason MitigationReason, info FileInfos) (err error) {
 	h.metricsCollector.AddMitigatedItem() // independently of notification success
+	h.metricsCollector.AddDetection(string(reason), info.Malwares)
+	if privacy := h.privacy.Load(); privacy != nil {
+		info = privacy.MinimizeFile(info)
+	}
+	if fieldCipher := h.fieldCipher.Load(); fieldCipher != nil {
+		if info, err = fieldCipher.EncryptFile(info); err != nil {
+			return
+		}
+	}
 	err = h.notifier.Notify(ctx, MitigationEvent{
 		Action:   action,
 		InfoType: InfoTypeFile,
@@ -148,6 +167,15 @@ func (h *Handler) NotifyFileMitigation(ctx context.Context, action MitigationAct
 
 func (h *Handler) NotifyEmailMitigation(ctx context.Context, action MitigationAction, elementID string, reason MitigationReason, info EmailInfos) (err error) {
 	h.metricsCollector.AddMitigatedItem()
+	h.metricsCollector.AddDetection(string(reason), info.Malwares)
+	if privacy := h.privacy.Load(); privacy != nil {
+		info = privacy.MinimizeEmail(info)
+	}
+	if fieldCipher := h.fieldCipher.Load(); fieldCipher != nil {
+		if info, err = fieldCipher.EncryptEmail(info); err != nil {
+			return
+		}
+	}
 	err = h.notifier.Notify(ctx, MitigationEvent{
 		Action:   action,
 		InfoType: InfoTypeEmail,
@@ -165,6 +193,15 @@ func (h *Handler) NotifyEmailMitigation(ctx context.Context, action MitigationAc
 
 func (h *Handler) NotifyURLMitigation(ctx context.Context, action MitigationAction, elementID string, reason MitigationReason, info URLInfos) (err error) {
 	h.metricsCollector.AddMitigatedItem()
+	h.metricsCollector.AddDetection(string(reason), info.Malwares)
+	if privacy := h.privacy.Load(); privacy != nil {
+		info = privacy.MinimizeURL(info)
+	}
+	if fieldCipher := h.fieldCipher.Load(); fieldCipher != nil {
+		if info, err = fieldCipher.EncryptURL(info); err != nil {
+			return
+		}
+	}
 	err = h.notifier.Notify(ctx, MitigationEvent{
 		Action:   action,
 		InfoType: InfoTypeURL,
@@ -0,0 +1,131 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// NDJSON on-disk format: one `Envelope` per line (current schema version),
+// lines separated by '\n'. Empty lines are ignored when reading.
+// A line may also record event creation time (`created` field, see WriteNDJSONAt).
+// It is used by disk spools, diagnostics bundles and replay tooling.
+
+// ndjsonLine is a line of NDJSON format.
+type ndjsonLine struct {
+	Envelope
+	Created int64 `json:"created,omitempty" desc:"unix nano"`
+}
+
+// WriteNDJSON writes events (which MUST be `Event`) to w, one envelope per line.
+func WriteNDJSON(w io.Writer, events ...any) (err error) {
+	err = writeNDJSON(w, 0, events)
+	return
+}
+
+// WriteNDJSONAt writes events like WriteNDJSON, recording they were created at created, for replays to keep the
+// idempotency key of their first notification (see NDJSONDecoder.Created and WithEventTime).
+func WriteNDJSONAt(w io.Writer, created time.Time, events ...any) (err error) {
+	err = writeNDJSON(w, created.UnixNano(), events)
+	return
+}
+
+func writeNDJSON(w io.Writer, created int64, events []any) (err error) {
+	enc := json.NewEncoder(w)
+	for i, event := range events {
+		envelope, envErr := NewEnvelope(SchemaVersionCurrent, event)
+		if envErr != nil {
+			err = fmt.Errorf("could not encode event %d, %w", i, envErr)
+			return
+		}
+		// Encode appends '\n' and never emits raw newlines inside JSON values
+		if err = enc.Encode(ndjsonLine{Envelope: envelope, Created: created}); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// NDJSONDecoder reads events written by WriteNDJSON one at a time.
+type NDJSONDecoder struct {
+	r       *bufio.Reader
+	line    int
+	created int64
+}
+
+func NewNDJSONDecoder(r io.Reader) *NDJSONDecoder {
+	return &NDJSONDecoder{r: bufio.NewReader(r)}
+}
+
+// Next returns next event, decoded into its `Event` struct, and io.EOF once all events are read.
+func (d *NDJSONDecoder) Next() (event any, err error) {
+	for {
+		raw, readErr := d.r.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			err = readErr
+			return
+		}
+		d.line++
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			if readErr != nil {
+				err = io.EOF
+				return
+			}
+			continue
+		}
+		d.created = 0
+		event, err = d.decode(raw)
+		if err != nil {
+			err = fmt.Errorf("invalid event at line %d, %w", d.line, err)
+		}
+		return
+	}
+}
+
+func (d *NDJSONDecoder) decode(raw []byte) (event any, err error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	line := ndjsonLine{}
+	if err = dec.Decode(&line); err != nil {
+		return
+	}
+	if event, err = line.Decode(); err != nil {
+		return
+	}
+	d.created = line.Created
+	return
+}
+
+// Created returns creation time of the last event read, ok is false if its line does not record it.
+func (d *NDJSONDecoder) Created() (created time.Time, ok bool) {
+	if d.created != 0 {
+		created, ok = time.Unix(0, d.created), true
+	}
+	return
+}
+
+// Line returns the number of the last line read.
+func (d *NDJSONDecoder) Line() int {
+	return d.line
+}
+
+// ReadNDJSON reads every event from r.
+func ReadNDJSON(r io.Reader) (events []any, err error) {
+	dec := NewNDJSONDecoder(r)
+	for {
+		event, nextErr := dec.Next()
+		switch {
+		case errors.Is(nextErr, io.EOF):
+			return
+		case nextErr != nil:
+			err = nextErr
+			return
+		}
+		events = append(events, event)
+	}
+}
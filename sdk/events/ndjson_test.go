@@ -0,0 +1,99 @@
+package events
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNDJSON_RoundTrip(t *testing.T) {
+	written := []any{
+		TaskEvent{TaskID: "1"},
+		ErrorEvent{Error: "multi\nline error", Type: GMalwareError, Time: fixtureTime},
+		ResolutionEvent{Types: []ErrorEventType{GMalwareError}, Resolution: "ok", Time: fixtureTime},
+		LogEvent{Level: "info", Message: "test", Time: fixtureTime},
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := WriteNDJSON(buffer, written...); err != nil {
+		t.Fatalf("WriteNDJSON() error = %v", err)
+	}
+	if lines := strings.Count(buffer.String(), "\n"); lines != len(written) {
+		t.Errorf("WriteNDJSON() wrote %d lines, want %d", lines, len(written))
+	}
+	read, err := ReadNDJSON(buffer)
+	if err != nil {
+		t.Fatalf("ReadNDJSON() error = %v", err)
+	}
+	if diff := cmp.Diff(read, written); diff != "" {
+		t.Errorf("ReadNDJSON() diff(got-want)=%s", diff)
+	}
+}
+
+func TestReadNDJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantEvents []any
+		wantErr    bool
+	}{
+		{
+			name:  "ok legacy envelope, empty lines and no trailing newline",
+			input: "{\"type\":\"task\",\"event\":{\"task_id\":\"1\",\"error\":\"\"}}\n\n{\"schema_version\":2,\"type\":\"task\",\"event\":{\"task_id\":\"2\",\"error\":\"\"}}",
+			wantEvents: []any{
+				TaskEvent{TaskID: "1"},
+				TaskEvent{TaskID: "2"},
+			},
+		},
+		{
+			name:    "ko invalid line",
+			input:   "{\"type\":\"task\",\"event\":{\"task_id\":\"1\",\"error\":\"\"}}\nnot json\n",
+			wantErr: true,
+		},
+		{
+			name:    "ko unknown event type",
+			input:   "{\"type\":\"toto\",\"event\":{}}\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotEvents, err := ReadNDJSON(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadNDJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(gotEvents, tt.wantEvents); diff != "" {
+				t.Errorf("ReadNDJSON() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestNDJSONDecoder_Created(t *testing.T) {
+	created := time.Date(2026, 10, 1, 10, 0, 0, 5, time.UTC)
+	buffer := bytes.NewBuffer(nil)
+	if err := WriteNDJSONAt(buffer, created, TaskEvent{TaskID: "1"}); err != nil {
+		t.Fatalf("WriteNDJSONAt() error = %v", err)
+	}
+	if err := WriteNDJSON(buffer, TaskEvent{TaskID: "2"}); err != nil {
+		t.Fatalf("WriteNDJSON() error = %v", err)
+	}
+	dec := NewNDJSONDecoder(buffer)
+	for _, want := range []struct {
+		created time.Time
+		ok      bool
+	}{{created: created, ok: true}, {}} {
+		if _, err := dec.Next(); err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		got, ok := dec.Created()
+		if !got.Equal(want.created) || ok != want.ok {
+			t.Errorf("Created() = %s, %v, want %s, %v", got, ok, want.created, want.ok)
+		}
+	}
+}
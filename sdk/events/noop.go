@@ -29,6 +29,10 @@ func (h NoopEventHandler) NotifyURLMitigation(ctx context.Context, action Mitiga
 	return
 }
 
+func (h NoopEventHandler) NotifyDiagnostic(ctx context.Context, name string, success bool, report any) (err error) {
+	return
+}
+
 func (h NoopEventHandler) GetLogHandler() slog.Handler {
 	return slog.DiscardHandler
 }
@@ -0,0 +1,199 @@
+package events
+
+import (
+	"crypto/fips140"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/glimps-re/connector-integration/sdk/validation"
+)
+
+// PIIPolicy defines how a personal data field is minimized before events are transmitted.
+type PIIPolicy string
+
+const (
+	// field is sent as is (default)
+	PIIKeep PIIPolicy = "keep"
+	// field is replaced by its keyed hash (HMAC-SHA256), so equal values can still be correlated
+	PIIHash PIIPolicy = "hash"
+	// field is shortened: email addresses keep their domain, subjects their first characters,
+	// file paths their base name, URLs their scheme and host
+	PIITruncate PIIPolicy = "truncate"
+)
+
+func (PIIPolicy) Values() []PIIPolicy {
+	return []PIIPolicy{PIIKeep, PIIHash, PIITruncate}
+}
+
+// PIIPolicyTag is the validator tag validating a PIIPolicy.
+const PIIPolicyTag = "pii_policy"
+
+func (PIIPolicy) Validation() validation.EnumValidation {
+	return validation.NewEnumValidation(PIIPolicy("").Values())
+}
+
+const (
+	DefaultPrivacyTruncateLength = 16
+	// MinFIPSHashKeyLength is the minimum hash key length (112 bits) in FIPS 140-3 mode
+	MinFIPSHashKeyLength = 14
+	truncateMark         = "…"
+)
+
+var (
+	ErrShortHashKey   = fmt.Errorf("privacy hash key must be at least %d bytes long in FIPS mode", MinFIPSHashKeyLength)
+	ErrMissingHashKey = errors.New("privacy hash key is required to hash fields")
+)
+
+// Privacy configures PII minimization of mitigation events, per field. Empty policies keep fields.
+type Privacy struct {
+	Subject        PIIPolicy `json:"subject" yaml:"subject" mapstructure:"subject" validate:"omitempty,pii_policy" desc:"Policy applied to email subjects"`
+	Sender         PIIPolicy `json:"sender" yaml:"sender" mapstructure:"sender" validate:"omitempty,pii_policy" desc:"Policy applied to email senders"`
+	Recipients     PIIPolicy `json:"recipients" yaml:"recipients" mapstructure:"recipients" validate:"omitempty,pii_policy" desc:"Policy applied to email recipients"`
+	Filename       PIIPolicy `json:"filename" yaml:"filename" mapstructure:"filename" validate:"omitempty,pii_policy" desc:"Policy applied to file paths"`
+	Owner          PIIPolicy `json:"owner" yaml:"owner" mapstructure:"owner" validate:"omitempty,pii_policy" desc:"Policy applied to file owners"`
+	URL            PIIPolicy `json:"url" yaml:"url" mapstructure:"url" validate:"omitempty,pii_policy" desc:"Policy applied to URLs"`
+	HashKey        string    `json:"hash_key" yaml:"hash_key" mapstructure:"hash_key" password:"true" desc:"Key of hashed fields, to prevent guessing hashed values. Changing it breaks correlation with previously hashed values"`
+	TruncateLength int       `json:"truncate_length" yaml:"truncate_length" mapstructure:"truncate_length" validate:"omitempty,min=1" desc:"Number of characters kept in truncated subjects (default 16)"`
+}
+
+func (p Privacy) policies() []PIIPolicy {
+	return []PIIPolicy{p.Subject, p.Sender, p.Recipients, p.Filename, p.Owner, p.URL}
+}
+
+// Enabled reports whether any field is minimized.
+func (p Privacy) Enabled() bool {
+	for _, policy := range p.policies() {
+		if policy != "" && policy != PIIKeep {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns ErrMissingHashKey if a field is hashed without key, and ErrShortHashKey if it is hashed, in FIPS
+// mode, with a key shorter than MinFIPSHashKeyLength.
+func (p Privacy) Check() (err error) {
+	if !slices.Contains(p.policies(), PIIHash) {
+		return
+	}
+	switch {
+	case p.HashKey == "":
+		err = ErrMissingHashKey
+	case fips140.Enabled() && len(p.HashKey) < MinFIPSHashKeyLength:
+		err = ErrShortHashKey
+	}
+	return
+}
+
+// MinimizeEmail returns info with PII fields minimized according to policies.
+func (p Privacy) MinimizeEmail(info EmailInfos) EmailInfos {
+	info.Subject = p.apply(p.Subject, info.Subject, p.truncateText)
+	info.Sender = p.apply(p.Sender, info.Sender, truncateEmail)
+	if len(info.Recipients) > 0 {
+		recipients := make([]string, len(info.Recipients))
+		for i, recipient := range info.Recipients {
+			recipients[i] = p.apply(p.Recipients, recipient, truncateEmail)
+		}
+		info.Recipients = recipients
+	}
+	return info
+}
+
+// MinimizeFile returns info with PII fields minimized according to policies.
+func (p Privacy) MinimizeFile(info FileInfos) FileInfos {
+	info.File = p.apply(p.Filename, info.File, truncatePath)
+	info.Owner = p.apply(p.Owner, info.Owner, truncateOwner)
+	if len(info.Members) > 0 {
+		members := make([]ArchiveMember, len(info.Members))
+		for i, member := range info.Members {
+			member.Path = p.apply(p.Filename, member.Path, truncatePath)
+			members[i] = member
+		}
+		info.Members = members
+	}
+	return info
+}
+
+// MinimizeURL returns info with PII fields minimized according to policies.
+func (p Privacy) MinimizeURL(info URLInfos) URLInfos {
+	info.URL = p.apply(p.URL, info.URL, truncateURL)
+	return info
+}
+
+func (p Privacy) apply(policy PIIPolicy, value string, truncate func(string) string) string {
+	if value == "" {
+		return value
+	}
+	switch policy {
+	case PIIHash:
+		if fips140.Enabled() && len(p.HashKey) < MinFIPSHashKeyLength {
+			// not approved (and panics in fips140=only mode), Check rejects such privacy
+			return ""
+		}
+		mac := hmac.New(sha256.New, []byte(p.HashKey))
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil))
+	case PIITruncate:
+		return truncate(value)
+	default:
+		return value
+	}
+}
+
+func (p Privacy) truncateText(value string) string {
+	length := p.TruncateLength
+	if length <= 0 {
+		length = DefaultPrivacyTruncateLength
+	}
+	runes := []rune(value)
+	if len(runes) <= length {
+		return value
+	}
+	return string(runes[:length]) + truncateMark
+}
+
+// "john.doe@example.com" => "…@example.com"
+func truncateEmail(value string) string {
+	at := strings.LastIndexByte(value, '@')
+	if at < 0 {
+		return truncateMark
+	}
+	return truncateMark + value[at:]
+}
+
+// "/home/john/invoice.exe" or `C:\Users\john\invoice.exe` => "…/invoice.exe"
+func truncatePath(value string) string {
+	base := path.Base(strings.ReplaceAll(value, `\`, "/"))
+	if base == value {
+		return value
+	}
+	return truncateMark + "/" + base
+}
+
+// "https://example.com/reset?user=john" => "https://example.com/…"
+func truncateURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return truncateMark
+	}
+	truncated := u.Scheme + "://" + u.Host
+	if u.Path != "" && u.Path != "/" || u.RawQuery != "" || u.Fragment != "" {
+		truncated += "/" + truncateMark
+	}
+	return truncated
+}
+
+// `CORP\john.doe` or "john.doe@example.com" => `CORP\…` or "…@example.com"
+func truncateOwner(value string) string {
+	if domain, _, ok := strings.Cut(value, `\`); ok {
+		return domain + `\` + truncateMark
+	}
+	return truncateEmail(value)
+}
@@ -0,0 +1,214 @@
+package events
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/google/go-cmp/cmp"
+)
+
+const testHashKey = "privacy-hash-key"
+
+func testHMAC(key string, value string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestPrivacy_MinimizeEmail(t *testing.T) {
+	info := EmailInfos{
+		CommonDetails: CommonDetails{SHA256: "9f86d081"},
+		Subject:       "Salary review of John Doe, confidential",
+		Sender:        "jane.doe@example.com",
+		Recipients:    []string{"john.doe@example.com", "hr@example.org"},
+	}
+	tests := []struct {
+		name    string
+		privacy Privacy
+		want    EmailInfos
+	}{
+		{
+			name: "keep",
+			want: info,
+		},
+		{
+			name:    "truncate",
+			privacy: Privacy{Subject: PIITruncate, Sender: PIITruncate, Recipients: PIITruncate, TruncateLength: 13},
+			want: EmailInfos{
+				CommonDetails: CommonDetails{SHA256: "9f86d081"},
+				Subject:       "Salary review…",
+				Sender:        "…@example.com",
+				Recipients:    []string{"…@example.com", "…@example.org"},
+			},
+		},
+		{
+			name:    "hash",
+			privacy: Privacy{Sender: PIIHash, Recipients: PIIHash, HashKey: testHashKey},
+			want: EmailInfos{
+				CommonDetails: CommonDetails{SHA256: "9f86d081"},
+				Subject:       info.Subject,
+				Sender:        testHMAC(testHashKey, "jane.doe@example.com"),
+				Recipients:    []string{testHMAC(testHashKey, "john.doe@example.com"), testHMAC(testHashKey, "hr@example.org")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.privacy.MinimizeEmail(info)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("MinimizeEmail() diff(got-want)=%s", diff)
+			}
+		})
+	}
+	if info.Recipients[0] != "john.doe@example.com" {
+		t.Errorf("MinimizeEmail() modified given recipients")
+	}
+}
+
+func TestPrivacy_MinimizeFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		privacy Privacy
+		info    FileInfos
+		want    FileInfos
+	}{
+		{
+			name:    "truncate unix path",
+			privacy: Privacy{Filename: PIITruncate, Owner: PIITruncate},
+			info:    FileInfos{File: "/home/john/invoice.exe", Owner: "john.doe@example.com"},
+			want:    FileInfos{File: "…/invoice.exe", Owner: "…@example.com"},
+		},
+		{
+			name:    "truncate windows path",
+			privacy: Privacy{Filename: PIITruncate, Owner: PIITruncate},
+			info:    FileInfos{File: `C:\Users\john\invoice.exe`, Owner: `CORP\john`},
+			want:    FileInfos{File: "…/invoice.exe", Owner: `CORP\…`},
+		},
+		{
+			name:    "truncate base name",
+			privacy: Privacy{Filename: PIITruncate},
+			info:    FileInfos{File: "invoice.exe"},
+			want:    FileInfos{File: "invoice.exe"},
+		},
+		{
+			name:    "hash",
+			privacy: Privacy{Filename: PIIHash, Owner: PIIKeep, HashKey: testHashKey},
+			info:    FileInfos{File: "/home/john/invoice.exe", Owner: "john"},
+			want:    FileInfos{File: testHMAC(testHashKey, "/home/john/invoice.exe"), Owner: "john"},
+		},
+		{
+			name:    "archive members",
+			privacy: Privacy{Filename: PIITruncate},
+			info:    FileInfos{File: "/home/john/archive.zip", Members: []ArchiveMember{{Path: "archive.zip/john/invoice.exe", SHA256: "a"}}},
+			want:    FileInfos{File: "…/archive.zip", Members: []ArchiveMember{{Path: "…/invoice.exe", SHA256: "a"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.privacy.MinimizeFile(tt.info), tt.want); diff != "" {
+				t.Errorf("MinimizeFile() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandler_SetPrivacy(t *testing.T) {
+	var got EmailInfos
+	h := NewHandler(notifierMock{notifyMock: func(ctx context.Context, event any) (err error) {
+		got = event.(MitigationEvent).Info.(EmailInfos)
+		return
+	}}, slog.LevelInfo, nil, &metrics.MetricsCollector{})
+	info := EmailInfos{Sender: "jane.doe@example.com"}
+
+	if err := h.SetPrivacy(Privacy{Sender: PIITruncate}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.NotifyEmailMitigation(t.Context(), ActionBlock, "id", ReasonPhishing, info); err != nil {
+		t.Fatal(err)
+	}
+	if got.Sender != "…@example.com" {
+		t.Errorf("NotifyEmailMitigation() sender = %s, want minimized", got.Sender)
+	}
+
+	if err := h.SetPrivacy(Privacy{Sender: PIIKeep}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.NotifyEmailMitigation(t.Context(), ActionBlock, "id", ReasonPhishing, info); err != nil {
+		t.Fatal(err)
+	}
+	if got.Sender != info.Sender {
+		t.Errorf("NotifyEmailMitigation() sender = %s, want %s", got.Sender, info.Sender)
+	}
+}
+
+func TestPrivacy_MinimizeURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		privacy Privacy
+		info    URLInfos
+		want    URLInfos
+	}{
+		{
+			name:    "truncate",
+			privacy: Privacy{URL: PIITruncate},
+			info:    URLInfos{Method: "GET", URL: "https://example.com/reset?user=john.doe"},
+			want:    URLInfos{Method: "GET", URL: "https://example.com/…"},
+		},
+		{
+			name:    "truncate host only",
+			privacy: Privacy{URL: PIITruncate},
+			info:    URLInfos{URL: "https://example.com/"},
+			want:    URLInfos{URL: "https://example.com"},
+		},
+		{
+			name:    "truncate invalid url",
+			privacy: Privacy{URL: PIITruncate},
+			info:    URLInfos{URL: "john.doe@example.com"},
+			want:    URLInfos{URL: "…"},
+		},
+		{
+			name:    "hash",
+			privacy: Privacy{URL: PIIHash, HashKey: testHashKey},
+			info:    URLInfos{URL: "https://example.com/reset"},
+			want:    URLInfos{URL: testHMAC(testHashKey, "https://example.com/reset")},
+		},
+		{
+			name:    "keep",
+			privacy: Privacy{Filename: PIIHash, HashKey: testHashKey},
+			info:    URLInfos{URL: "https://example.com/reset"},
+			want:    URLInfos{URL: "https://example.com/reset"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.privacy.MinimizeURL(tt.info), tt.want); diff != "" {
+				t.Errorf("MinimizeURL() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrivacy_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		privacy Privacy
+		wantErr error
+	}{
+		{name: "no hash", privacy: Privacy{Subject: PIITruncate}},
+		{name: "hash with key", privacy: Privacy{URL: PIIHash, HashKey: testHashKey}},
+		{name: "hash without key", privacy: Privacy{Owner: PIIHash}, wantErr: ErrMissingHashKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.privacy.Check(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
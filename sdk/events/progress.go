@@ -0,0 +1,13 @@
+package events
+
+// ProgressEvent reports progress of a long-running scan (e.g. initial scan of a repository), notified at each of its
+// checkpoints.
+type ProgressEvent struct {
+	Name      string `json:"name" validate:"required" desc:"scan name"`
+	Processed int64  `json:"processed" desc:"items handled so far, failed ones included"`
+	Failed    int64  `json:"failed"`
+	Total     int64  `json:"total,omitempty" desc:"items to scan, 0 if unknown"`
+	Done      bool   `json:"done"`
+	StartedAt int64  `json:"started_at" desc:"time scan started, kept when it resumes"`
+	Time      int64  `json:"time" validate:"required"`
+}
@@ -0,0 +1,181 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+var ErrUnknownTenant = errors.New("no event handler for tenant")
+
+// Context Key holding the tenant ID used by Router to select a tenant's handler
+type CtxTenantIDKey struct{}
+
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, CtxTenantIDKey{}, tenantID)
+}
+
+func TenantFromContext(ctx context.Context) (tenantID string, ok bool) {
+	tenantID, ok = ctx.Value(CtxTenantIDKey{}).(string)
+	return
+}
+
+var _ EventHandler = &Router{}
+
+// Router multiplexes events of a connector process serving several tenants
+// (e.g. one handler per tenant console client), using tenant ID found in context.
+// Events without tenant, or for an unknown tenant, go to fallback handler, if any.
+type Router struct {
+	lock     sync.RWMutex
+	handlers map[string]EventHandler
+	fallback EventHandler
+}
+
+// NewRouter returns a Router, fallback may be nil to reject events without known tenant with ErrUnknownTenant.
+func NewRouter(fallback EventHandler) (r *Router) {
+	return &Router{
+		handlers: make(map[string]EventHandler),
+		fallback: fallback,
+	}
+}
+
+func (r *Router) AddTenant(tenantID string, handler EventHandler) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.handlers[tenantID] = handler
+}
+
+func (r *Router) RemoveTenant(tenantID string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.handlers, tenantID)
+}
+
+func (r *Router) handler(ctx context.Context) (handler EventHandler, err error) {
+	tenantID, ok := TenantFromContext(ctx)
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if ok {
+		if handler, ok = r.handlers[tenantID]; ok {
+			return
+		}
+	}
+	if r.fallback != nil {
+		handler = r.fallback
+		return
+	}
+	err = ErrUnknownTenant
+	if ok {
+		err = fmt.Errorf("%w %q", ErrUnknownTenant, tenantID)
+	}
+	return
+}
+
+func (r *Router) NotifyError(ctx context.Context, errorType ErrorEventType, e error) (err error) {
+	h, err := r.handler(ctx)
+	if err != nil {
+		return
+	}
+	return h.NotifyError(ctx, errorType, e)
+}
+
+func (r *Router) NotifyResolution(ctx context.Context, msg string, errorTypes ...ErrorEventType) (err error) {
+	h, err := r.handler(ctx)
+	if err != nil {
+		return
+	}
+	return h.NotifyResolution(ctx, msg, errorTypes...)
+}
+
+func (r *Router) NotifyFileMitigation(ctx context.Context, action MitigationAction, elementID string, reason MitigationReason, info FileInfos) (err error) {
+	h, err := r.handler(ctx)
+	if err != nil {
+		return
+	}
+	return h.NotifyFileMitigation(ctx, action, elementID, reason, info)
+}
+
+func (r *Router) NotifyEmailMitigation(ctx context.Context, action MitigationAction, elementID string, reason MitigationReason, info EmailInfos) (err error) {
+	h, err := r.handler(ctx)
+	if err != nil {
+		return
+	}
+	return h.NotifyEmailMitigation(ctx, action, elementID, reason, info)
+}
+
+func (r *Router) NotifyURLMitigation(ctx context.Context, action MitigationAction, elementID string, reason MitigationReason, info URLInfos) (err error) {
+	h, err := r.handler(ctx)
+	if err != nil {
+		return
+	}
+	return h.NotifyURLMitigation(ctx, action, elementID, reason, info)
+}
+
+func (r *Router) NotifyDiagnostic(ctx context.Context, name string, success bool, report any) (err error) {
+	h, err := r.handler(ctx)
+	if err != nil {
+		return
+	}
+	return h.NotifyDiagnostic(ctx, name, success, report)
+}
+
+// GetLogHandler returns a log handler forwarding records to tenant's log handler.
+// Records must be logged with a context (e.g. logger.InfoContext) to be routed.
+func (r *Router) GetLogHandler() slog.Handler {
+	return routerLogHandler{router: r}
+}
+
+type routerLogHandler struct {
+	router *Router
+	// applied to tenant log handler, in order
+	wrappers []func(slog.Handler) slog.Handler
+}
+
+func (lh routerLogHandler) tenantHandler(ctx context.Context) (handler slog.Handler, ok bool) {
+	h, err := lh.router.handler(ctx)
+	if err != nil {
+		return
+	}
+	handler = h.GetLogHandler()
+	for _, wrap := range lh.wrappers {
+		handler = wrap(handler)
+	}
+	ok = true
+	return
+}
+
+func (lh routerLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	h, ok := lh.tenantHandler(ctx)
+	return ok && h.Enabled(ctx, level)
+}
+
+func (lh routerLogHandler) Handle(ctx context.Context, record slog.Record) (err error) {
+	h, ok := lh.tenantHandler(ctx)
+	if !ok {
+		return
+	}
+	return h.Handle(ctx, record)
+}
+
+func (lh routerLogHandler) with(wrap func(slog.Handler) slog.Handler) routerLogHandler {
+	wrappers := make([]func(slog.Handler) slog.Handler, 0, len(lh.wrappers)+1)
+	wrappers = append(wrappers, lh.wrappers...)
+	lh.wrappers = append(wrappers, wrap)
+	return lh
+}
+
+func (lh routerLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return lh
+	}
+	return lh.with(func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs) })
+}
+
+func (lh routerLogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return lh
+	}
+	return lh.with(func(h slog.Handler) slog.Handler { return h.WithGroup(name) })
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/google/go-cmp/cmp"
+)
+
+type recordingNotifier struct {
+	lock   sync.Mutex
+	events []any
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, event any) (err error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.events = append(n.events, event)
+	return
+}
+
+func (n *recordingNotifier) types() (types []string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for _, e := range n.events {
+		eventType, _ := eventTypeOf(e)
+		types = append(types, string(eventType))
+	}
+	return
+}
+
+func TestRouter(t *testing.T) {
+	tests := []struct {
+		name         string
+		tenant       string
+		withFallback bool
+		wantErr      error
+		wantA        []string
+		wantB        []string
+		wantFallback []string
+	}{
+		{
+			name:   "tenant a",
+			tenant: "a",
+			wantA:  []string{"mitigation", "error", "diagnostic", "log"},
+		},
+		{
+			name:   "tenant b",
+			tenant: "b",
+			wantB:  []string{"mitigation", "error", "diagnostic", "log"},
+		},
+		{
+			name:         "unknown tenant with fallback",
+			tenant:       "c",
+			withFallback: true,
+			wantFallback: []string{"mitigation", "error", "diagnostic", "log"},
+		},
+		{
+			name:         "no tenant with fallback",
+			withFallback: true,
+			wantFallback: []string{"mitigation", "error", "diagnostic", "log"},
+		},
+		{
+			name:    "unknown tenant",
+			tenant:  "c",
+			wantErr: ErrUnknownTenant,
+		},
+		{
+			name:    "no tenant",
+			wantErr: ErrUnknownTenant,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifierA, notifierB, notifierFallback := &recordingNotifier{}, &recordingNotifier{}, &recordingNotifier{}
+			var fallback EventHandler
+			if tt.withFallback {
+				fallback = NewHandler(notifierFallback, slog.LevelDebug, nil, &metrics.MetricsCollector{})
+			}
+			r := NewRouter(fallback)
+			r.AddTenant("a", NewHandler(notifierA, slog.LevelDebug, nil, &metrics.MetricsCollector{}))
+			r.AddTenant("b", NewHandler(notifierB, slog.LevelDebug, nil, &metrics.MetricsCollector{}))
+
+			ctx := t.Context()
+			if tt.tenant != "" {
+				ctx = WithTenant(ctx, tt.tenant)
+			}
+			err := r.NotifyFileMitigation(ctx, ActionQuarantine, "id", ReasonMalware, FileInfos{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Router.NotifyFileMitigation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err = r.NotifyError(ctx, GMalwareError, errors.New("unreachable")); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Router.NotifyError() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err = r.NotifyDiagnostic(ctx, "check", true, nil); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Router.NotifyDiagnostic() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			slog.New(r.GetLogHandler()).With(slog.String("k", "v")).WithGroup("g").InfoContext(ctx, "message")
+
+			if diff := cmp.Diff(notifierA.types(), tt.wantA); diff != "" {
+				t.Errorf("Router tenant a events diff(got-want)=%s", diff)
+			}
+			if diff := cmp.Diff(notifierB.types(), tt.wantB); diff != "" {
+				t.Errorf("Router tenant b events diff(got-want)=%s", diff)
+			}
+			if diff := cmp.Diff(notifierFallback.types(), tt.wantFallback); diff != "" {
+				t.Errorf("Router fallback events diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestRouter_RemoveTenant(t *testing.T) {
+	notifier := &recordingNotifier{}
+	r := NewRouter(nil)
+	r.AddTenant("a", NewHandler(notifier, slog.LevelDebug, nil, &metrics.MetricsCollector{}))
+	r.RemoveTenant("a")
+	err := r.NotifyDiagnostic(WithTenant(t.Context(), "a"), "check", true, nil)
+	if !errors.Is(err, ErrUnknownTenant) {
+		t.Errorf("Router.NotifyDiagnostic() error = %v, want %v", err, ErrUnknownTenant)
+	}
+}
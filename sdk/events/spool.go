@@ -0,0 +1,388 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/state"
+)
+
+const (
+	// DefaultSpoolMaxSize is the maximum size of spooled events, in bytes.
+	DefaultSpoolMaxSize int64 = 64 << 20
+	// DefaultSpoolRetention is the age after which spooled events are dropped.
+	DefaultSpoolRetention = 7 * 24 * time.Hour
+	// DefaultSpoolReplayInterval is the delay between replays of spooled events.
+	DefaultSpoolReplayInterval = 30 * time.Second
+	spoolFileSuffix            = ".ndjson"
+)
+
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil)).WithGroup("spool")
+
+var ErrSpoolFull = errors.New("event spool full")
+
+// rejectedError is implemented by notifier errors telling whether manager rejected an event (e.g. sdk.HTTPError
+// for 4xx responses), so sending it again is pointless.
+type rejectedError interface {
+	Rejected() bool
+}
+
+func isRejected(err error) bool {
+	var rejected rejectedError
+	return errors.As(err, &rejected) && rejected.Rejected()
+}
+
+type eventTimeKey struct{}
+
+// WithEventTime returns a copy of ctx carrying creation time of notified event, e.g. of a replayed event, for its
+// idempotency key to be the one of its first notification.
+func WithEventTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, eventTimeKey{}, t)
+}
+
+// EventTimeFromContext returns event creation time set by WithEventTime.
+func EventTimeFromContext(ctx context.Context) (t time.Time, ok bool) {
+	t, ok = ctx.Value(eventTimeKey{}).(time.Time)
+	return
+}
+
+type SpoolOptions struct {
+	// Dir events are spooled in, one NDJSON file per event (see WriteNDJSON). Spool is disabled if empty.
+	Dir string `mapstructure:"dir"`
+	// MaxSize of spooled events in bytes, DefaultSpoolMaxSize if 0. Events are dropped once it is reached.
+	MaxSize int64 `mapstructure:"max-size"`
+	// Retention is the age after which spooled events are dropped, DefaultSpoolRetention if 0.
+	Retention time.Duration `mapstructure:"retention"`
+	// ReplayInterval is the delay between replays (see Spool.Run), DefaultSpoolReplayInterval if 0.
+	ReplayInterval time.Duration `mapstructure:"replay-interval"`
+	// Cipher, if set, encrypts spooled events at rest (events hold mitigated items details).
+	Cipher *state.Cipher `mapstructure:"-"`
+}
+
+type spoolFile struct {
+	name    string
+	size    int64
+	spooled time.Time
+}
+
+var _ Notifier = &Spool{}
+
+// Spool is a Notifier spooling on disk mitigation, error, resolution and log events its notifier failed to send
+// (e.g. console unreachable), replayed in order by Replay. While events are spooled, new ones are spooled after
+// them, so console receives them in order. Events rejected by manager are not spooled. It is safe for concurrent
+// use.
+type Spool struct {
+	notifier Notifier
+	opts     SpoolOptions
+	now      func() time.Time
+
+	lock      sync.Mutex
+	replaying sync.Mutex
+	files     []spoolFile // oldest first
+	size      int64
+	seq       int
+}
+
+// NewSpool returns a Spool of events sent with notifier, loading events spooled in opts.Dir by a previous run.
+func NewSpool(notifier Notifier, opts SpoolOptions) (s *Spool, err error) {
+	if opts.Dir == "" {
+		err = errors.New("spool dir is required")
+		return
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultSpoolMaxSize
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultSpoolRetention
+	}
+	if opts.ReplayInterval <= 0 {
+		opts.ReplayInterval = DefaultSpoolReplayInterval
+	}
+	if err = os.MkdirAll(opts.Dir, 0o700); err != nil {
+		err = fmt.Errorf("could not create spool dir, %w", err)
+		return
+	}
+	s = &Spool{notifier: notifier, opts: opts, now: time.Now}
+	entries, err := os.ReadDir(opts.Dir)
+	if err != nil {
+		err = fmt.Errorf("could not read spool dir, %w", err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			// interrupted write
+			_ = os.Remove(filepath.Join(opts.Dir, entry.Name()))
+			continue
+		}
+		spooled, ok := spoolTime(entry.Name())
+		if !ok {
+			continue
+		}
+		info, infoErr := entry.Info()
+		if infoErr != nil {
+			continue
+		}
+		s.files = append(s.files, spoolFile{name: entry.Name(), size: info.Size(), spooled: spooled})
+		s.size += info.Size()
+	}
+	slices.SortFunc(s.files, func(a, b spoolFile) int {
+		return strings.Compare(a.name, b.name)
+	})
+	if err = s.migrate(); err != nil {
+		return
+	}
+	s.lock.Lock()
+	s.expire()
+	s.lock.Unlock()
+	if len(s.files) > 0 {
+		logger.Info("events spooled by previous run", slog.Int("events", len(s.files)))
+	}
+	return
+}
+
+// migrate encrypts events spooled before opts.Cipher was set, so they are replayed instead of being dropped as
+// invalid.
+func (s *Spool) migrate() (err error) {
+	if s.opts.Cipher == nil {
+		return
+	}
+	migrated := 0
+	for i, file := range s.files {
+		path := filepath.Join(s.opts.Dir, file.name)
+		data, readErr := os.ReadFile(path)
+		if readErr != nil {
+			err = fmt.Errorf("could not read spooled event, %w", readErr)
+			return
+		}
+		if state.IsSealed(data) {
+			continue
+		}
+		if data, err = s.opts.Cipher.Seal(data, []byte(file.name)); err != nil {
+			return
+		}
+		if err = os.WriteFile(path+".tmp", data, 0o600); err != nil {
+			err = fmt.Errorf("could not encrypt spooled event, %w", err)
+			return
+		}
+		if err = os.Rename(path+".tmp", path); err != nil {
+			err = fmt.Errorf("could not encrypt spooled event, %w", err)
+			return
+		}
+		s.size += int64(len(data)) - file.size
+		s.files[i].size = int64(len(data))
+		migrated++
+	}
+	if migrated > 0 {
+		logger.Info("unencrypted spooled events encrypted", slog.Int("events", migrated))
+	}
+	return
+}
+
+// spoolTime returns spool time of file name, "<unix nano>-<sequence>.ndjson".
+func spoolTime(name string) (t time.Time, ok bool) {
+	base, ok := strings.CutSuffix(name, spoolFileSuffix)
+	if !ok {
+		return
+	}
+	nanos, _, _ := strings.Cut(base, "-")
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		ok = false
+		return
+	}
+	t = time.Unix(0, n)
+	return
+}
+
+func spooledEvent(event any) bool {
+	switch event.(type) {
+	case MitigationEvent, ErrorEvent, ResolutionEvent, LogEvent:
+		return true
+	}
+	return false
+}
+
+// Notify notifies event, spooling it if notifier fails, or while previous events are spooled. It returns nil once
+// event is spooled, and ErrSpoolFull if it is dropped.
+func (s *Spool) Notify(ctx context.Context, event any) (err error) {
+	if !spooledEvent(event) {
+		err = s.notifier.Notify(ctx, event)
+		return
+	}
+	created := s.now()
+	s.lock.Lock()
+	pending := len(s.files) > 0
+	if pending {
+		err = s.write(event, created)
+	}
+	s.lock.Unlock()
+	if pending {
+		return
+	}
+	err = s.notifier.Notify(WithEventTime(ctx, created), event)
+	if err == nil || isRejected(err) {
+		return
+	}
+	logger.Debug("could not notify event, spool it", slog.String("error", err.Error()))
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	err = s.write(event, created)
+	return
+}
+
+// write spools event created at created, s.lock must be held.
+func (s *Spool) write(event any, created time.Time) (err error) {
+	var buf bytes.Buffer
+	if err = WriteNDJSONAt(&buf, created, event); err != nil {
+		return
+	}
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d%s", created.UnixNano(), s.seq%1_000_000, spoolFileSuffix)
+	data := buf.Bytes()
+	if s.opts.Cipher != nil {
+		if data, err = s.opts.Cipher.Seal(data, []byte(name)); err != nil {
+			return
+		}
+	}
+	if s.size+int64(len(data)) > s.opts.MaxSize {
+		err = fmt.Errorf("%w, %d bytes spooled", ErrSpoolFull, s.size)
+		logger.Warn("drop event, spool is full", slog.Int64("max-size", s.opts.MaxSize))
+		return
+	}
+	path := filepath.Join(s.opts.Dir, name)
+	if err = os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		err = fmt.Errorf("could not spool event, %w", err)
+		return
+	}
+	if err = os.Rename(path+".tmp", path); err != nil {
+		err = fmt.Errorf("could not spool event, %w", err)
+		return
+	}
+	s.files = append(s.files, spoolFile{name: name, size: int64(len(data)), spooled: created})
+	s.size += int64(len(data))
+	return
+}
+
+// expire drops events spooled for longer than retention, s.lock must be held.
+func (s *Spool) expire() {
+	expired := 0
+	for len(s.files) > 0 && s.now().Sub(s.files[0].spooled) >= s.opts.Retention {
+		s.remove()
+		expired++
+	}
+	if expired > 0 {
+		logger.Warn("drop spooled events, retention exceeded", slog.Int("events", expired), slog.String("retention", s.opts.Retention.String()))
+	}
+}
+
+// remove removes oldest spooled event, s.lock must be held.
+func (s *Spool) remove() {
+	file := s.files[0]
+	if err := os.Remove(filepath.Join(s.opts.Dir, file.name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("could not remove spooled event", slog.String("file", file.name), slog.String("error", err.Error()))
+	}
+	s.files = s.files[1:]
+	s.size -= file.size
+}
+
+// Len returns the number of spooled events.
+func (s *Spool) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.files)
+}
+
+// Replay sends spooled events in order, until all are sent or notifier fails (err is then its error). Events
+// rejected by manager, or that can not be read, are dropped.
+func (s *Spool) Replay(ctx context.Context) (replayed int, err error) {
+	s.replaying.Lock()
+	defer s.replaying.Unlock()
+	for {
+		s.lock.Lock()
+		s.expire()
+		if len(s.files) == 0 {
+			s.lock.Unlock()
+			return
+		}
+		file := s.files[0]
+		s.lock.Unlock()
+
+		event, readErr := s.read(file)
+		if readErr == nil {
+			err = s.notifier.Notify(WithEventTime(ctx, file.spooled), event)
+		}
+		switch {
+		case readErr != nil:
+			logger.Warn("drop invalid spooled event", slog.String("file", file.name), slog.String("error", readErr.Error()))
+		case isRejected(err):
+			logger.Warn("drop spooled event rejected by manager", slog.String("file", file.name), slog.String("error", err.Error()))
+			err = nil
+		case err != nil:
+			return
+		default:
+			replayed++
+		}
+		s.lock.Lock()
+		if len(s.files) > 0 && s.files[0].name == file.name {
+			s.remove()
+		}
+		s.lock.Unlock()
+	}
+}
+
+func (s *Spool) read(file spoolFile) (event any, err error) {
+	data, err := os.ReadFile(filepath.Join(s.opts.Dir, file.name))
+	if err != nil {
+		return
+	}
+	if s.opts.Cipher != nil {
+		if data, err = s.opts.Cipher.Open(data, []byte(file.name)); err != nil {
+			return
+		}
+	}
+	events, err := ReadNDJSON(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	if len(events) != 1 {
+		err = fmt.Errorf("%d events in spool file, want 1", len(events))
+		return
+	}
+	event = events[0]
+	return
+}
+
+// Run replays spooled events every ReplayInterval until ctx is done.
+func (s *Spool) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.ReplayInterval)
+	defer ticker.Stop()
+	for {
+		if s.Len() > 0 {
+			replayed, err := s.Replay(ctx)
+			if replayed > 0 {
+				logger.Info("spooled events replayed", slog.Int("events", replayed), slog.Int("left", s.Len()))
+			}
+			if err != nil && ctx.Err() == nil {
+				logger.Debug("could not replay spooled events", slog.String("error", err.Error()))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
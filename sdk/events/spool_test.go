@@ -0,0 +1,229 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/state"
+	"github.com/google/go-cmp/cmp"
+)
+
+type rejectedErrorMock struct{}
+
+func (rejectedErrorMock) Error() string  { return "bad request" }
+func (rejectedErrorMock) Rejected() bool { return true }
+
+func TestSpool(t *testing.T) {
+	errUnreachable := errors.New("connection refused")
+	mitigation := MitigationEvent{Action: ActionBlock, Time: 1}
+	errorEvent := ErrorEvent{Error: "gmalware down", Type: GMalwareError, Time: 2}
+	logEvent := LogEvent{Level: "info", Message: "started", Time: 3}
+	tests := []struct {
+		name       string
+		opts       SpoolOptions
+		notifyErr  error
+		events     []any
+		reopenAt   time.Duration // reopen spool this long after events
+		wantErrs   []error
+		wantSent   []any // sent while spooling
+		wantSpool  int
+		wantReplay []any
+	}{
+		{
+			name:       "console unreachable",
+			notifyErr:  errUnreachable,
+			events:     []any{mitigation, errorEvent, logEvent},
+			wantErrs:   []error{nil, nil, nil},
+			wantSpool:  3,
+			wantReplay: []any{mitigation, errorEvent, logEvent},
+		},
+		{
+			name:       "encrypted",
+			opts:       SpoolOptions{Cipher: testSpoolCipher(t)},
+			notifyErr:  errUnreachable,
+			events:     []any{mitigation, logEvent},
+			wantErrs:   []error{nil, nil},
+			wantSpool:  2,
+			wantReplay: []any{mitigation, logEvent},
+		},
+		{
+			name:     "console reachable",
+			events:   []any{mitigation, logEvent},
+			wantErrs: []error{nil, nil},
+			wantSent: []any{mitigation, logEvent},
+		},
+		{
+			name:      "rejected events not spooled",
+			notifyErr: rejectedErrorMock{},
+			events:    []any{mitigation},
+			wantErrs:  []error{rejectedErrorMock{}},
+		},
+		{
+			name:      "other events not spooled",
+			notifyErr: errUnreachable,
+			events:    []any{TaskEvent{TaskID: "task-1"}},
+			wantErrs:  []error{errUnreachable},
+		},
+		{
+			name:       "max size",
+			opts:       SpoolOptions{MaxSize: 280},
+			notifyErr:  errUnreachable,
+			events:     []any{mitigation, errorEvent, errorEvent},
+			wantErrs:   []error{nil, ErrSpoolFull, ErrSpoolFull},
+			wantSpool:  1,
+			wantReplay: []any{mitigation},
+		},
+		{
+			name:      "retention",
+			opts:      SpoolOptions{Retention: time.Hour},
+			notifyErr: errUnreachable,
+			events:    []any{mitigation, logEvent},
+			wantErrs:  []error{nil, nil},
+			reopenAt:  2 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []any
+			var times []time.Time
+			notifyErr := tt.notifyErr
+			notifier := notifierMock{notifyMock: func(ctx context.Context, event any) (err error) {
+				if notifyErr != nil {
+					err = notifyErr
+					return
+				}
+				created, ok := EventTimeFromContext(ctx)
+				if !ok {
+					t.Errorf("Notify() context has no event time")
+				}
+				sent = append(sent, event)
+				times = append(times, created)
+				return
+			}}
+			tt.opts.Dir = filepath.Join(t.TempDir(), "spool")
+			now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+			s, err := NewSpool(notifier, tt.opts)
+			if err != nil {
+				t.Fatalf("NewSpool() error = %v", err)
+			}
+			s.now = func() time.Time { return now }
+			var spoolTimes []time.Time
+			for i, event := range tt.events {
+				now = now.Add(time.Second)
+				spoolTimes = append(spoolTimes, now)
+				if err = s.Notify(t.Context(), event); !errors.Is(err, tt.wantErrs[i]) {
+					t.Fatalf("Notify() error = %v, want %v", err, tt.wantErrs[i])
+				}
+			}
+			if diff := cmp.Diff(sent, tt.wantSent); diff != "" {
+				t.Errorf("sent events diff(got-want)=%s", diff)
+			}
+			if tt.opts.Cipher != nil {
+				files, _ := os.ReadDir(tt.opts.Dir)
+				data, _ := os.ReadFile(filepath.Join(tt.opts.Dir, files[0].Name()))
+				if !state.IsSealed(data) || bytes.Contains(data, []byte("block")) {
+					t.Errorf("spooled event is not encrypted: %s", data)
+				}
+			}
+
+			// restarted connector replays events spooled by previous run
+			s, err = NewSpool(notifier, tt.opts)
+			if err != nil {
+				t.Fatalf("NewSpool() error = %v", err)
+			}
+			now = now.Add(tt.reopenAt)
+			s.now = func() time.Time { return now }
+			s.lock.Lock()
+			s.expire()
+			s.lock.Unlock()
+			if got := s.Len(); got != tt.wantSpool {
+				t.Fatalf("Len() = %d, want %d", got, tt.wantSpool)
+			}
+			// events are spooled after previous ones while they are
+			if tt.wantSpool > 0 {
+				notifyErr = nil
+				if err = s.Notify(t.Context(), logEvent); err != nil {
+					t.Fatalf("Notify() error = %v", err)
+				}
+				tt.wantReplay = append(tt.wantReplay, logEvent)
+			}
+			sent, times = nil, nil
+			replayed, err := s.Replay(t.Context())
+			if err != nil {
+				t.Fatalf("Replay() error = %v", err)
+			}
+			if replayed != len(tt.wantReplay) {
+				t.Errorf("Replay() = %d, want %d", replayed, len(tt.wantReplay))
+			}
+			if diff := cmp.Diff(sent, tt.wantReplay); diff != "" {
+				t.Errorf("replayed events diff(got-want)=%s", diff)
+			}
+			for i := range min(len(times), tt.wantSpool) {
+				if !times[i].Equal(spoolTimes[i]) {
+					t.Errorf("replayed event %d time = %s, want %s", i, times[i], spoolTimes[i])
+				}
+			}
+			if got := s.Len(); got != 0 {
+				t.Errorf("Len() after replay = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func testSpoolCipher(t *testing.T) *state.Cipher {
+	c, err := state.NewCipher(bytes.Repeat([]byte{1}, state.KeySize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	return c
+}
+
+func TestSpool_encryptionMigration(t *testing.T) {
+	errUnreachable := errors.New("connection refused")
+	event := MitigationEvent{Action: ActionBlock, Time: 1}
+	var sent []any
+	notifyErr := errUnreachable
+	notifier := notifierMock{notifyMock: func(ctx context.Context, event any) (err error) {
+		if notifyErr != nil {
+			err = notifyErr
+			return
+		}
+		sent = append(sent, event)
+		return
+	}}
+	dir := filepath.Join(t.TempDir(), "spool")
+	s, err := NewSpool(notifier, SpoolOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewSpool() error = %v", err)
+	}
+	if err = s.Notify(t.Context(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	// encryption turned on after event was spooled
+	s, err = NewSpool(notifier, SpoolOptions{Dir: dir, Cipher: testSpoolCipher(t)})
+	if err != nil {
+		t.Fatalf("NewSpool() error = %v", err)
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("spool files = %d, want 1", len(files))
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	if !state.IsSealed(data) {
+		t.Errorf("spooled event is not encrypted: %s", data)
+	}
+	notifyErr = nil
+	replayed, err := s.Replay(t.Context())
+	if err != nil || replayed != 1 {
+		t.Fatalf("Replay() = %d, %v, want 1 replayed event", replayed, err)
+	}
+	if diff := cmp.Diff(sent, []any{event}); diff != "" {
+		t.Errorf("replayed events diff(got-want)=%s", diff)
+	}
+}
@@ -0,0 +1,16 @@
+package events
+
+// StatusEvent details the health of connector subsystems (e.g. webhook listener, scanner pool, uploader),
+// notified when one of them changes state.
+type StatusEvent struct {
+	State      string            `json:"state" validate:"required" desc:"aggregated state of subsystems"`
+	Subsystems []SubsystemStatus `json:"subsystems"`
+	Time       int64             `json:"time" validate:"required"`
+}
+
+type SubsystemStatus struct {
+	Name    string `json:"name" validate:"required"`
+	State   string `json:"state" validate:"required"`
+	Message string `json:"message,omitempty"`
+	Since   int64  `json:"since" desc:"time subsystem entered its state"`
+}
@@ -0,0 +1,65 @@
+package events
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+)
+
+// SummaryEvent aggregates connector activity over a period (e.g. a day), so the console
+// can send digest reports without recomputing them from raw events.
+type SummaryEvent struct {
+	PeriodStart        int64                      `json:"period_start" validate:"required"`
+	PeriodEnd          int64                      `json:"period_end" validate:"required,gtfield=PeriodStart"`
+	ItemsProcessed     int64                      `json:"items_processed_total"`
+	SizeProcessed      int64                      `json:"processed_bytes_total"`
+	ItemsMitigated     int64                      `json:"items_mitigated_total"`
+	ItemsError         int64                      `json:"items_error_total"`
+	DetectionsByReason map[MitigationReason]int64 `json:"detections_by_reason"`
+	TopMalwares        []MalwareCount             `json:"top_malwares" desc:"most detected malware families, by decreasing count"`
+	Quota              QuotaUsage                 `json:"quota" desc:"quotas at period end"`
+}
+
+type MalwareCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type QuotaUsage struct {
+	DailyQuota          int64 `json:"daily_quota"`
+	AvailableDailyQuota int64 `json:"available_daily_quota"`
+}
+
+// NewSummaryEvent builds a SummaryEvent from metrics accumulated between start and end,
+// keeping the topMalwares most detected malware families (all of them if topMalwares <= 0).
+func NewSummaryEvent(start time.Time, end time.Time, summary metrics.SummaryMetrics, topMalwares int) (event SummaryEvent) {
+	event = SummaryEvent{
+		PeriodStart:        start.Unix(),
+		PeriodEnd:          end.Unix(),
+		ItemsProcessed:     summary.ItemsProcessed,
+		SizeProcessed:      summary.SizeProcessed,
+		ItemsMitigated:     summary.ItemsMitigated,
+		ItemsError:         summary.ItemsError,
+		DetectionsByReason: make(map[MitigationReason]int64, len(summary.Detections)),
+		TopMalwares:        make([]MalwareCount, 0, len(summary.Malwares)),
+		Quota: QuotaUsage{
+			DailyQuota:          summary.DailyQuota,
+			AvailableDailyQuota: summary.AvailableDailyQuota,
+		},
+	}
+	for reason, count := range summary.Detections {
+		event.DetectionsByReason[MitigationReason(reason)] = count
+	}
+	for name, count := range summary.Malwares {
+		event.TopMalwares = append(event.TopMalwares, MalwareCount{Name: name, Count: count})
+	}
+	slices.SortFunc(event.TopMalwares, func(a, b MalwareCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Name, b.Name))
+	})
+	if topMalwares > 0 && len(event.TopMalwares) > topMalwares {
+		event.TopMalwares = event.TopMalwares[:topMalwares]
+	}
+	return
+}
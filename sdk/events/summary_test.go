@@ -0,0 +1,66 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewSummaryEvent(t *testing.T) {
+	start := time.Unix(fixtureTime-86400, 0)
+	end := time.Unix(fixtureTime, 0)
+	summary := metrics.SummaryMetrics{
+		ItemsProcessed:      10,
+		SizeProcessed:       1024,
+		ItemsMitigated:      4,
+		ItemsError:          1,
+		Detections:          map[string]int64{"malware": 3, "phishing": 1},
+		Malwares:            map[string]int64{"Trojan.Generic": 2, "Ransom.Lockbit": 1, "Phishing.Generic": 1, "Worm.Conficker": 3},
+		DailyQuota:          1000,
+		AvailableDailyQuota: 250,
+	}
+	tests := []struct {
+		name        string
+		topMalwares int
+		want        []MalwareCount
+	}{
+		{
+			name:        "top 3",
+			topMalwares: 3,
+			want: []MalwareCount{
+				{Name: "Worm.Conficker", Count: 3},
+				{Name: "Trojan.Generic", Count: 2},
+				{Name: "Phishing.Generic", Count: 1},
+			},
+		},
+		{
+			name: "all",
+			want: []MalwareCount{
+				{Name: "Worm.Conficker", Count: 3},
+				{Name: "Trojan.Generic", Count: 2},
+				{Name: "Phishing.Generic", Count: 1},
+				{Name: "Ransom.Lockbit", Count: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := SummaryEvent{
+				PeriodStart:        fixtureTime - 86400,
+				PeriodEnd:          fixtureTime,
+				ItemsProcessed:     10,
+				SizeProcessed:      1024,
+				ItemsMitigated:     4,
+				ItemsError:         1,
+				DetectionsByReason: map[MitigationReason]int64{ReasonMalware: 3, ReasonPhishing: 1},
+				TopMalwares:        tt.want,
+				Quota:              QuotaUsage{DailyQuota: 1000, AvailableDailyQuota: 250},
+			}
+			if diff := cmp.Diff(NewSummaryEvent(start, end, summary, tt.topMalwares), want); diff != "" {
+				t.Errorf("NewSummaryEvent() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
@@ -3,4 +3,10 @@ package events
 type TaskEvent struct {
 	TaskID string `json:"task_id" validate:"required"`
 	Error  string `json:"error"`
+	// ConfigHash identifies the config revision the connector is running, see `ConfigHash`
+	ConfigHash string `json:"config_hash,omitempty"`
+	Result     any    `json:"result,omitempty" desc:"task result, e.g. effective config for get-effective-config task"`
+	RequestID  string `json:"request_id,omitempty" desc:"request id of the task, also sent as X-Request-Id of requests made while handling it"`
+	// ConfigProvenance is the source of each effective config field, by path (e.g. "monitoring.period": "file")
+	ConfigProvenance map[string]string `json:"config_provenance,omitempty" desc:"source of effective config fields (default, console, environment, file, secrets), for get-effective-config task"`
 }
@@ -0,0 +1,263 @@
+// Package export converts mitigation history into CSV or JSON Lines, for periodic compliance exports
+// from connectors keeping local history (e.g. NDJSON spools, see events.NDJSONDecoder).
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+func (Format) Values() []Format {
+	return []Format{FormatCSV, FormatJSONL}
+}
+
+var (
+	ErrUnknownFormat    = errors.New("unknown export format")
+	ErrInfoTypeMismatch = errors.New("mitigation event info type differs from export one")
+)
+
+// ListSeparator joins list values (e.g. malwares, recipients) in CSV cells.
+const ListSeparator = "|"
+
+// event fields, then info fields common to every info type
+var (
+	eventColumns  = []string{"time", "action", "reason", "info_type", "element_id"}
+	commonColumns = []string{"malwares", "gmalware_urls", "quarantine_location", "sha256", "analysis_error", "additional_info"}
+	infoColumns   = map[events.MitigationInfoType][]string{
+		events.InfoTypeFile:  {"filename", "filetype", "size", "owner", "archive_members"},
+		events.InfoTypeEmail: {"subject", "sender", "recipients"},
+		events.InfoTypeURL:   {"method", "url", "content_length", "content_type"},
+	}
+)
+
+// Columns returns flattened fields exported for mitigation events of infoType.
+func Columns(infoType events.MitigationInfoType) (columns []string, err error) {
+	specific, ok := infoColumns[infoType]
+	if !ok {
+		err = fmt.Errorf("unknown mitigation info type %q", infoType)
+		return
+	}
+	columns = make([]string, 0, len(eventColumns)+len(specific)+len(commonColumns))
+	columns = append(columns, eventColumns...)
+	columns = append(columns, specific...)
+	columns = append(columns, commonColumns...)
+	return
+}
+
+// Writer writes mitigation events of a single info type, one flattened record per event:
+// event fields then info fields (see Columns), time formatted as RFC 3339 (UTC).
+// CSV output starts with a header, list values are joined with ListSeparator, objects (e.g. archive members) are
+// written as JSON, and cells a spreadsheet would
+// evaluate as a formula (starting with '=', '+', '-', '@', tab or carriage return) are prefixed with a single quote.
+// JSON Lines output keeps value types (numbers, lists).
+// Flush MUST be called once all events are written.
+type Writer struct {
+	format   Format
+	infoType events.MitigationInfoType
+	columns  []string
+	csv      *csv.Writer
+	json     *json.Encoder
+	header   bool
+}
+
+func NewWriter(w io.Writer, format Format, infoType events.MitigationInfoType) (writer *Writer, err error) {
+	columns, err := Columns(infoType)
+	if err != nil {
+		return
+	}
+	writer = &Writer{
+		format:   format,
+		infoType: infoType,
+		columns:  columns,
+	}
+	switch format {
+	case FormatCSV:
+		writer.csv = csv.NewWriter(w)
+	case FormatJSONL:
+		writer.json = json.NewEncoder(w)
+	default:
+		writer = nil
+		err = fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+		return
+	}
+	return
+}
+
+// Write writes event, which MUST be of writer info type. Info may be a typed struct (e.g. FileInfos)
+// or its decoded JSON (e.g. events read with events.NDJSONDecoder).
+func (w *Writer) Write(event events.MitigationEvent) (err error) {
+	if event.InfoType != w.infoType {
+		err = fmt.Errorf("%w: %s, expected %s", ErrInfoTypeMismatch, event.InfoType, w.infoType)
+		return
+	}
+	record, err := flatten(event)
+	if err != nil {
+		err = fmt.Errorf("could not flatten event %s, %w", event.ElementID, err)
+		return
+	}
+	if w.format == FormatJSONL {
+		err = w.json.Encode(orderedRecord{columns: w.columns, values: record})
+		return
+	}
+	if !w.header {
+		if err = w.csv.Write(w.columns); err != nil {
+			return
+		}
+		w.header = true
+	}
+	row := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		row[i] = csvCell(record[column])
+	}
+	err = w.csv.Write(row)
+	return
+}
+
+// Flush writes buffered records, and CSV header if no event was written.
+func (w *Writer) Flush() (err error) {
+	if w.format == FormatJSONL {
+		return
+	}
+	if !w.header {
+		if err = w.csv.Write(w.columns); err != nil {
+			return
+		}
+		w.header = true
+	}
+	w.csv.Flush()
+	err = w.csv.Error()
+	return
+}
+
+func flatten(event events.MitigationEvent) (record map[string]any, err error) {
+	rawInfo, err := json.Marshal(event.Info)
+	if err != nil {
+		return
+	}
+	record = make(map[string]any)
+	if string(rawInfo) != "null" {
+		dec := json.NewDecoder(bytes.NewReader(rawInfo))
+		dec.UseNumber()
+		if err = dec.Decode(&record); err != nil {
+			return
+		}
+	}
+	record["time"] = time.Unix(event.Time, 0).UTC().Format(time.RFC3339)
+	record["action"] = string(event.Action)
+	record["reason"] = string(event.Reason)
+	record["info_type"] = string(event.InfoType)
+	record["element_id"] = event.ElementID
+	return
+}
+
+// formulaPrefixes start cells spreadsheets evaluate as formulas (CSV injection).
+const formulaPrefixes = "=+-@\t\r"
+
+// csvCell returns CSV cell of value, escaped so spreadsheets do not evaluate it as a formula. Numbers are kept as is.
+func csvCell(value any) (cell string) {
+	cell = csvValue(value)
+	if _, number := value.(json.Number); !number && cell != "" && strings.ContainsRune(formulaPrefixes, rune(cell[0])) {
+		cell = "'" + cell
+	}
+	return
+}
+
+func csvValue(value any) (cell string) {
+	switch v := value.(type) {
+	case nil:
+	case string:
+		cell = v
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = csvValue(item)
+		}
+		cell = strings.Join(items, ListSeparator)
+	case map[string]any:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			cell = fmt.Sprint(v)
+			break
+		}
+		cell = string(raw)
+	default:
+		cell = fmt.Sprint(v)
+	}
+	return
+}
+
+// orderedRecord marshals a JSON object with keys in columns order, missing values as null.
+type orderedRecord struct {
+	columns []string
+	values  map[string]any
+}
+
+func (r orderedRecord) MarshalJSON() (raw []byte, err error) {
+	buf := bytes.NewBufferString("{")
+	for i, column := range r.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, keyErr := json.Marshal(column)
+		if keyErr != nil {
+			err = keyErr
+			return
+		}
+		value, valueErr := json.Marshal(r.values[column])
+		if valueErr != nil {
+			err = valueErr
+			return
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	raw = buf.Bytes()
+	return
+}
+
+// ExportNDJSON writes mitigation events of infoType read from NDJSON history r (see events.WriteNDJSON),
+// skipping other events. It returns the number of events exported.
+func ExportNDJSON(w io.Writer, r io.Reader, format Format, infoType events.MitigationInfoType) (exported int, err error) {
+	writer, err := NewWriter(w, format, infoType)
+	if err != nil {
+		return
+	}
+	dec := events.NewNDJSONDecoder(r)
+	for {
+		event, nextErr := dec.Next()
+		if errors.Is(nextErr, io.EOF) {
+			break
+		}
+		if nextErr != nil {
+			err = nextErr
+			return
+		}
+		mitigation, ok := event.(events.MitigationEvent)
+		if !ok || mitigation.InfoType != infoType {
+			continue
+		}
+		if err = writer.Write(mitigation); err != nil {
+			return
+		}
+		exported++
+	}
+	err = writer.Flush()
+	return
+}
@@ -0,0 +1,170 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	fileEvent = events.MitigationEvent{
+		Action:    events.ActionQuarantine,
+		InfoType:  events.InfoTypeFile,
+		Time:      1738000000,
+		ElementID: "f1c1e4a2",
+		Reason:    events.ReasonMalware,
+		Info: events.FileInfos{
+			CommonDetails: events.CommonDetails{
+				Malwares: []string{"Trojan.Generic", "Ransom.Lockbit"},
+				SHA256:   "131f95c51cc819465fa1797f6ccacf9d494aaaff46fa3eac73ae63ffbdfd8267",
+			},
+			File:     "/home/user/invoice, 2025.exe",
+			Filetype: "exe",
+			Size:     73802,
+			Members: []events.ArchiveMember{
+				{Path: "invoice/run.exe", SHA256: "4f2a", Malwares: []string{"Trojan.Generic"}},
+				{Path: "invoice/lock.dll", SHA256: "9b1c", Malwares: []string{"Ransom.Lockbit"}},
+			},
+		},
+	}
+	emailEvent = events.MitigationEvent{
+		Action:    events.ActionBlock,
+		InfoType:  events.InfoTypeEmail,
+		Time:      1738000060,
+		ElementID: "AAMkAGI2TG93AAA=",
+		Reason:    events.ReasonPhishing,
+		Info: events.EmailInfos{
+			Subject:    "Your invoice",
+			Sender:     "billing@example.net",
+			Recipients: []string{"john.doe@example.com", "jane.doe@example.com"},
+		},
+	}
+)
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		infoType events.MitigationInfoType
+		events   []events.MitigationEvent
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "csv file",
+			format:   FormatCSV,
+			infoType: events.InfoTypeFile,
+			events:   []events.MitigationEvent{fileEvent},
+			want: "time,action,reason,info_type,element_id,filename,filetype,size,owner,archive_members,malwares,gmalware_urls,quarantine_location,sha256,analysis_error,additional_info\n" +
+				`2025-01-27T17:46:40Z,quarantine,malware,file,f1c1e4a2,"/home/user/invoice, 2025.exe",exe,73802,,"{""malwares"":[""Trojan.Generic""],""path"":""invoice/run.exe"",""sha256"":""4f2a""}|{""malwares"":[""Ransom.Lockbit""],""path"":""invoice/lock.dll"",""sha256"":""9b1c""}",Trojan.Generic|Ransom.Lockbit,,,131f95c51cc819465fa1797f6ccacf9d494aaaff46fa3eac73ae63ffbdfd8267,,` + "\n",
+		},
+		{
+			name:     "csv formulas",
+			format:   FormatCSV,
+			infoType: events.InfoTypeEmail,
+			events: []events.MitigationEvent{{
+				Action:    events.ActionBlock,
+				InfoType:  events.InfoTypeEmail,
+				Time:      1738000060,
+				ElementID: "@SUM(A1)",
+				Info: events.EmailInfos{
+					Subject:    "=HYPERLINK(\"http://evil.example\")",
+					Sender:     "+33 1 23 45 67 89",
+					Recipients: []string{"-2+3", "john.doe@example.com"},
+				},
+			}},
+			want: "time,action,reason,info_type,element_id,subject,sender,recipients,malwares,gmalware_urls,quarantine_location,sha256,analysis_error,additional_info\n" +
+				`2025-01-27T17:47:40Z,block,,email,'@SUM(A1),"'=HYPERLINK(""http://evil.example"")",'+33 1 23 45 67 89,'-2+3|john.doe@example.com,,,,,,` + "\n",
+		},
+		{
+			name:     "csv no event",
+			format:   FormatCSV,
+			infoType: events.InfoTypeURL,
+			want:     "time,action,reason,info_type,element_id,method,url,content_length,content_type,malwares,gmalware_urls,quarantine_location,sha256,analysis_error,additional_info\n",
+		},
+		{
+			name:     "jsonl email",
+			format:   FormatJSONL,
+			infoType: events.InfoTypeEmail,
+			events:   []events.MitigationEvent{emailEvent},
+			want: `{"time":"2025-01-27T17:47:40Z","action":"block","reason":"phishing","info_type":"email","element_id":"AAMkAGI2TG93AAA=",` +
+				`"subject":"Your invoice","sender":"billing@example.net","recipients":["john.doe@example.com","jane.doe@example.com"],` +
+				`"malwares":null,"gmalware_urls":null,"quarantine_location":"","sha256":"","analysis_error":null,"additional_info":null}` + "\n",
+		},
+		{
+			name:     "info type mismatch",
+			format:   FormatJSONL,
+			infoType: events.InfoTypeFile,
+			events:   []events.MitigationEvent{emailEvent},
+			wantErr:  ErrInfoTypeMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			w, err := NewWriter(buf, tt.format, tt.infoType)
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+			for _, e := range tt.events {
+				if err = w.Write(e); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Write() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if err = w.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if diff := cmp.Diff(buf.String(), tt.want); diff != "" {
+				t.Errorf("Writer output diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewWriter_unknownFormat(t *testing.T) {
+	if _, err := NewWriter(bytes.NewBuffer(nil), "xml", events.InfoTypeFile); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("NewWriter() error = %v, want %v", err, ErrUnknownFormat)
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	history := bytes.NewBuffer(nil)
+	err := events.WriteNDJSON(history, fileEvent, emailEvent, events.ErrorEvent{Error: "x", Type: events.GMalwareError, Time: 1}, fileEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromTyped := bytes.NewBuffer(nil)
+	w, err := NewWriter(fromTyped, FormatCSV, events.InfoTypeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err = w.Write(fileEvent); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := bytes.NewBuffer(nil)
+	exported, err := ExportNDJSON(got, history, FormatCSV, events.InfoTypeFile)
+	if err != nil {
+		t.Fatalf("ExportNDJSON() error = %v", err)
+	}
+	if exported != 2 {
+		t.Errorf("ExportNDJSON() exported %d events, want 2", exported)
+	}
+	if diff := cmp.Diff(got.String(), fromTyped.String()); diff != "" {
+		t.Errorf("ExportNDJSON() diff(got-want)=%s", diff)
+	}
+}
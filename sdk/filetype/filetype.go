@@ -0,0 +1,212 @@
+// Package filetype detects file types (magic bytes, with file extension as fallback) and evaluates file type
+// policies, so connectors skip or block the same files the same way.
+package filetype
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// HeaderSize is the number of bytes of file content used to detect its type.
+const HeaderSize = 3072
+
+// Category groups file types, e.g. for policies to match all videos.
+type Category string
+
+const (
+	Executable Category = "executable"
+	Script     Category = "script"
+	Archive    Category = "archive"
+	Document   Category = "document"
+	Image      Category = "image"
+	Video      Category = "video"
+	Audio      Category = "audio"
+	Text       Category = "text"
+	// Unknown is the category of types not in any other one
+	Unknown Category = "unknown"
+)
+
+func (Category) Values() []Category {
+	return []Category{Executable, Script, Archive, Document, Image, Video, Audio, Text, Unknown}
+}
+
+// genericMIME is the type of content mimetype could not identify.
+const genericMIME = "application/octet-stream"
+
+var mimeCategories = map[string]Category{
+	"application/vnd.microsoft.portable-executable": Executable,
+	"application/x-elf":                             Executable,
+	"application/x-mach-binary":                     Executable,
+	"application/x-ms-installer":                    Executable,
+	"application/java-archive":                      Executable,
+	"application/vnd.android.package-archive":       Executable,
+	"application/x-java-applet":                     Executable,
+	"application/wasm":                              Executable,
+	"application/x-ms-shortcut":                     Executable,
+	"text/x-shellscript":                            Script,
+	"text/x-python":                                 Script,
+	"text/x-perl":                                   Script,
+	"text/x-php":                                    Script,
+	"text/x-ruby":                                   Script,
+	"text/x-lua":                                    Script,
+	"text/x-tcl":                                    Script,
+	"text/javascript":                               Script,
+	"application/x-bat":                             Script,
+	"application/x-powershell":                      Script,
+	"text/vbscript":                                 Script,
+	"application/zip":                               Archive,
+	"application/x-7z-compressed":                   Archive,
+	"application/x-rar-compressed":                  Archive,
+	"application/gzip":                              Archive,
+	"application/x-bzip2":                           Archive,
+	"application/x-xz":                              Archive,
+	"application/x-tar":                             Archive,
+	"application/zstd":                              Archive,
+	"application/vnd.ms-cab-compressed":             Archive,
+	"application/x-iso9660-image":                   Archive,
+	"application/pdf":                               Document,
+	"application/x-ole-storage":                     Document,
+	"application/msword":                            Document,
+	"application/vnd.ms-excel":                      Document,
+	"application/vnd.ms-powerpoint":                 Document,
+	"application/vnd.ms-outlook":                    Document,
+	"text/rtf":                                      Document,
+	"application/epub+zip":                          Document,
+}
+
+var mimePrefixCategories = []struct {
+	prefix   string
+	category Category
+}{
+	{"application/vnd.openxmlformats-officedocument.", Document},
+	{"application/vnd.ms-", Document},
+	{"application/vnd.oasis.opendocument.", Document},
+	{"image/", Image},
+	{"video/", Video},
+	{"audio/", Audio},
+	{"text/", Text},
+}
+
+// extensionMIMEs are types of extensions content detection cannot tell (e.g. scripts without shebang), preferred to
+// system MIME types.
+var extensionMIMEs = map[string]string{
+	"exe":  "application/vnd.microsoft.portable-executable",
+	"dll":  "application/vnd.microsoft.portable-executable",
+	"scr":  "application/vnd.microsoft.portable-executable",
+	"sys":  "application/vnd.microsoft.portable-executable",
+	"msi":  "application/x-ms-installer",
+	"lnk":  "application/x-ms-shortcut",
+	"bat":  "application/x-bat",
+	"cmd":  "application/x-bat",
+	"ps1":  "application/x-powershell",
+	"psm1": "application/x-powershell",
+	"vbs":  "text/vbscript",
+	"js":   "text/javascript",
+	"sh":   "text/x-shellscript",
+	"py":   "text/x-python",
+	"iso":  "application/x-iso9660-image",
+	"docm": "application/vnd.ms-word.document.macroenabled.12",
+	"xlsm": "application/vnd.ms-excel.sheet.macroenabled.12",
+	"pptm": "application/vnd.ms-powerpoint.presentation.macroenabled.12",
+}
+
+// FileType is the detected type of a file.
+type FileType struct {
+	// MIME is the media type, without parameters (e.g. video/mp4)
+	MIME     string
+	Category Category
+	// Extension is the lowercase extension of file name, without dot
+	Extension string
+	// FromContent reports whether type was detected from content, rather than from extension
+	FromContent bool
+}
+
+// Detect returns type of a file from the beginning of its content (HeaderSize bytes are enough), and from its name
+// if content is not identified. Plain text and zip content is refined by extension (e.g. scripts, macro enabled
+// office files), but not content of other types: an executable named .txt is an executable.
+func Detect(header []byte, filename string) (ft FileType) {
+	ft.Extension = extension(filename)
+	detected := mimetype.Detect(header)
+	if m := mediaType(detected.String()); m != genericMIME {
+		ft.MIME, ft.Category, ft.FromContent = m, Unknown, true
+		for parent := detected; parent != nil; parent = parent.Parent() {
+			if category, ok := categoryOf(mediaType(parent.String())); ok {
+				ft.Category = category
+				break
+			}
+		}
+	}
+	if ft.FromContent && ft.Category != Text && ft.Category != Archive {
+		return
+	}
+	if extMIME := extensionMIME(ft.Extension); extMIME != "" {
+		category, ok := categoryOf(extMIME)
+		if !ok {
+			category = Unknown
+		}
+		if !ft.FromContent || (ft.Category == Text && category == Script) || (ft.Category == Archive && category == Document) {
+			ft = FileType{MIME: extMIME, Category: category, Extension: ft.Extension}
+			return
+		}
+	}
+	if ft.MIME == "" {
+		ft.MIME, ft.Category = genericMIME, Unknown
+	}
+	return
+}
+
+// DetectReaderAt returns type of file r (see Detect).
+func DetectReaderAt(r io.ReaderAt, filename string) (ft FileType, err error) {
+	header := make([]byte, HeaderSize)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("could not read file header, %w", err)
+		return
+	}
+	err = nil
+	ft = Detect(header[:n], filename)
+	return
+}
+
+func extension(filename string) string {
+	// Windows paths
+	if i := strings.LastIndexAny(filename, `/\`); i >= 0 {
+		filename = filename[i+1:]
+	}
+	return strings.ToLower(strings.TrimPrefix(path.Ext(filename), "."))
+}
+
+func extensionMIME(ext string) string {
+	if ext == "" {
+		return ""
+	}
+	if m, ok := extensionMIMEs[ext]; ok {
+		return m
+	}
+	return mediaType(mime.TypeByExtension("." + ext))
+}
+
+// mediaType returns media type m without its parameters (e.g. charset).
+func mediaType(m string) string {
+	m, _, _ = strings.Cut(m, ";")
+	return strings.ToLower(strings.TrimSpace(m))
+}
+
+// categoryOf returns category of media type m, ok being false for types of no known category.
+func categoryOf(m string) (category Category, ok bool) {
+	if category, ok = mimeCategories[m]; ok {
+		return
+	}
+	for _, p := range mimePrefixCategories {
+		if strings.HasPrefix(m, p.prefix) {
+			category, ok = p.category, true
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,79 @@
+package filetype
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []byte
+		filename string
+		want     FileType
+	}{
+		{
+			name:     "PE executable",
+			header:   append([]byte("MZ"), make([]byte, 62)...),
+			filename: "setup.exe",
+			want:     FileType{MIME: "application/vnd.microsoft.portable-executable", Category: Executable, Extension: "exe", FromContent: true},
+		},
+		{
+			name:     "executable with misleading extension",
+			header:   []byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00"),
+			filename: `C:\Users\bob\report.TXT`,
+			want:     FileType{MIME: "application/x-executable", Category: Executable, Extension: "txt", FromContent: true},
+		},
+		{
+			name:     "shell script",
+			header:   []byte("#!/bin/sh\necho hello\n"),
+			filename: "run",
+			want:     FileType{MIME: "text/x-shellscript", Category: Script, FromContent: true},
+		},
+		{
+			name:     "plain text refined by extension",
+			header:   []byte("Write-Host hello\r\n"),
+			filename: "/tmp/run.ps1",
+			want:     FileType{MIME: "application/x-powershell", Category: Script, Extension: "ps1"},
+		},
+		{
+			name:     "plain text",
+			header:   []byte("hello world\n"),
+			filename: "notes.txt",
+			want:     FileType{MIME: "text/plain", Category: Text, Extension: "txt", FromContent: true},
+		},
+		{
+			name:     "video",
+			header:   []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"),
+			filename: "movie.mp4",
+			want:     FileType{MIME: "video/mp4", Category: Video, Extension: "mp4", FromContent: true},
+		},
+		{
+			name:     "pdf",
+			header:   []byte("%PDF-1.7\n"),
+			filename: "doc.pdf",
+			want:     FileType{MIME: "application/pdf", Category: Document, Extension: "pdf", FromContent: true},
+		},
+		{
+			name:     "unknown content, extension fallback",
+			header:   bytes.Repeat([]byte{0x00, 0xff}, 16),
+			filename: "installer.msi",
+			want:     FileType{MIME: "application/x-ms-installer", Category: Executable, Extension: "msi"},
+		},
+		{
+			name:   "unknown",
+			header: bytes.Repeat([]byte{0x00, 0xff}, 16),
+			want:   FileType{MIME: "application/octet-stream", Category: Unknown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(tt.header, tt.filename)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Detect() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
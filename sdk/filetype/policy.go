@@ -0,0 +1,100 @@
+package filetype
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/glimps-re/connector-integration/sdk/validation"
+)
+
+// Action is what a policy decides for a file.
+type Action string
+
+const (
+	// analyze file
+	ActionScan Action = "scan"
+	// let file through without analysis
+	ActionSkip Action = "skip"
+	// block file without analysis
+	ActionBlock Action = "block"
+)
+
+func (Action) Values() []Action {
+	return []Action{ActionScan, ActionSkip, ActionBlock}
+}
+
+// ActionTag is the validator tag validating an Action.
+const ActionTag = "filetype_action"
+
+func (Action) Validation() validation.EnumValidation {
+	return validation.NewEnumValidation(Action("").Values())
+}
+
+// Policy decides per file type whether files are analyzed, skipped or blocked,
+// e.g. skip videos, always block executables from temp directories.
+type Policy struct {
+	Default Action `json:"default" yaml:"default" mapstructure:"default" validate:"omitempty,filetype_action" desc:"Action on files matching no rule: scan, skip or block (default: scan)"`
+	Rules   []Rule `json:"rules" yaml:"rules" mapstructure:"rules" validate:"omitempty,dive" desc:"File type rules, the first rule matching a file decides its action" default:"[]"`
+}
+
+// Rule applies Action to files matching all its set criteria, a rule without criteria matching every file.
+type Rule struct {
+	Action Action   `json:"action" yaml:"action" mapstructure:"action" validate:"required,filetype_action" desc:"Action on files matching rule: scan, skip or block"`
+	Types  []string `json:"types" yaml:"types" mapstructure:"types" desc:"Match files of one of these categories (executable, script, archive, document, image, video, audio, text, unknown) or MIME types, * wildcards accepted (e.g. video/*)" default:"[]"`
+	Paths  []string `json:"paths" yaml:"paths" mapstructure:"paths" desc:"Match files under one of these directories, case insensitive, * wildcards accepted (e.g. /tmp, C:\\Users\\*\\AppData\\Local\\Temp)" default:"[]"`
+}
+
+func (r Rule) matches(ft FileType, filePath string) bool {
+	if len(r.Types) > 0 && !slices.ContainsFunc(r.Types, func(t string) bool {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == string(ft.Category) {
+			return true
+		}
+		ok, err := path.Match(t, ft.MIME)
+		return err == nil && ok
+	}) {
+		return false
+	}
+	if len(r.Paths) > 0 && !slices.ContainsFunc(r.Paths, func(dir string) bool {
+		return underDir(filePath, dir)
+	}) {
+		return false
+	}
+	return true
+}
+
+// normalizePath returns lowercase p with slash separators and no trailing slash, so Windows and Unix paths compare.
+func normalizePath(p string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.ReplaceAll(p, `\`, "/")), "/")
+}
+
+// underDir reports whether filePath is in directory dir (or one of its subdirectories), dir segments may hold
+// * wildcards.
+func underDir(filePath string, dir string) bool {
+	fileSegments := strings.Split(normalizePath(filePath), "/")
+	dirSegments := strings.Split(normalizePath(dir), "/")
+	if len(dirSegments) >= len(fileSegments) {
+		return false
+	}
+	for i, segment := range dirSegments {
+		if ok, err := path.Match(segment, fileSegments[i]); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Evaluate returns action of first rule (in policy order) matching file of type ft at filePath, policy default if
+// none matches.
+func (p Policy) Evaluate(ft FileType, filePath string) (action Action) {
+	for _, r := range p.Rules {
+		if r.matches(ft, filePath) {
+			return r.Action
+		}
+	}
+	if p.Default != "" {
+		return p.Default
+	}
+	return ActionScan
+}
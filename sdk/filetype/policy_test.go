@@ -0,0 +1,40 @@
+package filetype
+
+import "testing"
+
+func TestPolicy_Evaluate(t *testing.T) {
+	policy := Policy{
+		Rules: []Rule{
+			{Action: ActionBlock, Types: []string{"executable", "script"}, Paths: []string{"/tmp", `C:\Users\*\AppData\Local\Temp`}},
+			{Action: ActionSkip, Types: []string{"video/*"}},
+			{Action: ActionSkip, Types: []string{"image"}, Paths: []string{"/data/photos"}},
+		},
+	}
+	exe := FileType{MIME: "application/vnd.microsoft.portable-executable", Category: Executable}
+	video := FileType{MIME: "video/mp4", Category: Video}
+	image := FileType{MIME: "image/png", Category: Image}
+	tests := []struct {
+		name     string
+		policy   Policy
+		ft       FileType
+		filePath string
+		want     Action
+	}{
+		{name: "executable in temp dir", policy: policy, ft: exe, filePath: "/tmp/x/setup.exe", want: ActionBlock},
+		{name: "executable in windows temp dir", policy: policy, ft: exe, filePath: `c:\users\bob\appdata\local\temp\setup.exe`, want: ActionBlock},
+		{name: "executable elsewhere", policy: policy, ft: exe, filePath: "/home/bob/tmp/setup.exe", want: ActionScan},
+		{name: "temp dir itself is not under it", policy: policy, ft: exe, filePath: "/tmp", want: ActionScan},
+		{name: "video", policy: policy, ft: video, filePath: "/tmp/movie.mp4", want: ActionSkip},
+		{name: "image in photos", policy: policy, ft: image, filePath: "/data/photos/2024/a.png", want: ActionSkip},
+		{name: "image elsewhere", policy: policy, ft: image, filePath: "/data/a.png", want: ActionScan},
+		{name: "policy default", policy: Policy{Default: ActionSkip}, ft: exe, filePath: "/tmp/setup.exe", want: ActionSkip},
+		{name: "empty policy", ft: exe, filePath: "/tmp/setup.exe", want: ActionScan},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Evaluate(tt.ft, tt.filePath); got != tt.want {
+				t.Errorf("Evaluate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package sdk
+
+import (
+	"context"
+	"maps"
+)
+
+// FeatureFlagsListener may be implemented by connectors to be notified when console changes feature flags.
+type FeatureFlagsListener interface {
+	// FeatureFlagsChanged is called with all feature flags, before connector is reconfigured.
+	FeatureFlagsChanged(ctx context.Context, flags map[string]bool)
+}
+
+// FeatureFlags returns feature flags set by console, used to toggle experimental connector behaviors per customer.
+func (c ConnectorManagerClient) FeatureFlags() (flags map[string]bool) {
+	flags = make(map[string]bool)
+	if current := c.featureFlags.Load(); current != nil {
+		maps.Copy(flags, *current)
+	}
+	return
+}
+
+// FeatureEnabled reports whether console enabled feature flag name, unknown flags are disabled.
+func (c ConnectorManagerClient) FeatureEnabled(name string) (enabled bool) {
+	if current := c.featureFlags.Load(); current != nil {
+		enabled = (*current)[name]
+	}
+	return
+}
+
+func (c ConnectorManagerClient) storeFeatureFlags(flags map[string]bool) (changed bool) {
+	flags = maps.Clone(flags)
+	if flags == nil {
+		flags = make(map[string]bool)
+	}
+	previous := c.featureFlags.Swap(&flags)
+	changed = previous == nil || !maps.Equal(*previous, flags)
+	return
+}
+
+// updateFeatureFlags stores flags fetched with config and notifies connector if they changed.
+func (c ConnectorManagerClient) updateFeatureFlags(ctx context.Context, connector Connector, flags map[string]bool) {
+	if !c.storeFeatureFlags(flags) {
+		return
+	}
+	if listener, ok := connector.(FeatureFlagsListener); ok {
+		listener.FeatureFlagsChanged(ctx, c.FeatureFlags())
+	}
+}
@@ -0,0 +1,62 @@
+package sdk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type flagsRecorder struct {
+	fakeConnector
+	changes []map[string]bool
+}
+
+func (c *flagsRecorder) FeatureFlagsChanged(ctx context.Context, flags map[string]bool) {
+	c.changes = append(c.changes, flags)
+}
+
+func TestConnectorManagerClient_updateFeatureFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		registered  map[string]bool
+		updates     []map[string]bool
+		wantChanges []map[string]bool
+		wantEnabled bool
+	}{
+		{
+			name:        "set by update",
+			updates:     []map[string]bool{{"new-parser": true}},
+			wantChanges: []map[string]bool{{"new-parser": true}},
+			wantEnabled: true,
+		},
+		{
+			name:        "unchanged since registration",
+			registered:  map[string]bool{"new-parser": true},
+			updates:     []map[string]bool{{"new-parser": true}},
+			wantEnabled: true,
+		},
+		{
+			name:        "disabled then removed",
+			registered:  map[string]bool{"new-parser": true},
+			updates:     []map[string]bool{{"new-parser": false}, nil, {}},
+			wantChanges: []map[string]bool{{"new-parser": false}, {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: "http://127.0.0.1:0", APIKey: "key"})
+			c.storeFeatureFlags(tt.registered)
+			connector := &flagsRecorder{}
+			for _, flags := range tt.updates {
+				c.updateFeatureFlags(t.Context(), connector, flags)
+			}
+			if diff := cmp.Diff(connector.changes, tt.wantChanges); diff != "" {
+				t.Errorf("FeatureFlagsChanged() calls diff(got-want)=%s", diff)
+			}
+			if got := c.FeatureEnabled("new-parser"); got != tt.wantEnabled {
+				t.Errorf("FeatureEnabled() = %v, want %v", got, tt.wantEnabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultHealthPort is the port health endpoints listen to by default, probed by generated deployments (see
+	// CapabilityHealth).
+	DefaultHealthPort = 8080
+	// DefaultHealthAddress is the default listen address of health endpoints, on DefaultHealthPort.
+	DefaultHealthAddress = ":8080"
+	// DefaultManagerTimeout is the default delay without manager answer after which a connector is not ready.
+	DefaultManagerTimeout = 5 * time.Minute
+	// DefaultMetricsPort is the port metrics listen to by default, scraped by generated deployments (see
+	// CapabilityHealth).
+	DefaultMetricsPort = 9090
+	// DefaultMetricsAddress is the default listen address of metrics, on DefaultMetricsPort.
+	DefaultMetricsAddress = ":9090"
+)
+
+// Paths of health endpoints.
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+	// MetricsPath serves MetricsHandler on metrics address, for Prometheus scrapes.
+	MetricsPath = "/metrics"
+)
+
+type HealthOptions struct {
+	// Enabled serves health endpoints (see ConnectorManagerClient.HealthHandler), for orchestrator probes and load
+	// balancers.
+	Enabled bool
+	// Address health endpoints listen to, DefaultHealthAddress if empty. Unlike debug and admin servers, it is not
+	// bound to localhost by default, for probes to reach it.
+	Address string
+	// ManagerTimeout is the delay without manager answer after which connector is not ready,
+	// DefaultManagerTimeout if 0.
+	ManagerTimeout time.Duration
+	// MetricsAddress MetricsHandler listens to, on MetricsPath, DefaultMetricsAddress if empty. Metrics expose
+	// connector internals (e.g. manager requests by endpoint), they are served apart from health endpoints, for
+	// these to be exposed without them.
+	MetricsAddress string
+}
+
+type healthReport struct {
+	Status      string `json:"status" desc:"ok or unavailable"`
+	Connector   string `json:"connector" desc:"connector status, unknown until connector is built"`
+	Manager     string `json:"manager,omitempty" desc:"reachable or unreachable, readiness only"`
+	LastContact int64  `json:"last_contact,omitempty" desc:"unix timestamp of last manager answer"`
+	Offline     bool   `json:"offline,omitempty" desc:"connector started from cached console config, not registered yet"`
+	Breaker     string `json:"breaker,omitempty" desc:"open or half-open when requests to manager fail fast, readiness only"`
+}
+
+// HealthHandler serves health endpoints, answering 200 when healthy and 503 otherwise:
+//   - GET /healthz: liveness, unhealthy for degraded connectors (e.g. stalled ones, see Watchdog), so they are
+//     restarted. Stopped connectors are alive.
+//   - GET /readyz: readiness, ready once connector is built and started, and manager answered within
+//     managerTimeout (DefaultManagerTimeout if 0).
+//
+// connector returns the connector, nil until it is built. Endpoints expose no connector internals, they may be
+// served publicly (e.g. on connector webhook server).
+func (c ConnectorManagerClient) HealthHandler(connector func() Connector, managerTimeout time.Duration) http.Handler {
+	if managerTimeout <= 0 {
+		managerTimeout = DefaultManagerTimeout
+	}
+	status := func() (report healthReport) {
+		report.Connector = "unknown"
+		if conn := connector(); conn != nil {
+			report.Connector = conn.Status().String()
+		}
+		report.Offline = !c.OfflineSince().IsZero()
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+LivenessPath, func(w http.ResponseWriter, r *http.Request) {
+		report := status()
+		code := http.StatusOK
+		report.Status = "ok"
+		if report.Connector == Degraded.String() {
+			code = http.StatusServiceUnavailable
+			report.Status = "unavailable"
+		}
+		writeHealth(w, code, report)
+	})
+	mux.HandleFunc("GET "+ReadinessPath, func(w http.ResponseWriter, r *http.Request) {
+		report := status()
+		report.Manager = "unreachable"
+		if state := c.BreakerState(); state != BreakerClosed {
+			report.Breaker = state.String()
+		}
+		if last := c.metricsCollector.Client().LastContact(); !last.IsZero() {
+			report.LastContact = last.Unix()
+			if time.Since(last) <= managerTimeout {
+				report.Manager = "reachable"
+			}
+		}
+		code := http.StatusOK
+		report.Status = "ok"
+		if report.Connector != Started.String() || report.Manager != "reachable" {
+			code = http.StatusServiceUnavailable
+			report.Status = "unavailable"
+		}
+		writeHealth(w, code, report)
+	})
+	return mux
+}
+
+// ServeHealth serves HealthHandler on opts Address, and MetricsHandler on MetricsPath of opts MetricsAddress, until
+// ctx is done. It is started by Run with RunOptions.Health, connectors not run by Run may start it themselves.
+func (c ConnectorManagerClient) ServeHealth(ctx context.Context, opts HealthOptions, connector func() Connector) (err error) {
+	if opts.Address == "" {
+		opts.Address = DefaultHealthAddress
+	}
+	if opts.MetricsAddress == "" {
+		opts.MetricsAddress = DefaultMetricsAddress
+	}
+	if err = serve(ctx, "health", opts.Address, c.HealthHandler(connector, opts.ManagerTimeout)); err != nil {
+		return
+	}
+	metrics := http.NewServeMux()
+	metrics.Handle("GET "+MetricsPath, c.MetricsHandler())
+	err = serve(ctx, "metrics", opts.MetricsAddress, metrics)
+	return
+}
+
+// builtConnector returns connector stored in built, nil until it is.
+func builtConnector(built *atomic.Pointer[Connector]) func() Connector {
+	return func() (connector Connector) {
+		if p := built.Load(); p != nil {
+			connector = *p
+		}
+		return
+	}
+}
+
+func writeHealth(w http.ResponseWriter, code int, report healthReport) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.Warn("could not write health response", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,162 @@
+package sdk
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type statusConnector struct {
+	fakeConnector
+	status ConnectorStatus
+}
+
+func (c *statusConnector) Status() (status ConnectorStatus) { return c.status }
+
+func TestConnectorManagerClient_HealthHandler(t *testing.T) {
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: "http://manager.example.com", APIKey: "key"})
+	var connector Connector
+	handler := c.HealthHandler(func() Connector { return connector }, 0)
+	staleHandler := c.HealthHandler(func() Connector { return connector }, time.Nanosecond)
+
+	type probe struct {
+		path       string
+		wantStatus int
+		wantReport healthReport
+	}
+	tests := []struct {
+		name    string
+		setup   func()
+		handler http.Handler
+		probes  []probe
+	}{
+		{
+			name:    "connector not built",
+			handler: handler,
+			probes: []probe{
+				{path: "/healthz", wantStatus: http.StatusOK, wantReport: healthReport{Status: "ok", Connector: "unknown"}},
+				{path: "/readyz", wantStatus: http.StatusServiceUnavailable, wantReport: healthReport{Status: "unavailable", Connector: "unknown", Manager: "unreachable"}},
+			},
+		},
+		{
+			name:    "manager never answered",
+			setup:   func() { connector = &statusConnector{status: Started} },
+			handler: handler,
+			probes: []probe{
+				{path: "/healthz", wantStatus: http.StatusOK, wantReport: healthReport{Status: "ok", Connector: "started"}},
+				{path: "/readyz", wantStatus: http.StatusServiceUnavailable, wantReport: healthReport{Status: "unavailable", Connector: "started", Manager: "unreachable"}},
+			},
+		},
+		{
+			name: "ready",
+			setup: func() {
+				c.metricsCollector.Client().ObserveRequest("tasks", http.StatusUnauthorized, time.Millisecond)
+				c.metricsCollector.Client().ObserveRequest("tasks", http.StatusNoContent, time.Millisecond)
+			},
+			handler: handler,
+			probes: []probe{
+				{path: "/healthz", wantStatus: http.StatusOK, wantReport: healthReport{Status: "ok", Connector: "started"}},
+				{path: "/readyz", wantStatus: http.StatusOK, wantReport: healthReport{Status: "ok", Connector: "started", Manager: "reachable"}},
+			},
+		},
+		{
+			name:    "manager unreachable",
+			handler: staleHandler,
+			probes: []probe{
+				{path: "/readyz", wantStatus: http.StatusServiceUnavailable, wantReport: healthReport{Status: "unavailable", Connector: "started", Manager: "unreachable"}},
+			},
+		},
+		{
+			name:    "stopped",
+			setup:   func() { connector = &statusConnector{status: Stopped} },
+			handler: handler,
+			probes: []probe{
+				{path: "/healthz", wantStatus: http.StatusOK, wantReport: healthReport{Status: "ok", Connector: "stopped"}},
+				{path: "/readyz", wantStatus: http.StatusServiceUnavailable, wantReport: healthReport{Status: "unavailable", Connector: "stopped", Manager: "reachable"}},
+			},
+		},
+		{
+			name:    "degraded",
+			setup:   func() { connector = &statusConnector{status: Degraded} },
+			handler: handler,
+			probes: []probe{
+				{path: "/healthz", wantStatus: http.StatusServiceUnavailable, wantReport: healthReport{Status: "unavailable", Connector: "degraded"}},
+				{path: "/readyz", wantStatus: http.StatusServiceUnavailable, wantReport: healthReport{Status: "unavailable", Connector: "degraded", Manager: "reachable"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+			for _, p := range tt.probes {
+				rec := httptest.NewRecorder()
+				tt.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, p.path, nil))
+				if rec.Code != p.wantStatus {
+					t.Errorf("GET %s status = %d, want %d", p.path, rec.Code, p.wantStatus)
+				}
+				got := healthReport{}
+				if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+					t.Fatalf("GET %s invalid body %q, error = %v", p.path, rec.Body.String(), err)
+				}
+				if last := c.metricsCollector.Client().LastContact(); p.wantReport.Manager != "" && !last.IsZero() && last.Unix() != got.LastContact {
+					t.Errorf("GET %s last contact = %d, want %d", p.path, got.LastContact, last.Unix())
+				}
+				got.LastContact = 0
+				if diff := cmp.Diff(got, p.wantReport); diff != "" {
+					t.Errorf("GET %s diff(got-want)=%s", p.path, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestConnectorManagerClient_ServeHealth(t *testing.T) {
+	healthAddr, metricsAddr := freeAddr(t), freeAddr(t)
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: "http://manager.example.com", APIKey: "key"})
+	opts := HealthOptions{Address: healthAddr, MetricsAddress: metricsAddr}
+	if err := c.ServeHealth(t.Context(), opts, func() Connector { return nil }); err != nil {
+		t.Fatalf("ServeHealth() error = %v", err)
+	}
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{name: "liveness", url: "http://" + healthAddr + LivenessPath, wantStatus: http.StatusOK},
+		{name: "no metrics on health address", url: "http://" + healthAddr + MetricsPath, wantStatus: http.StatusNotFound},
+		{name: "metrics", url: "http://" + metricsAddr + MetricsPath, wantStatus: http.StatusOK},
+		{name: "no health on metrics address", url: "http://" + metricsAddr + LivenessPath, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(tt.url)
+			if err != nil {
+				t.Fatalf("GET %s error = %v", tt.url, err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("GET %s status = %d, want %d", tt.url, resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// freeAddr returns a localhost address free to listen to.
+func freeAddr(t *testing.T) (addr string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr = listener.Addr().String()
+	if err = listener.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
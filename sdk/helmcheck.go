@@ -0,0 +1,217 @@
+package sdk
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidHelmChart = errors.New("invalid helm chart")
+
+// chartVersion returns version of Chart.yaml at the root of chart archive (e.g. sharepoint/Chart.yaml).
+func chartVersion(chartPath string) (version string, err error) {
+	rawChart, err := chartFile(chartPath)
+	if err != nil {
+		return
+	}
+	chart := struct {
+		Version string `yaml:"version"`
+	}{}
+	if err = yaml.Unmarshal(rawChart, &chart); err != nil {
+		err = fmt.Errorf("%w: invalid Chart.yaml in %s, %w", ErrInvalidHelmChart, chartPath, err)
+		return
+	}
+	version = chart.Version
+	return
+}
+
+// chartFile returns Chart.yaml at the root of chart archive.
+func chartFile(chartPath string) (rawChart []byte, err error) {
+	f, err := configFS.Open(chartPath)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		err = fmt.Errorf("%w: %s is not a gzip archive, %w", ErrInvalidHelmChart, chartPath, err)
+		return
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, nextErr := tr.Next()
+		if errors.Is(nextErr, io.EOF) {
+			err = fmt.Errorf("%w: no Chart.yaml in %s", ErrInvalidHelmChart, chartPath)
+			return
+		}
+		if nextErr != nil {
+			err = fmt.Errorf("%w: could not read %s, %w", ErrInvalidHelmChart, chartPath, nextErr)
+			return
+		}
+		if parts := strings.Split(path.Clean(header.Name), "/"); len(parts) != 2 || parts[1] != "Chart.yaml" {
+			continue
+		}
+		if rawChart, err = io.ReadAll(tr); err != nil {
+			err = fmt.Errorf("%w: could not read %s, %w", ErrInvalidHelmChart, chartPath, err)
+		}
+		return
+	}
+}
+
+// checkHelmValues checks every field used by values template resolves against helm config of connector type
+// (see ConfigHelmer), whichever branches are taken when templating it.
+func checkHelmValues(connectorTypeID string, rawValues []byte) (err error) {
+	config, err := InitDefault(connectorTypeID)
+	if err != nil {
+		return
+	}
+	helmer, ok := config.(ConfigHelmer)
+	if !ok {
+		err = fmt.Errorf("%w: %s config does not implement ConfigHelmer", ErrInvalidHelmChart, connectorTypeID)
+		return
+	}
+	helmConfig, err := helmer.GetHelmConfig(ConsoleConfig{})
+	if err != nil {
+		return
+	}
+	tmpl, err := template.New(helmValuesFileName).Parse(string(rawValues))
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidHelmChart, err)
+		return
+	}
+	checker := templateChecker{tree: tmpl.Tree, root: reflect.TypeOf(helmConfig)}
+	if err = checker.check(tmpl.Root, checker.root); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidHelmChart, err)
+	}
+	return
+}
+
+// templateChecker checks fields used by a template tree resolve against root type, the type template is executed with.
+type templateChecker struct {
+	tree *parse.Tree
+	root reflect.Type
+}
+
+// check checks fields used in node resolve against dot type, nil dot types (e.g. interfaces) being unchecked.
+func (c templateChecker) check(node parse.Node, dot reflect.Type) (err error) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			if err = c.check(child, dot); err != nil {
+				return
+			}
+		}
+	case *parse.ActionNode:
+		err = c.check(n.Pipe, dot)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				if err = c.check(arg, dot); err != nil {
+					return
+				}
+			}
+		}
+	case *parse.FieldNode:
+		_, err = c.fieldType(n, dot, n.Ident)
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" {
+			_, err = c.fieldType(n, c.root, n.Ident[1:])
+		}
+	case *parse.IfNode:
+		err = c.checkBranch(&n.BranchNode, dot, dot)
+	case *parse.WithNode:
+		err = c.checkBranch(&n.BranchNode, c.pipeType(n.Pipe, dot), dot)
+	case *parse.RangeNode:
+		elem := c.pipeType(n.Pipe, dot)
+		switch {
+		case elem == nil:
+		case elem.Kind() == reflect.Slice, elem.Kind() == reflect.Array, elem.Kind() == reflect.Map:
+			elem = elem.Elem()
+		default:
+			elem = nil
+		}
+		err = c.checkBranch(&n.BranchNode, elem, dot)
+	}
+	return
+}
+
+// checkBranch checks if, with and range nodes, bodyDot being dot type of their body.
+func (c templateChecker) checkBranch(n *parse.BranchNode, bodyDot reflect.Type, dot reflect.Type) (err error) {
+	if err = c.check(n.Pipe, dot); err != nil {
+		return
+	}
+	if err = c.check(n.List, bodyDot); err != nil {
+		return
+	}
+	if n.ElseList != nil {
+		err = c.check(n.ElseList, dot)
+	}
+	return
+}
+
+// pipeType returns type of pipe made of a single field, nil if unknown.
+func (c templateChecker) pipeType(pipe *parse.PipeNode, dot reflect.Type) (t reflect.Type) {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 || len(pipe.Decl) > 0 {
+		return
+	}
+	field, ok := pipe.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok {
+		return
+	}
+	t, _ = c.fieldType(field, dot, field.Ident)
+	return
+}
+
+// fieldType returns type of chained fields or methods idents of t, nil if it could not be known.
+func (c templateChecker) fieldType(node parse.Node, t reflect.Type, idents []string) (result reflect.Type, err error) {
+	location, _ := c.tree.ErrorContext(node)
+	for _, ident := range idents {
+		if t == nil {
+			return
+		}
+		if method, ok := t.MethodByName(ident); ok {
+			if method.Type.NumOut() == 0 {
+				err = fmt.Errorf("%s: method %s of %s returns nothing", location, ident, t)
+				return
+			}
+			t = method.Type.Out(0)
+			continue
+		}
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := t.FieldByName(ident)
+			if !ok || !field.IsExported() {
+				err = fmt.Errorf("%s: field %s not found in %s", location, ident, t)
+				return
+			}
+			t = field.Type
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Interface:
+			t = nil
+		default:
+			err = fmt.Errorf("%s: can't evaluate field %s in %s", location, ident, t)
+			return
+		}
+	}
+	result = t
+	return
+}
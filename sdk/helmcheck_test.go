@@ -0,0 +1,123 @@
+package sdk
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+type testHelmImage struct {
+	Tag string
+}
+
+type testHelmConfig struct {
+	APIKey string
+	Image  testHelmImage
+	Hosts  []testHelmImage
+	Labels map[string]string
+	Extra  any
+}
+
+func (testHelmConfig) Insecure() bool { return false }
+
+func Test_templateChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  string
+		wantErr string
+	}{
+		{name: "fields", values: "key: {{ .APIKey }}\ntag: {{ .Image.Tag }}\ninsecure: {{ .Insecure }}"},
+		{name: "unknown field", values: "key: {{ .APIKey }}\nurl: {{ .URL }}", wantErr: "values.yaml:2:8: field URL not found in sdk.testHelmConfig"},
+		{name: "unknown nested field", values: "registry: {{ .Image.Registry }}", wantErr: "field Registry not found in sdk.testHelmImage"},
+		{name: "field in branch not taken", values: "{{ if .APIKey }}key: yes{{ else }}{{ .Token }}{{ end }}", wantErr: "field Token not found"},
+		{name: "with", values: "{{ with .Image }}tag: {{ .Tag }} key: {{ $.APIKey }}{{ end }}"},
+		{name: "with unknown field", values: "{{ with .Image }}{{ .APIKey }}{{ end }}", wantErr: "field APIKey not found in sdk.testHelmImage"},
+		{name: "root variable unknown field", values: "{{ with .Image }}{{ $.Tag }}{{ end }}", wantErr: "field Tag not found in sdk.testHelmConfig"},
+		{name: "range", values: "{{ range .Hosts }}- {{ .Tag }}{{ end }}{{ range $k, $v := .Labels }}{{ $k }}: {{ $v }}{{ end }}"},
+		{name: "range unknown field", values: "{{ range .Hosts }}{{ .Registry }}{{ end }}", wantErr: "field Registry not found"},
+		{name: "map and interface", values: "{{ .Labels.app }} {{ .Extra.Anything }}"},
+		{name: "field of string", values: "{{ .APIKey.Value }}", wantErr: "can't evaluate field Value in string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New(helmValuesFileName).Parse(tt.values)
+			if err != nil {
+				t.Fatalf("could not parse template, error: %v", err)
+			}
+			c := templateChecker{tree: tmpl.Tree, root: reflect.TypeFor[testHelmConfig]()}
+			err = c.check(tmpl.Root, c.root)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("check() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("check() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_checkHelmValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  string
+		wantErr error
+	}{
+		{name: "ok", values: "api-key: {{ .APIKey }}\n{{ with .Image.Tag }}version: {{ . }}{{ end }}"},
+		{name: "unknown field", values: "api-key: {{ .ApiKey }}", wantErr: ErrInvalidHelmChart},
+		{name: "invalid template", values: "api-key: {{ .APIKey ", wantErr: ErrInvalidHelmChart},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkHelmValues("dummy", []byte(tt.values)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkHelmValues() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_chartVersion(t *testing.T) {
+	for _, id := range []string{"dummy", "sharepoint"} {
+		t.Run(id, func(t *testing.T) {
+			got, err := chartVersion(filepath.Join(connectorsFolderName, id, helmFolderName, id+"-0.1.0.tgz"))
+			if err != nil {
+				t.Fatalf("chartVersion() error = %v", err)
+			}
+			if got != "0.1.0" {
+				t.Errorf("chartVersion() = %s, want 0.1.0", got)
+			}
+		})
+	}
+	if _, err := chartVersion(filepath.Join(connectorsFolderName, "dummy", helmFolderName, helmValuesFileName)); !errors.Is(err, ErrInvalidHelmChart) {
+		t.Errorf("chartVersion() error = %v, want ErrInvalidHelmChart", err)
+	}
+}
+
+func Test_helmChartVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		wantVersion string
+		wantOK      bool
+	}{
+		{name: "chart", file: "dummy-0.1.0.tgz", wantVersion: "0.1.0", wantOK: true},
+		{name: "other chart", file: "other-dummy-0.1.0.tgz"},
+		{name: "id prefix", file: "dummy2-0.1.0.tgz"},
+		{name: "invalid version", file: "dummy-0.1.tgz"},
+		{name: "not an archive", file: "dummy-0.1.0.tar"},
+		{name: "values", file: "values-0.1.0.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, ok := helmChartVersion("dummy", tt.file)
+			if version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("helmChartVersion() = %s, %v, want %s, %v", version, ok, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
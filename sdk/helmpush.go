@@ -0,0 +1,424 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// media types of helm charts pushed to OCI registries, as helm push does
+const (
+	ociManifestMediaType   = "application/vnd.oci.image.manifest.v1+json"
+	ociEmptyMediaType      = "application/vnd.oci.empty.v1+json"
+	helmConfigMediaType    = "application/vnd.cncf.helm.config.v1+json"
+	helmChartMediaType     = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	helmValuesMediaType    = "application/yaml"
+	helmValuesArtifactType = "application/vnd.glimps.connector.helm.values.v1"
+)
+
+var ErrRegistryPush = errors.New("could not push to OCI registry")
+
+// RegistryCredentials authenticates pushes to an OCI registry, with basic auth or the token it issues for them
+// (e.g. Harbor, distribution), anonymous if empty.
+type RegistryCredentials struct {
+	Username string
+	Password string
+}
+
+// PushedChart is a helm chart pushed to an OCI registry by PushHelmChart.
+type PushedChart struct {
+	// Reference of pushed chart, e.g. registry.example.com/charts/onedrive-sharepoint:0.1.0
+	Reference string `json:"reference"`
+	// Digest of chart manifest
+	Digest string `json:"digest"`
+	// ValuesDigest of default values artifact, referring to chart manifest (e.g. oras discover <Reference>)
+	ValuesDigest string `json:"values_digest"`
+}
+
+// PushHelmChart pushes latest helm chart of connector type to OCI registry at registryURL (e.g.
+// oci://registry.example.com/charts), so that clusters without internet access pull it with
+// helm pull oci://registry.example.com/charts/<chart name> --version <version>.
+// Default values rendered for the connector type are pushed next to it as an artifact referring to the chart.
+func (c ConnectorTypeLoader) PushHelmChart(ctx context.Context, connectorTypeID string, registryURL string, creds RegistryCredentials) (pushed PushedChart, err error) {
+	connectorType, ok := c.connectorsTypes[connectorTypeID]
+	if !ok {
+		err = ErrConnectorTypeNotFound
+		return
+	}
+	if !connectorType.Helm {
+		err = ErrNoHelmForConnector
+		return
+	}
+	chartPath := filepath.Join(connectorsFolderName, connectorTypeID, helmFolderName, fmt.Sprintf("%v-%v.tgz", connectorTypeID, connectorType.HelmVersion))
+	rawChart, err := configFS.ReadFile(chartPath)
+	if err != nil {
+		return
+	}
+	rawChartFile, err := chartFile(chartPath)
+	if err != nil {
+		return
+	}
+	chartMetadata := map[string]any{}
+	if err = yaml.Unmarshal(rawChartFile, &chartMetadata); err != nil {
+		err = fmt.Errorf("%w: invalid Chart.yaml in %s, %w", ErrInvalidHelmChart, chartPath, err)
+		return
+	}
+	chartName, _ := chartMetadata["name"].(string)
+	if chartName == "" {
+		err = fmt.Errorf("%w: no name in %s Chart.yaml", ErrInvalidHelmChart, chartPath)
+		return
+	}
+	rawMetadata, err := json.Marshal(chartMetadata)
+	if err != nil {
+		return
+	}
+	values, err := c.defaultHelmValues(connectorTypeID)
+	if err != nil {
+		return
+	}
+
+	registry, err := newRegistryClient(registryURL, chartName, creds)
+	if err != nil {
+		return
+	}
+	chartManifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		Config:        newOCIDescriptor(helmConfigMediaType, rawMetadata),
+		Layers:        []ociDescriptor{newOCIDescriptor(helmChartMediaType, rawChart)},
+		Annotations: map[string]string{
+			"org.opencontainers.image.title":   chartName,
+			"org.opencontainers.image.version": connectorType.HelmVersion,
+		},
+	}
+	if err = registry.pushBlob(ctx, chartManifest.Config, rawMetadata); err != nil {
+		return
+	}
+	if err = registry.pushBlob(ctx, chartManifest.Layers[0], rawChart); err != nil {
+		return
+	}
+	chartDescriptor, err := registry.pushManifest(ctx, connectorType.HelmVersion, chartManifest)
+	if err != nil {
+		return
+	}
+
+	emptyConfig := []byte("{}")
+	valuesLayer := newOCIDescriptor(helmValuesMediaType, values)
+	valuesLayer.Annotations = map[string]string{"org.opencontainers.image.title": helmValuesFileName}
+	valuesManifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		ArtifactType:  helmValuesArtifactType,
+		Config:        newOCIDescriptor(ociEmptyMediaType, emptyConfig),
+		Layers:        []ociDescriptor{valuesLayer},
+		Subject:       &chartDescriptor,
+	}
+	if err = registry.pushBlob(ctx, valuesManifest.Config, emptyConfig); err != nil {
+		return
+	}
+	if err = registry.pushBlob(ctx, valuesLayer, values); err != nil {
+		return
+	}
+	valuesDescriptor, err := registry.pushManifest(ctx, "", valuesManifest)
+	if err != nil {
+		return
+	}
+	pushed = PushedChart{
+		Reference:    registry.reference(connectorType.HelmVersion),
+		Digest:       chartDescriptor.Digest,
+		ValuesDigest: valuesDescriptor.Digest,
+	}
+	return
+}
+
+// defaultHelmValues returns latest helm values of connector type, rendered with its default config.
+func (c ConnectorTypeLoader) defaultHelmValues(connectorTypeID string) (values []byte, err error) {
+	config, err := InitDefault(connectorTypeID)
+	if err != nil {
+		return
+	}
+	helmer, ok := config.(ConfigHelmer)
+	if !ok {
+		err = ErrNoHelmConfig
+		return
+	}
+	helmConfig, err := helmer.GetHelmConfig(ConsoleConfig{})
+	if err != nil {
+		return
+	}
+	r, err := c.GetTemplatedHelm(connectorTypeID, helmConfig, HelmOptions{ValuesOnly: true})
+	if err != nil {
+		return
+	}
+	values, err = io.ReadAll(r)
+	return
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+func newOCIDescriptor(mediaType string, content []byte) ociDescriptor {
+	sum := sha256.Sum256(content)
+	return ociDescriptor{MediaType: mediaType, Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: int64(len(content))}
+}
+
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Subject       *ociDescriptor    `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// registryClient pushes to a repository of an OCI registry, see OCI distribution spec.
+type registryClient struct {
+	httpClient *http.Client
+	baseURL    *url.URL
+	repository string
+	creds      RegistryCredentials
+	// authorization is the Authorization header of requests, once registry asked for it
+	authorization string
+}
+
+// newRegistryClient returns client of repository name under registryURL path,
+// registryURL scheme being oci (same as https), https or http.
+func newRegistryClient(registryURL string, name string, creds RegistryCredentials) (client *registryClient, err error) {
+	if !strings.Contains(registryURL, "://") {
+		registryURL = "oci://" + registryURL
+	}
+	u, err := url.Parse(registryURL)
+	if err != nil {
+		err = fmt.Errorf("%w: invalid registry URL, %w", ErrRegistryPush, err)
+		return
+	}
+	switch u.Scheme {
+	case "oci":
+		u.Scheme = "https"
+	case "https", "http":
+	default:
+		err = fmt.Errorf("%w: unsupported registry URL scheme %s", ErrRegistryPush, u.Scheme)
+		return
+	}
+	if u.Host == "" {
+		err = fmt.Errorf("%w: no host in registry URL %s", ErrRegistryPush, registryURL)
+		return
+	}
+	client = &registryClient{
+		httpClient: http.DefaultClient,
+		baseURL:    &url.URL{Scheme: u.Scheme, Host: u.Host},
+		repository: strings.TrimPrefix(path.Join(u.Path, strings.ToLower(name)), "/"),
+		creds:      creds,
+	}
+	return
+}
+
+func (r *registryClient) reference(tag string) string {
+	return r.baseURL.Host + "/" + r.repository + ":" + tag
+}
+
+// pushBlob uploads content of descriptor d, unless registry already has it.
+func (r *registryClient) pushBlob(ctx context.Context, d ociDescriptor, content []byte) (err error) {
+	resp, err := r.do(ctx, http.MethodHead, r.url("/v2/"+r.repository+"/blobs/"+d.Digest), nil, "")
+	if err != nil {
+		return
+	}
+	if resp.StatusCode == http.StatusOK {
+		return
+	}
+	resp, err = r.do(ctx, http.MethodPost, r.url("/v2/"+r.repository+"/blobs/uploads/"), nil, "")
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		err = fmt.Errorf("%w: start of blob %s upload, %s", ErrRegistryPush, d.Digest, resp.Status)
+		return
+	}
+	location, err := r.baseURL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		err = fmt.Errorf("%w: invalid upload location, %w", ErrRegistryPush, err)
+		return
+	}
+	query := location.Query()
+	query.Set("digest", d.Digest)
+	location.RawQuery = query.Encode()
+	resp, err = r.do(ctx, http.MethodPut, location, content, "application/octet-stream")
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusCreated {
+		err = fmt.Errorf("%w: upload of blob %s, %s", ErrRegistryPush, d.Digest, resp.Status)
+	}
+	return
+}
+
+// pushManifest pushes manifest under tag, by digest if tag is empty.
+func (r *registryClient) pushManifest(ctx context.Context, tag string, manifest ociManifest) (d ociDescriptor, err error) {
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return
+	}
+	d = newOCIDescriptor(manifest.MediaType, raw)
+	if tag == "" {
+		tag = d.Digest
+	}
+	resp, err := r.do(ctx, http.MethodPut, r.url("/v2/"+r.repository+"/manifests/"+tag), raw, manifest.MediaType)
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusCreated {
+		err = fmt.Errorf("%w: push of manifest %s, %s", ErrRegistryPush, tag, resp.Status)
+	}
+	return
+}
+
+func (r *registryClient) url(p string) *url.URL {
+	return r.baseURL.JoinPath(p)
+}
+
+// do sends request, authenticating as registry asks on first unauthorized response. Response body is discarded.
+func (r *registryClient) do(ctx context.Context, method string, u *url.URL, body []byte, contentType string) (resp *http.Response, err error) {
+	for attempt := 0; ; attempt++ {
+		resp, err = r.send(ctx, method, u, body, contentType)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return
+		}
+		if err = r.authenticate(ctx, resp.Header.Get("WWW-Authenticate")); err != nil {
+			return
+		}
+	}
+}
+
+func (r *registryClient) send(ctx context.Context, method string, u *url.URL, body []byte, contentType string) (resp *http.Response, err error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if r.authorization != "" {
+		req.Header.Set("Authorization", r.authorization)
+	}
+	resp, err = r.httpClient.Do(req) //nolint:gosec // registry URL given by operator
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrRegistryPush, err)
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	_ = resp.Body.Close()
+	return
+}
+
+// authenticate sets authorization of next requests, as asked by challenge (WWW-Authenticate header of unauthorized
+// response): basic auth, or a token issued by challenge realm for repository push.
+func (r *registryClient) authenticate(ctx context.Context, challenge string) (err error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if r.creds.Username == "" {
+			err = fmt.Errorf("%w: registry requires credentials", ErrRegistryPush)
+			return
+		}
+		req := http.Request{Header: http.Header{}}
+		req.SetBasicAuth(r.creds.Username, r.creds.Password)
+		r.authorization = req.Header.Get("Authorization")
+	case "bearer":
+		var token string
+		if token, err = r.fetchToken(ctx, parseChallengeParams(params)); err != nil {
+			return
+		}
+		r.authorization = "Bearer " + token
+	default:
+		err = fmt.Errorf("%w: unsupported registry authentication %q", ErrRegistryPush, challenge)
+	}
+	return
+}
+
+func (r *registryClient) fetchToken(ctx context.Context, params map[string]string) (token string, err error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		err = fmt.Errorf("%w: invalid token realm %q", ErrRegistryPush, params["realm"])
+		return
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", "repository:"+r.repository+":pull,push")
+	realm.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return
+	}
+	if r.creds.Username != "" {
+		req.SetBasicAuth(r.creds.Username, r.creds.Password)
+	}
+	resp, err := r.httpClient.Do(req) //nolint:gosec // token realm given by registry
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrRegistryPush, err)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("%w: registry token request, %s", ErrRegistryPush, resp.Status)
+		return
+	}
+	res := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&res); err != nil {
+		err = fmt.Errorf("%w: invalid registry token response, %w", ErrRegistryPush, err)
+		return
+	}
+	token = res.Token
+	if token == "" {
+		token = res.AccessToken
+	}
+	if token == "" {
+		err = fmt.Errorf("%w: registry issued no token", ErrRegistryPush)
+	}
+	return
+}
+
+// parseChallengeParams parses auth-params of a WWW-Authenticate challenge, e.g.
+// realm="https://auth.example.com/token",service="registry",scope="repository:charts/x:pull,push".
+func parseChallengeParams(params string) (parsed map[string]string) {
+	parsed = map[string]string{}
+	for params != "" {
+		key, rest, ok := strings.Cut(strings.TrimLeft(params, " ,"), "=")
+		if !ok {
+			return
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				return
+			}
+			value, params = rest[1:end+1], rest[end+2:]
+		} else {
+			value, params, _ = strings.Cut(rest, ",")
+		}
+		parsed[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return
+}
@@ -0,0 +1,213 @@
+package sdk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeRegistry is an in memory OCI registry, issuing tokens to user:pass on /token.
+type fakeRegistry struct {
+	lock      sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	server    *httptest.Server
+}
+
+func newFakeRegistry(t *testing.T) (r *fakeRegistry) {
+	t.Helper()
+	r = &fakeRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+	uploads := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /token", func(w http.ResponseWriter, req *http.Request) {
+		if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "pass" || req.URL.Query().Get("scope") != "repository:charts/onedrive-sharepoint:pull,push" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"token":"registry-token"}`))
+	})
+	authorized := func(w http.ResponseWriter, req *http.Request) bool {
+		if req.Header.Get("Authorization") != "Bearer registry-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake",scope="repository:charts/onedrive-sharepoint:push"`, r.server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		}
+		return true
+	}
+	mux.HandleFunc("HEAD /v2/charts/onedrive-sharepoint/blobs/{digest}", func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(w, req) {
+			return
+		}
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		if _, ok := r.blobs[req.PathValue("digest")]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	mux.HandleFunc("POST /v2/charts/onedrive-sharepoint/blobs/uploads/", func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(w, req) {
+			return
+		}
+		r.lock.Lock()
+		uploads++
+		w.Header().Set("Location", fmt.Sprintf("/v2/charts/onedrive-sharepoint/blobs/uploads/%d?state=abc", uploads))
+		r.lock.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("PUT /v2/charts/onedrive-sharepoint/blobs/uploads/{id}", func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(w, req) {
+			return
+		}
+		content, _ := io.ReadAll(req.Body)
+		sum := sha256.Sum256(content)
+		digest := req.URL.Query().Get("digest")
+		if digest != "sha256:"+hex.EncodeToString(sum[:]) || req.URL.Query().Get("state") != "abc" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.lock.Lock()
+		r.blobs[digest] = content
+		r.lock.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("PUT /v2/charts/onedrive-sharepoint/manifests/{reference}", func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(w, req) {
+			return
+		}
+		content, _ := io.ReadAll(req.Body)
+		manifest := ociManifest{}
+		if err := json.Unmarshal(content, &manifest); err != nil || req.Header.Get("Content-Type") != ociManifestMediaType {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		for _, d := range append(manifest.Layers, manifest.Config) {
+			if _, ok := r.blobs[d.Digest]; !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		r.manifests[req.PathValue("reference")] = content
+		w.WriteHeader(http.StatusCreated)
+	})
+	r.server = httptest.NewServer(mux)
+	t.Cleanup(r.server.Close)
+	return
+}
+
+func TestConnectorTypeLoader_PushHelmChart(t *testing.T) {
+	c, err := NewConnectorsTypesLoader(true)
+	if err != nil {
+		t.Fatalf("could not init connector types loader, err: %v", err)
+	}
+	registry := newFakeRegistry(t)
+	host := strings.TrimPrefix(registry.server.URL, "http://")
+
+	pushed, err := c.PushHelmChart(t.Context(), DummyKey, registry.server.URL+"/charts", RegistryCredentials{Username: "user", Password: "pass"})
+	if err != nil {
+		t.Fatalf("PushHelmChart() error = %v", err)
+	}
+	if pushed.Reference != host+"/charts/onedrive-sharepoint:0.1.0" {
+		t.Errorf("PushHelmChart() reference = %s", pushed.Reference)
+	}
+
+	chartManifest := ociManifest{}
+	if err = json.Unmarshal(registry.manifests["0.1.0"], &chartManifest); err != nil {
+		t.Fatalf("invalid chart manifest, error: %v", err)
+	}
+	rawChart, err := configFS.ReadFile("connectors/dummy/helm/dummy-0.1.0.tgz")
+	if err != nil {
+		t.Fatalf("could not read chart, error: %v", err)
+	}
+	if diff := cmp.Diff(chartManifest.Layers, []ociDescriptor{newOCIDescriptor(helmChartMediaType, rawChart)}); diff != "" {
+		t.Errorf("chart manifest layers diff(got-want)=%s", diff)
+	}
+	metadata := map[string]any{}
+	if err = json.Unmarshal(registry.blobs[chartManifest.Config.Digest], &metadata); err != nil || metadata["version"] != "0.1.0" || chartManifest.Config.MediaType != helmConfigMediaType {
+		t.Errorf("chart config = %s, error: %v", registry.blobs[chartManifest.Config.Digest], err)
+	}
+
+	valuesManifest := ociManifest{}
+	if err = json.Unmarshal(registry.manifests[pushed.ValuesDigest], &valuesManifest); err != nil {
+		t.Fatalf("invalid values manifest, error: %v", err)
+	}
+	if valuesManifest.Subject == nil || valuesManifest.Subject.Digest != pushed.Digest || valuesManifest.ArtifactType != helmValuesArtifactType {
+		t.Errorf("values manifest = %+v, want it to refer chart %s", valuesManifest, pushed.Digest)
+	}
+	if values := registry.blobs[valuesManifest.Layers[0].Digest]; !bytes.HasPrefix(values, []byte("config:\n  connector-manager:")) {
+		t.Errorf("values = %s, want rendered values.yaml", values)
+	}
+
+	// pushed again, blobs already in registry
+	if _, err = c.PushHelmChart(t.Context(), DummyKey, registry.server.URL+"/charts", RegistryCredentials{Username: "user", Password: "pass"}); err != nil {
+		t.Errorf("PushHelmChart() again error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		typeID      string
+		registryURL string
+		creds       RegistryCredentials
+		wantErr     error
+	}{
+		{name: "unknown connector type", typeID: "unknown", registryURL: registry.server.URL, wantErr: ErrConnectorTypeNotFound},
+		{name: "no helm", typeID: ICAPKey, registryURL: registry.server.URL, wantErr: ErrNoHelmForConnector},
+		{name: "wrong credentials", typeID: DummyKey, registryURL: registry.server.URL + "/charts", creds: RegistryCredentials{Username: "user", Password: "wrong"}, wantErr: ErrRegistryPush},
+		{name: "invalid scheme", typeID: DummyKey, registryURL: "ftp://" + host, wantErr: ErrRegistryPush},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.PushHelmChart(t.Context(), tt.typeID, tt.registryURL, tt.creds); !errors.Is(err, tt.wantErr) {
+				t.Errorf("PushHelmChart() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_parseChallengeParams(t *testing.T) {
+	got := parseChallengeParams(`realm="https://auth.example.com/token",service="registry.example.com", scope="repository:charts/x:pull,push",error=invalid_token`)
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:charts/x:pull,push",
+		"error":   "invalid_token",
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("parseChallengeParams() diff(got-want)=%s", diff)
+	}
+}
+
+func Test_newRegistryClient(t *testing.T) {
+	tests := []struct {
+		registryURL    string
+		wantBase       string
+		wantRepository string
+	}{
+		{registryURL: "oci://registry.example.com/charts", wantBase: "https://registry.example.com", wantRepository: "charts/chart"},
+		{registryURL: "registry.example.com:5000", wantBase: "https://registry.example.com:5000", wantRepository: "chart"},
+		{registryURL: "http://localhost:5000/a/b/", wantBase: "http://localhost:5000", wantRepository: "a/b/chart"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.registryURL, func(t *testing.T) {
+			got, err := newRegistryClient(tt.registryURL, "Chart", RegistryCredentials{})
+			if err != nil {
+				t.Fatalf("newRegistryClient() error = %v", err)
+			}
+			if got.baseURL.String() != tt.wantBase || got.repository != tt.wantRepository {
+				t.Errorf("newRegistryClient() = %s %s, want %s %s", got.baseURL, got.repository, tt.wantBase, tt.wantRepository)
+			}
+		})
+	}
+}
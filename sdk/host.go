@@ -1,22 +1,94 @@
 package sdk
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/glimps-re/connector-integration/sdk/validation"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// HostOS is the operating system a host connector runs on, selecting platform specific configuration.
+type HostOS string
+
+const (
+	// Linux is the default host OS
+	HostLinux   HostOS = "linux"
+	HostWindows HostOS = "windows"
+)
+
+func (HostOS) Values() []HostOS {
+	return []HostOS{HostLinux, HostWindows}
+}
+
+// HostOSTag is the validator tag validating a HostOS.
+const HostOSTag = "host_os"
+
+func (HostOS) Validation() validation.EnumValidation {
+	return validation.NewEnumValidation(HostOS("").Values())
+}
+
 type HostConfig struct {
 	CommonConnectorConfig    `yaml:",inline" mapstructure:",squash"`
-	Workers                  int                  `json:"workers" mapstructure:"workers" yaml:"workers" validate:"min=1" desc:"Number of concurrent workers for file analysis (default: 4, affects CPU usage)"`
-	ExtractWorkers           int                  `json:"extract_workers" mapstructure:"extract_workers" yaml:"extract_workers" validate:"min=1" desc:"Number of input archives processed simultaneously. Setting it too high makes many archives compete for the same analysis pool, slowing the completion of each individual input. Ratio should be at least 1:10 compared to analysis workers (1:20 can be a good choice to speed up input process time). (default: 2, used when extract is enabled)"`
+	Workers                  int                  `json:"workers" mapstructure:"workers" yaml:"workers" validate:"min=1" desc:"Number of concurrent workers for file analysis (default: 4, affects CPU usage)" default:"4"`
+	ExtractWorkers           int                  `json:"extract_workers" mapstructure:"extract_workers" yaml:"extract_workers" validate:"min=1" desc:"Number of input archives processed simultaneously. Setting it too high makes many archives compete for the same analysis pool, slowing the completion of each individual input. Ratio should be at least 1:10 compared to analysis workers (1:20 can be a good choice to speed up input process time). (default: 2, used when extract is enabled)" default:"2"`
 	Extract                  bool                 `json:"extract" mapstructure:"extract" yaml:"extract" desc:"Enable archive extraction (archives are unpacked and contents scanned)"`
-	RecursiveExtractMaxDepth int                  `json:"recursive_extract_max_depth" mapstructure:"recursive_extract_max_depth" yaml:"recursive_extract_max_depth" desc:"Maximum nesting level for recursive extraction. Beyond this depth, nested archives are sent for analysis instead of being extracted"`
-	RecursiveExtractMaxSize  string               `json:"recursive_extract_max_size" mapstructure:"recursive_extract_max_size" yaml:"recursive_extract_max_size" desc:"Maximum total size of extracted content across all nesting levels (e.g., '5GB'). When reached, remaining archives are sent for analysis instead of being extracted. Note: may exceed by up to one archive's extracted content"`
-	RecursiveExtractMaxFiles int                  `json:"recursive_extract_max_files" mapstructure:"recursive_extract_max_files" yaml:"recursive_extract_max_files" desc:"Maximum number of files to extract recursively"`
-	MaxFileSize              string               `json:"max_file_size" mapstructure:"max_file_size" yaml:"max_file_size" desc:"Maximum file size to submit to GLIMPS Malware Detect (e.g., '100MB')"`
-	Paths                    []string             `json:"paths" yaml:"paths" validate:"required,min=1" desc:"List of directories or files to monitor and scan (can be absolute or relative paths)"`
+	RecursiveExtractMaxDepth int                  `json:"recursive_extract_max_depth" mapstructure:"recursive_extract_max_depth" yaml:"recursive_extract_max_depth" desc:"Maximum nesting level for recursive extraction. Beyond this depth, nested archives are sent for analysis instead of being extracted" default:"10"`
+	RecursiveExtractMaxSize  string               `json:"recursive_extract_max_size" mapstructure:"recursive_extract_max_size" yaml:"recursive_extract_max_size" desc:"Maximum total size of extracted content across all nesting levels (e.g., '5GB'). When reached, remaining archives are sent for analysis instead of being extracted. Note: may exceed by up to one archive's extracted content" default:"5GB"`
+	RecursiveExtractMaxFiles int                  `json:"recursive_extract_max_files" mapstructure:"recursive_extract_max_files" yaml:"recursive_extract_max_files" desc:"Maximum number of files to extract recursively" default:"10000"`
+	MaxFileSize              string               `json:"max_file_size" mapstructure:"max_file_size" yaml:"max_file_size" desc:"Maximum file size to submit to GLIMPS Malware Detect (e.g., '100MB')" default:"100MiB"`
+	Paths                    []string             `json:"paths" yaml:"paths" validate:"required,min=1" desc:"List of directories or files to monitor and scan (can be absolute or relative paths)" default:"[]"`
 	FollowSymlinks           bool                 `json:"follow_symlinks" yaml:"follow_symlinks" desc:"Follow symbolic links when scanning directories (if disabled, symlinks are skipped)"`
+	ScanNetworkMounts        bool                 `json:"scan_network_mounts" mapstructure:"scan_network_mounts" yaml:"scan_network_mounts" desc:"Scan files on network mounts (NFS, SMB...) found in monitored paths (if disabled, network mounts are skipped)"`
+	MountTypesAllowList      []string             `json:"mount_types_allow_list" mapstructure:"mount_types_allow_list" yaml:"mount_types_allow_list" validate:"dive,required,excludesall= /" desc:"Network filesystem types scanned when scan_network_mounts is enabled (e.g., 'nfs4', 'cifs'), all types if empty" default:"[]"`
+	StaleMountTimeout        Duration             `json:"stale_mount_timeout" mapstructure:"stale_mount_timeout" yaml:"stale_mount_timeout" validate:"durmin=0s" desc:"Maximum time to wait for a network mount to answer before skipping it as stale (e.g., '10s', required when scan_network_mounts is enabled)" default:"10s"`
 	Actions                  HostActionsConfig    `json:"actions" mapstructure:"actions" yaml:"actions" desc:"Actions to perform on scanned files (delete, quarantine, log, move, print)"`
 	Quarantine               HostQuarantineConfig `json:"quarantine" mapstructure:"quarantine" yaml:"quarantine" desc:"Configuration for encrypted quarantine storage of malware files"`
 	Monitoring               HostMonitoringConfig `json:"monitoring" mapstructure:"monitoring" yaml:"monitoring" desc:"Configuration for continuous directory monitoring and periodic re-scanning"`
 	Move                     HostMoveConfig       `json:"move" mapstructure:"move" yaml:"move" desc:"Configuration for moving clean files from source to destination after scanning"`
 	Print                    HostPrintConfig      `json:"print" mapstructure:"print" yaml:"print" desc:"Configuration for outputting scan reports to console or file"`
-	PluginsConfig            string               `json:"plugins_config" yaml:"plugins_config" mapstructure:"plugins_config" desc:"Path to plugins configuration file (required for host connector plugin functionality)"`
+	OS                       HostOS               `json:"os" mapstructure:"os" yaml:"os" validate:"omitempty,host_os" desc:"Operating system host connector runs on (default: linux), paths must be valid for this OS" default:"linux"`
+	Windows                  HostWindowsConfig    `json:"windows" mapstructure:"windows" yaml:"windows" desc:"Windows specific configuration (os must be windows)"`
+	Plugins                  []HostPluginConfig   `json:"plugins" mapstructure:"plugins" yaml:"plugins" validate:"unique=Name,dive" desc:"Plugins run by host connector" default:"[]"`
+	// Deprecated: use Plugins
+	PluginsConfig string `json:"plugins_config" yaml:"plugins_config" mapstructure:"plugins_config" desc:"Path to plugins configuration file (deprecated, use plugins)"`
+}
+
+type HostWindowsConfig struct {
+	Drives                 []string `json:"drives" mapstructure:"drives" yaml:"drives" desc:"Drive letters to monitor and scan entirely (e.g., 'D:')" default:"[]"`
+	UNCPaths               []string `json:"unc_paths" mapstructure:"unc_paths" yaml:"unc_paths" desc:"Network shares to monitor and scan (e.g., '\\\\server\\share')" default:"[]"`
+	UseVSS                 bool     `json:"use_vss" mapstructure:"use_vss" yaml:"use_vss" desc:"Read locked files from a Volume Shadow Copy snapshot instead of skipping them"`
+	DefenderExclusionHints []string `json:"defender_exclusion_hints" mapstructure:"defender_exclusion_hints" yaml:"defender_exclusion_hints" desc:"Paths to exclude from Microsoft Defender real-time scanning (e.g., quarantine location), reported to help administrators avoid scan conflicts" default:"[]"`
+}
+
+type HostPluginConfig struct {
+	Name    string   `json:"name" mapstructure:"name" yaml:"name" validate:"required" desc:"Plugin name, unique among host connector plugins"`
+	Path    string   `json:"path" mapstructure:"path" yaml:"path" validate:"required" desc:"Path to plugin binary"`
+	SHA256  string   `json:"sha256" mapstructure:"sha256" yaml:"sha256" validate:"omitempty,len=64,hexadecimal" desc:"Expected SHA256 of plugin binary (hex encoded), plugin is not loaded if binary does not match"`
+	Args    []string `json:"args" mapstructure:"args" yaml:"args" desc:"Arguments given to plugin"`
+	Enabled bool     `json:"enabled" mapstructure:"enabled" yaml:"enabled" desc:"Enable plugin"`
+}
+
+// PluginInfo describes a plugin run by a connector, reported to console at registration.
+type PluginInfo struct {
+	Name    string `json:"name"`
+	SHA256  string `json:"sha256,omitempty"`
+	Enabled bool   `json:"enabled"`
+}
+
+// PluginInventory returns host connector plugins, to give to ConnectorManagerClient.SetPlugins.
+func (c HostConfig) PluginInventory() (plugins []PluginInfo) {
+	plugins = make([]PluginInfo, 0, len(c.Plugins))
+	for _, p := range c.Plugins {
+		plugins = append(plugins, PluginInfo{
+			Name:    p.Name,
+			SHA256:  strings.ToLower(p.SHA256),
+			Enabled: p.Enabled,
+		})
+	}
+	return
 }
 
 type HostPrintConfig struct {
@@ -25,22 +97,55 @@ type HostPrintConfig struct {
 }
 
 type HostActionsConfig struct {
-	Delete     bool `json:"delete" mapstructure:"delete" yaml:"delete" desc:"Delete detected malware files automatically"`
-	Quarantine bool `json:"quarantine" mapstructure:"quarantine" yaml:"quarantine" desc:"Move malware files to encrypted quarantine storage (requires quarantine configuration)"`
+	Delete     bool `json:"delete" mapstructure:"delete" yaml:"delete" desc:"Delete detected malware files automatically" default:"true"`
+	Quarantine bool `json:"quarantine" mapstructure:"quarantine" yaml:"quarantine" desc:"Move malware files to encrypted quarantine storage (requires quarantine configuration)" default:"true"`
 	Print      bool `json:"print" mapstructure:"print" yaml:"print" desc:"Output scan results to console or file (see print configuration)"`
-	Log        bool `json:"log" mapstructure:"log" yaml:"log" desc:"Log malware detections (written to connector logs)"`
+	Log        bool `json:"log" mapstructure:"log" yaml:"log" desc:"Log malware detections (written to connector logs)" default:"true"`
 	Move       bool `json:"move" mapstructure:"move" yaml:"move" desc:"Move clean files from source to destination after scanning (requires move configuration)"`
 }
 
 type HostMonitoringConfig struct {
-	PreScan           bool     `json:"prescan" mapstructure:"prescan" yaml:"prescan" desc:"Immediately scan all existing files in monitored paths when monitoring starts"`
-	Period            Duration `json:"period" mapstructure:"period" yaml:"period" desc:"If set, enable periodic re-scan. Interval between periodic re-scans (e.g., '1h', '30m')"`
-	ModificationDelay Duration `json:"modification_delay" mapstructure:"modification_delay" yaml:"modification_delay" desc:"Wait time after file modification before scanning (e.g., '30s', prevents scanning incomplete writes)"`
+	PreScan           bool               `json:"prescan" mapstructure:"prescan" yaml:"prescan" desc:"Immediately scan all existing files in monitored paths when monitoring starts"`
+	Period            Duration           `json:"period" mapstructure:"period" yaml:"period" validate:"omitempty,durmin=1m" desc:"If set, enable periodic re-scan. Interval between periodic re-scans (e.g., '1h', '30m')"`
+	ModificationDelay Duration           `json:"modification_delay" mapstructure:"modification_delay" yaml:"modification_delay" validate:"durmin=1s,durmax=24h" desc:"Wait time after file modification before scanning (e.g., '30s', prevents scanning incomplete writes)" default:"30s"`
+	RealTime          HostRealTimeConfig `json:"realtime" mapstructure:"realtime" yaml:"realtime" desc:"On-access scanning of monitored paths"`
+}
+
+// RealTimeEngine is the mechanism used by host connector to be notified of file accesses.
+type RealTimeEngine string
+
+const (
+	// fanotify permission events (Linux only), the only engine able to block file opens
+	RealTimeFanotify RealTimeEngine = "fanotify"
+	// eBPF probes on file syscalls (Linux only), lower overhead but notification only
+	RealTimeEBPF RealTimeEngine = "ebpf"
+	// periodic filesystem polling, available on every OS (default)
+	RealTimePolling RealTimeEngine = "polling"
+)
+
+func (RealTimeEngine) Values() []RealTimeEngine {
+	return []RealTimeEngine{RealTimeFanotify, RealTimeEBPF, RealTimePolling}
+}
+
+// RealTimeEngineTag is the validator tag validating a RealTimeEngine.
+const RealTimeEngineTag = "realtime_engine"
+
+func (RealTimeEngine) Validation() validation.EnumValidation {
+	return validation.NewEnumValidation(RealTimeEngine("").Values())
+}
+
+const DefaultRealTimeMaxQueue = 10000
+
+type HostRealTimeConfig struct {
+	Enabled     bool           `json:"enabled" mapstructure:"enabled" yaml:"enabled" desc:"Enable on-access scanning"`
+	Engine      RealTimeEngine `json:"engine" mapstructure:"engine" yaml:"engine" validate:"omitempty,realtime_engine" desc:"File access notification engine: fanotify or ebpf (Linux only), polling (default)" default:"polling"`
+	BlockOnOpen bool           `json:"block_on_open" mapstructure:"block_on_open" yaml:"block_on_open" desc:"Deny file opens until file is analyzed (fanotify engine only, delays applications accessing files)"`
+	MaxQueue    int            `json:"max_queue" mapstructure:"max_queue" yaml:"max_queue" validate:"min=0" desc:"Maximum number of file accesses waiting for analysis, further accesses are allowed without analysis (default: 10000)" default:"10000"`
 }
 
 type HostQuarantineConfig struct {
-	Location string `json:"location" mapstructure:"location" yaml:"location" desc:"Directory path where quarantined files are stored (files are encrypted with .lock extension)"`
-	Password string `json:"password" mapstructure:"password" yaml:"password" password:"true" desc:"Password for encrypting quarantined files (required to restore files later)"`
+	Location string `json:"location" mapstructure:"location" yaml:"location" desc:"Directory path where quarantined files are stored (files are encrypted with .lock extension)" default:"/var/lib/gmhost"`
+	Password string `json:"password" mapstructure:"password" yaml:"password" password:"true" desc:"Password for encrypting quarantined files (required to restore files later)" default:"infected"`
 	Registry string `json:"registry" mapstructure:"registry" yaml:"registry" desc:"Path to the database that store quarantined and restored file entry (leave empty for in-memory store, lost on restart)"`
 }
 
@@ -48,3 +153,102 @@ type HostMoveConfig struct {
 	Destination string `json:"destination" mapstructure:"destination" yaml:"destination" desc:"Target directory for moving clean files (preserves subdirectory structure)"`
 	Source      string `json:"source" mapstructure:"source" yaml:"source" desc:"Source directory filter (only clean files within this path are moved to destination)"`
 }
+
+// Host OS struct-level validation tags.
+const (
+	HostOSPathTag      = "host_os_path"
+	HostWindowsOnlyTag = "host_windows_only"
+	DriveLetterTag     = "drive_letter"
+	UNCPathTag         = "unc_path"
+	HostOSEngineTag    = "host_os_engine"
+	BlockOnOpenTag     = "block_on_open"
+	NetworkMountsTag   = "network_mounts"
+	StaleMountTag      = "stale_mount_timeout_required"
+)
+
+var (
+	driveLetterRe = regexp.MustCompile(`^[A-Za-z]:$`)
+	windowsPathRe = regexp.MustCompile(`^([A-Za-z]:([\\/]|$)|\\\\)`)
+	uncPathRe     = regexp.MustCompile(`^\\\\[^\\/]+\\[^\\/]+`)
+)
+
+// ValidateHostConfig checks paths are valid for the selected OS, windows configuration is only set on Windows,
+// and network mounts settings are consistent.
+func ValidateHostConfig(sl validator.StructLevel) {
+	config, ok := sl.Current().Interface().(HostConfig)
+	if !ok {
+		return
+	}
+	validateHostOS(sl, config)
+	validateNetworkMounts(sl, config)
+}
+
+func validateNetworkMounts(sl validator.StructLevel, config HostConfig) {
+	if config.ScanNetworkMounts {
+		// scans would hang on dead mounts
+		if config.StaleMountTimeout == 0 {
+			sl.ReportError(config.StaleMountTimeout, "stale_mount_timeout", "StaleMountTimeout", StaleMountTag, "")
+		}
+		return
+	}
+	if len(config.MountTypesAllowList) > 0 {
+		sl.ReportError(config.MountTypesAllowList, "mount_types_allow_list", "MountTypesAllowList", NetworkMountsTag, "")
+	}
+}
+
+func validateHostOS(sl validator.StructLevel, config HostConfig) {
+	windows := config.OS == HostWindows
+	for i, p := range config.Paths {
+		// unix absolute paths on windows, windows drive or UNC paths elsewhere
+		if (windows && strings.HasPrefix(p, "/")) || (!windows && windowsPathRe.MatchString(p)) {
+			sl.ReportError(p, fmt.Sprintf("paths[%d]", i), fmt.Sprintf("Paths[%d]", i), HostOSPathTag, string(config.OS))
+		}
+	}
+	if engine := config.Monitoring.RealTime.Engine; windows && (engine == RealTimeFanotify || engine == RealTimeEBPF) {
+		sl.ReportError(engine, "monitoring.realtime.engine", "Monitoring.RealTime.Engine", HostOSEngineTag, string(engine))
+	}
+	if !windows {
+		w := config.Windows
+		if len(w.Drives) > 0 || len(w.UNCPaths) > 0 || w.UseVSS || len(w.DefenderExclusionHints) > 0 {
+			sl.ReportError(config.Windows, "windows", "Windows", HostWindowsOnlyTag, "")
+		}
+		return
+	}
+	for i, d := range config.Windows.Drives {
+		if !driveLetterRe.MatchString(d) {
+			sl.ReportError(d, fmt.Sprintf("windows.drives[%d]", i), fmt.Sprintf("Windows.Drives[%d]", i), DriveLetterTag, "")
+		}
+	}
+	for i, p := range config.Windows.UNCPaths {
+		if !uncPathRe.MatchString(p) {
+			sl.ReportError(p, fmt.Sprintf("windows.unc_paths[%d]", i), fmt.Sprintf("Windows.UNCPaths[%d]", i), UNCPathTag, "")
+		}
+	}
+}
+
+// ValidateHostRealTime checks block on open is only enabled with fanotify engine.
+func ValidateHostRealTime(sl validator.StructLevel) {
+	config, ok := sl.Current().Interface().(HostRealTimeConfig)
+	if !ok {
+		return
+	}
+	if config.BlockOnOpen && config.Engine != RealTimeFanotify {
+		sl.ReportError(config.BlockOnOpen, "block_on_open", "BlockOnOpen", BlockOnOpenTag, "")
+	}
+}
+
+func registerHostValidation(validate *validator.Validate, trans ut.Translator) (err error) {
+	validate.RegisterStructValidation(ValidateHostConfig, HostConfig{})
+	validate.RegisterStructValidation(ValidateHostRealTime, HostRealTimeConfig{})
+	err = registerTranslations(validate, trans, map[string]string{
+		HostOSPathTag:      "{0} is not a valid path for host os",
+		HostWindowsOnlyTag: "{0} requires os to be windows",
+		DriveLetterTag:     "{0} must be a drive letter followed by a colon (e.g., 'D:')",
+		UNCPathTag:         "{0} must be a UNC path (e.g., '\\\\server\\share')",
+		HostOSEngineTag:    "{0} is only available on linux",
+		BlockOnOpenTag:     "{0} requires fanotify engine",
+		NetworkMountsTag:   "{0} requires scan_network_mounts to be enabled",
+		StaleMountTag:      "{0} must be greater than 0 when scan_network_mounts is enabled",
+	})
+	return
+}
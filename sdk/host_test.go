@@ -0,0 +1,263 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/go-cmp/cmp"
+)
+
+const testPluginSHA256 = "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08"
+
+func TestHostConfig_plugins(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugins []HostPluginConfig
+		want    []string
+	}{
+		{name: "no plugin"},
+		{
+			name: "ok",
+			plugins: []HostPluginConfig{
+				{Name: "yara", Path: "/usr/lib/gmhost/yara.so", SHA256: testPluginSHA256, Args: []string{"--rules", "/etc/yara"}, Enabled: true},
+				{Name: "report", Path: "/usr/lib/gmhost/report.so"},
+			},
+		},
+		{
+			name:    "missing path",
+			plugins: []HostPluginConfig{{Name: "yara"}},
+			want:    []string{"HostConfig.plugins[0].path: path is a required field"},
+		},
+		{
+			name:    "invalid hash",
+			plugins: []HostPluginConfig{{Name: "yara", Path: "/usr/lib/gmhost/yara.so", SHA256: "abcdefgh"}},
+			want:    []string{"HostConfig.plugins[0].sha256: sha256 must be 64 characters in length"},
+		},
+		{
+			name: "duplicated name",
+			plugins: []HostPluginConfig{
+				{Name: "yara", Path: "/usr/lib/gmhost/yara.so"},
+				{Name: "yara", Path: "/opt/yara.so"},
+			},
+			want: []string{"HostConfig.plugins: plugins must contain unique values"},
+		},
+	}
+	v, err := DefaultValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawConfig, err := InitDefault(HostKey)
+			if err != nil {
+				t.Fatalf("InitDefault() error = %v", err)
+			}
+			config := rawConfig.(*HostConfig)
+			config.GMalwareAPIURL = "https://gmalware.example.com"
+			config.GMalwareAPIToken = "token"
+			config.Paths = []string{"/data"}
+			config.Plugins = tt.plugins
+
+			var got []string
+			var fieldErrs validator.ValidationErrors
+			if err := v.Validate(config); errors.As(err, &fieldErrs) {
+				for _, fe := range fieldErrs {
+					got = append(got, fe.Namespace()+": "+fe.Translate(v.Trans))
+				}
+			} else if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Validate() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateHostConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		os       HostOS
+		paths    []string
+		windows  HostWindowsConfig
+		realtime HostRealTimeConfig
+		mounts   func(c *HostConfig)
+		want     []string
+	}{
+		{name: "linux", paths: []string{"/data", "relative/dir"}},
+		{
+			name:  "network mounts",
+			paths: []string{"/data"},
+			mounts: func(c *HostConfig) {
+				c.ScanNetworkMounts = true
+				c.MountTypesAllowList = []string{"nfs4", "fuse.sshfs"}
+				c.StaleMountTimeout = Duration(10 * time.Second)
+			},
+		},
+		{
+			name:  "duration bounds",
+			paths: []string{"/data"},
+			mounts: func(c *HostConfig) {
+				c.GMalwareTimeout = Duration(-time.Second)
+				c.Monitoring.Period = Duration(-time.Hour)
+				c.Monitoring.ModificationDelay = Duration(48 * time.Hour)
+			},
+			want: []string{
+				"HostConfig.CommonConnectorConfig.gmalware_timeout: gmalware_timeout must be at least 0s",
+				"HostConfig.monitoring.period: period must be at least 1m",
+				"HostConfig.monitoring.modification_delay: modification_delay must be at most 24h",
+			},
+		},
+		{
+			name:  "network mounts without stale timeout",
+			paths: []string{"/data"},
+			mounts: func(c *HostConfig) {
+				c.ScanNetworkMounts = true
+				c.MountTypesAllowList = []string{"nfs 4"}
+			},
+			want: []string{
+				"HostConfig.mount_types_allow_list[0]: mount_types_allow_list[0] cannot contain any of the following characters ' /'",
+				"HostConfig.stale_mount_timeout: stale_mount_timeout must be greater than 0 when scan_network_mounts is enabled",
+			},
+		},
+		{
+			name:   "mount types without network mounts",
+			paths:  []string{"/data"},
+			mounts: func(c *HostConfig) { c.MountTypesAllowList = []string{"nfs"} },
+			want:   []string{"HostConfig.mount_types_allow_list: mount_types_allow_list requires scan_network_mounts to be enabled"},
+		},
+		{
+			name:     "fanotify block on open",
+			paths:    []string{"/data"},
+			realtime: HostRealTimeConfig{Enabled: true, Engine: RealTimeFanotify, BlockOnOpen: true, MaxQueue: 100},
+		},
+		{
+			name:     "ebpf block on open",
+			paths:    []string{"/data"},
+			realtime: HostRealTimeConfig{Enabled: true, Engine: RealTimeEBPF, BlockOnOpen: true},
+			want:     []string{"HostConfig.monitoring.realtime.block_on_open: block_on_open requires fanotify engine"},
+		},
+		{
+			name:     "ebpf on windows",
+			os:       HostWindows,
+			paths:    []string{`C:\Users`},
+			realtime: HostRealTimeConfig{Enabled: true, Engine: RealTimeEBPF},
+			want:     []string{"HostConfig.monitoring.realtime.engine: monitoring.realtime.engine is only available on linux"},
+		},
+		{
+			name:     "invalid engine",
+			paths:    []string{"/data"},
+			realtime: HostRealTimeConfig{Engine: "inotify", MaxQueue: -1},
+			want: []string{
+				"HostConfig.monitoring.realtime.engine: engine must be one of [fanotify ebpf polling]",
+				"HostConfig.monitoring.realtime.max_queue: max_queue must be 0 or greater",
+			},
+		},
+		{
+			name:  "windows",
+			os:    HostWindows,
+			paths: []string{`C:\Users`, `\\server\share\dir`, "relative"},
+			windows: HostWindowsConfig{
+				Drives:                 []string{"D:", "e:"},
+				UNCPaths:               []string{`\\server\share`},
+				UseVSS:                 true,
+				DefenderExclusionHints: []string{`C:\ProgramData\gmhost`},
+			},
+		},
+		{
+			name:  "windows path on linux",
+			os:    HostLinux,
+			paths: []string{"/data", `C:\Users`, `\\server\share`},
+			want: []string{
+				"HostConfig.paths[1]: paths[1] is not a valid path for host os",
+				"HostConfig.paths[2]: paths[2] is not a valid path for host os",
+			},
+		},
+		{
+			name:    "windows config on linux",
+			paths:   []string{"/data"},
+			windows: HostWindowsConfig{UseVSS: true},
+			want:    []string{"HostConfig.windows: windows requires os to be windows"},
+		},
+		{
+			name:  "invalid windows values",
+			os:    HostWindows,
+			paths: []string{"/data"},
+			windows: HostWindowsConfig{
+				Drives:   []string{"D", `D:\`},
+				UNCPaths: []string{`\\server`},
+			},
+			want: []string{
+				"HostConfig.paths[0]: paths[0] is not a valid path for host os",
+				"HostConfig.windows.drives[0]: windows.drives[0] must be a drive letter followed by a colon (e.g., 'D:')",
+				"HostConfig.windows.drives[1]: windows.drives[1] must be a drive letter followed by a colon (e.g., 'D:')",
+				`HostConfig.windows.unc_paths[0]: windows.unc_paths[0] must be a UNC path (e.g., '\\server\share')`,
+			},
+		},
+	}
+	v, err := DefaultValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := HostConfig{
+				CommonConnectorConfig: CommonConnectorConfig{GMalwareAPIURL: "https://gmalware.example.com", GMalwareAPIToken: "token"},
+				Workers:               1,
+				ExtractWorkers:        1,
+				OS:                    tt.os,
+				Paths:                 tt.paths,
+				Windows:               tt.windows,
+				Monitoring:            HostMonitoringConfig{ModificationDelay: Duration(30 * time.Second), RealTime: tt.realtime},
+			}
+			if tt.mounts != nil {
+				tt.mounts(&config)
+			}
+			var got []string
+			var fieldErrs validator.ValidationErrors
+			if err := v.Validate(config); errors.As(err, &fieldErrs) {
+				for _, fe := range fieldErrs {
+					got = append(got, fe.Namespace()+": "+fe.Translate(v.Trans))
+				}
+			} else if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Validate() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectorManagerClient_SetPlugins(t *testing.T) {
+	var got registerRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("could not decode register request, error: %v", err)
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	config := HostConfig{Plugins: []HostPluginConfig{
+		{Name: "yara", Path: "/usr/lib/gmhost/yara.so", SHA256: testPluginSHA256, Enabled: true},
+		{Name: "report", Path: "/usr/lib/gmhost/report.so"},
+	}}
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"})
+	c.SetPlugins(config.PluginInventory())
+	if err := c.Register(t.Context(), "1.0.0", &RegistrationInfo{}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	want := []PluginInfo{
+		{Name: "yara", SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", Enabled: true},
+		{Name: "report"},
+	}
+	if diff := cmp.Diff(got.Plugins, want); diff != "" {
+		t.Errorf("Register() plugins diff(got-want)=%s", diff)
+	}
+}
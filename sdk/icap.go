@@ -1,5 +1,10 @@
 package sdk
 
+import (
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
 type ICAPConfig struct {
 	CommonConnectorConfig
 	Sampling ICAPSamplingConfig `json:"sampling" yaml:"sampling" mapstructure:"sampling" desc:"if enabled, and file size higher than treshold, file will be sampled (head and tail) before analysis"`
@@ -7,6 +12,40 @@ type ICAPConfig struct {
 
 type ICAPSamplingConfig struct {
 	Threshold int64 `json:"threshold" yaml:"threshold" mapstructure:"threshold" validate:"min=0" desc:"Sampling threshold for ICAP requests (disabled = 0)"`
-	HeadSize  int64 `json:"head_size" yaml:"head_size" mapstructure:"head_size" validate:"min=0" desc:"Size of head sample in bytes"`
-	TailSize  int64 `json:"tail_size" yaml:"tail_size" mapstructure:"tail_size" validate:"min=0" desc:"Size of tail sample in bytes"`
+	HeadSize  int64 `json:"head_size" yaml:"head_size" mapstructure:"head_size" validate:"min=0" desc:"Size of head sample in bytes, required if sampling is enabled"`
+	TailSize  int64 `json:"tail_size" yaml:"tail_size" mapstructure:"tail_size" validate:"min=0" desc:"Size of tail sample in bytes, required if sampling is enabled"`
+}
+
+// Sampling struct-level validation tags, reported on head_size and tail_size fields.
+const (
+	SamplingSizeRequiredTag = "sampling_size_required"
+	SamplingExceedsTag      = "sampling_exceeds_threshold"
+)
+
+// ValidateICAPSampling checks, when sampling is enabled (threshold > 0), that head and tail sizes are set
+// and that head_size + tail_size <= threshold.
+func ValidateICAPSampling(sl validator.StructLevel) {
+	sampling, ok := sl.Current().Interface().(ICAPSamplingConfig)
+	if !ok || sampling.Threshold <= 0 {
+		return
+	}
+	if sampling.HeadSize == 0 {
+		sl.ReportError(sampling.HeadSize, "head_size", "HeadSize", SamplingSizeRequiredTag, "")
+	}
+	if sampling.TailSize == 0 {
+		sl.ReportError(sampling.TailSize, "tail_size", "TailSize", SamplingSizeRequiredTag, "")
+	}
+	if sampling.HeadSize+sampling.TailSize > sampling.Threshold {
+		sl.ReportError(sampling.HeadSize, "head_size", "HeadSize", SamplingExceedsTag, "")
+		sl.ReportError(sampling.TailSize, "tail_size", "TailSize", SamplingExceedsTag, "")
+	}
+}
+
+func registerICAPSamplingValidation(validate *validator.Validate, trans ut.Translator) (err error) {
+	validate.RegisterStructValidation(ValidateICAPSampling, ICAPSamplingConfig{})
+	err = registerTranslations(validate, trans, map[string]string{
+		SamplingSizeRequiredTag: "{0} must be greater than 0 when sampling threshold is set",
+		SamplingExceedsTag:      "head_size + tail_size must be lower than or equal to sampling threshold",
+	})
+	return
 }
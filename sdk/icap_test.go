@@ -0,0 +1,57 @@
+package sdk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidateICAPSampling(t *testing.T) {
+	tests := []struct {
+		name     string
+		sampling ICAPSamplingConfig
+		want     []string
+	}{
+		{name: "disabled", sampling: ICAPSamplingConfig{HeadSize: 100}},
+		{name: "ok", sampling: ICAPSamplingConfig{Threshold: 1000, HeadSize: 500, TailSize: 500}},
+		{
+			name:     "missing tail",
+			sampling: ICAPSamplingConfig{Threshold: 1000, HeadSize: 500},
+			want:     []string{"ICAPConfig.sampling.tail_size: tail_size must be greater than 0 when sampling threshold is set"},
+		},
+		{
+			name:     "exceeds threshold",
+			sampling: ICAPSamplingConfig{Threshold: 1000, HeadSize: 600, TailSize: 500},
+			want: []string{
+				"ICAPConfig.sampling.head_size: head_size + tail_size must be lower than or equal to sampling threshold",
+				"ICAPConfig.sampling.tail_size: head_size + tail_size must be lower than or equal to sampling threshold",
+			},
+		},
+	}
+	v, err := DefaultValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ICAPConfig{
+				CommonConnectorConfig: CommonConnectorConfig{GMalwareAPIURL: "https://gmalware.example.com", GMalwareAPIToken: "token"},
+				Sampling:              tt.sampling,
+			}
+			var got []string
+			var fieldErrs validator.ValidationErrors
+			if err := v.Validate(config); errors.As(err, &fieldErrs) {
+				for _, fe := range fieldErrs {
+					got = append(got, fe.Namespace()+": "+fe.Translate(v.Trans))
+				}
+			} else if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Validate() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
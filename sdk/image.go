@@ -0,0 +1,96 @@
+package sdk
+
+import (
+	"strings"
+
+	"github.com/glimps-re/connector-integration/sdk/validation"
+)
+
+// PullPolicy is the image pull policy of generated compose files and helm values, named as kubernetes does.
+type PullPolicy string
+
+const (
+	PullAlways       PullPolicy = "Always"
+	PullIfNotPresent PullPolicy = "IfNotPresent"
+	PullNever        PullPolicy = "Never"
+)
+
+func (PullPolicy) Values() []PullPolicy {
+	return []PullPolicy{PullAlways, PullIfNotPresent, PullNever}
+}
+
+// PullPolicyTag is the validator tag validating a PullPolicy.
+const PullPolicyTag = "pull_policy"
+
+func (PullPolicy) Validation() validation.EnumValidation {
+	return validation.NewEnumValidation(PullPolicy("").Values())
+}
+
+// ImageConfig overrides connector images of generated compose files and helm values (see ConsoleConfig.Image),
+// e.g. to test release candidates from a staging registry. Empty fields keep connector type defaults.
+type ImageConfig struct {
+	// Registry images are pulled from, prefixing their repository (e.g. registry.example.com/glimpsre/icap-detect)
+	Registry string `validate:"omitempty,excludesall= "`
+	// Tag of connector image
+	Tag string `validate:"omitempty,excludesall= /"`
+	// PullPolicy of images
+	PullPolicy PullPolicy `validate:"omitempty,pull_policy"`
+}
+
+// DevImageDefaults are image settings of loaders in dev mode (see NewConnectorsTypesLoader), when console config
+// does not set them: images pulled from staging registry on each deployment.
+var DevImageDefaults = ImageConfig{
+	Registry:   "registry.staging.glimps.re",
+	PullPolicy: PullAlways,
+}
+
+// Ref returns reference of connector image repository, e.g. {{ .Image.Ref "glimpsre/icap-detect" "latest" }},
+// with Registry and Tag overrides, defaultTag being used when Tag is empty (no tag if both are empty).
+func (i ImageConfig) Ref(repository string, defaultTag string) string {
+	ref := i.Mirror(repository)
+	tag := defaultTag
+	if i.Tag != "" {
+		tag = i.Tag
+	}
+	if tag == "" {
+		return ref
+	}
+	return ref + ":" + tag
+}
+
+// Mirror returns image with Registry override only, for companion images versioned apart from the connector
+// (e.g. {{ .Image.Mirror "glimpsre/smtprelay:v3.6.0" }}).
+func (i ImageConfig) Mirror(image string) string {
+	if i.Registry == "" {
+		return image
+	}
+	return strings.TrimSuffix(i.Registry, "/") + "/" + image
+}
+
+// ComposePullPolicy returns PullPolicy as a docker compose pull_policy, empty if not set.
+func (i ImageConfig) ComposePullPolicy() string {
+	switch i.PullPolicy {
+	case PullAlways:
+		return "always"
+	case PullIfNotPresent:
+		return "missing"
+	case PullNever:
+		return "never"
+	default:
+		return ""
+	}
+}
+
+// withDefaults returns i with its empty fields set from defaults.
+func (i ImageConfig) withDefaults(defaults ImageConfig) ImageConfig {
+	if i.Registry == "" {
+		i.Registry = defaults.Registry
+	}
+	if i.Tag == "" {
+		i.Tag = defaults.Tag
+	}
+	if i.PullPolicy == "" {
+		i.PullPolicy = defaults.PullPolicy
+	}
+	return i
+}
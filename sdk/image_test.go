@@ -0,0 +1,174 @@
+package sdk
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImageConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		image          ImageConfig
+		wantRef        string
+		wantMirror     string
+		wantPullPolicy string
+	}{
+		{name: "defaults", wantRef: "glimpsre/icap-detect:latest", wantMirror: "glimpsre/smtprelay:v3.6.0"},
+		{
+			name:           "overrides",
+			image:          ImageConfig{Registry: "registry.example.com/", Tag: "1.2.0-rc1", PullPolicy: PullAlways},
+			wantRef:        "registry.example.com/glimpsre/icap-detect:1.2.0-rc1",
+			wantMirror:     "registry.example.com/glimpsre/smtprelay:v3.6.0",
+			wantPullPolicy: "always",
+		},
+		{
+			name:           "if not present",
+			image:          ImageConfig{PullPolicy: PullIfNotPresent},
+			wantRef:        "glimpsre/icap-detect:latest",
+			wantMirror:     "glimpsre/smtprelay:v3.6.0",
+			wantPullPolicy: "missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.image.Ref("glimpsre/icap-detect", "latest"); got != tt.wantRef {
+				t.Errorf("ImageConfig.Ref() = %s, want %s", got, tt.wantRef)
+			}
+			if got := tt.image.Mirror("glimpsre/smtprelay:v3.6.0"); got != tt.wantMirror {
+				t.Errorf("ImageConfig.Mirror() = %s, want %s", got, tt.wantMirror)
+			}
+			if got := tt.image.ComposePullPolicy(); got != tt.wantPullPolicy {
+				t.Errorf("ImageConfig.ComposePullPolicy() = %s, want %s", got, tt.wantPullPolicy)
+			}
+		})
+	}
+	if got := (ImageConfig{}).Ref("gmhost", ""); got != "gmhost" {
+		t.Errorf("ImageConfig.Ref() = %s, want gmhost", got)
+	}
+}
+
+func TestConnectorTypeLoader_GetTemplatedDockerCompose_image(t *testing.T) {
+	tests := []struct {
+		name    string
+		dev     bool
+		config  ConsoleConfig
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: "name: icap-server\nservices:\n  icap-server:\n    image: glimpsre/icap-detect:latest\n    environment:\n",
+		},
+		{
+			name:   "overrides",
+			config: ConsoleConfig{Image: ImageConfig{Registry: "registry.example.com", Tag: "1.2.0-rc1", PullPolicy: PullNever}},
+			want:   "name: icap-server\nservices:\n  icap-server:\n    image: registry.example.com/glimpsre/icap-detect:1.2.0-rc1\n    pull_policy: never\n    environment:\n",
+		},
+		{
+			name: "dev defaults",
+			dev:  true,
+			want: "name: icap-server\nservices:\n  icap-server:\n    image: " + DevImageDefaults.Registry + "/glimpsre/icap-detect:latest\n    pull_policy: always\n    environment:\n",
+		},
+		{
+			name:   "dev overrides",
+			dev:    true,
+			config: ConsoleConfig{Image: ImageConfig{Registry: "registry.example.com", Tag: "1.2.0-rc1"}},
+			want:   "name: icap-server\nservices:\n  icap-server:\n    image: registry.example.com/glimpsre/icap-detect:1.2.0-rc1\n    pull_policy: always\n    environment:\n",
+		},
+		{
+			name:    "unknown pull policy",
+			config:  ConsoleConfig{Image: ImageConfig{PullPolicy: "Sometimes"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid tag",
+			config:  ConsoleConfig{Image: ImageConfig{Tag: "glimpsre/1.2.0"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewConnectorsTypesLoader(tt.dev)
+			if err != nil {
+				t.Fatalf("could not init connector types loader, err: %v", err)
+			}
+			got, err := c.GetTemplatedDockerCompose(ICAPKey, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConnectorTypeLoader.GetTemplatedDockerCompose() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.As(err, &ValidationError{}) {
+					t.Errorf("ConnectorTypeLoader.GetTemplatedDockerCompose() error = %v, want a ValidationError", err)
+				}
+				return
+			}
+			if diff := cmp.Diff(got[:min(len(got), len(tt.want))], tt.want); diff != "" {
+				t.Errorf("ConnectorTypeLoader.GetTemplatedDockerCompose() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectorTypeLoader_GetTemplatedHelm_image(t *testing.T) {
+	c, err := NewConnectorsTypesLoader(true)
+	if err != nil {
+		t.Fatalf("could not init connector types loader, err: %v", err)
+	}
+	tests := []struct {
+		name    string
+		image   ImageConfig
+		want    string
+		wantErr bool
+	}{
+		{name: "dev defaults", want: "image:\n  version: v1.3.1\n  registry: " + DevImageDefaults.Registry + "\n  pullPolicy: Always\n"},
+		{
+			name:  "overrides",
+			image: ImageConfig{Registry: "registry.example.com", Tag: "v1.4.0-rc1", PullPolicy: PullIfNotPresent},
+			want:  "image:\n  version: v1.4.0-rc1\n  registry: registry.example.com\n  pullPolicy: IfNotPresent\n",
+		},
+		{name: "unknown pull policy", image: ImageConfig{PullPolicy: "Sometimes"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := SharepointConfig{}
+			helmConfig, err := config.GetHelmConfig(ConsoleConfig{APIKey: "api-key", Image: tt.image})
+			if err != nil {
+				t.Fatalf("GetHelmConfig() error = %v", err)
+			}
+			r, err := c.GetTemplatedHelm(SharepointKey, helmConfig, HelmOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConnectorTypeLoader.GetTemplatedHelm() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.As(err, &ValidationError{}) {
+					t.Errorf("ConnectorTypeLoader.GetTemplatedHelm() error = %v, want a ValidationError", err)
+				}
+				return
+			}
+			raw, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("could not read helm archive, error: %v", err)
+			}
+			archive, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+			if err != nil {
+				t.Fatalf("invalid helm archive, error: %v", err)
+			}
+			f, err := archive.Open(helmValuesFileName)
+			if err != nil {
+				t.Fatalf("no values in helm archive, error: %v", err)
+			}
+			values, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatalf("could not read helm values, error: %v", err)
+			}
+			if !bytes.Contains(values, []byte(tt.want)) {
+				t.Errorf("ConnectorTypeLoader.GetTemplatedHelm() values = %s, want it to contain %s", values, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package sdk
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// unknownFieldPrefix prefixes encoding/json errors on unknown fields with DisallowUnknownFields.
+const unknownFieldPrefix = "json: unknown field "
+
+// unknownJSONFields returns paths (e.g. "monitoring.realtime.engin", "plugins[1].nme") of every object key of raw
+// matching no field of t, as encoding/json would match them. Values decoded by a json.Unmarshaler, maps keys and
+// interfaces accept any field. Scanning stops at the first syntax error, fields found until then being returned.
+func unknownJSONFields(raw []byte, t reflect.Type) (fields []string) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	_ = scanJSONFields(dec, t, "", &fields)
+	return
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// scanJSONFields reads next value of dec, of path, appending unknown fields of t to fields.
+func scanJSONFields(dec *json.Decoder, t reflect.Type, path string, fields *[]string) (err error) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		err = dec.Decode(&json.RawMessage{})
+		return
+	}
+	token, err := dec.Token()
+	if err != nil {
+		return
+	}
+	switch token {
+	case json.Delim('{'):
+		for dec.More() {
+			keyToken, keyErr := dec.Token()
+			if keyErr != nil {
+				err = keyErr
+				return
+			}
+			key, _ := keyToken.(string)
+			keyPath := jsonPath(path, key)
+			var valueType reflect.Type
+			switch t.Kind() {
+			case reflect.Map:
+				valueType = t.Elem()
+			case reflect.Struct:
+				field, ok := jsonField(t, key)
+				if !ok {
+					*fields = append(*fields, keyPath)
+				}
+				valueType = field.Type
+			}
+			if err = scanJSONFields(dec, valueType, keyPath, fields); err != nil {
+				return
+			}
+		}
+		_, err = dec.Token()
+	case json.Delim('['):
+		var elemType reflect.Type
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			elemType = t.Elem()
+		}
+		for i := 0; dec.More(); i++ {
+			if err = scanJSONFields(dec, elemType, path+"["+strconv.Itoa(i)+"]", fields); err != nil {
+				return
+			}
+		}
+		_, err = dec.Token()
+	}
+	return
+}
+
+// jsonField returns field of struct t decoded from key, exact names being preferred over case-insensitive ones.
+// Fields of embedded structs without json name are promoted, outer fields hiding them as with encoding/json.
+func jsonField(t reflect.Type, key string) (field reflect.StructField, ok bool) {
+	fields, names := jsonFields(t)
+	for i, name := range names {
+		if name == key {
+			field, ok = fields[i], true
+			return
+		}
+	}
+	for i, name := range names {
+		if strings.EqualFold(name, key) {
+			field, ok = fields[i], true
+			return
+		}
+	}
+	return
+}
+
+// jsonFields returns decodable fields of struct t and their JSON name, outer fields first. Indexes of promoted fields
+// are relative to t (see reflect.Value.FieldByIndex).
+func jsonFields(t reflect.Type) (fields []reflect.StructField, names []string) {
+	var embedded []reflect.StructField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			embeddedType := f.Type
+			if embeddedType.Kind() == reflect.Pointer {
+				embeddedType = embeddedType.Elem()
+			}
+			if embeddedType.Kind() == reflect.Struct {
+				embedded = append(embedded, f)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, f)
+		names = append(names, name)
+	}
+	for _, e := range embedded {
+		embeddedType := e.Type
+		if embeddedType.Kind() == reflect.Pointer {
+			embeddedType = embeddedType.Elem()
+		}
+		embeddedFields, embeddedNames := jsonFields(embeddedType)
+		for _, f := range embeddedFields {
+			f.Index = append([]int{e.Index[0]}, f.Index...)
+			fields = append(fields, f)
+		}
+		names = append(names, embeddedNames...)
+	}
+	return
+}
+
+// jsonPath returns path of member key of object at path, e.g. "monitoring.realtime".
+func jsonPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
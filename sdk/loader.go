@@ -3,6 +3,7 @@ package sdk
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"embed"
 	"encoding/base64"
 	"encoding/json"
@@ -20,6 +21,13 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/coreos/go-semver/semver"
+	"github.com/glimps-re/connector-integration/sdk/archive"
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/filetype"
+	"github.com/glimps-re/connector-integration/sdk/msauth"
+	"github.com/glimps-re/connector-integration/sdk/setupflow"
+	"github.com/glimps-re/connector-integration/sdk/validation"
 	"gopkg.in/yaml.v3"
 )
 
@@ -44,6 +52,7 @@ const (
 
 type ConnectorTypeLoader struct {
 	connectorsTypes map[string]ConnectorType
+	dev             bool
 }
 
 func NewConnectorsTypesLoader(dev bool) (connLoader ConnectorTypeLoader, err error) {
@@ -53,6 +62,7 @@ func NewConnectorsTypesLoader(dev bool) (connLoader ConnectorTypeLoader, err err
 	}
 	connLoader = ConnectorTypeLoader{
 		connectorsTypes: make(map[string]ConnectorType),
+		dev:             dev,
 	}
 	for _, entry := range entries {
 		if !entry.IsDir() {
@@ -73,18 +83,95 @@ func NewConnectorsTypesLoader(dev bool) (connLoader ConnectorTypeLoader, err err
 }
 
 type ConnectorType struct {
-	Name               string        `yaml:"name" json:"name"`
-	ID                 string        `yaml:"-" json:"id" desc:"e.g. icap,sharepoint,m365"`
-	Description        string        `yaml:"description" json:"description"`
-	DevOnly            bool          `yaml:"dev_only" json:"-"`
-	SetupSteps         []Step        `yaml:"setup_steps" json:"setup" desc:"prerequisite steps"`
-	Configs            []ConfigField `json:"config_fields"`
-	LaunchSteps        []Step        `yaml:"launch_steps" json:"-" desc:"steps to deploy connector"`
-	MitigationInfoType string        `yaml:"mitigation_info_type" json:"mitigation_info_type" desc:"what's connector treat : file, email, url"`
-	Logo               string        `yaml:"-" json:"logo"`
-	Helm               bool          `yaml:"-" json:"helm" desc:"whether helm chart is available for this connector type"`
-	DockerCompose      bool          `yaml:"-" json:"docker_compose" desc:"whether docker compose is available for this connector type"`
-	HelmVersion        string        `yaml:"-" json:"helm_version" desc:"helm chart version"`
+	Name                string                      `yaml:"name" json:"name"`
+	ID                  string                      `yaml:"-" json:"id" desc:"e.g. icap,sharepoint,m365"`
+	Description         string                      `yaml:"description" json:"description"`
+	DevOnly             bool                        `yaml:"dev_only" json:"-"`
+	SetupSteps          []Step                      `yaml:"setup_steps" json:"setup" desc:"prerequisite steps"`
+	Configs             []ConfigField               `yaml:"-" json:"config_fields"`
+	LaunchSteps         []Step                      `yaml:"launch_steps" json:"-" desc:"steps to deploy connector"`
+	HowTo               []Step                      `yaml:"how-to" json:"how_to" desc:"optional guides for specific deployments (e.g. behind a proxy)"`
+	MitigationInfoTypes []events.MitigationInfoType `yaml:"mitigation_info_types" json:"mitigation_info_types" desc:"what's connector treat : file, email, url"`
+	Logo                string                      `yaml:"-" json:"logo"`
+	Helm                bool                        `yaml:"-" json:"helm" desc:"whether helm chart is available for this connector type"`
+	DockerCompose       bool                        `yaml:"-" json:"docker_compose" desc:"whether docker compose is available for this connector type"`
+	HelmVersion         string                      `yaml:"-" json:"helm_version" desc:"latest helm chart version"`
+	HelmVersions        []string                    `yaml:"-" json:"helm_versions,omitempty" desc:"available helm chart versions, most recent first"`
+	SetupFlow           *setupflow.Flow             `yaml:"setup_flow" json:"setup_flow,omitempty" desc:"optional multi-page setup wizard"`
+	Capabilities        []Capability                `yaml:"capabilities" json:"capabilities" desc:"actions supported by connectors of this type, known before any instance registers (e.g. to render action buttons)"`
+	Changelog           []ChangelogRelease          `yaml:"-" json:"-" desc:"releases from CHANGELOG.yaml, see GetConnectorTypeChangelog"`
+	ConfigSchemaVersion int                         `yaml:"config_schema_version" json:"config_schema_version,omitempty" desc:"schema version of current config fields"`
+	ConfigSchemas       []ConfigSchema              `yaml:"config_schemas" json:"config_schemas,omitempty" desc:"connector versions running each config schema version"`
+	// Deprecated: use MitigationInfoTypes. Still parsed from connector.yaml, and set to the first of MitigationInfoTypes.
+	MitigationInfoType string `yaml:"mitigation_info_type" json:"mitigation_info_type" desc:"deprecated, first of mitigation_info_types"`
+}
+
+// Capability is an action a connector type supports, declared in connector.yaml capabilities section.
+type Capability string
+
+const (
+	// CapabilityRestore connectors restore mitigated items (restore task)
+	CapabilityRestore Capability = "restore"
+	// CapabilityPurge connectors permanently delete mitigated items
+	CapabilityPurge Capability = "purge"
+	// CapabilityRescan connectors scan again their whole scope on demand (see Rescanner)
+	CapabilityRescan Capability = "rescan"
+	// CapabilityPause connectors may be stopped and started again (stop and start tasks)
+	CapabilityPause Capability = "pause"
+	// CapabilityQuarantineList connectors list their local quarantine (see QuarantineLister)
+	CapabilityQuarantineList Capability = "quarantine-list"
+	// CapabilityMetrics connectors push metrics (see ConnectorManagerClient.NewMetricCollecter)
+	CapabilityMetrics Capability = "metrics"
+	// CapabilityHealth connectors serve health endpoints on DefaultHealthAddress (see RunOptions.Health), their
+	// generated deployments are probed and scraped (see withProbes)
+	CapabilityHealth Capability = "health"
+)
+
+func (Capability) Values() []Capability {
+	return []Capability{CapabilityRestore, CapabilityPurge, CapabilityRescan, CapabilityPause, CapabilityQuarantineList, CapabilityMetrics, CapabilityHealth}
+}
+
+// HasCapability reports whether connectors of type c support capability.
+func (c ConnectorType) HasCapability(capability Capability) bool {
+	return slices.Contains(c.Capabilities, capability)
+}
+
+// normalizeMitigationInfoTypes merges deprecated singular mitigation info type of c into its list, and checks it.
+func normalizeMitigationInfoTypes(c *ConnectorType) (err error) {
+	if c.MitigationInfoType != "" && !slices.Contains(c.MitigationInfoTypes, events.MitigationInfoType(c.MitigationInfoType)) {
+		c.MitigationInfoTypes = append([]events.MitigationInfoType{events.MitigationInfoType(c.MitigationInfoType)}, c.MitigationInfoTypes...)
+	}
+	if len(c.MitigationInfoTypes) == 0 {
+		err = fmt.Errorf("%w: at least one is required", ErrInvalidMitigationInfoType)
+		return
+	}
+	for i, infoType := range c.MitigationInfoTypes {
+		if !slices.Contains(events.MitigationInfoType("").Values(), infoType) {
+			err = fmt.Errorf("%w: %q", ErrInvalidMitigationInfoType, infoType)
+			return
+		}
+		if slices.Contains(c.MitigationInfoTypes[:i], infoType) {
+			err = fmt.Errorf("%w: %q declared twice", ErrInvalidMitigationInfoType, infoType)
+			return
+		}
+	}
+	c.MitigationInfoType = string(c.MitigationInfoTypes[0])
+	return
+}
+
+// checkCapabilities returns ErrInvalidCapability if capabilities hold an unknown or duplicated capability.
+func checkCapabilities(capabilities []Capability) (err error) {
+	for i, capability := range capabilities {
+		if !slices.Contains(Capability("").Values(), capability) {
+			err = fmt.Errorf("%w: %q", ErrInvalidCapability, capability)
+			return
+		}
+		if slices.Contains(capabilities[:i], capability) {
+			err = fmt.Errorf("%w: %q declared twice", ErrInvalidCapability, capability)
+			return
+		}
+	}
+	return
 }
 
 type ConnectorFile struct {
@@ -105,8 +192,9 @@ const (
 	FrontValidURL   FrontValidation = "url"
 	FrontValidEmail FrontValidation = "email"
 
-	// TO DO : add these to our custom validator
+	// FrontValidDuration fields are bounded durations (see DurationMinTag)
 	FrontValidDuration FrontValidation = "duration"
+	// FrontValidFileSize fields are sizes (see FileSizeTag)
 	FrontValidFileSize FrontValidation = "filesize"
 
 	ReconfigurableTag string = "reconfigurable"
@@ -155,26 +243,212 @@ type ConfigHelmer interface {
 	GetHelmConfig(consoleConfig ConsoleConfig) (helmConfig any, err error)
 }
 
+// Connector *Config must satisfy this interface if it relies on a Microsoft Entra app registration,
+// so the console can validate it during setup (see msauth package)
+type ConfigMSAuther interface {
+	MSCredentials() msauth.Credentials
+	// Features enabled by current config, used to check required Graph permissions
+	GraphFeatures() []msauth.Feature
+}
+
+// DiagnoseMSPermissions reports Microsoft Graph permissions granted to the Entra app registration of config, against
+// the ones its enabled features need (see msauth.Client.DiagnosePermissions). It returns ErrNoMSAuthConfig if config
+// is not a ConfigMSAuther.
+func DiagnoseMSPermissions(ctx context.Context, client msauth.Client, config any) (report msauth.PermissionReport, err error) {
+	auther, ok := config.(ConfigMSAuther)
+	if !ok {
+		err = ErrNoMSAuthConfig
+		return
+	}
+	report, err = client.DiagnosePermissions(ctx, auther.MSCredentials(), auther.GraphFeatures()...)
+	return
+}
+
 type CommonConnectorConfig struct {
-	GMalwareAPIURL      string   `json:"gmalware_api_url" yaml:"gmalware_api_url" mapstructure:"gmalware_api_url" validate:"required,url" desc:"GLIMPS Malware API URL" `
-	GMalwareExpertURL   string   `json:"gmalware_expert_url" yaml:"gmalware_expert_url" validate:"omitempty,url" mapstructure:"gmalware_expert_url" desc:"GLIMPS Malware expert URL"`
-	GMalwareAPIToken    string   `json:"gmalware_api_token" yaml:"gmalware_api_token" mapstructure:"gmalware_api_token" validate:"required" desc:"GLIMPS Malware API Token" `
-	GMalwareNoCertCheck bool     `json:"gmalware_no_cert_check" yaml:"gmalware_no_cert_check" mapstructure:"gmalware_no_cert_check" desc:"Disable certificate check for GLIMPS Malware"`
-	GMalwareUserTags    []string `json:"gmalware_user_tags" yaml:"gmalware_user_tags" mapstructure:"gmalware_user_tags" desc:"List of tags set by connector on GLIMPS Malware detect submission"`
-	GMalwareTimeout     Duration `json:"gmalware_timeout" yaml:"gmalware_timeout" mapstructure:"gmalware_timeout" desc:"gmalware submission timeout" `
-	GMalwareBypassCache bool     `json:"gmalware_bypass_cache" yaml:"gmalware_bypass_cache" mapstructure:"gmalware_bypass_cache" desc:"bypass gmalware"`
-	GMalwareSyndetect   bool     `json:"gmalware_syndetect" yaml:"gmalware_syndetect" mapstructure:"gmalware_syndetect" desc:"use syndetect"`
-	Debug               bool     `json:"debug" yaml:"debug" mapstructure:"debug" desc:"Enable debug log"`
+	GMalwareAPIURL           string                 `json:"gmalware_api_url" yaml:"gmalware_api_url" mapstructure:"gmalware_api_url" validate:"required,url" desc:"GLIMPS Malware API URL" `
+	GMalwareExpertURL        string                 `json:"gmalware_expert_url" yaml:"gmalware_expert_url" validate:"omitempty,url" mapstructure:"gmalware_expert_url" desc:"GLIMPS Malware expert URL"`
+	GMalwareAPIToken         string                 `json:"gmalware_api_token" yaml:"gmalware_api_token" mapstructure:"gmalware_api_token" validate:"required" desc:"GLIMPS Malware API Token" `
+	GMalwareFallbackAPIURL   string                 `json:"gmalware_fallback_api_url" yaml:"gmalware_fallback_api_url" mapstructure:"gmalware_fallback_api_url" validate:"omitempty,url" desc:"Optional secondary GLIMPS Malware API URL, used when primary one is unavailable"`
+	GMalwareFallbackAPIToken string                 `json:"gmalware_fallback_api_token" yaml:"gmalware_fallback_api_token" mapstructure:"gmalware_fallback_api_token" validate:"required_with=GMalwareFallbackAPIURL" password:"true" desc:"Secondary GLIMPS Malware API Token"`
+	GMalwareNoCertCheck      bool                   `json:"gmalware_no_cert_check" yaml:"gmalware_no_cert_check" mapstructure:"gmalware_no_cert_check" desc:"Disable certificate check for GLIMPS Malware"`
+	OutboundProxyURL         string                 `json:"outbound_proxy_url" yaml:"outbound_proxy_url" mapstructure:"outbound_proxy_url" validate:"omitempty,url" desc:"Optional proxy for outbound requests to GLIMPS Malware and console (e.g. http://proxy.example.com:3128), proxy of environment (HTTPS_PROXY) is used if empty"`
+	ProxyUsername            string                 `json:"proxy_username" yaml:"proxy_username" mapstructure:"proxy_username" desc:"Optional username to authenticate to outbound proxy"`
+	ProxyPassword            string                 `json:"proxy_password" yaml:"proxy_password" mapstructure:"proxy_password" password:"true" desc:"Password to authenticate to outbound proxy"`
+	CustomCACertsPEM         PEMBundle              `json:"custom_ca_certs_pem" yaml:"custom_ca_certs_pem" mapstructure:"custom_ca_certs_pem" desc:"Optional PEM encoded CA certificates trusted along system ones for GLIMPS Malware and console (e.g. a corporate TLS inspection CA), preferred to disabling certificate check" default:"[]"`
+	GMalwareUserTags         []string               `json:"gmalware_user_tags" yaml:"gmalware_user_tags" mapstructure:"gmalware_user_tags" desc:"List of tags set by connector on GLIMPS Malware detect submission, templates of submission context (e.g. site:{{.Site}}, {{.ConnectorID}})" default:"[]"`
+	GMalwareTimeout          Duration               `json:"gmalware_timeout" yaml:"gmalware_timeout" mapstructure:"gmalware_timeout" validate:"durmin=0s" desc:"gmalware submission timeout" default:"5m"`
+	GMalwareBypassCache      bool                   `json:"gmalware_bypass_cache" yaml:"gmalware_bypass_cache" mapstructure:"gmalware_bypass_cache" desc:"bypass gmalware"`
+	GMalwareSyndetect        bool                   `json:"gmalware_syndetect" yaml:"gmalware_syndetect" mapstructure:"gmalware_syndetect" desc:"use syndetect (deprecated, use gmalware_routing)"`
+	GMalwareRouting          GMalwareRouting        `json:"gmalware_routing" yaml:"gmalware_routing" mapstructure:"gmalware_routing" desc:"Routing of items to GLIMPS Malware detect or syndetect, by file type, size or source"`
+	EncryptedArchives        archive.Policy         `json:"encrypted_archives" yaml:"encrypted_archives" mapstructure:"encrypted_archives" desc:"Handling of password-protected archives: passwords tried, and action on archives none of them opens"`
+	FileTypePolicy           filetype.Policy        `json:"file_type_policy" yaml:"file_type_policy" mapstructure:"file_type_policy" desc:"Scan, skip or block files per type and location (e.g. skip videos, block executables from temp directories)"`
+	AnalysisWorkers          int                    `json:"analysis_workers" yaml:"analysis_workers" mapstructure:"analysis_workers" validate:"min=0" desc:"Number of concurrent GLIMPS Malware submissions" default:"4"`
+	GMalwareDailyBudget      int                    `json:"gmalware_daily_budget" yaml:"gmalware_daily_budget" mapstructure:"gmalware_daily_budget" validate:"min=0" desc:"Optional maximum number of GLIMPS Malware submissions per day (UTC) of this connector, so it can not consume the whole organization quota, 0 for no limit" default:"0"`
+	GMalwareProfiles         []GMalwareProfile      `json:"gmalware_profiles" yaml:"gmalware_profiles" mapstructure:"gmalware_profiles" validate:"omitempty,unique=Name,dive" desc:"Optional GLIMPS Malware profiles (e.g. one per department), the first profile whose rules match an item is used to analyze it. Items matching no profile use default GLIMPS Malware settings" default:"[]"`
+	Privacy                  events.Privacy         `json:"privacy" yaml:"privacy" mapstructure:"privacy" desc:"Personal data minimization (hash or truncate) applied to events before they are sent to console"`
+	FieldEncryption          events.FieldEncryption `json:"field_encryption" yaml:"field_encryption" mapstructure:"field_encryption" desc:"Encryption of sensitive event fields with a key shared with console"`
+	Debug                    bool                   `json:"debug" yaml:"debug" mapstructure:"debug" desc:"Enable debug log, and the localhost debug server from next connector start"`
 }
 
 type ConsoleConfig struct {
 	APIKey   string
 	URL      string
 	Insecure bool
+	// Image overrides connector images in compose files and helm values (templates: {{ .Image.Ref "repository" "tag" }})
+	Image ImageConfig
+}
+
+// WithImageDefaults returns config with image defaults of loader applied (DevImageDefaults in dev mode).
+// It is applied by GetTemplatedLaunchSteps, GetTemplatedDockerCompose, and by GetTemplatedHelm to the console config
+// embedded in helm config (see ConfigHelmer).
+func (c ConnectorTypeLoader) WithImageDefaults(config ConsoleConfig) ConsoleConfig {
+	if c.dev {
+		config.Image = config.Image.withDefaults(DevImageDefaults)
+	}
+	return config
+}
+
+// withValidImage returns config with image defaults of loader applied (see WithImageDefaults), or a ValidationError
+// if its image overrides are invalid (e.g. unknown pull policy).
+func (c ConnectorTypeLoader) withValidImage(config ConsoleConfig) (validConfig ConsoleConfig, err error) {
+	validator, err := DefaultValidator()
+	if err != nil {
+		return
+	}
+	if err = validator.Validate(config.Image); err != nil {
+		err = newValidationError(err)
+		return
+	}
+	validConfig = c.WithImageDefaults(config)
+	return
 }
 
-func (c ConnectorTypeLoader) GetConnectorTypes() (connectorTypes []ConnectorType) {
+// helmConfigWithImage returns a copy of helmConfig whose ConsoleConfig, embedded or helmConfig itself, has image
+// defaults of loader applied (see withValidImage). Helm configs without ConsoleConfig are returned as is.
+func (c ConnectorTypeLoader) helmConfigWithImage(helmConfig any) (config any, err error) {
+	config = helmConfig
+	value := reflect.ValueOf(helmConfig)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	consoleConfigType := reflect.TypeFor[ConsoleConfig]()
+	if value.Kind() != reflect.Struct {
+		return
+	}
+	copied := reflect.New(value.Type()).Elem()
+	copied.Set(value)
+	field := copied
+	if value.Type() != consoleConfigType {
+		field = copied.FieldByName("ConsoleConfig")
+		if !field.IsValid() || field.Type() != consoleConfigType {
+			return
+		}
+	}
+	consoleConfig, err := c.withValidImage(field.Interface().(ConsoleConfig))
+	if err != nil {
+		return
+	}
+	field.Set(reflect.ValueOf(consoleConfig))
+	config = copied.Interface()
+	return
+}
+
+// DeploymentMethod is a way to deploy connectors of a type, provided by the console.
+type DeploymentMethod string
+
+const (
+	DeployHelm          DeploymentMethod = "helm"
+	DeployDockerCompose DeploymentMethod = "docker-compose"
+)
+
+func (DeploymentMethod) Values() []DeploymentMethod {
+	return []DeploymentMethod{DeployHelm, DeployDockerCompose}
+}
+
+// DeploymentMethodTag is the validator tag validating a DeploymentMethod.
+const DeploymentMethodTag = "deployment_method"
+
+func (DeploymentMethod) Validation() validation.EnumValidation {
+	return validation.NewEnumValidation(DeploymentMethod("").Values())
+}
+
+// FilterOptions selects connector types returned by GetConnectorTypes, empty fields matching any connector type.
+type FilterOptions struct {
+	// MitigationType selects connector types mitigating this info type
+	MitigationType events.MitigationInfoType `validate:"omitempty,mitigation_infotype"`
+	// DeploymentMethod selects connector types the console can deploy this way
+	DeploymentMethod DeploymentMethod `validate:"omitempty,deployment_method"`
+	// Search selects connector types whose id, name or description contains it, case insensitive
+	Search string
+	// IncludeDev includes dev only connector types, loaded in dev mode only (see NewConnectorsTypesLoader)
+	IncludeDev bool
+
+	// OmitLogo returns connector types without their logo, e.g. for list views
+	OmitLogo bool
+	// OmitConfigFields returns connector types without their config fields
+	OmitConfigFields bool
+	// IDAndNameOnly returns only id and name of connector types
+	IDAndNameOnly bool
+
+	// Offset is the number of matching connector types skipped
+	Offset int `validate:"min=0"`
+	// Limit is the maximum number of connector types returned, all if 0
+	Limit int `validate:"min=0"`
+}
+
+// project returns c with fields excluded by o cleared, c slices being left untouched.
+func (o FilterOptions) project(c ConnectorType) ConnectorType {
+	if o.IDAndNameOnly {
+		return ConnectorType{ID: c.ID, Name: c.Name}
+	}
+	if o.OmitLogo {
+		c.Logo = ""
+	}
+	if o.OmitConfigFields {
+		c.Configs = nil
+	}
+	return c
+}
+
+func (o FilterOptions) match(c ConnectorType) bool {
+	switch {
+	case c.DevOnly && !o.IncludeDev:
+		return false
+	case o.MitigationType != "" && !slices.Contains(c.MitigationInfoTypes, o.MitigationType):
+		return false
+	case o.DeploymentMethod == DeployHelm && !c.Helm, o.DeploymentMethod == DeployDockerCompose && !c.DockerCompose:
+		return false
+	case o.Search == "":
+		return true
+	}
+	search := strings.ToLower(o.Search)
+	for _, field := range []string{c.ID, c.Name, c.Description} {
+		if strings.Contains(strings.ToLower(field), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetConnectorTypes returns the page of connector types matching opts, sorted by id, projected as requested by opts,
+// and total, the number of matching connector types. Use GetConnectorType for a complete connector type.
+// Invalid opts (e.g. unknown deployment method) are reported as a ValidationError.
+func (c ConnectorTypeLoader) GetConnectorTypes(opts FilterOptions) (connectorTypes []ConnectorType, total int, err error) {
+	validator, err := DefaultValidator()
+	if err != nil {
+		return
+	}
+	if err = validator.Validate(opts); err != nil {
+		err = newValidationError(err)
+		return
+	}
 	for _, v := range c.connectorsTypes {
+		if !opts.match(v) {
+			continue
+		}
 		connectorTypes = append(connectorTypes, v)
 	}
 	slices.SortStableFunc(connectorTypes, func(a, b ConnectorType) int {
@@ -187,6 +461,14 @@ func (c ConnectorTypeLoader) GetConnectorTypes() (connectorTypes []ConnectorType
 			return -1
 		}
 	})
+	total = len(connectorTypes)
+	connectorTypes = connectorTypes[min(opts.Offset, total):]
+	if opts.Limit > 0 && opts.Limit < len(connectorTypes) {
+		connectorTypes = connectorTypes[:opts.Limit]
+	}
+	for i, connectorType := range connectorTypes {
+		connectorTypes[i] = opts.project(connectorType)
+	}
 	return
 }
 
@@ -206,8 +488,29 @@ var (
 	ErrConnectorFileNotFound = errors.New("error connector file not found")
 	ErrBadConfigFieldStruct  = errors.New("error config field has unknown type, could not prepare config form properly")
 	ErrDevConnector          = errors.New("error connector only available in dev mode")
+	ErrNoSetupFlow           = errors.New("no setup flow for this connector type")
+	ErrInvalidCapability     = errors.New("invalid connector capability")
+	ErrInvalidConnectorFile  = errors.New("invalid connector.yaml")
+	ErrUnknownHelmVersion    = errors.New("unknown helm chart version")
+
+	ErrInvalidMitigationInfoType = errors.New("invalid connector mitigation info type")
 )
 
+// GetSetupFlow returns the setup wizard declared for given connector type.
+func (c ConnectorTypeLoader) GetSetupFlow(connectorTypeID string) (flow setupflow.Flow, err error) {
+	connectorType, ok := c.connectorsTypes[connectorTypeID]
+	if !ok {
+		err = ErrConnectorTypeNotFound
+		return
+	}
+	if connectorType.SetupFlow == nil {
+		err = ErrNoSetupFlow
+		return
+	}
+	flow = *connectorType.SetupFlow
+	return
+}
+
 type LaunchStepConfig struct {
 	ConnectorConfig any
 	ConsoleConfig   ConsoleConfig
@@ -218,6 +521,9 @@ func (c ConnectorTypeLoader) GetTemplatedLaunchSteps(connectorType string, confi
 		err = ErrConnectorTypeNotFound
 		return
 	}
+	if config.ConsoleConfig, err = c.withValidImage(config.ConsoleConfig); err != nil {
+		return
+	}
 	steps = make([]Step, 0, len(c.connectorsTypes[connectorType].LaunchSteps))
 	for _, step := range c.connectorsTypes[connectorType].LaunchSteps {
 		tmpl, tmplErr := template.New("step").Parse(step.Description)
@@ -247,6 +553,9 @@ func (c ConnectorTypeLoader) GetTemplatedDockerCompose(connectorTypeID string, c
 		return
 	}
 
+	if config, err = c.withValidImage(config); err != nil {
+		return
+	}
 	rawCompose, err := configFS.ReadFile(filepath.Join(connectorsFolderName, connectorTypeID, dockerComposeFileName))
 	if err != nil {
 		return
@@ -259,11 +568,29 @@ func (c ConnectorTypeLoader) GetTemplatedDockerCompose(connectorTypeID string, c
 	if err = tmpl.Execute(b, config); err != nil {
 		return
 	}
+	if connectorType.HasCapability(CapabilityHealth) {
+		var probed []byte
+		if probed, err = withComposeProbes(b.Bytes()); err != nil {
+			return
+		}
+		b = bytes.NewBuffer(probed)
+	}
 	dockerCompose = b.String()
 	return
 }
 
-func (c ConnectorTypeLoader) GetTemplatedHelm(connectorTypeID string, config any) (r io.Reader, err error) {
+// HelmOptions selects what GetTemplatedHelm returns.
+type HelmOptions struct {
+	// Version is the helm chart version, latest one if empty (see ConnectorType.HelmVersions)
+	Version string
+	// ValuesOnly returns rendered values.yaml only, instead of a zip archive, e.g. for charts pulled from an OCI registry
+	ValuesOnly bool
+}
+
+// GetTemplatedHelm returns a zip archive holding helm chart of connector type and its values templated with config,
+// or these values only with opts.ValuesOnly. Image defaults are applied to the ConsoleConfig embedded in config, a
+// ValidationError being returned for invalid image overrides.
+func (c ConnectorTypeLoader) GetTemplatedHelm(connectorTypeID string, config any, opts HelmOptions) (r io.Reader, err error) {
 	connectorType, ok := c.connectorsTypes[connectorTypeID]
 	if !ok {
 		err = ErrConnectorTypeNotFound
@@ -275,8 +602,18 @@ func (c ConnectorTypeLoader) GetTemplatedHelm(connectorTypeID string, config any
 		return
 	}
 
+	version := opts.Version
+	if version == "" {
+		version = connectorType.HelmVersion
+	}
+	if !slices.Contains(connectorType.HelmVersions, version) {
+		err = fmt.Errorf("%w %s for %s", ErrUnknownHelmVersion, version, connectorTypeID)
+		return
+	}
+
 	// get helm values templated
-	rawValues, err := configFS.ReadFile(filepath.Join(connectorsFolderName, connectorTypeID, helmFolderName, helmValuesFileName))
+	helmFolder := filepath.Join(connectorsFolderName, connectorTypeID, helmFolderName)
+	rawValues, err := configFS.ReadFile(helmValuesPath(helmFolder, version))
 	if err != nil {
 		return
 	}
@@ -284,6 +621,24 @@ func (c ConnectorTypeLoader) GetTemplatedHelm(connectorTypeID string, config any
 	if err != nil {
 		return
 	}
+	if config, err = c.helmConfigWithImage(config); err != nil {
+		return
+	}
+	values := bytes.NewBuffer(nil)
+	if err = tmpl.Execute(values, config); err != nil {
+		return
+	}
+	if connectorType.HasCapability(CapabilityHealth) {
+		var probed []byte
+		if probed, err = withHelmProbes(values.Bytes()); err != nil {
+			return
+		}
+		values = bytes.NewBuffer(probed)
+	}
+	if opts.ValuesOnly {
+		r = values
+		return
+	}
 	// add files to archive
 	buffer := bytes.NewBuffer(nil)
 	archive := zip.NewWriter(buffer)
@@ -296,17 +651,16 @@ func (c ConnectorTypeLoader) GetTemplatedHelm(connectorTypeID string, config any
 	if err != nil {
 		return
 	}
-
-	if err = tmpl.Execute(w, config); err != nil {
+	if _, err = io.Copy(w, values); err != nil {
 		return
 	}
 
-	helmFileName := fmt.Sprintf("%v-%v.tgz", connectorTypeID, connectorType.HelmVersion)
+	helmFileName := fmt.Sprintf("%v-%v.tgz", connectorTypeID, version)
 	w, err = archive.Create(helmFileName)
 	if err != nil {
 		return
 	}
-	helmFile, err := configFS.Open(filepath.Join(connectorsFolderName, connectorTypeID, helmFolderName, helmFileName))
+	helmFile, err := configFS.Open(filepath.Join(helmFolder, helmFileName))
 	if err != nil {
 		return
 	}
@@ -340,30 +694,74 @@ func (c ConnectorTypeLoader) GetConnectorFile(connectorType string, fileID strin
 	}
 }
 
-func checkHelmFolder(connectorFolder string) (helmVersion string, err error) {
-	entries, err := configFS.ReadDir(filepath.Join(connectorFolder, helmFolderName))
+// helmChartVersion returns version of name, a helm chart archive of connector type id (<id>-<version>.tgz).
+// Archives of other charts, e.g. whose name only ends with id, are ignored.
+func helmChartVersion(id string, name string) (version string, ok bool) {
+	match := regexp.MustCompile(`^` + regexp.QuoteMeta(id) + `-(\d+\.\d+\.\d+)\.tgz$`).FindStringSubmatch(name)
+	if len(match) < 2 {
+		return
+	}
+	version, ok = match[1], true
+	return
+}
+
+// helmValuesPath returns path of values template of helm chart version, values-<version>.yaml if it exists (e.g. for
+// older charts), values.yaml otherwise.
+func helmValuesPath(helmFolder string, version string) string {
+	versionPath := filepath.Join(helmFolder, fmt.Sprintf("values-%s.yaml", version))
+	if _, err := fs.Stat(configFS, versionPath); err == nil {
+		return versionPath
+	}
+	return filepath.Join(helmFolder, helmValuesFileName)
+}
+
+// checkHelmFolder returns helm chart versions of connector type id, most recent first, an ErrInvalidHelmChart error if
+// folder has no values template or chart archive, if a chart archive version does not match its name or if its values
+// template uses fields unknown to connector helm config.
+func checkHelmFolder(id string, connectorFolder string) (helmVersions []string, err error) {
+	helmFolder := filepath.Join(connectorFolder, helmFolderName)
+	entries, err := configFS.ReadDir(helmFolder)
 	if err != nil {
 		return
 	}
 	valuesOK := false
-	chartOK := false
+	versions := []string{}
 	for _, entry := range entries {
 		if entry.Name() == helmValuesFileName {
 			valuesOK = true
 			continue
 		}
-		r := regexp.MustCompile(`.*-(\d\.\d\.\d)\.tgz`)
-		version := r.FindStringSubmatch(entry.Name())
-		if len(version) > 1 {
-			helmVersion = version[1]
-			chartOK = true
-			continue
+		if version, ok := helmChartVersion(id, entry.Name()); ok {
+			versions = append(versions, version)
 		}
 	}
-	if !valuesOK || !chartOK {
-		err = errors.New("invalid helm folder")
+	if !valuesOK || len(versions) == 0 {
+		err = fmt.Errorf("%w: %s needs %s and at least one %s-<version>.tgz chart", ErrInvalidHelmChart, helmFolder, helmValuesFileName, id)
 		return
 	}
+	slices.SortFunc(versions, func(a, b string) int { return semver.New(b).Compare(*semver.New(a)) })
+	for _, helmVersion := range versions {
+		chartPath := filepath.Join(helmFolder, fmt.Sprintf("%v-%v.tgz", id, helmVersion))
+		version, versionErr := chartVersion(chartPath)
+		if versionErr != nil {
+			err = versionErr
+			return
+		}
+		if version != helmVersion {
+			err = fmt.Errorf("%w: %s Chart.yaml version is %s", ErrInvalidHelmChart, chartPath, version)
+			return
+		}
+		rawValues, readErr := configFS.ReadFile(helmValuesPath(helmFolder, helmVersion))
+		if readErr != nil {
+			err = readErr
+			return
+		}
+		if err = checkHelmValues(id, rawValues); err != nil {
+			err = fmt.Errorf("chart %s: %w", helmVersion, err)
+			return
+		}
+		helmVersions = append(helmVersions, helmVersion)
+	}
 	return
 }
 
@@ -371,6 +769,7 @@ func getConnectorDesc(id string, connectorFolder string, devMode bool) (connecto
 	connectorType = ConnectorType{
 		SetupSteps:  []Step{},
 		LaunchSteps: []Step{},
+		HowTo:       []Step{},
 		Configs:     []ConfigField{},
 	}
 	entries, err := configFS.ReadDir(connectorFolder)
@@ -384,15 +783,18 @@ func getConnectorDesc(id string, connectorFolder string, devMode bool) (connecto
 			connectorType.DockerCompose = true
 			continue
 		case helmFolderName:
-			helmVersion, helmErr := checkHelmFolder(connectorFolder)
+			helmVersions, helmErr := checkHelmFolder(id, connectorFolder)
 			if helmErr != nil {
-				continue
+				err = helmErr
+				return
 			}
-			connectorType.HelmVersion = helmVersion
+			connectorType.HelmVersions = helmVersions
+			connectorType.HelmVersion = helmVersions[0]
 			connectorType.Helm = true
 			continue
 		case logoFileName:
 		case connectorFileName:
+		case changelogFileName:
 		default:
 			continue
 		}
@@ -420,8 +822,13 @@ func getConnectorDesc(id string, connectorFolder string, devMode bool) (connecto
 		switch filepath.Base(entry.Name()) {
 		case logoFileName:
 			connectorType.Logo = base64.StdEncoding.EncodeToString(rawContent)
+		case changelogFileName:
+			connectorType.Changelog, err = decodeChangelog(rawContent)
+			if err != nil {
+				return
+			}
 		case connectorFileName:
-			err = yaml.Unmarshal(rawContent, &connectorType)
+			err = decodeConnectorFile(rawContent, &connectorType)
 			if err != nil {
 				return
 			}
@@ -439,8 +846,33 @@ func getConnectorDesc(id string, connectorFolder string, devMode bool) (connecto
 					connectorType.LaunchSteps[i].Files = make([]string, 0)
 				}
 			}
+			for i, s := range connectorType.HowTo {
+				if s.Files == nil {
+					connectorType.HowTo[i].Files = make([]string, 0)
+				}
+			}
 			connectorType.ID = id
 
+			if err = normalizeMitigationInfoTypes(&connectorType); err != nil {
+				return
+			}
+			if err = checkCapabilities(connectorType.Capabilities); err != nil {
+				return
+			}
+			if err = checkConfigSchemas(connectorType); err != nil {
+				return
+			}
+			if connectorType.Capabilities == nil {
+				connectorType.Capabilities = make([]Capability, 0)
+			}
+
+			if connectorType.SetupFlow != nil {
+				if flowErr := connectorType.SetupFlow.Validate(); flowErr != nil {
+					err = flowErr
+					return
+				}
+			}
+
 			defaultConfig, defaultErr := InitDefault(connectorType.ID)
 			if defaultErr != nil {
 				err = defaultErr
@@ -458,6 +890,21 @@ func getConnectorDesc(id string, connectorFolder string, devMode bool) (connecto
 	return
 }
 
+// decodeConnectorFile decodes connector.yaml content into connectorType. Unknown fields are rejected, so a typo
+// (e.g. setup_stepz) fails with its line instead of silently producing an empty connector type.
+func decodeConnectorFile(rawContent []byte, connectorType *ConnectorType) (err error) {
+	dec := yaml.NewDecoder(bytes.NewReader(rawContent))
+	dec.KnownFields(true)
+	err = dec.Decode(connectorType)
+	switch {
+	case errors.Is(err, io.EOF):
+		err = fmt.Errorf("%w: %s is empty", ErrInvalidConnectorFile, connectorFileName)
+	case err != nil:
+		err = fmt.Errorf("%w: %w", ErrInvalidConnectorFile, err)
+	}
+	return
+}
+
 // extracts info from any connector config struct to build configFields.
 // e.g. ICAPConfig ; SharepointConfig ; M365Config
 func getConfigFields(config any) (configFields []ConfigField, err error) {
@@ -499,10 +946,12 @@ func getConfigFields(config any) (configFields []ConfigField, err error) {
 				fieldType = StringArray
 			case reflect.Struct:
 				fieldType = ObjectArray
-				// create a zero instance to extract it's subfields (to not directly pass an array)
-				elemType := field.Type.Elem()
-				zeroElem := reflect.New(elemType).Elem().Interface()
-				subConfigFields, subErr := getConfigFields(zeroElem)
+				// create a default instance to extract it's subfields (to not directly pass an array)
+				defaultElem := reflect.New(field.Type.Elem())
+				if err = SetDefaults(defaultElem.Interface()); err != nil {
+					return
+				}
+				subConfigFields, subErr := getConfigFields(defaultElem.Elem().Interface())
 				if subErr != nil {
 					err = subErr
 					return
@@ -560,17 +1009,22 @@ func getConfigFields(config any) (configFields []ConfigField, err error) {
 					validation = append(validation, FrontValidURL)
 				case rule == "email":
 					validation = append(validation, FrontValidEmail)
+				case strings.HasPrefix(rule, DurationMinTag+"="), strings.HasPrefix(rule, DurationMaxTag+"="):
+					if !slices.Contains(validation, FrontValidDuration) {
+						validation = append(validation, FrontValidDuration)
+					}
+				case rule == FileSizeTag:
+					validation = append(validation, FrontValidFileSize)
 				case strings.HasPrefix(rule, "oneof="):
 					parts := strings.SplitN(rule, "=", 2)
 					if len(parts) == 2 && parts[1] != "" {
 						enumValues = strings.Fields(parts[1])
 					}
-
-					// to add when available in our custom validator :
-					// case "duration":
-					// 	validation = append(validation, FrontValidDuration)
-					// case "filesize":
-					// 	validation = append(validation, FrontValidDuration)
+				default:
+					// enum validations registered by the SDK (e.g. events.PIIPolicyTag)
+					if enum, isEnum := CustomValidations()[rule]; isEnum {
+						enumValues = slices.Clone(enum.Allowed)
+					}
 				}
 			}
 		}
@@ -598,76 +1052,30 @@ func getConfigFields(config any) (configFields []ConfigField, err error) {
 	return
 }
 
-// InitDefault gives pointer to default config struct for given connector type
+// InitDefault gives pointer to default config struct for given connector type, defaults are set from
+// fields default tag (see SetDefaults)
 func InitDefault(connectorType string) (config any, err error) {
-	defaultCommonConfig := CommonConnectorConfig{
-		GMalwareUserTags: []string{},
-		GMalwareTimeout:  Duration(time.Minute * 5),
-	}
-
 	switch connectorType {
 	case M365Key:
-		config = &M365Config{
-			CommonConnectorConfig: defaultCommonConfig,
-		}
+		config = &M365Config{}
 	case DummyKey:
-		config = &DummyConfig{
-			ReconfigurableDummyConfig: ReconfigurableDummyConfig{
-				CommonConnectorConfig: defaultCommonConfig,
-				Objects:               []DummyObject{},
-			},
-		}
+		config = &DummyConfig{}
 	case ICAPKey:
-		config = &ICAPConfig{
-			CommonConnectorConfig: defaultCommonConfig,
-		}
+		config = &ICAPConfig{}
 	case SharepointKey:
-		config = &SharepointConfig{
-			ReconfigurableSharepointConfig: ReconfigurableSharepointConfig{
-				CommonConnectorConfig:             defaultCommonConfig,
-				MaxUploadSize:                     "100MB",
-				RetryFrequency:                    Duration(10 * time.Minute),
-				MonitoringFrequency:               Duration(10 * time.Minute),
-				SitesToMonitorWithoutInitialScan:  []string{},
-				SitesToMonitorWithInitialScan:     []string{},
-				GroupsToMonitorWithoutInitialScan: []string{},
-				GroupsToMonitorWithInitialScan:    []string{},
-				SitesToIgnore:                     []string{},
-				GroupsToIgnore:                    []string{},
-				ExclusionRules:                    []SPExclusionRule{},
-				TimeoutFactor:                     1,
-			},
-		}
+		config = &SharepointConfig{}
 	case HostKey:
-		config = &HostConfig{
-			CommonConnectorConfig: defaultCommonConfig,
-			Actions: HostActionsConfig{
-				Delete:     true,
-				Quarantine: true,
-				Log:        true,
-			},
-			Quarantine: HostQuarantineConfig{
-				Password: "infected",
-				Location: "/var/lib/gmhost",
-			},
-			Monitoring: HostMonitoringConfig{
-				ModificationDelay: Duration(time.Second * 30),
-			},
-			Workers:                  4,
-			ExtractWorkers:           2,
-			MaxFileSize:              "100MiB",
-			RecursiveExtractMaxDepth: 10,
-			RecursiveExtractMaxSize:  "5GB",
-			RecursiveExtractMaxFiles: 10000,
-			Paths:                    []string{},
-		}
+		config = &HostConfig{}
 	default:
 		err = ErrInvalidConnectorType
 		return
 	}
+	err = SetDefaults(config)
 	return
 }
 
+// PatchConfig applies rawConfig, a JSON merge patch (see PatchAndValidateRaw), to rawActualConfig: absent fields are
+// kept and null ones cleared. Only reconfigurable fields can be patched on dummy and sharepoint connectors.
 func PatchConfig(connectorType string, rawActualConfig any, rawConfig json.RawMessage) (config any, err error) {
 	if rawConfig == nil {
 		config = rawActualConfig
@@ -680,7 +1088,7 @@ func PatchConfig(connectorType string, rawActualConfig any, rawConfig json.RawMe
 			err = errors.New("invalid config")
 			return
 		}
-		err = BindAndValidateRaw(actualConfig, rawConfig)
+		err = PatchAndValidateRaw(actualConfig, rawConfig)
 		if err != nil {
 			return
 		}
@@ -696,7 +1104,7 @@ func PatchConfig(connectorType string, rawActualConfig any, rawConfig json.RawMe
 			err = errors.New("invalid config")
 			return
 		}
-		err = BindAndValidateRaw(actualConfig, rawConfig)
+		err = PatchAndValidateRaw(actualConfig, rawConfig)
 		if err != nil {
 			return
 		}
@@ -707,7 +1115,7 @@ func PatchConfig(connectorType string, rawActualConfig any, rawConfig json.RawMe
 			err = errors.New("invalid config")
 			return
 		}
-		err = BindAndValidateRaw(actualConfig, rawConfig)
+		err = PatchAndValidateRaw(actualConfig, rawConfig)
 		if err != nil {
 			return
 		}
@@ -723,7 +1131,7 @@ func PatchConfig(connectorType string, rawActualConfig any, rawConfig json.RawMe
 			err = errors.New("invalid config")
 			return
 		}
-		err = BindAndValidateRaw(actualConfig, rawConfig)
+		err = PatchAndValidateRaw(actualConfig, rawConfig)
 		if err != nil {
 			return
 		}
@@ -734,7 +1142,7 @@ func PatchConfig(connectorType string, rawActualConfig any, rawConfig json.RawMe
 			err = errors.New("invalid config")
 			return
 		}
-		err = BindAndValidateRaw(actualConfig, rawConfig)
+		err = PatchAndValidateRaw(actualConfig, rawConfig)
 		if err != nil {
 			return
 		}
@@ -1,8 +1,17 @@
 package sdk
 
 import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/msauth"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 )
@@ -54,6 +63,7 @@ func TestConnectorsTypesLoader_GetConnectorTypes(t *testing.T) {
 	tests := []struct {
 		name               string
 		fields             fields
+		opts               FilterOptions
 		wantConnectorTypes []ConnectorType
 		wantErr            bool
 	}{
@@ -71,7 +81,7 @@ func TestConnectorsTypesLoader_GetConnectorTypes(t *testing.T) {
 								Description: "Deploy it",
 							},
 						},
-						MitigationInfoType: "infotype1",
+						MitigationInfoTypes: []events.MitigationInfoType{events.InfoTypeFile},
 					},
 					"connector2": {
 						Name:        "Connector 2",
@@ -83,7 +93,7 @@ func TestConnectorsTypesLoader_GetConnectorTypes(t *testing.T) {
 								Description: "Deploy it",
 							},
 						},
-						MitigationInfoType: "infotype2",
+						MitigationInfoTypes: []events.MitigationInfoType{events.InfoTypeEmail},
 					},
 				},
 			},
@@ -98,7 +108,7 @@ func TestConnectorsTypesLoader_GetConnectorTypes(t *testing.T) {
 							Description: "Deploy it",
 						},
 					},
-					MitigationInfoType: "infotype1",
+					MitigationInfoTypes: []events.MitigationInfoType{events.InfoTypeFile},
 				},
 				{
 					Name:        "Connector 2",
@@ -110,7 +120,7 @@ func TestConnectorsTypesLoader_GetConnectorTypes(t *testing.T) {
 							Description: "Deploy it",
 						},
 					},
-					MitigationInfoType: "infotype2",
+					MitigationInfoTypes: []events.MitigationInfoType{events.InfoTypeEmail},
 				},
 			},
 		},
@@ -120,7 +130,10 @@ func TestConnectorsTypesLoader_GetConnectorTypes(t *testing.T) {
 			c := ConnectorTypeLoader{
 				connectorsTypes: tt.fields.connectorsTypes,
 			}
-			gotConnectorTypes := c.GetConnectorTypes()
+			gotConnectorTypes, _, err := c.GetConnectorTypes(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConnectorsTypesLoader.GetConnectorTypes() error = %v, wantErr %v", err, tt.wantErr)
+			}
 			if diff := cmp.Diff(gotConnectorTypes, tt.wantConnectorTypes); diff != "" {
 				t.Errorf("ConnectorsTypesLoader.GetConnectorTypes() diff(got-want)=%s", diff)
 			}
@@ -330,12 +343,21 @@ func TestConnectorTypeLoader_GetTemplatedHelm(t *testing.T) {
 	type args struct {
 		connectorType string
 		config        any
+		opts          HelmOptions
+	}
+	dummyConf := DummyHelmConf{
+		ConsoleConfig: ConsoleConfig{
+			APIKey: "api-key",
+			Image:  ImageConfig{Tag: "1.2.0"},
+		},
+		DummyField1: "custom",
 	}
 	tests := []struct {
-		name              string
-		args              args
-		wantDockerCompose string
-		wantErr           bool
+		name      string
+		args      args
+		wantChart string
+		wantImage bool
+		wantErr   error
 	}{
 		{
 			name: "error unknown connector type",
@@ -343,7 +365,7 @@ func TestConnectorTypeLoader_GetTemplatedHelm(t *testing.T) {
 				connectorType: "toto",
 				config:        ConsoleConfig{},
 			},
-			wantErr: true,
+			wantErr: ErrConnectorTypeNotFound,
 		},
 		{
 			name: "ok sharepoint",
@@ -356,18 +378,43 @@ func TestConnectorTypeLoader_GetTemplatedHelm(t *testing.T) {
 					SharepointWebhookHost: "client1.sharepoint.monserveur.glimps.lan",
 				},
 			},
+			wantChart: "sharepoint-0.1.0.tgz",
 		},
 		{
-			name: "ok dummy",
+			name: "ok dummy latest",
 			args: args{
 				connectorType: DummyKey,
-				config: DummyHelmConf{
-					ConsoleConfig: ConsoleConfig{
-						APIKey: "api-key",
-					},
-					DummyField1: "custom",
-				},
+				config:        dummyConf,
 			},
+			wantChart: "dummy-0.1.0.tgz",
+			wantImage: true,
+		},
+		{
+			name: "ok dummy older version",
+			args: args{
+				connectorType: DummyKey,
+				config:        dummyConf,
+				opts:          HelmOptions{Version: "0.0.1"},
+			},
+			wantChart: "dummy-0.0.1.tgz",
+		},
+		{
+			name: "ok dummy values only",
+			args: args{
+				connectorType: DummyKey,
+				config:        dummyConf,
+				opts:          HelmOptions{ValuesOnly: true},
+			},
+			wantImage: true,
+		},
+		{
+			name: "error unknown version",
+			args: args{
+				connectorType: DummyKey,
+				config:        dummyConf,
+				opts:          HelmOptions{Version: "9.9.9"},
+			},
+			wantErr: ErrUnknownHelmVersion,
 		},
 	}
 	for _, tt := range tests {
@@ -376,15 +423,66 @@ func TestConnectorTypeLoader_GetTemplatedHelm(t *testing.T) {
 			if err != nil {
 				t.Fatalf("could not init connector types loader, err: %v", err)
 			}
-			_, err = c.GetTemplatedHelm(tt.args.connectorType, tt.args.config)
-			if (err != nil) != tt.wantErr {
+			r, err := c.GetTemplatedHelm(tt.args.connectorType, tt.args.config, tt.args.opts)
+			if !errors.Is(err, tt.wantErr) {
 				t.Errorf("ConnectorTypeLoader.GetTemplatedHelm() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if err != nil {
+				return
+			}
+			raw, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("could not read helm archive, error: %v", err)
+			}
+			rawValues := raw
+			if !tt.args.opts.ValuesOnly {
+				archive, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+				if err != nil {
+					t.Fatalf("invalid helm archive, error: %v", err)
+				}
+				files := []string{}
+				for _, f := range archive.File {
+					files = append(files, f.Name)
+				}
+				if diff := cmp.Diff(files, []string{helmValuesFileName, tt.wantChart}); diff != "" {
+					t.Errorf("ConnectorTypeLoader.GetTemplatedHelm() files diff(got-want)=%s", diff)
+				}
+				values, err := archive.Open(helmValuesFileName)
+				if err != nil {
+					t.Fatalf("could not open values, error: %v", err)
+				}
+				if rawValues, err = io.ReadAll(values); err != nil {
+					t.Fatalf("could not read values, error: %v", err)
+				}
+			}
+			if !bytes.HasPrefix(rawValues, []byte("config:\n  connector-manager:")) {
+				t.Errorf("ConnectorTypeLoader.GetTemplatedHelm() values = %s, want rendered values.yaml", rawValues)
+			}
+			if got := bytes.Contains(rawValues, []byte("version: 1.2.0")); got != tt.wantImage {
+				t.Errorf("ConnectorTypeLoader.GetTemplatedHelm() values = %s, want image override %v", rawValues, tt.wantImage)
+			}
 		})
 	}
 }
 
+func TestNewConnectorsTypesLoader_helmVersions(t *testing.T) {
+	c, err := NewConnectorsTypesLoader(true)
+	if err != nil {
+		t.Fatalf("could not init connector types loader, err: %v", err)
+	}
+	connectorType, err := c.GetConnectorType(DummyKey)
+	if err != nil {
+		t.Fatalf("GetConnectorType() error = %v", err)
+	}
+	if connectorType.HelmVersion != "0.1.0" {
+		t.Errorf("HelmVersion = %s, want latest 0.1.0", connectorType.HelmVersion)
+	}
+	if diff := cmp.Diff(connectorType.HelmVersions, []string{"0.1.0", "0.0.1"}); diff != "" {
+		t.Errorf("HelmVersions diff(got-want)=%s", diff)
+	}
+}
+
 func Test_getConfigFields(t *testing.T) {
 	type TestCommonConnectorConfig struct {
 		ClientName          string   `json:"client_name" validate:"required" desc:"Name of the client"`
@@ -404,6 +502,7 @@ func Test_getConfigFields(t *testing.T) {
 		NotEnum        string `json:"not_enum" validate:"required" desc:"Field without enum (no oneof)"`
 		Enum           string `json:"mitigation_action" mapstructure:"mitigation_action" validate:"required,oneof=quarantine delete log" desc:"Action to perform when a file is detected as malware."`
 		EnumWithSpaces string `json:"enum_with_spaces" validate:"oneof=opt1  opt2   opt3" desc:"Enum with spaces"`
+		RegisteredEnum string `json:"registered_enum" validate:"omitempty,pii_policy" desc:"Enum of a registered validation"`
 	}
 	type testNestedObject struct {
 		Field1 string `json:"field_1" desc:"Nested field 1"`
@@ -656,6 +755,16 @@ func Test_getConfigFields(t *testing.T) {
 					DefaultValue:   "",
 					Enum:           []string{"opt1", "opt2", "opt3"},
 				},
+				{
+					Name:           "RegisteredEnum",
+					Key:            "registered_enum",
+					Type:           "string",
+					Description:    "Enum of a registered validation",
+					Validation:     []FrontValidation{},
+					Reconfigurable: true,
+					DefaultValue:   "",
+					Enum:           []string{"keep", "hash", "truncate"},
+				},
 			},
 		},
 		{
@@ -760,3 +869,381 @@ func Test_getConfigFields(t *testing.T) {
 		})
 	}
 }
+
+func Test_getConfigFields_commonPasswords(t *testing.T) {
+	configFields, err := getConfigFields(CommonConnectorConfig{})
+	if err != nil {
+		t.Fatalf("getConfigFields() error = %v", err)
+	}
+	passwords := map[string]bool{}
+	var collect func(prefix string, fields []ConfigField)
+	collect = func(prefix string, fields []ConfigField) {
+		for _, field := range fields {
+			if field.Password {
+				passwords[prefix+field.Key] = true
+			}
+			collect(prefix+field.Key+".", field.Properties)
+		}
+	}
+	collect("", configFields)
+	for _, key := range []string{"gmalware_fallback_api_token", "gmalware_profiles.api_token", "field_encryption.key"} {
+		if !passwords[key] {
+			t.Errorf("getConfigFields() %s is not a password field", key)
+		}
+	}
+}
+
+func TestConnectorTypeLoader_GetSetupFlow(t *testing.T) {
+	tests := []struct {
+		name          string
+		connectorType string
+		wantFirstPage string
+		wantErr       error
+	}{
+		{
+			name:          "error connector type not found",
+			connectorType: "toto",
+			wantErr:       ErrConnectorTypeNotFound,
+		},
+		{
+			name:          "error no setup flow",
+			connectorType: ICAPKey,
+			wantErr:       ErrNoSetupFlow,
+		},
+		{
+			name:          "ok sharepoint",
+			connectorType: SharepointKey,
+			wantFirstPage: "app_registration",
+		},
+		{
+			name:          "ok m365",
+			connectorType: M365Key,
+			wantFirstPage: "app_registration",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewConnectorsTypesLoader(true)
+			if err != nil {
+				t.Fatalf("could not init connector types loader, err: %v", err)
+			}
+			flow, err := c.GetSetupFlow(tt.connectorType)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConnectorTypeLoader.GetSetupFlow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if flow.Pages[0].ID != tt.wantFirstPage {
+				t.Errorf("ConnectorTypeLoader.GetSetupFlow() first page = %s, want %s", flow.Pages[0].ID, tt.wantFirstPage)
+			}
+		})
+	}
+}
+
+func TestConnectorType_Capabilities(t *testing.T) {
+	c, err := NewConnectorsTypesLoader(true)
+	if err != nil {
+		t.Fatalf("could not init connector types loader, err: %v", err)
+	}
+	tests := []struct {
+		name          string
+		connectorType string
+		capability    Capability
+		want          bool
+	}{
+		{name: "host quarantine list", connectorType: HostKey, capability: CapabilityQuarantineList, want: true},
+		{name: "host restore", connectorType: HostKey, capability: CapabilityRestore, want: true},
+		{name: "icap metrics", connectorType: ICAPKey, capability: CapabilityMetrics, want: true},
+		{name: "icap restore", connectorType: ICAPKey, capability: CapabilityRestore},
+		{name: "sharepoint rescan", connectorType: SharepointKey, capability: CapabilityRescan, want: true},
+		{name: "dummy health", connectorType: DummyKey, capability: CapabilityHealth, want: true},
+		{name: "dummy no rescan", connectorType: DummyKey, capability: CapabilityRescan},
+		{name: "sharepoint health", connectorType: SharepointKey, capability: CapabilityHealth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connectorType, err := c.GetConnectorType(tt.connectorType)
+			if err != nil {
+				t.Fatalf("GetConnectorType() error = %v", err)
+			}
+			if got := connectorType.HasCapability(tt.capability); got != tt.want {
+				t.Errorf("ConnectorType.HasCapability(%s) = %v, want %v", tt.capability, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkCapabilities(t *testing.T) {
+	tests := []struct {
+		name         string
+		capabilities []Capability
+		wantErr      error
+	}{
+		{name: "none"},
+		{name: "ok", capabilities: []Capability{CapabilityRestore, CapabilityMetrics}},
+		{name: "unknown", capabilities: []Capability{CapabilityRestore, "reboot"}, wantErr: ErrInvalidCapability},
+		{name: "duplicated", capabilities: []Capability{CapabilityPause, CapabilityPause}, wantErr: ErrInvalidCapability},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkCapabilities(tt.capabilities); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkCapabilities() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_normalizeMitigationInfoTypes(t *testing.T) {
+	tests := []struct {
+		name          string
+		connectorType ConnectorType
+		want          []events.MitigationInfoType
+		wantErr       error
+	}{
+		{
+			name:          "list",
+			connectorType: ConnectorType{MitigationInfoTypes: []events.MitigationInfoType{events.InfoTypeURL, events.InfoTypeFile}},
+			want:          []events.MitigationInfoType{events.InfoTypeURL, events.InfoTypeFile},
+		},
+		{
+			name:          "deprecated singular",
+			connectorType: ConnectorType{MitigationInfoType: "email"},
+			want:          []events.MitigationInfoType{events.InfoTypeEmail},
+		},
+		{
+			name:          "singular and list",
+			connectorType: ConnectorType{MitigationInfoType: "url", MitigationInfoTypes: []events.MitigationInfoType{events.InfoTypeFile}},
+			want:          []events.MitigationInfoType{events.InfoTypeURL, events.InfoTypeFile},
+		},
+		{
+			name:          "singular in list",
+			connectorType: ConnectorType{MitigationInfoType: "file", MitigationInfoTypes: []events.MitigationInfoType{events.InfoTypeURL, events.InfoTypeFile}},
+			want:          []events.MitigationInfoType{events.InfoTypeURL, events.InfoTypeFile},
+		},
+		{name: "none", wantErr: ErrInvalidMitigationInfoType},
+		{
+			name:          "unknown",
+			connectorType: ConnectorType{MitigationInfoTypes: []events.MitigationInfoType{"sms"}},
+			wantErr:       ErrInvalidMitigationInfoType,
+		},
+		{
+			name:          "duplicated",
+			connectorType: ConnectorType{MitigationInfoTypes: []events.MitigationInfoType{events.InfoTypeFile, events.InfoTypeFile}},
+			wantErr:       ErrInvalidMitigationInfoType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := normalizeMitigationInfoTypes(&tt.connectorType)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("normalizeMitigationInfoTypes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.connectorType.MitigationInfoTypes, tt.want); diff != "" {
+				t.Errorf("normalizeMitigationInfoTypes() diff(got-want)=%s", diff)
+			}
+			if tt.connectorType.MitigationInfoType != string(tt.want[0]) {
+				t.Errorf("normalizeMitigationInfoTypes() MitigationInfoType = %s, want %s", tt.connectorType.MitigationInfoType, tt.want[0])
+			}
+		})
+	}
+}
+
+func Test_decodeConnectorFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        ConnectorType
+		wantErr     error
+		wantMessage string
+	}{
+		{
+			name:    "ok",
+			content: "name: Test\nmitigation_info_types:\n  - file\nsetup_steps:\n  - name: step\n    files: [a.txt]\nhow-to:\n  - name: proxy\n",
+			want: ConnectorType{
+				Name:                "Test",
+				MitigationInfoTypes: []events.MitigationInfoType{events.InfoTypeFile},
+				SetupSteps:          []Step{{Name: "step", Files: []string{"a.txt"}}},
+				HowTo:               []Step{{Name: "proxy"}},
+			},
+		},
+		{
+			name:        "unknown field",
+			content:     "name: Test\nsetup_stepz:\n  - name: step\n",
+			wantErr:     ErrInvalidConnectorFile,
+			wantMessage: "line 2: field setup_stepz not found",
+		},
+		{
+			name:        "unknown nested field",
+			content:     "name: Test\nlaunch_steps:\n  - name: step\n    descrption: typo\n",
+			wantErr:     ErrInvalidConnectorFile,
+			wantMessage: "line 4: field descrption not found",
+		},
+		{
+			name:        "computed field",
+			content:     "name: Test\nlogo: abc\n",
+			wantErr:     ErrInvalidConnectorFile,
+			wantMessage: "field logo not found",
+		},
+		{name: "empty", wantErr: ErrInvalidConnectorFile, wantMessage: "connector.yaml is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ConnectorType{}
+			err := decodeConnectorFile([]byte(tt.content), &got)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("decodeConnectorFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), tt.wantMessage) {
+					t.Errorf("decodeConnectorFile() error = %v, want it to contain %q", err, tt.wantMessage)
+				}
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("decodeConnectorFile() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectorTypeLoader_GetConnectorTypes_filter(t *testing.T) {
+	c, err := NewConnectorsTypesLoader(true)
+	if err != nil {
+		t.Fatalf("could not init connector types loader, err: %v", err)
+	}
+	tests := []struct {
+		name    string
+		opts    FilterOptions
+		wantIDs []string
+		wantErr bool
+	}{
+		{name: "default", wantIDs: []string{HostKey, ICAPKey, SharepointKey}},
+		{name: "include dev", opts: FilterOptions{IncludeDev: true}, wantIDs: []string{DummyKey, HostKey, ICAPKey, M365Key, SharepointKey}},
+		{name: "helm", opts: FilterOptions{DeploymentMethod: DeployHelm}, wantIDs: []string{SharepointKey}},
+		{name: "helm include dev", opts: FilterOptions{DeploymentMethod: DeployHelm, IncludeDev: true}, wantIDs: []string{DummyKey, SharepointKey}},
+		{name: "docker compose", opts: FilterOptions{DeploymentMethod: DeployDockerCompose}, wantIDs: []string{HostKey, ICAPKey, SharepointKey}},
+		{name: "email", opts: FilterOptions{MitigationType: events.InfoTypeEmail, IncludeDev: true}, wantIDs: []string{M365Key}},
+		{name: "email without dev", opts: FilterOptions{MitigationType: events.InfoTypeEmail}},
+		{name: "search id", opts: FilterOptions{Search: "icap"}, wantIDs: []string{ICAPKey}},
+		{name: "search name case insensitive", opts: FilterOptions{Search: "ONEDRIVE"}, wantIDs: []string{SharepointKey}},
+		{name: "search description", opts: FilterOptions{Search: "security agent"}, wantIDs: []string{HostKey}},
+		{name: "search no match", opts: FilterOptions{Search: "teams"}},
+		{name: "unknown deployment method", opts: FilterOptions{DeploymentMethod: "kubectl"}, wantErr: true},
+		{name: "unknown mitigation type", opts: FilterOptions{MitigationType: "sms"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotIDs []string
+			connectorTypes, _, err := c.GetConnectorTypes(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConnectorTypeLoader.GetConnectorTypes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.As(err, &ValidationError{}) {
+					t.Errorf("ConnectorTypeLoader.GetConnectorTypes() error = %v, want a ValidationError", err)
+				}
+				return
+			}
+			for _, connectorType := range connectorTypes {
+				gotIDs = append(gotIDs, connectorType.ID)
+			}
+			if diff := cmp.Diff(gotIDs, tt.wantIDs); diff != "" {
+				t.Errorf("ConnectorTypeLoader.GetConnectorTypes() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectorTypeLoader_GetConnectorTypes_page(t *testing.T) {
+	c, err := NewConnectorsTypesLoader(true)
+	if err != nil {
+		t.Fatalf("could not init connector types loader, err: %v", err)
+	}
+	tests := []struct {
+		name       string
+		opts       FilterOptions
+		wantIDs    []string
+		wantTotal  int
+		wantLogo   bool
+		wantConfig bool
+		wantErr    bool
+	}{
+		{name: "complete", opts: FilterOptions{IncludeDev: true}, wantIDs: []string{DummyKey, HostKey, ICAPKey, M365Key, SharepointKey}, wantTotal: 5, wantLogo: true, wantConfig: true},
+		{name: "first page", opts: FilterOptions{IncludeDev: true, Limit: 2}, wantIDs: []string{DummyKey, HostKey}, wantTotal: 5, wantLogo: true, wantConfig: true},
+		{name: "last page", opts: FilterOptions{IncludeDev: true, Offset: 4, Limit: 2}, wantIDs: []string{SharepointKey}, wantTotal: 5, wantLogo: true, wantConfig: true},
+		{name: "after last page", opts: FilterOptions{IncludeDev: true, Offset: 6, Limit: 2}, wantIDs: []string{}, wantTotal: 5},
+		{name: "filtered page", opts: FilterOptions{Limit: 1, Offset: 1}, wantIDs: []string{ICAPKey}, wantTotal: 3, wantLogo: true, wantConfig: true},
+		{name: "omit logo", opts: FilterOptions{OmitLogo: true, Limit: 1}, wantIDs: []string{HostKey}, wantTotal: 3, wantConfig: true},
+		{name: "omit config fields", opts: FilterOptions{OmitConfigFields: true, Limit: 1}, wantIDs: []string{HostKey}, wantTotal: 3, wantLogo: true},
+		{name: "id and name only", opts: FilterOptions{IDAndNameOnly: true, Limit: 1}, wantIDs: []string{HostKey}, wantTotal: 3},
+		{name: "negative offset", opts: FilterOptions{Offset: -1}, wantErr: true},
+		{name: "negative limit", opts: FilterOptions{Limit: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connectorTypes, total, err := c.GetConnectorTypes(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConnectorTypeLoader.GetConnectorTypes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if total != tt.wantTotal {
+				t.Errorf("ConnectorTypeLoader.GetConnectorTypes() total = %d, want %d", total, tt.wantTotal)
+			}
+			gotIDs := []string{}
+			for _, connectorType := range connectorTypes {
+				gotIDs = append(gotIDs, connectorType.ID)
+				if connectorType.Name == "" {
+					t.Errorf("ConnectorTypeLoader.GetConnectorTypes() %s has no name", connectorType.ID)
+				}
+				if gotLogo := connectorType.Logo != ""; gotLogo != tt.wantLogo {
+					t.Errorf("ConnectorTypeLoader.GetConnectorTypes() %s logo = %v, want %v", connectorType.ID, gotLogo, tt.wantLogo)
+				}
+				if gotConfig := len(connectorType.Configs) > 0; gotConfig != tt.wantConfig {
+					t.Errorf("ConnectorTypeLoader.GetConnectorTypes() %s config fields = %v, want %v", connectorType.ID, gotConfig, tt.wantConfig)
+				}
+			}
+			if diff := cmp.Diff(gotIDs, tt.wantIDs); diff != "" {
+				t.Errorf("ConnectorTypeLoader.GetConnectorTypes() diff(got-want)=%s", diff)
+			}
+		})
+	}
+
+	// projection does not alter loaded connector types
+	connectorType, err := c.GetConnectorType(HostKey)
+	if err != nil {
+		t.Fatalf("GetConnectorType() error = %v", err)
+	}
+	if connectorType.Logo == "" || len(connectorType.Configs) == 0 || len(connectorType.LaunchSteps) == 0 {
+		t.Errorf("GetConnectorType() = incomplete connector type after projected listing")
+	}
+}
+
+func TestDiagnoseMSPermissions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tenant/oauth2/v2.0/token" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+	}))
+	defer server.Close()
+	client := msauth.NewClient(server.URL, server.Client())
+
+	if _, err := DiagnoseMSPermissions(t.Context(), client, &HostConfig{}); !errors.Is(err, ErrNoMSAuthConfig) {
+		t.Errorf("DiagnoseMSPermissions() error = %v, want ErrNoMSAuthConfig", err)
+	}
+	config := &SharepointConfig{ReconfigurableSharepointConfig: ReconfigurableSharepointConfig{M365TenantID: "tenant", M365ClientID: "client", M365ClientSecret: "secret"}}
+	report, err := DiagnoseMSPermissions(t.Context(), client, config)
+	if !errors.Is(err, msauth.ErrInvalidClient) {
+		t.Fatalf("DiagnoseMSPermissions() error = %v, want msauth.ErrInvalidClient", err)
+	}
+	if report.Error == "" {
+		t.Errorf("DiagnoseMSPermissions() report = %+v, want authentication error", report)
+	}
+}
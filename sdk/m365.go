@@ -1,5 +1,7 @@
 package sdk
 
+import "github.com/glimps-re/connector-integration/sdk/msauth"
+
 type M365Config struct {
 	CommonConnectorConfig
 	JournalRecipient             string `json:"journal_recipient" validate:"required" desc:"recipient of the journaling mail"`
@@ -19,3 +21,22 @@ func (c *M365Config) Strip() any {
 	cc.M365ClientSecret = ""
 	return cc
 }
+
+func (c *M365Config) MSCredentials() msauth.Credentials {
+	return msauth.Credentials{
+		TenantID:     c.M365ClientTenant,
+		ClientID:     c.M365ClientID,
+		ClientSecret: c.M365ClientSecret,
+	}
+}
+
+func (c *M365Config) GraphFeatures() (features []msauth.Feature) {
+	features = []msauth.Feature{msauth.FeatureMailRead}
+	if c.DeleteMalwareMail || c.SetLegitCategory || c.AddNotAnalyzedRule {
+		features = append(features, msauth.FeatureMailWrite)
+	}
+	if c.QuarantineMailbox != "" {
+		features = append(features, msauth.FeatureQuarantineMailbox)
+	}
+	return
+}
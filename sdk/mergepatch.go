@@ -0,0 +1,162 @@
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrInvalidMergePatch is returned for merge patches that are not a JSON object.
+var ErrInvalidMergePatch = errors.New("invalid merge patch, must be a JSON object")
+
+// PatchAndValidateRaw applies patch, a JSON merge patch (RFC 7386), to model then validates it. Object members
+// are merged recursively (struct fields and maps), absent members keep their current value, null members clear
+// their field (zero value) or map key, and other values (e.g. arrays) replace current value. A null patch is a no-op.
+// Members matching no field of model are rejected, each one being reported in ValidationError details.
+func PatchAndValidateRaw(model any, patch []byte) (err error) {
+	if fields := unknownJSONFields(patch, reflect.TypeOf(model)); len(fields) > 0 {
+		return unknownFieldsError(fields)
+	}
+	if err = mergePatch(model, patch); err != nil {
+		return newValidationError(err)
+	}
+	v, err := DefaultValidator()
+	if err != nil {
+		return newValidationError(err)
+	}
+	if err := v.Validate(model); err != nil {
+		return newValidationError(err)
+	}
+	return
+}
+
+// mergePatch applies merge patch to model, a pointer.
+func mergePatch(model any, patch []byte) (err error) {
+	value := reflect.ValueOf(model)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		err = fmt.Errorf("could not merge patch into %T, need a non nil pointer", model)
+		return
+	}
+	raw := json.RawMessage{}
+	if err = decodeSingle(json.NewDecoder(bytes.NewReader(patch)), &raw); err != nil {
+		return
+	}
+	raw = bytes.TrimSpace(raw)
+	switch {
+	case bytes.Equal(raw, []byte("null")):
+		return
+	case len(raw) == 0 || raw[0] != '{':
+		err = ErrInvalidMergePatch
+		return
+	}
+	err = mergePatchValue(value.Elem(), raw, "")
+	return
+}
+
+// mergePatchValue merges raw, a non null JSON value, into value of path.
+func mergePatchValue(value reflect.Value, raw json.RawMessage, path string) (err error) {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		err = mergePatchValue(value.Elem(), raw, path)
+		return
+	}
+	isObject := len(raw) > 0 && raw[0] == '{'
+	unmarshaler := reflect.PointerTo(value.Type()).Implements(jsonUnmarshalerType) || reflect.PointerTo(value.Type()).Implements(textUnmarshalerType)
+	switch {
+	case isObject && !unmarshaler && value.Kind() == reflect.Struct:
+		err = mergePatchStruct(value, raw, path)
+	case isObject && !unmarshaler && value.Kind() == reflect.Map:
+		err = mergePatchMap(value, raw, path)
+	default:
+		// replaced, from zero value so that previous slice elements or struct fields do not leak into new value
+		replaced := reflect.New(value.Type())
+		if err = json.Unmarshal(raw, replaced.Interface()); err != nil {
+			err = fmt.Errorf("%s: %w", patchPath(path), err)
+			return
+		}
+		value.Set(replaced.Elem())
+	}
+	return
+}
+
+func mergePatchStruct(value reflect.Value, raw json.RawMessage, path string) (err error) {
+	members := map[string]json.RawMessage{}
+	if err = json.Unmarshal(raw, &members); err != nil {
+		err = fmt.Errorf("%s: %w", patchPath(path), err)
+		return
+	}
+	for key, member := range members {
+		field, ok := jsonField(value.Type(), key)
+		if !ok {
+			err = fmt.Errorf("%s: unknown field", jsonPath(path, key))
+			return
+		}
+		fieldValue := structField(value, field.Index)
+		if bytes.Equal(member, []byte("null")) {
+			fieldValue.SetZero()
+			continue
+		}
+		if err = mergePatchValue(fieldValue, member, jsonPath(path, key)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func mergePatchMap(value reflect.Value, raw json.RawMessage, path string) (err error) {
+	members := map[string]json.RawMessage{}
+	if err = json.Unmarshal(raw, &members); err != nil {
+		err = fmt.Errorf("%s: %w", patchPath(path), err)
+		return
+	}
+	if value.IsNil() {
+		value.Set(reflect.MakeMap(value.Type()))
+	}
+	if value.Type().Key().Kind() != reflect.String {
+		err = fmt.Errorf("%s: unsupported map key type %s", patchPath(path), value.Type().Key())
+		return
+	}
+	for key, member := range members {
+		mapKey := reflect.ValueOf(key).Convert(value.Type().Key())
+		if bytes.Equal(member, []byte("null")) {
+			value.SetMapIndex(mapKey, reflect.Value{})
+			continue
+		}
+		// map elements are not addressable, merged into a copy
+		elem := reflect.New(value.Type().Elem()).Elem()
+		if current := value.MapIndex(mapKey); current.IsValid() {
+			elem.Set(current)
+		}
+		if err = mergePatchValue(elem, member, jsonPath(path, key)); err != nil {
+			return
+		}
+		value.SetMapIndex(mapKey, elem)
+	}
+	return
+}
+
+// structField returns field of value at index, allocating nil embedded struct pointers on the way.
+func structField(value reflect.Value, index []int) (field reflect.Value) {
+	field = value
+	for i, fieldIndex := range index {
+		if i > 0 && field.Kind() == reflect.Pointer {
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			field = field.Elem()
+		}
+		field = field.Field(fieldIndex)
+	}
+	return
+}
+
+func patchPath(path string) string {
+	if path == "" {
+		return "merge patch"
+	}
+	return path
+}
@@ -0,0 +1,137 @@
+package sdk
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/labstack/echo/v4"
+)
+
+type testPatchLabel struct {
+	Value string `json:"value"`
+	Color string `json:"color"`
+}
+
+type testPatchConfig struct {
+	Name    string                    `json:"name"`
+	Timeout Duration                  `json:"timeout"`
+	Tags    []string                  `json:"tags"`
+	Labels  map[string]testPatchLabel `json:"labels"`
+	Nested  *testPatchLabel           `json:"nested"`
+	Plugins []HostPluginConfig        `json:"plugins"`
+}
+
+func TestPatchAndValidateRaw(t *testing.T) {
+	current := func() testPatchConfig {
+		return testPatchConfig{
+			Name:    "connector",
+			Timeout: Duration(time.Minute),
+			Tags:    []string{"a", "b"},
+			Labels:  map[string]testPatchLabel{"env": {Value: "prod", Color: "red"}, "team": {Value: "sec"}},
+			Nested:  &testPatchLabel{Value: "v", Color: "blue"},
+			Plugins: []HostPluginConfig{{Name: "first", Path: "/bin/first", Args: []string{"-v"}}},
+		}
+	}
+	tests := []struct {
+		name    string
+		patch   string
+		want    func(c *testPatchConfig)
+		wantErr error
+	}{
+		{name: "empty patch", patch: `{}`, want: func(c *testPatchConfig) {}},
+		{name: "null patch", patch: `null`, want: func(c *testPatchConfig) {}},
+		{name: "replace scalars", patch: `{"name":"renamed","timeout":"5m"}`, want: func(c *testPatchConfig) {
+			c.Name = "renamed"
+			c.Timeout = Duration(5 * time.Minute)
+		}},
+		{name: "null clears", patch: `{"name":null,"timeout":null,"tags":null,"nested":null}`, want: func(c *testPatchConfig) {
+			c.Name = ""
+			c.Timeout = 0
+			c.Tags = nil
+			c.Nested = nil
+		}},
+		{name: "arrays replaced", patch: `{"tags":[],"plugins":[{"name":"second","path":"/bin/second"}]}`, want: func(c *testPatchConfig) {
+			c.Tags = []string{}
+			c.Plugins = []HostPluginConfig{{Name: "second", Path: "/bin/second"}}
+		}},
+		{name: "objects merged", patch: `{"labels":{"env":{"color":"green"},"team":null,"owner":{"value":"me"}},"nested":{"color":null}}`, want: func(c *testPatchConfig) {
+			c.Labels = map[string]testPatchLabel{"env": {Value: "prod", Color: "green"}, "owner": {Value: "me"}}
+			c.Nested = &testPatchLabel{Value: "v"}
+		}},
+		{name: "not an object", patch: `["name"]`, wantErr: ErrInvalidMergePatch},
+		{name: "invalid duration", patch: `{"timeout":"soon"}`, wantErr: ErrInvalidDuration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := current()
+			err := PatchAndValidateRaw(&got, []byte(tt.patch))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PatchAndValidateRaw() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			want := current()
+			tt.want(&want)
+			if diff := cmp.Diff(got, want); diff != "" {
+				t.Errorf("PatchAndValidateRaw() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestPatchAndValidateRaw_unknownFields(t *testing.T) {
+	got := testPatchConfig{Name: "connector"}
+	err := PatchAndValidateRaw(&got, []byte(`{"nme":"renamed","nested":{"colour":"red"},"plugins":[{"nam":"first"}]}`))
+	var validationErr ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("PatchAndValidateRaw() error = %v, want ValidationError", err)
+	}
+	want := []echo.Map{{"nme": "unknown field"}, {"nested.colour": "unknown field"}, {"plugins[0].nam": "unknown field"}}
+	if diff := cmp.Diff(validationErr.Details, want); diff != "" {
+		t.Errorf("PatchAndValidateRaw() details diff(got-want)=%s", diff)
+	}
+	if diff := cmp.Diff(got, testPatchConfig{Name: "connector"}); diff != "" {
+		t.Errorf("PatchAndValidateRaw() patched model diff(got-want)=%s", diff)
+	}
+}
+
+func TestPatchConfig(t *testing.T) {
+	current := func() *HostConfig {
+		config, err := InitDefault(HostKey)
+		if err != nil {
+			t.Fatalf("InitDefault() error = %v", err)
+		}
+		hostConfig := config.(*HostConfig)
+		hostConfig.GMalwareAPIURL = "https://gmalware.example.com"
+		hostConfig.GMalwareAPIToken = "token"
+		hostConfig.Paths = []string{"/data"}
+		hostConfig.GMalwareUserTags = []string{"host"}
+		return hostConfig
+	}
+
+	config, err := PatchConfig(HostKey, current(), []byte(`{"gmalware_user_tags":null,"monitoring":{"realtime":{"enabled":true}}}`))
+	if err != nil {
+		t.Fatalf("PatchConfig() error = %v", err)
+	}
+	want := current()
+	want.GMalwareUserTags = nil
+	want.Monitoring.RealTime.Enabled = true
+	if diff := cmp.Diff(config, want); diff != "" {
+		t.Errorf("PatchConfig() diff(got-want)=%s", diff)
+	}
+
+	// workers cleared to 0, under its minimum
+	if _, err = PatchConfig(HostKey, current(), []byte(`{"workers":null}`)); err == nil || !strings.Contains(err.Error(), "workers") {
+		t.Errorf("PatchConfig() error = %v, want workers validation error", err)
+	}
+	if _, err = PatchConfig(DummyKey, &DummyConfig{}, []byte(`{"dummy_string_2":null}`)); err == nil {
+		t.Error("PatchConfig() of dummy non reconfigurable field, want error")
+	}
+	if _, err = PatchConfig(HostKey, current(), []byte(`{"wrkers":4}`)); err == nil {
+		t.Error("PatchConfig() of unknown host field, want error")
+	}
+}
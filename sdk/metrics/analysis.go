@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AnalysisMetrics records the analysis submission pool of a connector (see analysis.Pool). The methods are
+// thread-safe, its zero value is ready to use.
+type AnalysisMetrics struct {
+	lock     sync.Mutex
+	duration *Histogram
+
+	workers   atomic.Int64
+	queued    atomic.Int64
+	running   atomic.Int64
+	completed atomic.Int64
+	failed    atomic.Int64
+	rejected  atomic.Int64 // by daily budget
+	deferred  atomic.Int64
+	expired   atomic.Int64 // deferred items dropped
+}
+
+// SetWorkers sets the number of pool workers.
+func (m *AnalysisMetrics) SetWorkers(n int64) {
+	m.workers.Store(n)
+}
+
+// AddQueued adds delta to the number of submissions waiting for a worker.
+func (m *AnalysisMetrics) AddQueued(delta int64) {
+	m.queued.Add(delta)
+}
+
+// AddRunning adds delta to the number of submissions being run by a worker.
+func (m *AnalysisMetrics) AddRunning(delta int64) {
+	m.running.Add(delta)
+}
+
+// AddBudgetRejected records a submission rejected because connector daily budget is consumed (see
+// analysis.BudgetClient).
+func (m *AnalysisMetrics) AddBudgetRejected() {
+	m.rejected.Add(1)
+}
+
+// SetDeferred sets the number of items deferred due to quota (see analysis.DeferredQueue).
+func (m *AnalysisMetrics) SetDeferred(n int64) {
+	m.deferred.Store(n)
+}
+
+// AddDeferredExpired records a deferred item dropped, too old to be retried.
+func (m *AnalysisMetrics) AddDeferredExpired() {
+	m.expired.Add(1)
+}
+
+// ObserveSubmission records a finished submission and its duration, failed or not.
+func (m *AnalysisMetrics) ObserveSubmission(d time.Duration, failed bool) {
+	if failed {
+		m.failed.Add(1)
+	} else {
+		m.completed.Add(1)
+	}
+	m.lock.Lock()
+	if m.duration == nil {
+		m.duration = NewHistogram(DefaultBuckets)
+	}
+	h := m.duration
+	m.lock.Unlock()
+	h.Observe(d)
+}
+
+// AnalysisMetricsSnapshot is a copy of AnalysisMetrics state.
+type AnalysisMetricsSnapshot struct {
+	Workers   int64
+	Queued    int64
+	Running   int64
+	Completed int64
+	Failed    int64
+	// BudgetRejected is the number of submissions rejected by daily budget
+	BudgetRejected int64
+	// Deferred is the number of items waiting for a retry, DeferredExpired the number of ones dropped
+	Deferred        int64
+	DeferredExpired int64
+	Duration        HistogramSnapshot
+}
+
+func (m *AnalysisMetrics) Snapshot() (s AnalysisMetricsSnapshot) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	s = AnalysisMetricsSnapshot{
+		Workers:         m.workers.Load(),
+		Queued:          m.queued.Load(),
+		Running:         m.running.Load(),
+		Completed:       m.completed.Load(),
+		Failed:          m.failed.Load(),
+		BudgetRejected:  m.rejected.Load(),
+		Deferred:        m.deferred.Load(),
+		DeferredExpired: m.expired.Load(),
+	}
+	if m.duration != nil {
+		s.Duration = m.duration.Snapshot()
+	} else {
+		s.Duration = NewHistogram(DefaultBuckets).Snapshot()
+	}
+	return
+}
@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets are histogram upper bounds, in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Histogram is a thread-safe cumulative histogram of durations.
+type Histogram struct {
+	lock    sync.Mutex
+	buckets []float64
+	counts  []int64 // counts[i] is the number of observations <= buckets[i]
+	count   int64
+	sum     float64
+}
+
+func NewHistogram(buckets []float64) (h *Histogram) {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]int64, len(buckets)),
+	}
+}
+
+func (h *Histogram) Observe(d time.Duration) {
+	v := d.Seconds()
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.count++
+	h.sum += v
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+}
+
+// HistogramSnapshot is a copy of a Histogram state.
+type HistogramSnapshot struct {
+	Buckets []float64
+	Counts  []int64 `desc:"cumulative, Counts[i] is the number of observations <= Buckets[i]"`
+	Count   int64
+	Sum     float64
+}
+
+func (h *Histogram) Snapshot() (s HistogramSnapshot) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	s = HistogramSnapshot{
+		Buckets: slices.Clone(h.buckets),
+		Counts:  slices.Clone(h.counts),
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+	return
+}
+
+// ClientMetrics records connector manager communication, to distinguish a broken connector
+// from an unreachable console. The methods are thread-safe, its zero value is ready to use.
+type ClientMetrics struct {
+	lock            sync.Mutex
+	requests        map[RequestKey]int64
+	retries         map[string]int64
+	requestDuration map[string]*Histogram
+	taskLatency     *Histogram
+
+	lastContact     atomic.Int64 // unix nanoseconds of last answered request
+	eventsPending   atomic.Int64
+	tasksQueued     atomic.Int64
+	tasksOverflow   atomic.Int64
+	tasksSuperseded atomic.Int64
+}
+
+// RequestKey identifies requests to the manager by endpoint (e.g. "tasks") and status
+// (HTTP status code, or "error" when no response was received).
+type RequestKey struct {
+	Endpoint string
+	Status   string
+}
+
+// ObserveRequest records a request to the manager, statusCode 0 meaning no response received.
+func (m *ClientMetrics) ObserveRequest(endpoint string, statusCode int, duration time.Duration) {
+	status := "error"
+	if statusCode > 0 {
+		status = strconv.Itoa(statusCode)
+	}
+	if statusCode > 0 && statusCode < http.StatusBadRequest {
+		m.lastContact.Store(time.Now().UnixNano())
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.requests == nil {
+		m.requests = make(map[RequestKey]int64)
+		m.requestDuration = make(map[string]*Histogram)
+	}
+	m.requests[RequestKey{Endpoint: endpoint, Status: status}]++
+	h, ok := m.requestDuration[endpoint]
+	if !ok {
+		h = NewHistogram(DefaultBuckets)
+		m.requestDuration[endpoint] = h
+	}
+	h.Observe(duration)
+}
+
+// LastContact returns time of last request successfully answered by the manager, zero if none.
+func (m *ClientMetrics) LastContact() (t time.Time) {
+	if nanos := m.lastContact.Load(); nanos > 0 {
+		t = time.Unix(0, nanos)
+	}
+	return
+}
+
+// AddRetry records a retried request to the manager.
+func (m *ClientMetrics) AddRetry(endpoint string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.retries == nil {
+		m.retries = make(map[string]int64)
+	}
+	m.retries[endpoint]++
+}
+
+// ObserveTaskLatency records duration between a task reception and its ack.
+func (m *ClientMetrics) ObserveTaskLatency(d time.Duration) {
+	m.lock.Lock()
+	if m.taskLatency == nil {
+		m.taskLatency = NewHistogram(DefaultBuckets)
+	}
+	h := m.taskLatency
+	m.lock.Unlock()
+	h.Observe(d)
+}
+
+// AddEventsPending adds delta to the number of events being pushed to the manager.
+func (m *ClientMetrics) AddEventsPending(delta int64) {
+	m.eventsPending.Add(delta)
+}
+
+// SetTasksQueued sets the number of tasks received and waiting to be handled.
+func (m *ClientMetrics) SetTasksQueued(n int64) {
+	m.tasksQueued.Store(n)
+}
+
+// AddTasksOverflow records tasks fetching paused because tasks queue was full.
+func (m *ClientMetrics) AddTasksOverflow() {
+	m.tasksOverflow.Add(1)
+}
+
+// AddTasksSuperseded records a queued update-config task superseded by a newer one.
+func (m *ClientMetrics) AddTasksSuperseded() {
+	m.tasksSuperseded.Add(1)
+}
+
+// ClientMetricsSnapshot is a copy of ClientMetrics state.
+type ClientMetricsSnapshot struct {
+	Requests        map[RequestKey]int64
+	Retries         map[string]int64
+	RequestDuration map[string]HistogramSnapshot
+	TaskLatency     HistogramSnapshot
+	EventsPending   int64
+	TasksQueued     int64
+	TasksOverflow   int64
+	TasksSuperseded int64
+}
+
+func (m *ClientMetrics) Snapshot() (s ClientMetricsSnapshot) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	s = ClientMetricsSnapshot{
+		Requests:        make(map[RequestKey]int64, len(m.requests)),
+		Retries:         make(map[string]int64, len(m.retries)),
+		RequestDuration: make(map[string]HistogramSnapshot, len(m.requestDuration)),
+		EventsPending:   m.eventsPending.Load(),
+		TasksQueued:     m.tasksQueued.Load(),
+		TasksOverflow:   m.tasksOverflow.Load(),
+		TasksSuperseded: m.tasksSuperseded.Load(),
+	}
+	for k, v := range m.requests {
+		s.Requests[k] = v
+	}
+	for k, v := range m.retries {
+		s.Retries[k] = v
+	}
+	for k, h := range m.requestDuration {
+		s.RequestDuration[k] = h.Snapshot()
+	}
+	if m.taskLatency != nil {
+		s.TaskLatency = m.taskLatency.Snapshot()
+	} else {
+		s.TaskLatency = NewHistogram(DefaultBuckets).Snapshot()
+	}
+	return
+}
@@ -21,6 +21,10 @@ type MetricCollecter interface {
 
 var _ MetricCollecter = &MetricsCollector{}
 
+// processStart is when connector process started, told apart from registrations so console distinguishes restarts
+// from re-registrations.
+var processStart = time.Now()
+
 // MetricsCollector provides thread-safe methods for collecting connector metrics.
 type MetricsCollector struct {
 	// counters
@@ -32,33 +36,60 @@ type MetricsCollector struct {
 	dailyQuota          atomic.Int64 // automatically collected
 	availableDailyQuota atomic.Int64 // automatically collected
 	lastStart           atomic.Int64 // automatically collected
+	quota               quotaForecast
 
 	detectClient gdetect.GDetectSubmitter
+
+	client   ClientMetrics
+	analysis AnalysisMetrics
+	webhook  WebhookMetrics
+	summary  summaryCounters
+}
+
+// Client returns metrics about connector manager communication, filled by the SDK client.
+func (m *MetricsCollector) Client() *ClientMetrics {
+	return &m.client
+}
+
+// Analysis returns metrics about the analysis submission pool, filled by analysis.Pool.
+func (m *MetricsCollector) Analysis() *AnalysisMetrics {
+	return &m.analysis
+}
+
+// Webhook returns metrics about webhook receivers, filled by webhook.Receiver and webhook.Renewer.
+func (m *MetricsCollector) Webhook() *WebhookMetrics {
+	return &m.webhook
 }
 
 // ConnectorMetrics represents current state of connector metrics.
 type ConnectorMetrics struct {
 	// no omitempty tags, so metrics are always explicit
-	DailyQuota          int64 `json:"daily_quota"`
-	AvailableDailyQuota int64 `json:"available_daily_quota"`
-	LastStart           int64 `json:"last_start_timestamp_seconds" desc:"Unix time (in seconds) when connector last registered"`
-	ItemsProcessed      int64 `json:"items_processed_total" desc:"number of items processed"`
-	SizeProcessed       int64 `json:"processed_bytes_total" desc:"total size processed, in bytes"`
-	ItemsMitigated      int64 `json:"items_mitigated_total"`
-	ItemsError          int64 `json:"items_error_total" desc:"items in error, for X reason"`
+	DailyQuota          int64   `json:"daily_quota"`
+	AvailableDailyQuota int64   `json:"available_daily_quota"`
+	LastStart           int64   `json:"last_start_timestamp_seconds" desc:"Unix time (in seconds) when connector last registered"`
+	ProcessStart        int64   `json:"process_start_timestamp_seconds" desc:"Unix time (in seconds) when connector process started"`
+	QuotaConsumption    float64 `json:"quota_consumption_per_hour" desc:"daily quota consumed per hour, over the last hour"`
+	QuotaExhaustion     int64   `json:"quota_exhaustion_seconds" desc:"estimated seconds until available daily quota is consumed at current rate, 0 if unknown"`
+	ItemsProcessed      int64   `json:"items_processed_total" desc:"number of items processed"`
+	SizeProcessed       int64   `json:"processed_bytes_total" desc:"total size processed, in bytes"`
+	ItemsMitigated      int64   `json:"items_mitigated_total"`
+	ItemsError          int64   `json:"items_error_total" desc:"items in error, for X reason"`
 }
 
 func (m *MetricsCollector) AddItemProcessed(size int64) {
 	m.itemsProcessed.Add(1)
 	m.sizeProcessed.Add(size)
+	m.summary.add(SummaryMetrics{ItemsProcessed: 1, SizeProcessed: size})
 }
 
 func (m *MetricsCollector) AddErrorItem() {
 	m.itemsError.Add(1)
+	m.summary.add(SummaryMetrics{ItemsError: 1})
 }
 
 func (m *MetricsCollector) AddMitigatedItem() {
 	m.itemsMitigated.Add(1)
+	m.summary.add(SummaryMetrics{ItemsMitigated: 1})
 }
 
 // SetDetectClient sets the gdetect client used to retrieve quotas.
@@ -81,13 +112,38 @@ func (m *MetricsCollector) GetAndStoreQuotas(ctx context.Context) (err error) {
 	}
 	m.dailyQuota.Store(int64(status.DailyQuota))
 	m.availableDailyQuota.Store(int64(status.AvailableDailyQuota))
+	m.quota.observe(time.Now(), int64(status.AvailableDailyQuota))
 	return
 }
 
+// Deprecated: use SetRegistered.
 func (m *MetricsCollector) SetLastStart(rs int64) {
 	m.lastStart.Store(rs)
 }
 
+// SetRegistered records when connector last registered, set automatically on Register.
+func (m *MetricsCollector) SetRegistered(at time.Time) {
+	m.lastStart.Store(at.Unix())
+}
+
+// Registered returns when connector last registered, zero if it did not.
+func (m *MetricsCollector) Registered() (at time.Time) {
+	if unix := m.lastStart.Load(); unix != 0 {
+		at = time.Unix(unix, 0)
+	}
+	return
+}
+
+// ProcessStart returns when connector process started.
+func (m *MetricsCollector) ProcessStart() time.Time {
+	return processStart
+}
+
+// Uptime returns how long connector process has been running, across re-registrations.
+func (m *MetricsCollector) Uptime() time.Duration {
+	return time.Since(processStart)
+}
+
 // GetAndReset returns current metrics and resets counters to zero.
 // Gauges are read without reset.
 func (m *MetricsCollector) GetAndReset() (metrics ConnectorMetrics) {
@@ -101,7 +157,11 @@ func (m *MetricsCollector) GetAndReset() (metrics ConnectorMetrics) {
 		DailyQuota:          m.dailyQuota.Load(),
 		AvailableDailyQuota: m.availableDailyQuota.Load(),
 		LastStart:           m.lastStart.Load(),
+		ProcessStart:        processStart.Unix(),
 	}
+	perHour, exhaustion := m.quota.estimate()
+	metrics.QuotaConsumption = perHour
+	metrics.QuotaExhaustion = int64(exhaustion.Seconds())
 	return
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
@@ -178,6 +179,7 @@ func Test_MetricsCollector_GetAndReset(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			m := newTestMetricsCollector(tt.fields.initialMetrics)
+			tt.want.ProcessStart = processStart.Unix()
 
 			got := m.GetAndReset()
 
@@ -392,3 +394,22 @@ func Test_MetricsCollector_AddMitigatedItem(t *testing.T) {
 		})
 	}
 }
+
+func Test_MetricsCollector_SetRegistered(t *testing.T) {
+	m := &MetricsCollector{}
+	if !m.Registered().IsZero() {
+		t.Errorf("Registered() = %s, want zero before registration", m.Registered())
+	}
+	registered := time.Unix(1738000000, 0)
+	m.SetRegistered(registered)
+	if !m.Registered().Equal(registered) {
+		t.Errorf("Registered() = %s, want %s", m.Registered(), registered)
+	}
+	got := m.GetAndReset()
+	if got.LastStart != registered.Unix() || got.ProcessStart != m.ProcessStart().Unix() {
+		t.Errorf("GetAndReset() last start = %d, process start = %d", got.LastStart, got.ProcessStart)
+	}
+	if m.Uptime() <= 0 {
+		t.Errorf("Uptime() = %s, want positive", m.Uptime())
+	}
+}
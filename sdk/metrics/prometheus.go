@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// PrometheusHandler serves gauges of m and connector manager communication metrics
+// in Prometheus text exposition format.
+// Counters pushed to the manager (items processed...) are not exposed: they are reset on each push.
+func PrometheusHandler(m *MetricsCollector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", prometheusContentType)
+		_ = WritePrometheus(w, m)
+	})
+}
+
+// WritePrometheus writes metrics of m in Prometheus text exposition format.
+func WritePrometheus(w io.Writer, m *MetricsCollector) (err error) {
+	pw := &promWriter{w: w}
+	pw.metric("connector_daily_quota", "gauge", "GLIMPS Malware daily quota.")
+	pw.sample("connector_daily_quota", nil, float64(m.dailyQuota.Load()))
+	pw.metric("connector_available_daily_quota", "gauge", "GLIMPS Malware available daily quota.")
+	pw.sample("connector_available_daily_quota", nil, float64(m.availableDailyQuota.Load()))
+	pw.metric("connector_last_start_timestamp_seconds", "gauge", "Unix time when connector last registered.")
+	pw.sample("connector_last_start_timestamp_seconds", nil, float64(m.lastStart.Load()))
+	pw.metric("connector_process_start_timestamp_seconds", "gauge", "Unix time when connector process started.")
+	pw.sample("connector_process_start_timestamp_seconds", nil, float64(processStart.Unix()))
+	perHour, exhaustion := m.quota.estimate()
+	pw.metric("connector_quota_consumption_per_hour", "gauge", "GLIMPS Malware daily quota consumed per hour, over the last hour.")
+	pw.sample("connector_quota_consumption_per_hour", nil, perHour)
+	pw.metric("connector_quota_exhaustion_seconds", "gauge", "Estimated seconds until available daily quota is consumed at current rate, 0 if unknown.")
+	pw.sample("connector_quota_exhaustion_seconds", nil, exhaustion.Seconds())
+
+	s := m.client.Snapshot()
+	pw.metric("connector_manager_requests_total", "counter", "Requests sent to connector manager, by endpoint and status.")
+	keys := slices.SortedFunc(maps.Keys(s.Requests), func(a, b RequestKey) int {
+		if c := strings.Compare(a.Endpoint, b.Endpoint); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Status, b.Status)
+	})
+	for _, k := range keys {
+		pw.sample("connector_manager_requests_total", []string{"endpoint", k.Endpoint, "status", k.Status}, float64(s.Requests[k]))
+	}
+	pw.metric("connector_manager_retries_total", "counter", "Requests to connector manager retried, by endpoint.")
+	for _, endpoint := range slices.Sorted(maps.Keys(s.Retries)) {
+		pw.sample("connector_manager_retries_total", []string{"endpoint", endpoint}, float64(s.Retries[endpoint]))
+	}
+	pw.metric("connector_manager_request_duration_seconds", "histogram", "Duration of requests to connector manager, by endpoint.")
+	for _, endpoint := range slices.Sorted(maps.Keys(s.RequestDuration)) {
+		pw.histogram("connector_manager_request_duration_seconds", []string{"endpoint", endpoint}, s.RequestDuration[endpoint])
+	}
+	pw.metric("connector_manager_task_latency_seconds", "histogram", "Duration between task reception and ack.")
+	pw.histogram("connector_manager_task_latency_seconds", nil, s.TaskLatency)
+	pw.metric("connector_manager_events_pending", "gauge", "Events being pushed to connector manager.")
+	pw.sample("connector_manager_events_pending", nil, float64(s.EventsPending))
+	pw.metric("connector_manager_tasks_queued", "gauge", "Tasks received and waiting to be handled.")
+	pw.sample("connector_manager_tasks_queued", nil, float64(s.TasksQueued))
+	pw.metric("connector_manager_tasks_overflow_total", "counter", "Times tasks fetching paused because tasks queue was full.")
+	pw.sample("connector_manager_tasks_overflow_total", nil, float64(s.TasksOverflow))
+	pw.metric("connector_manager_tasks_superseded_total", "counter", "Queued update-config tasks superseded by a newer one.")
+	pw.sample("connector_manager_tasks_superseded_total", nil, float64(s.TasksSuperseded))
+
+	a := m.analysis.Snapshot()
+	pw.metric("connector_analysis_workers", "gauge", "Workers of analysis submission pool.")
+	pw.sample("connector_analysis_workers", nil, float64(a.Workers))
+	pw.metric("connector_analysis_queued", "gauge", "Analysis submissions waiting for a worker.")
+	pw.sample("connector_analysis_queued", nil, float64(a.Queued))
+	pw.metric("connector_analysis_running", "gauge", "Analysis submissions being run.")
+	pw.sample("connector_analysis_running", nil, float64(a.Running))
+	pw.metric("connector_analysis_submissions_total", "counter", "Analysis submissions run, by result.")
+	pw.sample("connector_analysis_submissions_total", []string{"result", "completed"}, float64(a.Completed))
+	pw.sample("connector_analysis_submissions_total", []string{"result", "failed"}, float64(a.Failed))
+	pw.metric("connector_analysis_budget_rejected_total", "counter", "Analysis submissions rejected because connector daily budget is consumed.")
+	pw.sample("connector_analysis_budget_rejected_total", nil, float64(a.BudgetRejected))
+	pw.metric("connector_analysis_deferred", "gauge", "Items deferred due to quota, waiting for a retry.")
+	pw.sample("connector_analysis_deferred", nil, float64(a.Deferred))
+	pw.metric("connector_analysis_deferred_expired_total", "counter", "Deferred items dropped, too old to be retried.")
+	pw.sample("connector_analysis_deferred_expired_total", nil, float64(a.DeferredExpired))
+	pw.metric("connector_analysis_submission_duration_seconds", "histogram", "Duration of analysis submissions.")
+	pw.histogram("connector_analysis_submission_duration_seconds", nil, a.Duration)
+
+	wh := m.webhook.Snapshot()
+	pw.metric("connector_webhook_deliveries_total", "counter", "Webhook deliveries received, by result.")
+	pw.sample("connector_webhook_deliveries_total", []string{"result", "handled"}, float64(wh.Received))
+	pw.sample("connector_webhook_deliveries_total", []string{"result", "rejected"}, float64(wh.Rejected))
+	pw.sample("connector_webhook_deliveries_total", []string{"result", "replayed"}, float64(wh.Replayed))
+	pw.sample("connector_webhook_deliveries_total", []string{"result", "failed"}, float64(wh.Failed))
+	pw.metric("connector_webhook_renewals_total", "counter", "Webhook subscription renewals, by result.")
+	pw.sample("connector_webhook_renewals_total", []string{"result", "renewed"}, float64(wh.Renewals))
+	pw.sample("connector_webhook_renewals_total", []string{"result", "failed"}, float64(wh.RenewalFailures))
+	return pw.err
+}
+
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *promWriter) printf(format string, args ...any) {
+	if pw.err != nil {
+		return
+	}
+	_, pw.err = fmt.Fprintf(pw.w, format, args...)
+}
+
+func (pw *promWriter) metric(name string, metricType string, help string) {
+	pw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// labels are name, value pairs
+func (pw *promWriter) sample(name string, labels []string, value float64) {
+	pw.printf("%s%s %s\n", name, formatLabels(labels), strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func (pw *promWriter) histogram(name string, labels []string, h HistogramSnapshot) {
+	for i, upper := range h.Buckets {
+		pw.sample(name+"_bucket", append(slices.Clip(labels), "le", strconv.FormatFloat(upper, 'g', -1, 64)), float64(h.Counts[i]))
+	}
+	pw.sample(name+"_bucket", append(slices.Clip(labels), "le", "+Inf"), float64(h.Count))
+	pw.sample(name+"_sum", labels, h.Sum)
+	pw.sample(name+"_count", labels, float64(h.Count))
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, labels[i]+`="`+labelValueReplacer.Replace(labels[i+1])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
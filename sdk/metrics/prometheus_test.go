@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPrometheusHandler(t *testing.T) {
+	m := newTestMetricsCollector(ConnectorMetrics{DailyQuota: 100, AvailableDailyQuota: 40, LastStart: 1738000000, ItemsProcessed: 3})
+	m.Client().ObserveRequest("tasks", 200, 20*time.Millisecond)
+	m.Client().ObserveRequest("tasks", 200, 2*time.Second)
+	m.Client().ObserveRequest("events", 0, 3*time.Second)
+	m.Client().AddRetry("events")
+	m.Client().AddRetry("events")
+	m.Client().AddEventsPending(2)
+	m.Client().SetTasksQueued(1)
+	m.Client().ObserveTaskLatency(100 * time.Millisecond)
+	m.Analysis().SetWorkers(4)
+	m.Analysis().AddQueued(3)
+	m.Analysis().ObserveSubmission(time.Second, true)
+	m.Analysis().AddBudgetRejected()
+	m.Analysis().SetDeferred(5)
+	m.quota.observe(time.Unix(1738000000, 0), 50)
+	m.quota.observe(time.Unix(1738000000, 0).Add(30*time.Minute), 40)
+	m.Webhook().AddRejected()
+	m.Webhook().ObserveRenewal(false)
+
+	rec := httptest.NewRecorder()
+	PrometheusHandler(m).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got := rec.Header().Get("Content-Type"); got != prometheusContentType {
+		t.Errorf("PrometheusHandler() content type = %s", got)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"connector_daily_quota 100",
+		"connector_available_daily_quota 40",
+		"connector_last_start_timestamp_seconds 1.738e+09",
+		"# TYPE connector_process_start_timestamp_seconds gauge",
+		"connector_quota_consumption_per_hour 20",
+		"connector_quota_exhaustion_seconds 7200",
+		`connector_manager_requests_total{endpoint="events",status="error"} 1`,
+		`connector_manager_requests_total{endpoint="tasks",status="200"} 2`,
+		`connector_manager_retries_total{endpoint="events"} 2`,
+		`connector_manager_request_duration_seconds_bucket{endpoint="tasks",le="0.025"} 1`,
+		`connector_manager_request_duration_seconds_bucket{endpoint="tasks",le="2.5"} 2`,
+		`connector_manager_request_duration_seconds_bucket{endpoint="tasks",le="+Inf"} 2`,
+		`connector_manager_request_duration_seconds_count{endpoint="tasks"} 2`,
+		`connector_manager_request_duration_seconds_sum{endpoint="events"} 3`,
+		`connector_manager_task_latency_seconds_bucket{le="0.1"} 1`,
+		"connector_manager_task_latency_seconds_count 1",
+		"connector_manager_events_pending 2",
+		"connector_manager_tasks_queued 1",
+		"# TYPE connector_manager_request_duration_seconds histogram",
+		"connector_analysis_workers 4",
+		"connector_analysis_queued 3",
+		`connector_analysis_submissions_total{result="failed"} 1`,
+		"connector_analysis_submission_duration_seconds_count 1",
+		"connector_analysis_budget_rejected_total 1",
+		"connector_analysis_deferred 5",
+		"connector_analysis_deferred_expired_total 0",
+		`connector_webhook_deliveries_total{result="rejected"} 1`,
+		`connector_webhook_renewals_total{result="renewed"} 1`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("PrometheusHandler() missing %q in:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "items_processed") {
+		t.Errorf("PrometheusHandler() exposes pushed counters")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{1, 0.1})
+	h.Observe(50 * time.Millisecond)
+	h.Observe(500 * time.Millisecond)
+	h.Observe(5 * time.Second)
+	want := HistogramSnapshot{
+		Buckets: []float64{0.1, 1},
+		Counts:  []int64{1, 2},
+		Count:   3,
+		Sum:     5.55,
+	}
+	if diff := cmp.Diff(h.Snapshot(), want); diff != "" {
+		t.Errorf("Histogram.Snapshot() diff(got-want)=%s", diff)
+	}
+}
+
+func Test_formatLabels(t *testing.T) {
+	if got := formatLabels([]string{"endpoint", `a"b\c`}); got != `{endpoint="a\"b\\c"}` {
+		t.Errorf("formatLabels() = %s", got)
+	}
+}
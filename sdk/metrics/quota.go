@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// QuotaForecastWindow is the period quota consumption rate is computed over.
+const QuotaForecastWindow = time.Hour
+
+type quotaSample struct {
+	at        time.Time
+	available int64
+}
+
+// quotaForecast estimates quota consumption from available quota samples, retrieved on each get tasks cycle.
+type quotaForecast struct {
+	lock    sync.Mutex
+	samples []quotaSample // oldest first, within QuotaForecastWindow
+}
+
+// observe records available quota at time at. Samples before a quota reset (available quota going up) are dropped.
+func (f *quotaForecast) observe(at time.Time, available int64) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if n := len(f.samples); n > 0 && available > f.samples[n-1].available {
+		f.samples = f.samples[:0]
+	}
+	f.samples = append(f.samples, quotaSample{at: at, available: available})
+	for len(f.samples) > 1 && at.Sub(f.samples[1].at) >= QuotaForecastWindow {
+		f.samples = f.samples[1:]
+	}
+}
+
+// estimate returns quota consumed per hour over samples, and time until available quota is consumed at that rate,
+// 0 if unknown (less than two samples, or nothing consumed).
+func (f *quotaForecast) estimate() (perHour float64, exhaustion time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if len(f.samples) < 2 {
+		return
+	}
+	first, last := f.samples[0], f.samples[len(f.samples)-1]
+	elapsed := last.at.Sub(first.at)
+	consumed := first.available - last.available
+	if elapsed <= 0 || consumed <= 0 {
+		return
+	}
+	perHour = float64(consumed) / elapsed.Hours()
+	exhaustion = time.Duration(float64(last.available) / float64(consumed) * float64(elapsed))
+	return
+}
+
+// QuotaConsumptionRate returns daily quota consumed per hour over QuotaForecastWindow, 0 if unknown.
+func (m *MetricsCollector) QuotaConsumptionRate() (perHour float64) {
+	perHour, _ = m.quota.estimate()
+	return
+}
+
+// QuotaExhaustion returns estimated time until available daily quota is consumed at current consumption rate,
+// 0 if unknown.
+func (m *MetricsCollector) QuotaExhaustion() (exhaustion time.Duration) {
+	_, exhaustion = m.quota.estimate()
+	return
+}
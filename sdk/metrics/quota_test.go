@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuotaForecast(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		samples        map[time.Duration]int64 // available quota by time since start
+		wantPerHour    float64
+		wantExhaustion time.Duration
+	}{
+		{name: "no samples"},
+		{name: "single sample", samples: map[time.Duration]int64{0: 1000}},
+		{name: "nothing consumed", samples: map[time.Duration]int64{0: 1000, 30 * time.Minute: 1000}},
+		{
+			name:           "consumed",
+			samples:        map[time.Duration]int64{0: 1000, 30 * time.Minute: 900},
+			wantPerHour:    200,
+			wantExhaustion: 270 * time.Minute,
+		},
+		{
+			name:           "older samples dropped",
+			samples:        map[time.Duration]int64{0: 2000, 90 * time.Minute: 1000, 120 * time.Minute: 900, 150 * time.Minute: 800},
+			wantPerHour:    200,
+			wantExhaustion: 4 * time.Hour,
+		},
+		{
+			name:           "quota reset",
+			samples:        map[time.Duration]int64{0: 100, 30 * time.Minute: 1000, 60 * time.Minute: 950},
+			wantPerHour:    100,
+			wantExhaustion: 570 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &quotaForecast{}
+			for _, at := range []time.Duration{0, 30 * time.Minute, 60 * time.Minute, 90 * time.Minute, 120 * time.Minute, 150 * time.Minute} {
+				if available, ok := tt.samples[at]; ok {
+					f.observe(start.Add(at), available)
+				}
+			}
+			perHour, exhaustion := f.estimate()
+			if perHour != tt.wantPerHour || exhaustion != tt.wantExhaustion {
+				t.Errorf("estimate() = %v, %s, want %v, %s", perHour, exhaustion, tt.wantPerHour, tt.wantExhaustion)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"maps"
+	"sync"
+)
+
+// SummaryMetrics are metrics accumulated over a summary period (e.g. a day),
+// independently of pushed metrics which are reset on each push.
+type SummaryMetrics struct {
+	ItemsProcessed int64
+	SizeProcessed  int64
+	ItemsMitigated int64
+	ItemsError     int64
+	Detections     map[string]int64 `desc:"mitigated items by mitigation reason"`
+	Malwares       map[string]int64 `desc:"mitigated items by malware family"`
+	// gauges, read without reset
+	DailyQuota          int64
+	AvailableDailyQuota int64
+}
+
+type summaryCounters struct {
+	lock           sync.Mutex
+	itemsProcessed int64
+	sizeProcessed  int64
+	itemsMitigated int64
+	itemsError     int64
+	detections     map[string]int64
+	malwares       map[string]int64
+}
+
+func (s *summaryCounters) add(m SummaryMetrics) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.itemsProcessed += m.ItemsProcessed
+	s.sizeProcessed += m.SizeProcessed
+	s.itemsMitigated += m.ItemsMitigated
+	s.itemsError += m.ItemsError
+	if s.detections == nil {
+		s.detections = make(map[string]int64)
+		s.malwares = make(map[string]int64)
+	}
+	for k, v := range m.Detections {
+		s.detections[k] += v
+	}
+	for k, v := range m.Malwares {
+		s.malwares[k] += v
+	}
+}
+
+// AddDetection records reason and malware families of a mitigated item, for summaries.
+func (m *MetricsCollector) AddDetection(reason string, malwares []string) {
+	detection := SummaryMetrics{
+		Detections: map[string]int64{reason: 1},
+		Malwares:   make(map[string]int64, len(malwares)),
+	}
+	for _, malware := range malwares {
+		detection.Malwares[malware] = 1
+	}
+	m.summary.add(detection)
+}
+
+// GetAndResetSummary returns metrics accumulated since last call and resets them.
+func (m *MetricsCollector) GetAndResetSummary() (summary SummaryMetrics) {
+	m.summary.lock.Lock()
+	defer m.summary.lock.Unlock()
+	summary = SummaryMetrics{
+		ItemsProcessed:      m.summary.itemsProcessed,
+		SizeProcessed:       m.summary.sizeProcessed,
+		ItemsMitigated:      m.summary.itemsMitigated,
+		ItemsError:          m.summary.itemsError,
+		Detections:          maps.Clone(m.summary.detections),
+		Malwares:            maps.Clone(m.summary.malwares),
+		DailyQuota:          m.dailyQuota.Load(),
+		AvailableDailyQuota: m.availableDailyQuota.Load(),
+	}
+	if summary.Detections == nil {
+		summary.Detections = map[string]int64{}
+		summary.Malwares = map[string]int64{}
+	}
+	m.summary.itemsProcessed = 0
+	m.summary.sizeProcessed = 0
+	m.summary.itemsMitigated = 0
+	m.summary.itemsError = 0
+	m.summary.detections = nil
+	m.summary.malwares = nil
+	return
+}
+
+// RestoreSummary adds given summary counters to current ones, e.g. when summary could not be sent.
+func (m *MetricsCollector) RestoreSummary(summary SummaryMetrics) {
+	m.summary.add(summary)
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMetricsCollector_GetAndResetSummary(t *testing.T) {
+	m := newTestMetricsCollector(ConnectorMetrics{DailyQuota: 100, AvailableDailyQuota: 60})
+	m.AddItemProcessed(10)
+	m.AddItemProcessed(20)
+	m.AddErrorItem()
+	m.AddMitigatedItem()
+	m.AddDetection("malware", []string{"Trojan.Generic", "Ransom.Lockbit"})
+	m.AddMitigatedItem()
+	m.AddDetection("malware", []string{"Trojan.Generic"})
+	// pushed metrics reset must not reset summary
+	m.GetAndReset()
+
+	want := SummaryMetrics{
+		ItemsProcessed:      2,
+		SizeProcessed:       30,
+		ItemsMitigated:      2,
+		ItemsError:          1,
+		Detections:          map[string]int64{"malware": 2},
+		Malwares:            map[string]int64{"Trojan.Generic": 2, "Ransom.Lockbit": 1},
+		DailyQuota:          100,
+		AvailableDailyQuota: 60,
+	}
+	got := m.GetAndResetSummary()
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("GetAndResetSummary() diff(got-want)=%s", diff)
+	}
+
+	wantReset := SummaryMetrics{
+		Detections:          map[string]int64{},
+		Malwares:            map[string]int64{},
+		DailyQuota:          100,
+		AvailableDailyQuota: 60,
+	}
+	if diff := cmp.Diff(m.GetAndResetSummary(), wantReset); diff != "" {
+		t.Errorf("GetAndResetSummary() after reset diff(got-want)=%s", diff)
+	}
+
+	m.RestoreSummary(got)
+	m.AddItemProcessed(5)
+	want.ItemsProcessed = 3
+	want.SizeProcessed = 35
+	if diff := cmp.Diff(m.GetAndResetSummary(), want); diff != "" {
+		t.Errorf("GetAndResetSummary() after restore diff(got-want)=%s", diff)
+	}
+}
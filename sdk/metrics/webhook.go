@@ -0,0 +1,65 @@
+package metrics
+
+import "sync/atomic"
+
+// WebhookMetrics records the webhook receivers of a connector (see webhook.Receiver). The methods are thread-safe,
+// its zero value is ready to use.
+type WebhookMetrics struct {
+	received        atomic.Int64
+	rejected        atomic.Int64
+	replayed        atomic.Int64
+	failed          atomic.Int64
+	renewals        atomic.Int64
+	renewalFailures atomic.Int64
+}
+
+// AddReceived records a delivery handled successfully.
+func (m *WebhookMetrics) AddReceived() {
+	m.received.Add(1)
+}
+
+// AddRejected records a delivery failing validation (signature, client state, timestamp...).
+func (m *WebhookMetrics) AddRejected() {
+	m.rejected.Add(1)
+}
+
+// AddReplayed records a delivery already received, not handled again.
+func (m *WebhookMetrics) AddReplayed() {
+	m.replayed.Add(1)
+}
+
+// AddFailed records a valid delivery whose handling failed.
+func (m *WebhookMetrics) AddFailed() {
+	m.failed.Add(1)
+}
+
+// ObserveRenewal records a subscription renewal, failed or not.
+func (m *WebhookMetrics) ObserveRenewal(failed bool) {
+	if failed {
+		m.renewalFailures.Add(1)
+		return
+	}
+	m.renewals.Add(1)
+}
+
+// WebhookMetricsSnapshot is a copy of WebhookMetrics state.
+type WebhookMetricsSnapshot struct {
+	Received        int64
+	Rejected        int64
+	Replayed        int64
+	Failed          int64
+	Renewals        int64
+	RenewalFailures int64
+}
+
+func (m *WebhookMetrics) Snapshot() (s WebhookMetricsSnapshot) {
+	s = WebhookMetricsSnapshot{
+		Received:        m.received.Load(),
+		Rejected:        m.rejected.Load(),
+		Replayed:        m.replayed.Load(),
+		Failed:          m.failed.Load(),
+		Renewals:        m.renewals.Load(),
+		RenewalFailures: m.renewalFailures.Load(),
+	}
+	return
+}
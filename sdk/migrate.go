@@ -0,0 +1,186 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/glimps-re/connector-integration/sdk/state"
+)
+
+var ErrInvalidMigration = errors.New("invalid migrate task")
+
+// migratedEndpointKey is the state key the manager connector migrated to is persisted under.
+const migratedEndpointKey = "console-endpoint"
+
+// migratedEndpoint is the manager connector migrated to, persisted for it to be used after restarts.
+type migratedEndpoint struct {
+	URL    string `json:"url"`
+	APIKey string `json:"api_key"`
+}
+
+type enrollRequest struct {
+	Version          string                           `json:"version"`
+	UnresolvedErrors map[events.ErrorEventType]string `json:"unresolved_errors"`
+	Metrics          metrics.ConnectorMetrics         `json:"metrics" desc:"counters not pushed to previous manager yet"`
+}
+
+type enrollResponse struct {
+	APIKey string `json:"api_key"`
+}
+
+// enroll exchanges a one-time enrollment token for an api key on a new manager,
+// transferring unresolved errors and pending metrics counters.
+func (c ConnectorManagerClient) enroll(ctx context.Context, content MigrateActionContent, enrollReq enrollRequest) (apiKey string, err error) {
+	enrollEndpoint := &managerEndpoint{url: content.URL, auth: authorizationHeader("EnrollmentToken " + content.EnrollmentToken)}
+	resp := new(enrollResponse)
+	err = c.callEndpoint(ctx, enrollEndpoint, http.MethodPost, "enroll", enrollReq, resp)
+	if err != nil {
+		return
+	}
+	if resp.APIKey == "" {
+		err = errors.New("new manager returned an empty api key")
+		return
+	}
+	apiKey = resp.APIKey
+	return
+}
+
+// migrate handles an ActionMigrate task: it enrolls and registers the connector on the new manager,
+// acks task on current manager, then switches to the new manager, persists it (see persistEndpoint) and applies its
+// config. Returned error is the one of the ack, if migration fails connector keeps using current manager.
+func (c ConnectorManagerClient) migrate(ctx context.Context, connector Connector, task Task) (err error) {
+	var taskError string
+	next, info, pending, migrateErr := c.prepareMigration(ctx, task)
+	if migrateErr != nil {
+		taskError = fmt.Sprintf("error migrating connector, error: %v", migrateErr)
+		logger.Error(taskError)
+		// counters transferred at enroll are not pushed again to current manager
+		if !info.enrolled {
+			c.metricsCollector.RestoreCounterMetrics(pending)
+		}
+	}
+	err = c.Notify(ctx, events.TaskEvent{
+		TaskID:     task.ID,
+		Error:      taskError,
+		ConfigHash: c.currentConfigHash(),
+		RequestID:  task.RequestID,
+	})
+	switch {
+	case migrateErr != nil:
+		return
+	case errors.Is(err, ErrUnauthorizedConnector):
+		// previous manager already revoked us, the new one is ready anyway
+		err = nil
+	case err != nil:
+		logger.Error("could not push event to ack task", slog.String("task-id", task.ID))
+		err = nil
+	}
+
+	c.endpoint.Store(apiKeyEndpoint(next.URL, next.APIKey))
+	c.persistEndpoint(next)
+	c.assignConnectorID(info.connectorID)
+	c.schemaVersion.Store(int64(info.schemaVersion))
+	c.configETag.Store(nil)
+	logger.Info("connector migrated to new manager", slog.String("url", next.URL))
+	c.updateFeatureFlags(ctx, connector, info.featureFlags)
+
+	if len(info.config) == 0 || string(info.config) == "null" {
+		return
+	}
+	if configErr := c.configure(ctx, connector, info.config); configErr != nil {
+		logger.Error("could not apply new manager config", slog.String("error", configErr.Error()))
+	}
+	return
+}
+
+type migrationInfo struct {
+	// enrolled is true once connector enrolled on new manager, which got pending metrics counters
+	enrolled      bool
+	connectorID   string
+	schemaVersion int
+	config        json.RawMessage
+	featureFlags  map[string]bool
+}
+
+func (c ConnectorManagerClient) prepareMigration(ctx context.Context, task Task) (next migratedEndpoint, info migrationInfo, pending metrics.ConnectorMetrics, err error) {
+	content := new(MigrateActionContent)
+	if err = json.Unmarshal(task.Content, content); err != nil {
+		err = errors.Join(ErrInvalidMigration, err)
+		return
+	}
+	if content.URL == "" || content.EnrollmentToken == "" {
+		err = fmt.Errorf("%w, url and enrollment token are required", ErrInvalidMigration)
+		return
+	}
+	var version string
+	if v := c.version.Load(); v != nil {
+		version = *v
+	}
+	enrollReq := enrollRequest{Version: version}
+	if h := c.handler.Load(); h != nil {
+		enrollReq.UnresolvedErrors = h.UnresolvedErrors()
+	}
+	pending = c.metricsCollector.GetAndReset()
+	enrollReq.Metrics = pending
+
+	next.URL = content.URL
+	next.APIKey, err = c.enroll(ctx, *content, enrollReq)
+	if err != nil {
+		err = fmt.Errorf("could not enroll on new manager, %w", err)
+		return
+	}
+	info.enrolled = true
+	var config json.RawMessage
+	regInfo := &RegistrationInfo{Config: &config}
+	info.schemaVersion, err = c.register(ctx, apiKeyEndpoint(next.URL, next.APIKey), version, regInfo)
+	if err != nil {
+		err = fmt.Errorf("could not register on new manager, %w", err)
+		return
+	}
+	info.connectorID = regInfo.ConnectorID
+	info.config = config
+	info.featureFlags = regInfo.FeatureFlags
+	return
+}
+
+// persistEndpoint persists the manager connector migrated to, for it to be used after restarts instead of the one of
+// client config (see loadEndpoint). It holds an api key, it is only persisted in stores encrypting it.
+func (c ConnectorManagerClient) persistEndpoint(endpoint migratedEndpoint) {
+	if !c.cachesConfig() {
+		logger.Warn("migrated manager is not persisted without an encrypted state store, client config must be updated before restart", slog.String("url", endpoint.URL))
+		return
+	}
+	raw, err := json.Marshal(endpoint)
+	if err == nil {
+		err = c.store.Put(migratedEndpointKey, raw)
+	}
+	if err != nil {
+		logger.Warn("could not persist migrated manager", slog.String("url", endpoint.URL), slog.String("error", err.Error()))
+	}
+}
+
+// loadEndpoint makes client use the manager connector migrated to, if persisted in store.
+func (c ConnectorManagerClient) loadEndpoint() {
+	if !c.cachesConfig() {
+		return
+	}
+	raw, err := c.store.Get(migratedEndpointKey)
+	if errors.Is(err, state.ErrNotFound) {
+		return
+	}
+	endpoint := migratedEndpoint{}
+	if err == nil {
+		err = json.Unmarshal(raw, &endpoint)
+	}
+	if err != nil {
+		logger.Warn("could not load migrated manager", slog.String("error", err.Error()))
+		return
+	}
+	c.endpoint.Store(apiKeyEndpoint(endpoint.URL, endpoint.APIKey))
+}
@@ -0,0 +1,224 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/glimps-re/connector-integration/sdk/state"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+type fakeConnector struct {
+	configs []string
+}
+
+func (f *fakeConnector) Start(ctx context.Context) (err error) { return }
+func (f *fakeConnector) Stop(ctx context.Context) (err error)  { return }
+func (f *fakeConnector) Configure(ctx context.Context, config json.RawMessage) (err error) {
+	f.configs = append(f.configs, string(config))
+	return
+}
+func (f *fakeConnector) Restore(ctx context.Context, restoreInfo RestoreActionContent) (err error) {
+	return
+}
+func (f *fakeConnector) Status() (status ConnectorStatus) { return Started }
+
+type fakeManager struct {
+	lock     sync.Mutex
+	requests []string // "METHOD path authorization"
+	enrolled enrollRequest
+	events   []events.Envelope
+	failOn   string
+	// config is console config sent at register, {"dummy_string":"new"} if empty
+	config string
+}
+
+func (m *fakeManager) handler(t *testing.T) http.Handler {
+	t.Helper()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		m.requests = append(m.requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization"))
+		if r.URL.Path == basePath+"/"+m.failOn {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case basePath + "/enroll":
+			if err := json.NewDecoder(r.Body).Decode(&m.enrolled); err != nil {
+				t.Errorf("could not decode enroll request, error: %v", err)
+			}
+			_, _ = w.Write([]byte(`{"api_key":"new-key"}`))
+		case basePath + "/register":
+			config := m.config
+			if config == "" {
+				config = `{"dummy_string":"new"}`
+			}
+			_, _ = w.Write([]byte(`{"config":` + config + `,"schema_version":2,"connector_id":"new-connector"}`))
+		case basePath + "/events":
+			envelope := events.Envelope{}
+			if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+				t.Errorf("could not decode event, error: %v", err)
+			}
+			m.events = append(m.events, envelope)
+		}
+	})
+}
+
+func TestConnectorManagerClient_migrate(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		failOn          string
+		wantURL         string // "old" or "new"
+		wantTaskError   bool
+		wantConfigs     []string
+		wantOldRequests []string
+		wantNewRequests []string
+		wantRestored    metrics.ConnectorMetrics
+	}{
+		{
+			name:        "ok",
+			content:     `{"url":"%NEW%","enrollment_token":"token"}`,
+			wantURL:     "new",
+			wantConfigs: []string{`{"dummy_string":"new"}`},
+			wantOldRequests: []string{
+				"POST /api/v1/connectors/events ApiKey old-key",
+			},
+			wantNewRequests: []string{
+				"POST /api/v1/connectors/enroll EnrollmentToken token",
+				"POST /api/v1/connectors/register ApiKey new-key",
+			},
+		},
+		{
+			name:          "missing token",
+			content:       `{"url":"%NEW%"}`,
+			wantURL:       "old",
+			wantTaskError: true,
+			wantOldRequests: []string{
+				"POST /api/v1/connectors/events ApiKey old-key",
+			},
+			wantRestored: metrics.ConnectorMetrics{ItemsProcessed: 3, SizeProcessed: 30, ItemsError: 1},
+		},
+		{
+			name:          "register fails",
+			content:       `{"url":"%NEW%","enrollment_token":"token"}`,
+			failOn:        "register",
+			wantURL:       "old",
+			wantTaskError: true,
+			wantOldRequests: []string{
+				"POST /api/v1/connectors/events ApiKey old-key",
+			},
+			wantNewRequests: []string{
+				"POST /api/v1/connectors/enroll EnrollmentToken token",
+				"POST /api/v1/connectors/register ApiKey new-key",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldManager := &fakeManager{}
+			oldServer := httptest.NewServer(oldManager.handler(t))
+			defer oldServer.Close()
+			newManager := &fakeManager{failOn: tt.failOn}
+			newServer := httptest.NewServer(newManager.handler(t))
+			defer newServer.Close()
+
+			cipher, err := state.NewCipher(bytes.Repeat([]byte{1}, state.KeySize))
+			if err != nil {
+				t.Fatalf("NewCipher() error = %v", err)
+			}
+			store, err := state.NewFileStore(t.TempDir(), cipher)
+			if err != nil {
+				t.Fatalf("NewFileStore() error = %v", err)
+			}
+			config := ConnectorManagerClientConfig{URL: oldServer.URL, APIKey: "old-key"}
+			c := NewConnectorManagerClient(t.Context(), config, WithStateStore(store))
+			c.storeConnectorID("old-connector")
+			version := "1.2.3"
+			c.version.Store(&version)
+			unresolved := map[events.ErrorEventType]string{events.GMalwareError: "unreachable"}
+			c.NewConsoleEventHandler(slog.LevelError, unresolved)
+			for range 3 {
+				c.metricsCollector.AddItemProcessed(10)
+			}
+			c.metricsCollector.AddErrorItem()
+
+			content := json.RawMessage(strings.ReplaceAll(tt.content, "%NEW%", newServer.URL))
+			connector := &fakeConnector{}
+			if err := c.migrate(t.Context(), connector, Task{ID: "task-1", Action: ActionMigrate, Content: content}); err != nil {
+				t.Fatalf("migrate() error = %v", err)
+			}
+
+			wantURL := oldServer.URL
+			if tt.wantURL == "new" {
+				wantURL = newServer.URL
+			}
+			if got := c.endpoint.Load().url; got != wantURL {
+				t.Errorf("migrate() endpoint = %s, want %s", got, wantURL)
+			}
+			if diff := cmp.Diff(oldManager.requests, tt.wantOldRequests); diff != "" {
+				t.Errorf("migrate() old manager requests diff(got-want)=%s", diff)
+			}
+			if diff := cmp.Diff(newManager.requests, tt.wantNewRequests); diff != "" {
+				t.Errorf("migrate() new manager requests diff(got-want)=%s", diff)
+			}
+			if diff := cmp.Diff(connector.configs, tt.wantConfigs); diff != "" {
+				t.Errorf("migrate() configs diff(got-want)=%s", diff)
+			}
+			if len(oldManager.events) != 1 {
+				t.Fatalf("migrate() old manager got %d events, want 1", len(oldManager.events))
+			}
+			ack, err := oldManager.events[0].Decode()
+			if err != nil {
+				t.Fatalf("could not decode ack, error: %v", err)
+			}
+			if gotErr := ack.(events.TaskEvent).Error != ""; gotErr != tt.wantTaskError {
+				t.Errorf("migrate() ack = %+v, want error %v", ack, tt.wantTaskError)
+			}
+			if diff := cmp.Diff(c.metricsCollector.GetAndReset(), tt.wantRestored, cmpopts.IgnoreFields(metrics.ConnectorMetrics{}, "ProcessStart")); diff != "" {
+				t.Errorf("migrate() remaining metrics diff(got-want)=%s", diff)
+			}
+			wantConnectorID := "old-connector"
+			if tt.wantURL == "new" {
+				wantConnectorID = "new-connector"
+			}
+			if got := c.ConnectorID(); got != wantConnectorID {
+				t.Errorf("migrate() connector id = %s, want %s", got, wantConnectorID)
+			}
+			// a restarted client uses the persisted manager
+			restarted := NewConnectorManagerClient(t.Context(), config, WithStateStore(store))
+			if got := restarted.endpoint.Load().url; got != wantURL {
+				t.Errorf("migrate() persisted endpoint = %s, want %s", got, wantURL)
+			}
+			if tt.wantURL != "new" {
+				return
+			}
+			if got := restarted.endpoint.Load().auth; got != NewAPIKeyAuthenticator("new-key") {
+				t.Errorf("migrate() persisted authenticator = %v, want new-key one", got)
+			}
+			wantEnroll := enrollRequest{
+				Version:          version,
+				UnresolvedErrors: unresolved,
+				Metrics:          metrics.ConnectorMetrics{ItemsProcessed: 3, SizeProcessed: 30, ItemsError: 1},
+			}
+			if diff := cmp.Diff(newManager.enrolled, wantEnroll, cmpopts.IgnoreFields(metrics.ConnectorMetrics{}, "ProcessStart")); diff != "" {
+				t.Errorf("migrate() enroll request diff(got-want)=%s", diff)
+			}
+			if got := c.schemaVersion.Load(); got != events.SchemaVersionCurrent {
+				t.Errorf("migrate() schema version = %d, want %d", got, events.SchemaVersionCurrent)
+			}
+		})
+	}
+}
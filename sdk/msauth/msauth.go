@@ -0,0 +1,293 @@
+// Package msauth helps validating Microsoft Entra app registrations during
+// M365/Sharepoint connectors onboarding: client credentials check, granted
+// Graph application permissions and device-code consent flow.
+package msauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultAuthority = "https://login.microsoftonline.com"
+	GraphScope       = "https://graph.microsoft.com/.default"
+
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+var (
+	// used when identity platform does not give a polling interval
+	defaultPollInterval = 5 * time.Second
+	// added to polling interval when identity platform answers slow_down (RFC 8628)
+	slowDownIncrement = 5 * time.Second
+)
+
+var (
+	ErrInvalidTenant        = errors.New("invalid or unknown tenant")
+	ErrInvalidClient        = errors.New("invalid client id or secret")
+	ErrAuthorizationPending = errors.New("authorization pending")
+	ErrAuthorizationDenied  = errors.New("authorization declined by user")
+	ErrDeviceCodeExpired    = errors.New("device code expired")
+	ErrMissingPermissions   = errors.New("missing graph permissions")
+
+	errSlowDown = fmt.Errorf("%w, slow down", ErrAuthorizationPending)
+)
+
+type Credentials struct {
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+}
+
+type Client struct {
+	httpClient *http.Client
+	authority  string
+}
+
+// NewClient returns a Client using given authority (DefaultAuthority if empty)
+// and http client (http.DefaultClient if nil).
+func NewClient(authority string, httpClient *http.Client) (c Client) {
+	if authority == "" {
+		authority = DefaultAuthority
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	c = Client{
+		httpClient: httpClient,
+		authority:  authority,
+	}
+	return
+}
+
+// Token is an access token returned by Microsoft identity platform.
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Roles returns application permissions granted in access token ("roles" claim).
+// Token signature is not verified: it must only be used for diagnostic purposes.
+func (t Token) Roles() (roles []string, err error) {
+	parts := strings.Split(t.AccessToken, ".")
+	if len(parts) != 3 {
+		err = errors.New("invalid access token format")
+		return
+	}
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		err = fmt.Errorf("could not decode access token claims, %w", err)
+		return
+	}
+	claims := struct {
+		Roles []string `json:"roles"`
+	}{}
+	err = json.Unmarshal(rawClaims, &claims)
+	if err != nil {
+		err = fmt.Errorf("could not parse access token claims, %w", err)
+		return
+	}
+	roles = claims.Roles
+	return
+}
+
+type tokenError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	ErrorCodes       []int  `json:"error_codes"`
+}
+
+// see https://learn.microsoft.com/en-us/entra/identity-platform/reference-error-codes
+const (
+	aadTenantNotFound      = 90002
+	aadInvalidTenantName   = 900023
+	aadInvalidClientSecret = 7000215
+	aadAppNotFound         = 700016
+)
+
+func (e tokenError) toError() error {
+	desc := e.Error
+	if e.ErrorDescription != "" {
+		desc = fmt.Sprintf("%s: %s", e.Error, strings.SplitN(e.ErrorDescription, "\r\n", 2)[0])
+	}
+	switch {
+	case slices.Contains(e.ErrorCodes, aadTenantNotFound), slices.Contains(e.ErrorCodes, aadInvalidTenantName):
+		return fmt.Errorf("%w, %s", ErrInvalidTenant, desc)
+	case e.Error == "invalid_client", e.Error == "unauthorized_client",
+		slices.Contains(e.ErrorCodes, aadInvalidClientSecret), slices.Contains(e.ErrorCodes, aadAppNotFound):
+		return fmt.Errorf("%w, %s", ErrInvalidClient, desc)
+	case e.Error == "authorization_pending":
+		return ErrAuthorizationPending
+	case e.Error == "slow_down":
+		return errSlowDown
+	case e.Error == "authorization_declined", e.Error == "access_denied":
+		return ErrAuthorizationDenied
+	case e.Error == "expired_token", e.Error == "bad_verification_code":
+		return ErrDeviceCodeExpired
+	default:
+		return errors.New(desc)
+	}
+}
+
+func (c Client) post(ctx context.Context, tenantID string, endpoint string, form url.Values, res any) (err error) {
+	if tenantID == "" {
+		err = ErrInvalidTenant
+		return
+	}
+	reqURL, err := url.JoinPath(c.authority, url.PathEscape(tenantID), "oauth2/v2.0", endpoint)
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if e := resp.Body.Close(); e != nil && err == nil {
+			err = e
+		}
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		tokenErr := tokenError{}
+		if jsonErr := json.Unmarshal(body, &tokenErr); jsonErr != nil || tokenErr.Error == "" {
+			err = fmt.Errorf("unexpected response from identity platform, %d: %s", resp.StatusCode, body)
+			return
+		}
+		err = tokenErr.toError()
+		return
+	}
+	err = json.Unmarshal(body, res)
+	return
+}
+
+// ClientCredentialsToken requests a Graph token using client credentials grant.
+// It validates tenant, client ID and client secret at once.
+func (c Client) ClientCredentialsToken(ctx context.Context, creds Credentials) (token Token, err error) {
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {creds.ClientID},
+		"client_secret": {creds.ClientSecret},
+		"scope":         {GraphScope},
+	}
+	err = c.post(ctx, creds.TenantID, "token", form, &token)
+	return
+}
+
+// CheckResult reports app registration validation outcome.
+type CheckResult struct {
+	GrantedPermissions []string `json:"granted_permissions"`
+	MissingPermissions []string `json:"missing_permissions"`
+}
+
+// CheckClientCredentials validates credentials and verifies that every required
+// application permission has been granted (admin consent given). When
+// permissions are missing, result is filled and ErrMissingPermissions returned.
+func (c Client) CheckClientCredentials(ctx context.Context, creds Credentials, required []string) (result CheckResult, err error) {
+	token, err := c.ClientCredentialsToken(ctx, creds)
+	if err != nil {
+		return
+	}
+	granted, err := token.Roles()
+	if err != nil {
+		return
+	}
+	result.GrantedPermissions = granted
+	result.MissingPermissions = []string{}
+	for _, p := range required {
+		if !slices.Contains(granted, p) {
+			result.MissingPermissions = append(result.MissingPermissions, p)
+		}
+	}
+	if len(result.MissingPermissions) > 0 {
+		err = fmt.Errorf("%w: %s", ErrMissingPermissions, strings.Join(result.MissingPermissions, ", "))
+		return
+	}
+	return
+}
+
+// DeviceCode is returned when starting a device code flow. Message, UserCode and
+// VerificationURI must be displayed to the user.
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int64  `json:"expires_in"`
+	Interval        int64  `json:"interval"`
+	Message         string `json:"message"`
+}
+
+// StartDeviceCode starts a device code flow for given public client, scopes
+// default to GraphScope.
+func (c Client) StartDeviceCode(ctx context.Context, tenantID string, clientID string, scopes ...string) (code DeviceCode, err error) {
+	if len(scopes) == 0 {
+		scopes = []string{GraphScope}
+	}
+	form := url.Values{
+		"client_id": {clientID},
+		"scope":     {strings.Join(scopes, " ")},
+	}
+	err = c.post(ctx, tenantID, "devicecode", form, &code)
+	return
+}
+
+// PollDeviceCode polls token endpoint until user completes (or declines) the
+// device code flow, code expires or ctx is done. Polling interval is increased
+// by 5 seconds each time identity platform asks to slow down.
+func (c Client) PollDeviceCode(ctx context.Context, tenantID string, clientID string, code DeviceCode) (token Token, err error) {
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	expiresIn := time.Duration(code.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = 15 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, expiresIn)
+	defer cancel()
+	form := url.Values{
+		"grant_type":  {deviceCodeGrantType},
+		"client_id":   {clientID},
+		"device_code": {code.DeviceCode},
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err = c.post(ctx, tenantID, "token", form, &token)
+		if !errors.Is(err, ErrAuthorizationPending) {
+			return
+		}
+		if errors.Is(err, errSlowDown) {
+			interval += slowDownIncrement
+			ticker.Reset(interval)
+		}
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = ErrDeviceCodeExpired
+				return
+			}
+			err = ctx.Err()
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package msauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func fakeAccessToken(t *testing.T, roles []string) string {
+	t.Helper()
+	claims, err := json.Marshal(map[string]any{"roles": roles})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "header." + base64.RawURLEncoding.EncodeToString(claims) + ".signature"
+}
+
+func TestClient_CheckClientCredentials(t *testing.T) {
+	tests := []struct {
+		name       string
+		creds      Credentials
+		required   []string
+		status     int
+		response   any
+		wantResult CheckResult
+		wantErr    error
+	}{
+		{
+			name:     "ko unknown tenant",
+			creds:    Credentials{TenantID: "tenant", ClientID: "id", ClientSecret: "secret"},
+			status:   http.StatusBadRequest,
+			response: tokenError{Error: "invalid_request", ErrorCodes: []int{aadTenantNotFound}},
+			wantErr:  ErrInvalidTenant,
+		},
+		{
+			name:     "ko invalid secret",
+			creds:    Credentials{TenantID: "tenant", ClientID: "id", ClientSecret: "secret"},
+			status:   http.StatusUnauthorized,
+			response: tokenError{Error: "invalid_client", ErrorCodes: []int{aadInvalidClientSecret}},
+			wantErr:  ErrInvalidClient,
+		},
+		{
+			name:    "ko empty tenant",
+			creds:   Credentials{ClientID: "id", ClientSecret: "secret"},
+			wantErr: ErrInvalidTenant,
+		},
+		{
+			name:     "ko missing permissions",
+			creds:    Credentials{TenantID: "tenant", ClientID: "id", ClientSecret: "secret"},
+			required: []string{"Sites.ReadWrite.All", "Group.Read.All"},
+			status:   http.StatusOK,
+			response: map[string]any{"roles": []string{"Sites.ReadWrite.All"}},
+			wantResult: CheckResult{
+				GrantedPermissions: []string{"Sites.ReadWrite.All"},
+				MissingPermissions: []string{"Group.Read.All"},
+			},
+			wantErr: ErrMissingPermissions,
+		},
+		{
+			name:     "ok",
+			creds:    Credentials{TenantID: "tenant", ClientID: "id", ClientSecret: "secret"},
+			required: []string{"Sites.ReadWrite.All"},
+			status:   http.StatusOK,
+			response: map[string]any{"roles": []string{"Sites.ReadWrite.All"}},
+			wantResult: CheckResult{
+				GrantedPermissions: []string{"Sites.ReadWrite.All"},
+				MissingPermissions: []string{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/tenant/oauth2/v2.0/token" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				if err := r.ParseForm(); err != nil {
+					t.Fatal(err)
+				}
+				if r.PostForm.Get("grant_type") != "client_credentials" {
+					t.Errorf("unexpected grant type %s", r.PostForm.Get("grant_type"))
+				}
+				w.WriteHeader(tt.status)
+				response := tt.response
+				if m, ok := response.(map[string]any); ok {
+					response = Token{AccessToken: fakeAccessToken(t, m["roles"].([]string))}
+				}
+				_ = json.NewEncoder(w).Encode(response)
+			}))
+			defer server.Close()
+
+			c := NewClient(server.URL, server.Client())
+			gotResult, err := c.CheckClientCredentials(t.Context(), tt.creds, tt.required)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Client.CheckClientCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(gotResult, tt.wantResult); diff != "" {
+				t.Errorf("Client.CheckClientCredentials() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestClient_PollDeviceCode(t *testing.T) {
+	previousInterval, previousIncrement := defaultPollInterval, slowDownIncrement
+	t.Cleanup(func() { defaultPollInterval, slowDownIncrement = previousInterval, previousIncrement })
+	defaultPollInterval, slowDownIncrement = 10*time.Millisecond, 50*time.Millisecond
+	tests := []struct {
+		name      string
+		responses []string
+		// wantMinGaps are minimum delays between successive polls
+		wantMinGaps []time.Duration
+	}{
+		{name: "pending", responses: []string{"authorization_pending", ""}, wantMinGaps: []time.Duration{10 * time.Millisecond}},
+		{
+			name:        "slow down",
+			responses:   []string{"slow_down", "authorization_pending", ""},
+			wantMinGaps: []time.Duration{60 * time.Millisecond, 60 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []time.Time
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, time.Now())
+				if response := tt.responses[min(len(calls), len(tt.responses))-1]; response != "" {
+					w.WriteHeader(http.StatusBadRequest)
+					_ = json.NewEncoder(w).Encode(tokenError{Error: response})
+					return
+				}
+				_ = json.NewEncoder(w).Encode(Token{AccessToken: "token"})
+			}))
+			defer server.Close()
+
+			c := NewClient(server.URL, server.Client())
+			token, err := c.PollDeviceCode(t.Context(), "tenant", "client", DeviceCode{DeviceCode: "code", Interval: 0, ExpiresIn: 30})
+			if err != nil {
+				t.Fatalf("Client.PollDeviceCode() error = %v", err)
+			}
+			if token.AccessToken != "token" {
+				t.Errorf("Client.PollDeviceCode() token = %s, want token", token.AccessToken)
+			}
+			if len(calls) != len(tt.responses) {
+				t.Fatalf("Client.PollDeviceCode() polls = %d, want %d", len(calls), len(tt.responses))
+			}
+			for i, wantGap := range tt.wantMinGaps {
+				if gap := calls[i+1].Sub(calls[i]); gap < wantGap {
+					t.Errorf("Client.PollDeviceCode() poll %d after %v, want at least %v", i+1, gap, wantGap)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package msauth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+// Feature is a connector capability relying on Graph application permissions.
+type Feature string
+
+const (
+	FeatureMailRead          Feature = "mail-read"
+	FeatureMailWrite         Feature = "mail-write"
+	FeatureQuarantineMailbox Feature = "quarantine-mailbox"
+	FeatureDriveRead         Feature = "drive-read"
+	FeatureDriveWrite        Feature = "drive-write"
+	FeatureWebhooks          Feature = "webhooks"
+	FeatureGroupsRead        Feature = "groups-read"
+	FeatureUsersRead         Feature = "users-read"
+)
+
+// PermissionReportName is the diagnostic name used for permission report events.
+const PermissionReportName = "graph-permissions"
+
+// each requirement is satisfied by any of the listed permissions, first one being the least privileged
+type requirement []string
+
+var featureRequirements = map[Feature][]requirement{
+	FeatureMailRead:          {{"Mail.Read", "Mail.ReadWrite"}},
+	FeatureMailWrite:         {{"Mail.ReadWrite"}},
+	FeatureQuarantineMailbox: {{"Mail.ReadWrite"}, {"User.Read.All", "User.ReadWrite.All", "Directory.Read.All"}},
+	FeatureDriveRead:         {{"Sites.Read.All", "Sites.ReadWrite.All"}, {"Files.Read.All", "Files.ReadWrite.All"}},
+	FeatureDriveWrite:        {{"Sites.ReadWrite.All"}, {"Files.ReadWrite.All"}},
+	FeatureWebhooks:          {{"Files.ReadWrite.All"}},
+	FeatureGroupsRead:        {{"Group.Read.All", "Group.ReadWrite.All", "Directory.Read.All"}},
+	FeatureUsersRead:         {{"User.Read.All", "User.ReadWrite.All", "Directory.Read.All"}},
+}
+
+// RequiredPermissions returns least privileged permissions needed by given features.
+func RequiredPermissions(features ...Feature) (permissions []string) {
+	permissions = []string{}
+	for _, f := range features {
+		for _, req := range featureRequirements[f] {
+			p := req[0]
+			if !slices.Contains(permissions, p) {
+				permissions = append(permissions, p)
+			}
+		}
+	}
+	return
+}
+
+type FeatureReport struct {
+	Feature Feature  `json:"feature"`
+	OK      bool     `json:"ok"`
+	Missing []string `json:"missing" desc:"least privileged permissions to grant for this feature"`
+}
+
+// PermissionReport compares granted Graph application permissions with the ones required by enabled features.
+type PermissionReport struct {
+	OK       bool            `json:"ok"`
+	Granted  []string        `json:"granted"`
+	Features []FeatureReport `json:"features"`
+	Error    string          `json:"error,omitempty" desc:"set if permissions could not be retrieved"`
+}
+
+// BuildPermissionReport checks each feature requirements against granted permissions.
+func BuildPermissionReport(granted []string, features ...Feature) (report PermissionReport) {
+	report = PermissionReport{
+		OK:       true,
+		Granted:  granted,
+		Features: make([]FeatureReport, 0, len(features)),
+	}
+	for _, f := range features {
+		featureReport := FeatureReport{
+			Feature: f,
+			OK:      true,
+			Missing: []string{},
+		}
+		for _, req := range featureRequirements[f] {
+			if slices.ContainsFunc(req, func(p string) bool { return slices.Contains(granted, p) }) {
+				continue
+			}
+			featureReport.OK = false
+			featureReport.Missing = append(featureReport.Missing, req[0])
+		}
+		report.OK = report.OK && featureReport.OK
+		report.Features = append(report.Features, featureReport)
+	}
+	return
+}
+
+// DiagnosePermissions retrieves permissions granted to app registration and builds
+// a report for given features. A failure to authenticate is reported in report.Error
+// and returned as err.
+func (c Client) DiagnosePermissions(ctx context.Context, creds Credentials, features ...Feature) (report PermissionReport, err error) {
+	token, err := c.ClientCredentialsToken(ctx, creds)
+	if err != nil {
+		report = PermissionReport{Granted: []string{}, Features: []FeatureReport{}, Error: err.Error()}
+		return
+	}
+	granted, err := token.Roles()
+	if err != nil {
+		report = PermissionReport{Granted: []string{}, Features: []FeatureReport{}, Error: err.Error()}
+		return
+	}
+	report = BuildPermissionReport(granted, features...)
+	return
+}
+
+// Notify pushes report to the console as a diagnostic event.
+func (r PermissionReport) Notify(ctx context.Context, h events.EventDiagnosticHandler) (err error) {
+	err = h.NotifyDiagnostic(ctx, PermissionReportName, r.OK, r)
+	return
+}
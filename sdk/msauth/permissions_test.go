@@ -0,0 +1,59 @@
+package msauth
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildPermissionReport(t *testing.T) {
+	tests := []struct {
+		name       string
+		granted    []string
+		features   []Feature
+		wantReport PermissionReport
+	}{
+		{
+			name:     "ok higher privilege satisfies requirement",
+			granted:  []string{"Sites.ReadWrite.All", "Files.ReadWrite.All"},
+			features: []Feature{FeatureDriveRead},
+			wantReport: PermissionReport{
+				OK:      true,
+				Granted: []string{"Sites.ReadWrite.All", "Files.ReadWrite.All"},
+				Features: []FeatureReport{
+					{Feature: FeatureDriveRead, OK: true, Missing: []string{}},
+				},
+			},
+		},
+		{
+			name:     "ko missing permissions for some features",
+			granted:  []string{"Sites.Read.All", "Files.Read.All"},
+			features: []Feature{FeatureDriveRead, FeatureDriveWrite, FeatureGroupsRead},
+			wantReport: PermissionReport{
+				OK:      false,
+				Granted: []string{"Sites.Read.All", "Files.Read.All"},
+				Features: []FeatureReport{
+					{Feature: FeatureDriveRead, OK: true, Missing: []string{}},
+					{Feature: FeatureDriveWrite, OK: false, Missing: []string{"Sites.ReadWrite.All", "Files.ReadWrite.All"}},
+					{Feature: FeatureGroupsRead, OK: false, Missing: []string{"Group.Read.All"}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotReport := BuildPermissionReport(tt.granted, tt.features...)
+			if diff := cmp.Diff(gotReport, tt.wantReport); diff != "" {
+				t.Errorf("BuildPermissionReport() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestRequiredPermissions(t *testing.T) {
+	got := RequiredPermissions(FeatureMailRead, FeatureMailWrite, FeatureQuarantineMailbox)
+	want := []string{"Mail.Read", "Mail.ReadWrite", "User.Read.All"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("RequiredPermissions() diff(got-want)=%s", diff)
+	}
+}
@@ -0,0 +1,257 @@
+// Package pipeline models the flow of connectors mitigating files: an acquired item goes through pre-filters, is
+// analyzed, a verdict is decided from the analysis result, then acted on (quarantine, delete, log...), notified to
+// console and recorded in metrics. Connectors compose stages (many are provided) rather than reimplementing it.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/analysis"
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+)
+
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: sdk.LogLevel})).WithGroup("pipeline")
+
+var (
+	ErrInvalidPipeline = errors.New("invalid pipeline")
+	ErrNoActor         = errors.New("no actor for action")
+)
+
+// Item is a file going through a pipeline.
+type Item struct {
+	// ID identifies item in mitigation events
+	ID string
+	// Path is the file path or name, reported to console
+	Path  string
+	Size  int64
+	Owner string
+	// Open opens item content, it may be called by several stages
+	Open func(ctx context.Context) (r io.ReadCloser, err error)
+	// Remove deletes item where it was acquired, required by delete and quarantine actors
+	Remove func(ctx context.Context) (err error)
+}
+
+// Verdict is what a pipeline decided for an item.
+type Verdict struct {
+	// Action is the mitigation applied to item, none if empty
+	Action events.MitigationAction
+	Reason events.MitigationReason
+	// Result is the analysis result, zero for items stopped by a filter
+	Result gdetect.Result
+	// AnalysisError is set when item is mitigated because it could not be analyzed
+	AnalysisError string
+	// Info gives context on mitigation, reported to console
+	Info string
+	// QuarantineLocation is set by actors quarantining item (e.g. quarantine id)
+	QuarantineLocation string
+}
+
+// Filter checks items before analysis. When stop is true, item is not analyzed and verdict (with no action for
+// skipped items) is applied.
+type Filter interface {
+	Filter(ctx context.Context, item Item) (verdict Verdict, stop bool, err error)
+}
+
+// Analyzer analyzes items, e.g. with GLIMPS Malware (see SubmitterAnalyzer).
+type Analyzer interface {
+	Analyze(ctx context.Context, item Item) (result gdetect.Result, err error)
+}
+
+// Decider decides verdict of an analyzed item from its analysis result.
+type Decider interface {
+	Decide(ctx context.Context, item Item, result gdetect.Result) (verdict Verdict, err error)
+}
+
+// Actor applies a mitigation action to an item, it may complete verdict (e.g. QuarantineLocation).
+type Actor interface {
+	Act(ctx context.Context, item Item, verdict *Verdict) (err error)
+}
+
+type FilterFunc func(ctx context.Context, item Item) (verdict Verdict, stop bool, err error)
+
+func (f FilterFunc) Filter(ctx context.Context, item Item) (verdict Verdict, stop bool, err error) {
+	return f(ctx, item)
+}
+
+type DeciderFunc func(ctx context.Context, item Item, result gdetect.Result) (verdict Verdict, err error)
+
+func (f DeciderFunc) Decide(ctx context.Context, item Item, result gdetect.Result) (verdict Verdict, err error) {
+	return f(ctx, item, result)
+}
+
+type ActorFunc func(ctx context.Context, item Item, verdict *Verdict) (err error)
+
+func (f ActorFunc) Act(ctx context.Context, item Item, verdict *Verdict) (err error) {
+	return f(ctx, item, verdict)
+}
+
+// Stages are the stages of a Pipeline.
+type Stages struct {
+	// Filters run in order before analysis, the first stopping an item decides its verdict
+	Filters []Filter
+	// Analyzer is required
+	Analyzer Analyzer
+	// Decider is required (e.g. MalwareDecider)
+	Decider Decider
+	// Actors apply verdict action, one per action decided by filters and decider
+	Actors map[events.MitigationAction]Actor
+	// ErrorAction, if set, is applied (with events.ReasonError) to items that could not be analyzed, analysis errors
+	// are returned otherwise
+	ErrorAction events.MitigationAction
+	// Notifier, if set, notifies mitigations to console (e.g. ConnectorManagerClient handler)
+	Notifier events.EventMitigationHandler
+	// Metrics, if set, records processed and failed items
+	Metrics *metrics.MetricsCollector
+}
+
+// Pipeline processes items through its stages, its methods can be called concurrently (e.g. from analysis.Pool
+// jobs).
+type Pipeline struct {
+	stages Stages
+}
+
+// New returns a pipeline of stages.
+func New(stages Stages) (p *Pipeline, err error) {
+	switch {
+	case stages.Analyzer == nil:
+		err = fmt.Errorf("%w, analyzer is required", ErrInvalidPipeline)
+	case stages.Decider == nil:
+		err = fmt.Errorf("%w, decider is required", ErrInvalidPipeline)
+	case stages.ErrorAction != "" && stages.Actors[stages.ErrorAction] == nil:
+		err = fmt.Errorf("%w, %w %s", ErrInvalidPipeline, ErrNoActor, stages.ErrorAction)
+	}
+	if err != nil {
+		return
+	}
+	p = &Pipeline{stages: stages}
+	return
+}
+
+// Process runs item through pipeline stages, and returns its verdict. Items whose processing or analysis failed are
+// recorded in metrics as error items.
+func (p *Pipeline) Process(ctx context.Context, item Item) (verdict Verdict, err error) {
+	defer func() {
+		if p.stages.Metrics == nil {
+			return
+		}
+		if err != nil || verdict.AnalysisError != "" {
+			p.stages.Metrics.AddErrorItem()
+			return
+		}
+		p.stages.Metrics.AddItemProcessed(item.Size)
+	}()
+
+	verdict, stopped, err := p.filter(ctx, item)
+	if err != nil {
+		return
+	}
+	if !stopped {
+		if verdict, err = p.analyze(ctx, item); err != nil {
+			return
+		}
+	}
+	if verdict.Action == "" {
+		return
+	}
+	actor := p.stages.Actors[verdict.Action]
+	if actor == nil {
+		err = fmt.Errorf("%w %s", ErrNoActor, verdict.Action)
+		return
+	}
+	if err = actor.Act(ctx, item, &verdict); err != nil {
+		err = fmt.Errorf("could not %s %s, %w", verdict.Action, item.Path, err)
+		return
+	}
+	if p.stages.Notifier != nil {
+		if notifyErr := p.stages.Notifier.NotifyFileMitigation(ctx, verdict.Action, item.ID, verdict.Reason, p.fileInfos(item, verdict)); notifyErr != nil {
+			// item is mitigated anyway
+			logger.Warn("could not notify mitigation", slog.String("item", item.ID), slog.String("error", notifyErr.Error()))
+		}
+	}
+	return
+}
+
+func (p *Pipeline) filter(ctx context.Context, item Item) (verdict Verdict, stopped bool, err error) {
+	for _, f := range p.stages.Filters {
+		verdict, stopped, err = f.Filter(ctx, item)
+		if err != nil {
+			err = fmt.Errorf("could not filter %s, %w", item.Path, err)
+			return
+		}
+		if stopped {
+			return
+		}
+	}
+	return
+}
+
+func (p *Pipeline) analyze(ctx context.Context, item Item) (verdict Verdict, err error) {
+	result, err := p.stages.Analyzer.Analyze(ctx, item)
+	if err != nil {
+		if p.stages.ErrorAction == "" || ctx.Err() != nil {
+			err = fmt.Errorf("could not analyze %s, %w", item.Path, err)
+			return
+		}
+		verdict = Verdict{Action: p.stages.ErrorAction, Reason: events.ReasonError, AnalysisError: err.Error()}
+		err = nil
+		return
+	}
+	verdict, err = p.stages.Decider.Decide(ctx, item, result)
+	if err != nil {
+		err = fmt.Errorf("could not decide verdict of %s, %w", item.Path, err)
+		return
+	}
+	verdict.Result = result
+	return
+}
+
+// fileInfos returns mitigation infos of item, GLIMPS Malware results being reported as analysis.Verdict does.
+func (p *Pipeline) fileInfos(item Item, verdict Verdict) (info events.FileInfos) {
+	var submitter gdetect.GDetectSubmitter
+	if analyzer, ok := p.stages.Analyzer.(SubmitterAnalyzer); ok {
+		submitter = analyzer.Submitter
+	}
+	info = analysis.NewVerdict(verdict.Result, submitter).FileInfos(item.Path)
+	info.QuarantineLocation = verdict.QuarantineLocation
+	if verdict.AnalysisError != "" {
+		info.AnalysisError = verdict.AnalysisError
+	}
+	info.AdditionalInfo = verdict.Info
+	info.Size = item.Size
+	info.Owner = item.Owner
+	return
+}
+
+// Source acquires items to process, Next returning io.EOF when there are no more.
+type Source interface {
+	Next(ctx context.Context) (item Item, err error)
+}
+
+// Run processes items of source one after the other until source is exhausted or ctx is done. Processing errors
+// are logged, and do not stop run.
+func (p *Pipeline) Run(ctx context.Context, source Source) (err error) {
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		item, nextErr := source.Next(ctx)
+		if errors.Is(nextErr, io.EOF) {
+			return
+		}
+		if nextErr != nil {
+			err = fmt.Errorf("could not acquire item, %w", nextErr)
+			return
+		}
+		if _, processErr := p.Process(ctx, item); processErr != nil {
+			logger.Error("could not process item", slog.String("item", item.ID), slog.String("error", processErr.Error()))
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/filetype"
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/glimps-re/connector-integration/sdk/quarantine"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+	gdetectmock "github.com/glimps-re/go-gdetect/pkg/gdetect/mock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+type mitigation struct {
+	Action events.MitigationAction
+	ID     string
+	Reason events.MitigationReason
+	Info   events.FileInfos
+}
+
+type mitigationRecorder struct {
+	events.NoopEventHandler
+	mitigations []mitigation
+}
+
+func (r *mitigationRecorder) NotifyFileMitigation(ctx context.Context, action events.MitigationAction, elementID string, reason events.MitigationReason, info events.FileInfos) (err error) {
+	r.mitigations = append(r.mitigations, mitigation{Action: action, ID: elementID, Reason: reason, Info: info})
+	return
+}
+
+func memoryItem(id string, content string) Item {
+	return Item{
+		ID:   id,
+		Path: "/data/" + id,
+		Size: int64(len(content)),
+		Open: func(context.Context) (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader([]byte(content))), nil
+		},
+	}
+}
+
+func TestPipeline_Process(t *testing.T) {
+	malware := gdetect.Result{Done: true, Malware: true, Malwares: []string{"Trojan.Generic"}, SHA256: "abc", FileType: "exe"}
+	submitter := &gdetectmock.MockGDetectSubmitter{
+		WaitForReaderMock: func(ctx context.Context, r io.Reader, options gdetect.WaitForOptions) (result gdetect.Result, err error) {
+			content, _ := io.ReadAll(r)
+			switch string(content) {
+			case "unreachable":
+				err = errors.New("gmalware unreachable")
+			case "evil":
+				result = malware
+			default:
+				result = gdetect.Result{Done: true}
+			}
+			return
+		},
+	}
+	var acted []string
+	recordActor := func(action events.MitigationAction) Actor {
+		return ActorFunc(func(ctx context.Context, item Item, verdict *Verdict) (err error) {
+			acted = append(acted, string(action)+" "+item.ID)
+			return
+		})
+	}
+	stages := Stages{
+		Filters: []Filter{
+			MaxSizeFilter{MaxSize: 16, Action: events.ActionLog},
+			FileTypeFilter{Policy: filetype.Policy{Rules: []filetype.Rule{{Action: filetype.ActionSkip, Types: []string{"image"}}}}},
+		},
+		Analyzer: SubmitterAnalyzer{Submitter: submitter},
+		Decider:  MalwareDecider{Action: events.ActionQuarantine},
+		Actors: map[events.MitigationAction]Actor{
+			events.ActionQuarantine: recordActor(events.ActionQuarantine),
+			events.ActionLog:        recordActor(events.ActionLog),
+		},
+		ErrorAction: events.ActionLog,
+	}
+
+	tests := []struct {
+		name            string
+		stages          Stages
+		item            Item
+		wantVerdict     Verdict
+		wantErr         bool
+		wantActed       []string
+		wantMitigations []mitigation
+		wantMetrics     metrics.ConnectorMetrics
+	}{
+		{
+			name:        "clean",
+			stages:      stages,
+			item:        memoryItem("clean", "hello"),
+			wantVerdict: Verdict{Result: gdetect.Result{Done: true}},
+			wantMetrics: metrics.ConnectorMetrics{ItemsProcessed: 1, SizeProcessed: 5},
+		},
+		{
+			name:   "malware",
+			stages: stages,
+			item:   memoryItem("malware", "evil"),
+			wantVerdict: Verdict{
+				Action: events.ActionQuarantine,
+				Reason: events.ReasonMalware,
+				Result: malware,
+			},
+			wantActed: []string{"quarantine malware"},
+			wantMitigations: []mitigation{{
+				Action: events.ActionQuarantine,
+				ID:     "malware",
+				Reason: events.ReasonMalware,
+				Info: events.FileInfos{
+					CommonDetails: events.CommonDetails{Malwares: []string{"Trojan.Generic"}, SHA256: "abc"},
+					File:          "/data/malware",
+					Filetype:      "exe",
+					Size:          4,
+				},
+			}},
+			wantMetrics: metrics.ConnectorMetrics{ItemsProcessed: 1, SizeProcessed: 4},
+		},
+		{
+			name:        "too big",
+			stages:      stages,
+			item:        memoryItem("big", "content bigger than 16 bytes"),
+			wantVerdict: Verdict{Action: events.ActionLog, Reason: events.ReasonTooBig, Info: "file of 28 bytes exceeds 16 bytes"},
+			wantActed:   []string{"log big"},
+			wantMitigations: []mitigation{{
+				Action: events.ActionLog,
+				ID:     "big",
+				Reason: events.ReasonTooBig,
+				Info: events.FileInfos{
+					CommonDetails: events.CommonDetails{AdditionalInfo: "file of 28 bytes exceeds 16 bytes"},
+					File:          "/data/big",
+					Size:          28,
+				},
+			}},
+			wantMetrics: metrics.ConnectorMetrics{ItemsProcessed: 1, SizeProcessed: 28},
+		},
+		{
+			name:        "skipped by file type",
+			stages:      stages,
+			item:        memoryItem("image", "\x89PNG\r\n\x1a\n"),
+			wantMetrics: metrics.ConnectorMetrics{ItemsProcessed: 1, SizeProcessed: 8},
+		},
+		{
+			name:        "analysis error action",
+			stages:      stages,
+			item:        memoryItem("error", "unreachable"),
+			wantVerdict: Verdict{Action: events.ActionLog, Reason: events.ReasonError, AnalysisError: "gmalware unreachable"},
+			wantActed:   []string{"log error"},
+			wantMitigations: []mitigation{{
+				Action: events.ActionLog,
+				ID:     "error",
+				Reason: events.ReasonError,
+				Info: events.FileInfos{
+					CommonDetails: events.CommonDetails{AnalysisError: "gmalware unreachable"},
+					File:          "/data/error",
+					Size:          11,
+				},
+			}},
+			wantMetrics: metrics.ConnectorMetrics{ItemsError: 1},
+		},
+		{
+			name:        "analysis error",
+			stages:      Stages{Analyzer: stages.Analyzer, Decider: stages.Decider, Actors: stages.Actors},
+			item:        memoryItem("error", "unreachable"),
+			wantErr:     true,
+			wantMetrics: metrics.ConnectorMetrics{ItemsError: 1},
+		},
+		{
+			name:        "no actor",
+			stages:      Stages{Analyzer: stages.Analyzer, Decider: MalwareDecider{Action: events.ActionRemove}},
+			item:        memoryItem("malware", "evil"),
+			wantVerdict: Verdict{Action: events.ActionRemove, Reason: events.ReasonMalware, Result: malware},
+			wantErr:     true,
+			wantMetrics: metrics.ConnectorMetrics{ItemsError: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acted = nil
+			recorder := &mitigationRecorder{}
+			collector := &metrics.MetricsCollector{}
+			tt.stages.Notifier = recorder
+			tt.stages.Metrics = collector
+			p, err := New(tt.stages)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			got, err := p.Process(t.Context(), tt.item)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(got, tt.wantVerdict); diff != "" {
+				t.Errorf("Process() diff(got-want)=%s", diff)
+			}
+			if diff := cmp.Diff(acted, tt.wantActed); diff != "" {
+				t.Errorf("actors diff(got-want)=%s", diff)
+			}
+			if diff := cmp.Diff(recorder.mitigations, tt.wantMitigations); diff != "" {
+				t.Errorf("mitigations diff(got-want)=%s", diff)
+			}
+			if diff := cmp.Diff(collector.GetAndReset(), tt.wantMetrics, cmpopts.IgnoreFields(metrics.ConnectorMetrics{}, "ProcessStart")); diff != "" {
+				t.Errorf("metrics diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	analyzer := SubmitterAnalyzer{}
+	tests := []struct {
+		name   string
+		stages Stages
+	}{
+		{name: "missing analyzer", stages: Stages{Decider: MalwareDecider{}}},
+		{name: "missing decider", stages: Stages{Analyzer: analyzer}},
+		{name: "missing error actor", stages: Stages{Analyzer: analyzer, Decider: MalwareDecider{}, ErrorAction: events.ActionBlock}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.stages); !errors.Is(err, ErrInvalidPipeline) {
+				t.Errorf("New() error = %v, want %v", err, ErrInvalidPipeline)
+			}
+		})
+	}
+}
+
+func TestQuarantineActor(t *testing.T) {
+	store, err := quarantine.Open(quarantine.Config{Location: t.TempDir(), Password: "infected"})
+	if err != nil {
+		t.Fatalf("quarantine.Open() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "evil.exe")
+	if err = os.WriteFile(path, []byte("MZ evil"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	item, err := FileItem(path)
+	if err != nil {
+		t.Fatalf("FileItem() error = %v", err)
+	}
+	verdict := &Verdict{Action: events.ActionQuarantine, Result: gdetect.Result{Malwares: []string{"Trojan.Generic"}}}
+	if err = (QuarantineActor{Store: store}).Act(t.Context(), item, verdict); err != nil {
+		t.Fatalf("Act() error = %v", err)
+	}
+	if _, err = os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("quarantined file not removed, stat error = %v", err)
+	}
+	quarantined, err := store.InspectQuarantine(t.Context(), verdict.QuarantineLocation)
+	if err != nil {
+		t.Fatalf("InspectQuarantine() error = %v", err)
+	}
+	if quarantined.Name != path || quarantined.Size != 7 {
+		t.Errorf("quarantined item = %+v, want %s of 7 bytes", quarantined, path)
+	}
+
+	// items that cannot be removed are not kept in quarantine
+	verdict = &Verdict{Action: events.ActionQuarantine}
+	if err = (QuarantineActor{Store: store}).Act(t.Context(), memoryItem("remote", "MZ evil"), verdict); err == nil {
+		t.Errorf("Act() on item without Remove error = nil")
+	}
+	if items, _ := store.ListQuarantine(t.Context()); len(items) != 1 {
+		t.Errorf("quarantine holds %d items, want 1", len(items))
+	}
+}
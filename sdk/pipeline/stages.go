@@ -0,0 +1,179 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/filetype"
+	"github.com/glimps-re/connector-integration/sdk/quarantine"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+)
+
+// FileItem returns item of local file path, removed from disk by delete and quarantine actors.
+func FileItem(path string) (item Item, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	item = Item{
+		ID:   path,
+		Path: path,
+		Size: info.Size(),
+		Open: func(context.Context) (io.ReadCloser, error) {
+			return os.Open(path) //nolint:gosec // path of acquired item
+		},
+		Remove: func(context.Context) error {
+			return os.Remove(path)
+		},
+	}
+	return
+}
+
+// MaxSizeFilter stops items bigger than MaxSize with Action (no action skips them) and events.ReasonTooBig.
+type MaxSizeFilter struct {
+	MaxSize int64
+	Action  events.MitigationAction
+}
+
+func (f MaxSizeFilter) Filter(ctx context.Context, item Item) (verdict Verdict, stop bool, err error) {
+	if f.MaxSize <= 0 || item.Size <= f.MaxSize {
+		return
+	}
+	verdict = Verdict{Action: f.Action, Reason: events.ReasonTooBig, Info: fmt.Sprintf("file of %d bytes exceeds %d bytes", item.Size, f.MaxSize)}
+	stop = true
+	return
+}
+
+// FileTypeFilter applies file type policy (e.g. CommonConnectorConfig.FileTypePolicy): skipped items are not
+// analyzed, blocked ones are stopped with events.ActionBlock and events.ReasonFileType.
+type FileTypeFilter struct {
+	Policy filetype.Policy
+}
+
+func (f FileTypeFilter) Filter(ctx context.Context, item Item) (verdict Verdict, stop bool, err error) {
+	if len(f.Policy.Rules) == 0 && f.Policy.Default == "" {
+		return
+	}
+	r, err := item.Open(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { _ = r.Close() }()
+	header := make([]byte, filetype.HeaderSize)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return
+	}
+	err = nil
+	ft := filetype.Detect(header[:n], item.Path)
+	switch f.Policy.Evaluate(ft, item.Path) {
+	case filetype.ActionSkip:
+		stop = true
+	case filetype.ActionBlock:
+		verdict = Verdict{Action: events.ActionBlock, Reason: events.ReasonFileType, Info: fmt.Sprintf("%s files are blocked", ft.MIME)}
+		stop = true
+	}
+	return
+}
+
+// SubmitterAnalyzer analyzes items with GLIMPS Malware, waiting for their result.
+type SubmitterAnalyzer struct {
+	Submitter gdetect.GDetectSubmitter
+	// Options of submissions, file name defaulting to item base name
+	Options gdetect.WaitForOptions
+}
+
+func (a SubmitterAnalyzer) Analyze(ctx context.Context, item Item) (result gdetect.Result, err error) {
+	r, err := item.Open(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { _ = r.Close() }()
+	options := a.Options
+	if options.Filename == "" {
+		options.Filename = filepath.Base(item.Path)
+	}
+	result, err = a.Submitter.WaitForReader(ctx, r, options)
+	return
+}
+
+// MalwareDecider applies Action with events.ReasonMalware to malware, other items are left as is.
+type MalwareDecider struct {
+	Action events.MitigationAction
+}
+
+func (d MalwareDecider) Decide(ctx context.Context, item Item, result gdetect.Result) (verdict Verdict, err error) {
+	if result.Malware {
+		verdict = Verdict{Action: d.Action, Reason: events.ReasonMalware}
+	}
+	return
+}
+
+// QuarantineActor moves items to a local quarantine: item content is quarantined, then removed.
+type QuarantineActor struct {
+	Store *quarantine.Store
+}
+
+func (a QuarantineActor) Act(ctx context.Context, item Item, verdict *Verdict) (err error) {
+	r, err := item.Open(ctx)
+	if err != nil {
+		return
+	}
+	added, err := a.Store.Add(r, sdk.QuarantineItem{Name: item.Path, Malwares: verdict.Result.Malwares})
+	_ = r.Close()
+	if err != nil {
+		err = fmt.Errorf("could not quarantine item, %w", err)
+		return
+	}
+	verdict.QuarantineLocation = added.ID
+	if err = removeItem(ctx, item); err != nil {
+		// keep quarantine consistent with remaining items
+		_ = a.Store.Remove(added.ID)
+		verdict.QuarantineLocation = ""
+	}
+	return
+}
+
+// DeleteActor removes items.
+type DeleteActor struct{}
+
+func (DeleteActor) Act(ctx context.Context, item Item, verdict *Verdict) (err error) {
+	return removeItem(ctx, item)
+}
+
+func removeItem(ctx context.Context, item Item) (err error) {
+	if item.Remove == nil {
+		err = fmt.Errorf("item %s cannot be removed", item.ID)
+		return
+	}
+	if err = item.Remove(ctx); err != nil {
+		err = fmt.Errorf("could not remove item, %w", err)
+	}
+	return
+}
+
+// LogActor only logs items, e.g. for monitoring only deployments.
+type LogActor struct {
+	// Logger logs items, pipeline logger if nil
+	Logger *slog.Logger
+}
+
+func (a LogActor) Act(ctx context.Context, item Item, verdict *Verdict) (err error) {
+	l := a.Logger
+	if l == nil {
+		l = logger
+	}
+	l.WarnContext(ctx, "item mitigated",
+		slog.String("item", item.ID),
+		slog.String("path", item.Path),
+		slog.String("reason", string(verdict.Reason)),
+		slog.Any("malwares", verdict.Result.Malwares),
+	)
+	return
+}
@@ -0,0 +1,95 @@
+package sdk
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	// DefaultPollInterval is the delay between tasks requests without long polling (see
+	// ConnectorManagerClientConfig.TasksWait), when PollInterval is 0.
+	DefaultPollInterval = 2 * time.Second
+	// DefaultPollErrorBackoff is the initial delay before polling again after a failed tasks request.
+	DefaultPollErrorBackoff    = time.Second
+	maxPollErrorBackoff        = 5 * time.Minute
+	pollErrorBackoffRandomness = 0.5
+	// longPollTimeoutMargin is added to TasksWait to bound long-poll requests, so a connection silently dropped (e.g.
+	// by a proxy) does not hang tasks goroutine.
+	longPollTimeoutMargin = 30 * time.Second
+)
+
+// pollPolicy paces tasks requests: PollInterval plus a random jitter between polls, so connectors started together do
+// not poll in sync, and an exponential backoff after failed requests in a row, so an unavailable manager is not
+// hammered. With long polling, requests answered early without tasks (e.g. by a manager ignoring wait) are paced
+// as without it. It is only used by tasks goroutine.
+type pollPolicy struct {
+	interval  time.Duration
+	jitter    time.Duration
+	tasksWait time.Duration
+	failures  int  // failed requests in a row
+	early     bool // last long-poll request answered early without tasks
+	sleep     func(ctx context.Context, d time.Duration) (err error)
+}
+
+func newPollPolicy(interval time.Duration, jitter time.Duration, tasksWait time.Duration) (p *pollPolicy) {
+	if interval == 0 && tasksWait <= 0 {
+		interval = DefaultPollInterval
+	}
+	p = &pollPolicy{interval: max(interval, 0), jitter: max(jitter, 0), tasksWait: max(tasksWait, 0), sleep: sleepContext}
+	return
+}
+
+// polled records a successful tasks request answered after elapsed with tasks count tasks.
+func (p *pollPolicy) polled(elapsed time.Duration, tasks int) {
+	p.early = p.tasksWait > 0 && tasks == 0 && elapsed < p.tasksWait/2
+}
+
+// succeeded records a successful request not followed by a pause (e.g. a task stream), so next failure backs off
+// from DefaultPollErrorBackoff.
+func (p *pollPolicy) succeeded() {
+	p.failures = 0
+}
+
+// wait returns the delay before next poll, after a failed poll if failed.
+func (p *pollPolicy) wait(failed bool) (d time.Duration) {
+	if !failed {
+		p.failures = 0
+		d = p.interval
+		if p.early {
+			d = max(d, DefaultPollInterval)
+		}
+		if p.jitter > 0 {
+			d += rand.N(p.jitter) //nolint:gosec // jitter does not need a secure random
+		}
+		return
+	}
+	p.failures++
+	d = DefaultPollErrorBackoff
+	for range p.failures - 1 {
+		d *= 2
+		if d >= maxPollErrorBackoff {
+			d = maxPollErrorBackoff
+			break
+		}
+	}
+	jitter := (rand.Float64()*2 - 1) * pollErrorBackoffRandomness //nolint:gosec // jitter does not need a secure random
+	d += time.Duration(float64(d) * jitter)
+	// a failed poll is never retried sooner than a successful one
+	d = max(d, p.interval)
+	return
+}
+
+// pause waits before next poll until ctx is done, done is true if it is.
+func (p *pollPolicy) pause(ctx context.Context, failed bool) (done bool) {
+	d := p.wait(failed)
+	if failed {
+		logger.Debug("tasks request failed, back off", slog.Int("failures", p.failures), slog.String("wait", d.String()))
+	}
+	if d <= 0 {
+		return
+	}
+	done = p.sleep(ctx, d) != nil
+	return
+}
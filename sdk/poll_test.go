@@ -0,0 +1,45 @@
+package sdk
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPollPolicy_wait(t *testing.T) {
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		jitter    time.Duration
+		tasksWait time.Duration
+		failed    []bool // polls before the checked one
+		early     bool   // checked poll answered early without tasks
+		checked   bool
+		wantMin   time.Duration
+		wantMax   time.Duration
+	}{
+		{name: "default interval", wantMin: DefaultPollInterval, wantMax: DefaultPollInterval},
+		{name: "long polling", tasksWait: time.Minute},
+		{name: "long poll answered early", tasksWait: time.Minute, early: true, wantMin: DefaultPollInterval, wantMax: DefaultPollInterval},
+		{name: "interval with jitter", interval: 10 * time.Second, jitter: 2 * time.Second, wantMin: 10 * time.Second, wantMax: 12 * time.Second},
+		{name: "first failure", tasksWait: time.Minute, checked: true, wantMin: DefaultPollErrorBackoff / 2, wantMax: DefaultPollErrorBackoff * 3 / 2},
+		{name: "third failure", tasksWait: time.Minute, failed: []bool{true, true}, checked: true, wantMin: 2 * time.Second, wantMax: 6 * time.Second},
+		{name: "capped backoff", tasksWait: time.Minute, failed: slices.Repeat([]bool{true}, 20), checked: true, wantMin: maxPollErrorBackoff / 2, wantMax: maxPollErrorBackoff * 3 / 2},
+		{name: "failures reset by success", tasksWait: time.Minute, failed: []bool{true, true, true, false}, checked: true, wantMin: DefaultPollErrorBackoff / 2, wantMax: DefaultPollErrorBackoff * 3 / 2},
+		{name: "failure not retried before interval", interval: time.Minute, checked: true, wantMin: time.Minute, wantMax: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPollPolicy(tt.interval, tt.jitter, tt.tasksWait)
+			for _, failed := range tt.failed {
+				p.wait(failed)
+			}
+			if tt.early {
+				p.polled(time.Second, 0)
+			}
+			if got := p.wait(tt.checked); got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("wait() = %s, want between %s and %s", got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package sdk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlField is a key of a generated yaml mapping, with its default value.
+type yamlField struct {
+	key   string
+	value any
+}
+
+// prometheusAnnotations are annotations (and compose labels) of connectors scraped on MetricsPath.
+var prometheusAnnotations = map[string]string{
+	"prometheus.io/scrape": "true",
+	"prometheus.io/port":   strconv.Itoa(DefaultMetricsPort),
+	"prometheus.io/path":   MetricsPath,
+}
+
+// helmProbes are values of helm charts of CapabilityHealth connector types, their chart forwarding livenessProbe,
+// readinessProbe and podAnnotations values to connector pod.
+var helmProbes = []yamlField{
+	{key: "livenessProbe", value: map[string]any{
+		"httpGet":          map[string]any{"path": LivenessPath, "port": DefaultHealthPort},
+		"periodSeconds":    10,
+		"timeoutSeconds":   3,
+		"failureThreshold": 6,
+	}},
+	{key: "readinessProbe", value: map[string]any{
+		"httpGet":          map[string]any{"path": ReadinessPath, "port": DefaultHealthPort},
+		"periodSeconds":    10,
+		"timeoutSeconds":   3,
+		"failureThreshold": 3,
+	}},
+	{key: "podAnnotations", value: prometheusAnnotations},
+}
+
+// composeProbes are settings of connector service (the first one) of CapabilityHealth connector types compose files.
+// The healthcheck relies on wget, compose files of images without it set their own.
+var composeProbes = []yamlField{
+	{key: "healthcheck", value: map[string]any{
+		"test":         []string{"CMD", "wget", "-q", "-O", "/dev/null", fmt.Sprintf("http://127.0.0.1:%d%s", DefaultHealthPort, ReadinessPath)},
+		"interval":     "30s",
+		"timeout":      "3s",
+		"retries":      3,
+		"start_period": "30s",
+	}},
+	{key: "labels", value: prometheusAnnotations},
+}
+
+var errInvalidDeployment = errors.New("invalid generated deployment")
+
+// withHelmProbes returns rendered helm values with helmProbes, see withProbes.
+func withHelmProbes(values []byte) (probed []byte, err error) {
+	probed, err = withProbes(values, false, helmProbes)
+	return
+}
+
+// withComposeProbes returns rendered compose file with composeProbes, see withProbes.
+func withComposeProbes(compose []byte) (probed []byte, err error) {
+	probed, err = withProbes(compose, true, composeProbes)
+	return
+}
+
+// withProbes returns rendered yaml with fields merged into its root mapping, or into its first service with
+// service. Values set by connector type templates are kept, so they may tune probes (e.g. a slow starting connector),
+// mappings being merged key by key (e.g. podAnnotations).
+func withProbes(rendered []byte, service bool, fields []yamlField) (probed []byte, err error) {
+	doc := &yaml.Node{}
+	if err = yaml.Unmarshal(rendered, doc); err != nil {
+		err = fmt.Errorf("%w, %w", errInvalidDeployment, err)
+		return
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		err = fmt.Errorf("%w, root is not a mapping", errInvalidDeployment)
+		return
+	}
+	target := doc.Content[0]
+	if service {
+		services := mappingValue(target, "services")
+		if services == nil || services.Kind != yaml.MappingNode || len(services.Content) < 2 {
+			err = fmt.Errorf("%w, no compose service", errInvalidDeployment)
+			return
+		}
+		target = services.Content[1]
+		if target.Kind != yaml.MappingNode {
+			err = fmt.Errorf("%w, service %s is not a mapping", errInvalidDeployment, services.Content[0].Value)
+			return
+		}
+	}
+	for _, field := range fields {
+		value := &yaml.Node{}
+		if err = value.Encode(field.value); err != nil {
+			return
+		}
+		mergeMapping(target, field.key, value)
+	}
+	b := bytes.NewBuffer(nil)
+	encoder := yaml.NewEncoder(b)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(doc); err != nil {
+		return
+	}
+	if err = encoder.Close(); err != nil {
+		return
+	}
+	probed = b.Bytes()
+	return
+}
+
+// mergeMapping sets key of mapping to value if it is not set, or merges value keys into it if both are mappings.
+func mergeMapping(mapping *yaml.Node, key string, value *yaml.Node) {
+	existing := mappingValue(mapping, key)
+	switch {
+	case existing == nil:
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			mergeMapping(existing, value.Content[i].Value, value.Content[i+1])
+		}
+	case existing.Tag == "!!null" && existing.Kind == yaml.ScalarNode:
+		// e.g. "podAnnotations:" without value
+		*existing = *value
+	}
+}
+
+// mappingValue returns value of key in mapping, nil if not set.
+func mappingValue(mapping *yaml.Node, key string) (value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value = mapping.Content[i+1]
+			return
+		}
+	}
+	return
+}
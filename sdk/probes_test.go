@@ -0,0 +1,185 @@
+package sdk
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+func TestWithProbes(t *testing.T) {
+	tests := []struct {
+		name    string
+		service bool
+		fields  []yamlField
+		input   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "helm values",
+			fields: helmProbes,
+			input: `config:
+  api-key: key
+podAnnotations:
+  prometheus.io/scrape: "false"
+  team: detect
+readinessProbe:
+  failureThreshold: 12
+`,
+			want: `config:
+  api-key: key
+podAnnotations:
+  prometheus.io/scrape: "false"
+  team: detect
+  prometheus.io/path: /metrics
+  prometheus.io/port: "9090"
+readinessProbe:
+  failureThreshold: 12
+  httpGet:
+    path: /readyz
+    port: 8080
+  periodSeconds: 10
+  timeoutSeconds: 3
+livenessProbe:
+  failureThreshold: 6
+  httpGet:
+    path: /healthz
+    port: 8080
+  periodSeconds: 10
+  timeoutSeconds: 3
+`,
+		},
+		{
+			name:   "empty annotations",
+			fields: []yamlField{{key: "podAnnotations", value: prometheusAnnotations}},
+			input:  "podAnnotations:\n",
+			want: `podAnnotations:
+  prometheus.io/path: /metrics
+  prometheus.io/port: "9090"
+  prometheus.io/scrape: "true"
+`,
+		},
+		{
+			name:    "compose connector service",
+			service: true,
+			fields:  composeProbes,
+			input: `services:
+  connector:
+    image: connector
+    healthcheck:
+      test: ["CMD", "/connector", "health"]
+    labels:
+      - traefik.enable=true
+  smtprelay:
+    image: smtprelay
+`,
+			want: `services:
+  connector:
+    image: connector
+    healthcheck:
+      test: ["CMD", "/connector", "health"]
+      interval: 30s
+      retries: 3
+      start_period: 30s
+      timeout: 3s
+    labels:
+      - traefik.enable=true
+  smtprelay:
+    image: smtprelay
+`,
+		},
+		{
+			name:    "no compose service",
+			service: true,
+			fields:  composeProbes,
+			input:   "name: connector\n",
+			wantErr: errInvalidDeployment,
+		},
+		{
+			name:    "not a mapping",
+			fields:  helmProbes,
+			input:   "- config\n",
+			wantErr: errInvalidDeployment,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := withProbes([]byte(tt.input), tt.service, tt.fields)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("withProbes() error = %v, want %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(string(got), tt.want); tt.wantErr == nil && diff != "" {
+				t.Errorf("withProbes() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectorTypeLoader_probes(t *testing.T) {
+	c, err := NewConnectorsTypesLoader(true)
+	if err != nil {
+		t.Fatalf("could not init connector types loader, err: %v", err)
+	}
+	type deployment struct {
+		Services map[string]struct {
+			Healthcheck map[string]any    `yaml:"healthcheck"`
+			Labels      map[string]string `yaml:"labels"`
+		} `yaml:"services"`
+		LivenessProbe  map[string]any    `yaml:"livenessProbe"`
+		ReadinessProbe map[string]any    `yaml:"readinessProbe"`
+		PodAnnotations map[string]string `yaml:"podAnnotations"`
+	}
+	compose, err := c.GetTemplatedDockerCompose(DummyKey, ConsoleConfig{APIKey: "key", URL: "https://console.example.com"})
+	if err != nil {
+		t.Fatalf("GetTemplatedDockerCompose() error = %v", err)
+	}
+	got := deployment{}
+	if err = yaml.Unmarshal([]byte(compose), &got); err != nil {
+		t.Fatalf("invalid compose file %s, error = %v", compose, err)
+	}
+	if got.Services["dummy"].Healthcheck == nil {
+		t.Errorf("compose file %s, want healthcheck", compose)
+	}
+	if diff := cmp.Diff(got.Services["dummy"].Labels, prometheusAnnotations); diff != "" {
+		t.Errorf("compose labels diff(got-want)=%s", diff)
+	}
+
+	helmConfig, err := (&DummyConfig{}).GetHelmConfig(ConsoleConfig{APIKey: "key"})
+	if err != nil {
+		t.Fatalf("GetHelmConfig() error = %v", err)
+	}
+	r, err := c.GetTemplatedHelm(DummyKey, helmConfig, HelmOptions{ValuesOnly: true})
+	if err != nil {
+		t.Fatalf("GetTemplatedHelm() error = %v", err)
+	}
+	values, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("could not read values, error = %v", err)
+	}
+	got = deployment{}
+	if err = yaml.Unmarshal(values, &got); err != nil {
+		t.Fatalf("invalid values %s, error = %v", values, err)
+	}
+	if got.LivenessProbe == nil || got.ReadinessProbe == nil {
+		t.Errorf("values %s, want liveness and readiness probes", values)
+	}
+	if diff := cmp.Diff(got.PodAnnotations, prometheusAnnotations); diff != "" {
+		t.Errorf("pod annotations diff(got-want)=%s", diff)
+	}
+	if !strings.Contains(string(values), "api-key: key") {
+		t.Errorf("values %s, want templated values", values)
+	}
+
+	// connector types without CapabilityHealth are left as templated
+	compose, err = c.GetTemplatedDockerCompose(SharepointKey, ConsoleConfig{APIKey: "key"})
+	if err != nil {
+		t.Fatalf("GetTemplatedDockerCompose() error = %v", err)
+	}
+	if strings.Contains(compose, "prometheus.io") {
+		t.Errorf("sharepoint compose file %s, want no scrape labels", compose)
+	}
+}
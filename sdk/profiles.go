@@ -0,0 +1,94 @@
+package sdk
+
+import (
+	"path"
+	"strings"
+)
+
+// DefaultGMalwareProfileName is the name of the profile built from CommonConnectorConfig GLIMPS Malware fields.
+const DefaultGMalwareProfileName = "default"
+
+// GMalwareProfile is a named GLIMPS Malware endpoint, used for items matching any of its rules.
+type GMalwareProfile struct {
+	Name          string   `json:"name" yaml:"name" mapstructure:"name" validate:"required" desc:"Profile name (e.g. department name), must be unique"`
+	APIURL        string   `json:"api_url" yaml:"api_url" mapstructure:"api_url" validate:"required,url" desc:"GLIMPS Malware API URL"`
+	APIToken      string   `json:"api_token" yaml:"api_token" mapstructure:"api_token" validate:"required" password:"true" desc:"GLIMPS Malware API Token"`
+	NoCertCheck   bool     `json:"no_cert_check" yaml:"no_cert_check" mapstructure:"no_cert_check" desc:"Disable certificate check for GLIMPS Malware"`
+	UserTags      []string `json:"user_tags" yaml:"user_tags" mapstructure:"user_tags" desc:"List of tags set by connector on GLIMPS Malware detect submission"`
+	Paths         []string `json:"paths" yaml:"paths" mapstructure:"paths" desc:"Use profile for items under these monitored paths (e.g. /data/finance)"`
+	Sites         []string `json:"sites" yaml:"sites" mapstructure:"sites" desc:"Use profile for items of these sites (e.g. https://myTenant.sharepoint.com/sites/finance)"`
+	SenderDomains []string `json:"sender_domains" yaml:"sender_domains" mapstructure:"sender_domains" desc:"Use profile for emails sent from these domains, subdomains included (e.g. example.com)"`
+}
+
+// GMalwareItem describes an item to analyze, to select its GLIMPS Malware profile.
+// Connectors set the attributes they know about.
+type GMalwareItem struct {
+	Path   string
+	Site   string
+	Sender string `desc:"email address or domain"`
+	// Filename is the item file name, Path base name being used if empty (e.g. to match routing rules extensions)
+	Filename string
+	// Size is the item size in bytes, 0 if unknown
+	Size int64
+}
+
+func (p GMalwareProfile) matches(item GMalwareItem) bool {
+	return matchesSource(item, p.Paths, p.Sites, p.SenderDomains)
+}
+
+// matchesSource reports whether item is under one of paths, of one of sites, or sent from one of domains.
+func matchesSource(item GMalwareItem, paths []string, sites []string, domains []string) bool {
+	if item.Path != "" {
+		itemPath := path.Clean(item.Path)
+		for _, profilePath := range paths {
+			profilePath = path.Clean(profilePath)
+			if itemPath == profilePath || strings.HasPrefix(itemPath, strings.TrimSuffix(profilePath, "/")+"/") {
+				return true
+			}
+		}
+	}
+	if item.Site != "" {
+		site := strings.TrimSuffix(item.Site, "/")
+		for _, s := range sites {
+			if strings.EqualFold(site, strings.TrimSuffix(s, "/")) {
+				return true
+			}
+		}
+	}
+	if item.Sender != "" {
+		domain := item.Sender
+		if i := strings.LastIndex(domain, "@"); i >= 0 {
+			domain = domain[i+1:]
+		}
+		domain = strings.ToLower(domain)
+		for _, d := range domains {
+			d = strings.ToLower(strings.TrimPrefix(d, "@"))
+			if domain == d || strings.HasSuffix(domain, "."+d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DefaultGMalwareProfile returns the profile made of GLIMPS Malware fields of config.
+func (c CommonConnectorConfig) DefaultGMalwareProfile() GMalwareProfile {
+	return GMalwareProfile{
+		Name:        DefaultGMalwareProfileName,
+		APIURL:      c.GMalwareAPIURL,
+		APIToken:    c.GMalwareAPIToken,
+		NoCertCheck: c.GMalwareNoCertCheck,
+		UserTags:    c.GMalwareUserTags,
+	}
+}
+
+// SelectGMalwareProfile returns the first profile (in config order) matching item,
+// default profile if none matches.
+func (c CommonConnectorConfig) SelectGMalwareProfile(item GMalwareItem) (profile GMalwareProfile) {
+	for _, p := range c.GMalwareProfiles {
+		if p.matches(item) {
+			return p
+		}
+	}
+	return c.DefaultGMalwareProfile()
+}
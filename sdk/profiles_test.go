@@ -0,0 +1,89 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCommonConnectorConfig_SelectGMalwareProfile(t *testing.T) {
+	config := CommonConnectorConfig{
+		GMalwareAPIURL:   "https://gmalware.example.com",
+		GMalwareAPIToken: "default-token",
+		GMalwareUserTags: []string{"connector"},
+		GMalwareProfiles: []GMalwareProfile{
+			{
+				Name:          "finance",
+				APIURL:        "https://finance.gmalware.example.com",
+				APIToken:      "finance-token",
+				Paths:         []string{"/data/finance/"},
+				Sites:         []string{"https://tenant.sharepoint.com/sites/finance"},
+				SenderDomains: []string{"bank.example"},
+			},
+			{
+				Name:     "rd",
+				APIURL:   "https://rd.gmalware.example.com",
+				APIToken: "rd-token",
+				Paths:    []string{"/data"},
+			},
+		},
+	}
+	tests := []struct {
+		name string
+		item GMalwareItem
+		want string
+	}{
+		{name: "path in profile", item: GMalwareItem{Path: "/data/finance/2025/report.pdf"}, want: "finance"},
+		{name: "exact path", item: GMalwareItem{Path: "/data/finance"}, want: "finance"},
+		{name: "path prefix is not a parent", item: GMalwareItem{Path: "/data/finances/report.pdf"}, want: "rd"},
+		{name: "second profile", item: GMalwareItem{Path: "/data/rd/file"}, want: "rd"},
+		{name: "site case insensitive", item: GMalwareItem{Site: "https://tenant.sharepoint.com/sites/Finance/"}, want: "finance"},
+		{name: "sender address", item: GMalwareItem{Sender: "john@bank.example"}, want: "finance"},
+		{name: "sender subdomain", item: GMalwareItem{Sender: "john@mail.BANK.example"}, want: "finance"},
+		{name: "sender other domain", item: GMalwareItem{Sender: "john@notbank.example"}, want: DefaultGMalwareProfileName},
+		{name: "no match", item: GMalwareItem{Path: "/home/user/file"}, want: DefaultGMalwareProfileName},
+		{name: "empty item", item: GMalwareItem{}, want: DefaultGMalwareProfileName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.SelectGMalwareProfile(tt.item); got.Name != tt.want {
+				t.Errorf("SelectGMalwareProfile() = %s, want %s", got.Name, tt.want)
+			}
+		})
+	}
+	t.Run("default profile", func(t *testing.T) {
+		want := GMalwareProfile{
+			Name:     DefaultGMalwareProfileName,
+			APIURL:   "https://gmalware.example.com",
+			APIToken: "default-token",
+			UserTags: []string{"connector"},
+		}
+		if diff := cmp.Diff(config.SelectGMalwareProfile(GMalwareItem{}), want); diff != "" {
+			t.Errorf("SelectGMalwareProfile() diff(got-want)=%s", diff)
+		}
+	})
+}
+
+func TestGMalwareProfiles_validation(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles string
+		wantErr  bool
+	}{
+		{name: "no profiles", profiles: `[]`},
+		{name: "ok", profiles: `[{"name":"a","api_url":"https://a.example.com","api_token":"t"},{"name":"b","api_url":"https://b.example.com","api_token":"t"}]`},
+		{name: "duplicate name", profiles: `[{"name":"a","api_url":"https://a.example.com","api_token":"t"},{"name":"a","api_url":"https://b.example.com","api_token":"t"}]`, wantErr: true},
+		{name: "invalid url", profiles: `[{"name":"a","api_url":"not an url","api_token":"t"}]`, wantErr: true},
+		{name: "missing token", profiles: `[{"name":"a","api_url":"https://a.example.com"}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := `{"gmalware_api_url":"https://gmalware.example.com","gmalware_api_token":"token","gmalware_profiles":` + tt.profiles + `}`
+			config := new(ICAPConfig)
+			err := BindAndValidateRaw(config, []byte(raw))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BindAndValidateRaw() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ConfigSource is where the value of a config field comes from.
+type ConfigSource string
+
+const (
+	// default value (see SetDefaults), for fields set by no other source
+	ConfigSourceDefault ConfigSource = "default"
+	// config sent by console, at registration or on update-config tasks
+	ConfigSourceConsole ConfigSource = "console"
+	// environment variables read by connector
+	ConfigSourceEnvironment ConfigSource = "environment"
+	// local config file read by connector
+	ConfigSourceFile ConfigSource = "file"
+	// secrets store (see RunOptions.Secrets)
+	ConfigSourceSecrets ConfigSource = "secrets"
+)
+
+func (ConfigSource) Values() []ConfigSource {
+	return []ConfigSource{ConfigSourceDefault, ConfigSourceConsole, ConfigSourceEnvironment, ConfigSourceFile, ConfigSourceSecrets}
+}
+
+// ConfigProvenance records the source of config fields, by JSON path (e.g. "monitoring.realtime.engine"), arrays
+// being recorded as a whole (e.g. "paths"). The last source recorded for a field wins, so sources must be recorded
+// in the order they are applied to config. It is safe for concurrent use.
+type ConfigProvenance struct {
+	lock    sync.Mutex
+	sources map[string]ConfigSource
+}
+
+func NewConfigProvenance() (p *ConfigProvenance) {
+	p = &ConfigProvenance{sources: map[string]ConfigSource{}}
+	return
+}
+
+// Set records source for fields at paths, and for their sub-fields.
+func (p *ConfigProvenance) Set(source ConfigSource, paths ...string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, path := range paths {
+		for recorded := range p.sources {
+			if isSubPath(recorded, path) {
+				delete(p.sources, recorded)
+			}
+		}
+		p.sources[path] = source
+	}
+}
+
+// SetRaw records source for every field set by raw, a JSON object applied to config (e.g. a local config file
+// converted to JSON). Null members are recorded too, as they clear their field.
+func (p *ConfigProvenance) SetRaw(source ConfigSource, raw []byte) (err error) {
+	paths := []string{}
+	if err = rawFieldPaths(raw, "", &paths); err != nil {
+		return
+	}
+	p.Set(source, paths...)
+	return
+}
+
+// Merge records sources of other, as if they were recorded after p ones.
+func (p *ConfigProvenance) Merge(other *ConfigProvenance) {
+	other.lock.Lock()
+	sources := maps.Clone(other.sources)
+	other.lock.Unlock()
+	for _, path := range slices.Sorted(maps.Keys(sources)) {
+		p.Set(sources[path], path)
+	}
+}
+
+// Sources returns the source of every field of config, a config struct or pointer to it, fields set by no source
+// being ConfigSourceDefault. For other configs (e.g. json.RawMessage), recorded sources are returned.
+func (p *ConfigProvenance) Sources(config any) (sources map[string]ConfigSource) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	configType := reflect.TypeOf(config)
+	for configType != nil && configType.Kind() == reflect.Pointer {
+		configType = configType.Elem()
+	}
+	if configType == nil || configType.Kind() != reflect.Struct {
+		sources = maps.Clone(p.sources)
+		return
+	}
+	sources = map[string]ConfigSource{}
+	for _, path := range configFieldPaths(configType, "") {
+		sources[path] = p.source(path)
+	}
+	return
+}
+
+// source returns source recorded for path, one of its parents or, for maps, one of its sub-fields.
+func (p *ConfigProvenance) source(path string) (source ConfigSource) {
+	if recorded, ok := p.sources[path]; ok {
+		return recorded
+	}
+	parent := ""
+	for recorded := range p.sources {
+		if isSubPath(path, recorded) && len(recorded) > len(parent) {
+			parent = recorded
+		}
+	}
+	if parent != "" {
+		return p.sources[parent]
+	}
+	for _, recorded := range slices.Sorted(maps.Keys(p.sources)) {
+		if isSubPath(recorded, path) {
+			return p.sources[recorded]
+		}
+	}
+	return ConfigSourceDefault
+}
+
+// isSubPath returns whether path is a sub-field of parent.
+func isSubPath(path string, parent string) bool {
+	return strings.HasPrefix(path, parent+".")
+}
+
+// configFieldPaths returns paths of leaf fields of struct t: fields that are not structs, or decoded by a json.Unmarshaler.
+func configFieldPaths(t reflect.Type, path string) (paths []string) {
+	fields, names := jsonFields(t)
+	for i, field := range fields {
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		fieldPath := jsonPath(path, names[i])
+		if fieldType.Kind() == reflect.Struct && !reflect.PointerTo(fieldType).Implements(jsonUnmarshalerType) && !reflect.PointerTo(fieldType).Implements(textUnmarshalerType) {
+			paths = append(paths, configFieldPaths(fieldType, fieldPath)...)
+			continue
+		}
+		paths = append(paths, fieldPath)
+	}
+	return
+}
+
+// rawFieldPaths appends to paths the paths of raw non object values, raw being a JSON object at path.
+func rawFieldPaths(raw []byte, path string, paths *[]string) (err error) {
+	members := map[string]json.RawMessage{}
+	if err = json.Unmarshal(raw, &members); err != nil {
+		return
+	}
+	for key, member := range members {
+		memberPath := jsonPath(path, key)
+		if trimmed := bytes.TrimSpace(member); len(trimmed) > 0 && trimmed[0] == '{' {
+			if err = rawFieldPaths(trimmed, memberPath, paths); err != nil {
+				return
+			}
+			continue
+		}
+		*paths = append(*paths, memberPath)
+	}
+	return
+}
+
+// configCapture decodes JSON into config, keeping it raw to record its provenance.
+type configCapture struct {
+	config any
+	raw    json.RawMessage
+}
+
+func (c *configCapture) UnmarshalJSON(raw []byte) (err error) {
+	c.raw = bytes.Clone(raw)
+	err = json.Unmarshal(raw, c.config)
+	return
+}
@@ -0,0 +1,113 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfigProvenance_Sources(t *testing.T) {
+	type labelsConfig struct {
+		Labels map[string]string `json:"labels"`
+	}
+	type testConfig struct {
+		CommonConnectorConfig
+		Monitoring HostMonitoringConfig `json:"monitoring"`
+		Paths      []string             `json:"paths"`
+		Extra      labelsConfig         `json:"extra"`
+	}
+	p := NewConfigProvenance()
+	if err := p.SetRaw(ConfigSourceFile, []byte(`{"gmalware_api_url":"https://file","monitoring":{"period":"1h","realtime":{"engine":"ebpf"}},"paths":["/data"]}`)); err != nil {
+		t.Fatalf("SetRaw() error = %v", err)
+	}
+	if err := p.SetRaw(ConfigSourceConsole, []byte(`{"gmalware_api_url":"https://console","monitoring":{"prescan":null},"extra":{"labels":{"env":"prod"}}}`)); err != nil {
+		t.Fatalf("SetRaw() error = %v", err)
+	}
+	p.Set(ConfigSourceEnvironment, "monitoring.realtime", "gmalware_api_token")
+
+	got := p.Sources(&testConfig{})
+	want := map[string]ConfigSource{
+		"gmalware_api_url":              ConfigSourceConsole,
+		"gmalware_api_token":            ConfigSourceEnvironment,
+		"monitoring.period":             ConfigSourceFile,
+		"monitoring.prescan":            ConfigSourceConsole,
+		"monitoring.realtime.engine":    ConfigSourceEnvironment,
+		"monitoring.realtime.enabled":   ConfigSourceEnvironment,
+		"paths":                         ConfigSourceFile,
+		"extra.labels":                  ConfigSourceConsole,
+		"monitoring.modification_delay": ConfigSourceDefault,
+		"debug":                         ConfigSourceDefault,
+	}
+	for path, source := range want {
+		if got[path] != source {
+			t.Errorf("Sources()[%s] = %q, want %q", path, got[path], source)
+		}
+	}
+	if _, ok := got["monitoring"]; ok {
+		t.Errorf("Sources() has struct field monitoring, want only leaf fields")
+	}
+
+	raw := p.Sources(json.RawMessage(`{}`))
+	if raw["monitoring.realtime"] != ConfigSourceEnvironment || raw["paths"] != ConfigSourceFile {
+		t.Errorf("Sources() of raw config = %v, want recorded sources", raw)
+	}
+	if err := p.SetRaw(ConfigSourceConsole, []byte(`["not an object"]`)); err == nil {
+		t.Errorf("SetRaw() of an array, want error")
+	}
+}
+
+func TestConnectorManagerClient_Start_effectiveConfigProvenance(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	acks := make(chan events.TaskEvent, 1)
+	var served atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case basePath + "/register":
+			_, _ = w.Write([]byte(`{"config":{"dummy_string":"console"}}`))
+		case basePath + "/tasks":
+			if served.Swap(true) {
+				_, _ = w.Write([]byte(`{"tasks":[]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"tasks":[{"id":"task-1","action":"get-effective-config"}]}`))
+		case basePath + "/events":
+			envelope := events.Envelope{}
+			_ = json.NewDecoder(r.Body).Decode(&envelope)
+			if ack, err := envelope.Decode(); err == nil {
+				if ack, ok := ack.(events.TaskEvent); ok {
+					acks <- ack
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	c := NewConnectorManagerClient(ctx, ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"})
+	config := &DummyConfig{}
+	if err := c.Register(ctx, "1.0.0", &RegistrationInfo{Config: config}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if config.DummyString != "console" {
+		t.Fatalf("Register() config = %+v, want console config", config)
+	}
+	c.ConfigProvenance().Set(ConfigSourceFile, "password")
+	go c.Start(ctx, &effectiveConfigConnector{config: config})
+
+	select {
+	case ack := <-acks:
+		got := map[string]string{"dummy_string": ack.ConfigProvenance["dummy_string"], "password": ack.ConfigProvenance["password"], "enum": ack.ConfigProvenance["enum"]}
+		if diff := cmp.Diff(got, map[string]string{"dummy_string": "console", "password": "file", "enum": "default"}); diff != "" {
+			t.Errorf("effective config provenance diff(got-want)=%s", diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("task not acked")
+	}
+}
@@ -0,0 +1,65 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// ErrInvalidProxyURL is returned for outbound proxy URLs that are not an http, https or socks5 URL with a host.
+var ErrInvalidProxyURL = errors.New("invalid outbound proxy url")
+
+// OutboundProxy returns OutboundProxyURL with proxy credentials set, nil if no outbound proxy is configured.
+func (c CommonConnectorConfig) OutboundProxy() (proxyURL *url.URL, err error) {
+	if c.OutboundProxyURL == "" {
+		return
+	}
+	proxyURL, err = url.Parse(c.OutboundProxyURL)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidProxyURL, err)
+		return
+	}
+	if !slices.Contains([]string{"http", "https", "socks5"}, proxyURL.Scheme) || proxyURL.Host == "" {
+		err = fmt.Errorf("%w: %s", ErrInvalidProxyURL, proxyURL.Redacted())
+		proxyURL = nil
+		return
+	}
+	if c.ProxyUsername != "" {
+		proxyURL.User = url.UserPassword(c.ProxyUsername, c.ProxyPassword)
+	}
+	return
+}
+
+// SetOutboundProxy makes client reach manager through proxyURL (credentials in its user info), or through proxy
+// of environment (HTTPS_PROXY, see http.ProxyFromEnvironment) if nil. It is set from console config at
+// registration and on config updates (see CommonConnectorConfig.OutboundProxy).
+func (c ConnectorManagerClient) SetOutboundProxy(proxyURL *url.URL) {
+	c.proxy.Store(proxyURL)
+}
+
+// proxyFunc is the http.Transport Proxy of requests to manager.
+func (c ConnectorManagerClient) proxyFunc(req *http.Request) (proxyURL *url.URL, err error) {
+	if proxyURL = c.proxy.Load(); proxyURL != nil {
+		return
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// applyConsoleProxy sets outbound proxy of raw console config, keeping current one if it is invalid.
+func (c ConnectorManagerClient) applyConsoleProxy(raw json.RawMessage) {
+	common := CommonConnectorConfig{}
+	if err := json.Unmarshal(raw, &common); err != nil {
+		logger.Warn("could not read outbound proxy of console config", slog.String("error", err.Error()))
+		return
+	}
+	proxyURL, err := common.OutboundProxy()
+	if err != nil {
+		logger.Warn("could not set outbound proxy of console config", slog.String("error", err.Error()))
+		return
+	}
+	c.SetOutboundProxy(proxyURL)
+}
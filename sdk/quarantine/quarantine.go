@@ -0,0 +1,405 @@
+// Package quarantine stores quarantined files encrypted on local disk, shared by connectors keeping a local
+// quarantine (e.g. host connector). Files are encrypted with AES-256-GCM, by chunks, using a key derived from
+// quarantine password with PBKDF2-SHA256 (FIPS-approved algorithms); their metadata is encrypted as well.
+//
+// Store implements sdk.QuarantineBrowser, so connectors exposing it serve their quarantine on local admin api.
+package quarantine
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+)
+
+const (
+	// files are encrypted by chunks of chunkSize bytes, each chunk being authenticated with its index
+	chunkSize = 64 * 1024
+	// maxSealedChunk bounds sealed chunk length read from disk
+	maxSealedChunk = chunkSize + 64
+
+	saltFile      = ".salt"
+	saltSize      = 16
+	kdfIterations = 600000
+	keySize       = 32
+
+	lockSuffix = ".lock"
+	metaSuffix = ".meta"
+)
+
+var fileMagic = []byte("GLQUARv1")
+
+var (
+	ErrMissingPassword = errors.New("quarantine password is required")
+	ErrCorrupted       = errors.New("quarantined file is corrupted or password is wrong")
+)
+
+var idRegexp = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+var _ sdk.QuarantineBrowser = &Store{}
+
+type Config struct {
+	// Location is quarantine directory, created if needed
+	Location string
+	// Password encrypts quarantined files, it is required to restore them
+	Password string
+}
+
+// Store is a quarantine directory: each item is stored in "<id>.lock" (file content) and "<id>.meta" (item metadata).
+// Store methods are thread-safe.
+type Store struct {
+	dir  string
+	aead cipher.AEAD
+	lock sync.RWMutex
+	now  func() time.Time
+}
+
+// Open opens quarantine at config.Location. Key derivation salt is generated on first open.
+func Open(config Config) (s *Store, err error) {
+	if config.Password == "" {
+		err = ErrMissingPassword
+		return
+	}
+	if err = os.MkdirAll(config.Location, 0o700); err != nil {
+		err = fmt.Errorf("could not create quarantine directory, %w", err)
+		return
+	}
+	salt, err := readSalt(filepath.Join(config.Location, saltFile))
+	if err != nil {
+		return
+	}
+	key, err := pbkdf2.Key(sha256.New, config.Password, salt, kdfIterations, keySize)
+	if err != nil {
+		err = fmt.Errorf("could not derive quarantine key, %w", err)
+		return
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return
+	}
+	aead, err := cipher.NewGCMWithRandomNonce(block)
+	if err != nil {
+		return
+	}
+	s = &Store{dir: config.Location, aead: aead, now: time.Now}
+	return
+}
+
+func readSalt(path string) (salt []byte, err error) {
+	salt, err = os.ReadFile(path) //nolint:gosec // path from connector config
+	switch {
+	case err == nil && len(salt) == saltSize:
+		return
+	case err == nil:
+		err = fmt.Errorf("invalid quarantine salt file %s", path)
+		return
+	case !errors.Is(err, os.ErrNotExist):
+		return
+	}
+	salt = make([]byte, saltSize)
+	if _, err = rand.Read(salt); err != nil {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) //nolint:gosec // path from connector config
+	if errors.Is(err, os.ErrExist) {
+		// created concurrently
+		return readSalt(path)
+	}
+	if err != nil {
+		return
+	}
+	if _, err = f.Write(salt); err != nil {
+		_ = f.Close()
+		return
+	}
+	err = f.Close()
+	return
+}
+
+func newID() (id string, err error) {
+	b := make([]byte, 16)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+	id = hex.EncodeToString(b)
+	return
+}
+
+func (s *Store) path(id string, suffix string) string {
+	return filepath.Join(s.dir, id+suffix)
+}
+
+// chunkAD authenticates chunk position, so chunks cannot be reordered, dropped or moved to another item.
+func chunkAD(id string, index uint64, last bool) []byte {
+	ad := make([]byte, 0, len(id)+9)
+	ad = append(ad, id...)
+	ad = binary.BigEndian.AppendUint64(ad, index)
+	if last {
+		return append(ad, 1)
+	}
+	return append(ad, 0)
+}
+
+// Add quarantines content read from r. item ID, SHA256 and Size are set by Add, Time too if zero.
+func (s *Store) Add(r io.Reader, item sdk.QuarantineItem) (added sdk.QuarantineItem, err error) {
+	if item.ID, err = newID(); err != nil {
+		return
+	}
+	if item.Time == 0 {
+		item.Time = s.now().Unix()
+	}
+	tmp, err := os.CreateTemp(s.dir, ".quarantine-*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	hash := sha256.New()
+	w := bufio.NewWriter(tmp)
+	if _, err = w.Write(fileMagic); err != nil {
+		return
+	}
+	chunk := make([]byte, chunkSize)
+	next := make([]byte, chunkSize)
+	n, err := io.ReadFull(r, chunk)
+	for index := uint64(0); ; index++ {
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return
+		}
+		last := err != nil
+		var nextN int
+		if !last {
+			// a full chunk may be the last one
+			nextN, err = io.ReadFull(r, next)
+			last = errors.Is(err, io.EOF)
+		}
+		hash.Write(chunk[:n])
+		item.Size += int64(n)
+		sealed := s.aead.Seal(nil, nil, chunk[:n], chunkAD(item.ID, index, last))
+		if err = binary.Write(w, binary.BigEndian, uint32(len(sealed))); err != nil { //nolint:gosec // bounded by maxSealedChunk
+			return
+		}
+		if _, err = w.Write(sealed); err != nil {
+			return
+		}
+		if last {
+			err = nil
+			break
+		}
+		chunk, next, n = next, chunk, nextN
+	}
+	if err = w.Flush(); err != nil {
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	item.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err = s.writeMeta(item); err != nil {
+		return
+	}
+	if err = os.Rename(tmp.Name(), s.path(item.ID, lockSuffix)); err != nil {
+		_ = os.Remove(s.path(item.ID, metaSuffix))
+		return
+	}
+	added = item
+	return
+}
+
+func (s *Store) writeMeta(item sdk.QuarantineItem) (err error) {
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return
+	}
+	sealed := s.aead.Seal(nil, nil, raw, []byte(item.ID+metaSuffix))
+	err = os.WriteFile(s.path(item.ID, metaSuffix), sealed, 0o600)
+	return
+}
+
+func (s *Store) readMeta(id string) (item sdk.QuarantineItem, err error) {
+	if !idRegexp.MatchString(id) {
+		err = sdk.ErrQuarantineItemNotFound
+		return
+	}
+	sealed, err := os.ReadFile(s.path(id, metaSuffix))
+	if errors.Is(err, os.ErrNotExist) {
+		err = sdk.ErrQuarantineItemNotFound
+		return
+	}
+	if err != nil {
+		return
+	}
+	raw, err := s.aead.Open(nil, nil, sealed, []byte(id+metaSuffix))
+	if err != nil {
+		err = ErrCorrupted
+		return
+	}
+	err = json.Unmarshal(raw, &item)
+	return
+}
+
+// Extract writes content of quarantined item id to w, e.g. to restore it. Content is verified against item SHA256,
+// w may have received corrupted content when ErrCorrupted is returned.
+func (s *Store) Extract(id string, w io.Writer) (item sdk.QuarantineItem, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if item, err = s.readMeta(id); err != nil {
+		return
+	}
+	f, err := os.Open(s.path(id, lockSuffix))
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	r := bufio.NewReader(f)
+	magic := make([]byte, len(fileMagic))
+	if _, err = io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, fileMagic) {
+		err = ErrCorrupted
+		return
+	}
+	hash := sha256.New()
+	w = io.MultiWriter(w, hash)
+	sealed := make([]byte, 0, maxSealedChunk)
+	for index := uint64(0); ; index++ {
+		var size uint32
+		if err = binary.Read(r, binary.BigEndian, &size); err != nil || size > maxSealedChunk {
+			err = ErrCorrupted
+			return
+		}
+		sealed = sealed[:size]
+		if _, err = io.ReadFull(r, sealed); err != nil {
+			err = ErrCorrupted
+			return
+		}
+		_, peekErr := r.Peek(1)
+		last := errors.Is(peekErr, io.EOF)
+		chunk, openErr := s.aead.Open(nil, nil, sealed, chunkAD(id, index, last))
+		if openErr != nil {
+			err = ErrCorrupted
+			return
+		}
+		if _, err = w.Write(chunk); err != nil {
+			return
+		}
+		if last {
+			break
+		}
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != item.SHA256 {
+		err = ErrCorrupted
+	}
+	return
+}
+
+// Remove deletes quarantined item id, e.g. once restored.
+func (s *Store) Remove(id string) (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, err = s.readMeta(id); err != nil {
+		return
+	}
+	if err = os.Remove(s.path(id, lockSuffix)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	err = os.Remove(s.path(id, metaSuffix))
+	return
+}
+
+// ListQuarantine returns quarantined items, most recent first.
+func (s *Store) ListQuarantine(ctx context.Context) (items []sdk.QuarantineItem, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	items = make([]sdk.QuarantineItem, 0)
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), metaSuffix)
+		if !ok || !idRegexp.MatchString(id) {
+			continue
+		}
+		item, metaErr := s.readMeta(id)
+		if metaErr != nil {
+			err = fmt.Errorf("could not read quarantined item %s, %w", id, metaErr)
+			return
+		}
+		items = append(items, item)
+	}
+	slices.SortFunc(items, func(a, b sdk.QuarantineItem) int {
+		if a.Time != b.Time {
+			return int(b.Time - a.Time)
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return
+}
+
+// InspectQuarantine returns metadata of quarantined item id, sdk.ErrQuarantineItemNotFound if unknown.
+func (s *Store) InspectQuarantine(ctx context.Context, id string) (item sdk.QuarantineItem, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	item, err = s.readMeta(id)
+	return
+}
+
+// ExportQuarantine writes a tar archive of quarantined item id to w, for incident response:
+// "<id>.json" (metadata) then "<sha256>.bin" (decrypted content).
+func (s *Store) ExportQuarantine(ctx context.Context, id string, w io.Writer) (err error) {
+	item, err := s.InspectQuarantine(ctx, id)
+	if err != nil {
+		return
+	}
+	meta, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return
+	}
+	tw := tar.NewWriter(w)
+	modTime := time.Unix(item.Time, 0)
+	err = tw.WriteHeader(&tar.Header{Name: id + ".json", Mode: 0o600, Size: int64(len(meta)), ModTime: modTime, Typeflag: tar.TypeReg})
+	if err != nil {
+		return
+	}
+	if _, err = tw.Write(meta); err != nil {
+		return
+	}
+	err = tw.WriteHeader(&tar.Header{Name: item.SHA256 + ".bin", Mode: 0o400, Size: item.Size, ModTime: modTime, Typeflag: tar.TypeReg})
+	if err != nil {
+		return
+	}
+	if _, err = s.Extract(id, tw); err != nil {
+		return
+	}
+	err = tw.Close()
+	return
+}
@@ -0,0 +1,184 @@
+package quarantine
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(Config{Location: dir, Password: "infected"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	s.now = func() time.Time { return time.Unix(1738000000, 0) }
+
+	tests := []struct {
+		name    string
+		content []byte
+		item    sdk.QuarantineItem
+	}{
+		{name: "eicar", content: []byte(eicar), item: sdk.QuarantineItem{Name: "/data/eicar.com", Malwares: []string{"EICAR"}}},
+		{name: "empty", item: sdk.QuarantineItem{Name: "/data/empty", Time: 1738000100}},
+		{name: "chunk size", content: bytes.Repeat([]byte("a"), chunkSize), item: sdk.QuarantineItem{Name: "/data/a"}},
+		{name: "chunks", content: bytes.Repeat([]byte("0123456789"), chunkSize/4), item: sdk.QuarantineItem{Name: "/data/b"}},
+	}
+	added := make([]sdk.QuarantineItem, 0, len(tests))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, err := s.Add(bytes.NewReader(tt.content), tt.item)
+			if err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+			added = append(added, item)
+			if item.Size != int64(len(tt.content)) || len(item.SHA256) != 64 || item.Time == 0 {
+				t.Errorf("Add() = %+v, want size, sha256 and time set", item)
+			}
+
+			raw, err := os.ReadFile(filepath.Join(dir, item.ID+lockSuffix))
+			if err != nil {
+				t.Fatalf("could not read quarantined file, error: %v", err)
+			}
+			if len(tt.content) > 0 && bytes.Contains(raw, tt.content[:min(len(tt.content), 32)]) {
+				t.Errorf("quarantined file is not encrypted")
+			}
+
+			got, err := s.InspectQuarantine(t.Context(), item.ID)
+			if err != nil {
+				t.Fatalf("InspectQuarantine() error = %v", err)
+			}
+			if diff := cmp.Diff(got, item); diff != "" {
+				t.Errorf("InspectQuarantine() diff(got-want)=%s", diff)
+			}
+			content := bytes.Buffer{}
+			if _, err = s.Extract(item.ID, &content); err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if !bytes.Equal(content.Bytes(), tt.content) {
+				t.Errorf("Extract() content length = %d, want %d", content.Len(), len(tt.content))
+			}
+		})
+	}
+
+	// reopened with same password
+	s, err = Open(Config{Location: dir, Password: "infected"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	items, err := s.ListQuarantine(t.Context())
+	if err != nil {
+		t.Fatalf("ListQuarantine() error = %v", err)
+	}
+	if diff := cmp.Diff(items, added, cmpopts.SortSlices(func(a, b sdk.QuarantineItem) bool { return a.ID < b.ID })); diff != "" {
+		t.Errorf("ListQuarantine() diff(got-want)=%s", diff)
+	}
+	if items[0].Name != "/data/empty" {
+		t.Errorf("ListQuarantine() first = %s, want most recent", items[0].Name)
+	}
+
+	if err = s.Remove(added[0].ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	for _, id := range []string{added[0].ID, "../" + added[1].ID, "unknown"} {
+		if _, err = s.InspectQuarantine(t.Context(), id); !errors.Is(err, sdk.ErrQuarantineItemNotFound) {
+			t.Errorf("InspectQuarantine(%s) error = %v, want ErrQuarantineItemNotFound", id, err)
+		}
+	}
+
+	wrong, err := Open(Config{Location: dir, Password: "other"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err = wrong.InspectQuarantine(t.Context(), added[1].ID); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("InspectQuarantine() with wrong password error = %v, want ErrCorrupted", err)
+	}
+	if _, err = Open(Config{Location: dir}); !errors.Is(err, ErrMissingPassword) {
+		t.Errorf("Open() error = %v, want ErrMissingPassword", err)
+	}
+}
+
+func TestStore_Extract_corrupted(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(Config{Location: dir, Password: "infected"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	content := bytes.Repeat([]byte("abcdefgh"), chunkSize/2)
+	tests := []struct {
+		name   string
+		modify func(raw []byte) []byte
+	}{
+		{name: "flipped", modify: func(raw []byte) []byte { raw[len(raw)/2] ^= 1; return raw }},
+		{name: "truncated", modify: func(raw []byte) []byte { return raw[:len(raw)/2] }},
+		{name: "last chunk dropped", modify: func(raw []byte) []byte { return raw[:len(fileMagic)+4+chunkSize+28] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, err := s.Add(bytes.NewReader(content), sdk.QuarantineItem{Name: tt.name})
+			if err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+			path := filepath.Join(dir, item.ID+lockSuffix)
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("could not read quarantined file, error: %v", err)
+			}
+			if err = os.WriteFile(path, tt.modify(raw), 0o600); err != nil {
+				t.Fatalf("could not write quarantined file, error: %v", err)
+			}
+			if _, err = s.Extract(item.ID, io.Discard); !errors.Is(err, ErrCorrupted) {
+				t.Errorf("Extract() error = %v, want ErrCorrupted", err)
+			}
+		})
+	}
+}
+
+func TestStore_ExportQuarantine(t *testing.T) {
+	s, err := Open(Config{Location: t.TempDir(), Password: "infected"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	item, err := s.Add(strings.NewReader(eicar), sdk.QuarantineItem{Name: "/data/eicar.com", Time: 1738000000})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	archive := bytes.Buffer{}
+	if err = s.ExportQuarantine(t.Context(), item.ID, &archive); err != nil {
+		t.Fatalf("ExportQuarantine() error = %v", err)
+	}
+	got := map[string]string{}
+	tr := tar.NewReader(&archive)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid archive, error: %v", err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("invalid archive, error: %v", err)
+		}
+		got[header.Name] = string(content)
+	}
+	if got[item.SHA256+".bin"] != eicar || !strings.Contains(got[item.ID+".json"], `"name": "/data/eicar.com"`) || len(got) != 2 {
+		t.Errorf("ExportQuarantine() archive = %v", got)
+	}
+	if err = s.ExportQuarantine(t.Context(), "unknown", io.Discard); !errors.Is(err, sdk.ErrQuarantineItemNotFound) {
+		t.Errorf("ExportQuarantine() error = %v, want ErrQuarantineItemNotFound", err)
+	}
+}
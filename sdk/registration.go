@@ -0,0 +1,210 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/state"
+)
+
+const (
+	// DefaultRegistrationBackoff is the delay before first registration retry, when RegistrationOptions.Backoff is 0.
+	DefaultRegistrationBackoff = time.Second
+	// DefaultRegistrationMaxBackoff caps delay between registration retries, when RegistrationOptions.MaxBackoff is 0.
+	DefaultRegistrationMaxBackoff = 5 * time.Minute
+	// cachedConfigKey is the state key last console config is cached under.
+	cachedConfigKey = "console-config"
+)
+
+var ErrNoCachedConfig = errors.New("no cached console config")
+
+// encryptedStore is implemented by state stores reporting whether they encrypt values at rest (e.g. state.FileStore).
+// Console config holds credentials, it is only cached in stores encrypting it.
+type encryptedStore interface {
+	Encrypted() bool
+}
+
+// RegistrationOptions controls registration in Run, when manager is unavailable (e.g. down at startup).
+type RegistrationOptions struct {
+	// Retry retries failed registrations with an exponential backoff until ctx is done, instead of failing Run.
+	// Unauthorized registrations are not retried.
+	Retry bool
+	// Backoff is the delay before first retry, DefaultRegistrationBackoff if 0.
+	Backoff time.Duration
+	// MaxBackoff caps delay between retries, DefaultRegistrationMaxBackoff if 0.
+	MaxBackoff time.Duration
+	// OfflineAfter starts connector from last console config it applied, cached in RunOptions.State when it encrypts
+	// values at rest (e.g. state.FileStore with an encryption key), once registration failed for that long (at first
+	// failure without Retry). Connector then runs in degraded mode: registration is retried in background, and once
+	// manager is back an offline-start error is notified, then resolved when console config is applied.
+	// Connector is never started offline if 0, or without a cached config.
+	OfflineAfter time.Duration
+}
+
+// registerWithRetry registers connector, retrying failed registrations per opts for at most maxElapsed (until ctx
+// is done if 0). Console config is stored and applied only if applyConfig (see registerConnector).
+func (c ConnectorManagerClient) registerWithRetry(ctx context.Context, opts RegistrationOptions, maxElapsed time.Duration, version string, info *RegistrationInfo, applyConfig bool) (err error) {
+	if !opts.Retry {
+		err = c.registerConnector(ctx, version, info, applyConfig)
+		return
+	}
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = opts.Backoff
+	if b.InitialInterval <= 0 {
+		b.InitialInterval = DefaultRegistrationBackoff
+	}
+	b.MaxInterval = opts.MaxBackoff
+	if b.MaxInterval <= 0 {
+		b.MaxInterval = DefaultRegistrationMaxBackoff
+	}
+	_, err = backoff.Retry(
+		ctx,
+		func() (_ struct{}, err error) {
+			err = c.registerConnector(ctx, version, info, applyConfig)
+			if errors.Is(err, ErrUnauthorizedConnector) || errors.Is(err, ErrFIPSModeDisabled) || errors.Is(err, ErrInvalidTLSConfig) {
+				err = backoff.Permanent(err)
+			}
+			return
+		},
+		backoff.WithBackOff(b),
+		backoff.WithMaxElapsedTime(maxElapsed),
+		backoff.WithNotify(func(err error, next time.Duration) {
+			logger.Warn("could not register connector, retry", slog.String("error", err.Error()), slog.String("wait", next.String()))
+		}),
+	)
+	return
+}
+
+// registerForRun registers connector for Run. offline is true if registration failed but connector can start from
+// cached console config, loaded into info.
+func (c ConnectorManagerClient) registerForRun(ctx context.Context, opts RegistrationOptions, version string, info *RegistrationInfo) (offline bool, err error) {
+	if opts.OfflineAfter > 0 && !c.cachesConfig() {
+		logger.Warn("console config is not cached, state store is not set or does not encrypt values at rest")
+	}
+	var maxElapsed time.Duration
+	cached := opts.OfflineAfter > 0 && c.hasCachedConfig()
+	if cached {
+		maxElapsed = opts.OfflineAfter
+	}
+	err = c.registerWithRetry(ctx, opts, maxElapsed, version, info, true)
+	if err == nil || !cached || ctx.Err() != nil || errors.Is(err, ErrUnauthorizedConnector) || errors.Is(err, ErrFIPSModeDisabled) || errors.Is(err, ErrInvalidTLSConfig) {
+		return
+	}
+	if loadErr := c.loadCachedConfig(version, info); loadErr != nil {
+		err = errors.Join(err, loadErr)
+		return
+	}
+	logger.Warn("manager unavailable, start from cached config", slog.String("error", err.Error()))
+	offline = true
+	err = nil
+	return
+}
+
+// reregister registers connector started offline once manager is back, and applies console config on it. Console
+// config is only stored and cached once applied, connector rolling back to cached config it started from otherwise.
+func (c ConnectorManagerClient) reregister(ctx context.Context, opts RegistrationOptions, version string, connector Connector) {
+	opts.Retry = true
+	if c.cachesConfig() {
+		if cached, err := c.store.Get(cachedConfigKey); err == nil {
+			c.storeConfig(json.RawMessage(cached))
+		}
+	}
+	config := new(json.RawMessage)
+	info := &RegistrationInfo{Config: config}
+	if err := c.registerWithRetry(ctx, opts, 0, version, info, false); err != nil {
+		if ctx.Err() == nil {
+			logger.Error("could not register connector started offline", slog.String("error", err.Error()))
+		}
+		return
+	}
+	since := time.Unix(c.offlineSince.Swap(0), 0)
+	logger.Info("connector registered, manager is back", slog.String("offline-for", time.Since(since).Truncate(time.Second).String()))
+	c.storeFeatureFlags(info.FeatureFlags)
+	handler := c.handler.Load()
+	if handler != nil {
+		offlineErr := fmt.Errorf("connector started from cached console config at %s, manager was unavailable", since.UTC().Format(time.RFC3339))
+		if err := handler.NotifyError(ctx, events.OfflineStartError, offlineErr); err != nil {
+			logger.Error("could not notify offline start", slog.String("error", err.Error()))
+		}
+	}
+	if info.Config != nil && len(*config) > 0 && string(*config) != "null" {
+		if err := c.configure(ctx, connector, *config); err != nil {
+			logger.Error("could not apply console config after registration", slog.String("error", err.Error()))
+			return
+		}
+	}
+	if handler != nil {
+		if err := handler.NotifyResolution(ctx, "console config applied", events.OfflineStartError); err != nil {
+			logger.Error("could not notify offline start resolution", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// OfflineSince returns when connector started from cached console config (see RegistrationOptions.OfflineAfter),
+// zero if it did not or once it registered.
+func (c ConnectorManagerClient) OfflineSince() (since time.Time) {
+	if unix := c.offlineSince.Load(); unix != 0 {
+		since = time.Unix(unix, 0)
+	}
+	return
+}
+
+// cachesConfig reports whether console config is cached: store is set and encrypts values at rest.
+func (c ConnectorManagerClient) cachesConfig() bool {
+	encrypted, ok := c.store.(encryptedStore)
+	return ok && encrypted.Encrypted()
+}
+
+// cacheConsoleConfig caches console config applied by connector, for it to start from it when manager is
+// unavailable.
+func (c ConnectorManagerClient) cacheConsoleConfig(config json.RawMessage) {
+	if !c.cachesConfig() {
+		return
+	}
+	if err := c.store.Put(cachedConfigKey, config); err != nil {
+		logger.Warn("could not cache console config", slog.String("error", err.Error()))
+	}
+}
+
+func (c ConnectorManagerClient) hasCachedConfig() bool {
+	if !c.cachesConfig() {
+		return false
+	}
+	_, err := c.store.Get(cachedConfigKey)
+	return err == nil
+}
+
+// loadCachedConfig fills info with cached console config, as registration would.
+func (c ConnectorManagerClient) loadCachedConfig(version string, info *RegistrationInfo) (err error) {
+	if !c.cachesConfig() {
+		err = ErrNoCachedConfig
+		return
+	}
+	config, err := c.store.Get(cachedConfigKey)
+	if errors.Is(err, state.ErrNotFound) {
+		err = ErrNoCachedConfig
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("could not load cached console config, %w", err)
+		return
+	}
+	if info.Config != nil {
+		if err = json.Unmarshal(config, info.Config); err != nil {
+			err = fmt.Errorf("invalid cached console config, %w", err)
+			return
+		}
+		c.storeConfig(info.Config)
+	}
+	c.consoleConfigApplied(config)
+	c.version.Store(&version)
+	c.offlineSince.Store(time.Now().Unix())
+	info.ConnectorID = c.ConnectorID()
+	return
+}
@@ -0,0 +1,276 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/state"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConnectorManagerClient_registerForRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         RegistrationOptions
+		failures     int32 // failed registrations before manager is back, -1 for always
+		status       int
+		cached       string
+		unencrypted  bool
+		wantErr      bool
+		wantAttempts int32
+		wantOffline  bool
+		wantConfig   string
+		wantCached   string
+	}{
+		{
+			name:         "registered",
+			wantAttempts: 1,
+			wantConfig:   "new",
+			wantCached:   `{"dummy_string":"new"}`,
+		},
+		{
+			name:         "no retry",
+			failures:     -1,
+			status:       http.StatusInternalServerError,
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retried",
+			opts:         RegistrationOptions{Retry: true, Backoff: time.Millisecond},
+			failures:     2,
+			status:       http.StatusInternalServerError,
+			cached:       `{"dummy_string":"cached"}`,
+			wantAttempts: 3,
+			wantConfig:   "new",
+			wantCached:   `{"dummy_string":"new"}`,
+		},
+		{
+			name:         "unauthorized not retried",
+			opts:         RegistrationOptions{Retry: true, Backoff: time.Millisecond, OfflineAfter: time.Second},
+			failures:     -1,
+			status:       http.StatusUnauthorized,
+			cached:       `{"dummy_string":"cached"}`,
+			wantErr:      true,
+			wantAttempts: 1,
+			wantCached:   `{"dummy_string":"cached"}`,
+		},
+		{
+			name:         "offline without retry",
+			opts:         RegistrationOptions{OfflineAfter: time.Minute},
+			failures:     -1,
+			status:       http.StatusInternalServerError,
+			cached:       `{"dummy_string":"cached"}`,
+			wantAttempts: 1,
+			wantOffline:  true,
+			wantConfig:   "cached",
+			wantCached:   `{"dummy_string":"cached"}`,
+		},
+		{
+			name:        "offline after retries",
+			opts:        RegistrationOptions{Retry: true, Backoff: time.Millisecond, MaxBackoff: time.Millisecond, OfflineAfter: 50 * time.Millisecond},
+			failures:    -1,
+			status:      http.StatusInternalServerError,
+			cached:      `{"dummy_string":"cached"}`,
+			wantOffline: true,
+			wantConfig:  "cached",
+			wantCached:  `{"dummy_string":"cached"}`,
+		},
+		{
+			name:         "not cached in unencrypted store",
+			opts:         RegistrationOptions{OfflineAfter: time.Minute},
+			failures:     -1,
+			status:       http.StatusInternalServerError,
+			cached:       `{"dummy_string":"cached"}`,
+			unencrypted:  true,
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "not offline without cached config",
+			opts:         RegistrationOptions{OfflineAfter: time.Minute},
+			failures:     -1,
+			status:       http.StatusInternalServerError,
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if n := attempts.Add(1); tt.failures < 0 || n <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				_, _ = w.Write([]byte(`{"config":{"dummy_string":"new"}}`))
+			}))
+			defer server.Close()
+
+			var cipher *state.Cipher
+			if !tt.unencrypted {
+				var err error
+				if cipher, err = state.NewCipher(bytes.Repeat([]byte{1}, state.KeySize)); err != nil {
+					t.Fatalf("NewCipher() error = %v", err)
+				}
+			}
+			dir := t.TempDir()
+			store, err := state.NewFileStore(dir, cipher)
+			if err != nil {
+				t.Fatalf("NewFileStore() error = %v", err)
+			}
+			if tt.cached != "" {
+				if err = store.Put(cachedConfigKey, []byte(tt.cached)); err != nil {
+					t.Fatalf("Put() error = %v", err)
+				}
+			}
+			c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"}, WithStateStore(store))
+			config := &DummyConfig{}
+			offline, err := c.registerForRun(t.Context(), tt.opts, "1.0.0", &RegistrationInfo{Config: config})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("registerForRun() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if offline != tt.wantOffline || c.OfflineSince().IsZero() == tt.wantOffline {
+				t.Errorf("registerForRun() offline = %v, since %s, want %v", offline, c.OfflineSince(), tt.wantOffline)
+			}
+			if got := attempts.Load(); tt.wantAttempts > 0 && got != tt.wantAttempts {
+				t.Errorf("registerForRun() attempts = %d, want %d", got, tt.wantAttempts)
+			}
+			if err != nil {
+				return
+			}
+			if config.DummyString != tt.wantConfig {
+				t.Errorf("registerForRun() config dummy string = %q, want %q", config.DummyString, tt.wantConfig)
+			}
+			cached, err := store.Get(cachedConfigKey)
+			if err != nil || string(cached) != tt.wantCached {
+				t.Errorf("cached config = %s, error = %v, want %s", cached, err, tt.wantCached)
+			}
+			plain, _ := state.NewFileStore(dir, nil)
+			if sealed, _ := plain.Get(cachedConfigKey); !state.IsSealed(sealed) {
+				t.Errorf("cached config is not encrypted: %s", sealed)
+			}
+		})
+	}
+}
+
+func TestConnectorManagerClient_reregister(t *testing.T) {
+	var attempts atomic.Int32
+	var notified []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == basePath+"/events" {
+			envelope := events.Envelope{}
+			if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+				t.Errorf("could not decode event, error: %v", err)
+			}
+			notified = append(notified, string(envelope.EventType))
+			return
+		}
+		if attempts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"config":{"dummy_string":"new"}}`))
+	}))
+	defer server.Close()
+
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"})
+	c.NewConsoleEventHandler(LogLevel, nil)
+	c.offlineSince.Store(time.Now().Unix())
+	connector := &fakeConnector{}
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	c.reregister(ctx, RegistrationOptions{Backoff: time.Millisecond}, "1.0.0", connector)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Fatalf("reregister() did not register")
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("reregister() attempts = %d, want 3", got)
+	}
+	if diff := cmp.Diff(connector.configs, []string{`{"dummy_string":"new"}`}); diff != "" {
+		t.Errorf("reregister() configs diff(got-want)=%s", diff)
+	}
+	if diff := cmp.Diff(notified, []string{string(events.Error), string(events.Resolution)}); diff != "" {
+		t.Errorf("reregister() events diff(got-want)=%s", diff)
+	}
+	if !c.OfflineSince().IsZero() {
+		t.Errorf("reregister() OfflineSince() = %s, want zero", c.OfflineSince())
+	}
+}
+
+func TestConnectorManagerClient_reregister_applyFailed(t *testing.T) {
+	const (
+		cachedConfig = `{"dummy_string":"cached"}`
+		newConfig    = `{"dummy_string":"new"}`
+	)
+	var notified []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == basePath+"/events" {
+			envelope := events.Envelope{}
+			if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+				t.Errorf("could not decode event, error: %v", err)
+			}
+			notified = append(notified, string(envelope.EventType))
+			return
+		}
+		_, _ = w.Write([]byte(`{"config":` + newConfig + `}`))
+	}))
+	defer server.Close()
+
+	cipher, err := state.NewCipher(bytes.Repeat([]byte{1}, state.KeySize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	store, err := state.NewFileStore(t.TempDir(), cipher)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	if err = store.Put(cachedConfigKey, []byte(cachedConfig)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"}, WithStateStore(store))
+	c.NewConsoleEventHandler(LogLevel, nil)
+	c.offlineSince.Store(time.Now().Unix())
+	connector := &transactionalConnector{failOn: map[string]bool{newConfig: true}}
+	c.reregister(t.Context(), RegistrationOptions{Backoff: time.Millisecond}, "1.0.0", connector)
+	if diff := cmp.Diff(connector.applied, []string{newConfig, cachedConfig}); diff != "" {
+		t.Errorf("reregister() applied diff(got-want)=%s", diff)
+	}
+	if last := c.lastConfig.Load(); last == nil || string(*last) != cachedConfig {
+		t.Errorf("reregister() last config = %v, want %s", last, cachedConfig)
+	}
+	cached, err := store.Get(cachedConfigKey)
+	if err != nil || string(cached) != cachedConfig {
+		t.Errorf("cached config = %s, error = %v, want %s", cached, err, cachedConfig)
+	}
+	if diff := cmp.Diff(notified, []string{string(events.Error), string(events.Error)}); diff != "" {
+		t.Errorf("reregister() events diff(got-want)=%s", diff)
+	}
+}
+
+func TestConnectorManagerClient_configure_cached(t *testing.T) {
+	cipher, err := state.NewCipher(bytes.Repeat([]byte{1}, state.KeySize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	store, err := state.NewFileStore(t.TempDir(), cipher)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: "http://manager", APIKey: "key"}, WithStateStore(store))
+	if err = c.configure(t.Context(), &fakeConnector{}, json.RawMessage(`{"dummy_string":"reconfigured"}`)); err != nil {
+		t.Fatalf("configure() error = %v", err)
+	}
+	cached, err := store.Get(cachedConfigKey)
+	if err != nil || string(cached) != `{"dummy_string":"reconfigured"}` {
+		t.Errorf("cached config = %s, error = %v, want reconfigured one", cached, err)
+	}
+}
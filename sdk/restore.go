@@ -0,0 +1,60 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+var ErrInvalidRestore = errors.New("invalid restore request")
+
+// restore restores element of content (RestoreActionContent), for restore tasks and admin api restore requests. It
+// returns an ErrInvalidRestore error if content is invalid or does not give id of the element to restore.
+func restore(ctx context.Context, connector Connector, content json.RawMessage) (id string, err error) {
+	restoreAction := RestoreActionContent{}
+	if err = json.Unmarshal(content, &restoreAction); err != nil {
+		err = fmt.Errorf("%w, error: %w", ErrInvalidRestore, err)
+		return
+	}
+	if restoreAction.ID == "" {
+		err = fmt.Errorf("%w, the id of the element to restore is not provided", ErrInvalidRestore)
+		return
+	}
+	id = restoreAction.ID
+	if err = connector.Restore(ctx, restoreAction); err != nil {
+		err = fmt.Errorf("error restoring element %s, error: %w", id, err)
+	}
+	return
+}
+
+// notifyAdminRestore notifies console, as a log event, of element id restored from admin api or of restoreErr, for
+// restores that do not come from a console task to be audited too.
+func (c ConnectorManagerClient) notifyAdminRestore(ctx context.Context, id string, restoreErr error) {
+	level, message := slog.LevelInfo, "element restored from admin api"
+	attrs := []slog.Attr{slog.String("id", id)}
+	if restoreErr != nil {
+		level, message = slog.LevelError, "could not restore element from admin api"
+		attrs = append(attrs, slog.String("error", restoreErr.Error()))
+	}
+	var err error
+	if h := c.handler.Load(); h != nil {
+		record := slog.NewRecord(time.Now(), level, message, 0)
+		record.AddAttrs(attrs...)
+		err = h.GetLogHandler().Handle(ctx, record)
+	} else {
+		log := events.LogEvent{Level: "info", Message: message, Time: time.Now().Unix(), Attributes: map[string]any{"id": id}}
+		if restoreErr != nil {
+			log.Level = "error"
+			log.Attributes["error"] = restoreErr.Error()
+		}
+		err = c.Notify(ctx, log)
+	}
+	if err != nil {
+		logger.Error("could not notify admin restore", slog.String("id", id), slog.String("error", err.Error()))
+	}
+}
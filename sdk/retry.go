@@ -0,0 +1,70 @@
+package sdk
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+const (
+	// DefaultRetryMaxElapsed bounds retries of a request to manager, when ConnectorManagerClientConfig.RetryMaxElapsed
+	// is 0.
+	DefaultRetryMaxElapsed = 3 * time.Second
+	// DefaultRetryInitialInterval is the delay before first retry of a request, when
+	// ConnectorManagerClientConfig.RetryInitialInterval is 0.
+	DefaultRetryInitialInterval = backoff.DefaultInitialInterval
+)
+
+// DefaultRetryStatusCodes are manager response statuses retried, when ConnectorManagerClientConfig.RetryStatusCodes
+// is empty.
+var DefaultRetryStatusCodes = []int{http.StatusBadGateway}
+
+// retryPolicy retries failed requests to manager (no response, or a retried status) with an exponential backoff.
+type retryPolicy struct {
+	maxElapsed      time.Duration
+	maxRetries      int // 0 for no limit but maxElapsed
+	statusCodes     []int
+	initialInterval time.Duration
+}
+
+func newRetryPolicy(config ConnectorManagerClientConfig) (p retryPolicy) {
+	p = retryPolicy{
+		maxElapsed:      config.RetryMaxElapsed,
+		maxRetries:      config.RetryMaxRetries,
+		statusCodes:     config.RetryStatusCodes,
+		initialInterval: config.RetryInitialInterval,
+	}
+	if p.maxElapsed <= 0 {
+		p.maxElapsed = DefaultRetryMaxElapsed
+	}
+	if p.initialInterval <= 0 {
+		p.initialInterval = DefaultRetryInitialInterval
+	}
+	if len(p.statusCodes) == 0 {
+		p.statusCodes = DefaultRetryStatusCodes
+	}
+	return
+}
+
+// options returns backoff.Retry options of policy.
+func (p retryPolicy) options() (opts []backoff.RetryOption) {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = p.initialInterval
+	opts = []backoff.RetryOption{backoff.WithBackOff(b), backoff.WithMaxElapsedTime(p.maxElapsed)}
+	if p.maxRetries > 0 {
+		// tries include first one
+		opts = append(opts, backoff.WithMaxTries(uint(p.maxRetries)+1)) //nolint:gosec // maxRetries is positive
+	}
+	return
+}
+
+// retried returns the error of a response whose status is retried, nil otherwise.
+func (p retryPolicy) retried(resp *http.Response) (err error) {
+	if slices.Contains(p.statusCodes, resp.StatusCode) {
+		err = fmt.Errorf("retried status %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return
+}
@@ -0,0 +1,72 @@
+package sdk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConnectorManagerClient_retryDo(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    ConnectorManagerClientConfig
+		statuses  []int
+		wantErr   bool
+		wantCalls int
+	}{
+		{name: "default retried status", statuses: []int{http.StatusBadGateway, http.StatusOK}, wantCalls: 2},
+		{name: "default not retried status", statuses: []int{http.StatusServiceUnavailable}, wantCalls: 1},
+		{
+			name:      "retried status codes",
+			config:    ConnectorManagerClientConfig{RetryStatusCodes: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}},
+			statuses:  []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			wantCalls: 3,
+		},
+		{
+			name:      "max retries",
+			config:    ConnectorManagerClientConfig{RetryMaxRetries: 1},
+			statuses:  []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			wantErr:   true,
+			wantCalls: 2,
+		},
+		{
+			name:      "max elapsed",
+			config:    ConnectorManagerClientConfig{RetryMaxElapsed: time.Millisecond, RetryInitialInterval: 10 * time.Millisecond},
+			statuses:  []int{http.StatusBadGateway, http.StatusOK},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statuses[min(calls, len(tt.statuses)-1)])
+				calls++
+			}))
+			defer server.Close()
+
+			if tt.config.RetryInitialInterval == 0 {
+				tt.config.RetryInitialInterval = time.Millisecond
+			}
+			tt.config.URL = server.URL
+			tt.config.APIKey = "key"
+			c := NewConnectorManagerClient(t.Context(), tt.config)
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.retryDo(req, "tasks")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("retryDo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if resp != nil {
+				_ = resp.Body.Close()
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("retryDo() calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
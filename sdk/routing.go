@@ -0,0 +1,135 @@
+package sdk
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/glimps-re/connector-integration/sdk/validation"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// GMalwareEngine is the GLIMPS Malware analysis items are submitted to.
+type GMalwareEngine string
+
+const (
+	// classic detect analysis
+	GMalwareDetect GMalwareEngine = "detect"
+	// syndetect analysis
+	GMalwareSyndetect GMalwareEngine = "syndetect"
+)
+
+func (GMalwareEngine) Values() []GMalwareEngine {
+	return []GMalwareEngine{GMalwareDetect, GMalwareSyndetect}
+}
+
+// GMalwareEngineTag is the validator tag validating a GMalwareEngine.
+const GMalwareEngineTag = "gmalware_engine"
+
+func (GMalwareEngine) Validation() validation.EnumValidation {
+	return validation.NewEnumValidation(GMalwareEngine("").Values())
+}
+
+// GMalwareRouting decides per item whether it is submitted to detect or syndetect.
+type GMalwareRouting struct {
+	Default GMalwareEngine        `json:"default" yaml:"default" mapstructure:"default" validate:"omitempty,gmalware_engine" desc:"Engine of items matching no rule (default: detect, or syndetect with gmalware_syndetect)"`
+	Rules   []GMalwareRoutingRule `json:"rules" yaml:"rules" mapstructure:"rules" validate:"omitempty,dive" desc:"Routing rules, the first rule matching an item decides its engine" default:"[]"`
+}
+
+// GMalwareRoutingRule routes items matching all its set criteria to Engine, a rule without criteria matching every item.
+type GMalwareRoutingRule struct {
+	Engine        GMalwareEngine `json:"engine" yaml:"engine" mapstructure:"engine" validate:"required,gmalware_engine" desc:"Engine items matching rule are submitted to"`
+	Extensions    []string       `json:"extensions" yaml:"extensions" mapstructure:"extensions" desc:"Match files with one of these extensions, case insensitive (e.g. .docm, .exe)" default:"[]"`
+	MinSize       string         `json:"min_size" yaml:"min_size" mapstructure:"min_size" validate:"omitempty,filesize" desc:"Match files of at least this size (e.g. 10MB), files of unknown size never match"`
+	MaxSize       string         `json:"max_size" yaml:"max_size" mapstructure:"max_size" validate:"omitempty,filesize" desc:"Match files of at most this size (e.g. 100MiB), files of unknown size never match"`
+	Paths         []string       `json:"paths" yaml:"paths" mapstructure:"paths" desc:"Match items under one of these monitored paths (e.g. /data/finance)" default:"[]"`
+	Sites         []string       `json:"sites" yaml:"sites" mapstructure:"sites" desc:"Match items of one of these sites (e.g. https://myTenant.sharepoint.com/sites/finance)" default:"[]"`
+	SenderDomains []string       `json:"sender_domains" yaml:"sender_domains" mapstructure:"sender_domains" desc:"Match emails sent from one of these domains, subdomains included (e.g. example.com)" default:"[]"`
+}
+
+// RoutingSizeRangeTag is the struct-level validation tag of routing rules whose min_size exceeds max_size.
+const RoutingSizeRangeTag = "routing_size_range"
+
+// ValidateGMalwareRoutingRule checks min_size of rule does not exceed its max_size, when both are set.
+func ValidateGMalwareRoutingRule(sl validator.StructLevel) {
+	rule, ok := sl.Current().Interface().(GMalwareRoutingRule)
+	if !ok || rule.MinSize == "" || rule.MaxSize == "" {
+		return
+	}
+	minSize, minErr := ParseSize(rule.MinSize)
+	maxSize, maxErr := ParseSize(rule.MaxSize)
+	// invalid sizes are reported by filesize tag
+	if minErr != nil || maxErr != nil {
+		return
+	}
+	if minSize > maxSize {
+		sl.ReportError(rule.MinSize, "min_size", "MinSize", RoutingSizeRangeTag, "")
+	}
+}
+
+func registerRoutingValidation(validate *validator.Validate, trans ut.Translator) (err error) {
+	validate.RegisterStructValidation(ValidateGMalwareRoutingRule, GMalwareRoutingRule{})
+	err = registerTranslations(validate, trans, map[string]string{
+		RoutingSizeRangeTag: "{0} must be lower than or equal to max_size",
+	})
+	return
+}
+
+func (r GMalwareRoutingRule) matches(item GMalwareItem) bool {
+	if len(r.Extensions) > 0 {
+		ext := strings.ToLower(itemExtension(item))
+		if ext == "" || !slices.ContainsFunc(r.Extensions, func(e string) bool {
+			return strings.ToLower(strings.TrimPrefix(e, ".")) == ext
+		}) {
+			return false
+		}
+	}
+	if r.MinSize != "" {
+		minSize, err := ParseSize(r.MinSize)
+		if err != nil || item.Size <= 0 || item.Size < minSize {
+			return false
+		}
+	}
+	if r.MaxSize != "" {
+		maxSize, err := ParseSize(r.MaxSize)
+		if err != nil || item.Size <= 0 || item.Size > maxSize {
+			return false
+		}
+	}
+	if len(r.Paths) > 0 || len(r.Sites) > 0 || len(r.SenderDomains) > 0 {
+		return matchesSource(item, r.Paths, r.Sites, r.SenderDomains)
+	}
+	return true
+}
+
+// itemExtension returns extension of item file name, without dot.
+func itemExtension(item GMalwareItem) string {
+	name := item.Filename
+	if name == "" {
+		name = item.Path
+	}
+	// Windows paths
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimPrefix(path.Ext(name), ".")
+}
+
+// SelectGMalwareEngine returns the engine of first routing rule (in config order) matching item, routing default
+// if none matches.
+func (c CommonConnectorConfig) SelectGMalwareEngine(item GMalwareItem) (engine GMalwareEngine) {
+	for _, r := range c.GMalwareRouting.Rules {
+		if r.matches(item) {
+			return r.Engine
+		}
+	}
+	switch {
+	case c.GMalwareRouting.Default != "":
+		return c.GMalwareRouting.Default
+	case c.GMalwareSyndetect:
+		return GMalwareSyndetect
+	default:
+		return GMalwareDetect
+	}
+}
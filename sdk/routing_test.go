@@ -0,0 +1,104 @@
+package sdk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCommonConnectorConfig_SelectGMalwareEngine(t *testing.T) {
+	routing := GMalwareRouting{Rules: []GMalwareRoutingRule{
+		{Engine: GMalwareSyndetect, Extensions: []string{".docm", "XLSM"}},
+		{Engine: GMalwareDetect, MinSize: "10MB"},
+		{Engine: GMalwareSyndetect, MaxSize: "1KiB", Sites: []string{"https://tenant.sharepoint.com/sites/finance"}},
+		{Engine: GMalwareSyndetect, Paths: []string{"/data/untrusted"}, SenderDomains: []string{"example.com"}},
+	}}
+	tests := []struct {
+		name   string
+		config CommonConnectorConfig
+		item   GMalwareItem
+		want   GMalwareEngine
+	}{
+		{name: "no routing", item: GMalwareItem{Filename: "a.docm"}, want: GMalwareDetect},
+		{name: "deprecated syndetect", config: CommonConnectorConfig{GMalwareSyndetect: true}, want: GMalwareSyndetect},
+		{
+			name:   "routing default",
+			config: CommonConnectorConfig{GMalwareSyndetect: true, GMalwareRouting: GMalwareRouting{Default: GMalwareDetect}},
+			want:   GMalwareDetect,
+		},
+		{name: "extension", config: CommonConnectorConfig{GMalwareRouting: routing}, item: GMalwareItem{Filename: "report.DOCM", Size: 20_000_000}, want: GMalwareSyndetect},
+		{name: "path extension", config: CommonConnectorConfig{GMalwareRouting: routing}, item: GMalwareItem{Path: `C:\data.d\book.xlsm`}, want: GMalwareSyndetect},
+		{
+			name:   "min size",
+			config: CommonConnectorConfig{GMalwareSyndetect: true, GMalwareRouting: routing},
+			item:   GMalwareItem{Path: "/data/untrusted/big.bin", Size: 10_000_000},
+			want:   GMalwareDetect,
+		},
+		{
+			name:   "size and source",
+			config: CommonConnectorConfig{GMalwareRouting: routing},
+			item:   GMalwareItem{Site: "https://tenant.sharepoint.com/sites/finance/", Size: 1024},
+			want:   GMalwareSyndetect,
+		},
+		{
+			name:   "unknown size",
+			config: CommonConnectorConfig{GMalwareRouting: routing},
+			item:   GMalwareItem{Site: "https://tenant.sharepoint.com/sites/finance"},
+			want:   GMalwareDetect,
+		},
+		{name: "sender", config: CommonConnectorConfig{GMalwareRouting: routing}, item: GMalwareItem{Sender: "a@mail.example.com"}, want: GMalwareSyndetect},
+		{name: "no match", config: CommonConnectorConfig{GMalwareRouting: routing}, item: GMalwareItem{Path: "/data/a.exe", Size: 2048}, want: GMalwareDetect},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.SelectGMalwareEngine(tt.item); got != tt.want {
+				t.Errorf("SelectGMalwareEngine() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGMalwareRouting_validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		routing GMalwareRouting
+		want    []string
+	}{
+		{name: "ok", routing: GMalwareRouting{Default: GMalwareSyndetect, Rules: []GMalwareRoutingRule{{Engine: GMalwareDetect, MinSize: "5MiB", MaxSize: "1 GB"}}}},
+		{name: "invalid default", routing: GMalwareRouting{Default: "lite"}, want: []string{"default must be one of [detect syndetect]"}},
+		{name: "missing engine", routing: GMalwareRouting{Rules: []GMalwareRoutingRule{{}}}, want: []string{"engine is a required field"}},
+		{
+			name:    "invalid size",
+			routing: GMalwareRouting{Rules: []GMalwareRoutingRule{{Engine: GMalwareDetect, MaxSize: "big"}}},
+			want:    []string{"max_size must be a size, e.g. 100MB or 5MiB"},
+		},
+		{
+			name:    "min size exceeds max size",
+			routing: GMalwareRouting{Rules: []GMalwareRoutingRule{{Engine: GMalwareDetect, MinSize: "1GB", MaxSize: "100MB"}}},
+			want:    []string{"min_size must be lower than or equal to max_size"},
+		},
+		{name: "equal sizes", routing: GMalwareRouting{Rules: []GMalwareRoutingRule{{Engine: GMalwareDetect, MinSize: "1MiB", MaxSize: "1024KiB"}}}},
+	}
+	v, err := DefaultValidator()
+	if err != nil {
+		t.Fatalf("DefaultValidator() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			var fieldErrs validator.ValidationErrors
+			if err := v.Validate(tt.routing); errors.As(err, &fieldErrs) {
+				for _, fe := range fieldErrs {
+					got = append(got, fe.Translate(v.Trans))
+				}
+			} else if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Validate() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/secrets"
+	"github.com/glimps-re/connector-integration/sdk/state"
+)
+
+var ErrMissingAPIKey = errors.New("console api key is not set (neither in client config nor in secrets store)")
+
+type RunOptions struct {
+	Client  ConnectorManagerClientConfig
+	Version string
+	// Config is a pointer to connector config (e.g. *HostConfig), filled with console config at registration.
+	Config any
+	// ConfigProvenance optionally records sources of Config fields set before Run (e.g. ConfigSourceEnvironment,
+	// ConfigSourceFile), reported with effective config along with console ones.
+	ConfigProvenance *ConfigProvenance
+	// Secrets optionally stores credentials (e.g. secrets.NewKeyring("glimps-connector-host")):
+	// console api key is read from it if not set in client config, and stored in it otherwise,
+	// so it can be removed from environment or config files after first start.
+	// GLIMPS Malware token is read from it if console config (at registration or on config updates) does not set
+	// it, and stored in it otherwise.
+	Secrets secrets.Store
+	// State optionally persists connector ID, so console links connector history across restarts and api key
+	// rotations (see WithStateStore), and caches console config for Registration.OfflineAfter.
+	State state.Store
+	// Registration retries registration when manager is unavailable, and optionally starts connector from cached
+	// console config meanwhile.
+	Registration RegistrationOptions
+	// Debug starts a debug server on localhost (see ConnectorManagerClient.DebugHandler), to profile connectors in the field.
+	// It is also started if console config enables debug (see CommonConnectorConfig.Debug) at registration.
+	Debug DebugOptions
+	// Admin starts an admin api on localhost (see ConnectorManagerClient.AdminHandler), for on-host operators.
+	Admin AdminOptions
+	// Spool spools on disk events that could not be notified (console unreachable), replayed in order once it is
+	// back. Disabled if Spool.Dir is empty.
+	Spool events.SpoolOptions
+	// Health serves /healthz, /readyz, and /metrics on its own listener (see ConnectorManagerClient.ServeHealth) from
+	// start, for orchestrator probes, load balancers and Prometheus scrapes.
+	Health HealthOptions
+	// NewConnector builds connector once registered.
+	NewConnector func(ctx context.Context, run RunInfo) (connector Connector, err error)
+}
+
+// RunInfo is given to RunOptions.NewConnector, once connector is registered.
+type RunInfo struct {
+	Client       ConnectorManagerClient
+	Registration RegistrationInfo
+	EventHandler *events.Handler
+	// Secrets is RunOptions.Secrets, e.g. to fill GLIMPS Malware token of reconfigurations
+	Secrets secrets.Store
+}
+
+// commonConfig is implemented by connector configs embedding CommonConnectorConfig.
+type commonConfig interface {
+	commonConnectorConfig() *CommonConnectorConfig
+}
+
+func (c *CommonConnectorConfig) commonConnectorConfig() *CommonConnectorConfig {
+	return c
+}
+
+// Run registers connector on console, builds it and handles console tasks until ctx is done.
+func Run(ctx context.Context, opts RunOptions) (err error) {
+	if opts.NewConnector == nil {
+		err = errors.New("RunOptions.NewConnector is required")
+		return
+	}
+	if opts.Client.APIKey, err = resolveAPIKey(opts.Client.APIKey, opts.Secrets); err != nil {
+		return
+	}
+	var clientOpts []ClientOption
+	if opts.State != nil {
+		clientOpts = append(clientOpts, WithStateStore(opts.State))
+	}
+	if opts.Secrets != nil {
+		clientOpts = append(clientOpts, WithSecrets(opts.Secrets))
+	}
+	client := NewConnectorManagerClient(ctx, opts.Client, clientOpts...)
+	if opts.ConfigProvenance != nil {
+		client.ConfigProvenance().Merge(opts.ConfigProvenance)
+	}
+	var spool *events.Spool
+	if opts.Spool.Dir != "" {
+		if spool, err = events.NewSpool(client, opts.Spool); err != nil {
+			return
+		}
+		client.SetEventSpool(spool)
+	}
+	built := &atomic.Pointer[Connector]{}
+	if opts.Health.Enabled {
+		if err = client.ServeHealth(ctx, opts.Health, builtConnector(built)); err != nil {
+			return
+		}
+	}
+	info := RegistrationInfo{Config: opts.Config}
+	offline, err := client.registerForRun(ctx, opts.Registration, opts.Version, &info)
+	if err != nil {
+		err = fmt.Errorf("could not register connector, %w", err)
+		return
+	}
+	if err = resolveGMalwareToken(opts.Config, opts.Secrets, client.ConfigProvenance()); err != nil {
+		return
+	}
+	if spool != nil {
+		// events spooled by previous run are replayed once registered
+		go spool.Run(ctx)
+	}
+	run := RunInfo{
+		Client:       client,
+		Registration: info,
+		EventHandler: client.NewConsoleEventHandler(LogLevel, info.UnresolvedErrors),
+		Secrets:      opts.Secrets,
+	}
+	if common, ok := opts.Config.(commonConfig); ok {
+		if err = run.EventHandler.SetPrivacy(common.commonConnectorConfig().Privacy); err != nil {
+			err = fmt.Errorf("invalid privacy config, %w", err)
+			return
+		}
+		if err = run.EventHandler.SetFieldEncryption(common.commonConnectorConfig().FieldEncryption); err != nil {
+			err = fmt.Errorf("invalid field encryption config, %w", err)
+			return
+		}
+	}
+	connector, err := opts.NewConnector(ctx, run)
+	if err != nil {
+		err = fmt.Errorf("could not create connector, %w", err)
+		return
+	}
+	built.Store(&connector)
+	if offline {
+		go client.reregister(ctx, opts.Registration, opts.Version, connector)
+	}
+	config := func() any {
+		if effective, effectiveErr := effectiveConfig(connector); effectiveErr == nil {
+			return effective
+		}
+		return opts.Config
+	}
+	if opts.Debug.Enabled || debugEnabled(opts.Config) {
+		if err = client.serveDebug(ctx, opts.Debug, config); err != nil {
+			return
+		}
+	}
+	if opts.Admin.Enabled {
+		if err = client.serveAdmin(ctx, opts.Admin, connector, config); err != nil {
+			return
+		}
+	}
+	client.Start(ctx, connector)
+	return
+}
+
+// debugEnabled returns whether config, filled with console config, enables debug.
+func debugEnabled(config any) bool {
+	common, ok := config.(commonConfig)
+	return ok && common.commonConnectorConfig().Debug
+}
+
+func resolveAPIKey(apiKey string, store secrets.Store) (resolved string, err error) {
+	resolved = apiKey
+	switch {
+	case store == nil && apiKey == "":
+		err = ErrMissingAPIKey
+	case store == nil:
+	case apiKey != "":
+		if storeErr := store.Set(secrets.ConsoleAPIKey, apiKey); storeErr != nil {
+			logger.Warn("could not store console api key", slog.String("error", storeErr.Error()))
+		}
+	default:
+		resolved, err = store.Get(secrets.ConsoleAPIKey)
+		if errors.Is(err, secrets.ErrNotFound) {
+			err = ErrMissingAPIKey
+		}
+	}
+	return
+}
+
+// resolveGMalwareToken sets GLIMPS Malware token of config from store, if config does not set it, and stores it
+// otherwise.
+func resolveGMalwareToken(config any, store secrets.Store, provenance *ConfigProvenance) (err error) {
+	common, ok := config.(commonConfig)
+	if !ok || store == nil {
+		return
+	}
+	if token := common.commonConnectorConfig().GMalwareAPIToken; token != "" {
+		storeGMalwareToken(store, token)
+		return
+	}
+	token, found, err := readGMalwareToken(store)
+	if err != nil || !found {
+		return
+	}
+	common.commonConnectorConfig().GMalwareAPIToken = token
+	provenance.Set(ConfigSourceSecrets, "gmalware_api_token")
+	return
+}
+
+// readGMalwareToken returns GLIMPS Malware token of store, found being false if store does not hold one.
+func readGMalwareToken(store secrets.Store) (token string, found bool, err error) {
+	token, err = store.Get(secrets.GMalwareAPIToken)
+	if errors.Is(err, secrets.ErrNotFound) {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("could not read GLIMPS Malware token, %w", err)
+		return
+	}
+	found = true
+	return
+}
+
+// storeGMalwareToken stores token set by console config, for configs not setting it afterwards.
+func storeGMalwareToken(store secrets.Store, token string) {
+	if err := store.Set(secrets.GMalwareAPIToken, token); err != nil {
+		logger.Warn("could not store GLIMPS Malware token", slog.String("error", err.Error()))
+	}
+}
+
+// WithSecrets makes client keep GLIMPS Malware token of console config in store (see RunOptions.Secrets): config
+// updates not setting it are applied with the stored one, rather than blanking it.
+func WithSecrets(store secrets.Store) ClientOption {
+	return func(o *clientOptions) {
+		o.secrets = store
+	}
+}
+
+// withGMalwareToken returns console config applied by connector: config with GLIMPS Malware token of secrets store
+// (fromStore), if config does not set it, token being the one config sets otherwise. Config is returned as is
+// without secrets store, or if it is not an object.
+func (c ConnectorManagerClient) withGMalwareToken(config json.RawMessage) (applied json.RawMessage, token string, fromStore bool, err error) {
+	applied = config
+	if c.secrets == nil {
+		return
+	}
+	fields := map[string]json.RawMessage{}
+	if json.Unmarshal(config, &fields) != nil {
+		return
+	}
+	if raw, ok := fields["gmalware_api_token"]; ok {
+		// null token is not set
+		_ = json.Unmarshal(raw, &token)
+	}
+	if token != "" {
+		return
+	}
+	token, fromStore, err = readGMalwareToken(c.secrets)
+	if err != nil || !fromStore {
+		return
+	}
+	if fields["gmalware_api_token"], err = json.Marshal(token); err != nil {
+		return
+	}
+	applied, err = json.Marshal(fields)
+	return
+}
@@ -0,0 +1,209 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/secrets"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		apiKey      string
+		secrets     map[string]string
+		config      string
+		noStore     bool
+		privacy     events.Privacy
+		encryption  events.FieldEncryption
+		wantErr     error
+		wantAuth    string
+		wantToken   string
+		wantSecrets map[string]string
+	}{
+		{
+			name:        "api key stored",
+			apiKey:      "key",
+			wantAuth:    "ApiKey key",
+			wantSecrets: map[string]string{secrets.ConsoleAPIKey: "key"},
+		},
+		{
+			name:        "secrets read",
+			secrets:     map[string]string{secrets.ConsoleAPIKey: "stored-key", secrets.GMalwareAPIToken: "token"},
+			wantAuth:    "ApiKey stored-key",
+			wantToken:   "token",
+			wantSecrets: map[string]string{secrets.ConsoleAPIKey: "stored-key", secrets.GMalwareAPIToken: "token"},
+		},
+		{
+			name:        "token of console config stored",
+			apiKey:      "key",
+			secrets:     map[string]string{secrets.GMalwareAPIToken: "stored-token"},
+			config:      `{"dummy_string":"new","gmalware_api_token":"token"}`,
+			wantAuth:    "ApiKey key",
+			wantToken:   "token",
+			wantSecrets: map[string]string{secrets.ConsoleAPIKey: "key", secrets.GMalwareAPIToken: "token"},
+		},
+		{
+			name:    "missing api key",
+			wantErr: ErrMissingAPIKey,
+		},
+		{
+			name:    "invalid privacy",
+			apiKey:  "key",
+			privacy: events.Privacy{Owner: events.PIIHash},
+			wantErr: events.ErrMissingHashKey,
+		},
+		{
+			name:       "invalid field encryption",
+			apiKey:     "key",
+			encryption: events.FieldEncryption{Fields: []events.EncryptedField{events.EncryptSubject}, KeyID: "1", Key: "a2V5"},
+			wantErr:    events.ErrInvalidEncryptionKey,
+		},
+		{
+			name:     "no store",
+			apiKey:   "key",
+			noStore:  true,
+			wantAuth: "ApiKey key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &fakeManager{config: tt.config}
+			server := httptest.NewServer(manager.handler(t))
+			defer server.Close()
+			store := secrets.NewMemoryStore()
+			for name, secret := range tt.secrets {
+				if err := store.Set(name, secret); err != nil {
+					t.Fatal(err)
+				}
+			}
+			provenance := NewConfigProvenance()
+			provenance.Set(ConfigSourceEnvironment, "gmalware_api_url", "dummy_string")
+			config := &DummyConfig{}
+			config.Privacy = tt.privacy
+			config.FieldEncryption = tt.encryption
+			opts := RunOptions{
+				Client:           ConnectorManagerClientConfig{URL: server.URL, APIKey: tt.apiKey},
+				Version:          "1.0.0",
+				Config:           config,
+				ConfigProvenance: provenance,
+				Secrets:          store,
+			}
+			if tt.noStore {
+				opts.Secrets = nil
+			}
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			var run RunInfo
+			opts.NewConnector = func(ctx context.Context, info RunInfo) (connector Connector, err error) {
+				run = info
+				cancel()
+				connector = &fakeConnector{}
+				return
+			}
+
+			err := Run(ctx, opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			manager.lock.Lock()
+			gotRegister := manager.requests[0]
+			manager.lock.Unlock()
+			if want := "POST /api/v1/connectors/register " + tt.wantAuth; gotRegister != want {
+				t.Errorf("Run() register request = %s, want %s", gotRegister, want)
+			}
+			if config.DummyString != "new" || config.GMalwareAPIToken != tt.wantToken {
+				t.Errorf("Run() config = %+v", config)
+			}
+			wantTokenSource := ConfigSourceDefault
+			switch {
+			case tt.config != "":
+				wantTokenSource = ConfigSourceConsole
+			case tt.wantToken != "":
+				wantTokenSource = ConfigSourceSecrets
+			}
+			sources := run.Client.ConfigProvenance().Sources(config)
+			gotSources := map[string]ConfigSource{}
+			for _, path := range []string{"gmalware_api_url", "dummy_string", "gmalware_api_token", "debug"} {
+				gotSources[path] = sources[path]
+			}
+			wantSources := map[string]ConfigSource{
+				"gmalware_api_url":   ConfigSourceEnvironment,
+				"dummy_string":       ConfigSourceConsole,
+				"gmalware_api_token": wantTokenSource,
+				"debug":              ConfigSourceDefault,
+			}
+			if diff := cmp.Diff(gotSources, wantSources); diff != "" {
+				t.Errorf("Run() config provenance diff(got-want)=%s", diff)
+			}
+			if run.EventHandler == nil {
+				t.Errorf("Run() connector built without event handler")
+			}
+			if gotStore := run.Client.secrets != nil; gotStore != !tt.noStore {
+				t.Errorf("Run() client with secrets store = %v, want %v", gotStore, !tt.noStore)
+			}
+			gotSecrets := map[string]string{}
+			for _, name := range []string{secrets.ConsoleAPIKey, secrets.GMalwareAPIToken} {
+				if secret, err := store.Get(name); err == nil {
+					gotSecrets[name] = secret
+				}
+			}
+			if tt.wantSecrets == nil {
+				tt.wantSecrets = map[string]string{}
+			}
+			if diff := cmp.Diff(gotSecrets, tt.wantSecrets); diff != "" {
+				t.Errorf("Run() secrets diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestRun_debugConfig(t *testing.T) {
+	// debug port already in use, so debug server start fails if it is started
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+	port := listener.Addr().(*net.TCPAddr).Port
+	tests := []struct {
+		name      string
+		config    string
+		wantDebug bool
+	}{
+		{name: "debug disabled", config: `{"dummy_string":"new"}`},
+		{name: "debug enabled by console config", config: `{"dummy_string":"new","debug":true}`, wantDebug: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &fakeManager{config: tt.config}
+			server := httptest.NewServer(manager.handler(t))
+			defer server.Close()
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			err := Run(ctx, RunOptions{
+				Client:  ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"},
+				Version: "1.0.0",
+				Config:  &DummyConfig{},
+				Debug:   DebugOptions{Port: port},
+				NewConnector: func(ctx context.Context, info RunInfo) (connector Connector, err error) {
+					cancel()
+					connector = &fakeConnector{}
+					return
+				},
+			})
+			if gotDebug := err != nil && strings.Contains(err.Error(), "debug server"); gotDebug != tt.wantDebug {
+				t.Errorf("Run() error = %v, want debug server started %v", err, tt.wantDebug)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Package sampling produces samples (head and tail) of files too big to be analyzed whole, with the thresholds of
+// connector config (e.g. sdk.ICAPSamplingConfig), so ICAP, host or S3 connectors sample the same way.
+package sampling
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+var ErrInvalidSampler = errors.New("invalid sampler")
+
+// Sampler samples files bigger than Threshold, keeping their first HeadSize and last TailSize bytes.
+// Its zero value never samples.
+type Sampler struct {
+	// Threshold is the size above which files are sampled, sampling is disabled if 0
+	Threshold int64
+	HeadSize  int64
+	TailSize  int64
+}
+
+// NewSampler returns a sampler with thresholds checked the same way sdk.ValidateICAPSampling does.
+func NewSampler(threshold int64, headSize int64, tailSize int64) (s Sampler, err error) {
+	switch {
+	case threshold < 0 || headSize < 0 || tailSize < 0:
+		err = fmt.Errorf("%w, negative size", ErrInvalidSampler)
+	case threshold > 0 && (headSize == 0 || tailSize == 0):
+		err = fmt.Errorf("%w, head and tail sizes must be greater than 0 when threshold is set", ErrInvalidSampler)
+	case threshold > 0 && headSize+tailSize > threshold:
+		err = fmt.Errorf("%w, head size + tail size must be lower than or equal to threshold", ErrInvalidSampler)
+	}
+	if err != nil {
+		return
+	}
+	s = Sampler{Threshold: threshold, HeadSize: headSize, TailSize: tailSize}
+	return
+}
+
+// FromICAPConfig returns a sampler with thresholds of ICAP connector config.
+func FromICAPConfig(config sdk.ICAPSamplingConfig) (s Sampler, err error) {
+	return NewSampler(config.Threshold, config.HeadSize, config.TailSize)
+}
+
+// Enabled reports whether sampler samples big files.
+func (s Sampler) Enabled() bool {
+	return s.Threshold > 0
+}
+
+// Sample is the content to analyze for a file, the whole file if it was not sampled.
+type Sample struct {
+	io.Reader
+	// Size is the size of sample content
+	Size int64
+	// FileSize is the size of sampled file
+	FileSize int64
+	// Sampled reports whether content is a head and tail sample of the file
+	Sampled bool
+
+	headSize int64
+	tailSize int64
+}
+
+// Sample returns content of r (of size bytes) to analyze: a head and tail sample if size is above threshold, the
+// whole content otherwise.
+func (s Sampler) Sample(r io.ReaderAt, size int64) (sample Sample) {
+	if !s.Enabled() || size <= s.Threshold {
+		sample = Sample{Reader: io.NewSectionReader(r, 0, size), Size: size, FileSize: size}
+		return
+	}
+	sample = Sample{
+		Reader:   io.MultiReader(io.NewSectionReader(r, 0, s.HeadSize), io.NewSectionReader(r, size-s.TailSize, s.TailSize)),
+		Size:     s.HeadSize + s.TailSize,
+		FileSize: size,
+		Sampled:  true,
+		headSize: s.HeadSize,
+		tailSize: s.TailSize,
+	}
+	return
+}
+
+// Annotation tells a result is the one of a sample, to report along it (e.g. in events.CommonDetails.AdditionalInfo
+// of mitigation events).
+type Annotation struct {
+	// Reason is events.ReasonTooBig: file was too big to be analyzed whole
+	Reason events.MitigationReason
+	Info   string
+}
+
+// Annotation returns annotation of a sampled file, ok being false if file was not sampled.
+func (s Sample) Annotation() (annotation Annotation, ok bool) {
+	if !s.Sampled {
+		return
+	}
+	annotation = Annotation{
+		Reason: events.ReasonTooBig,
+		Info:   fmt.Sprintf("file of %d bytes sampled, first %d and last %d bytes analyzed", s.FileSize, s.headSize, s.tailSize),
+	}
+	ok = true
+	return
+}
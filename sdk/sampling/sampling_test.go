@@ -0,0 +1,86 @@
+package sampling
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFromICAPConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  sdk.ICAPSamplingConfig
+		wantErr error
+	}{
+		{name: "disabled"},
+		{name: "valid", config: sdk.ICAPSamplingConfig{Threshold: 100, HeadSize: 40, TailSize: 60}},
+		{name: "missing tail size", config: sdk.ICAPSamplingConfig{Threshold: 100, HeadSize: 40}, wantErr: ErrInvalidSampler},
+		{name: "samples exceed threshold", config: sdk.ICAPSamplingConfig{Threshold: 100, HeadSize: 60, TailSize: 60}, wantErr: ErrInvalidSampler},
+		{name: "negative size", config: sdk.ICAPSamplingConfig{Threshold: -1}, wantErr: ErrInvalidSampler},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := FromICAPConfig(tt.config); !errors.Is(err, tt.wantErr) {
+				t.Errorf("FromICAPConfig() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSampler_Sample(t *testing.T) {
+	tests := []struct {
+		name           string
+		sampler        Sampler
+		content        string
+		wantContent    string
+		wantSampled    bool
+		wantAnnotation Annotation
+	}{
+		{
+			name:        "sampling disabled",
+			content:     "0123456789",
+			wantContent: "0123456789",
+		},
+		{
+			name:        "below threshold",
+			sampler:     Sampler{Threshold: 10, HeadSize: 2, TailSize: 3},
+			content:     "0123456789",
+			wantContent: "0123456789",
+		},
+		{
+			name:        "above threshold",
+			sampler:     Sampler{Threshold: 8, HeadSize: 2, TailSize: 3},
+			content:     "0123456789",
+			wantContent: "01789",
+			wantSampled: true,
+			wantAnnotation: Annotation{
+				Reason: events.ReasonTooBig,
+				Info:   "file of 10 bytes sampled, first 2 and last 3 bytes analyzed",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample := tt.sampler.Sample(strings.NewReader(tt.content), int64(len(tt.content)))
+			got, err := io.ReadAll(sample)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if string(got) != tt.wantContent || sample.Size != int64(len(tt.wantContent)) {
+				t.Errorf("Sample() = %q (size %d), want %q", got, sample.Size, tt.wantContent)
+			}
+			annotation, ok := sample.Annotation()
+			if ok != tt.wantSampled || sample.Sampled != tt.wantSampled {
+				t.Errorf("Sample() sampled = %v, annotation = %v, want %v", sample.Sampled, ok, tt.wantSampled)
+			}
+			if diff := cmp.Diff(annotation, tt.wantAnnotation); diff != "" {
+				t.Errorf("Annotation() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Package sdktest provides helpers to test connectors built with the SDK.
+package sdktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+// ErrInjected is the default error returned by FlakyNotifier on injected failures.
+var ErrInjected = errors.New("injected notifier error")
+
+type Outcome int
+
+const (
+	OutcomeOK Outcome = iota
+	OutcomeError
+	OutcomeUnauthorized
+)
+
+type FlakyOptions struct {
+	// Outcomes are used in order for the first calls, before applying rates.
+	Script []Outcome
+	// ErrorRate is the probability (0 to 1) for a call to fail with Err.
+	ErrorRate float64
+	// UnauthorizedRate is the probability (0 to 1) for a call to fail like a revoked api key (sdk.ErrUnauthorizedConnector).
+	UnauthorizedRate float64
+	// Latency is added to every call, plus a random duration up to Jitter. Context cancellation is honored.
+	Latency time.Duration
+	Jitter  time.Duration
+	// Seed makes random failures and jitter reproducible.
+	Seed uint64
+	// Err is returned on injected errors, ErrInjected if nil.
+	Err error
+}
+
+var _ events.Notifier = &FlakyNotifier{}
+
+// FlakyNotifier wraps a Notifier and injects failures and latency, deterministically for a given seed.
+// It is safe for concurrent use.
+type FlakyNotifier struct {
+	next events.Notifier
+	opts FlakyOptions
+
+	lock      sync.Mutex
+	rand      *rand.Rand
+	calls     int
+	failures  int
+	delivered []any
+}
+
+// NewFlakyNotifier wraps next, which may be nil to only record delivered events.
+func NewFlakyNotifier(next events.Notifier, opts FlakyOptions) (n *FlakyNotifier) {
+	if opts.Err == nil {
+		opts.Err = ErrInjected
+	}
+	n = &FlakyNotifier{
+		next: next,
+		opts: opts,
+		rand: rand.New(rand.NewPCG(opts.Seed, opts.Seed)), //nolint:gosec // test helper, reproducibility wanted
+	}
+	return
+}
+
+func (n *FlakyNotifier) nextOutcome() (outcome Outcome, delay time.Duration) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.calls++
+	delay = n.opts.Latency
+	if n.opts.Jitter > 0 {
+		delay += time.Duration(n.rand.Int64N(int64(n.opts.Jitter)))
+	}
+	if n.calls <= len(n.opts.Script) {
+		outcome = n.opts.Script[n.calls-1]
+		return
+	}
+	r := n.rand.Float64()
+	switch {
+	case r < n.opts.UnauthorizedRate:
+		outcome = OutcomeUnauthorized
+	case r < n.opts.UnauthorizedRate+n.opts.ErrorRate:
+		outcome = OutcomeError
+	default:
+		outcome = OutcomeOK
+	}
+	return
+}
+
+func (n *FlakyNotifier) Notify(ctx context.Context, event any) (err error) {
+	outcome, delay := n.nextOutcome()
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			n.recordFailure()
+			return
+		case <-timer.C:
+		}
+	}
+	switch outcome {
+	case OutcomeError:
+		err = n.opts.Err
+	case OutcomeUnauthorized:
+		err = errors.Join(sdk.ErrUnauthorizedConnector, fmt.Errorf("injected: %w", sdk.NewHTTPError(http.StatusUnauthorized, []byte(`{"code":2}`))))
+	default:
+		if n.next != nil {
+			err = n.next.Notify(ctx, event)
+		}
+	}
+	if err != nil {
+		n.recordFailure()
+		return
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.delivered = append(n.delivered, event)
+	return
+}
+
+func (n *FlakyNotifier) recordFailure() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.failures++
+}
+
+// Calls returns number of Notify calls.
+func (n *FlakyNotifier) Calls() int {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.calls
+}
+
+// Failures returns number of Notify calls that returned an error.
+func (n *FlakyNotifier) Failures() int {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.failures
+}
+
+// Delivered returns a copy of events successfully notified, in order.
+func (n *FlakyNotifier) Delivered() (delivered []any) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	delivered = make([]any, len(n.delivered))
+	copy(delivered, n.delivered)
+	return
+}
@@ -0,0 +1,62 @@
+package sdktest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+func TestFlakyNotifier_Script(t *testing.T) {
+	n := NewFlakyNotifier(nil, FlakyOptions{
+		Script: []Outcome{OutcomeError, OutcomeUnauthorized, OutcomeOK},
+	})
+	ctx := t.Context()
+	if err := n.Notify(ctx, events.LogEvent{Message: "1"}); !errors.Is(err, ErrInjected) {
+		t.Errorf("Notify() error = %v, want ErrInjected", err)
+	}
+	if err := n.Notify(ctx, events.LogEvent{Message: "2"}); !errors.Is(err, sdk.ErrUnauthorizedConnector) {
+		t.Errorf("Notify() error = %v, want ErrUnauthorizedConnector", err)
+	}
+	if err := n.Notify(ctx, events.LogEvent{Message: "3"}); err != nil {
+		t.Errorf("Notify() error = %v", err)
+	}
+	if n.Calls() != 3 || n.Failures() != 2 || len(n.Delivered()) != 1 {
+		t.Errorf("got calls=%d failures=%d delivered=%d, want 3, 2, 1", n.Calls(), n.Failures(), len(n.Delivered()))
+	}
+}
+
+func TestFlakyNotifier_Deterministic(t *testing.T) {
+	outcomes := func() (failed []bool) {
+		n := NewFlakyNotifier(nil, FlakyOptions{ErrorRate: 0.5, Seed: 42})
+		for range 50 {
+			failed = append(failed, n.Notify(t.Context(), events.TaskEvent{}) != nil)
+		}
+		return
+	}
+	first, second := outcomes(), outcomes()
+	failures := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("outcome %d differs between runs with same seed", i)
+		}
+		if first[i] {
+			failures++
+		}
+	}
+	if failures == 0 || failures == len(first) {
+		t.Errorf("got %d failures out of %d, want some", failures, len(first))
+	}
+}
+
+func TestFlakyNotifier_Latency(t *testing.T) {
+	n := NewFlakyNotifier(nil, FlakyOptions{Latency: time.Second})
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if err := n.Notify(ctx, events.TaskEvent{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Notify() error = %v, want context.DeadlineExceeded", err)
+	}
+}
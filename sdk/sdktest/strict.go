@@ -0,0 +1,121 @@
+package sdktest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+// ErrAsymmetricEvent is returned by StrictNotifier for events that do not survive a JSON round-trip.
+var ErrAsymmetricEvent = errors.New("event does not survive JSON round-trip")
+
+// mitigationInfos are wire structs of mitigation event info, by info type
+var mitigationInfos = map[events.MitigationInfoType]reflect.Type{
+	events.InfoTypeFile:  reflect.TypeFor[events.FileInfos](),
+	events.InfoTypeEmail: reflect.TypeFor[events.EmailInfos](),
+	events.InfoTypeURL:   reflect.TypeFor[events.URLInfos](),
+}
+
+var _ events.Notifier = &StrictNotifier{}
+
+// StrictNotifier wraps a Notifier and checks every event round-trips through JSON as the manager decodes it:
+// marshaled in its envelope, unmarshaled into its wire struct (and mitigation info struct) with unknown fields
+// disallowed, then marshaled again to the same JSON. Asymmetric events fail the test and are not forwarded.
+// It is safe for concurrent use.
+type StrictNotifier struct {
+	t    testing.TB
+	next events.Notifier
+
+	lock    sync.Mutex
+	checked []any
+}
+
+// NewStrictNotifier wraps next, which may be nil to only record checked events.
+func NewStrictNotifier(t testing.TB, next events.Notifier) (n *StrictNotifier) {
+	n = &StrictNotifier{t: t, next: next}
+	return
+}
+
+func (n *StrictNotifier) Notify(ctx context.Context, event any) (err error) {
+	if err = CheckRoundTrip(event); err != nil {
+		n.t.Errorf("StrictNotifier: %v", err)
+		return
+	}
+	n.lock.Lock()
+	n.checked = append(n.checked, event)
+	n.lock.Unlock()
+	if n.next != nil {
+		err = n.next.Notify(ctx, event)
+	}
+	return
+}
+
+// Checked returns a copy of events that passed the round-trip check, in order.
+func (n *StrictNotifier) Checked() (checked []any) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	checked = make([]any, len(n.checked))
+	copy(checked, n.checked)
+	return
+}
+
+// CheckRoundTrip returns an ErrAsymmetricEvent error if event, in an envelope of current schema version, does not
+// decode strictly into its wire struct or does not marshal back to the same JSON.
+func CheckRoundTrip(event any) (err error) {
+	envelope, err := events.NewEnvelope(events.SchemaVersionCurrent, event)
+	if err != nil {
+		err = fmt.Errorf("%w: %T: %w", ErrAsymmetricEvent, event, err)
+		return
+	}
+	rawEnvelope, err := json.Marshal(envelope)
+	if err != nil {
+		err = fmt.Errorf("%w: %T: %w", ErrAsymmetricEvent, event, err)
+		return
+	}
+	decodedEnvelope := events.Envelope{}
+	if err = strictUnmarshal(rawEnvelope, &decodedEnvelope); err != nil {
+		err = fmt.Errorf("%w: envelope of %T: %w", ErrAsymmetricEvent, event, err)
+		return
+	}
+
+	decoded := reflect.New(reflect.TypeOf(event))
+	if mitigation, ok := event.(events.MitigationEvent); ok {
+		infoType, known := mitigationInfos[mitigation.InfoType]
+		if !known {
+			err = fmt.Errorf("%w: unknown mitigation info type %q", ErrAsymmetricEvent, mitigation.InfoType)
+			return
+		}
+		decoded.Interface().(*events.MitigationEvent).Info = reflect.New(infoType).Interface()
+	}
+	if err = strictUnmarshal(decodedEnvelope.Event, decoded.Interface()); err != nil {
+		err = fmt.Errorf("%w: %T: %w, event: %s", ErrAsymmetricEvent, event, err, decodedEnvelope.Event)
+		return
+	}
+	if mitigation, ok := decoded.Interface().(*events.MitigationEvent); ok && mitigation.Info != nil {
+		mitigation.Info = reflect.ValueOf(mitigation.Info).Elem().Interface()
+	}
+
+	rawDecoded, err := json.Marshal(decoded.Interface())
+	if err != nil {
+		err = fmt.Errorf("%w: decoded %T: %w", ErrAsymmetricEvent, event, err)
+		return
+	}
+	if !bytes.Equal(rawDecoded, decodedEnvelope.Event) {
+		err = fmt.Errorf("%w: %T marshals to %s, decoded one to %s", ErrAsymmetricEvent, event, decodedEnvelope.Event, rawDecoded)
+	}
+	return
+}
+
+func strictUnmarshal(raw []byte, v any) (err error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(v)
+	return
+}
@@ -0,0 +1,87 @@
+package sdktest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+// recorder records test errors instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCheckRoundTrip_fixtures(t *testing.T) {
+	fixtures, err := events.Fixtures()
+	if err != nil {
+		t.Fatalf("Fixtures() error = %v", err)
+	}
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			if err := CheckRoundTrip(f.Event); err != nil {
+				t.Errorf("CheckRoundTrip() error = %v", err)
+			}
+		})
+	}
+}
+
+// driftedInfos has a field unknown to FileInfos, as a connector built against a different SDK would send.
+type driftedInfos struct {
+	events.FileInfos
+	Checksum string `json:"checksum"`
+}
+
+func TestStrictNotifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   any
+		wantErr bool
+	}{
+		{name: "log", event: events.LogEvent{Level: "info", Message: "started", Time: 1738000000}},
+		{
+			name:  "file mitigation",
+			event: events.MitigationEvent{Action: events.ActionQuarantine, InfoType: events.InfoTypeFile, ElementID: "1", Info: events.FileInfos{File: "/tmp/eicar", Size: 68}},
+		},
+		{name: "no mitigation info", event: events.MitigationEvent{Action: events.ActionLog, InfoType: events.InfoTypeURL, ElementID: "1"}},
+		{
+			name:    "unknown info field",
+			event:   events.MitigationEvent{Action: events.ActionQuarantine, InfoType: events.InfoTypeFile, ElementID: "1", Info: driftedInfos{Checksum: "abc"}},
+			wantErr: true,
+		},
+		{
+			name:    "info not matching info type",
+			event:   events.MitigationEvent{Action: events.ActionBlock, InfoType: events.InfoTypeEmail, ElementID: "1", Info: events.URLInfos{URL: "http://example.com"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown info type",
+			event:   events.MitigationEvent{Action: events.ActionBlock, InfoType: "sms", ElementID: "1", Info: events.FileInfos{}},
+			wantErr: true,
+		},
+		{name: "not an event", event: struct{ Message string }{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{TB: t}
+			next := NewFlakyNotifier(nil, FlakyOptions{})
+			n := NewStrictNotifier(r, next)
+			err := n.Notify(t.Context(), tt.event)
+			if tt.wantErr {
+				if !errors.Is(err, ErrAsymmetricEvent) || len(r.errors) != 1 || len(next.Delivered()) != 0 {
+					t.Errorf("Notify() error = %v, test errors = %v, delivered = %d, want ErrAsymmetricEvent reported, not delivered", err, r.errors, len(next.Delivered()))
+				}
+				return
+			}
+			if err != nil || len(r.errors) != 0 || len(n.Checked()) != 1 || len(next.Delivered()) != 1 {
+				t.Errorf("Notify() error = %v, test errors = %v, want event checked and delivered", err, r.errors)
+			}
+		})
+	}
+}
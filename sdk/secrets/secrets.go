@@ -0,0 +1,111 @@
+// Package secrets stores connector credentials (console API key, GLIMPS Malware token)
+// outside of environment variables and config files, e.g. in the OS keyring.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Secret names used by the SDK.
+const (
+	ConsoleAPIKey    = "console-api-key"
+	GMalwareAPIToken = "gmalware-api-token"
+)
+
+var ErrNotFound = errors.New("secret not found")
+
+// Store stores secrets by name. Implementations MUST be thread-safe.
+type Store interface {
+	// Get returns ErrNotFound if secret is not set
+	Get(name string) (secret string, err error)
+	Set(name string, secret string) (err error)
+	// Delete does not fail if secret is not set
+	Delete(name string) (err error)
+}
+
+var (
+	_ Store = Keyring{}
+	_ Store = &MemoryStore{}
+)
+
+// Keyring stores secrets in the OS keyring: Windows Credential Manager, macOS Keychain,
+// or Secret Service (libsecret, e.g. GNOME Keyring) on Linux.
+type Keyring struct {
+	service string
+}
+
+// NewKeyring returns a Keyring storing secrets under service, which SHOULD identify the connector
+// (e.g. "glimps-connector-host"), so several connectors on a host do not share secrets.
+func NewKeyring(service string) Keyring {
+	return Keyring{service: service}
+}
+
+func (k Keyring) Get(name string) (secret string, err error) {
+	secret, err = keyring.Get(k.service, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		err = fmt.Errorf("%w: %s", ErrNotFound, name)
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("could not read %s from keyring, %w", name, err)
+		return
+	}
+	return
+}
+
+func (k Keyring) Set(name string, secret string) (err error) {
+	if err = keyring.Set(k.service, name, secret); err != nil {
+		err = fmt.Errorf("could not store %s in keyring, %w", name, err)
+		return
+	}
+	return
+}
+
+func (k Keyring) Delete(name string) (err error) {
+	err = keyring.Delete(k.service, name)
+	switch {
+	case errors.Is(err, keyring.ErrNotFound):
+		err = nil
+	case err != nil:
+		err = fmt.Errorf("could not delete %s from keyring, %w", name, err)
+	}
+	return
+}
+
+// MemoryStore keeps secrets in memory, e.g. for tests.
+type MemoryStore struct {
+	lock    sync.Mutex
+	secrets map[string]string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{secrets: make(map[string]string)}
+}
+
+func (m *MemoryStore) Get(name string) (secret string, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	secret, ok := m.secrets[name]
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return
+}
+
+func (m *MemoryStore) Set(name string, secret string) (err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.secrets[name] = secret
+	return
+}
+
+func (m *MemoryStore) Delete(name string) (err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.secrets, name)
+	return
+}
@@ -0,0 +1,41 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestStore(t *testing.T) {
+	keyring.MockInit()
+	tests := []struct {
+		name  string
+		store Store
+	}{
+		{name: "keyring", store: NewKeyring("glimps-connector-test")},
+		{name: "memory", store: NewMemoryStore()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.store.Get(ConsoleAPIKey); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() error = %v, want %v", err, ErrNotFound)
+			}
+			if err := tt.store.Set(ConsoleAPIKey, "api-key"); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if got, err := tt.store.Get(ConsoleAPIKey); err != nil || got != "api-key" {
+				t.Errorf("Get() = %s, %v", got, err)
+			}
+			if err := tt.store.Delete(ConsoleAPIKey); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if err := tt.store.Delete(ConsoleAPIKey); err != nil {
+				t.Errorf("Delete() missing secret error = %v", err)
+			}
+			if _, err := tt.store.Get(ConsoleAPIKey); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() deleted secret error = %v, want %v", err, ErrNotFound)
+			}
+		})
+	}
+}
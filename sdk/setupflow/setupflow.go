@@ -0,0 +1,257 @@
+// Package setupflow models multi-page connector onboarding wizards as data.
+// A Flow is declared in connector.yaml (setup_flow section) and exposed by the
+// loader, the console only has to render pages and follow transitions.
+package setupflow
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrPageNotFound    = errors.New("setup flow page not found")
+	ErrInvalidFlow     = errors.New("invalid setup flow")
+	ErrMissingInput    = errors.New("missing required input")
+	ErrInvalidOption   = errors.New("input value is not one of the allowed options")
+	ErrFlowCompleted   = errors.New("setup flow already completed")
+	ErrNoPreviousPages = errors.New("no previous page in setup flow")
+)
+
+type InputType string
+
+const (
+	InputString   InputType = "string"
+	InputBoolean  InputType = "boolean"
+	InputNumber   InputType = "number"
+	InputSelect   InputType = "select"
+	InputPassword InputType = "password"
+)
+
+// Flow is an ordered set of pages. First page is the entry point.
+type Flow struct {
+	Pages []Page `yaml:"pages" json:"pages"`
+}
+
+type Page struct {
+	ID          string   `yaml:"id" json:"id"`
+	Title       string   `yaml:"title" json:"title"`
+	Description string   `yaml:"description" json:"description"`
+	Inputs      []Input  `yaml:"inputs" json:"inputs"`
+	Branches    []Branch `yaml:"branches" json:"branches" desc:"conditional transitions, evaluated in order, first match wins"`
+	Next        string   `yaml:"next" json:"next" desc:"page to go to when no branch matches, empty means end of flow"`
+}
+
+type Input struct {
+	Key         string    `yaml:"key" json:"key" desc:"answer key, may match a config field key to prefill the config form"`
+	Label       string    `yaml:"label" json:"label"`
+	Description string    `yaml:"description" json:"description"`
+	Type        InputType `yaml:"type" json:"type"`
+	Required    bool      `yaml:"required" json:"required"`
+	Options     []string  `yaml:"options" json:"options,omitempty" desc:"allowed values, for select inputs"`
+	Default     any       `yaml:"default" json:"default,omitempty"`
+}
+
+// Branch moves the wizard to Goto when answer for Input equals one of Values.
+type Branch struct {
+	Input  string   `yaml:"input" json:"input"`
+	Values []string `yaml:"values" json:"values"`
+	Goto   string   `yaml:"goto" json:"goto"`
+}
+
+type Answers map[string]any
+
+func (b Branch) matches(answers Answers) bool {
+	v, ok := answers[b.Input]
+	if !ok {
+		return false
+	}
+	return slices.Contains(b.Values, fmt.Sprint(v))
+}
+
+// Page returns page identified by id.
+func (f Flow) Page(id string) (page Page, err error) {
+	for _, p := range f.Pages {
+		if p.ID == id {
+			page = p
+			return
+		}
+	}
+	err = fmt.Errorf("%w: %s", ErrPageNotFound, id)
+	return
+}
+
+// Validate checks flow consistency: unique page ids, unique input keys in each page, and known transition targets
+// and branch inputs.
+func (f Flow) Validate() (err error) {
+	ids := make(map[string]struct{}, len(f.Pages))
+	keys := make(map[string]struct{})
+	for _, p := range f.Pages {
+		if p.ID == "" {
+			return fmt.Errorf("%w: page without id", ErrInvalidFlow)
+		}
+		if _, ok := ids[p.ID]; ok {
+			return fmt.Errorf("%w: duplicated page id %s", ErrInvalidFlow, p.ID)
+		}
+		ids[p.ID] = struct{}{}
+		inputs := make(map[string]struct{}, len(p.Inputs))
+		for _, in := range p.Inputs {
+			if in.Key == "" {
+				return fmt.Errorf("%w: input without key in page %s", ErrInvalidFlow, p.ID)
+			}
+			if _, ok := inputs[in.Key]; ok {
+				return fmt.Errorf("%w: duplicated input %s in page %s", ErrInvalidFlow, in.Key, p.ID)
+			}
+			if in.Type == InputSelect && len(in.Options) == 0 {
+				return fmt.Errorf("%w: select input %s in page %s has no options", ErrInvalidFlow, in.Key, p.ID)
+			}
+			inputs[in.Key] = struct{}{}
+			keys[in.Key] = struct{}{}
+		}
+	}
+	for _, p := range f.Pages {
+		if _, ok := ids[p.Next]; p.Next != "" && !ok {
+			return fmt.Errorf("%w: page %s goes to unknown page %s", ErrInvalidFlow, p.ID, p.Next)
+		}
+		for _, b := range p.Branches {
+			if _, ok := ids[b.Goto]; !ok {
+				return fmt.Errorf("%w: page %s branches to unknown page %s", ErrInvalidFlow, p.ID, b.Goto)
+			}
+			if _, ok := keys[b.Input]; !ok {
+				return fmt.Errorf("%w: page %s branches on unknown input %s", ErrInvalidFlow, p.ID, b.Input)
+			}
+		}
+	}
+	return
+}
+
+// ValidateAnswers checks answers given for a page inputs.
+func (p Page) ValidateAnswers(answers Answers) (err error) {
+	for _, in := range p.Inputs {
+		v, ok := answers[in.Key]
+		if !ok || v == nil || v == "" {
+			if in.Required {
+				err = errors.Join(err, fmt.Errorf("%w: %s", ErrMissingInput, in.Key))
+			}
+			continue
+		}
+		if in.Type == InputSelect && !slices.Contains(in.Options, fmt.Sprint(v)) {
+			err = errors.Join(err, fmt.Errorf("%w: %s", ErrInvalidOption, in.Key))
+		}
+	}
+	return
+}
+
+// Next returns the page following pageID given all answers collected so far.
+// An empty next page means the flow is completed.
+func (f Flow) Next(pageID string, answers Answers) (next string, err error) {
+	page, err := f.Page(pageID)
+	if err != nil {
+		return
+	}
+	for _, b := range page.Branches {
+		if b.matches(answers) {
+			next = b.Goto
+			return
+		}
+	}
+	next = page.Next
+	return
+}
+
+// Session is the wizard state machine: it tracks current page, visited pages
+// and collected answers. It is not safe for concurrent use.
+type Session struct {
+	flow    Flow
+	current string
+	history []string
+	answers Answers
+}
+
+// NewSession starts a session on flow first page.
+func NewSession(flow Flow) (s *Session, err error) {
+	if len(flow.Pages) == 0 {
+		err = fmt.Errorf("%w: no page", ErrInvalidFlow)
+		return
+	}
+	if err = flow.Validate(); err != nil {
+		return
+	}
+	s = &Session{
+		flow:    flow,
+		current: flow.Pages[0].ID,
+		answers: make(Answers),
+	}
+	return
+}
+
+// Current returns current page, ok is false once flow is completed.
+func (s *Session) Current() (page Page, ok bool) {
+	if s.current == "" {
+		return
+	}
+	page, err := s.flow.Page(s.current)
+	ok = err == nil
+	return
+}
+
+func (s *Session) Done() bool {
+	return s.current == ""
+}
+
+// Answers returns a copy of collected answers.
+func (s *Session) Answers() (answers Answers) {
+	answers = make(Answers, len(s.answers))
+	for k, v := range s.answers {
+		answers[k] = v
+	}
+	return
+}
+
+// Submit validates answers for current page, stores them and moves to next page.
+func (s *Session) Submit(answers Answers) (next string, err error) {
+	page, ok := s.Current()
+	if !ok {
+		err = ErrFlowCompleted
+		return
+	}
+	if err = page.ValidateAnswers(answers); err != nil {
+		return
+	}
+	for _, in := range page.Inputs {
+		v, found := answers[in.Key]
+		switch {
+		case found:
+			s.answers[in.Key] = v
+		case in.Default != nil:
+			s.answers[in.Key] = in.Default
+		}
+	}
+	next, err = s.flow.Next(page.ID, s.answers)
+	if err != nil {
+		return
+	}
+	s.history = append(s.history, s.current)
+	s.current = next
+	return
+}
+
+// Back returns to previously visited page. Answers of pages left behind are
+// dropped, so they can't influence branches anymore.
+func (s *Session) Back() (previous string, err error) {
+	if len(s.history) == 0 {
+		err = ErrNoPreviousPages
+		return
+	}
+	previous = s.history[len(s.history)-1]
+	s.history = s.history[:len(s.history)-1]
+	page, err := s.flow.Page(previous)
+	if err != nil {
+		return
+	}
+	for _, in := range page.Inputs {
+		delete(s.answers, in.Key)
+	}
+	s.current = previous
+	return
+}
@@ -0,0 +1,192 @@
+package setupflow
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var testFlow = Flow{
+	Pages: []Page{
+		{
+			ID: "credentials",
+			Inputs: []Input{
+				{Key: "client_id", Type: InputString, Required: true},
+			},
+			Next: "mode",
+		},
+		{
+			ID: "mode",
+			Inputs: []Input{
+				{Key: "real_time", Type: InputBoolean, Default: false},
+			},
+			Branches: []Branch{
+				{Input: "real_time", Values: []string{"true"}, Goto: "webhook"},
+			},
+			Next: "action",
+		},
+		{
+			ID: "webhook",
+			Inputs: []Input{
+				{Key: "webhook_url", Type: InputString, Required: true},
+			},
+			Next: "action",
+		},
+		{
+			ID: "action",
+			Inputs: []Input{
+				{Key: "action", Type: InputSelect, Required: true, Options: []string{"delete", "log"}},
+			},
+		},
+	},
+}
+
+func TestFlow_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		flow    Flow
+		wantErr bool
+	}{
+		{
+			name: "ok",
+			flow: testFlow,
+		},
+		{
+			name:    "ko duplicated page",
+			flow:    Flow{Pages: []Page{{ID: "a"}, {ID: "a"}}},
+			wantErr: true,
+		},
+		{
+			name:    "ko unknown next",
+			flow:    Flow{Pages: []Page{{ID: "a", Next: "b"}}},
+			wantErr: true,
+		},
+		{
+			name:    "ko unknown branch target",
+			flow:    Flow{Pages: []Page{{ID: "a", Inputs: []Input{{Key: "x"}}, Branches: []Branch{{Input: "x", Values: []string{"1"}, Goto: "c"}}}}},
+			wantErr: true,
+		},
+		{
+			name:    "ko unknown branch input",
+			flow:    Flow{Pages: []Page{{ID: "a", Branches: []Branch{{Input: "x", Values: []string{"1"}, Goto: "a"}}}}},
+			wantErr: true,
+		},
+		{
+			name:    "ko duplicated input",
+			flow:    Flow{Pages: []Page{{ID: "a", Inputs: []Input{{Key: "x"}, {Key: "x"}}}}},
+			wantErr: true,
+		},
+		{
+			name:    "ko select without options",
+			flow:    Flow{Pages: []Page{{ID: "a", Inputs: []Input{{Key: "x", Type: InputSelect}}}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.flow.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Flow.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidFlow) {
+				t.Errorf("Flow.Validate() error = %v, want ErrInvalidFlow", err)
+			}
+		})
+	}
+}
+
+func TestSession(t *testing.T) {
+	tests := []struct {
+		name        string
+		submits     []Answers
+		wantPages   []string
+		wantAnswers Answers
+		wantErr     error
+	}{
+		{
+			name:        "ok without branch",
+			submits:     []Answers{{"client_id": "id"}, {}, {"action": "log"}},
+			wantPages:   []string{"mode", "action", ""},
+			wantAnswers: Answers{"client_id": "id", "real_time": false, "action": "log"},
+		},
+		{
+			name:        "ok with branch",
+			submits:     []Answers{{"client_id": "id"}, {"real_time": true}, {"webhook_url": "https://test"}, {"action": "delete"}},
+			wantPages:   []string{"mode", "webhook", "action", ""},
+			wantAnswers: Answers{"client_id": "id", "real_time": true, "webhook_url": "https://test", "action": "delete"},
+		},
+		{
+			name:      "ko missing required",
+			submits:   []Answers{{}},
+			wantPages: []string{},
+			wantErr:   ErrMissingInput,
+		},
+		{
+			name:      "ko invalid option",
+			submits:   []Answers{{"client_id": "id"}, {}, {"action": "quarantine"}},
+			wantPages: []string{"mode", "action"},
+			wantErr:   ErrInvalidOption,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSession(testFlow)
+			if err != nil {
+				t.Fatalf("NewSession() error = %v", err)
+			}
+			gotPages := []string{}
+			for _, answers := range tt.submits {
+				next, err := s.Submit(answers)
+				if err != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("Session.Submit() error = %v, want %v", err, tt.wantErr)
+					}
+					break
+				}
+				gotPages = append(gotPages, next)
+			}
+			if diff := cmp.Diff(gotPages, tt.wantPages); diff != "" {
+				t.Errorf("Session.Submit() pages diff(got-want)=%s", diff)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if !s.Done() {
+				t.Errorf("Session.Done() = false, want true")
+			}
+			if diff := cmp.Diff(s.Answers(), tt.wantAnswers); diff != "" {
+				t.Errorf("Session.Answers() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestSession_Back(t *testing.T) {
+	s, err := NewSession(testFlow)
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	if _, err = s.Back(); !errors.Is(err, ErrNoPreviousPages) {
+		t.Fatalf("Session.Back() error = %v, want ErrNoPreviousPages", err)
+	}
+	for _, answers := range []Answers{{"client_id": "id"}, {"real_time": true}} {
+		if _, err = s.Submit(answers); err != nil {
+			t.Fatalf("Session.Submit() error = %v", err)
+		}
+	}
+	previous, err := s.Back()
+	if err != nil {
+		t.Fatalf("Session.Back() error = %v", err)
+	}
+	if previous != "mode" {
+		t.Errorf("Session.Back() = %s, want mode", previous)
+	}
+	next, err := s.Submit(Answers{"real_time": false})
+	if err != nil {
+		t.Fatalf("Session.Submit() error = %v", err)
+	}
+	if next != "action" {
+		t.Errorf("Session.Submit() = %s, want action", next)
+	}
+}
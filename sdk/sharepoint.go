@@ -3,6 +3,8 @@ package sdk
 import (
 	"fmt"
 	"net/url"
+
+	"github.com/glimps-re/connector-integration/sdk/msauth"
 )
 
 type SharepointConfig struct {
@@ -10,6 +12,8 @@ type SharepointConfig struct {
 
 	Database string `json:"database" validate:"required" reconfigurable:"false" desc:"Database to use. e.g. 'example1' means connector will use database located at /etc/glimps_connector/bdd/example1.db (file will be created if it does not exist)."`
 
+	WebhookACME ACMEConfig `json:"webhook_acme" mapstructure:"webhook-acme" reconfigurable:"false" desc:"Certificate of webhook endpoint obtained from an ACME certificate authority (e.g. Let's Encrypt), when no certificate is provided"`
+
 	// Not necessary fields :
 	// RestoreToken (only for standalone, not needed for console)"`
 }
@@ -27,11 +31,11 @@ type ReconfigurableSharepointConfig struct {
 
 	MitigationAction SPMitigationActions `json:"mitigation_action" mapstructure:"mitigation_action" validate:"required" desc:"Action to perform when a file is detected as malware (only one can be selected)"`
 
-	MaxUploadSize string `json:"max_upload_size" desc:"Maximum file size to send to Detect (format as: 100MB). Files above that limit will not be analyzed and an alert will be raised."`
+	MaxUploadSize string `json:"max_upload_size" desc:"Maximum file size to send to Detect (format as: 100MB). Files above that limit will not be analyzed and an alert will be raised." default:"100MB"`
 
-	RetryFrequency Duration `json:"retry_frequency" desc:"Frequency to try/retry submitting files that were rejected due to reached quotas."`
+	RetryFrequency Duration `json:"retry_frequency" validate:"durmin=1m" desc:"Frequency to try/retry submitting files that were rejected due to reached quotas." default:"10m"`
 
-	MonitoringFrequency Duration `json:"monitoring_frequency" desc:"Frequency to analyze changes on monitored drives (independently of webhook notifications) (format: 10m)."`
+	MonitoringFrequency Duration `json:"monitoring_frequency" validate:"durmin=1m" desc:"Frequency to analyze changes on monitored drives (independently of webhook notifications) (format: 10m)." default:"10m"`
 
 	QuarantineURL                        string `json:"quarantine_url" validate:"omitempty,url" desc:"URL of the sharepoint site that will be used as quarantine"`
 	QuarantineLibName                    string `json:"quarantine_lib_name" desc:"Library name to use inside the quarantine site. If not provided, default library is used (usually named 'Documents')"`
@@ -41,19 +45,19 @@ type ReconfigurableSharepointConfig struct {
 	MonitorAllWithoutInitialScan bool `json:"monitor_all_without_initial_scan" desc:"Monitor all drives, with no initial scan made by default"`
 	MonitorAllWithInitialScan    bool `json:"monitor_all_with_initial_scan" desc:"Monitor all drives, with initial scan made by default"`
 
-	SitesToMonitorWithoutInitialScan []string `json:"sites_to_monitor_without_initial_scan" validate:"dive,url" desc:"Sites to monitor without initial scan (format: 'https://mySharepoint.com/sites/mySite')"`
-	SitesToMonitorWithInitialScan    []string `json:"sites_to_monitor_with_initial_scan" validate:"dive,url" desc:"Same as SitesToMonitor, but with initial scan"`
+	SitesToMonitorWithoutInitialScan []string `json:"sites_to_monitor_without_initial_scan" validate:"dive,url" desc:"Sites to monitor without initial scan (format: 'https://mySharepoint.com/sites/mySite')" default:"[]"`
+	SitesToMonitorWithInitialScan    []string `json:"sites_to_monitor_with_initial_scan" validate:"dive,url" desc:"Same as SitesToMonitor, but with initial scan" default:"[]"`
 
-	GroupsToMonitorWithoutInitialScan []string `json:"groups_to_monitor_without_initial_scan" validate:"dive" desc:"Groups to monitor without initial scan (group names, e.g. 'myGroup')"`
-	GroupsToMonitorWithInitialScan    []string `json:"groups_to_monitor_with_initial_scan" validate:"dive" desc:"Same as GroupsToMonitor, but with initial scan"`
+	GroupsToMonitorWithoutInitialScan []string `json:"groups_to_monitor_without_initial_scan" validate:"dive" desc:"Groups to monitor without initial scan (group names, e.g. 'myGroup')" default:"[]"`
+	GroupsToMonitorWithInitialScan    []string `json:"groups_to_monitor_with_initial_scan" validate:"dive" desc:"Same as GroupsToMonitor, but with initial scan" default:"[]"`
 
 	// MonitorAll mode only
-	SitesToIgnore  []string `json:"sites_to_ignore" validate:"dive,url" desc:"Sites to not monitor when scope is MonitorAll (format: 'https://mySharepoint.com/sites/mySite')"`
-	GroupsToIgnore []string `json:"groups_to_ignore" validate:"dive" desc:"Groups to not monitor when scope is MonitorAll (group names, e.g. 'myGroup')"`
+	SitesToIgnore  []string `json:"sites_to_ignore" validate:"dive,url" desc:"Sites to not monitor when scope is MonitorAll (format: 'https://mySharepoint.com/sites/mySite')" default:"[]"`
+	GroupsToIgnore []string `json:"groups_to_ignore" validate:"dive" desc:"Groups to not monitor when scope is MonitorAll (group names, e.g. 'myGroup')" default:"[]"`
 
-	ExclusionRules []SPExclusionRule `json:"exclusion_rules" mapstructure:"exclusion-rules" desc:"Exclusion rules allow to exclude certain files or folders from analysis. It is particularly useful for files that are modified regularly, for which whitelisting by hash is not sufficient. Each rule is associated to a single drive (= a library in a site)"`
+	ExclusionRules []SPExclusionRule `json:"exclusion_rules" mapstructure:"exclusion-rules" desc:"Exclusion rules allow to exclude certain files or folders from analysis. It is particularly useful for files that are modified regularly, for which whitelisting by hash is not sufficient. Each rule is associated to a single drive (= a library in a site)" default:"[]"`
 
-	TimeoutFactor int `json:"timeout_factor" mapstructure:"timeout-factor" validate:"min=1" desc:"Optional factor to increase timeouts (if set, must be an integer >= 1). 1 to use default timeouts values"`
+	TimeoutFactor int `json:"timeout_factor" mapstructure:"timeout-factor" validate:"min=1" desc:"Optional factor to increase timeouts (if set, must be an integer >= 1). 1 to use default timeouts values" default:"1"`
 }
 
 type SPMitigationActions struct {
@@ -103,3 +107,26 @@ func (c *SharepointConfig) GetHelmConfig(consoleConfig ConsoleConfig) (helmConfi
 	}
 	return
 }
+
+func (c *SharepointConfig) MSCredentials() msauth.Credentials {
+	return msauth.Credentials{
+		TenantID:     c.M365TenantID,
+		ClientID:     c.M365ClientID,
+		ClientSecret: c.M365ClientSecret,
+	}
+}
+
+func (c *SharepointConfig) GraphFeatures() (features []msauth.Feature) {
+	features = []msauth.Feature{msauth.FeatureDriveRead}
+	if c.MitigationAction.Delete || c.MitigationAction.Quarantine {
+		features = append(features, msauth.FeatureDriveWrite)
+	}
+	if c.RealTimeMonitoring {
+		features = append(features, msauth.FeatureWebhooks)
+	}
+	monitorAll := c.MonitorAllWithInitialScan || c.MonitorAllWithoutInitialScan
+	if monitorAll || len(c.GroupsToMonitorWithInitialScan) > 0 || len(c.GroupsToMonitorWithoutInitialScan) > 0 {
+		features = append(features, msauth.FeatureGroupsRead)
+	}
+	return
+}
@@ -0,0 +1,65 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrInvalidSize is returned for sizes that are not a number of bytes with an optional unit, e.g. "100MB" or "5MiB".
+var ErrInvalidSize = errors.New("invalid size")
+
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000}, {"TB", 1000 * 1000 * 1000 * 1000},
+	{"B", 1},
+}
+
+// ParseSize parses a size in bytes with an optional unit, SI (KB, MB, GB, TB) or binary (KiB, MiB, GiB, TiB),
+// case insensitive.
+func ParseSize(s string) (size int64, err error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	factor := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper = strings.TrimSuffix(upper, u.suffix)
+			factor = u.factor
+			break
+		}
+	}
+	n, parseErr := strconv.ParseInt(strings.TrimSpace(upper), 10, 64)
+	if parseErr != nil || n < 0 || n > (1<<63-1)/factor {
+		err = fmt.Errorf("%w: %q", ErrInvalidSize, s)
+		return
+	}
+	size = n * factor
+	return
+}
+
+// FileSizeTag is the validator tag validating size strings (see ParseSize), e.g. validate:"omitempty,filesize".
+const FileSizeTag = "filesize"
+
+func registerFileSizeValidation(validate *validator.Validate, trans ut.Translator) (err error) {
+	err = validate.RegisterValidation(FileSizeTag, func(fl validator.FieldLevel) bool {
+		if fl.Field().Kind() != reflect.String {
+			return false
+		}
+		_, parseErr := ParseSize(fl.Field().String())
+		return parseErr == nil
+	})
+	if err != nil {
+		return
+	}
+	err = registerTranslations(validate, trans, map[string]string{
+		FileSizeTag: "{0} must be a size, e.g. 100MB or 5MiB",
+	})
+	return
+}
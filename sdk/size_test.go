@@ -0,0 +1,41 @@
+package sdk
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int64
+		wantErr error
+	}{
+		{size: "0", want: 0},
+		{size: "512", want: 512},
+		{size: "512B", want: 512},
+		{size: "10KB", want: 10_000},
+		{size: "10kib", want: 10 << 10},
+		{size: "100MB", want: 100_000_000},
+		{size: "100MiB", want: 100 << 20},
+		{size: " 5 GB ", want: 5_000_000_000},
+		{size: "2TiB", want: 2 << 40},
+		{size: "", wantErr: ErrInvalidSize},
+		{size: "MB", wantErr: ErrInvalidSize},
+		{size: "-1MB", wantErr: ErrInvalidSize},
+		{size: "1.5GB", wantErr: ErrInvalidSize},
+		{size: "10PB", wantErr: ErrInvalidSize},
+		{size: "9000000TiB", wantErr: ErrInvalidSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			got, err := ParseSize(tt.size)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseSize() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
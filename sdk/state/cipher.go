@@ -0,0 +1,82 @@
+package state
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the size of at-rest encryption keys (AES-256).
+const KeySize = 32
+
+// sealedMagic starts sealed data, unsealed data (written before encryption was enabled) does not start with it.
+var sealedMagic = []byte("GLSEALv1")
+
+var (
+	ErrInvalidEncryptionKey = errors.New("invalid at-rest encryption key")
+	ErrDecrypt              = errors.New("could not decrypt data, wrong key or corrupted data")
+)
+
+// Cipher seals data stored on disk (state store, event spool) with AES-256-GCM.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+func NewCipher(key []byte) (c *Cipher, err error) {
+	if len(key) != KeySize {
+		err = fmt.Errorf("%w, key must be %d bytes long, got %d", ErrInvalidEncryptionKey, KeySize, len(key))
+		return
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return
+	}
+	// FIPS-approved: nonce generated by the module and prepended to ciphertext
+	aead, err := cipher.NewGCMWithRandomNonce(block)
+	if err != nil {
+		return
+	}
+	c = &Cipher{aead: aead}
+	return
+}
+
+// NewCipherFromBase64 returns a Cipher for a base64 encoded key, e.g. read from config or keyring.
+func NewCipherFromBase64(encoded string) (c *Cipher, err error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		err = fmt.Errorf("%w, %w", ErrInvalidEncryptionKey, err)
+		return
+	}
+	c, err = NewCipher(key)
+	return
+}
+
+// Seal encrypts data. additionalData (e.g. a record key) is authenticated, not stored.
+func (c *Cipher) Seal(data []byte, additionalData []byte) (sealed []byte, err error) {
+	sealed = make([]byte, len(sealedMagic), len(sealedMagic)+len(data)+c.aead.Overhead())
+	copy(sealed, sealedMagic)
+	sealed = c.aead.Seal(sealed, nil, data, additionalData)
+	return
+}
+
+// Open decrypts data sealed with the same additionalData.
+func (c *Cipher) Open(sealed []byte, additionalData []byte) (data []byte, err error) {
+	if !IsSealed(sealed) {
+		err = fmt.Errorf("%w, data is not sealed", ErrDecrypt)
+		return
+	}
+	data, err = c.aead.Open(nil, nil, sealed[len(sealedMagic):], additionalData)
+	if err != nil {
+		err = ErrDecrypt
+		return
+	}
+	return
+}
+
+// IsSealed reports whether data was sealed by a Cipher.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, sealedMagic)
+}
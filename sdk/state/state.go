@@ -0,0 +1,237 @@
+// Package state persists connector state (checkpoints, cursors, cached config...) on local disk,
+// optionally encrypted at rest.
+package state
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil)).WithGroup("state")
+
+var (
+	ErrNotFound   = errors.New("state key not found")
+	ErrInvalidKey = errors.New("invalid state key")
+	// ErrNotEncrypted is returned by encrypted stores for values found unencrypted once migrated, e.g. planted in
+	// store directory to bypass encryption.
+	ErrNotEncrypted = errors.New("state value is not encrypted")
+)
+
+// Store is a key/value store of connector state. Implementations MUST be thread-safe.
+type Store interface {
+	// Get returns ErrNotFound if key is not set
+	Get(key string) (value []byte, err error)
+	Put(key string, value []byte) (err error)
+	// Delete does not fail if key is not set
+	Delete(key string) (err error)
+	// Keys returns keys starting with prefix, sorted
+	Keys(prefix string) (keys []string, err error)
+}
+
+var _ Store = &FileStore{}
+
+type Config struct {
+	Dir string `mapstructure:"dir"`
+	// EncryptionKey is a base64 encoded AES-256 key. When set, values are encrypted at rest,
+	// and values written unencrypted are encrypted when store is opened.
+	EncryptionKey string `mapstructure:"encryption-key"`
+}
+
+const fileSuffix = ".state"
+
+// FileStore stores each key in a file of its directory, written atomically.
+type FileStore struct {
+	dir    string
+	cipher *Cipher
+	lock   sync.RWMutex
+}
+
+// Open opens (or creates) a FileStore from config.
+func Open(config Config) (s *FileStore, err error) {
+	var c *Cipher
+	if config.EncryptionKey != "" {
+		if c, err = NewCipherFromBase64(config.EncryptionKey); err != nil {
+			return
+		}
+	}
+	s, err = NewFileStore(config.Dir, c)
+	return
+}
+
+// NewFileStore opens (or creates) a FileStore in dir. If c is not nil, values are encrypted with it,
+// values previously written unencrypted are migrated (encrypted in place).
+func NewFileStore(dir string, c *Cipher) (s *FileStore, err error) {
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return
+	}
+	s = &FileStore{dir: dir, cipher: c}
+	if c == nil {
+		return
+	}
+	if err = s.migrate(); err != nil {
+		s = nil
+		return
+	}
+	return
+}
+
+func (s *FileStore) migrate() (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	keys, err := s.keys("")
+	if err != nil {
+		return
+	}
+	migrated := 0
+	for _, key := range keys {
+		raw, readErr := os.ReadFile(s.path(key))
+		if readErr != nil {
+			err = readErr
+			return
+		}
+		if IsSealed(raw) {
+			continue
+		}
+		if err = s.write(key, raw); err != nil {
+			err = fmt.Errorf("could not encrypt state %s, %w", key, err)
+			return
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		logger.Info("unencrypted state encrypted", slog.Int("keys", migrated))
+	}
+	return
+}
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, url.PathEscape(key)+fileSuffix)
+}
+
+func checkKey(key string) (err error) {
+	if key == "" {
+		err = fmt.Errorf("%w, key must not be empty", ErrInvalidKey)
+	}
+	return
+}
+
+func (s *FileStore) Get(key string) (value []byte, err error) {
+	if err = checkKey(key); err != nil {
+		return
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	raw, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		err = fmt.Errorf("%w: %s", ErrNotFound, key)
+		return
+	}
+	if err != nil {
+		return
+	}
+	if s.cipher == nil {
+		value = raw
+		return
+	}
+	if !IsSealed(raw) {
+		// values written before encryption was enabled are migrated on open
+		err = fmt.Errorf("%w: %s", ErrNotEncrypted, key)
+		return
+	}
+	value, err = s.cipher.Open(raw, []byte(key))
+	return
+}
+
+func (s *FileStore) Put(key string, value []byte) (err error) {
+	if err = checkKey(key); err != nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	err = s.write(key, value)
+	return
+}
+
+// MUST be used under Lock
+func (s *FileStore) write(key string, value []byte) (err error) {
+	if s.cipher != nil {
+		if value, err = s.cipher.Seal(value, []byte(key)); err != nil {
+			return
+		}
+	}
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(value); err != nil {
+		_ = tmp.Close()
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	err = os.Rename(tmp.Name(), s.path(key))
+	return
+}
+
+func (s *FileStore) Delete(key string) (err error) {
+	if err = checkKey(key); err != nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	err = os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+	}
+	return
+}
+
+// Encrypted reports whether values are encrypted at rest.
+func (s *FileStore) Encrypted() bool {
+	return s.cipher != nil
+}
+
+func (s *FileStore) Keys(prefix string) (keys []string, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	keys, err = s.keys(prefix)
+	return
+}
+
+// MUST be used under RLock
+func (s *FileStore) keys(prefix string) (keys []string, err error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), fileSuffix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		key, unescapeErr := url.PathUnescape(name)
+		if unescapeErr != nil || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return
+}
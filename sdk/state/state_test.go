@@ -0,0 +1,129 @@
+package state
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var testKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, KeySize))
+
+func TestFileStore(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{name: "plain"},
+		{name: "encrypted", key: testKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := Open(Config{Dir: dir, EncryptionKey: tt.key})
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if _, err = s.Get("missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() error = %v, want %v", err, ErrNotFound)
+			}
+			for key, value := range map[string]string{"cursor/site-1": "delta-1", "cursor/site-2": "delta-2", "config": `{"a":1}`} {
+				if err = s.Put(key, []byte(value)); err != nil {
+					t.Fatalf("Put(%s) error = %v", key, err)
+				}
+			}
+			got, err := s.Get("cursor/site-1")
+			if err != nil || string(got) != "delta-1" {
+				t.Errorf("Get() = %s, %v", got, err)
+			}
+			keys, err := s.Keys("cursor/")
+			if err != nil {
+				t.Fatalf("Keys() error = %v", err)
+			}
+			if diff := cmp.Diff(keys, []string{"cursor/site-1", "cursor/site-2"}); diff != "" {
+				t.Errorf("Keys() diff(got-want)=%s", diff)
+			}
+			if err = s.Delete("cursor/site-1"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if err = s.Delete("cursor/site-1"); err != nil {
+				t.Errorf("Delete() missing key error = %v", err)
+			}
+			if _, err = s.Get("cursor/site-1"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() deleted key error = %v", err)
+			}
+
+			raw, err := os.ReadFile(filepath.Join(dir, "config"+fileSuffix))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encrypted := tt.key != ""; IsSealed(raw) != encrypted || bytes.Contains(raw, []byte(`"a"`)) == encrypted {
+				t.Errorf("stored value %q, want encrypted %v", raw, encrypted)
+			}
+		})
+	}
+}
+
+func TestFileStore_migration(t *testing.T) {
+	dir := t.TempDir()
+	plain, err := NewFileStore(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = plain.Put("token", []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := Open(Config{Dir: dir, EncryptionKey: testKey})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "token"+fileSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(raw) {
+		t.Errorf("unencrypted value not migrated")
+	}
+	if got, err := encrypted.Get("token"); err != nil || string(got) != "secret" {
+		t.Errorf("Get() = %s, %v", got, err)
+	}
+
+	otherKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{8}, KeySize))
+	other, err := Open(Config{Dir: dir, EncryptionKey: otherKey})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err = other.Get("token"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Get() with other key error = %v, want %v", err, ErrDecrypt)
+	}
+
+	// planted once store is migrated
+	if err = os.WriteFile(filepath.Join(dir, "connector-id"+fileSuffix), []byte("attacker"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = encrypted.Get("connector-id"); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("Get() of unencrypted value error = %v, want %v", err, ErrNotEncrypted)
+	}
+}
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipherFromBase64(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := c.Seal([]byte("data"), []byte("key-a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Open(sealed, []byte("key-b")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open() with other additional data error = %v, want %v", err, ErrDecrypt)
+	}
+	if _, err = NewCipherFromBase64("c2hvcnQ="); !errors.Is(err, ErrInvalidEncryptionKey) {
+		t.Errorf("NewCipherFromBase64() error = %v, want %v", err, ErrInvalidEncryptionKey)
+	}
+}
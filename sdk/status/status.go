@@ -0,0 +1,145 @@
+// Package status aggregates the health of connector subsystems (e.g. webhook listener, scanner pool, uploader),
+// so connectors composed of several workers can report partial health.
+package status
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+type State string
+
+const (
+	OK       State = "ok"
+	Degraded State = "degraded"
+	Down     State = "down"
+)
+
+func (State) Values() []State {
+	return []State{OK, Degraded, Down}
+}
+
+type subsystem struct {
+	state   State
+	message string
+	since   time.Time
+}
+
+// Registry holds subsystems states. Its methods are thread-safe.
+type Registry struct {
+	lock       sync.Mutex
+	notifier   events.Notifier
+	subsystems map[string]subsystem
+	now        func() time.Time
+}
+
+// NewRegistry returns a registry notifying a StatusEvent when a subsystem changes state, notifier may be nil.
+func NewRegistry(notifier events.Notifier) (r *Registry) {
+	return &Registry{
+		notifier:   notifier,
+		subsystems: make(map[string]subsystem),
+		now:        time.Now,
+	}
+}
+
+// Report sets subsystem state. When state changes, a breakdown of subsystems states is notified,
+// returned error is the notification one (events.ErrUnsupportedEvent for managers not supporting status events).
+func (r *Registry) Report(ctx context.Context, name string, state State, message string) (err error) {
+	r.lock.Lock()
+	previous, ok := r.subsystems[name]
+	changed := !ok || previous.state != state
+	s := subsystem{state: state, message: message, since: previous.since}
+	if changed {
+		s.since = r.now()
+	}
+	r.subsystems[name] = s
+	event := r.event()
+	r.lock.Unlock()
+
+	if !changed || r.notifier == nil {
+		return
+	}
+	err = r.notifier.Notify(ctx, event)
+	return
+}
+
+// Remove removes a subsystem, e.g. a worker that is no longer run, and notifies the new breakdown.
+func (r *Registry) Remove(ctx context.Context, name string) (err error) {
+	r.lock.Lock()
+	_, ok := r.subsystems[name]
+	delete(r.subsystems, name)
+	event := r.event()
+	r.lock.Unlock()
+
+	if !ok || r.notifier == nil {
+		return
+	}
+	err = r.notifier.Notify(ctx, event)
+	return
+}
+
+// State aggregates subsystems states: Down if every subsystem is down, Degraded if any is not OK,
+// OK otherwise (including when no subsystem reported).
+func (r *Registry) State() (state State) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	state = r.state()
+	return
+}
+
+// Healthy reports whether aggregated state is OK.
+func (r *Registry) Healthy() bool {
+	return r.State() == OK
+}
+
+// Event returns current breakdown of subsystems states, sorted by name.
+func (r *Registry) Event() (event events.StatusEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	event = r.event()
+	return
+}
+
+func (r *Registry) state() (state State) {
+	state = OK
+	down := 0
+	for _, s := range r.subsystems {
+		switch s.state {
+		case OK:
+		case Down:
+			down++
+			state = Degraded
+		default:
+			state = Degraded
+		}
+	}
+	if down > 0 && down == len(r.subsystems) {
+		state = Down
+	}
+	return
+}
+
+func (r *Registry) event() (event events.StatusEvent) {
+	event = events.StatusEvent{
+		State:      string(r.state()),
+		Subsystems: make([]events.SubsystemStatus, 0, len(r.subsystems)),
+		Time:       r.now().Unix(),
+	}
+	for name, s := range r.subsystems {
+		event.Subsystems = append(event.Subsystems, events.SubsystemStatus{
+			Name:    name,
+			State:   string(s.state),
+			Message: s.message,
+			Since:   s.since.Unix(),
+		})
+	}
+	slices.SortFunc(event.Subsystems, func(a, b events.SubsystemStatus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return
+}
@@ -0,0 +1,121 @@
+package status
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+type notifierFunc func(ctx context.Context, event any) error
+
+func (f notifierFunc) Notify(ctx context.Context, event any) error { return f(ctx, event) }
+
+func TestRegistry(t *testing.T) {
+	type report struct {
+		name    string
+		state   State
+		message string
+		remove  bool
+	}
+	tests := []struct {
+		name       string
+		reports    []report
+		wantState  State
+		wantEvents []string
+		wantEvent  events.StatusEvent
+	}{
+		{
+			name:      "no subsystem",
+			wantState: OK,
+			wantEvent: events.StatusEvent{State: "ok", Subsystems: []events.SubsystemStatus{}, Time: 1738000000},
+		},
+		{
+			name: "all ok",
+			reports: []report{
+				{name: "webhook", state: OK},
+				{name: "scanner", state: OK},
+				{name: "scanner", state: OK, message: "4 workers"},
+			},
+			wantState:  OK,
+			wantEvents: []string{"ok", "ok"},
+			wantEvent: events.StatusEvent{State: "ok", Time: 1738000000, Subsystems: []events.SubsystemStatus{
+				{Name: "scanner", State: "ok", Message: "4 workers", Since: 1738000000},
+				{Name: "webhook", State: "ok", Since: 1738000000},
+			}},
+		},
+		{
+			name: "partially down",
+			reports: []report{
+				{name: "webhook", state: OK},
+				{name: "scanner", state: OK},
+				{name: "webhook", state: Down, message: "address already in use"},
+			},
+			wantState:  Degraded,
+			wantEvents: []string{"ok", "ok", "degraded"},
+			wantEvent: events.StatusEvent{State: "degraded", Time: 1738000000, Subsystems: []events.SubsystemStatus{
+				{Name: "scanner", State: "ok", Since: 1738000000},
+				{Name: "webhook", State: "down", Message: "address already in use", Since: 1738000000},
+			}},
+		},
+		{
+			name: "all down",
+			reports: []report{
+				{name: "webhook", state: Down},
+				{name: "scanner", state: Down},
+			},
+			wantState:  Down,
+			wantEvents: []string{"down", "down"},
+			wantEvent: events.StatusEvent{State: "down", Time: 1738000000, Subsystems: []events.SubsystemStatus{
+				{Name: "scanner", State: "down", Since: 1738000000},
+				{Name: "webhook", State: "down", Since: 1738000000},
+			}},
+		},
+		{
+			name: "degraded subsystem removed",
+			reports: []report{
+				{name: "webhook", state: OK},
+				{name: "uploader", state: Degraded},
+				{name: "uploader", remove: true},
+				{name: "unknown", remove: true},
+			},
+			wantState:  OK,
+			wantEvents: []string{"ok", "degraded", "ok"},
+			wantEvent: events.StatusEvent{State: "ok", Time: 1738000000, Subsystems: []events.SubsystemStatus{
+				{Name: "webhook", State: "ok", Since: 1738000000},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotEvents []string
+			r := NewRegistry(notifierFunc(func(ctx context.Context, event any) error {
+				gotEvents = append(gotEvents, event.(events.StatusEvent).State)
+				return nil
+			}))
+			r.now = func() time.Time { return time.Unix(1738000000, 0) }
+			for _, report := range tt.reports {
+				var err error
+				if report.remove {
+					err = r.Remove(t.Context(), report.name)
+				} else {
+					err = r.Report(t.Context(), report.name, report.state, report.message)
+				}
+				if err != nil {
+					t.Fatalf("Report() error = %v", err)
+				}
+			}
+			if got := r.State(); got != tt.wantState {
+				t.Errorf("State() = %s, want %s", got, tt.wantState)
+			}
+			if diff := cmp.Diff(gotEvents, tt.wantEvents); diff != "" {
+				t.Errorf("Report() notified states diff(got-want)=%s", diff)
+			}
+			if diff := cmp.Diff(r.Event(), tt.wantEvent); diff != "" {
+				t.Errorf("Event() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/validation"
+)
+
+// DefaultStopTimeout bounds wait for in-flight analyses on stop, when stop task sets no timeout.
+const DefaultStopTimeout = 30 * time.Second
+
+var (
+	ErrInvalidStop = errors.New("invalid stop task")
+	// ErrAnalysesStopped is wrapped by errors of AnalysisStopper.StopAnalyses when analyses are already stopped.
+	ErrAnalysesStopped = errors.New("analyses already stopped")
+)
+
+// StopPolicy is what becomes of in-flight analyses (queued or running submissions) when connector is stopped.
+type StopPolicy string
+
+const (
+	// StopAbandon cancels running analyses and drops queued ones
+	StopAbandon StopPolicy = "abandon"
+	// StopWait waits for queued and running analyses up to stop timeout, then abandons remaining ones
+	StopWait StopPolicy = "wait"
+	// StopRequeue cancels running analyses and keeps them, with queued ones, to run again on start
+	StopRequeue StopPolicy = "requeue"
+)
+
+func (StopPolicy) Values() []StopPolicy {
+	return []StopPolicy{StopAbandon, StopWait, StopRequeue}
+}
+
+// StopPolicyTag is the validator tag validating a StopPolicy.
+const StopPolicyTag = "stop_policy"
+
+func (StopPolicy) Validation() validation.EnumValidation {
+	return validation.NewEnumValidation(StopPolicy("").Values())
+}
+
+// StopReport tells what became of in-flight analyses on stop, sent back in stop task ack result.
+type StopReport struct {
+	Policy    StopPolicy `json:"policy"`
+	Completed int        `json:"completed" desc:"analyses finished while stopping"`
+	Abandoned int        `json:"abandoned" desc:"analyses canceled or dropped"`
+	Requeued  int        `json:"requeued" desc:"analyses kept to run again on start"`
+}
+
+// AnalysisStopper may be implemented by connectors running analyses in background (e.g. with an analysis.Pool),
+// for stop tasks to handle in-flight analyses per policy before connector is stopped. It is found through connector
+// wrappers (e.g. Watchdog, CompositeConnector), see ConnectorWrapper.
+type AnalysisStopper interface {
+	// StopAnalyses applies policy to in-flight analyses, waiting up to timeout with StopWait. It returns an error
+	// wrapping ErrAnalysesStopped if analyses are already stopped.
+	StopAnalyses(ctx context.Context, policy StopPolicy, timeout time.Duration) (report StopReport, err error)
+	// ResumeAnalyses runs analyses again (requeued ones first) once connector is started.
+	ResumeAnalyses()
+}
+
+// ConnectorWrapper is implemented by connectors wrapping another one (e.g. Watchdog, CompositeConnector), for
+// optional interfaces of the wrapped connector (e.g. AnalysisStopper) to be found.
+type ConnectorWrapper interface {
+	Unwrap() Connector
+}
+
+// asAnalysisStopper returns the AnalysisStopper of connector, or of a connector it wraps.
+func asAnalysisStopper(connector Connector) (stopper AnalysisStopper, ok bool) {
+	for connector != nil {
+		if stopper, ok = connector.(AnalysisStopper); ok {
+			return
+		}
+		wrapper, isWrapper := connector.(ConnectorWrapper)
+		if !isWrapper {
+			return
+		}
+		connector = wrapper.Unwrap()
+	}
+	return
+}
+
+// stopPolicy returns policy and timeout of a stop task, task content being optional.
+func stopPolicy(task Task) (policy StopPolicy, timeout time.Duration, err error) {
+	content := StopActionContent{}
+	if len(task.Content) > 0 && string(task.Content) != "null" {
+		if err = json.Unmarshal(task.Content, &content); err != nil {
+			err = fmt.Errorf("%w, %w", ErrInvalidStop, err)
+			return
+		}
+	}
+	validator, err := DefaultValidator()
+	if err != nil {
+		return
+	}
+	if err = validator.Validate(content); err != nil {
+		err = fmt.Errorf("%w, %w", ErrInvalidStop, err)
+		return
+	}
+	policy = content.Policy
+	if policy == "" {
+		policy = StopWait
+	}
+	timeout = time.Duration(content.Timeout) * time.Second
+	if timeout == 0 {
+		timeout = DefaultStopTimeout
+	}
+	return
+}
+
+// stop handles an ActionStop task: in-flight analyses of connector are handled per task policy, then connector is
+// stopped. Report is nil for connectors not implementing AnalysisStopper. Analyses already stopped (e.g. by a stop
+// task whose connector Stop failed) are not an error, for stop tasks to be retried.
+func stop(ctx context.Context, connector Connector, task Task) (report *StopReport, err error) {
+	policy, timeout, err := stopPolicy(task)
+	if err != nil {
+		return
+	}
+	if stopper, ok := asAnalysisStopper(connector); ok {
+		stopReport, stopErr := stopper.StopAnalyses(ctx, policy, timeout)
+		if errors.Is(stopErr, ErrAnalysesStopped) {
+			stopReport, stopErr = StopReport{Policy: policy}, nil
+		}
+		if stopErr != nil {
+			err = fmt.Errorf("could not stop analyses, %w", stopErr)
+			return
+		}
+		report = &stopReport
+	}
+	err = connector.Stop(ctx)
+	return
+}
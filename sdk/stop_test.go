@@ -0,0 +1,144 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/status"
+	"github.com/google/go-cmp/cmp"
+)
+
+type analysisStopper struct {
+	fakeConnector
+	policy  StopPolicy
+	timeout time.Duration
+	stopped bool
+	resumed bool
+	// stopErr is returned by StopAnalyses
+	stopErr error
+}
+
+func (s *analysisStopper) StopAnalyses(ctx context.Context, policy StopPolicy, timeout time.Duration) (report StopReport, err error) {
+	s.policy, s.timeout = policy, timeout
+	if err = s.stopErr; err != nil {
+		return
+	}
+	report = StopReport{Policy: policy, Completed: 1, Requeued: 2}
+	return
+}
+
+func (s *analysisStopper) ResumeAnalyses() {
+	s.resumed = true
+}
+
+func (s *analysisStopper) Stop(ctx context.Context) (err error) {
+	s.stopped = true
+	return
+}
+
+func TestStop(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantPolicy  StopPolicy
+		wantTimeout time.Duration
+		wantReport  *StopReport
+		wantErr     error
+	}{
+		{
+			name:        "default policy",
+			wantPolicy:  StopWait,
+			wantTimeout: DefaultStopTimeout,
+			wantReport:  &StopReport{Policy: StopWait, Completed: 1, Requeued: 2},
+		},
+		{
+			name:        "wait up to timeout",
+			content:     `{"policy":"wait","timeout":5}`,
+			wantPolicy:  StopWait,
+			wantTimeout: 5 * time.Second,
+			wantReport:  &StopReport{Policy: StopWait, Completed: 1, Requeued: 2},
+		},
+		{
+			name:        "requeue",
+			content:     `{"policy":"requeue"}`,
+			wantPolicy:  StopRequeue,
+			wantTimeout: DefaultStopTimeout,
+			wantReport:  &StopReport{Policy: StopRequeue, Completed: 1, Requeued: 2},
+		},
+		{
+			name:    "unknown policy",
+			content: `{"policy":"later"}`,
+			wantErr: ErrInvalidStop,
+		},
+		{
+			name:    "negative timeout",
+			content: `{"policy":"wait","timeout":-1}`,
+			wantErr: ErrInvalidStop,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connector := &analysisStopper{}
+			report, err := stop(t.Context(), connector, Task{Action: ActionStop, Content: json.RawMessage(tt.content)})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("stop() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if connector.stopped {
+					t.Errorf("connector stopped on invalid stop task")
+				}
+				return
+			}
+			if connector.policy != tt.wantPolicy || connector.timeout != tt.wantTimeout {
+				t.Errorf("StopAnalyses() called with %s, %s, want %s, %s", connector.policy, connector.timeout, tt.wantPolicy, tt.wantTimeout)
+			}
+			if diff := cmp.Diff(report, tt.wantReport); diff != "" {
+				t.Errorf("stop() diff(got-want)=%s", diff)
+			}
+			if !connector.stopped {
+				t.Errorf("connector not stopped")
+			}
+		})
+	}
+
+	t.Run("wrapped connector", func(t *testing.T) {
+		connector := &analysisStopper{}
+		wrapped := NewWatchdog(NewCompositeConnector(connector, status.NewRegistry(nil)), events.NoopEventHandler{}, WatchdogOptions{})
+		report, err := stop(t.Context(), wrapped, Task{Action: ActionStop})
+		if err != nil {
+			t.Fatalf("stop() error = %v", err)
+		}
+		if diff := cmp.Diff(report, &StopReport{Policy: StopWait, Completed: 1, Requeued: 2}); diff != "" {
+			t.Errorf("stop() diff(got-want)=%s", diff)
+		}
+		if !connector.stopped {
+			t.Errorf("connector not stopped")
+		}
+	})
+
+	t.Run("analyses already stopped", func(t *testing.T) {
+		connector := &analysisStopper{stopErr: fmt.Errorf("pool stopped, %w", ErrAnalysesStopped)}
+		report, err := stop(t.Context(), connector, Task{Action: ActionStop})
+		if err != nil {
+			t.Fatalf("stop() error = %v", err)
+		}
+		if diff := cmp.Diff(report, &StopReport{Policy: StopWait}); diff != "" {
+			t.Errorf("stop() diff(got-want)=%s", diff)
+		}
+		if !connector.stopped {
+			t.Errorf("connector not stopped")
+		}
+	})
+
+	t.Run("connector without analyses", func(t *testing.T) {
+		report, err := stop(t.Context(), &fakeConnector{}, Task{Action: ActionStop})
+		if err != nil || report != nil {
+			t.Errorf("stop() = %v, %v, want nil report", report, err)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+const (
+	DailySummary              = 24 * time.Hour
+	WeeklySummary             = 7 * DailySummary
+	DefaultSummaryTopMalwares = 10
+)
+
+type SummaryOptions struct {
+	// Period between two summaries, DailySummary if 0. Periods end on UTC boundaries of Period
+	// (midnight for DailySummary, monday midnight for WeeklySummary), first one starts when reports are started.
+	Period time.Duration
+	// TopMalwares is the number of malware families reported, DefaultSummaryTopMalwares if 0.
+	TopMalwares int
+}
+
+// RunSummaryReports pushes a SummaryEvent, built from metrics collected by the SDK, at the end of each period
+// until ctx is done. A summary that could not be pushed is merged into the next one.
+func (c ConnectorManagerClient) RunSummaryReports(ctx context.Context, opts SummaryOptions) {
+	if opts.Period <= 0 {
+		opts.Period = DailySummary
+	}
+	if opts.TopMalwares == 0 {
+		opts.TopMalwares = DefaultSummaryTopMalwares
+	}
+	start := time.Now()
+	end := start.Truncate(opts.Period).Add(opts.Period)
+	for {
+		timer := time.NewTimer(time.Until(end))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		err := c.pushSummary(ctx, start, end, opts.TopMalwares)
+		switch {
+		case errors.Is(err, events.ErrUnsupportedEvent):
+			logger.Warn("manager does not support summary events, summary dropped")
+			start = end
+		case err != nil:
+			logger.Error("could not push summary, it will be merged into next one", slog.String("error", err.Error()))
+		default:
+			start = end
+		}
+		end = end.Add(opts.Period)
+	}
+}
+
+func (c ConnectorManagerClient) pushSummary(ctx context.Context, start time.Time, end time.Time, topMalwares int) (err error) {
+	summary := c.metricsCollector.GetAndResetSummary()
+	err = c.Notify(ctx, events.NewSummaryEvent(start, end, summary, topMalwares))
+	if err != nil && !errors.Is(err, events.ErrUnsupportedEvent) {
+		c.metricsCollector.RestoreSummary(summary)
+		return
+	}
+	return
+}
@@ -0,0 +1,63 @@
+package sdk
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+func TestConnectorManagerClient_pushSummary(t *testing.T) {
+	tests := []struct {
+		name          string
+		schemaVersion int
+		failOn        string
+		wantErr       bool
+		wantEvents    int
+		wantRestored  int64
+	}{
+		{
+			name:          "ok",
+			schemaVersion: events.SchemaVersionCurrent,
+			wantEvents:    1,
+		},
+		{
+			name:          "manager error",
+			schemaVersion: events.SchemaVersionCurrent,
+			failOn:        "events",
+			wantErr:       true,
+			wantRestored:  1,
+		},
+		{
+			name:          "legacy manager",
+			schemaVersion: events.SchemaVersionLegacy,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &fakeManager{failOn: tt.failOn}
+			server := httptest.NewServer(manager.handler(t))
+			defer server.Close()
+			c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"})
+			c.schemaVersion.Store(int64(tt.schemaVersion))
+			c.metricsCollector.AddItemProcessed(10)
+
+			end := time.Now()
+			err := c.pushSummary(t.Context(), end.Add(-DailySummary), end, DefaultSummaryTopMalwares)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pushSummary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(manager.events) != tt.wantEvents {
+				t.Fatalf("pushSummary() pushed %d events, want %d", len(manager.events), tt.wantEvents)
+			}
+			if tt.wantEvents > 0 && manager.events[0].EventType != events.Summary {
+				t.Errorf("pushSummary() pushed %s event", manager.events[0].EventType)
+			}
+			if got := c.metricsCollector.GetAndResetSummary().ItemsProcessed; got != tt.wantRestored {
+				t.Errorf("pushSummary() restored %d items processed, want %d", got, tt.wantRestored)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ type Task struct {
 	ErrorMessage string          `json:"error_message"`
 	OriginalID   string          `json:"original_id"`
 	Content      json.RawMessage `json:"content,omitempty"`
+	RequestID    string          `json:"request_id,omitempty" desc:"set by manager, or X-Request-Id of the request that fetched task"`
 }
 
 type ActionType string
@@ -27,10 +28,13 @@ const (
 	ActionStop         ActionType = "stop"
 	ActionStart        ActionType = "start"
 	ActionRestore      ActionType = "restore"
+	ActionMigrate      ActionType = "migrate"
+	// ActionGetEffectiveConfig asks connector the config it actually runs, sent back in task ack result
+	ActionGetEffectiveConfig ActionType = "get-effective-config"
 )
 
 func (ActionType) Values() []ActionType {
-	return []ActionType{ActionUpdateConfig, ActionStop, ActionStart, ActionRestore}
+	return []ActionType{ActionUpdateConfig, ActionStop, ActionStart, ActionRestore, ActionMigrate, ActionGetEffectiveConfig}
 }
 
 // TaskActionTag is the validator tag validating an ActionType.
@@ -40,10 +44,41 @@ func (ActionType) Validation() validation.EnumValidation {
 	return validation.NewEnumValidation(ActionType("").Values())
 }
 
+// TaskPriority classifies tasks in the client queue: control tasks are handled before bulk ones.
+type TaskPriority int
+
+const (
+	// ControlPriority tasks (start, stop, config...) act on connector itself and must stay responsive
+	ControlPriority TaskPriority = iota
+	// BulkPriority tasks (restore) act on items and may come in large numbers
+	BulkPriority
+)
+
+// Priority returns priority of task action, unknown actions are bulk.
+func (a ActionType) Priority() (priority TaskPriority) {
+	switch a {
+	case ActionUpdateConfig, ActionStop, ActionStart, ActionMigrate, ActionGetEffectiveConfig:
+		priority = ControlPriority
+	default:
+		priority = BulkPriority
+	}
+	return
+}
+
 type RestoreActionContent struct {
 	ID string `json:"id" desc:"required"`
 }
 
+type MigrateActionContent struct {
+	URL             string `json:"url" desc:"required, URL of the new connector manager"`
+	EnrollmentToken string `json:"enrollment_token" desc:"required, one-time token issued by the new connector manager"`
+}
+
+type StopActionContent struct {
+	Policy  StopPolicy `json:"policy,omitempty" validate:"omitempty,stop_policy" desc:"abandon, wait or requeue in-flight analyses, wait if empty"`
+	Timeout int        `json:"timeout,omitempty" validate:"min=0" desc:"seconds to wait for in-flight analyses with wait policy, 30 if 0"`
+}
+
 type TaskStatus string
 
 const (
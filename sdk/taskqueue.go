@@ -0,0 +1,138 @@
+package sdk
+
+import (
+	"context"
+	"sync"
+)
+
+// controlBurst is the number of control tasks handled in a row while bulk tasks wait, so bulk tasks are not starved.
+const controlBurst = 4
+
+// taskQueue is a bounded queue of tasks received from manager, waiting to be handled by connector.
+// Tasks are queued in one lane per TaskPriority, each lane holding up to size tasks: control tasks preempt bulk ones,
+// except one bulk task is handled every controlBurst control tasks.
+// Producer blocks while a lane is full, so tasks stay on manager rather than piling up in memory.
+// A queued update-config task is superseded by a newer one: config is fetched when task is handled,
+// so only the latest one is useful, and it keeps its position so updates are not executed in stale order.
+type taskQueue struct {
+	lock          sync.Mutex
+	lanes         [BulkPriority + 1][]Task
+	size          int
+	controlStreak int // control tasks popped in a row while bulk tasks were queued
+	closed        bool
+	// signaled (non blocking send) when a task is pushed, or when a task is popped
+	pushed chan struct{}
+	popped chan struct{}
+}
+
+func newTaskQueue(size int) (q *taskQueue) {
+	if size <= 0 {
+		size = DefaultTaskQueueSize
+	}
+	return &taskQueue{
+		size:   size,
+		pushed: make(chan struct{}, 1),
+		popped: make(chan struct{}, 1),
+	}
+}
+
+// push queues task, blocking while its lane is full until ctx is done. full reports whether push had to wait,
+// superseded is the queued update-config task replaced by task, if any.
+func (q *taskQueue) push(ctx context.Context, task Task) (superseded *Task, full bool, err error) {
+	priority := task.Action.Priority()
+	for {
+		q.lock.Lock()
+		lane := q.lanes[priority]
+		if task.Action == ActionUpdateConfig {
+			for i, queued := range lane {
+				if queued.Action == ActionUpdateConfig {
+					superseded = &queued
+					lane[i] = task
+					q.lock.Unlock()
+					signal(q.pushed)
+					return
+				}
+			}
+		}
+		if len(lane) < q.size {
+			q.lanes[priority] = append(lane, task)
+			q.lock.Unlock()
+			signal(q.pushed)
+			return
+		}
+		q.lock.Unlock()
+		full = true
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-q.popped:
+		}
+	}
+}
+
+// pop returns next task, blocking until a task is queued. ok is false once ctx is done,
+// or queue is closed and empty.
+func (q *taskQueue) pop(ctx context.Context) (task Task, ok bool) {
+	for {
+		q.lock.Lock()
+		if task, ok = q.next(); ok {
+			q.lock.Unlock()
+			signal(q.popped)
+			return
+		}
+		closed := q.closed
+		q.lock.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.pushed:
+		}
+	}
+}
+
+// next pops next task, q.lock must be held.
+func (q *taskQueue) next() (task Task, ok bool) {
+	control, bulk := q.lanes[ControlPriority], q.lanes[BulkPriority]
+	switch {
+	case len(control) > 0 && (len(bulk) == 0 || q.controlStreak < controlBurst):
+		task, q.lanes[ControlPriority] = control[0], control[1:]
+		if len(bulk) > 0 {
+			q.controlStreak++
+		}
+	case len(bulk) > 0:
+		task, q.lanes[BulkPriority] = bulk[0], bulk[1:]
+		q.controlStreak = 0
+	default:
+		return
+	}
+	ok = true
+	return
+}
+
+// close stops producer side, queued tasks can still be popped.
+func (q *taskQueue) close() {
+	q.lock.Lock()
+	q.closed = true
+	q.lock.Unlock()
+	signal(q.pushed)
+}
+
+func (q *taskQueue) len() (n int) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for _, lane := range q.lanes {
+		n += len(lane)
+	}
+	return
+}
+
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,180 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTaskQueue(t *testing.T) {
+	tests := []struct {
+		name           string
+		size           int
+		push           []Task
+		wantSuperseded []string
+		wantFull       bool
+		wantPopped     []string
+	}{
+		{
+			name:       "fifo",
+			size:       3,
+			push:       []Task{{ID: "1", Action: ActionStop}, {ID: "2", Action: ActionStart}},
+			wantPopped: []string{"1", "2"},
+		},
+		{
+			name: "update-config superseded",
+			size: 3,
+			push: []Task{
+				{ID: "1", Action: ActionUpdateConfig},
+				{ID: "2", Action: ActionStop},
+				{ID: "3", Action: ActionUpdateConfig},
+				{ID: "4", Action: ActionUpdateConfig},
+			},
+			wantSuperseded: []string{"1", "3"},
+			wantPopped:     []string{"4", "2"},
+		},
+		{
+			name:       "control preempts bulk",
+			size:       3,
+			push:       []Task{{ID: "r1", Action: ActionRestore}, {ID: "r2", Action: ActionRestore}, {ID: "s1", Action: ActionStop}},
+			wantPopped: []string{"s1", "r1", "r2"},
+		},
+		{
+			name: "bulk not starved",
+			size: 10,
+			push: []Task{
+				{ID: "r1", Action: ActionRestore},
+				{ID: "c1", Action: ActionStop},
+				{ID: "c2", Action: ActionStart},
+				{ID: "c3", Action: ActionStop},
+				{ID: "c4", Action: ActionStart},
+				{ID: "c5", Action: ActionStop},
+				{ID: "c6", Action: ActionStart},
+				{ID: "r2", Action: ActionRestore},
+			},
+			wantPopped: []string{"c1", "c2", "c3", "c4", "r1", "c5", "c6", "r2"},
+		},
+		{
+			name:       "full bulk lane",
+			size:       1,
+			push:       []Task{{ID: "r1", Action: ActionRestore}, {ID: "r2", Action: ActionRestore}, {ID: "s1", Action: ActionStop}},
+			wantFull:   true,
+			wantPopped: []string{"s1", "r1"},
+		},
+		{
+			name:       "full",
+			size:       1,
+			push:       []Task{{ID: "1", Action: ActionStop}, {ID: "2", Action: ActionStart}},
+			wantFull:   true,
+			wantPopped: []string{"1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTaskQueue(tt.size)
+			var gotSuperseded []string
+			var gotFull bool
+			for _, task := range tt.push {
+				ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+				superseded, full, err := q.push(ctx, task)
+				cancel()
+				if full != (err != nil) {
+					t.Fatalf("push() full = %v, error = %v", full, err)
+				}
+				gotFull = gotFull || full
+				if superseded != nil {
+					gotSuperseded = append(gotSuperseded, superseded.ID)
+				}
+			}
+			q.close()
+			var gotPopped []string
+			for {
+				task, ok := q.pop(t.Context())
+				if !ok {
+					break
+				}
+				gotPopped = append(gotPopped, task.ID)
+			}
+			if gotFull != tt.wantFull {
+				t.Errorf("push() full = %v, want %v", gotFull, tt.wantFull)
+			}
+			if diff := cmp.Diff(gotSuperseded, tt.wantSuperseded); diff != "" {
+				t.Errorf("push() superseded diff(got-want)=%s", diff)
+			}
+			if diff := cmp.Diff(gotPopped, tt.wantPopped); diff != "" {
+				t.Errorf("pop() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestTaskQueue_backpressure(t *testing.T) {
+	q := newTaskQueue(1)
+	if _, _, err := q.push(t.Context(), Task{ID: "1"}); err != nil {
+		t.Fatalf("push() error = %v", err)
+	}
+	pushed := make(chan bool)
+	go func() {
+		_, full, err := q.push(t.Context(), Task{ID: "2"})
+		pushed <- full && err == nil
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("push() did not wait for queue to have room")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if task, ok := q.pop(t.Context()); !ok || task.ID != "1" {
+		t.Fatalf("pop() = %v, %v, want task 1", task, ok)
+	}
+	if ok := <-pushed; !ok {
+		t.Error("push() full = false or error, want waited push")
+	}
+}
+
+func TestConnectorManagerClient_queueTask(t *testing.T) {
+	var acks []events.TaskEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		envelope := events.Envelope{}
+		if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+			t.Errorf("could not decode event, error: %v", err)
+		}
+		var ack events.TaskEvent
+		if err := json.Unmarshal(envelope.Event, &ack); err != nil {
+			t.Errorf("could not decode task event, error: %v", err)
+		}
+		acks = append(acks, ack)
+	}))
+	defer server.Close()
+
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"})
+	q := newTaskQueue(2)
+	for _, task := range []Task{{ID: "1", Action: ActionUpdateConfig, RequestID: "req-1"}, {ID: "2", Action: ActionUpdateConfig}} {
+		if err := c.queueTask(t.Context(), q, task); err != nil {
+			t.Fatalf("queueTask() error = %v", err)
+		}
+	}
+	want := []events.TaskEvent{{TaskID: "1", Result: map[string]any{"superseded_by": "2"}, RequestID: "req-1"}}
+	if diff := cmp.Diff(acks, want); diff != "" {
+		t.Errorf("queueTask() acks diff(got-want)=%s", diff)
+	}
+	if got := c.metricsCollector.Client().Snapshot().TasksSuperseded; got != 1 {
+		t.Errorf("queueTask() superseded metric = %d, want 1", got)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_ = c.queueTask(ctx, q, Task{ID: "3", Action: ActionStop})
+	if err := c.queueTask(ctx, q, Task{ID: "4", Action: ActionStop}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("queueTask() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := c.metricsCollector.Client().Snapshot().TasksOverflow; got != 1 {
+		t.Errorf("queueTask() overflow metric = %d, want 1", got)
+	}
+}
@@ -0,0 +1,105 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+)
+
+const (
+	taskStreamPath = "tasks/stream"
+	// taskStreamMediaType is the media type of task streams: one Task JSON object per line, empty objects being
+	// keep-alives.
+	taskStreamMediaType = "application/x-ndjson"
+	// taskStreamWindow bounds task streams, so metrics are still pushed between them.
+	taskStreamWindow = time.Minute
+	// taskStreamRetryInterval is the delay tasks are polled for before a stream is opened again, once it could not be.
+	taskStreamRetryInterval = 5 * time.Minute
+)
+
+var (
+	errTaskStreamUnavailable = errors.New("task stream unavailable")
+	errTaskStreamClosed      = errors.New("task stream closed early")
+)
+
+// streamTasks queues tasks pushed by manager on a task stream, until taskStreamWindow elapses. It fails with
+// errTaskStreamUnavailable if stream could not be opened (e.g. manager without streaming support), for tasks to be
+// polled instead, and with errTaskStreamClosed if stream ended before, for streams to be opened again after a back off.
+// Opening a stream goes through the circuit breaker, as other requests to manager (ErrCircuitOpen).
+func (c ConnectorManagerClient) streamTasks(ctx context.Context, queue *taskQueue) (err error) {
+	streamCtx, cancel := context.WithTimeout(ctx, taskStreamWindow)
+	defer cancel()
+	req, err := c.prepareRequest(streamCtx, c.endpoint.Load(), http.MethodGet, taskStreamPath, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Accept", taskStreamMediaType)
+	if err = c.breaker.allow(); err != nil {
+		return
+	}
+	start := time.Now()
+	resp, err := c.httpClient.Do(req) //nolint:gosec // Base URL from client config, not user input
+	if err != nil {
+		c.breaker.done(err)
+		c.metricsCollector.Client().ObserveRequest(taskStreamPath, 0, time.Since(start))
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			return
+		}
+		err = fmt.Errorf("%w, %w", errTaskStreamUnavailable, err)
+		return
+	}
+	c.metricsCollector.Client().ObserveRequest(taskStreamPath, resp.StatusCode, time.Since(start))
+	// statuses retried by other requests are manager failures too
+	c.breaker.done(c.retry.retried(resp))
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			logger.Warn("could not close task stream properly", slog.String("error", e.Error()))
+		}
+	}()
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		if auth, ok := c.endpoint.Load().auth.(CachingAuthenticator); ok {
+			auth.Invalidate()
+		}
+		err = errors.Join(ErrUnauthorizedConnector, NewHTTPError(resp.StatusCode, body))
+		return
+	case resp.StatusCode != http.StatusOK:
+		err = fmt.Errorf("%w, status %d", errTaskStreamUnavailable, resp.StatusCode)
+		return
+	case mediaType != taskStreamMediaType:
+		err = fmt.Errorf("%w, content type %q", errTaskStreamUnavailable, mediaType)
+		return
+	}
+	c.unauthorized.reset()
+	logger.Debug("task stream opened")
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var task Task
+		if decodeErr := decoder.Decode(&task); decodeErr != nil {
+			if streamCtx.Err() == nil {
+				err = fmt.Errorf("%w, %w", errTaskStreamClosed, decodeErr)
+			}
+			return
+		}
+		if task.ID == "" {
+			// keep-alive
+			continue
+		}
+		if task.RequestID == "" {
+			task.RequestID = generateReqID()
+		}
+		if err = c.queueTask(ctx, queue, task); err != nil {
+			return
+		}
+	}
+}
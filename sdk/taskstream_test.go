@@ -0,0 +1,196 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConnectorManagerClient_streamTasks(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantTasks   []string
+		wantErr     error
+	}{
+		{
+			name:        "streamed",
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson; charset=utf-8",
+			body:        "{}\n{\"id\":\"task-1\",\"action\":\"start\",\"request_id\":\"req-1\"}\n{}\n{\"id\":\"task-2\",\"action\":\"stop\"}\n",
+			wantTasks:   []string{"task-1", "task-2"},
+			wantErr:     errTaskStreamClosed,
+		},
+		{
+			name:        "interrupted",
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson",
+			body:        "{\"id\":\"task-1\",\"action\":\"start\"}\n{\"id\":",
+			wantTasks:   []string{"task-1"},
+			wantErr:     errTaskStreamClosed,
+		},
+		{name: "closed", status: http.StatusOK, contentType: "application/x-ndjson", wantErr: errTaskStreamClosed},
+		{name: "not supported", status: http.StatusNotFound, wantErr: errTaskStreamUnavailable},
+		{name: "not a stream", status: http.StatusOK, contentType: "application/json", body: `{"tasks":[]}`, wantErr: errTaskStreamUnavailable},
+		{name: "unauthorized", status: http.StatusUnauthorized, body: `{"code":"invalid_api_key"}`, wantErr: ErrUnauthorizedConnector},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != basePath+"/tasks/stream" || r.Header.Get("Accept") != taskStreamMediaType {
+					t.Errorf("unexpected request %s, accept %s", r.URL.Path, r.Header.Get("Accept"))
+				}
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key"})
+			queue := newTaskQueue(0)
+			err := c.streamTasks(t.Context(), queue)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("streamTasks() error = %v, want %v", err, tt.wantErr)
+			}
+			var got []string
+			for queue.len() > 0 {
+				task, _ := queue.pop(t.Context())
+				if task.RequestID == "" {
+					t.Errorf("streamTasks() task %s has no request id", task.ID)
+				}
+				got = append(got, task.ID)
+			}
+			if diff := cmp.Diff(got, tt.wantTasks); diff != "" {
+				t.Errorf("streamTasks() tasks diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectorManagerClient_streamTasks_breaker(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{
+		URL:                  server.URL,
+		APIKey:               "key",
+		BreakerThreshold:     2,
+		BreakerProbeInterval: time.Hour,
+	})
+	var gotErrs []error
+	for range 3 {
+		gotErrs = append(gotErrs, c.streamTasks(t.Context(), newTaskQueue(0)))
+	}
+	if !errors.Is(gotErrs[1], errTaskStreamUnavailable) || !errors.Is(gotErrs[2], ErrCircuitOpen) {
+		t.Fatalf("streamTasks() errors = %v, want circuit open on third stream", gotErrs)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("manager calls = %d, want 2", got)
+	}
+	if got := c.BreakerState(); got != BreakerOpen {
+		t.Errorf("BreakerState() = %s, want %s", got, BreakerOpen)
+	}
+}
+
+func TestConnectorManagerClient_tasks_stream(t *testing.T) {
+	tests := []struct {
+		name        string
+		streaming   bool
+		wantStreams int32
+		wantPolled  bool
+	}{
+		{name: "streamed", streaming: true, wantStreams: 1},
+		{name: "fallback to polling", wantStreams: 1, wantPolled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var streams, polls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case basePath + "/tasks/stream":
+					if streams.Add(1) > 1 || !tt.streaming {
+						http.NotFound(w, r)
+						return
+					}
+					w.Header().Set("Content-Type", taskStreamMediaType)
+					_, _ = w.Write([]byte("{\"id\":\"task-1\",\"action\":\"start\"}\n"))
+					w.(http.Flusher).Flush()
+					// stream stays open until connector is done
+					<-r.Context().Done()
+				case basePath + "/tasks":
+					if polls.Add(1) > 1 {
+						// long polling
+						time.Sleep(10 * time.Millisecond)
+						_, _ = w.Write([]byte(`{"tasks":[]}`))
+						return
+					}
+					_, _ = w.Write([]byte(`{"tasks":[{"id":"task-1","action":"start"}]}`))
+				}
+			}))
+			defer server.Close()
+			// open stream is closed before server
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			c := NewConnectorManagerClient(ctx, ConnectorManagerClientConfig{URL: server.URL, APIKey: "key", TaskStream: true})
+			queue := c.tasks(ctx)
+			popCtx, popCancel := context.WithTimeout(ctx, 5*time.Second)
+			defer popCancel()
+			task, ok := queue.pop(popCtx)
+			if !ok || task.ID != "task-1" {
+				t.Fatalf("tasks() task = %+v, %v, want task-1", task, ok)
+			}
+			if got := streams.Load(); got != tt.wantStreams {
+				t.Errorf("tasks() streams = %d, want %d", got, tt.wantStreams)
+			}
+			if got := polls.Load() > 0; got != tt.wantPolled {
+				t.Errorf("tasks() polled = %v, want %v", got, tt.wantPolled)
+			}
+		})
+	}
+}
+
+func TestConnectorManagerClient_tasks_streamClosed(t *testing.T) {
+	var streams, metrics atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case basePath + "/tasks/stream":
+			// stream closed as soon as opened
+			streams.Add(1)
+			w.Header().Set("Content-Type", taskStreamMediaType)
+		case basePath + "/metrics":
+			metrics.Add(1)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	c := NewConnectorManagerClient(ctx, ConnectorManagerClientConfig{URL: server.URL, APIKey: "key", TaskStream: true})
+	c.tasks(ctx)
+	// streams are opened again after a back off, of at least half DefaultPollErrorBackoff
+	time.Sleep(DefaultPollErrorBackoff / 4)
+	if got := streams.Load(); got != 1 {
+		t.Errorf("tasks() streams = %d, want 1", got)
+	}
+	if got := metrics.Load(); got > 1 {
+		t.Errorf("tasks() metrics pushes = %d, want at most 1", got)
+	}
+}
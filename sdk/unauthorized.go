@@ -0,0 +1,116 @@
+package sdk
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+const (
+	DefaultUnauthorizedBackoff    = 5 * time.Second
+	maxUnauthorizedBackoff        = 5 * time.Minute
+	unauthorizedBackoffRandomness = 0.5
+)
+
+// UnauthorizedHandler is called when connector gives up after manager kept answering unauthorized.
+// It returns true to keep running, e.g. once it got new credentials (see ConnectorManagerClient.SetAPIKey),
+// false to stop handling tasks (ConnectorManagerClient.Start returns).
+type UnauthorizedHandler func(ctx context.Context, err error) (retry bool)
+
+// unauthorizedPolicy tolerates unauthorized responses in a row, e.g. during api key rotation.
+type unauthorizedPolicy struct {
+	retries  int
+	backoff  time.Duration
+	attempts atomic.Int64 // unauthorized responses in a row
+	lock     sync.Mutex
+	handler  UnauthorizedHandler
+	sleep    func(ctx context.Context, d time.Duration) (err error)
+}
+
+func newUnauthorizedPolicy(retries int, backoff time.Duration) (p *unauthorizedPolicy) {
+	if backoff <= 0 {
+		backoff = DefaultUnauthorizedBackoff
+	}
+	return &unauthorizedPolicy{retries: retries, backoff: backoff, sleep: sleepContext}
+}
+
+func (p *unauthorizedPolicy) reset() {
+	p.attempts.Store(0)
+}
+
+// wait returns the wait before retry number attempt (from 1): backoff doubled on each retry, randomized by ±50%.
+func (p *unauthorizedPolicy) wait(attempt int64) (d time.Duration) {
+	d = p.backoff
+	for range attempt - 1 {
+		d *= 2
+		if d >= maxUnauthorizedBackoff {
+			d = maxUnauthorizedBackoff
+			break
+		}
+	}
+	jitter := (rand.Float64()*2 - 1) * unauthorizedBackoffRandomness //nolint:gosec // jitter does not need a secure random
+	d += time.Duration(float64(d) * jitter)
+	return
+}
+
+// SetUnauthorizedHandler sets the handler called when connector gives up after unauthorized responses.
+func (c ConnectorManagerClient) SetUnauthorizedHandler(handler UnauthorizedHandler) {
+	c.unauthorized.lock.Lock()
+	defer c.unauthorized.lock.Unlock()
+	c.unauthorized.handler = handler
+}
+
+// SetAPIKey replaces console api key, e.g. from an UnauthorizedHandler once connector got new credentials.
+func (c ConnectorManagerClient) SetAPIKey(apiKey string) {
+	c.endpoint.Store(apiKeyEndpoint(c.endpoint.Load().url, apiKey))
+}
+
+// giveUpUnauthorized handles an unauthorized response: it waits before a retry while retries are left,
+// then notifies an unauthorized-connector error and calls UnauthorizedHandler. giveUp is true when
+// connector must stop handling tasks.
+func (c ConnectorManagerClient) giveUpUnauthorized(ctx context.Context, err error) (giveUp bool) {
+	p := c.unauthorized
+	attempt := p.attempts.Add(1)
+	if attempt <= int64(p.retries) {
+		wait := p.wait(attempt)
+		logger.Warn("connector unauthorized by manager, retry", slog.Int64("attempt", attempt), slog.Int("retries", p.retries), slog.String("wait", wait.String()))
+		giveUp = p.sleep(ctx, wait) != nil
+		return
+	}
+
+	logger.Error("connector unauthorized by manager, give up", slog.Int64("attempts", attempt), slog.String("error", err.Error()))
+	// best effort, manager is likely to refuse it as well
+	notifyErr := c.Notify(ctx, events.ErrorEvent{
+		Error: err.Error(),
+		Type:  events.UnauthorizedConnectorError,
+		Time:  time.Now().Unix(),
+	})
+	if notifyErr != nil {
+		logger.Debug("could not notify unauthorized connector", slog.String("error", notifyErr.Error()))
+	}
+	p.lock.Lock()
+	handler := p.handler
+	p.lock.Unlock()
+	if handler == nil || !handler(ctx, err) {
+		giveUp = true
+		return
+	}
+	p.reset()
+	return
+}
+
+func sleepContext(ctx context.Context, d time.Duration) (err error) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timer.C:
+	}
+	return
+}
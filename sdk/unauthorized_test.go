@@ -0,0 +1,194 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConnectorManagerClient_giveUpUnauthorized(t *testing.T) {
+	tests := []struct {
+		name         string
+		retries      int
+		handler      func(c ConnectorManagerClient) UnauthorizedHandler
+		calls        int
+		wantGiveUp   []bool
+		wantSleeps   int
+		wantHandlers int
+	}{
+		{
+			name:       "no retry",
+			calls:      1,
+			wantGiveUp: []bool{true},
+		},
+		{
+			name:       "retries exhausted",
+			retries:    2,
+			calls:      3,
+			wantGiveUp: []bool{false, false, true},
+			wantSleeps: 2,
+		},
+		{
+			name:    "handler sets new credentials",
+			retries: 1,
+			handler: func(c ConnectorManagerClient) UnauthorizedHandler {
+				return func(ctx context.Context, err error) bool {
+					c.SetAPIKey("new-key")
+					return true
+				}
+			},
+			calls:        4,
+			wantGiveUp:   []bool{false, false, false, false},
+			wantSleeps:   2,
+			wantHandlers: 2,
+		},
+		{
+			name: "handler exits",
+			handler: func(c ConnectorManagerClient) UnauthorizedHandler {
+				return func(ctx context.Context, err error) bool { return false }
+			},
+			calls:        1,
+			wantGiveUp:   []bool{true},
+			wantHandlers: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAuthorizations []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAuthorizations = append(gotAuthorizations, r.Header.Get("Authorization"))
+				w.WriteHeader(http.StatusUnauthorized)
+			}))
+			defer server.Close()
+
+			c := NewConnectorManagerClient(t.Context(), ConnectorManagerClientConfig{URL: server.URL, APIKey: "key", UnauthorizedRetries: tt.retries})
+			var gotSleeps int
+			c.unauthorized.sleep = func(ctx context.Context, d time.Duration) error {
+				gotSleeps++
+				return nil
+			}
+			var gotHandlers int
+			if tt.handler != nil {
+				handler := tt.handler(c)
+				c.SetUnauthorizedHandler(func(ctx context.Context, err error) bool {
+					gotHandlers++
+					return handler(ctx, err)
+				})
+			}
+			var gotGiveUp []bool
+			for range tt.calls {
+				gotGiveUp = append(gotGiveUp, c.giveUpUnauthorized(t.Context(), ErrUnauthorizedConnector))
+			}
+			if diff := cmp.Diff(gotGiveUp, tt.wantGiveUp); diff != "" {
+				t.Errorf("giveUpUnauthorized() diff(got-want)=%s", diff)
+			}
+			if gotSleeps != tt.wantSleeps {
+				t.Errorf("giveUpUnauthorized() sleeps = %d, want %d", gotSleeps, tt.wantSleeps)
+			}
+			if gotHandlers != tt.wantHandlers {
+				t.Errorf("giveUpUnauthorized() handler calls = %d, want %d", gotHandlers, tt.wantHandlers)
+			}
+			// unauthorized-connector error notified on each give up
+			if want := len(tt.wantGiveUp) - tt.wantSleeps; len(gotAuthorizations) != want {
+				t.Errorf("giveUpUnauthorized() notifications = %d, want %d", len(gotAuthorizations), want)
+			}
+			if tt.wantHandlers > 1 && gotAuthorizations[len(gotAuthorizations)-1] != "ApiKey new-key" {
+				t.Errorf("giveUpUnauthorized() authorization = %s, want new key", gotAuthorizations[len(gotAuthorizations)-1])
+			}
+		})
+	}
+}
+
+func TestConnectorManagerClient_Start_unauthorizedAck(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	var served atomic.Bool
+	var lock sync.Mutex
+	var acks []events.Envelope
+	acked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case basePath + "/tasks":
+			if served.Swap(true) {
+				_, _ = w.Write([]byte(`{"tasks":[]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"tasks":[{"id":"task-1","action":"restore","content":{"id":"elem"}}]}`))
+		case basePath + "/events":
+			envelope := events.Envelope{}
+			_ = json.NewDecoder(r.Body).Decode(&envelope)
+			lock.Lock()
+			defer lock.Unlock()
+			acks = append(acks, envelope)
+			if len(acks) == 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			close(acked)
+		}
+	}))
+	defer server.Close()
+
+	c := NewConnectorManagerClient(ctx, ConnectorManagerClientConfig{URL: server.URL, APIKey: "key", UnauthorizedRetries: 1})
+	c.schemaVersion.Store(events.SchemaVersionCurrent)
+	c.unauthorized.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	connector := &restoreRecorder{requestIDs: make(chan string, 2)}
+	go c.Start(ctx, connector)
+
+	select {
+	case <-acked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task ack not retried")
+	}
+	if handled := len(connector.requestIDs); handled != 1 {
+		t.Errorf("Start() task handled %d times, want once", handled)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if acks[0].IdempotencyKey == "" || acks[1].IdempotencyKey != acks[0].IdempotencyKey {
+		t.Errorf("Start() retried ack idempotency key = %q, want %q", acks[1].IdempotencyKey, acks[0].IdempotencyKey)
+	}
+	// reset once ack response is received
+	deadline := time.Now().Add(5 * time.Second)
+	for c.unauthorized.attempts.Load() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Start() unauthorized attempts = %d after ack, want reset", c.unauthorized.attempts.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestUnauthorizedPolicy_wait(t *testing.T) {
+	p := newUnauthorizedPolicy(10, time.Second)
+	tests := []struct {
+		attempt int64
+		base    time.Duration
+	}{
+		{attempt: 1, base: time.Second},
+		{attempt: 3, base: 4 * time.Second},
+		{attempt: 20, base: maxUnauthorizedBackoff},
+	}
+	for _, tt := range tests {
+		got := p.wait(tt.attempt)
+		if low, high := tt.base/2, tt.base*3/2; got < low || got > high {
+			t.Errorf("wait(%d) = %s, want within [%s, %s]", tt.attempt, got, low, high)
+		}
+	}
+}
+
+func TestSleepContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := sleepContext(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("sleepContext() error = %v, want %v", err, context.Canceled)
+	}
+}
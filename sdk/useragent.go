@@ -0,0 +1,49 @@
+package sdk
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+const sdkModulePath = "github.com/glimps-re/connector-integration"
+
+// SDKVersion returns the version of this SDK module the binary was built with, "devel" if unknown
+// (e.g. binaries of this repository, or built from a workspace).
+var SDKVersion = sync.OnceValue(func() (version string) {
+	version = "devel"
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	if info.Main.Path == sdkModulePath && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		version = info.Main.Version
+		return
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != sdkModulePath {
+			continue
+		}
+		if dep.Replace != nil && dep.Replace.Version != "" {
+			dep = dep.Replace
+		}
+		if dep.Version != "" {
+			version = dep.Version
+		}
+		return
+	}
+	return
+})
+
+// userAgent returns User-Agent of requests to manager, e.g.
+// "glimps-connector-host/1.2.0 connector-integration/v0.9.0 go/go1.26.0".
+func userAgent(connectorType string, version string) string {
+	product := "glimps-connector"
+	if connectorType != "" {
+		product += "-" + connectorType
+	}
+	if version == "" {
+		version = "unknown"
+	}
+	return product + "/" + version + " connector-integration/" + SDKVersion() + " go/" + runtime.Version()
+}
@@ -3,12 +3,15 @@ package sdk
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"regexp"
 	"strings"
 
 	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/filetype"
 	"github.com/glimps-re/connector-integration/sdk/validation"
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
@@ -32,8 +35,17 @@ func CustomValidations() map[string]validation.EnumValidation {
 		events.MitigationReasonTag:   events.MitigationReason("").Validation(),
 		events.MitigationInfoTypeTag: events.MitigationInfoType("").Validation(),
 		events.EventTypeTag:          events.EventType("").Validation(),
+		events.PIIPolicyTag:          events.PIIPolicy("").Validation(),
+		events.EncryptedFieldTag:     events.EncryptedField("").Validation(),
+		HostOSTag:                    HostOS("").Validation(),
+		RealTimeEngineTag:            RealTimeEngine("").Validation(),
 		TaskActionTag:                ActionType("").Validation(),
 		TaskStatusTag:                TaskStatus("").Validation(),
+		DeploymentMethodTag:          DeploymentMethod("").Validation(),
+		PullPolicyTag:                PullPolicy("").Validation(),
+		GMalwareEngineTag:            GMalwareEngine("").Validation(),
+		StopPolicyTag:                StopPolicy("").Validation(),
+		filetype.ActionTag:           filetype.Action("").Validation(),
 	}
 }
 
@@ -52,11 +64,15 @@ func (d StrictJSONSerializer) Serialize(c echo.Context, i any, indent string) er
 
 // Deserialize reads a JSON from a request body and converts it into an interface.
 func (d StrictJSONSerializer) Deserialize(c echo.Context, i any) (err error) {
-	dec := json.NewDecoder(c.Request().Body)
+	raw, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return newValidationError(err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
 	dec.DisallowUnknownFields()
 	err = dec.Decode(i)
 	if err != nil {
-		return newValidationError(err)
+		return newStrictValidationError(err, raw, i)
 	}
 	return
 }
@@ -78,6 +94,26 @@ func DefaultValidator() (v *defaultValidator, err error) {
 	if err != nil {
 		return
 	}
+	err = registerICAPSamplingValidation(validate, trans)
+	if err != nil {
+		return
+	}
+	err = registerHostValidation(validate, trans)
+	if err != nil {
+		return
+	}
+	err = registerDurationValidation(validate, trans)
+	if err != nil {
+		return
+	}
+	err = registerFileSizeValidation(validate, trans)
+	if err != nil {
+		return
+	}
+	err = registerRoutingValidation(validate, trans)
+	if err != nil {
+		return
+	}
 	err = en_translations.RegisterDefaultTranslations(validate, trans)
 	if err != nil {
 		return
@@ -86,6 +122,25 @@ func DefaultValidator() (v *defaultValidator, err error) {
 	return
 }
 
+// registerTranslations registers english messages of struct-level validation tags, {0} being the field name.
+func registerTranslations(validate *validator.Validate, trans ut.Translator, messages map[string]string) (err error) {
+	for tag, message := range messages {
+		err = validate.RegisterTranslation(tag, trans, func(ut ut.Translator) error {
+			return ut.Add(tag, message, false)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, transErr := ut.T(tag, fe.Field())
+			if transErr != nil {
+				return fe.(error).Error()
+			}
+			return t
+		})
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 type defaultValidator struct {
 	Trans     ut.Translator
 	Validator *validator.Validate
@@ -122,12 +177,13 @@ func BindAndValidateConfig(connectorType string, payload json.RawMessage) (confi
 	return
 }
 
+// BindRaw decodes raw, a single JSON value, into model, unknown fields being rejected.
 func BindRaw(model any, raw []byte) (err error) {
 	dec := json.NewDecoder(bytes.NewReader(raw))
 	dec.DisallowUnknownFields()
-	err = dec.Decode(model)
+	err = decodeSingle(dec, model)
 	if err != nil {
-		return newValidationError(err)
+		return newStrictValidationError(err, raw, model)
 	}
 	return
 }
@@ -135,7 +191,7 @@ func BindRaw(model any, raw []byte) (err error) {
 // Can be used to validate raw data. Useful to validate embed json.RawMessage for instance.
 func BindAndValidateRaw(model any, raw []byte) (err error) {
 	dec := json.NewDecoder(bytes.NewReader(raw))
-	err = dec.Decode(model)
+	err = decodeSingle(dec, model)
 	if err != nil {
 		return newValidationError(err)
 	}
@@ -149,7 +205,26 @@ func BindAndValidateRaw(model any, raw []byte) (err error) {
 	return
 }
 
+// errTrailingData is returned when a JSON payload holds data after its first value.
+var errTrailingData = errors.New("invalid JSON, data after top-level value")
+
+// decodeSingle decodes next value of dec into model, failing if dec holds more data.
+func decodeSingle(dec *json.Decoder, model any) (err error) {
+	if err = dec.Decode(model); err != nil {
+		return
+	}
+	if _, err = dec.Token(); !errors.Is(err, io.EOF) {
+		err = errTrailingData
+		return
+	}
+	err = nil
+	return
+}
+
 func newValidationError(err error) (newError ValidationError) {
+	if errors.As(err, &newError) {
+		return
+	}
 	reg := regexp.MustCompile("json: unknown field \"(.*)\"")
 	field := reg.FindStringSubmatch(err.Error())
 	switch {
@@ -161,6 +236,31 @@ func newValidationError(err error) (newError ValidationError) {
 	return
 }
 
+// newStrictValidationError returns a ValidationError of err, returned by strict decoding of raw into model. Unknown
+// fields are all reported at once in details (one per field, nested ones by path e.g. "monitoring.realtime.engin").
+func newStrictValidationError(err error, raw []byte, model any) (newError ValidationError) {
+	if !strings.HasPrefix(err.Error(), unknownFieldPrefix) {
+		newError = newValidationError(err)
+		return
+	}
+	fields := unknownJSONFields(raw, reflect.TypeOf(model))
+	if len(fields) == 0 {
+		newError = newValidationError(err)
+		return
+	}
+	newError = unknownFieldsError(fields)
+	return
+}
+
+// unknownFieldsError reports each of unknown fields paths in details of a ValidationError.
+func unknownFieldsError(fields []string) (newError ValidationError) {
+	newError.Details = make([]echo.Map, 0, len(fields))
+	for _, field := range fields {
+		newError.Details = append(newError.Details, echo.Map{field: "unknown field"})
+	}
+	return
+}
+
 // MUST BE used for validation error. Will be return as ResponseError with a http code 400 (bad request)
 type ValidationError struct {
 	Details []echo.Map `json:"details"`
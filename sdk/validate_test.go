@@ -0,0 +1,176 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/google/go-cmp/cmp"
+	"github.com/labstack/echo/v4"
+)
+
+func TestBindRaw(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    HostConfig
+		wantErr bool
+	}{
+		{name: "ok", raw: `{"stale_mount_timeout":"5s"}`, want: HostConfig{StaleMountTimeout: Duration(5 * time.Second)}},
+		{name: "trailing whitespace", raw: "{}\n\t ", want: HostConfig{}},
+		{name: "unknown field", raw: `{"unknown":1}`, wantErr: true},
+		{name: "trailing value", raw: `{} {"stale_mount_timeout":"5s"}`, wantErr: true},
+		{name: "trailing garbage", raw: `{}]`, wantErr: true},
+		{name: "empty", raw: ``, wantErr: true},
+		{name: "invalid duration", raw: `{"stale_mount_timeout":1e300}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HostConfig{}
+			err := BindRaw(&got, []byte(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BindRaw() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if validationErr := (ValidationError{}); !errors.As(err, &validationErr) {
+					t.Errorf("BindRaw() error = %T, want ValidationError", err)
+				}
+				return
+			}
+			if got.StaleMountTimeout != tt.want.StaleMountTimeout {
+				t.Errorf("BindRaw() stale mount timeout = %v, want %v", got.StaleMountTimeout, tt.want.StaleMountTimeout)
+			}
+		})
+	}
+}
+
+func TestBindRaw_unknownFields(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []echo.Map
+	}{
+		{
+			name: "all unknown fields",
+			raw:  `{"workerz":2,"paths":["/data"],"monitoring":{"prescan":true,"realtime":{"engin":"ebpf"}},"plugins":[{"name":"a"},{"nme":"b"}],"gmalware_api_tokn":"t"}`,
+			want: []echo.Map{
+				{"workerz": "unknown field"},
+				{"monitoring.realtime.engin": "unknown field"},
+				{"plugins[1].nme": "unknown field"},
+				{"gmalware_api_tokn": "unknown field"},
+			},
+		},
+		{name: "case insensitive and promoted fields", raw: `{"WORKERS":2,"gmalware_timeout":"1m","field_encryption":{"Key_ID":"k","format":"v2"}}`, want: []echo.Map{{"field_encryption.format": "unknown field"}}},
+		{name: "nested unknown value", raw: `{"stale_mount_timeout":"10s","unknown":{"nested":{"a":1}},"move":{"source":"/a"}}`, want: []echo.Map{{"unknown": "unknown field"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := BindRaw(&HostConfig{}, []byte(tt.raw))
+			validationErr := ValidationError{}
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("BindRaw() error = %v, want ValidationError", err)
+			}
+			if diff := cmp.Diff(validationErr.Details, tt.want); diff != "" {
+				t.Errorf("BindRaw() details diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestBindAndValidate_unknownFields(t *testing.T) {
+	e := echo.New()
+	e.JSONSerializer = StrictJSONSerializer{}
+	v, err := DefaultValidator()
+	if err != nil {
+		t.Fatalf("DefaultValidator() error = %v", err)
+	}
+	e.Validator = v
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id":"a","actoin":"purge","statu":"done"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	err = BindAndValidate(c, &struct {
+		ID     string `json:"id"`
+		Action string `json:"action"`
+	}{})
+	validationErr := ValidationError{}
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("BindAndValidate() error = %v, want ValidationError", err)
+	}
+	if diff := cmp.Diff(validationErr.Details, []echo.Map{{"actoin": "unknown field"}, {"statu": "unknown field"}}); diff != "" {
+		t.Errorf("BindAndValidate() details diff(got-want)=%s", diff)
+	}
+}
+
+func FuzzBindRaw(f *testing.F) {
+	for _, seed := range []string{`{}`, `{"stale_mount_timeout":"10s","paths":["/data"]}`, `{"stale_mount_timeout":-1}`, `{"unknown":1}`, `{} {}`, `null`, `[`} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		config, err := InitDefault(HostKey)
+		if err != nil {
+			t.Fatalf("InitDefault() error = %v", err)
+		}
+		if err = BindRaw(config, raw); err != nil {
+			if validationErr := (ValidationError{}); !errors.As(err, &validationErr) {
+				t.Errorf("BindRaw() error = %T, want ValidationError", err)
+			}
+			return
+		}
+		marshaled, err := json.Marshal(config)
+		if err != nil {
+			t.Fatalf("could not marshal bound config, error: %v", err)
+		}
+		again, err := InitDefault(HostKey)
+		if err != nil {
+			t.Fatalf("InitDefault() error = %v", err)
+		}
+		if err = BindRaw(again, marshaled); err != nil {
+			t.Errorf("BindRaw() of marshaled config %s error = %v", marshaled, err)
+		}
+	})
+}
+
+func TestDefaultValidator_enumTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		// fields are validated alone if set
+		fields  []string
+		wantErr bool
+	}{
+		{name: "encrypted fields", value: events.FieldEncryption{Fields: []events.EncryptedField{events.EncryptSubject, events.EncryptURL}, KeyID: "1", Key: "a2V5"}},
+		{name: "encrypted fields without key", value: events.FieldEncryption{Fields: []events.EncryptedField{events.EncryptSubject}}, wantErr: true},
+		{name: "no encrypted field", value: events.FieldEncryption{}},
+		{name: "unknown encrypted field", value: events.FieldEncryption{Fields: []events.EncryptedField{events.EncryptedField("body")}}, wantErr: true},
+		{name: "host os", value: HostConfig{OS: HostWindows}, fields: []string{"OS"}},
+		{name: "unknown host os", value: HostConfig{OS: HostOS("bsd")}, fields: []string{"OS"}, wantErr: true},
+		{name: "realtime engine", value: HostRealTimeConfig{Engine: RealTimeEBPF}},
+		{name: "unknown realtime engine", value: HostRealTimeConfig{Engine: RealTimeEngine("inotify")}, wantErr: true},
+		{name: "gmalware engine", value: GMalwareRouting{Default: GMalwareSyndetect, Rules: []GMalwareRoutingRule{{Engine: GMalwareDetect}}}},
+		{name: "unknown gmalware default engine", value: GMalwareRouting{Default: GMalwareEngine("extract")}, wantErr: true},
+		{name: "unknown gmalware rule engine", value: GMalwareRouting{Rules: []GMalwareRoutingRule{{Engine: GMalwareEngine("extract")}}}, wantErr: true},
+	}
+	v, err := DefaultValidator()
+	if err != nil {
+		t.Fatalf("DefaultValidator() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if len(tt.fields) > 0 {
+				err = v.Validator.StructPartial(tt.value, tt.fields...)
+			} else {
+				err = v.Validator.Struct(tt.value)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Struct() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
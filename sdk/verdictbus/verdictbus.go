@@ -0,0 +1,604 @@
+// Package verdictbus is an optional local pub/sub over a Unix socket, where connectors of a site
+// (e.g. ICAP, host and Sharepoint connectors on the same machine) share GLIMPS Malware verdicts,
+// avoiding duplicate submissions of the same file.
+//
+// The first connector joining the bus serves it, others connect to it. When it stops, the remaining
+// ones reconnect and one of them serves the bus in turn. Verdict sharing is best effort: verdicts
+// published while disconnected, or to too slow subscribers, are lost.
+// Any process able to connect to the socket may publish verdicts, its permissions (0660) must be
+// restricted to connectors, e.g. by placing it in a directory owned by their group. Verdicts are
+// authenticated with Options.Key, shared by connectors: without it, only malware verdicts of peers
+// are trusted, so a local process can not whitelist a file.
+package verdictbus
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/go-gdetect/pkg/gdetect"
+)
+
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: sdk.LogLevel})).WithGroup("verdictbus")
+
+const (
+	DefaultSocketPath = "/run/glimps/verdicts.sock"
+	DefaultCacheSize  = 100000
+	DefaultTTL        = 24 * time.Hour
+
+	socketMode = 0o660
+	// maxLineSize bounds verdict messages, larger lines are dropped with their connection
+	maxLineSize = 64 * 1024
+	// subscriberBuffer is the number of verdicts queued per connection, newer ones are dropped when full
+	subscriberBuffer = 256
+)
+
+// reconnectInterval is the wait between two attempts to join the bus once disconnected
+var reconnectInterval = time.Second
+
+// serveWait is the wait before connecting again to a bus another connector is about to serve, up to serveAttempts
+// times
+var (
+	serveWait     = 50 * time.Millisecond
+	serveAttempts = 20
+)
+
+var (
+	ErrClosed         = errors.New("verdict bus closed")
+	ErrNotConnected   = errors.New("verdict bus not connected")
+	ErrInvalidVerdict = errors.New("invalid verdict")
+
+	// errLocked is returned by lockFile when another connector serves the bus
+	errLocked = errors.New("verdict bus served by another connector")
+)
+
+var sha256Regexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Verdict is the result of a file analysis shared on the bus.
+type Verdict struct {
+	SHA256   string   `json:"sha256"`
+	Malware  bool     `json:"is_malware"`
+	Score    int      `json:"score"`
+	Malwares []string `json:"malwares,omitempty"`
+	Source   string   `json:"source" desc:"type of the connector which got verdict, e.g. icap"`
+	Time     int64    `json:"time" desc:"unix timestamp of analysis"`
+}
+
+// FromResult returns verdict of a GLIMPS Malware analysis result, source being the connector type.
+func FromResult(result gdetect.Result, source string) (v Verdict) {
+	v = Verdict{
+		SHA256:   strings.ToLower(result.SHA256),
+		Malware:  result.Malware,
+		Score:    result.Score,
+		Malwares: result.Malwares,
+		Source:   source,
+		Time:     result.Timestamp,
+	}
+	return
+}
+
+// message is a verdict line on the bus, MAC authenticating Verdict with Options.Key.
+type message struct {
+	Verdict json.RawMessage `json:"verdict"`
+	MAC     string          `json:"mac,omitempty"`
+}
+
+func (v Verdict) validate() (err error) {
+	if !sha256Regexp.MatchString(v.SHA256) {
+		err = fmt.Errorf("%w, sha256 must be 64 lowercase hexadecimal characters", ErrInvalidVerdict)
+	}
+	return
+}
+
+type Options struct {
+	// Path of bus Unix socket, DefaultSocketPath if empty
+	Path string
+	// Source identifies verdicts published by this connector, e.g. sdk.ICAPKey
+	Source string
+	// CacheSize is the maximum number of verdicts kept for Lookup, DefaultCacheSize if 0
+	CacheSize int
+	// TTL is the duration verdicts are kept for Lookup, DefaultTTL if 0
+	TTL time.Duration
+	// Key authenticates verdicts (HMAC-SHA256), it must be shared by connectors of the bus: verdicts not signed
+	// with it are ignored. Without Key, only malware verdicts of peers are kept.
+	Key string
+}
+
+// Bus is a connection to the verdict bus, serving it when no other connector does.
+type Bus struct {
+	opts  Options
+	cache *cache
+
+	lock        sync.Mutex
+	conn        net.Conn
+	broker      *broker
+	subscribers map[int]func(Verdict)
+	nextID      int
+	closed      bool
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+// Join connects to the bus at opts.Path, serving it if no connector does yet.
+// Bus then stays connected, reconnecting (and serving the bus in turn) until Close.
+func Join(ctx context.Context, opts Options) (b *Bus, err error) {
+	if opts.Path == "" {
+		opts.Path = DefaultSocketPath
+	}
+	if opts.CacheSize <= 0 {
+		opts.CacheSize = DefaultCacheSize
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	b = &Bus{
+		opts:        opts,
+		cache:       newCache(opts.CacheSize, opts.TTL),
+		subscribers: make(map[int]func(Verdict)),
+		done:        make(chan struct{}),
+	}
+	conn, err := b.connect(ctx)
+	if err != nil {
+		b = nil
+		return
+	}
+	b.conn = conn
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	b.cancel = cancel
+	go b.run(runCtx, conn)
+	return
+}
+
+// connect dials bus, serving it first if no connector does.
+func (b *Bus) connect(ctx context.Context) (conn net.Conn, err error) {
+	dialer := net.Dialer{}
+	for attempt := 1; ; attempt++ {
+		conn, err = dialer.DialContext(ctx, "unix", b.opts.Path)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, syscall.ENOENT) && !errors.Is(err, syscall.ECONNREFUSED) {
+			err = fmt.Errorf("could not connect to verdict bus, %w", err)
+			return
+		}
+		brk, listenErr := listen(b.opts.Path)
+		if errors.Is(listenErr, errLocked) && attempt < serveAttempts {
+			// another connector is about to serve the bus
+			timer := time.NewTimer(serveWait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				err = ctx.Err()
+				return
+			case <-timer.C:
+			}
+			continue
+		}
+		if listenErr != nil {
+			err = fmt.Errorf("could not serve verdict bus, %w", listenErr)
+			return
+		}
+		conn, err = dialer.DialContext(ctx, "unix", b.opts.Path)
+		if err != nil {
+			brk.close()
+			err = fmt.Errorf("could not connect to verdict bus, %w", err)
+			return
+		}
+		logger.Info("serving verdict bus", slog.String("path", b.opts.Path))
+		b.lock.Lock()
+		b.broker = brk
+		b.lock.Unlock()
+		return
+	}
+}
+
+func (b *Bus) run(ctx context.Context, conn net.Conn) {
+	defer close(b.done)
+	for {
+		b.lock.Lock()
+		if b.closed {
+			// closed while reconnecting
+			brk := b.broker
+			b.broker = nil
+			b.lock.Unlock()
+			_ = conn.Close()
+			if brk != nil {
+				brk.close()
+			}
+			return
+		}
+		b.conn = conn
+		b.lock.Unlock()
+
+		b.read(conn)
+
+		b.lock.Lock()
+		b.conn = nil
+		closed := b.closed
+		b.lock.Unlock()
+		if closed {
+			return
+		}
+		logger.Warn("disconnected from verdict bus, reconnect")
+		for {
+			timer := time.NewTimer(reconnectInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			var err error
+			conn, err = b.connect(ctx)
+			if err == nil {
+				break
+			}
+			logger.Debug("could not reconnect to verdict bus", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (b *Bus) read(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineSize)
+	for scanner.Scan() {
+		v, err := b.verdict(scanner.Bytes())
+		if err != nil {
+			logger.Debug("ignore verdict from bus", slog.String("error", err.Error()))
+			continue
+		}
+		b.cache.put(v)
+		b.lock.Lock()
+		subscribers := make([]func(Verdict), 0, len(b.subscribers))
+		for _, s := range b.subscribers {
+			subscribers = append(subscribers, s)
+		}
+		b.lock.Unlock()
+		for _, s := range subscribers {
+			s(v)
+		}
+	}
+	_ = conn.Close()
+}
+
+// verdict returns verdict of message line, if it is trusted: signed with Options.Key, or a malware one without it.
+func (b *Bus) verdict(line []byte) (v Verdict, err error) {
+	msg := message{}
+	if err = json.Unmarshal(line, &msg); err != nil {
+		err = fmt.Errorf("%w, %w", ErrInvalidVerdict, err)
+		return
+	}
+	if b.opts.Key != "" {
+		mac, decodeErr := hex.DecodeString(msg.MAC)
+		if decodeErr != nil || !hmac.Equal(mac, b.sign(msg.Verdict)) {
+			err = fmt.Errorf("%w, not authenticated", ErrInvalidVerdict)
+			return
+		}
+	}
+	if err = json.Unmarshal(msg.Verdict, &v); err != nil {
+		err = fmt.Errorf("%w, %w", ErrInvalidVerdict, err)
+		return
+	}
+	if err = v.validate(); err != nil {
+		return
+	}
+	if b.opts.Key == "" && !v.Malware {
+		err = fmt.Errorf("%w, clean verdicts are only trusted on authenticated bus", ErrInvalidVerdict)
+	}
+	return
+}
+
+// sign returns MAC of raw verdict with Options.Key, nil without it.
+func (b *Bus) sign(raw []byte) (mac []byte) {
+	if b.opts.Key == "" {
+		return
+	}
+	h := hmac.New(sha256.New, []byte(b.opts.Key))
+	h.Write(raw)
+	mac = h.Sum(nil)
+	return
+}
+
+// Publish shares v with other connectors, Source and Time being set if empty.
+// It is also kept for Lookup. ErrNotConnected is returned while bus is reconnecting.
+func (b *Bus) Publish(ctx context.Context, v Verdict) (err error) {
+	v.SHA256 = strings.ToLower(v.SHA256)
+	if err = v.validate(); err != nil {
+		return
+	}
+	if v.Source == "" {
+		v.Source = b.opts.Source
+	}
+	if v.Time == 0 {
+		v.Time = time.Now().Unix()
+	}
+	b.cache.put(v)
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	msg := message{Verdict: raw}
+	if mac := b.sign(raw); mac != nil {
+		msg.MAC = hex.EncodeToString(mac)
+	}
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	switch {
+	case b.closed:
+		err = ErrClosed
+		return
+	case b.conn == nil:
+		err = ErrNotConnected
+		return
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = b.conn.SetWriteDeadline(deadline)
+		defer func() { _ = b.conn.SetWriteDeadline(time.Time{}) }()
+	}
+	_, err = b.conn.Write(line)
+	return
+}
+
+// Lookup returns verdict of file with given sha256, published by any connector in the last TTL.
+func (b *Bus) Lookup(sha256 string) (v Verdict, ok bool) {
+	return b.cache.get(strings.ToLower(sha256))
+}
+
+// Subscribe calls fn with verdicts published by other connectors, until unsubscribe is called.
+// fn is called from bus reading goroutine, it must not block.
+func (b *Bus) Subscribe(fn func(Verdict)) (unsubscribe func()) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = fn
+	return func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+// Serving reports whether this connector currently serves the bus.
+func (b *Bus) Serving() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.broker != nil
+}
+
+// Close disconnects from bus, and stops serving it: other connectors then reconnect to a new one.
+func (b *Bus) Close() (err error) {
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		return
+	}
+	b.closed = true
+	conn, brk := b.conn, b.broker
+	b.broker = nil
+	b.lock.Unlock()
+
+	if conn != nil {
+		err = conn.Close()
+	}
+	if brk != nil {
+		brk.close()
+	}
+	b.cancel()
+	<-b.done
+	return
+}
+
+// broker relays each verdict line received from a connection to the other ones.
+type broker struct {
+	listener net.Listener
+	path     string
+	// serveLock is locked while serving the bus, by a single connector
+	serveLock *os.File
+	lock      sync.Mutex
+	conns     map[net.Conn]chan []byte
+	wg        sync.WaitGroup
+}
+
+// listen serves the bus on path, unless another connector does (errLocked). Connectors serve it holding a lock on
+// path.lock, a socket at path without it is a stale one of a stopped connector.
+func listen(path string) (brk *broker, err error) {
+	serveLock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, socketMode)
+	if err != nil {
+		return
+	}
+	if err = lockFile(serveLock); err != nil {
+		_ = serveLock.Close()
+		return
+	}
+	listener, err := listenSocket(path)
+	if err != nil {
+		_ = serveLock.Close()
+		return
+	}
+	brk = &broker{listener: listener, path: path, serveLock: serveLock, conns: make(map[net.Conn]chan []byte)}
+	brk.wg.Go(brk.serve)
+	return
+}
+
+// listenSocket listens on a socket created in a private directory, moved to path once its permissions are set, so
+// it is never reachable with default ones.
+func listenSocket(path string) (listener *net.UnixListener, err error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".verdictbus-")
+	if err != nil {
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	tmp := filepath.Join(dir, "sock")
+	listener, err = net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return
+	}
+	// socket is removed by broker close, at path
+	listener.SetUnlinkOnClose(false)
+	if err = os.Chmod(tmp, socketMode); err == nil {
+		if err = os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = listener.Close()
+		listener = nil
+	}
+	return
+}
+
+func (brk *broker) serve() {
+	for {
+		conn, err := brk.listener.Accept()
+		if err != nil {
+			return
+		}
+		out := make(chan []byte, subscriberBuffer)
+		brk.lock.Lock()
+		brk.conns[conn] = out
+		brk.lock.Unlock()
+		brk.wg.Go(func() { brk.write(conn, out) })
+		brk.wg.Go(func() { brk.relay(conn) })
+	}
+}
+
+func (brk *broker) write(conn net.Conn, out chan []byte) {
+	for line := range out {
+		if _, err := conn.Write(line); err != nil {
+			_ = conn.Close()
+			return
+		}
+	}
+}
+
+func (brk *broker) relay(conn net.Conn) {
+	defer func() {
+		brk.lock.Lock()
+		if out, ok := brk.conns[conn]; ok {
+			delete(brk.conns, conn)
+			close(out)
+		}
+		brk.lock.Unlock()
+		_ = conn.Close()
+	}()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineSize)
+	for scanner.Scan() {
+		line := append(append([]byte(nil), scanner.Bytes()...), '\n')
+		brk.lock.Lock()
+		for other, out := range brk.conns {
+			if other == conn {
+				continue
+			}
+			select {
+			case out <- line:
+			default:
+				// subscriber too slow, verdict sharing is best effort
+			}
+		}
+		brk.lock.Unlock()
+	}
+}
+
+func (brk *broker) close() {
+	_ = brk.listener.Close()
+	// removed while serve lock is held, not to remove socket of next connector serving the bus
+	if err := os.Remove(brk.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("could not remove verdict bus socket", slog.String("error", err.Error()))
+	}
+	defer func() { _ = brk.serveLock.Close() }()
+	brk.lock.Lock()
+	for conn, out := range brk.conns {
+		delete(brk.conns, conn)
+		close(out)
+		_ = conn.Close()
+	}
+	brk.lock.Unlock()
+	brk.wg.Wait()
+}
+
+// cache keeps last verdicts by sha256, evicting least recently updated ones.
+type cache struct {
+	lock    sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List // front is most recently updated
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	verdict Verdict
+	added   time.Time
+}
+
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+func (c *cache) put(v Verdict) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry := cacheEntry{verdict: v, added: c.now()}
+	if e, ok := c.entries[v.SHA256]; ok {
+		e.Value = entry
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[v.SHA256] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(cacheEntry).verdict.SHA256)
+	}
+}
+
+func (c *cache) get(sha256 string) (v Verdict, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.entries[sha256]
+	if !ok {
+		return
+	}
+	entry := e.Value.(cacheEntry)
+	if c.now().Sub(entry.added) > c.ttl {
+		c.order.Remove(e)
+		delete(c.entries, sha256)
+		ok = false
+		return
+	}
+	v = entry.verdict
+	return
+}
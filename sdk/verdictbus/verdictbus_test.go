@@ -0,0 +1,298 @@
+package verdictbus
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	hashA = strings.Repeat("a", 64)
+	hashB = strings.Repeat("b", 64)
+)
+
+const testKey = "bus-key"
+
+func join(t *testing.T, path string, source string) (b *Bus, received chan Verdict) {
+	t.Helper()
+	b, received = joinWith(t, Options{Path: path, Source: source, Key: testKey})
+	return
+}
+
+func joinWith(t *testing.T, opts Options) (b *Bus, received chan Verdict) {
+	t.Helper()
+	b, err := Join(t.Context(), opts)
+	if err != nil {
+		t.Fatalf("Join() error = %v", err)
+	}
+	received = make(chan Verdict, 10)
+	b.Subscribe(func(v Verdict) { received <- v })
+	return
+}
+
+func waitVerdict(t *testing.T, received chan Verdict) (v Verdict) {
+	t.Helper()
+	select {
+	case v = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no verdict received")
+	}
+	return
+}
+
+// waitConnections waits until bus served by b has n connections, verdicts published before are not relayed to all.
+func waitConnections(t *testing.T, b *Bus, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		b.lock.Lock()
+		brk := b.broker
+		b.lock.Unlock()
+		if brk != nil {
+			brk.lock.Lock()
+			got := len(brk.conns)
+			brk.lock.Unlock()
+			if got == n {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("bus has not %d connections", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBus(t *testing.T) {
+	reconnectInterval = 10 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "verdicts.sock")
+
+	icap, icapReceived := join(t, path, "icap")
+	host, hostReceived := join(t, path, "host")
+	defer func() { _ = host.Close() }()
+	if !icap.Serving() || host.Serving() {
+		t.Fatalf("Serving() = %v, %v, want first joined connector only", icap.Serving(), host.Serving())
+	}
+	waitConnections(t, icap, 2)
+
+	err := host.Publish(t.Context(), Verdict{SHA256: strings.ToUpper(hashA), Malware: true, Score: 1000, Time: 1738000000})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	want := Verdict{SHA256: hashA, Malware: true, Score: 1000, Source: "host", Time: 1738000000}
+	if diff := cmp.Diff(waitVerdict(t, icapReceived), want); diff != "" {
+		t.Errorf("Subscribe() verdict diff(got-want)=%s", diff)
+	}
+	for name, b := range map[string]*Bus{"icap": icap, "host": host} {
+		got, ok := b.Lookup(hashA)
+		if !ok {
+			t.Fatalf("%s Lookup() not found", name)
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("%s Lookup() diff(got-want)=%s", name, diff)
+		}
+	}
+	select {
+	case v := <-hostReceived:
+		t.Errorf("publisher received its own verdict %v", v)
+	default:
+	}
+
+	if err = host.Publish(t.Context(), Verdict{SHA256: "invalid"}); !errors.Is(err, ErrInvalidVerdict) {
+		t.Errorf("Publish() error = %v, want ErrInvalidVerdict", err)
+	}
+
+	// host serves the bus once icap stops
+	if err = icap.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err = icap.Publish(t.Context(), Verdict{SHA256: hashB}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish() error = %v, want ErrClosed", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !host.Serving() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Serving() = false after broker stopped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	sharepoint, _ := join(t, path, "sharepoint")
+	defer func() { _ = sharepoint.Close() }()
+	waitConnections(t, host, 2)
+	if err = sharepoint.Publish(t.Context(), Verdict{SHA256: hashB}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if got := waitVerdict(t, hostReceived); got.SHA256 != hashB || got.Source != "sharepoint" {
+		t.Errorf("Subscribe() verdict = %v, want %s from sharepoint", got, hashB)
+	}
+}
+
+func TestBus_untrusted(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverKey  string
+		peerKey    string
+		verdicts   []Verdict
+		wantHashes []string
+	}{
+		{
+			name:       "unauthenticated bus",
+			verdicts:   []Verdict{{SHA256: hashA}, {SHA256: hashB, Malware: true}},
+			wantHashes: []string{hashB},
+		},
+		{
+			name:       "authenticated bus",
+			serverKey:  testKey,
+			peerKey:    testKey,
+			verdicts:   []Verdict{{SHA256: hashA}, {SHA256: hashB, Malware: true}},
+			wantHashes: []string{hashA, hashB},
+		},
+		{
+			name:      "other key",
+			serverKey: testKey,
+			peerKey:   "other",
+			verdicts:  []Verdict{{SHA256: hashA}, {SHA256: hashB, Malware: true}},
+		},
+		{
+			name:      "unsigned",
+			serverKey: testKey,
+			verdicts:  []Verdict{{SHA256: hashA}, {SHA256: hashB, Malware: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "verdicts.sock")
+			server, received := joinWith(t, Options{Path: path, Source: "icap", Key: tt.serverKey})
+			defer func() { _ = server.Close() }()
+			peer, _ := joinWith(t, Options{Path: path, Source: "host", Key: tt.peerKey})
+			defer func() { _ = peer.Close() }()
+			waitConnections(t, server, 2)
+			for _, v := range tt.verdicts {
+				if err := peer.Publish(t.Context(), v); err != nil {
+					t.Fatalf("Publish() error = %v", err)
+				}
+			}
+			// last verdict flushes previous ones
+			if err := peer.Publish(t.Context(), Verdict{SHA256: strings.Repeat("c", 64), Malware: true}); err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+			var got []string
+			for {
+				v := Verdict{}
+				select {
+				case v = <-received:
+				case <-time.After(200 * time.Millisecond):
+				}
+				if v.SHA256 == "" || v.SHA256 == strings.Repeat("c", 64) {
+					break
+				}
+				got = append(got, v.SHA256)
+			}
+			if diff := cmp.Diff(got, tt.wantHashes); diff != "" {
+				t.Errorf("Subscribe() verdicts diff(got-want)=%s", diff)
+			}
+			for _, hash := range []string{hashA, hashB} {
+				_, ok := server.Lookup(hash)
+				if want := slices.Contains(tt.wantHashes, hash); ok != want {
+					t.Errorf("Lookup(%s) found = %v, want %v", hash, ok, want)
+				}
+			}
+		})
+	}
+}
+
+func TestJoin_socket(t *testing.T) {
+	reconnectInterval = 10 * time.Millisecond
+	serveWait = time.Millisecond
+	dir := t.TempDir()
+
+	t.Run("stale socket", func(t *testing.T) {
+		path := filepath.Join(dir, "stale.sock")
+		listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		listener.SetUnlinkOnClose(false)
+		_ = listener.Close()
+		b, _ := join(t, path, "icap")
+		defer func() { _ = b.Close() }()
+		if !b.Serving() {
+			t.Errorf("Serving() = false, want stale socket replaced")
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != socketMode {
+			t.Errorf("socket mode = %o, want %o", mode, socketMode)
+		}
+	})
+
+	t.Run("served by another connector", func(t *testing.T) {
+		path := filepath.Join(dir, "locked.sock")
+		listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		listener.SetUnlinkOnClose(false)
+		_ = listener.Close()
+		// connector about to serve the bus
+		serveLock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, socketMode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = serveLock.Close() }()
+		if err = lockFile(serveLock); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = Join(t.Context(), Options{Path: path}); err == nil {
+			t.Fatalf("Join() error = nil, want bus served by another connector")
+		}
+		if _, err = os.Stat(path); err != nil {
+			t.Errorf("Join() removed socket of connector serving the bus, %v", err)
+		}
+	})
+}
+
+func TestCache(t *testing.T) {
+	now := time.Unix(1738000000, 0)
+	c := newCache(2, time.Hour)
+	c.now = func() time.Time { return now }
+	hashC := strings.Repeat("c", 64)
+
+	c.put(Verdict{SHA256: hashA})
+	c.put(Verdict{SHA256: hashB})
+	c.put(Verdict{SHA256: hashA, Malware: true})
+	c.put(Verdict{SHA256: hashC})
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		sha256  string
+		want    Verdict
+		wantOK  bool
+	}{
+		{name: "updated", sha256: hashA, want: Verdict{SHA256: hashA, Malware: true}, wantOK: true},
+		{name: "evicted", sha256: hashB},
+		{name: "kept", sha256: hashC, want: Verdict{SHA256: hashC}, wantOK: true},
+		{name: "expired", elapsed: 2 * time.Hour, sha256: hashC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.elapsed)
+			got, ok := c.get(tt.sha256)
+			if ok != tt.wantOK {
+				t.Fatalf("get() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("get() diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
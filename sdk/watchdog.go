@@ -0,0 +1,208 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+)
+
+const (
+	DefaultWatchdogInterval       = 5 * time.Minute
+	DefaultWatchdogRestartTimeout = time.Minute
+)
+
+type WatchdogOptions struct {
+	// Interval is the maximum duration between two heartbeats of a started connector, DefaultWatchdogInterval if 0.
+	Interval time.Duration
+	// Restart enables connector restart (Stop then Start) when it is detected stalled.
+	Restart bool
+	// RestartTimeout bounds contexts given to Stop and Start on restart, DefaultWatchdogRestartTimeout if 0.
+	RestartTimeout time.Duration
+}
+
+// Watchdog wraps a Connector to detect when it stalls (e.g. deadlocked goroutines):
+// connector must call Heartbeat periodically while started. When no heartbeat is received within interval,
+// watchdog notifies a StalledConnectorError, reports Degraded status and, if enabled, restarts connector.
+// Watchdog is a Connector, it is meant to be given to ConnectorManagerClient.Start in place of the wrapped connector.
+type Watchdog struct {
+	Connector
+	handler        events.EventErrorHandler
+	interval       time.Duration
+	restart        bool
+	restartTimeout time.Duration
+
+	lastBeat    atomic.Int64 // unix nano
+	stalledBeat atomic.Int64 // lastBeat when connector was detected stalled, 0 if not stalled
+	lastRestart time.Time    // used by Run goroutine only
+	lock        sync.Mutex   // serializes Start, Stop and restart steps
+	calls       uint64       // Start and Stop calls, guarded by lock
+	now         func() time.Time
+}
+
+func NewWatchdog(connector Connector, handler events.EventErrorHandler, opts WatchdogOptions) (w *Watchdog) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWatchdogInterval
+	}
+	if opts.RestartTimeout <= 0 {
+		opts.RestartTimeout = DefaultWatchdogRestartTimeout
+	}
+	w = &Watchdog{
+		Connector:      connector,
+		handler:        handler,
+		interval:       opts.Interval,
+		restart:        opts.Restart,
+		restartTimeout: opts.RestartTimeout,
+		now:            time.Now,
+	}
+	w.Heartbeat()
+	return
+}
+
+// Unwrap returns wrapped connector.
+func (w *Watchdog) Unwrap() Connector {
+	return w.Connector
+}
+
+// Heartbeat records connector is alive. It is cheap and safe to call from any goroutine.
+func (w *Watchdog) Heartbeat() {
+	w.lastBeat.Store(w.now().UnixNano())
+}
+
+// Status returns Degraded when wrapped connector is started but stalled.
+func (w *Watchdog) Status() (status ConnectorStatus) {
+	status = w.Connector.Status()
+	if status == Started && w.stalledBeat.Load() != 0 {
+		status = Degraded
+	}
+	return
+}
+
+// Start starts wrapped connector. A degraded connector is stopped first, so a start task restarts it.
+// A restarted connector is reported degraded until it beats again.
+func (w *Watchdog) Start(ctx context.Context) (err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.calls++
+	if w.Status() == Degraded {
+		if err = w.Connector.Stop(ctx); err != nil {
+			return
+		}
+	}
+	err = w.Connector.Start(ctx)
+	return
+}
+
+func (w *Watchdog) Stop(ctx context.Context) (err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.calls++
+	err = w.Connector.Stop(ctx)
+	return
+}
+
+// Run checks heartbeats until ctx is done.
+func (w *Watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check(ctx)
+		}
+	}
+}
+
+func (w *Watchdog) check(ctx context.Context) {
+	if w.Connector.Status() == Stopped {
+		// stopped connectors do not beat, they get a full interval once started
+		w.Heartbeat()
+		w.resolve(ctx)
+		return
+	}
+	beat := w.lastBeat.Load()
+	if stalledBeat := w.stalledBeat.Load(); stalledBeat != 0 && beat != stalledBeat {
+		w.resolve(ctx)
+	}
+	since := w.now().Sub(time.Unix(0, beat))
+	if since <= w.interval {
+		return
+	}
+	if w.stalledBeat.CompareAndSwap(0, beat) {
+		stallErr := fmt.Errorf("connector stalled, no heartbeat for %s", since.Truncate(time.Second))
+		logger.Error("connector stalled", slog.String("since", since.String()))
+		if err := w.handler.NotifyError(ctx, events.StalledConnectorError, stallErr); err != nil {
+			logger.Error("could not notify stalled connector", slog.String("error", err.Error()))
+		}
+	}
+	// a restarted connector gets a full interval to beat before being restarted again
+	if w.restart && w.now().Sub(w.lastRestart) > w.interval {
+		w.lastRestart = w.now()
+		w.restartConnector(ctx)
+	}
+}
+
+// restartConnector stops then starts connector, lock being released in between for Start and Stop calls (e.g. of
+// console tasks) not to wait for the whole restart: connector is not started again if one of them ran meanwhile.
+func (w *Watchdog) restartConnector(ctx context.Context) {
+	stopper, isStopper := asAnalysisStopper(w.Connector)
+	calls, stopped := w.stopStalled(ctx, stopper, isStopper)
+	if !stopped {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.calls != calls {
+		logger.Info("stalled connector started or stopped during restart, restart canceled")
+		return
+	}
+	startCtx, cancel := context.WithTimeout(ctx, w.restartTimeout)
+	defer cancel()
+	if err := w.Connector.Start(startCtx); err != nil {
+		logger.Error("could not start stalled connector", slog.String("error", err.Error()))
+		return
+	}
+	if isStopper {
+		stopper.ResumeAnalyses()
+	}
+}
+
+// stopStalled stops stalled connector for restartConnector, returning Start and Stop calls count once stopped.
+func (w *Watchdog) stopStalled(ctx context.Context, stopper AnalysisStopper, isStopper bool) (calls uint64, stopped bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.Connector.Status() == Stopped {
+		return
+	}
+	logger.Warn("restart stalled connector")
+	stopCtx, cancel := context.WithTimeout(ctx, w.restartTimeout)
+	defer cancel()
+	// in-flight analyses are run again once restarted
+	if isStopper {
+		if _, err := stopper.StopAnalyses(stopCtx, StopRequeue, w.restartTimeout); err != nil && !errors.Is(err, ErrAnalysesStopped) {
+			logger.Error("could not stop analyses of stalled connector", slog.String("error", err.Error()))
+		}
+	}
+	if err := w.Connector.Stop(stopCtx); err != nil {
+		logger.Error("could not stop stalled connector", slog.String("error", err.Error()))
+		return
+	}
+	calls, stopped = w.calls, true
+	return
+}
+
+func (w *Watchdog) resolve(ctx context.Context) {
+	if w.stalledBeat.Swap(0) == 0 {
+		return
+	}
+	if err := w.handler.NotifyResolution(ctx, "connector heartbeat received", events.StalledConnectorError); err != nil {
+		logger.Error("could not notify stalled connector resolution", slog.String("error", err.Error()))
+	}
+}
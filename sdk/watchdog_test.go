@@ -0,0 +1,160 @@
+package sdk
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/events"
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/google/go-cmp/cmp"
+)
+
+type restartRecorder struct {
+	fakeConnector
+	status ConnectorStatus
+	calls  []string
+}
+
+func (c *restartRecorder) Start(ctx context.Context) (err error) {
+	c.calls = append(c.calls, "start")
+	c.status = Started
+	return
+}
+
+func (c *restartRecorder) Stop(ctx context.Context) (err error) {
+	c.calls = append(c.calls, "stop")
+	c.status = Stopped
+	return
+}
+
+func (c *restartRecorder) Status() (status ConnectorStatus) { return c.status }
+
+func TestWatchdog(t *testing.T) {
+	type step struct {
+		elapsed    time.Duration
+		beat       bool
+		wantStatus ConnectorStatus
+	}
+	tests := []struct {
+		name       string
+		restart    bool
+		status     ConnectorStatus
+		steps      []step
+		wantEvents []events.EventType
+		wantCalls  []string
+	}{
+		{
+			name:   "alive",
+			status: Started,
+			steps: []step{
+				{elapsed: 4 * time.Minute, beat: true, wantStatus: Started},
+				{elapsed: 4 * time.Minute, wantStatus: Started},
+			},
+		},
+		{
+			name:   "stalled then resumed",
+			status: Started,
+			steps: []step{
+				{elapsed: 6 * time.Minute, wantStatus: Degraded},
+				{elapsed: time.Minute, wantStatus: Degraded},
+				{elapsed: time.Minute, beat: true, wantStatus: Started},
+			},
+			wantEvents: []events.EventType{events.Error, events.Resolution},
+		},
+		{
+			name:    "stalled restarted",
+			restart: true,
+			status:  Started,
+			steps: []step{
+				{elapsed: 6 * time.Minute, wantStatus: Degraded},
+				{elapsed: time.Minute, wantStatus: Degraded},
+				{elapsed: 5 * time.Minute, wantStatus: Degraded},
+			},
+			wantEvents: []events.EventType{events.Error},
+			wantCalls:  []string{"stop", "start", "stop", "start"},
+		},
+		{
+			name:   "degraded stalled",
+			status: Degraded,
+			steps: []step{
+				{elapsed: 6 * time.Minute, wantStatus: Degraded},
+				{elapsed: time.Minute, beat: true, wantStatus: Degraded},
+			},
+			wantEvents: []events.EventType{events.Error, events.Resolution},
+		},
+		{
+			name:   "stopped",
+			status: Stopped,
+			steps: []step{
+				{elapsed: 6 * time.Minute, wantStatus: Stopped},
+				{elapsed: 6 * time.Minute, wantStatus: Stopped},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotEvents []events.EventType
+			handler := events.NewHandler(notifierFunc(func(ctx context.Context, event any) error {
+				switch event.(type) {
+				case events.ErrorEvent:
+					gotEvents = append(gotEvents, events.Error)
+				case events.ResolutionEvent:
+					gotEvents = append(gotEvents, events.Resolution)
+				}
+				return nil
+			}), slog.LevelError, nil, &metrics.MetricsCollector{})
+			connector := &restartRecorder{status: tt.status}
+			now := time.Unix(1738000000, 0)
+			w := NewWatchdog(connector, handler, WatchdogOptions{Restart: tt.restart})
+			w.now = func() time.Time { return now }
+			w.Heartbeat()
+
+			for i, s := range tt.steps {
+				now = now.Add(s.elapsed)
+				if s.beat {
+					w.Heartbeat()
+				}
+				w.check(t.Context())
+				if got := w.Status(); got != s.wantStatus {
+					t.Errorf("step %d: Status() = %v, want %v", i, got, s.wantStatus)
+				}
+			}
+			if diff := cmp.Diff(gotEvents, tt.wantEvents); diff != "" {
+				t.Errorf("Watchdog events diff(got-want)=%s", diff)
+			}
+			if diff := cmp.Diff(connector.calls, tt.wantCalls); diff != "" {
+				t.Errorf("Watchdog connector calls diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestWatchdog_Start(t *testing.T) {
+	connector := &restartRecorder{status: Started}
+	w := NewWatchdog(connector, events.NoopEventHandler{}, WatchdogOptions{})
+	w.stalledBeat.Store(w.lastBeat.Load())
+	if err := w.Start(t.Context()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if diff := cmp.Diff(connector.calls, []string{"stop", "start"}); diff != "" {
+		t.Errorf("Start() connector calls diff(got-want)=%s", diff)
+	}
+}
+
+func TestWatchdog_restartAnalyses(t *testing.T) {
+	connector := &analysisStopper{}
+	now := time.Unix(1738000000, 0)
+	w := NewWatchdog(connector, events.NoopEventHandler{}, WatchdogOptions{Restart: true})
+	w.now = func() time.Time { return now }
+	w.Heartbeat()
+	now = now.Add(6 * time.Minute)
+	w.check(t.Context())
+	if connector.policy != StopRequeue || connector.timeout != DefaultWatchdogRestartTimeout {
+		t.Errorf("StopAnalyses() called with %s, %s, want %s, %s", connector.policy, connector.timeout, StopRequeue, DefaultWatchdogRestartTimeout)
+	}
+	if !connector.stopped || !connector.resumed {
+		t.Errorf("restart stopped = %v, resumed = %v, want both", connector.stopped, connector.resumed)
+	}
+}
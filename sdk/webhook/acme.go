@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ACME challenges of sdk.ACMEConfig.
+const (
+	ChallengeTLSALPN = "tls-alpn-01"
+	ChallengeHTTP    = "http-01"
+)
+
+// DefaultACMEHTTPAddress is the default listen address of http-01 challenges.
+const DefaultACMEHTTPAddress = ":80"
+
+// shutdownTimeout bounds graceful shutdown of ServeACME servers.
+const shutdownTimeout = 10 * time.Second
+
+var ErrInvalidACME = errors.New("invalid ACME config")
+
+// NewCertManager returns the manager of certificates of config domains, obtained and renewed from config ACME
+// certificate authority, and cached in its CacheDir.
+func NewCertManager(config sdk.ACMEConfig) (m *autocert.Manager, err error) {
+	switch {
+	case !config.Enabled:
+		err = fmt.Errorf("%w, ACME is not enabled", ErrInvalidACME)
+	case len(config.Domains) == 0:
+		err = fmt.Errorf("%w, domains are required", ErrInvalidACME)
+	case config.CacheDir == "":
+		err = fmt.Errorf("%w, cache directory is required", ErrInvalidACME)
+	case !config.AcceptTOS:
+		err = fmt.Errorf("%w, terms of service of certificate authority must be accepted", ErrInvalidACME)
+	case config.Challenge != "" && config.Challenge != ChallengeTLSALPN && config.Challenge != ChallengeHTTP:
+		err = fmt.Errorf("%w, unknown challenge %s", ErrInvalidACME, config.Challenge)
+	}
+	if err != nil {
+		return
+	}
+	m = &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(config.CacheDir),
+		HostPolicy: autocert.HostWhitelist(config.Domains...),
+		Email:      config.Email,
+	}
+	if config.DirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: config.DirectoryURL}
+	}
+	return
+}
+
+// ServeACME serves handler (e.g. echo instance of a Receiver) with TLS on addr until ctx is done, its certificate
+// being obtained from config ACME certificate authority on first request. tls-alpn-01 challenges are answered on
+// addr, http-01 ones by a listener on config HTTPAddress, redirecting other requests to https.
+func ServeACME(ctx context.Context, handler http.Handler, addr string, config sdk.ACMEConfig) (err error) {
+	m, err := NewCertManager(config)
+	if err != nil {
+		return
+	}
+	tlsConfig := m.TLSConfig()
+	tlsConfig.MinVersion = tls.VersionTLS12
+	servers := []*http.Server{{
+		Addr:              addr,
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}}
+	if config.Challenge == ChallengeHTTP {
+		httpAddr := config.HTTPAddress
+		if httpAddr == "" {
+			httpAddr = DefaultACMEHTTPAddress
+		}
+		servers = append(servers, &http.Server{
+			Addr:              httpAddr,
+			Handler:           m.HTTPHandler(nil),
+			ReadHeaderTimeout: 10 * time.Second,
+		})
+	}
+
+	errs := make(chan error, len(servers))
+	for i, server := range servers {
+		go func() {
+			if i == 0 {
+				errs <- server.ListenAndServeTLS("", "")
+				return
+			}
+			errs <- server.ListenAndServe()
+		}()
+	}
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case err = <-errs:
+		err = fmt.Errorf("could not serve webhook endpoint, %w", err)
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil {
+			logger.Warn("could not shut down webhook server", slog.String("address", server.Addr), slog.String("error", shutdownErr.Error()))
+		}
+	}
+	return
+}
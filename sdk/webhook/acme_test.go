@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+)
+
+func TestNewCertManager(t *testing.T) {
+	valid := sdk.ACMEConfig{
+		Enabled:      true,
+		Domains:      []string{"webhook.example.com"},
+		Email:        "admin@example.com",
+		DirectoryURL: "https://acme.example.com/directory",
+		CacheDir:     t.TempDir(),
+		AcceptTOS:    true,
+	}
+	m, err := NewCertManager(valid)
+	if err != nil {
+		t.Fatalf("NewCertManager() error = %v", err)
+	}
+	if m.Client.DirectoryURL != valid.DirectoryURL || m.Email != valid.Email {
+		t.Errorf("NewCertManager() directory = %s, email = %s, want config ones", m.Client.DirectoryURL, m.Email)
+	}
+	if err = m.HostPolicy(t.Context(), "webhook.example.com"); err != nil {
+		t.Errorf("HostPolicy(webhook.example.com) error = %v", err)
+	}
+	if err = m.HostPolicy(t.Context(), "other.example.com"); err == nil {
+		t.Errorf("HostPolicy(other.example.com) error = nil, want certificates of config domains only")
+	}
+	if !slices.Contains(m.TLSConfig().NextProtos, "acme-tls/1") {
+		t.Errorf("TLSConfig() protocols = %v, want tls-alpn-01 support", m.TLSConfig().NextProtos)
+	}
+
+	tests := []struct {
+		name   string
+		config func(c sdk.ACMEConfig) sdk.ACMEConfig
+	}{
+		{name: "disabled", config: func(c sdk.ACMEConfig) sdk.ACMEConfig { c.Enabled = false; return c }},
+		{name: "no domain", config: func(c sdk.ACMEConfig) sdk.ACMEConfig { c.Domains = []string{}; return c }},
+		{name: "no cache", config: func(c sdk.ACMEConfig) sdk.ACMEConfig { c.CacheDir = ""; return c }},
+		{name: "tos not accepted", config: func(c sdk.ACMEConfig) sdk.ACMEConfig { c.AcceptTOS = false; return c }},
+		{name: "unknown challenge", config: func(c sdk.ACMEConfig) sdk.ACMEConfig { c.Challenge = "dns-01"; return c }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCertManager(tt.config(valid)); !errors.Is(err, ErrInvalidACME) {
+				t.Errorf("NewCertManager() error = %v, want %v", err, ErrInvalidACME)
+			}
+		})
+	}
+}
+
+func TestServeACME(t *testing.T) {
+	config := sdk.ACMEConfig{
+		Enabled:     true,
+		Domains:     []string{"webhook.example.com"},
+		Challenge:   ChallengeHTTP,
+		HTTPAddress: "127.0.0.1:0",
+		CacheDir:    t.TempDir(),
+		AcceptTOS:   true,
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if err := ServeACME(ctx, http.NotFoundHandler(), "127.0.0.1:0", config); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ServeACME() error = %v, want servers stopped with ctx", err)
+	}
+
+	config.HTTPAddress = "127.0.0.1:invalid"
+	if err := ServeACME(t.Context(), http.NotFoundHandler(), "127.0.0.1:0", config); err == nil || errors.Is(err, context.Canceled) {
+		t.Errorf("ServeACME() error = %v, want listen error", err)
+	}
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+)
+
+const (
+	// DefaultRenewMargin is the default delay before expiration subscriptions are renewed.
+	DefaultRenewMargin = 10 * time.Minute
+	// DefaultRenewRetryInterval is the default delay between failed renewals.
+	DefaultRenewRetryInterval = time.Minute
+)
+
+// Subscription is an expiring webhook subscription, e.g. a Graph subscription (at most 30 days for drive items).
+type Subscription interface {
+	// Renew creates or renews subscription, and returns its new expiration
+	Renew(ctx context.Context) (expiration time.Time, err error)
+}
+
+type RenewerOptions struct {
+	// Margin is the delay before expiration subscription is renewed, DefaultRenewMargin if 0
+	Margin time.Duration
+	// RetryInterval is the delay between failed renewals, DefaultRenewRetryInterval if 0
+	RetryInterval time.Duration
+	// Metrics, if set, records renewals (e.g. ConnectorManagerClient.WebhookMetrics)
+	Metrics *metrics.WebhookMetrics
+}
+
+// Renewer schedules renewals of a subscription, so it does not expire while connector runs.
+type Renewer struct {
+	subscription Subscription
+	opts         RenewerOptions
+	now          func() time.Time
+}
+
+func NewRenewer(subscription Subscription, opts RenewerOptions) (r *Renewer) {
+	if opts.Margin <= 0 {
+		opts.Margin = DefaultRenewMargin
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = DefaultRenewRetryInterval
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = &metrics.WebhookMetrics{}
+	}
+	r = &Renewer{subscription: subscription, opts: opts, now: time.Now}
+	return
+}
+
+// Run renews subscription Margin before expiration until ctx is done, failed renewals being retried every
+// RetryInterval. Subscription is renewed right away for a zero (or past) expiration, e.g. to create it.
+func (r *Renewer) Run(ctx context.Context, expiration time.Time) (err error) {
+	next := expiration.Add(-r.opts.Margin)
+	for {
+		timer := time.NewTimer(max(next.Sub(r.now()), 0))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+			return
+		case <-timer.C:
+		}
+		renewed, renewErr := r.subscription.Renew(ctx)
+		if renewErr != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+				return
+			}
+			r.opts.Metrics.ObserveRenewal(true)
+			logger.Error("could not renew webhook subscription", slog.String("error", renewErr.Error()), slog.Time("expiration", expiration))
+			next = r.now().Add(r.opts.RetryInterval)
+			continue
+		}
+		r.opts.Metrics.ObserveRenewal(false)
+		expiration = renewed
+		next = expiration.Add(-r.opts.Margin)
+		if next.Before(r.now()) {
+			// subscription shorter than margin
+			next = r.now().Add(r.opts.RetryInterval)
+		}
+		logger.Debug("webhook subscription renewed", slog.Time("expiration", expiration))
+	}
+}
@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultSignatureHeader is the default header of HMACValidator signatures.
+const DefaultSignatureHeader = "X-Signature"
+
+// ErrMissingSecret is returned by validators without secret, which would accept forged deliveries.
+var ErrMissingSecret = errors.New("webhook validator secret is required")
+
+// checker is implemented by validators checking their settings, called by NewReceiver.
+type checker interface {
+	Check() (err error)
+}
+
+// HMACValidator checks HMAC-SHA256 signatures of deliveries, hex encoded in SignatureHeader.
+type HMACValidator struct {
+	Secret string `password:"true"`
+	// SignatureHeader, DefaultSignatureHeader if empty
+	SignatureHeader string
+	// Prefix of signatures, e.g. "sha256="
+	Prefix string
+	// TimestampHeader, if set, is signed with body: signature is computed on "<timestamp>.<body>"
+	TimestampHeader string
+}
+
+// Check returns ErrMissingSecret without Secret.
+func (v HMACValidator) Check() (err error) {
+	if v.Secret == "" {
+		err = ErrMissingSecret
+	}
+	return
+}
+
+func (v HMACValidator) Validate(delivery Delivery) (err error) {
+	if err = v.Check(); err != nil {
+		err = fmt.Errorf("%w, %w", ErrInvalidDelivery, err)
+		return
+	}
+	header := v.SignatureHeader
+	if header == "" {
+		header = DefaultSignatureHeader
+	}
+	encoded, ok := strings.CutPrefix(delivery.Header.Get(header), v.Prefix)
+	if !ok || encoded == "" {
+		err = fmt.Errorf("%w, missing %s signature", ErrInvalidDelivery, header)
+		return
+	}
+	signature, err := hex.DecodeString(encoded)
+	if err != nil {
+		err = fmt.Errorf("%w, invalid signature encoding", ErrInvalidDelivery)
+		return
+	}
+	if !hmac.Equal(signature, v.Sign(delivery)) {
+		err = fmt.Errorf("%w, signature mismatch", ErrInvalidDelivery)
+	}
+	return
+}
+
+// Sign returns signature of delivery, e.g. to send signed deliveries in tests.
+func (v HMACValidator) Sign(delivery Delivery) []byte {
+	mac := hmac.New(sha256.New, []byte(v.Secret))
+	if v.TimestampHeader != "" {
+		mac.Write([]byte(delivery.Header.Get(v.TimestampHeader) + "."))
+	}
+	mac.Write(delivery.Body)
+	return mac.Sum(nil)
+}
+
+// ClientStateValidator checks clientState of Graph change notifications, the secret given on subscription creation.
+// Every notification of a delivery must carry it.
+type ClientStateValidator struct {
+	ClientState string `password:"true"`
+}
+
+type graphNotifications struct {
+	Value []struct {
+		ClientState string `json:"clientState"`
+	} `json:"value"`
+}
+
+// Check returns ErrMissingSecret without ClientState.
+func (v ClientStateValidator) Check() (err error) {
+	if v.ClientState == "" {
+		err = ErrMissingSecret
+	}
+	return
+}
+
+func (v ClientStateValidator) Validate(delivery Delivery) (err error) {
+	if err = v.Check(); err != nil {
+		err = fmt.Errorf("%w, %w", ErrInvalidDelivery, err)
+		return
+	}
+	notifications := graphNotifications{}
+	if err = json.Unmarshal(delivery.Body, &notifications); err != nil {
+		err = fmt.Errorf("%w, invalid notifications, %w", ErrInvalidDelivery, err)
+		return
+	}
+	if len(notifications.Value) == 0 {
+		err = fmt.Errorf("%w, no notification", ErrInvalidDelivery)
+		return
+	}
+	for _, n := range notifications.Value {
+		if subtle.ConstantTimeCompare([]byte(n.ClientState), []byte(v.ClientState)) != 1 {
+			err = fmt.Errorf("%w, client state mismatch", ErrInvalidDelivery)
+			return
+		}
+	}
+	return
+}
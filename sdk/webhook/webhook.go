@@ -0,0 +1,226 @@
+// Package webhook provides the HTTPS webhook endpoints of connectors notified of changes (e.g. Sharepoint/Graph
+// change notifications): deliveries are validated (signature, client state), replays are rejected, and expiring
+// subscriptions are renewed ahead of their expiration.
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk"
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: sdk.LogLevel})).WithGroup("webhook")
+
+const (
+	// DefaultReplayWindow is the default delay during which a delivery is accepted once.
+	DefaultReplayWindow = 5 * time.Minute
+	// DefaultMaxBodySize bounds delivery bodies.
+	DefaultMaxBodySize = 1 << 20
+)
+
+var (
+	ErrInvalidReceiver = errors.New("invalid webhook receiver")
+	// ErrInvalidDelivery is returned by validators rejecting a delivery
+	ErrInvalidDelivery = errors.New("invalid webhook delivery")
+)
+
+// Delivery is a webhook request received by a Receiver.
+type Delivery struct {
+	Header http.Header
+	Query  map[string][]string
+	Body   []byte
+}
+
+// Validator authenticates deliveries, returning an error wrapping ErrInvalidDelivery for rejected ones.
+type Validator interface {
+	Validate(delivery Delivery) (err error)
+}
+
+// Handler handles a validated delivery. When it fails, delivery is answered 500, for the sender to deliver it again.
+type Handler func(ctx context.Context, delivery Delivery) (err error)
+
+type ReceiverOptions struct {
+	// Validators authenticate deliveries, every one of them must accept a delivery, at least one is required
+	Validators []Validator
+	// TimestampHeader, if set, is the header of deliveries sending time (unix seconds), deliveries sent more than
+	// ReplayWindow away from now being rejected. It should be signed (see HMACValidator.TimestampHeader).
+	TimestampHeader string
+	// ReplayWindow is the delay during which identical deliveries are handled once, DefaultReplayWindow if 0.
+	// Without TimestampHeader, it does not bound replays: a delivery is accepted again once ReplayWindow elapsed
+	// since it was handled, set TimestampHeader for captured deliveries to be rejected past it.
+	ReplayWindow time.Duration
+	// ValidationHandshake answers subscription validation requests (Graph validationToken query parameter) with
+	// their token, before validators
+	ValidationHandshake bool
+	// MaxBodySize bounds delivery bodies, DefaultMaxBodySize if 0
+	MaxBodySize int64
+	// Metrics, if set, records deliveries (e.g. ConnectorManagerClient.WebhookMetrics)
+	Metrics *metrics.WebhookMetrics
+}
+
+// Receiver is the echo handler of a webhook endpoint. Valid deliveries are answered 202 once handled, replays 200
+// without being handled again, and rejected ones 401.
+type Receiver struct {
+	handle Handler
+	opts   ReceiverOptions
+	now    func() time.Time
+
+	lock sync.Mutex
+	seen map[string]time.Time // delivery digest => expiration
+}
+
+// NewReceiver returns a receiver of deliveries handled by handle.
+func NewReceiver(handle Handler, opts ReceiverOptions) (r *Receiver, err error) {
+	switch {
+	case handle == nil:
+		err = fmt.Errorf("%w, handler is required", ErrInvalidReceiver)
+	case len(opts.Validators) == 0:
+		err = fmt.Errorf("%w, at least one validator is required", ErrInvalidReceiver)
+	}
+	if err != nil {
+		return
+	}
+	for _, validator := range opts.Validators {
+		if c, ok := validator.(checker); ok {
+			if err = c.Check(); err != nil {
+				err = fmt.Errorf("%w, %T, %w", ErrInvalidReceiver, validator, err)
+				return
+			}
+		}
+	}
+	if opts.ReplayWindow <= 0 {
+		opts.ReplayWindow = DefaultReplayWindow
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = &metrics.WebhookMetrics{}
+	}
+	r = &Receiver{
+		handle: handle,
+		opts:   opts,
+		now:    time.Now,
+		seen:   map[string]time.Time{},
+	}
+	return
+}
+
+// Register registers receiver on POST path of e.
+func (r *Receiver) Register(e *echo.Echo, path string) {
+	e.POST(path, r.Handle)
+}
+
+// Handle is the echo.HandlerFunc of receiver.
+func (r *Receiver) Handle(c echo.Context) (err error) {
+	req := c.Request()
+	if token := c.QueryParam("validationToken"); r.opts.ValidationHandshake && token != "" {
+		// plain text, so token is not interpreted by clients
+		err = c.String(http.StatusOK, token)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response(), req.Body, r.opts.MaxBodySize))
+	if err != nil {
+		err = echo.NewHTTPError(http.StatusRequestEntityTooLarge, "delivery body too large")
+		return
+	}
+	delivery := Delivery{Header: req.Header, Query: c.QueryParams(), Body: body}
+	if err = r.validate(delivery); err != nil {
+		r.opts.Metrics.AddRejected()
+		logger.Warn("webhook delivery rejected", slog.String("remote", c.RealIP()), slog.String("error", err.Error()))
+		err = echo.NewHTTPError(http.StatusUnauthorized, "invalid delivery")
+		return
+	}
+
+	digest := r.digest(delivery)
+	if !r.record(digest) {
+		r.opts.Metrics.AddReplayed()
+		err = c.NoContent(http.StatusOK)
+		return
+	}
+	if handleErr := r.handle(req.Context(), delivery); handleErr != nil {
+		// delivered again by sender
+		r.forget(digest)
+		r.opts.Metrics.AddFailed()
+		logger.Error("could not handle webhook delivery", slog.String("error", handleErr.Error()))
+		err = echo.NewHTTPError(http.StatusInternalServerError, "could not handle delivery")
+		return
+	}
+	r.opts.Metrics.AddReceived()
+	err = c.NoContent(http.StatusAccepted)
+	return
+}
+
+func (r *Receiver) validate(delivery Delivery) (err error) {
+	if r.opts.TimestampHeader != "" {
+		if err = r.checkTimestamp(delivery.Header.Get(r.opts.TimestampHeader)); err != nil {
+			return
+		}
+	}
+	for _, v := range r.opts.Validators {
+		if err = v.Validate(delivery); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (r *Receiver) checkTimestamp(value string) (err error) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		err = fmt.Errorf("%w, invalid timestamp %q", ErrInvalidDelivery, value)
+		return
+	}
+	if age := r.now().Sub(time.Unix(seconds, 0)).Abs(); age > r.opts.ReplayWindow {
+		err = fmt.Errorf("%w, delivery sent %s away from now", ErrInvalidDelivery, age.Truncate(time.Second))
+	}
+	return
+}
+
+// digest identifies a delivery by its body and timestamp.
+func (r *Receiver) digest(delivery Delivery) string {
+	h := sha256.New()
+	if r.opts.TimestampHeader != "" {
+		h.Write([]byte(delivery.Header.Get(r.opts.TimestampHeader)))
+		h.Write([]byte{0})
+	}
+	h.Write(delivery.Body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// record records digest, returning false if it was already seen within replay window.
+func (r *Receiver) record(digest string) (ok bool) {
+	now := r.now()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for d, expiration := range r.seen {
+		if now.After(expiration) {
+			delete(r.seen, d)
+		}
+	}
+	if _, seen := r.seen[digest]; seen {
+		return
+	}
+	r.seen[digest] = now.Add(r.opts.ReplayWindow)
+	ok = true
+	return
+}
+
+func (r *Receiver) forget(digest string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.seen, digest)
+}
@@ -0,0 +1,213 @@
+package webhook
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glimps-re/connector-integration/sdk/metrics"
+	"github.com/google/go-cmp/cmp"
+	"github.com/labstack/echo/v4"
+)
+
+func TestReceiver_Handle(t *testing.T) {
+	now := time.Unix(1738000000, 0)
+	signer := HMACValidator{Secret: "webhook-secret", Prefix: "sha256=", TimestampHeader: "X-Timestamp"}
+	signed := func(body string, timestamp time.Time) http.Header {
+		header := http.Header{}
+		header.Set("X-Timestamp", strconv.FormatInt(timestamp.Unix(), 10))
+		header.Set(DefaultSignatureHeader, "sha256="+hex.EncodeToString(signer.Sign(Delivery{Header: header, Body: []byte(body)})))
+		return header
+	}
+
+	type request struct {
+		query  string
+		header http.Header
+		body   string
+	}
+	tests := []struct {
+		name        string
+		opts        ReceiverOptions
+		failFirst   bool
+		requests    []request
+		wantCodes   []int
+		wantHandled []string
+		wantMetrics metrics.WebhookMetricsSnapshot
+	}{
+		{
+			name: "signed deliveries",
+			opts: ReceiverOptions{Validators: []Validator{signer}, TimestampHeader: "X-Timestamp"},
+			requests: []request{
+				{header: signed(`{"id":1}`, now), body: `{"id":1}`},
+				{header: signed(`{"id":2}`, now.Add(-time.Minute)), body: `{"id":2}`},
+				// replayed
+				{header: signed(`{"id":1}`, now), body: `{"id":1}`},
+				// signature of another body
+				{header: signed(`{"id":3}`, now), body: `{"id":4}`},
+				// stale
+				{header: signed(`{"id":5}`, now.Add(-time.Hour)), body: `{"id":5}`},
+				{body: `{"id":6}`},
+			},
+			wantCodes:   []int{http.StatusAccepted, http.StatusAccepted, http.StatusOK, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized},
+			wantHandled: []string{`{"id":1}`, `{"id":2}`},
+			wantMetrics: metrics.WebhookMetricsSnapshot{Received: 2, Replayed: 1, Rejected: 3},
+		},
+		{
+			name:      "failed delivery delivered again",
+			opts:      ReceiverOptions{Validators: []Validator{signer}, TimestampHeader: "X-Timestamp"},
+			failFirst: true,
+			requests: []request{
+				{header: signed(`{"id":1}`, now), body: `{"id":1}`},
+				{header: signed(`{"id":1}`, now), body: `{"id":1}`},
+			},
+			wantCodes:   []int{http.StatusInternalServerError, http.StatusAccepted},
+			wantHandled: []string{`{"id":1}`},
+			wantMetrics: metrics.WebhookMetricsSnapshot{Received: 1, Failed: 1},
+		},
+		{
+			name: "graph notifications",
+			opts: ReceiverOptions{Validators: []Validator{ClientStateValidator{ClientState: "client-secret"}}, ValidationHandshake: true},
+			requests: []request{
+				{query: "validationToken=token%3Cscript%3E"},
+				{body: `{"value":[{"clientState":"client-secret","resource":"drives/1/root"}]}`},
+				{body: `{"value":[{"clientState":"client-secret"},{"clientState":"other"}]}`},
+				{body: `{"value":[]}`},
+			},
+			wantCodes:   []int{http.StatusOK, http.StatusAccepted, http.StatusUnauthorized, http.StatusUnauthorized},
+			wantHandled: []string{`{"value":[{"clientState":"client-secret","resource":"drives/1/root"}]}`},
+			wantMetrics: metrics.WebhookMetricsSnapshot{Received: 1, Rejected: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled []string
+			failed := false
+			tt.opts.Metrics = &metrics.WebhookMetrics{}
+			r, err := NewReceiver(func(ctx context.Context, delivery Delivery) (err error) {
+				if tt.failFirst && !failed {
+					failed = true
+					err = errors.New("queue full")
+					return
+				}
+				handled = append(handled, string(delivery.Body))
+				return
+			}, tt.opts)
+			if err != nil {
+				t.Fatalf("NewReceiver() error = %v", err)
+			}
+			r.now = func() time.Time { return now }
+			e := echo.New()
+			r.Register(e, "/webhook")
+
+			var codes []int
+			for _, req := range tt.requests {
+				httpReq := httptest.NewRequest(http.MethodPost, "/webhook?"+req.query, strings.NewReader(req.body))
+				if req.header != nil {
+					httpReq.Header = req.header
+				}
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, httpReq)
+				codes = append(codes, rec.Code)
+				if req.query != "" && rec.Body.String() != "token<script>" {
+					t.Errorf("validation handshake body = %q, want token", rec.Body.String())
+				}
+				if req.query != "" && !strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), echo.MIMETextPlain) {
+					t.Errorf("validation handshake content type = %q, want text/plain", rec.Header().Get(echo.HeaderContentType))
+				}
+			}
+			if diff := cmp.Diff(codes, tt.wantCodes); diff != "" {
+				t.Errorf("status codes diff(got-want)=%s", diff)
+			}
+			if diff := cmp.Diff(handled, tt.wantHandled); diff != "" {
+				t.Errorf("handled deliveries diff(got-want)=%s", diff)
+			}
+			if diff := cmp.Diff(tt.opts.Metrics.Snapshot(), tt.wantMetrics); diff != "" {
+				t.Errorf("metrics diff(got-want)=%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewReceiver(t *testing.T) {
+	handle := func(context.Context, Delivery) error { return nil }
+	if _, err := NewReceiver(handle, ReceiverOptions{}); !errors.Is(err, ErrInvalidReceiver) {
+		t.Errorf("NewReceiver() without validator error = %v, want %v", err, ErrInvalidReceiver)
+	}
+	if _, err := NewReceiver(nil, ReceiverOptions{Validators: []Validator{HMACValidator{}}}); !errors.Is(err, ErrInvalidReceiver) {
+		t.Errorf("NewReceiver() without handler error = %v, want %v", err, ErrInvalidReceiver)
+	}
+	for _, validator := range []Validator{HMACValidator{}, ClientStateValidator{}} {
+		if _, err := NewReceiver(handle, ReceiverOptions{Validators: []Validator{validator}}); !errors.Is(err, ErrMissingSecret) {
+			t.Errorf("NewReceiver() with %T without secret error = %v, want %v", validator, err, ErrMissingSecret)
+		}
+	}
+}
+
+func TestValidators_missingSecret(t *testing.T) {
+	forged := http.Header{}
+	forged.Set(DefaultSignatureHeader, hex.EncodeToString(HMACValidator{}.Sign(Delivery{Body: []byte(`{}`)})))
+	tests := []struct {
+		name      string
+		validator Validator
+		delivery  Delivery
+	}{
+		{name: "hmac", validator: HMACValidator{}, delivery: Delivery{Header: forged, Body: []byte(`{}`)}},
+		{name: "client state", validator: ClientStateValidator{}, delivery: Delivery{Body: []byte(`{"value":[{"resource":"drives/1/root"}]}`)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.validator.Validate(tt.delivery); !errors.Is(err, ErrInvalidDelivery) || !errors.Is(err, ErrMissingSecret) {
+				t.Errorf("Validate() error = %v, want %v", err, ErrMissingSecret)
+			}
+		})
+	}
+}
+
+type fakeSubscription struct {
+	renewals int
+	failures int // renewals failing before next success
+	lifetime time.Duration
+	cancel   context.CancelFunc
+}
+
+func (s *fakeSubscription) Renew(ctx context.Context) (expiration time.Time, err error) {
+	if s.failures > 0 {
+		s.failures--
+		err = errors.New("subscription service unavailable")
+		return
+	}
+	s.renewals++
+	if s.renewals == 3 {
+		s.cancel()
+	}
+	expiration = time.Now().Add(s.lifetime)
+	return
+}
+
+func TestRenewer_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	subscription := &fakeSubscription{failures: 1, lifetime: 20 * time.Millisecond, cancel: cancel}
+	m := &metrics.WebhookMetrics{}
+	r := NewRenewer(subscription, RenewerOptions{Margin: 15 * time.Millisecond, RetryInterval: time.Millisecond, Metrics: m})
+	start := time.Now()
+	// not subscribed yet
+	if err := r.Run(ctx, time.Time{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want %v", err, context.Canceled)
+	}
+	if subscription.renewals != 3 {
+		t.Errorf("renewals = %d, want 3", subscription.renewals)
+	}
+	// renewed 5ms after each renewal
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("3 renewals took %s, want renewals scheduled before expiration", elapsed)
+	}
+	if got := m.Snapshot(); got.Renewals != 3 || got.RenewalFailures != 1 {
+		t.Errorf("metrics = %+v, want 3 renewals, 1 failure", got)
+	}
+}